@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+)
+
+// A handler that gets a plain error from the service layer has to guess:
+// was it the user's fault (4xx) or ours (500)? Guessing with strings.Contains(err.Error(), "not found")
+// breaks as soon as someone rewords a message. Instead the service attaches a Code
+// to its errors, and ONE function turns codes into HTTP statuses.
+
+type Code string
+
+const (
+	NotFound     Code = "not_found"
+	Invalid      Code = "invalid"
+	Conflict     Code = "conflict"
+	Unauthorized Code = "unauthorized"
+	Internal     Code = "internal" // anything without a code ends up here
+)
+
+// Error is a domain error: a code, a message safe to show the client, and the cause
+type Error struct {
+	Code Code
+	Msg  string
+	Err  error // may be nil
+}
+
+// E builds an *Error. wrapped is kept for errors.Is/As and for the logs, never sent to the client.
+func E(code Code, msg string, wrapped error) error {
+	return &Error{Code: code, Msg: msg, Err: wrapped}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// CodeOf walks the unwrap chain (fmt.Errorf %w, errors.Join...) and returns the first code found.
+// nil has no code, any other error without one is Internal.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
+
+// HTTPStatus is the only place that knows how codes map to statuses
+func HTTPStatus(code Code) int {
+	switch code {
+	case NotFound:
+		return http.StatusNotFound
+	case Invalid:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case Unauthorized:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// publicMessage is what the client may see. An internal error's text can hold SQL,
+// file paths or hostnames, so it is replaced with a generic message.
+func publicMessage(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Code != Internal {
+		return e.Msg
+	}
+	return "internal error"
+}
+
+// ----------------------------------------------------------------------------
+// Store and service
+
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// UserStore knows nothing about HTTP or codes, it returns its own sentinel errors
+type UserStore struct {
+	mu      sync.Mutex
+	users   map[int]User
+	byEmail map[string]int
+	nextID  int
+	broken  bool // to show what happens with an unexpected error
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[int]User{}, byEmail: map[string]int{}, nextID: 1}
+}
+
+func (s *UserStore) Insert(u User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken {
+		return User{}, errors.New("write users.db: disk quota exceeded on /var/lib/app")
+	}
+	if _, dup := s.byEmail[u.Email]; dup {
+		return User{}, fmt.Errorf("insert %s: %w", u.Email, ErrDuplicateEmail)
+	}
+	u.ID = s.nextID
+	s.nextID++
+	s.users[u.ID] = u
+	s.byEmail[u.Email] = u.ID
+	return u, nil
+}
+
+func (s *UserStore) Get(id int) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	return u, ok
+}
+
+// UserService is where errors get their meaning
+type UserService struct {
+	store *UserStore
+}
+
+func (s *UserService) Create(name, email string) (User, error) {
+	if strings.TrimSpace(name) == "" {
+		return User{}, E(Invalid, "name is required", nil)
+	}
+	if _, err := mail.ParseAddress(email); err != nil {
+		return User{}, E(Invalid, "email is not valid", err)
+	}
+	u, err := s.store.Insert(User{Name: name, Email: email})
+	if errors.Is(err, ErrDuplicateEmail) {
+		return User{}, E(Conflict, "email already registered", err)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("create user: %w", err) // no code: Internal
+	}
+	return u, nil
+}
+
+func (s *UserService) Get(id int) (User, error) {
+	u, ok := s.store.Get(id)
+	if !ok {
+		return User{}, E(NotFound, fmt.Sprintf("user %d not found", id), nil)
+	}
+	return u, nil
+}
+
+// ----------------------------------------------------------------------------
+// Handlers: they just return the error
+
+// handler is an http.HandlerFunc that can fail
+type handler func(w http.ResponseWriter, r *http.Request) error
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h(w, r); err != nil {
+		writeError(w, r, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	code := CodeOf(err)
+	status := HTTPStatus(code)
+	if status == http.StatusInternalServerError {
+		// the full error goes to the log, the client only gets the public message
+		fmt.Printf("    log: %s %s: %v\n", r.Method, r.URL.Path, err)
+	}
+	writeJSON(w, status, map[string]string{"error": publicMessage(err), "code": string(code)})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func routes(svc *UserService) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("POST /users", handler(func(w http.ResponseWriter, r *http.Request) error {
+		var in struct{ Name, Email string }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			return E(Invalid, "body must be JSON", err)
+		}
+		u, err := svc.Create(in.Name, in.Email)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusCreated, u)
+		return nil
+	}))
+	mux.Handle("GET /users/{id}", handler(func(w http.ResponseWriter, r *http.Request) error {
+		var id int
+		if _, err := fmt.Sscan(r.PathValue("id"), &id); err != nil {
+			return E(Invalid, "id must be a number", err)
+		}
+		u, err := svc.Get(id)
+		if err != nil {
+			return err
+		}
+		writeJSON(w, http.StatusOK, u)
+		return nil
+	}))
+	return mux
+}
+
+func main() {
+	fmt.Println("Learning error codes that map to HTTP statuses in Go")
+
+	store := NewUserStore()
+	h := routes(&UserService{store: store})
+	do := func(method, path, body string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		fmt.Printf("  %-4s %-10s %-50s -> %d %s", method, path, body, rec.Code, rec.Body.String())
+	}
+	do("POST", "/users", `{"name":"Rishabh","email":"rishabh@example.com"}`)
+	do("POST", "/users", `{"name":"Other","email":"rishabh@example.com"}`)
+	do("POST", "/users", `{"name":"","email":"a@example.com"}`)
+	do("POST", "/users", `{"name":"Bob","email":"not-an-email"}`)
+	do("POST", "/users", `{oops`)
+	do("GET", "/users/1", "")
+	do("GET", "/users/9", "")
+	do("GET", "/users/abc", "")
+	store.broken = true
+	do("POST", "/users", `{"name":"Alice","email":"alice@example.com"}`)
+
+	// the code survives extra wrapping, and the cause is still there for errors.Is
+	fmt.Println("Through the chain:")
+	err := fmt.Errorf("signup flow: %w", E(Conflict, "email already registered", fmt.Errorf("insert: %w", ErrDuplicateEmail)))
+	fmt.Println("  CodeOf:", CodeOf(err), "| status:", HTTPStatus(CodeOf(err)), "| is ErrDuplicateEmail:", errors.Is(err, ErrDuplicateEmail))
+	err = errors.New("connection reset")
+	fmt.Println("  plain error:", CodeOf(err), HTTPStatus(CodeOf(err)), "| client sees:", publicMessage(err))
+	fmt.Printf("  nil: %q\n", CodeOf(nil))
+}
+
+// Give errors a code where their meaning is known (the service), not where they are printed.
+// Map codes to statuses in ONE function, handlers just return errors upward.
+// errors.As finds the code through any amount of %w wrapping, and the cause stays for errors.Is.
+// An error without a code is a 500, and its text goes to the log, never to the client.
+// Handlers returning error (instead of writing the error themselves) can't forget a return after http.Error.
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCodeOf(t *testing.T) {
+	dup := fmt.Errorf("insert: %w", ErrDuplicateEmail)
+	tests := []struct {
+		name string
+		err  error
+		want Code
+	}{
+		{"nil", nil, ""},
+		{"plain", errors.New("boom"), Internal},
+		{"direct", E(NotFound, "user 1 not found", nil), NotFound},
+		{"wrapped with %w", fmt.Errorf("signup: %w", E(Conflict, "taken", dup)), Conflict},
+		{"wrapped twice", fmt.Errorf("a: %w", fmt.Errorf("b: %w", E(Invalid, "bad", nil))), Invalid},
+		{"wrapped with %v loses it", fmt.Errorf("signup: %v", E(Conflict, "taken", dup)), Internal},
+		{"joined", errors.Join(errors.New("x"), E(Unauthorized, "who are you", nil)), Unauthorized},
+		{"the outermost code wins", E(NotFound, "outer", E(Conflict, "inner", nil)), NotFound},
+		{"sentinel without a code", dup, Internal},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.want {
+			t.Errorf("%s: CodeOf = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	tests := map[Code]int{
+		NotFound:     http.StatusNotFound,
+		Invalid:      http.StatusBadRequest,
+		Conflict:     http.StatusConflict,
+		Unauthorized: http.StatusUnauthorized,
+		Internal:     http.StatusInternalServerError,
+		"":           http.StatusInternalServerError,
+		"teapot":     http.StatusInternalServerError,
+	}
+	for code, want := range tests {
+		if got := HTTPStatus(code); got != want {
+			t.Errorf("HTTPStatus(%q) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestErrorChain(t *testing.T) {
+	cause := fmt.Errorf("insert a@b.c: %w", ErrDuplicateEmail)
+	err := fmt.Errorf("signup: %w", E(Conflict, "email already registered", cause))
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Error("the cause is lost")
+	}
+	if got := err.Error(); got != "signup: email already registered: insert a@b.c: duplicate email" {
+		t.Errorf("Error() = %q", got)
+	}
+	if got := E(NotFound, "user 3 not found", nil).Error(); got != "user 3 not found" {
+		t.Errorf("without a cause: %q", got)
+	}
+}
+
+func TestPublicMessage(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{E(Conflict, "email already registered", errors.New("pq: unique_violation users_email_key")), "email already registered"},
+		{fmt.Errorf("ctx: %w", E(Invalid, "name is required", nil)), "name is required"},
+		{E(Internal, "query failed on db-3.internal", nil), "internal error"},
+		{errors.New("open /var/lib/app/users.db: permission denied"), "internal error"},
+	}
+	for _, tt := range tests {
+		if got := publicMessage(tt.err); got != tt.want {
+			t.Errorf("publicMessage(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	store := NewUserStore()
+	h := routes(&UserService{store: store})
+	do := func(method, path, body string) (int, map[string]any) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s %s: Content-Type %q", method, path, ct)
+		}
+		var out map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, rec.Body)
+		}
+		return rec.Code, out
+	}
+
+	if code, u := do("POST", "/users", `{"name":"Rishabh","email":"rishabh@example.com"}`); code != 201 || u["id"] != 1.0 {
+		t.Fatalf("create: %d %v", code, u)
+	}
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               Code
+		msg                string
+	}{
+		{"POST", "/users", `{"name":"Other","email":"rishabh@example.com"}`, 409, Conflict, "email already registered"},
+		{"POST", "/users", `{"name":" ","email":"a@example.com"}`, 400, Invalid, "name is required"},
+		{"POST", "/users", `{"name":"Bob","email":"not-an-email"}`, 400, Invalid, "email is not valid"},
+		{"POST", "/users", `{oops`, 400, Invalid, "body must be JSON"},
+		{"GET", "/users/9", "", 404, NotFound, "user 9 not found"},
+		{"GET", "/users/abc", "", 400, Invalid, "id must be a number"},
+	}
+	for _, tt := range tests {
+		status, body := do(tt.method, tt.path, tt.body)
+		if status != tt.status || body["code"] != string(tt.code) || body["error"] != tt.msg {
+			t.Errorf("%s %s %s: %d %v, want %d %s %q", tt.method, tt.path, tt.body, status, body, tt.status, tt.code, tt.msg)
+		}
+	}
+	if status, u := do("GET", "/users/1", ""); status != 200 || u["email"] != "rishabh@example.com" {
+		t.Errorf("get: %d %v", status, u)
+	}
+
+	// an unexpected error: 500, and nothing of its text reaches the client
+	store.broken = true
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"Alice","email":"alice@example.com"}`)))
+	if rec.Code != 500 || strings.TrimSpace(rec.Body.String()) != `{"code":"internal","error":"internal error"}` {
+		t.Errorf("broken store: %d %s", rec.Code, rec.Body)
+	}
+	for _, secret := range []string{"disk", "quota", "/var/lib", "users.db"} {
+		if strings.Contains(rec.Body.String(), secret) {
+			t.Errorf("response leaks %q: %s", secret, rec.Body)
+		}
+	}
+}
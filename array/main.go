@@ -1,6 +1,191 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOutOfBounds is returned when a row or column is outside the grid
+var ErrOutOfBounds = errors.New("index out of bounds")
+
+// Grid is a 2-D table stored in ONE flat slice, row after row:
+// cell (r, c) is cells[r*cols+c]. One allocation instead of one slice per row.
+type Grid[T any] struct {
+	rows, cols int
+	cells      []T
+}
+
+func NewGrid[T any](rows, cols int) (*Grid[T], error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("new grid %dx%d: rows and cols must be at least 1", rows, cols)
+	}
+	return &Grid[T]{rows: rows, cols: cols, cells: make([]T, rows*cols)}, nil
+}
+
+func (g *Grid[T]) Rows() int { return g.rows }
+func (g *Grid[T]) Cols() int { return g.cols }
+
+func (g *Grid[T]) inBounds(r, c int) bool {
+	return r >= 0 && r < g.rows && c >= 0 && c < g.cols
+}
+
+// At returns the cell at row r, column c
+func (g *Grid[T]) At(r, c int) (T, error) {
+	if !g.inBounds(r, c) {
+		var zero T
+		return zero, fmt.Errorf("at (%d, %d) in %dx%d grid: %w", r, c, g.rows, g.cols, ErrOutOfBounds)
+	}
+	return g.cells[r*g.cols+c], nil
+}
+
+func (g *Grid[T]) Set(r, c int, v T) error {
+	if !g.inBounds(r, c) {
+		return fmt.Errorf("set (%d, %d) in %dx%d grid: %w", r, c, g.rows, g.cols, ErrOutOfBounds)
+	}
+	g.cells[r*g.cols+c] = v
+	return nil
+}
+
+// Fill sets every cell to v
+func (g *Grid[T]) Fill(v T) {
+	for i := range g.cells {
+		g.cells[i] = v
+	}
+}
+
+// RowSlice returns a copy of row r, changing it doesn't change the grid
+func (g *Grid[T]) RowSlice(r int) ([]T, error) {
+	if r < 0 || r >= g.rows {
+		return nil, fmt.Errorf("row %d in %dx%d grid: %w", r, g.rows, g.cols, ErrOutOfBounds)
+	}
+	row := make([]T, g.cols)
+	copy(row, g.cells[r*g.cols:(r+1)*g.cols])
+	return row, nil
+}
+
+// ColSlice returns a copy of column c
+func (g *Grid[T]) ColSlice(c int) ([]T, error) {
+	if c < 0 || c >= g.cols {
+		return nil, fmt.Errorf("column %d in %dx%d grid: %w", c, g.rows, g.cols, ErrOutOfBounds)
+	}
+	col := make([]T, g.rows)
+	for r := range col {
+		col[r] = g.cells[r*g.cols+c]
+	}
+	return col, nil
+}
+
+// Transpose returns a new cols x rows grid with rows and columns swapped
+func (g *Grid[T]) Transpose() *Grid[T] {
+	t := &Grid[T]{rows: g.cols, cols: g.rows, cells: make([]T, len(g.cells))}
+	for r := 0; r < g.rows; r++ {
+		for c := 0; c < g.cols; c++ {
+			t.cells[c*t.cols+r] = g.cells[r*g.cols+c]
+		}
+	}
+	return t
+}
+
+// Neighbors returns the values of the up to 8 cells around (r, c).
+// Corner cells have 3 neighbors and edge cells 5, nothing wraps around.
+func (g *Grid[T]) Neighbors(r, c int) ([]T, error) {
+	if !g.inBounds(r, c) {
+		return nil, fmt.Errorf("neighbors of (%d, %d) in %dx%d grid: %w", r, c, g.rows, g.cols, ErrOutOfBounds)
+	}
+	var result []T
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			if (dr != 0 || dc != 0) && g.inBounds(r+dr, c+dc) {
+				result = append(result, g.cells[(r+dr)*g.cols+c+dc])
+			}
+		}
+	}
+	return result, nil
+}
+
+func (g *Grid[T]) String() string {
+	var sb strings.Builder
+	for r := 0; r < g.rows; r++ {
+		fmt.Fprintln(&sb, g.cells[r*g.cols:(r+1)*g.cols])
+	}
+	return sb.String()
+}
+
+// lifeStep computes the next generation of Conway's Game of Life:
+// a live cell with 2 or 3 live neighbors survives, a dead cell with exactly 3 comes alive
+func lifeStep(g *Grid[bool]) *Grid[bool] {
+	next, _ := NewGrid[bool](g.Rows(), g.Cols())
+	for r := 0; r < g.Rows(); r++ {
+		for c := 0; c < g.Cols(); c++ {
+			neighbors, _ := g.Neighbors(r, c) // r, c are always in bounds here
+			alive := 0
+			for _, n := range neighbors {
+				if n {
+					alive++
+				}
+			}
+			cell, _ := g.At(r, c)
+			next.Set(r, c, alive == 3 || (cell && alive == 2))
+		}
+	}
+	return next
+}
+
+func printLife(g *Grid[bool]) {
+	for r := 0; r < g.Rows(); r++ {
+		row, _ := g.RowSlice(r)
+		var sb strings.Builder
+		for _, alive := range row {
+			if alive {
+				sb.WriteString("# ")
+			} else {
+				sb.WriteString(". ")
+			}
+		}
+		fmt.Println(" ", strings.TrimSpace(sb.String()))
+	}
+}
+
+// GridExamples shows the Grid type and runs a few generations of the Game of Life
+func GridExamples() {
+	fmt.Println("\nA generic 2-D grid")
+
+	g, _ := NewGrid[int](2, 3) // not square on purpose
+	for r := 0; r < 2; r++ {
+		for c := 0; c < 3; c++ {
+			g.Set(r, c, r*10+c)
+		}
+	}
+	fmt.Print("2x3 grid:\n", g)
+	t := g.Transpose()
+	fmt.Print("Transposed 3x2:\n", t)
+	fmt.Println("Transposed twice equals original:", t.Transpose().String() == g.String())
+	row, _ := g.RowSlice(1)
+	col, _ := g.ColSlice(2)
+	fmt.Println("Row 1:", row, "column 2:", col)
+	corner, _ := g.Neighbors(0, 0)
+	middle, _ := g.Neighbors(0, 1)
+	fmt.Println("Neighbors of corner (0,0):", corner, "of edge (0,1):", middle)
+
+	if _, err := g.At(2, 0); errors.Is(err, ErrOutOfBounds) {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("Set outside:", g.Set(-1, 0, 5))
+	_, err := NewGrid[int](0, 3)
+	fmt.Println("Empty grid:", err)
+
+	// Game of Life: a glider moves one cell diagonally every 4 generations
+	life, _ := NewGrid[bool](6, 6)
+	for _, cell := range [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}} {
+		life.Set(cell[0], cell[1], true)
+	}
+	for gen := 0; gen <= 4; gen++ {
+		fmt.Println("Generation", gen)
+		printLife(life)
+		life = lifeStep(life)
+	}
+}
 
 func main() {
 	fmt.Println("Learning Go arrays")
@@ -38,4 +223,9 @@ func main() {
 		fmt.Printf("Value is %d and Type is %T\n", val, val)
 	}
 
+	GridExamples()
 }
+
+// Arrays have a fixed size that is part of the type: [3]int and [4]int are different types.
+// [3][3]int works for fixed 2-D tables, for sizes known only at run time use a flat slice
+// with index r*cols+c like Grid does.
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// numbered returns a rows x cols grid with cell (r, c) = r*10+c
+func numbered(t *testing.T, rows, cols int) *Grid[int] {
+	t.Helper()
+	g, err := NewGrid[int](rows, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r := range rows {
+		for c := range cols {
+			if err := g.Set(r, c, r*10+c); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return g
+}
+
+func TestNewGrid(t *testing.T) {
+	for _, size := range [][2]int{{0, 3}, {3, 0}, {-1, 2}} {
+		if g, err := NewGrid[int](size[0], size[1]); err == nil {
+			t.Errorf("NewGrid(%d, %d) = %v, want an error", size[0], size[1], g)
+		}
+	}
+	g, err := NewGrid[string](1, 1)
+	if err != nil || g.Rows() != 1 || g.Cols() != 1 {
+		t.Fatalf("1x1: %v, %v", g, err)
+	}
+}
+
+func TestBounds(t *testing.T) {
+	g := numbered(t, 2, 3)
+	for _, rc := range [][2]int{{-1, 0}, {0, -1}, {2, 0}, {0, 3}, {2, 3}} {
+		if _, err := g.At(rc[0], rc[1]); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("At%v: %v", rc, err)
+		}
+		if err := g.Set(rc[0], rc[1], 1); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("Set%v: %v", rc, err)
+		}
+		if _, err := g.Neighbors(rc[0], rc[1]); !errors.Is(err, ErrOutOfBounds) {
+			t.Errorf("Neighbors%v: %v", rc, err)
+		}
+	}
+	if _, err := g.RowSlice(2); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("RowSlice(2): %v", err)
+	}
+	if _, err := g.ColSlice(3); !errors.Is(err, ErrOutOfBounds) {
+		t.Errorf("ColSlice(3): %v", err)
+	}
+	if v, err := g.At(1, 2); err != nil || v != 12 {
+		t.Errorf("At(1, 2) = %d, %v", v, err)
+	}
+}
+
+func TestRowsColsAreCopies(t *testing.T) {
+	g := numbered(t, 2, 3)
+	row, _ := g.RowSlice(1)
+	col, _ := g.ColSlice(2)
+	if !slices.Equal(row, []int{10, 11, 12}) || !slices.Equal(col, []int{2, 12}) {
+		t.Fatalf("row %v, col %v", row, col)
+	}
+	row[0], col[0] = -1, -1
+	if v, _ := g.At(1, 0); v != 10 {
+		t.Error("changing the row copy changed the grid")
+	}
+	if v, _ := g.At(0, 2); v != 2 {
+		t.Error("changing the column copy changed the grid")
+	}
+
+	g.Fill(7)
+	for r := range 2 {
+		if row, _ := g.RowSlice(r); !slices.Equal(row, []int{7, 7, 7}) {
+			t.Errorf("after Fill row %d = %v", r, row)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	for _, size := range [][2]int{{1, 1}, {1, 5}, {2, 3}, {4, 2}, {3, 3}} {
+		g := numbered(t, size[0], size[1])
+		tr := g.Transpose()
+		if tr.Rows() != g.Cols() || tr.Cols() != g.Rows() {
+			t.Fatalf("%v: transposed to %dx%d", size, tr.Rows(), tr.Cols())
+		}
+		for r := range g.Rows() {
+			for c := range g.Cols() {
+				a, _ := g.At(r, c)
+				b, _ := tr.At(c, r)
+				if a != b {
+					t.Fatalf("%v: (%d,%d)=%d but transposed (%d,%d)=%d", size, r, c, a, c, r, b)
+				}
+			}
+		}
+		if back := tr.Transpose(); back.String() != g.String() {
+			t.Errorf("%v: round trip\n%s\nwant\n%s", size, back, g)
+		}
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	g := numbered(t, 3, 4)
+	tests := []struct {
+		r, c int
+		want []int
+	}{
+		{0, 0, []int{1, 10, 11}},                   // corner
+		{2, 3, []int{12, 13, 22}},                  // opposite corner
+		{0, 1, []int{0, 2, 10, 11, 12}},            // top edge
+		{1, 3, []int{2, 3, 12, 22, 23}},            // right edge
+		{1, 1, []int{0, 1, 2, 10, 12, 20, 21, 22}}, // inside
+		{1, 2, []int{1, 2, 3, 11, 13, 21, 22, 23}}, // inside
+	}
+	for _, tt := range tests {
+		got, err := g.Neighbors(tt.r, tt.c)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("Neighbors(%d, %d) = %v, %v; want %v", tt.r, tt.c, got, err, tt.want)
+		}
+	}
+	one, _ := NewGrid[int](1, 1)
+	if n, _ := one.Neighbors(0, 0); len(n) != 0 {
+		t.Errorf("a 1x1 grid has neighbors %v", n)
+	}
+}
+
+func TestLifeGlider(t *testing.T) {
+	life, _ := NewGrid[bool](6, 6)
+	glider := [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}
+	for _, cell := range glider {
+		life.Set(cell[0], cell[1], true)
+	}
+	for range 4 {
+		life = lifeStep(life)
+	}
+	// after 4 generations the glider is the same shape, one cell down and right
+	want, _ := NewGrid[bool](6, 6)
+	for _, cell := range glider {
+		want.Set(cell[0]+1, cell[1]+1, true)
+	}
+	if life.String() != want.String() {
+		t.Errorf("got\n%s\nwant\n%s", life, want)
+	}
+
+	// a blinker in the corner: cells at the edge only count the neighbors that exist
+	blinker, _ := NewGrid[bool](3, 3)
+	blinker.Set(0, 0, true)
+	blinker.Set(0, 1, true)
+	blinker.Set(0, 2, true)
+	next := lifeStep(blinker)
+	for _, cell := range [][3]int{{0, 1, 1}, {1, 1, 1}, {0, 0, 0}, {0, 2, 0}, {1, 0, 0}} {
+		if v, _ := next.At(cell[0], cell[1]); v != (cell[2] == 1) {
+			t.Errorf("(%d,%d) = %v", cell[0], cell[1], v)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"time"
+)
+
+// The same users, three ways to turn them into bytes:
+//   - encoding/json: readable, works with every language, the biggest and slowest
+//   - encoding/gob: Go-only, describes the types once per stream, then sends compact values
+//   - encoding/binary by hand: the fastest, no reflection at all, but every field is code you
+//     write, and changing the format later means versioning it yourself
+
+var ErrShortData = errors.New("data too short")
+
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// equal compares times with Equal: a round trip may change the *time.Location, not the instant
+func (u User) equal(o User) bool {
+	return u.ID == o.ID && u.Name == o.Name && u.Email == o.Email && u.Active == o.Active && u.CreatedAt.Equal(o.CreatedAt)
+}
+
+// ----------------------------------------------------------------------------
+// Hand-written binary format, all integers big-endian and fixed width:
+//
+//	id int64 | len(name) uint32 | name | len(email) uint32 | email | active uint8 |
+//	created seconds int64 | created nanoseconds uint32
+//
+// Seconds + nanoseconds instead of UnixNano: UnixNano only covers the years 1678-2262,
+// and the zero time.Time is outside that.
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func AppendUser(b []byte, u User) []byte {
+	b = binary.BigEndian.AppendUint64(b, uint64(u.ID))
+	b = appendString(b, u.Name)
+	b = appendString(b, u.Email)
+	active := byte(0)
+	if u.Active {
+		active = 1
+	}
+	b = append(b, active)
+	b = binary.BigEndian.AppendUint64(b, uint64(u.CreatedAt.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(u.CreatedAt.Nanosecond()))
+}
+
+// reader walks a byte slice and remembers the first error, so decoding code reads
+// straight through and checks once at the end (like bufio.Scanner's Err)
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = fmt.Errorf("%w: need %d bytes, have %d", ErrShortData, n, len(r.b))
+		return nil
+	}
+	out := r.b[:n]
+	r.b = r.b[n:]
+	return out
+}
+
+func (r *reader) uint64() uint64 {
+	if b := r.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *reader) uint32() uint32 {
+	if b := r.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *reader) string() string {
+	n := r.uint32()
+	return string(r.take(int(n))) // take checks n against what's left, a bogus length can't allocate 4 GB
+}
+
+// ReadUser decodes one user from the front of b and returns the rest
+func ReadUser(b []byte) (User, []byte, error) {
+	r := &reader{b: b}
+	u := User{ID: int64(r.uint64()), Name: r.string(), Email: r.string()}
+	if a := r.take(1); a != nil {
+		u.Active = a[0] == 1
+		if a[0] > 1 { // only 0 and 1 are written, anything else is damage
+			r.err = fmt.Errorf("active is %d, not 0 or 1", a[0])
+		}
+	}
+	sec := int64(r.uint64())
+	nsec := r.uint32()
+	if nsec >= 1e9 {
+		r.err = fmt.Errorf("%d nanoseconds is more than a second", nsec)
+	}
+	if r.err != nil {
+		return User{}, nil, fmt.Errorf("read user: %w", r.err)
+	}
+	u.CreatedAt = time.Unix(sec, int64(nsec)).UTC()
+	return u, r.b, nil
+}
+
+func EncodeBinary(users []User) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(users)))
+	for _, u := range users {
+		b = AppendUser(b, u)
+	}
+	return b
+}
+
+func DecodeBinary(b []byte) ([]User, error) {
+	r := &reader{b: b}
+	n := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+	// each user takes at least 29 bytes, don't trust a count the data can't hold
+	if int(n) > len(r.b)/29 {
+		return nil, fmt.Errorf("%w: %d users can't fit in %d bytes", ErrShortData, n, len(r.b))
+	}
+	users := make([]User, 0, n)
+	rest := r.b
+	for range n {
+		var u User
+		var err error
+		u, rest, err = ReadUser(rest)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	// bytes left over mean a wrong count or a wrong length somewhere: the users may be garbage
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d bytes after the last user", len(rest))
+	}
+	return users, nil
+}
+
+// ----------------------------------------------------------------------------
+// The other two, for the comparison
+
+func EncodeJSON(users []User) ([]byte, error) { return json.Marshal(users) }
+
+func DecodeJSON(b []byte) ([]User, error) {
+	var users []User
+	err := json.Unmarshal(b, &users)
+	return users, err
+}
+
+func EncodeGob(users []User) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(users)
+	return buf.Bytes(), err
+}
+
+func DecodeGob(b []byte) ([]User, error) {
+	var users []User
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&users)
+	return users, err
+}
+
+type codec struct {
+	name   string
+	encode func([]User) ([]byte, error)
+	decode func([]byte) ([]User, error)
+}
+
+var codecs = []codec{
+	{"json", EncodeJSON, DecodeJSON},
+	{"gob", EncodeGob, DecodeGob},
+	{"binary", func(u []User) ([]byte, error) { return EncodeBinary(u), nil }, DecodeBinary},
+}
+
+func roundTrip(c codec, users []User) error {
+	data, err := c.encode(users)
+	if err != nil {
+		return fmt.Errorf("encode: %w", err)
+	}
+	got, err := c.decode(data)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+	if len(got) != len(users) {
+		return fmt.Errorf("got %d users, want %d", len(got), len(users))
+	}
+	for i := range users {
+		if !users[i].equal(got[i]) {
+			return fmt.Errorf("user %d: got %+v, want %+v", i, got[i], users[i])
+		}
+	}
+	return nil
+}
+
+func makeUsers(n int) []User {
+	rng := rand.New(rand.NewPCG(1, 2))
+	names := []string{"Rishabh", "Sanchay", "Alice", "Bob", "Maya", "Omar", "Priya", "Leo"}
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]User, n)
+	for i := range users {
+		name := names[rng.IntN(len(names))]
+		users[i] = User{
+			ID:        int64(i + 1),
+			Name:      name,
+			Email:     fmt.Sprintf("%s%d@example.com", name, i),
+			Active:    rng.IntN(4) > 0,
+			CreatedAt: start.Add(time.Duration(rng.Int64N(int64(5 * 365 * 24 * time.Hour)))),
+		}
+	}
+	return users
+}
+
+// timeRuns calls fn runs times and returns the time and allocations per call. Rough numbers
+// for the table in main, go test main.go main_test.go -bench . -benchmem measures properly.
+func timeRuns(runs int, fn func()) (time.Duration, uint64) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for range runs {
+		fn()
+	}
+	perOp := time.Since(start) / time.Duration(runs)
+	runtime.ReadMemStats(&after)
+	return perOp, (after.Mallocs - before.Mallocs) / uint64(runs)
+}
+
+func main() {
+	fmt.Println("Learning binary encodings in Go")
+
+	edge := []User{
+		{}, // zero values everywhere, including the zero time
+		{ID: math.MaxInt64, Name: "max", CreatedAt: time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)},
+		{ID: math.MinInt64, Name: "min", CreatedAt: time.Unix(-1, 1).UTC()},
+		{ID: 3, Name: "रिषभ 🦫", Email: "ü@例え.jp", Active: true, CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 5, time.UTC)},
+		{ID: 4, Name: "quote\" back\\slash \x00 nul", Email: "\n"},
+	}
+	fmt.Println("Round trips over edge cases:")
+	for _, c := range codecs {
+		fmt.Printf("  %-6s %v\n", c.name, roundTrip(c, edge))
+	}
+
+	one := EncodeBinary(edge[3:4])
+	fmt.Printf("One user in binary (%d bytes): % x\n", len(one), one[:24])
+	_, err := DecodeBinary(one[:len(one)-3])
+	fmt.Println("Cut off:", err, "| is ErrShortData:", errors.Is(err, ErrShortData))
+	_, err = DecodeBinary([]byte{0xff, 0xff, 0xff, 0xff})
+	fmt.Println("A count of 4 billion:", err)
+
+	users := makeUsers(10_000)
+	fmt.Printf("%d users:\n", len(users))
+	fmt.Printf("  %-7s %10s %12s %12s %10s\n", "codec", "bytes", "encode", "decode", "allocs/op")
+	for _, c := range codecs {
+		data, _ := c.encode(users)
+		enc, _ := timeRuns(20, func() { c.encode(users) })
+		dec, allocs := timeRuns(20, func() { c.decode(data) })
+		fmt.Printf("  %-7s %10d %12s %12s %10d\n", c.name, len(data),
+			enc.Round(time.Microsecond), dec.Round(time.Microsecond), allocs)
+	}
+
+	// gob describes the type at the start of every stream: one value alone pays for it
+	single, _ := EncodeGob(users[:1])
+	fmt.Printf("gob for 1 user: %d bytes (binary: %d), the type description dominates\n", len(single), len(EncodeBinary(users[:1])))
+}
+
+// JSON for APIs and anything a human or another language reads, it's big but everyone speaks it.
+// gob between Go programs: compact after the first value, since it describes the types once per stream.
+// Hand-written encoding/binary is the fastest, but fixed widths aren't the smallest (gob's varints are).
+// Length-prefixed strings: check every length against the data left before allocating anything.
+// Round-trip edge cases (zero values, max ints, unicode, times) for every codec, that's where they differ.
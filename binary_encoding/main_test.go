@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var edgeUsers = []User{
+	{}, // zero values everywhere, including the zero time
+	{ID: math.MaxInt64, Name: "max", CreatedAt: time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)},
+	{ID: math.MinInt64, Name: "min", CreatedAt: time.Unix(-1, 1).UTC()},
+	{ID: -1, Name: "", Email: "", Active: true, CreatedAt: time.Date(1, 1, 1, 0, 0, 0, 1, time.UTC)},
+	{ID: 3, Name: "रिषभ 🦫", Email: "ü@例え.jp", Active: true, CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 5, time.UTC)},
+	{ID: 4, Name: "quote\" back\\slash \x00 nul", Email: "\n"},
+	{ID: 5, Name: string(bytes.Repeat([]byte("long "), 10_000))},
+	{ID: 6, CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.FixedZone("IST", 5*3600+1800))}, // only the instant survives
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			for _, users := range [][]User{nil, edgeUsers, makeUsers(10_000)} {
+				if err := roundTrip(c, users); err != nil {
+					t.Errorf("%d users: %v", len(users), err)
+				}
+			}
+		})
+	}
+}
+
+// the layout in the comment above AppendUser, byte for byte
+func TestBinaryLayout(t *testing.T) {
+	u := User{ID: 258, Name: "Ri", Email: "é", Active: true, CreatedAt: time.Unix(1, 2)}
+	want := "" +
+		"0000000000000102" + // id
+		"00000002" + "5269" + // name
+		"00000002" + "c3a9" + // email, UTF-8
+		"01" + // active
+		"0000000000000001" + "00000002" // seconds, nanoseconds
+	if got := hex.EncodeToString(AppendUser(nil, u)); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestDecodeBinaryErrors(t *testing.T) {
+	good := EncodeBinary(edgeUsers[:5])
+	// every cut is ErrShortData, never a panic or a shorter list
+	for cut := range len(good) {
+		if _, err := DecodeBinary(good[:cut]); !errors.Is(err, ErrShortData) {
+			t.Fatalf("cut at %d: %v", cut, err)
+		}
+	}
+	one := EncodeBinary(edgeUsers[4:5])
+	withCount := func(n uint32, b []byte) []byte {
+		b = bytes.Clone(b)
+		b[0], b[1], b[2], b[3] = byte(n>>24), byte(n>>16), byte(n>>8), byte(n)
+		return b
+	}
+	set := func(at int, v ...byte) []byte {
+		b := bytes.Clone(one)
+		copy(b[at:], v)
+		return b
+	}
+	nameLen := 4 + 8
+	tests := []struct {
+		name  string
+		data  []byte
+		short bool
+	}{
+		{"count of 4 billion", withCount(math.MaxUint32, one), true},
+		{"count too high", withCount(2, one), true},
+		{"count too low", withCount(0, one), false},
+		{"trailing bytes", append(bytes.Clone(one), 0), false},
+		{"name length past the end", set(nameLen, 0x7f, 0xff, 0xff, 0xff), true},
+		{"name length too short", set(nameLen+3, 3), true}, // the email length is read from the name
+		{"active 2", set(len(one)-13, 2), false},
+		{"nanoseconds over a second", set(len(one)-4, 0x3b, 0x9a, 0xca, 0x00), false}, // exactly 1e9
+	}
+	for _, tt := range tests {
+		users, err := DecodeBinary(tt.data)
+		if err == nil || users != nil || errors.Is(err, ErrShortData) != tt.short {
+			t.Errorf("%s: %v %v", tt.name, users, err)
+		}
+	}
+}
+
+// ReadUser leaves the rest for the next value, the way a record reader uses it
+func TestReadUserRest(t *testing.T) {
+	b := AppendUser(nil, edgeUsers[4])
+	b = append(b, "next"...)
+	u, rest, err := ReadUser(b)
+	if err != nil || !u.equal(edgeUsers[4]) || string(rest) != "next" {
+		t.Errorf("%+v %q %v", u, rest, err)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	users := makeUsers(10_000)
+	size := map[string]int{}
+	for _, c := range codecs {
+		data, err := c.encode(users)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size[c.name] = len(data)
+	}
+	// fixed widths lose to gob's varints, both beat JSON by far
+	if !(size["gob"] < size["binary"] && size["binary"] < size["json"]/2) {
+		t.Errorf("sizes %v", size)
+	}
+}
+
+// whatever decodes cleanly is exactly what the encoder would write: nothing is skipped or
+// read loosely, so a damaged length or flag can't pass for a different user
+func FuzzDecodeBinary(f *testing.F) {
+	f.Add(EncodeBinary(edgeUsers[:5]))
+	f.Add(EncodeBinary(nil))
+	f.Add([]byte{0, 0, 0, 1, 0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		users, err := DecodeBinary(data)
+		if err != nil {
+			return
+		}
+		if again := EncodeBinary(users); !bytes.Equal(again, data) {
+			t.Errorf("%x decodes to %+v, which encodes as %x", data, users, again)
+		}
+	})
+}
+
+func benchmarkCodecs(b *testing.B, run func(b *testing.B, c codec, users []User, data []byte)) {
+	users := makeUsers(10_000)
+	for _, c := range codecs {
+		data, _ := c.encode(users)
+		b.Run(c.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ReportMetric(float64(len(data)), "bytes")
+			run(b, c, users, data)
+		})
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	benchmarkCodecs(b, func(b *testing.B, c codec, users []User, _ []byte) {
+		for b.Loop() {
+			c.encode(users)
+		}
+	})
+}
+
+func BenchmarkDecode(b *testing.B) {
+	benchmarkCodecs(b, func(b *testing.B, c codec, _ []User, data []byte) {
+		for b.Loop() {
+			c.decode(data)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Permission flags: each one is a single bit, so they can be combined in one uint8
+const (
+	Read   uint8 = 1 << iota // 0001
+	Write                    // 0010
+	Delete                   // 0100
+	Admin                    // 1000
+)
+
+// permissionNames keeps the flags in a fixed order for printing and parsing
+var permissionNames = []struct {
+	flag uint8
+	name string
+}{
+	{Read, "read"},
+	{Write, "write"},
+	{Delete, "delete"},
+	{Admin, "admin"},
+}
+
+// HasPermission reports whether every bit of flag is set in mask
+func HasPermission(mask, flag uint8) bool {
+	return mask&flag == flag
+}
+
+// GrantPermission turns the flag bits on (OR)
+func GrantPermission(mask, flag uint8) uint8 {
+	return mask | flag
+}
+
+// RevokePermission turns the flag bits off (AND NOT)
+func RevokePermission(mask, flag uint8) uint8 {
+	return mask &^ flag
+}
+
+// PermissionsString renders a mask like "read|write", or "none"
+func PermissionsString(mask uint8) string {
+	var names []string
+	for _, p := range permissionNames {
+		if HasPermission(mask, p.flag) {
+			names = append(names, p.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// AdminUser stores permissions as a list of names, which is easy to read in JSON
+// but slow to check and easy to misspell. The mask form is the compact alternative.
+type AdminUser struct {
+	Name        string
+	Permissions []string
+}
+
+// PermissionsToMask converts the []string form into a bitmask, rejecting unknown names
+func PermissionsToMask(perms []string) (uint8, error) {
+	var mask uint8
+	for _, perm := range perms {
+		found := false
+		for _, p := range permissionNames {
+			if strings.EqualFold(perm, p.name) {
+				mask = GrantPermission(mask, p.flag)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown permission %q", perm)
+		}
+	}
+	return mask, nil
+}
+
+// MaskToPermissions converts a bitmask back into the []string form
+func MaskToPermissions(mask uint8) []string {
+	perms := []string{}
+	for _, p := range permissionNames {
+		if HasPermission(mask, p.flag) {
+			perms = append(perms, p.name)
+		}
+	}
+	return perms
+}
+
+func main() {
+	fmt.Println("Learning bit manipulation in Go")
+
+	// Basic operators
+	a, b := uint8(0b1100), uint8(0b1010)
+	fmt.Printf("a      = %04b\n", a)
+	fmt.Printf("b      = %04b\n", b)
+	fmt.Printf("a & b  = %04b (AND: both bits set)\n", a&b)
+	fmt.Printf("a | b  = %04b (OR: either bit set)\n", a|b)
+	fmt.Printf("a ^ b  = %04b (XOR: bits differ)\n", a^b)
+	fmt.Printf("a &^ b = %04b (AND NOT: clear b's bits from a)\n", a&^b)
+
+	// Shifts: << multiplies by 2, >> divides by 2
+	fmt.Println("1 << 3 =", 1<<3, "| 40 >> 2 =", 40>>2)
+	fmt.Println("bits.OnesCount8(0b1011) =", bits.OnesCount8(0b1011))
+
+	// Permission flags built with iota
+	fmt.Printf("Read=%04b Write=%04b Delete=%04b Admin=%04b\n", Read, Write, Delete, Admin)
+
+	var mask uint8
+	mask = GrantPermission(mask, Read|Write)
+	fmt.Printf("After granting read+write: %04b %s\n", mask, PermissionsString(mask))
+	fmt.Println("Can write?", HasPermission(mask, Write), "| Can delete?", HasPermission(mask, Delete))
+	mask = GrantPermission(mask, Delete)
+	mask = RevokePermission(mask, Write)
+	fmt.Printf("After granting delete, revoking write: %04b %s\n", mask, PermissionsString(mask))
+	fmt.Println("Number of permissions:", bits.OnesCount8(mask))
+
+	// Every combination of the four flags (2^4 = 16)
+	fmt.Println("All combinations:")
+	for m := uint8(0); m < 16; m++ {
+		fmt.Printf("  %04b = %s\n", m, PermissionsString(m))
+	}
+
+	// Converting between the []string form and the bitmask
+	admin := AdminUser{Name: "Rishabh", Permissions: []string{"read", "Delete", "admin"}}
+	adminMask, err := PermissionsToMask(admin.Permissions)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("%s: %v -> %04b -> %v\n", admin.Name, admin.Permissions, adminMask, MaskToPermissions(adminMask))
+	if _, err := PermissionsToMask([]string{"read", "superuser"}); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// Flags with 1 << iota give every constant its own bit
+// mask | flag  -> turn on    mask &^ flag -> turn off
+// mask & flag  -> check      mask ^ flag  -> toggle
+// A uint8 fits 8 flags, use uint16/32/64 for more
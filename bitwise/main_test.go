@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/bits"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const allPermissions = Read | Write | Delete | Admin
+
+// every combination of the four flags, 0000 to 1111
+func TestAllFlagCombinations(t *testing.T) {
+	flags := []uint8{Read, Write, Delete, Admin}
+	for mask := uint8(0); mask <= allPermissions; mask++ {
+		for _, f := range flags {
+			want := mask&f != 0
+			if got := HasPermission(mask, f); got != want {
+				t.Errorf("HasPermission(%04b, %04b) = %v, want %v", mask, f, got, want)
+			}
+			if granted := GrantPermission(mask, f); !HasPermission(granted, f) || granted&^f != mask&^f {
+				t.Errorf("GrantPermission(%04b, %04b) = %04b", mask, f, granted)
+			}
+			if revoked := RevokePermission(mask, f); HasPermission(revoked, f) || revoked != mask&^f {
+				t.Errorf("RevokePermission(%04b, %04b) = %04b", mask, f, revoked)
+			}
+		}
+		if HasPermission(mask, Read|Write) != (mask&Read != 0 && mask&Write != 0) {
+			t.Errorf("HasPermission(%04b, read|write) must need both bits", mask)
+		}
+
+		str := PermissionsString(mask)
+		if mask == 0 {
+			if str != "none" {
+				t.Errorf("PermissionsString(0) = %q, want none", str)
+			}
+			continue
+		}
+		if n := strings.Count(str, "|") + 1; n != bits.OnesCount8(mask) {
+			t.Errorf("PermissionsString(%04b) = %q, %d names for %d bits", mask, str, n, bits.OnesCount8(mask))
+		}
+	}
+}
+
+func TestPermissionsString(t *testing.T) {
+	tests := []struct {
+		mask uint8
+		want string
+	}{
+		{0, "none"},
+		{Read, "read"},
+		{Read | Write, "read|write"},
+		{Admin | Read, "read|admin"},
+		{allPermissions, "read|write|delete|admin"},
+		{allPermissions | 0b1_0000, "read|write|delete|admin"}, // unknown bits are ignored
+	}
+	for _, tt := range tests {
+		if got := PermissionsString(tt.mask); got != tt.want {
+			t.Errorf("PermissionsString(%04b) = %q, want %q", tt.mask, got, tt.want)
+		}
+	}
+}
+
+func TestMaskRoundTrip(t *testing.T) {
+	for mask := uint8(0); mask <= allPermissions; mask++ {
+		perms := MaskToPermissions(mask)
+		back, err := PermissionsToMask(perms)
+		if err != nil || back != mask {
+			t.Errorf("%04b -> %v -> %04b, %v", mask, perms, back, err)
+		}
+	}
+
+	// and from the []string side: case and duplicates don't matter, order is normalized
+	mask, err := PermissionsToMask([]string{"Admin", "read", "READ"})
+	if err != nil || mask != Admin|Read {
+		t.Fatalf("PermissionsToMask = %04b, %v", mask, err)
+	}
+	if got := MaskToPermissions(mask); !slices.Equal(got, []string{"read", "admin"}) {
+		t.Errorf("MaskToPermissions = %v", got)
+	}
+	if got := MaskToPermissions(0); got == nil || len(got) != 0 {
+		t.Errorf("MaskToPermissions(0) = %#v, want an empty non-nil slice", got)
+	}
+
+	if _, err := PermissionsToMask([]string{"read", "sudo"}); err == nil || !strings.Contains(err.Error(), `"sudo"`) {
+		t.Errorf("unknown permission: error = %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Retries, timeouts and circuit breakers only prove themselves when things go wrong, and
+// in a demo nothing goes wrong. A chaos middleware makes it go wrong on purpose: slow
+// answers, 500s and dropped connections, on chosen paths, switched on and off at runtime.
+
+type ChaosConfig struct {
+	Enabled      bool     `json:"enabled"`
+	Paths        []string `json:"paths"` // path prefixes, empty means every path
+	MinLatencyMS int      `json:"min_latency_ms"`
+	MaxLatencyMS int      `json:"max_latency_ms"`
+	ErrorRate    float64  `json:"error_rate"` // 0..1, answered with one of ErrorCodes
+	ErrorCodes   []int    `json:"error_codes"`
+	DropRate     float64  `json:"drop_rate"` // 0..1, the connection is closed without an answer
+	Seed         uint64   `json:"seed"`      // same seed, same sequence of decisions
+}
+
+// chaosState is never changed after it's stored: a config change builds a new one and swaps
+// the pointer, so a request sees either the whole old config or the whole new one.
+type chaosState struct {
+	cfg ChaosConfig
+	mu  sync.Mutex // rand.Rand is not safe for concurrent use
+	rng *rand.Rand
+}
+
+type Chaos struct {
+	state atomic.Pointer[chaosState]
+}
+
+func (c *Chaos) Set(cfg ChaosConfig) {
+	c.state.Store(&chaosState{cfg: cfg, rng: rand.New(rand.NewPCG(cfg.Seed, cfg.Seed))})
+}
+
+func (c *Chaos) Config() ChaosConfig {
+	if st := c.state.Load(); st != nil {
+		return st.cfg
+	}
+	return ChaosConfig{}
+}
+
+type decision struct {
+	latency time.Duration
+	drop    bool
+	status  int // 0: pass the request on
+}
+
+// decide draws every random number for one request under one lock, in a fixed order,
+// so a sequential run with the same seed makes the same decisions
+func (st *chaosState) decide() decision {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	var d decision
+	if span := st.cfg.MaxLatencyMS - st.cfg.MinLatencyMS; st.cfg.MaxLatencyMS > 0 {
+		d.latency = time.Duration(st.cfg.MinLatencyMS+st.rng.IntN(max(span, 0)+1)) * time.Millisecond
+	}
+	roll := st.rng.Float64()
+	switch {
+	case roll < st.cfg.DropRate:
+		d.drop = true
+	case roll < st.cfg.DropRate+st.cfg.ErrorRate && len(st.cfg.ErrorCodes) > 0:
+		d.status = st.cfg.ErrorCodes[st.rng.IntN(len(st.cfg.ErrorCodes))]
+	}
+	return d
+}
+
+func (st *chaosState) matches(path string) bool {
+	if len(st.cfg.Paths) == 0 {
+		return true
+	}
+	for _, p := range st.cfg.Paths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware costs one atomic load while chaos is off
+func (c *Chaos) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := c.state.Load()
+		if st == nil || !st.cfg.Enabled || !st.matches(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d := st.decide()
+		if d.latency > 0 {
+			select {
+			case <-time.After(d.latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if d.drop {
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close() // the client sees EOF or "connection reset", no status at all
+					return
+				}
+			}
+			panic(http.ErrAbortHandler) // the server aborts the response without logging a stack
+		}
+		if d.status != 0 {
+			http.Error(w, "chaos: injected failure", d.status)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleAdmin: GET shows the config, PUT replaces it. X-Role stands in for real auth.
+func (c *Chaos) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Role") != "admin" {
+		http.Error(w, "admins only", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodPut {
+		var cfg ChaosConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil ||
+			cfg.ErrorRate < 0 || cfg.DropRate < 0 || cfg.ErrorRate+cfg.DropRate > 1 || cfg.MinLatencyMS > cfg.MaxLatencyMS {
+			http.Error(w, "invalid chaos config", http.StatusBadRequest)
+			return
+		}
+		c.Set(cfg)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Config())
+}
+
+// ----------------------------------------------------------------------------
+// A client that copes: retries network errors and 5xx
+
+func getWithRetry(client *http.Client, url string, attempts int) (status, tries int, err error) {
+	for tries = 1; ; tries++ {
+		resp, err := client.Get(url)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode < 500 {
+				return resp.StatusCode, tries, nil
+			}
+			status = resp.StatusCode
+		}
+		if tries == attempts {
+			return status, tries, err
+		}
+		time.Sleep(time.Duration(tries) * 2 * time.Millisecond)
+	}
+}
+
+// hammer sends n requests from 8 goroutines and returns the share that failed
+func hammer(client *http.Client, url string, n int) float64 {
+	var failed atomic.Int64
+	var wg sync.WaitGroup
+	per := n / 8
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range per {
+				resp, err := client.Get(url)
+				if err != nil {
+					failed.Add(1)
+					continue
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode >= 500 {
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return float64(failed.Load()) / float64(per*8)
+}
+
+func main() {
+	fmt.Println("Learning chaos injection middleware in Go")
+
+	chaos := &Chaos{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, `["rishabh","sanchay"]`) })
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, "ok") })
+	mux.HandleFunc("/api/admin/chaos", chaos.handleAdmin)
+	srv := httptest.NewServer(chaos.Middleware(mux))
+	defer srv.Close()
+	// Without keep-alives: on a reused connection the Transport quietly resends a GET that got
+	// no answer at all, which would hide most of the dropped connections from this demo
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	put := func(role, body string) {
+		req, _ := http.NewRequest("PUT", srv.URL+"/api/admin/chaos", strings.NewReader(body))
+		req.Header.Set("X-Role", role)
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(resp.Body)
+		fmt.Printf("PUT /api/admin/chaos role=%s -> %d %s", role, resp.StatusCode, text)
+	}
+	put("user", `{"enabled":true}`)
+	put("admin", `{"enabled":true,"error_rate":0.8,"drop_rate":0.5}`)
+	put("admin", `{"enabled":true,"paths":["/api/users"],"error_rate":0.3,"error_codes":[500,503],"drop_rate":0.1,"seed":42}`)
+
+	// the same seed gives the same outcomes, one request at a time
+	outcomes := func() string {
+		var sb strings.Builder
+		for range 20 {
+			resp, err := client.Get(srv.URL + "/api/users")
+			switch {
+			case err != nil:
+				sb.WriteString("x")
+			case resp.StatusCode == 200:
+				sb.WriteString(".")
+			default:
+				sb.WriteString(fmt.Sprint(resp.StatusCode / 100))
+			}
+			if err == nil {
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		}
+		return sb.String()
+	}
+	first := outcomes()
+	chaos.Set(chaos.Config()) // same config, fresh RNG from the same seed
+	fmt.Printf("Outcomes (. ok, 5 error, x dropped): %s\n", first)
+	fmt.Println("Same seed again gives the same run:", first == outcomes())
+	resp, _ := client.Get(srv.URL + "/healthz")
+	fmt.Println("/healthz isn't in Paths:", resp.StatusCode)
+	resp.Body.Close()
+
+	// retries turn 40% failures into a few
+	fmt.Println("100 calls, plain vs with 3 attempts:")
+	plainFailed, retriedFailed, totalTries := 0, 0, 0
+	for range 100 {
+		if st, _, err := getWithRetry(client, srv.URL+"/api/users", 1); err != nil || st >= 500 {
+			plainFailed++
+		}
+		st, tries, err := getWithRetry(client, srv.URL+"/api/users", 3)
+		if err != nil || st >= 500 {
+			retriedFailed++
+		}
+		totalTries += tries
+	}
+	fmt.Printf("  plain: %d failed | with retries: %d failed, %.2f tries per call\n", plainFailed, retriedFailed, float64(totalTries)/100)
+
+	// flip the config while traffic is running: every request sees a whole config, and the
+	// observed error ratio follows the configured one
+	fmt.Println("Changing the config under load (8 goroutines):")
+	for _, rate := range []float64{0, 0.5, 0.2} {
+		chaos.Set(ChaosConfig{Enabled: true, ErrorRate: rate, ErrorCodes: []int{503}, Seed: 7})
+		got := hammer(client, srv.URL+"/api/users", 2000)
+		fmt.Printf("  error_rate %.2f -> observed %.3f, within 0.05: %v\n", rate, got, got > rate-0.05 && got < rate+0.05)
+	}
+
+	// what does it cost when it's off? A rough timing, BenchmarkMiddleware measures it properly
+	chaos.Set(ChaosConfig{})
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	nsPerOp := func(h http.Handler) int64 {
+		const runs = 1_000_000
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		w := httptest.NewRecorder()
+		start := time.Now()
+		for range runs {
+			h.ServeHTTP(w, req)
+		}
+		return time.Since(start).Nanoseconds() / runs
+	}
+	bare, wrapped := nsPerOp(hello), nsPerOp(chaos.Middleware(hello))
+	fmt.Printf("Disabled overhead: bare %d ns/op, with middleware %d ns/op\n", bare, wrapped)
+}
+
+// Switch chaos on at runtime, on chosen paths only, so a demo can break /api/users while /healthz stays up.
+// Keep the config in an atomic.Pointer and replace it whole: no request ever sees half of a change.
+// Draw from a seeded RNG, the same seed replays the same failures when a demo needs repeating.
+// A dropped connection (Hijack + Close) tests different client code than a 500, inject both.
+// When disabled it's one atomic load per request, cheap enough to leave in the binary.
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprint(w, "ok") })
+
+func serve(h http.Handler, path string) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	return rec.Code
+}
+
+func TestDecideIsReproducible(t *testing.T) {
+	cfg := ChaosConfig{Enabled: true, MinLatencyMS: 5, MaxLatencyMS: 50, ErrorRate: 0.3, ErrorCodes: []int{500, 503}, DropRate: 0.1, Seed: 42}
+	run := func() []decision {
+		var c Chaos
+		c.Set(cfg)
+		st := c.state.Load()
+		out := make([]decision, 200)
+		for i := range out {
+			out[i] = st.decide()
+		}
+		return out
+	}
+	first := run()
+	if !slices.Equal(first, run()) {
+		t.Fatal("the same seed made different decisions")
+	}
+	cfg.Seed = 43
+	if slices.Equal(first, run()) {
+		t.Error("another seed made the same 200 decisions")
+	}
+	kinds := map[string]int{}
+	for _, d := range first {
+		if d.latency < 5*time.Millisecond || d.latency > 50*time.Millisecond {
+			t.Errorf("latency %v outside 5..50ms", d.latency)
+		}
+		switch {
+		case d.drop:
+			kinds["drop"]++
+		case d.status != 0:
+			if d.status != 500 && d.status != 503 {
+				t.Errorf("status %d", d.status)
+			}
+			kinds["error"]++
+		default:
+			kinds["pass"]++
+		}
+	}
+	if len(kinds) != 3 {
+		t.Errorf("200 decisions, kinds %v", kinds)
+	}
+}
+
+func TestDecideEdges(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ChaosConfig
+		want decision
+	}{
+		{"nothing", ChaosConfig{}, decision{}},
+		{"fixed latency", ChaosConfig{MinLatencyMS: 7, MaxLatencyMS: 7}, decision{latency: 7 * time.Millisecond}},
+		{"always drop", ChaosConfig{DropRate: 1, ErrorRate: 0, ErrorCodes: []int{500}}, decision{drop: true}},
+		{"always fail", ChaosConfig{ErrorRate: 1, ErrorCodes: []int{418}}, decision{status: 418}},
+		{"errors without codes pass", ChaosConfig{ErrorRate: 1}, decision{}},
+	}
+	for _, tt := range tests {
+		var c Chaos
+		c.Set(tt.cfg)
+		for range 20 {
+			if got := c.state.Load().decide(); got != tt.want {
+				t.Fatalf("%s: %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestPaths(t *testing.T) {
+	var c Chaos
+	h := c.Middleware(ok)
+	if code := serve(h, "/api/users"); code != 200 {
+		t.Errorf("never configured: %d", code)
+	}
+	c.Set(ChaosConfig{Enabled: false, ErrorRate: 1, ErrorCodes: []int{500}})
+	if code := serve(h, "/api/users"); code != 200 {
+		t.Errorf("disabled: %d", code)
+	}
+	c.Set(ChaosConfig{Enabled: true, Paths: []string{"/api/users", "/api/orders/"}, ErrorRate: 1, ErrorCodes: []int{500}})
+	for path, want := range map[string]int{
+		"/api/users":       500,
+		"/api/users/7":     500,
+		"/api/orders/1":    500,
+		"/api/orders":      200,
+		"/healthz":         200,
+		"/api/admin/chaos": 200,
+		"/":                200,
+	} {
+		if code := serve(h, path); code != want {
+			t.Errorf("%s: %d, want %d", path, code, want)
+		}
+	}
+	c.Set(ChaosConfig{Enabled: true, ErrorRate: 1, ErrorCodes: []int{503}})
+	if code := serve(h, "/healthz"); code != 503 {
+		t.Errorf("no paths means every path: %d", code)
+	}
+}
+
+// the error ratio follows the configured rate, whichever goroutine draws
+func TestErrorRatio(t *testing.T) {
+	var c Chaos
+	h := c.Middleware(ok)
+	const n = 4000
+	for _, rate := range []float64{0, 0.1, 0.5, 0.9, 1} {
+		c.Set(ChaosConfig{Enabled: true, ErrorRate: rate, ErrorCodes: []int{500, 503}, Seed: 7})
+		var failed atomic.Int64
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Go(func() {
+				for range n / 8 {
+					if serve(h, "/api/users") >= 500 {
+						failed.Add(1)
+					}
+				}
+			})
+		}
+		wg.Wait()
+		if got := float64(failed.Load()) / n; got < rate-0.03 || got > rate+0.03 {
+			t.Errorf("error_rate %.2f: observed %.3f", rate, got)
+		}
+	}
+}
+
+// configs flip while traffic runs: each phase shows its own ratio and its own codes
+func TestFlipUnderTraffic(t *testing.T) {
+	var c Chaos
+	srv := httptest.NewServer(c.Middleware(ok))
+	defer srv.Close()
+	client := srv.Client()
+
+	phases := []ChaosConfig{
+		{Enabled: true, ErrorRate: 0.2, ErrorCodes: []int{500}, Seed: 1},
+		{Enabled: true, ErrorRate: 0.6, ErrorCodes: []int{503}, Seed: 2},
+		{Enabled: false, ErrorRate: 1, ErrorCodes: []int{502}},
+	}
+	var phase atomic.Int64
+	c.Set(phases[0])
+	counts := make([]map[int]int, len(phases)) // per phase: status -> count
+	for i := range counts {
+		counts[i] = map[int]int{}
+	}
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				before := phase.Load()
+				resp, err := client.Get(srv.URL + "/api/users")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				if after := phase.Load(); after == before {
+					mu.Lock()
+					counts[before][resp.StatusCode]++
+					mu.Unlock()
+				}
+			}
+		})
+	}
+	for i := range phases {
+		if i > 0 {
+			c.Set(phases[i])
+			phase.Store(int64(i))
+		}
+		for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(time.Millisecond) {
+			mu.Lock()
+			total := 0
+			for _, n := range counts[i] {
+				total += n
+			}
+			mu.Unlock()
+			if total >= 1500 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("phase %d: only %d requests", i, total)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	for i, cfg := range phases {
+		total, failed := 0, 0
+		for status, n := range counts[i] {
+			total += n
+			if status != 200 {
+				failed += n
+				// a phase boundary may let a request of the previous phase through, but never a 502,
+				// and never a code that belongs to no config
+				if status == 502 || !slices.ContainsFunc(phases[:i+1], func(p ChaosConfig) bool { return slices.Contains(p.ErrorCodes, status) }) {
+					t.Errorf("phase %d: status %d", i, status)
+				}
+			}
+		}
+		want := cfg.ErrorRate
+		if !cfg.Enabled {
+			want = 0
+		}
+		if got := float64(failed) / float64(total); got < want-0.05 || got > want+0.05 {
+			t.Errorf("phase %d: error ratio %.3f over %d requests, want %.2f", i, got, total, want)
+		}
+	}
+}
+
+// a rate of 1 with one config's codes and 0 with the other's: a request that mixed the two
+// would answer 503, which only a partial read can produce
+func TestNoPartialConfig(t *testing.T) {
+	var c Chaos
+	h := c.Middleware(ok)
+	a := ChaosConfig{Enabled: true, ErrorRate: 1, ErrorCodes: []int{500}}
+	b := ChaosConfig{Enabled: true, ErrorRate: 0, ErrorCodes: []int{503}}
+	c.Set(a)
+	stop := make(chan struct{})
+	var flipper, readers sync.WaitGroup
+	flipper.Go(func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				c.Set(b)
+			} else {
+				c.Set(a)
+			}
+		}
+	})
+	for range 4 {
+		readers.Go(func() {
+			for range 2000 {
+				if code := serve(h, "/"); code != 200 && code != 500 {
+					t.Errorf("status %d", code)
+					return
+				}
+			}
+		})
+	}
+	readers.Wait()
+	close(stop)
+	flipper.Wait()
+}
+
+func TestLatency(t *testing.T) {
+	var c Chaos
+	var called atomic.Bool
+	h := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called.Store(true) }))
+	c.Set(ChaosConfig{Enabled: true, MinLatencyMS: 30, MaxLatencyMS: 30})
+	start := time.Now()
+	if code := serve(h, "/"); code != 200 || time.Since(start) < 30*time.Millisecond || !called.Load() {
+		t.Errorf("%d after %v", code, time.Since(start))
+	}
+
+	// a client that gives up stops the wait, and the handler never runs
+	called.Store(false)
+	c.Set(ChaosConfig{Enabled: true, MinLatencyMS: 5000, MaxLatencyMS: 5000})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if time.Since(start) > time.Second || called.Load() {
+		t.Errorf("cancelled wait took %v, handler called: %v", time.Since(start), called.Load())
+	}
+}
+
+func TestDrop(t *testing.T) {
+	var c Chaos
+	c.Set(ChaosConfig{Enabled: true, DropRate: 1})
+	srv := httptest.NewServer(c.Middleware(ok))
+	defer srv.Close()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("dropped connection answered %d", resp.StatusCode)
+	}
+
+	// no Hijacker (a recorder, HTTP/2): the handler aborts instead
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler", r)
+		}
+	}()
+	c.Middleware(ok).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	t.Error("no panic without a Hijacker")
+}
+
+func TestAdmin(t *testing.T) {
+	var c Chaos
+	do := func(method, role, body string) (int, string) {
+		req := httptest.NewRequest(method, "/api/admin/chaos", strings.NewReader(body))
+		req.Header.Set("X-Role", role)
+		rec := httptest.NewRecorder()
+		c.handleAdmin(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	if code, _ := do("PUT", "user", `{"enabled":true}`); code != http.StatusForbidden || c.Config().Enabled {
+		t.Errorf("user PUT: %d", code)
+	}
+	for _, body := range []string{
+		`{"error_rate":-0.1}`,
+		`{"drop_rate":-1}`,
+		`{"error_rate":0.8,"drop_rate":0.5}`,
+		`{"min_latency_ms":10,"max_latency_ms":5}`,
+		`{oops`,
+	} {
+		if code, _ := do("PUT", "admin", body); code != http.StatusBadRequest {
+			t.Errorf("%s: %d", body, code)
+		}
+	}
+	want := ChaosConfig{Enabled: true, Paths: []string{"/api"}, MaxLatencyMS: 5, ErrorRate: 0.5, ErrorCodes: []int{503}, DropRate: 0.5, Seed: 9}
+	body, _ := json.Marshal(want)
+	if code, _ := do("PUT", "admin", string(body)); code != 200 {
+		t.Fatalf("valid PUT: %d", code)
+	}
+	code, text := do("GET", "admin", "")
+	var got ChaosConfig
+	json.Unmarshal([]byte(text), &got)
+	if code != 200 || !slices.Equal(got.Paths, want.Paths) || got.Seed != 9 || got.ErrorRate != 0.5 {
+		t.Errorf("GET: %d %s", code, text)
+	}
+}
+
+func TestGetWithRetry(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	if status, tries, err := getWithRetry(srv.Client(), srv.URL, 5); status != 200 || tries != 3 || err != nil {
+		t.Errorf("got %d after %d tries, %v", status, tries, err)
+	}
+	calls.Store(-100)
+	if status, tries, err := getWithRetry(srv.Client(), srv.URL, 2); status != 503 || tries != 2 || err != nil {
+		t.Errorf("gave up with %d after %d tries, %v", status, tries, err)
+	}
+}
+
+// go test main.go main_test.go -bench Middleware -benchmem
+// "disabled" should be within a few ns of "bare": one atomic load and a nil check
+func BenchmarkMiddleware(b *testing.B) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	run := func(b *testing.B, h http.Handler) {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		w := httptest.NewRecorder()
+		for b.Loop() {
+			h.ServeHTTP(w, req)
+		}
+	}
+	b.Run("bare", func(b *testing.B) { run(b, hello) })
+	b.Run("never_set", func(b *testing.B) { run(b, (&Chaos{}).Middleware(hello)) })
+	b.Run("disabled", func(b *testing.B) {
+		c := &Chaos{}
+		c.Set(ChaosConfig{})
+		run(b, c.Middleware(hello))
+	})
+	b.Run("other_path", func(b *testing.B) {
+		c := &Chaos{}
+		c.Set(ChaosConfig{Enabled: true, Paths: []string{"/api/orders"}, ErrorRate: 1, ErrorCodes: []int{500}})
+		run(b, c.Middleware(hello))
+	})
+	b.Run("enabled_pass", func(b *testing.B) {
+		c := &Chaos{}
+		c.Set(ChaosConfig{Enabled: true})
+		run(b, c.Middleware(hello))
+	})
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A circuit breaker sits in front of a call to another service.
+// When that service keeps failing, it stops calling it for a while ("opens") and fails fast,
+// so we don't pile up slow requests or hammer a service that is trying to recover.
+//
+//	Closed   --(Threshold failures in a row)-->       Open
+//	Open     --(OpenFor has passed)-->                HalfOpen
+//	HalfOpen --(HalfOpenProbes successes)-->          Closed
+//	HalfOpen --(any failure)-->                       Open
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// ErrCircuitOpen is returned without calling fn while the breaker is open,
+// or when all half-open probe slots are taken
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type CircuitBreaker struct {
+	Threshold      int           // failures in a row that open the breaker
+	OpenFor        time.Duration // how long to fail fast before probing again
+	HalfOpenProbes int           // calls allowed (and successes needed) in half-open
+	OnStateChange  func(from, to State)
+	Now            func() time.Time // a fake clock can be set in demos and tests
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	inFlight int // probes running in half-open
+	passed   int // probes that succeeded in half-open
+	// generation changes with every state change, a call only counts in the generation it
+	// started in: a slow probe from an earlier half-open must not count in the current one
+	generation uint64
+}
+
+// NewCircuitBreaker returns a closed breaker
+func NewCircuitBreaker(threshold int, openFor time.Duration, halfOpenProbes int) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, OpenFor: openFor, HalfOpenProbes: halfOpenProbes, Now: time.Now}
+}
+
+// State returns the current state, moving from open to half-open if OpenFor has passed
+func (cb *CircuitBreaker) State() State {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.refreshLocked()
+	return cb.state
+}
+
+func (cb *CircuitBreaker) refreshLocked() {
+	if cb.state == Open && cb.Now().Sub(cb.openedAt) >= cb.OpenFor {
+		cb.setStateLocked(HalfOpen)
+	}
+}
+
+func (cb *CircuitBreaker) setStateLocked(to State) {
+	from := cb.state
+	if from == to {
+		return
+	}
+	cb.state = to
+	cb.generation++
+	cb.failures, cb.inFlight, cb.passed = 0, 0, 0
+	if to == Open {
+		cb.openedAt = cb.Now()
+	}
+	if cb.OnStateChange != nil {
+		// called with the lock held so callbacks arrive in order: it must not call the breaker
+		cb.OnStateChange(from, to)
+	}
+}
+
+// Execute calls fn unless the breaker is open. fn's error counts as a failure,
+// except when ctx was cancelled: our caller giving up says nothing about the other service.
+func (cb *CircuitBreaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	cb.mu.Lock()
+	cb.refreshLocked()
+	switch cb.state {
+	case Open:
+		cb.mu.Unlock()
+		return ErrCircuitOpen
+	case HalfOpen:
+		if cb.inFlight+cb.passed >= cb.HalfOpenProbes {
+			cb.mu.Unlock()
+			return fmt.Errorf("%w (half-open, %d probes already running)", ErrCircuitOpen, cb.inFlight)
+		}
+		cb.inFlight++
+	}
+	generation := cb.generation
+	cb.mu.Unlock()
+
+	defer func() {
+		// a panicking fn is a failure too, and in half-open it must give its probe slot back
+		if v := recover(); v != nil {
+			cb.record(generation, true)
+			panic(v)
+		}
+	}()
+	err := fn(ctx)
+	cb.record(generation, err != nil && ctx.Err() == nil)
+	return err
+}
+
+// record counts the result of a call started in generation
+func (cb *CircuitBreaker) record(generation uint64, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.generation != generation {
+		// the state changed while fn ran, maybe several times and back to the same state:
+		// this result is old news, and its probe slot was freed by the change
+		return
+	}
+	switch cb.state {
+	case Closed:
+		if !failed {
+			cb.failures = 0
+		} else if cb.failures++; cb.failures >= cb.Threshold {
+			cb.setStateLocked(Open)
+		}
+	case HalfOpen:
+		cb.inFlight--
+		if failed {
+			cb.setStateLocked(Open) // still broken: wait a full OpenFor again
+		} else if cb.passed++; cb.passed >= cb.HalfOpenProbes {
+			cb.setStateLocked(Closed)
+		}
+	}
+}
+
+// fakeClock is a time the demo moves by hand
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+var errUnavailable = errors.New("503 service unavailable")
+
+// dependency simulates a service that can be switched between healthy and down
+type dependency struct {
+	mu    sync.Mutex
+	down  bool
+	calls int
+}
+
+func (d *dependency) call(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	if d.down {
+		return errUnavailable
+	}
+	return nil
+}
+
+func (d *dependency) setDown(down bool) {
+	d.mu.Lock()
+	d.down = down
+	d.mu.Unlock()
+}
+
+// getWithRetry is the retry loop of an HTTP client: it retries failures with backoff,
+// but gives up at once when the breaker is open, retrying would only wait for nothing
+func getWithRetry(ctx context.Context, cb *CircuitBreaker, fn func(context.Context) error, attempts int) (int, error) {
+	var err error
+	for i := 1; i <= attempts; i++ {
+		err = cb.Execute(ctx, fn)
+		if err == nil || errors.Is(err, ErrCircuitOpen) {
+			return i, err
+		}
+		time.Sleep(time.Duration(i) * time.Millisecond) // real code: exponential backoff with jitter
+	}
+	return attempts, fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+func main() {
+	fmt.Println("Learning the circuit breaker pattern in Go")
+
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cb := NewCircuitBreaker(3, 10*time.Second, 2)
+	cb.Now = clock.Now
+	cb.OnStateChange = func(from, to State) {
+		fmt.Printf("  [%s] breaker %s -> %s\n", clock.Now().Format("15:04:05"), from, to)
+	}
+	dep := &dependency{}
+	ctx := context.Background()
+
+	fmt.Println("1. The dependency goes down")
+	dep.setDown(true)
+	for i := 1; i <= 5; i++ {
+		err := cb.Execute(ctx, dep.call)
+		fmt.Printf("  call %d: %v\n", i, err)
+	}
+	fmt.Println("  the dependency was called", dep.calls, "times, not 5")
+
+	fmt.Println("2. A retrying client stops at once while open")
+	tries, err := getWithRetry(ctx, cb, dep.call, 4)
+	fmt.Printf("  tries %d: %v\n", tries, err)
+
+	fmt.Println("3. After OpenFor, a failing probe opens it again")
+	clock.Advance(10 * time.Second)
+	fmt.Println("  state:", cb.State())
+	fmt.Println("  probe:", cb.Execute(ctx, dep.call))
+	fmt.Println("  right after:", cb.Execute(ctx, dep.call))
+
+	fmt.Println("4. Half-open lets only HalfOpenProbes calls through at once")
+	clock.Advance(10 * time.Second)
+	dep.setDown(false)
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	slowProbe := func(ctx context.Context) error {
+		started <- struct{}{}
+		<-release // hold the probe slot until all calls were attempted
+		return dep.call(ctx)
+	}
+	var wg sync.WaitGroup
+	results := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- cb.Execute(ctx, slowProbe)
+		}()
+	}
+	<-started
+	<-started // both probe slots are taken
+	for i := 0; i < 3; i++ {
+		fmt.Println("  rejected:", <-results) // the other 3 fail fast
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+	for err := range results {
+		fmt.Println("  probe result:", err)
+	}
+	fmt.Println("  state:", cb.State())
+
+	fmt.Println("5. Closed again: a single failure doesn't open it")
+	dep.setDown(true)
+	fmt.Println("  ", cb.Execute(ctx, dep.call), "| state:", cb.State())
+	dep.setDown(false)
+	fmt.Println("  ", cb.Execute(ctx, dep.call), "| state:", cb.State(), "(success resets the count)")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	fmt.Println("Cancelled context:", cb.Execute(cancelled, dep.call), "| state:", cb.State())
+}
+
+// Closed: calls go through, count failures in a row. Open: fail fast with ErrCircuitOpen.
+// HalfOpen: let a few probes through; all succeed -> Closed, any fails -> Open again.
+// Don't retry ErrCircuitOpen, and don't count the caller's own cancellation as a failure.
+// Make the clock injectable, then every transition can be checked without sleeping.
+// In real projects see github.com/sony/gobreaker.
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func fail(context.Context) error { return errBoom }
+func ok(context.Context) error   { return nil }
+
+// newTestBreaker returns a breaker on a fake clock that logs its transitions
+func newTestBreaker(threshold, probes int) (*CircuitBreaker, *fakeClock, *[]string) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cb := NewCircuitBreaker(threshold, 10*time.Second, probes)
+	cb.Now = clock.Now
+	var changes []string
+	cb.OnStateChange = func(from, to State) { changes = append(changes, fmt.Sprintf("%s->%s", from, to)) }
+	return cb, clock, &changes
+}
+
+func TestOpensAfterThreshold(t *testing.T) {
+	cb, _, changes := newTestBreaker(3, 1)
+	ctx := context.Background()
+	for range 2 {
+		cb.Execute(ctx, fail)
+	}
+	cb.Execute(ctx, ok) // a success resets the count
+	for range 2 {
+		cb.Execute(ctx, fail)
+	}
+	if cb.State() != Closed {
+		t.Fatal("opened without 3 failures in a row")
+	}
+	cb.Execute(ctx, fail)
+	if cb.State() != Open {
+		t.Fatal("not open after 3 failures in a row")
+	}
+	called := false
+	err := cb.Execute(ctx, func(context.Context) error { called = true; return nil })
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Errorf("open: %v, fn called %v", err, called)
+	}
+	if want := []string{"closed->open"}; !slices.Equal(*changes, want) {
+		t.Errorf("changes %v", *changes)
+	}
+}
+
+func TestHalfOpenTransitions(t *testing.T) {
+	cb, clock, changes := newTestBreaker(1, 2)
+	ctx := context.Background()
+	cb.Execute(ctx, fail)
+
+	clock.Advance(9 * time.Second)
+	if cb.State() != Open {
+		t.Fatal("half-open before OpenFor")
+	}
+	clock.Advance(time.Second)
+	if cb.State() != HalfOpen {
+		t.Fatal("not half-open after OpenFor")
+	}
+	// a failed probe opens it again for a full OpenFor
+	if err := cb.Execute(ctx, fail); !errors.Is(err, errBoom) || cb.State() != Open {
+		t.Fatalf("failed probe: %v, %v", err, cb.State())
+	}
+	clock.Advance(5 * time.Second)
+	if cb.State() != Open {
+		t.Fatal("the failed probe didn't restart OpenFor")
+	}
+	clock.Advance(5 * time.Second)
+	// HalfOpenProbes successes close it
+	cb.Execute(ctx, ok)
+	if cb.State() != HalfOpen {
+		t.Fatal("closed after one of two probes")
+	}
+	cb.Execute(ctx, ok)
+	if cb.State() != Closed {
+		t.Fatal("not closed after two probes")
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !slices.Equal(*changes, want) {
+		t.Errorf("changes\n got %v\nwant %v", *changes, want)
+	}
+}
+
+func TestHalfOpenLimitsConcurrentProbes(t *testing.T) {
+	cb, clock, _ := newTestBreaker(1, 2)
+	ctx := context.Background()
+	cb.Execute(ctx, fail)
+	clock.Advance(10 * time.Second)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 20)
+	probe := func(context.Context) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+	var wg sync.WaitGroup
+	results := make(chan error, 20)
+	for range 20 {
+		wg.Go(func() { results <- cb.Execute(ctx, probe) })
+	}
+	<-started
+	<-started
+	rejected := 0
+	for range 18 {
+		if err := <-results; errors.Is(err, ErrCircuitOpen) {
+			rejected++
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+	for err := range results {
+		if err != nil {
+			t.Errorf("probe: %v", err)
+		}
+	}
+	if rejected != 18 || len(started) != 0 || cb.State() != Closed {
+		t.Errorf("rejected %d, extra probes %d, state %v", rejected, len(started), cb.State())
+	}
+}
+
+// a probe slower than OpenFor finishes in the next half-open: its result must not count there
+func TestSlowProbeFromAnEarlierHalfOpen(t *testing.T) {
+	cb, clock, changes := newTestBreaker(1, 2)
+	ctx := context.Background()
+	cb.Execute(ctx, fail)
+	clock.Advance(10 * time.Second)
+
+	slowProbe := func(release chan error) chan error {
+		started, done := make(chan struct{}), make(chan error, 1)
+		go func() {
+			done <- cb.Execute(ctx, func(context.Context) error {
+				close(started)
+				return <-release
+			})
+		}()
+		<-started
+		return done
+	}
+	releaseOld := make(chan error)
+	oldDone := slowProbe(releaseOld) // first half-open
+	cb.Execute(ctx, fail)            // the other probe fails: open again
+	clock.Advance(10 * time.Second)
+	if cb.State() != HalfOpen {
+		t.Fatalf("state %v, want half-open", cb.State())
+	}
+	releaseCurrent := make(chan error)
+	currentDone := slowProbe(releaseCurrent) // second half-open
+
+	releaseOld <- nil // the old probe succeeds now
+	<-oldDone
+	cb.mu.Lock()
+	inFlight, passed := cb.inFlight, cb.passed
+	cb.mu.Unlock()
+	if inFlight != 1 || passed != 0 {
+		t.Errorf("after the old probe: %d in flight, %d passed; want 1 and 0", inFlight, passed)
+	}
+	// with the old success ignored, one more success isn't enough to close
+	if err := cb.Execute(ctx, ok); err != nil || cb.State() != HalfOpen {
+		t.Fatalf("second probe: %v, state %v; want half-open", err, cb.State())
+	}
+	releaseCurrent <- nil
+	<-currentDone
+	if cb.State() != Closed {
+		t.Errorf("state %v after two current successes", cb.State())
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !slices.Equal(*changes, want) {
+		t.Errorf("changes %v", *changes)
+	}
+}
+
+// a call started while closed that fails after the breaker went round to closed again
+// belongs to the old closed period
+func TestSlowCallFromAnEarlierClosed(t *testing.T) {
+	cb, clock, _ := newTestBreaker(2, 1)
+	ctx := context.Background()
+	release := make(chan error)
+	done := make(chan error, 1)
+	started := make(chan struct{})
+	go func() {
+		done <- cb.Execute(ctx, func(context.Context) error {
+			close(started)
+			return <-release
+		})
+	}()
+	<-started
+	cb.Execute(ctx, fail)
+	cb.Execute(ctx, fail) // open
+	clock.Advance(10 * time.Second)
+	cb.Execute(ctx, ok) // closed again
+	cb.Execute(ctx, fail)
+
+	release <- errBoom
+	<-done
+	if cb.State() != Closed {
+		t.Errorf("an old failure counted towards the new threshold: %v", cb.State())
+	}
+}
+
+func TestHalfOpenPanicFreesTheSlot(t *testing.T) {
+	cb, clock, _ := newTestBreaker(1, 1)
+	ctx := context.Background()
+	cb.Execute(ctx, fail)
+	clock.Advance(10 * time.Second)
+
+	func() {
+		defer func() {
+			if v := recover(); v != "probe exploded" {
+				t.Errorf("recovered %v, want the panic to go on up", v)
+			}
+		}()
+		cb.Execute(ctx, func(context.Context) error { panic("probe exploded") })
+	}()
+	// the panic counted as a failed probe: open again, and after OpenFor the slot is free
+	if cb.State() != Open {
+		t.Fatalf("state after a panicking probe: %v", cb.State())
+	}
+	clock.Advance(10 * time.Second)
+	if err := cb.Execute(ctx, ok); err != nil || cb.State() != Closed {
+		t.Errorf("probe after the panic: %v, %v", err, cb.State())
+	}
+}
+
+func TestClosedPanicCountsAsFailure(t *testing.T) {
+	cb, _, _ := newTestBreaker(2, 1)
+	for range 2 {
+		func() {
+			defer func() { recover() }()
+			cb.Execute(context.Background(), func(context.Context) error { panic("bug") })
+		}()
+	}
+	if cb.State() != Open {
+		t.Errorf("two panics in a row: %v", cb.State())
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	cb, _, _ := newTestBreaker(1, 1)
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cb.Execute(cancelled, ok); !errors.Is(err, context.Canceled) {
+		t.Errorf("Execute with a cancelled ctx: %v", err)
+	}
+	// fn fails because our ctx was cancelled while it ran: not the dependency's fault
+	ctx, cancel := context.WithCancel(context.Background())
+	err := cb.Execute(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) || cb.State() != Closed {
+		t.Errorf("%v, state %v", err, cb.State())
+	}
+}
+
+func TestGetWithRetry(t *testing.T) {
+	cb, _, _ := newTestBreaker(2, 1)
+	ctx := context.Background()
+	calls := 0
+	counted := func(ctx context.Context) error { calls++; return errBoom }
+	tries, err := getWithRetry(ctx, cb, counted, 5)
+	// the breaker opens after 2 failures, the third try fails fast and stops the loop
+	if tries != 3 || calls != 2 || !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("tries %d, calls %d, %v", tries, calls, err)
+	}
+	cb2, _, _ := newTestBreaker(10, 1)
+	if tries, err := getWithRetry(ctx, cb2, fail, 3); tries != 3 || !errors.Is(err, errBoom) {
+		t.Errorf("without the breaker opening: %d, %v", tries, err)
+	}
+}
+
+func TestStateString(t *testing.T) {
+	for s, want := range map[State]string{Closed: "closed", Open: "open", HalfOpen: "half-open", 7: "State(7)"} {
+		if s.String() != want {
+			t.Errorf("%d: %q", int(s), s.String())
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrQueueClosed     = errors.New("queue is closed")
+	ErrInvalidCapacity = errors.New("capacity must be at least 1")
+)
+
+// BoundedQueue is a FIFO queue with a maximum size: Put waits while it's full, Take waits while it's empty.
+// sync.Cond lets a goroutine sleep until another one says "something changed" (Broadcast).
+type BoundedQueue[T any] struct {
+	mu       sync.Mutex
+	changed  *sync.Cond // signalled whenever an item is added or removed, or the queue is closed
+	items    []T        // ring buffer
+	head     int        // index of the oldest item
+	count    int
+	closed   bool
+	capacity int
+}
+
+func NewBoundedQueue[T any](capacity int) (*BoundedQueue[T], error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("new bounded queue with capacity %d: %w", capacity, ErrInvalidCapacity)
+	}
+	q := &BoundedQueue[T]{items: make([]T, capacity), capacity: capacity}
+	q.changed = sync.NewCond(&q.mu) // the Cond uses our mutex
+	return q, nil
+}
+
+// Put adds v at the end, waiting while the queue is full.
+// It fails with ErrQueueClosed if the queue is (or gets) closed.
+func (q *BoundedQueue[T]) Put(v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	// always wait in a loop: after waking up the condition may already be false again
+	for q.count == q.capacity && !q.closed {
+		q.changed.Wait() // unlocks mu while sleeping, locks it again before returning
+	}
+	if q.closed {
+		return ErrQueueClosed
+	}
+	q.items[(q.head+q.count)%q.capacity] = v
+	q.count++
+	q.changed.Broadcast()
+	return nil
+}
+
+// Take removes and returns the oldest item, waiting while the queue is empty.
+// After Close the remaining items are still returned, then ErrQueueClosed.
+func (q *BoundedQueue[T]) Take() (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.count == 0 && !q.closed {
+		q.changed.Wait()
+	}
+	var zero T
+	if q.count == 0 { // closed and drained
+		return zero, ErrQueueClosed
+	}
+	v := q.items[q.head]
+	q.items[q.head] = zero // don't keep a reference to the item for the garbage collector
+	q.head = (q.head + 1) % q.capacity
+	q.count--
+	q.changed.Broadcast()
+	return v, nil
+}
+
+// Close wakes every waiting goroutine: blocked Puts fail, blocked Takes drain then fail
+func (q *BoundedQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.changed.Broadcast()
+}
+
+func (q *BoundedQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+func main() {
+	fmt.Println("Learning sync.Cond with a bounded queue")
+
+	if _, err := NewBoundedQueue[int](0); err != nil {
+		fmt.Println("Zero capacity:", err)
+	}
+
+	// 1. 4 producers, 3 consumers, every item must arrive exactly once
+	q, _ := NewBoundedQueue[int](5)
+	const producers, perProducer = 4, 2500
+	var producersWG, consumersWG sync.WaitGroup
+	var sum, taken atomic.Int64
+	for p := 0; p < producers; p++ {
+		producersWG.Add(1)
+		go func(p int) {
+			defer producersWG.Done()
+			for i := 1; i <= perProducer; i++ {
+				q.Put(p*perProducer + i)
+			}
+		}(p)
+	}
+	for c := 0; c < 3; c++ {
+		consumersWG.Add(1)
+		go func() {
+			defer consumersWG.Done()
+			for {
+				v, err := q.Take()
+				if errors.Is(err, ErrQueueClosed) {
+					return
+				}
+				sum.Add(int64(v))
+				taken.Add(1)
+			}
+		}()
+	}
+	producersWG.Wait()
+	q.Close() // no more items: consumers drain what's left and stop
+	consumersWG.Wait()
+	n := int64(producers * perProducer)
+	fmt.Printf("Items taken: %d of %d, sum correct: %v\n", taken.Load(), n, sum.Load() == n*(n+1)/2)
+
+	// 2. Close while goroutines are blocked
+	full, _ := NewBoundedQueue[string](1)
+	full.Put("only item")
+	putResult := make(chan error)
+	go func() { putResult <- full.Put("waits, queue is full") }()
+
+	empty, _ := NewBoundedQueue[string](1)
+	takeResult := make(chan error)
+	go func() {
+		_, err := empty.Take()
+		takeResult <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond) // give both goroutines time to block
+	full.Close()
+	empty.Close()
+	fmt.Println("Blocked Put after Close:", <-putResult)
+	fmt.Println("Blocked Take after Close:", <-takeResult)
+	v, err := full.Take()
+	fmt.Printf("Take drains after Close: %q %v\n", v, err)
+	_, err = full.Take()
+	fmt.Println("Then:", err)
+
+	// 3. the same producer/consumer with a buffered channel
+	ch := make(chan int, 5)
+	go func() {
+		for i := 1; i <= 10; i++ {
+			ch <- i
+		}
+		close(ch)
+	}()
+	total := 0
+	for v := range ch {
+		total += v
+	}
+	fmt.Println("Buffered channel sum:", total)
+}
+
+// A buffered channel IS a bounded queue and is usually the better choice in Go:
+// it works with select (timeouts, cancellation) and range, and needs no locks.
+// sync.Cond is useful when the "condition" is more than "is there an item":
+//   several conditions on the same data, waiting on state a channel can't express,
+//   or sending on a closed channel would panic but you want an error (like Put here).
+// Always call Wait inside a for loop that re-checks the condition, wake-ups can be spurious or stale.
+// Broadcast wakes all waiters, Signal wakes one. Broadcast is simpler to get right.
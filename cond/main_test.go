@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewBoundedQueueRejectsCapacity(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		if q, err := NewBoundedQueue[int](capacity); q != nil || !errors.Is(err, ErrInvalidCapacity) {
+			t.Errorf("capacity %d: %v, %v", capacity, q, err)
+		}
+	}
+}
+
+func TestFIFOAndWrapAround(t *testing.T) {
+	q, _ := NewBoundedQueue[int](3)
+	next := 0
+	for round := range 5 { // pushes the head around the ring buffer several times
+		for range 2 + round%2 {
+			q.Put(next)
+			next++
+		}
+		for q.Len() > 0 {
+			want := next - q.Len()
+			if v, err := q.Take(); err != nil || v != want {
+				t.Fatalf("Take() = %d, %v; want %d", v, err, want)
+			}
+		}
+	}
+}
+
+// run with -race: every item put is taken exactly once
+func TestProducersConsumersExact(t *testing.T) {
+	q, _ := NewBoundedQueue[int](4)
+	const producers, perProducer, consumers = 6, 1000, 5
+	var producersWG, consumersWG sync.WaitGroup
+	seen := make([]atomic.Int32, producers*perProducer)
+	for p := range producers {
+		producersWG.Go(func() {
+			for i := range perProducer {
+				if err := q.Put(p*perProducer + i); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	for range consumers {
+		consumersWG.Go(func() {
+			for {
+				v, err := q.Take()
+				if errors.Is(err, ErrQueueClosed) {
+					return
+				}
+				seen[v].Add(1)
+			}
+		})
+	}
+	producersWG.Wait()
+	q.Close()
+	consumersWG.Wait()
+	for v := range seen {
+		if n := seen[v].Load(); n != 1 {
+			t.Fatalf("item %d taken %d times", v, n)
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("%d items left", q.Len())
+	}
+}
+
+// waitBlocked waits until n more goroutines than before exist and have had time to park
+func waitBlocked(t *testing.T, before, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() < before+n {
+		if time.Now().After(deadline) {
+			t.Fatal("goroutines did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestCloseWakesBlockedGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	full, _ := NewBoundedQueue[string](1)
+	full.Put("kept")
+	empty, _ := NewBoundedQueue[string](1)
+
+	putErrs, takeErrs := make(chan error, 3), make(chan error, 3)
+	for range 3 {
+		go func() { putErrs <- full.Put("blocked") }()
+		go func() {
+			_, err := empty.Take()
+			takeErrs <- err
+		}()
+	}
+	waitBlocked(t, before, 6)
+	full.Close()
+	empty.Close()
+	for range 3 {
+		for _, ch := range []chan error{putErrs, takeErrs} {
+			select {
+			case err := <-ch:
+				if !errors.Is(err, ErrQueueClosed) {
+					t.Errorf("err = %v, want ErrQueueClosed", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Close left a goroutine blocked")
+			}
+		}
+	}
+
+	// Take drains what was there before Close, then reports closed; Put fails at once
+	if v, err := full.Take(); v != "kept" || err != nil {
+		t.Errorf("drain: %q, %v", v, err)
+	}
+	if _, err := full.Take(); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("after the drain: %v", err)
+	}
+	if err := full.Put("late"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Put after Close: %v", err)
+	}
+}
+
+func BenchmarkBoundedQueue(b *testing.B) {
+	q, _ := NewBoundedQueue[int](64)
+	go func() {
+		for {
+			if _, err := q.Take(); err != nil {
+				return
+			}
+		}
+	}()
+	for b.Loop() {
+		q.Put(1)
+	}
+	q.Close()
+}
+
+func BenchmarkBufferedChannel(b *testing.B) {
+	ch := make(chan int, 64)
+	go func() {
+		for range ch {
+		}
+	}()
+	for b.Loop() {
+		ch <- 1
+	}
+	close(ch)
+}
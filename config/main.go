@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvOr returns the environment variable or fallback when it is unset or empty
+func EnvOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && strings.TrimSpace(value) != "" {
+		return value
+	}
+	return fallback
+}
+
+// EnvInt reads an integer environment variable.
+// Unset or empty -> fallback, malformed -> error naming the variable and the bad value.
+func EnvInt(key string, fallback int) (int, error) {
+	raw := EnvOr(key, "")
+	if raw == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return fallback, fmt.Errorf("env %s=%q: expected an integer", key, raw)
+	}
+	return n, nil
+}
+
+// EnvBool accepts the same values as strconv.ParseBool: 1, t, true, 0, f, false (any case)
+func EnvBool(key string, fallback bool) (bool, error) {
+	raw := EnvOr(key, "")
+	if raw == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return fallback, fmt.Errorf("env %s=%q: expected true/false/1/0", key, raw)
+	}
+	return b, nil
+}
+
+// EnvDuration accepts Go duration strings like "500ms", "30s", "1h30m"
+func EnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	raw := EnvOr(key, "")
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return fallback, fmt.Errorf("env %s=%q: expected a duration like 30s or 1m", key, raw)
+	}
+	return d, nil
+}
+
+// stringList is a custom flag type: "-tags go,backend,api" becomes []string{"go", "backend", "api"}.
+// Anything with String() and Set(string) error satisfies flag.Value.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return fmt.Errorf("empty item in list %q", value)
+		}
+		*s = append(*s, part)
+	}
+	return nil
+}
+
+// Options is what the command line parses into
+type Options struct {
+	Name    string
+	Port    int
+	Verbose bool
+	Tags    stringList
+}
+
+// parseFlags uses its own FlagSet instead of the global flag.CommandLine,
+// so it can be called many times with made-up argument slices (great for tests)
+func parseFlags(args []string) (Options, error) {
+	var opts Options
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // we print the error ourselves
+	fs.StringVar(&opts.Name, "name", "go_learning", "application name")
+	fs.IntVar(&opts.Port, "port", 8080, "port to listen on")
+	fs.BoolVar(&opts.Verbose, "verbose", false, "print more output")
+	fs.Var(&opts.Tags, "tags", "comma separated list of tags")
+	if err := fs.Parse(args); err != nil {
+		return Options{}, fmt.Errorf("parse flags %v: %w", args, err)
+	}
+	return opts, nil
+}
+
+func main() {
+	fmt.Println("Learning environment variables and flags in Go")
+
+	// Environment variables
+	fmt.Println("HOME =", os.Getenv("HOME"))
+	fmt.Printf("Unset variable with Getenv: %q (can't tell unset from empty)\n", os.Getenv("GO_LEARNING_MISSING"))
+	if _, ok := os.LookupEnv("GO_LEARNING_MISSING"); !ok {
+		fmt.Println("LookupEnv says GO_LEARNING_MISSING is not set")
+	}
+
+	// Simulate a few variables (only for this process)
+	os.Setenv("APP_PORT", "9090")
+	os.Setenv("APP_WORKERS", "")
+	os.Setenv("APP_DEBUG", "yes")
+	os.Setenv("APP_TIMEOUT", "1m30s")
+
+	fmt.Println("APP_ENV:", EnvOr("APP_ENV", "development"))
+	port, err := EnvInt("APP_PORT", 8080)
+	fmt.Println("APP_PORT:", port, err)
+	workers, err := EnvInt("APP_WORKERS", 4) // set but empty -> fallback
+	fmt.Println("APP_WORKERS:", workers, err)
+	debug, err := EnvBool("APP_DEBUG", false) // "yes" is not a valid bool
+	fmt.Println("APP_DEBUG:", debug, "error:", err)
+	timeout, err := EnvDuration("APP_TIMEOUT", 30*time.Second)
+	fmt.Println("APP_TIMEOUT:", timeout, err)
+
+	// Flags parsed from made-up argument slices
+	argSets := [][]string{
+		{},
+		{"-name", "api", "-port=3000", "-verbose", "-tags", "go,backend"},
+		{"-tags", "go", "-tags", "api"}, // custom flag.Value can be repeated
+		{"-port", "abc"},
+		{"-unknown"},
+		{"-tags", "go,,api"},
+	}
+	for _, args := range argSets {
+		opts, err := parseFlags(args)
+		if err != nil {
+			fmt.Println("  error:", err)
+			continue
+		}
+		fmt.Printf("  %v -> %+v\n", args, opts)
+	}
+}
+
+// Priority usually is: flag > environment variable > default value
+// os.Getenv returns "" for unset variables, os.LookupEnv tells you if it was set
+// flag.Parse() uses os.Args and exits on error, a FlagSet with ContinueOnError returns the error instead
+// Custom flag types implement flag.Value: String() string and Set(string) error
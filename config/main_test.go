@@ -0,0 +1,106 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvOr(t *testing.T) {
+	t.Setenv("TEST_SET", "value")
+	t.Setenv("TEST_EMPTY", "")
+	t.Setenv("TEST_BLANK", "   ")
+	tests := []struct{ key, want string }{
+		{"TEST_SET", "value"},
+		{"TEST_EMPTY", "fallback"},
+		{"TEST_BLANK", "fallback"},
+		{"TEST_UNSET_FOR_SURE", "fallback"},
+	}
+	for _, tt := range tests {
+		if got := EnvOr(tt.key, "fallback"); got != tt.want {
+			t.Errorf("EnvOr(%s) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestEnvTyped(t *testing.T) {
+	t.Setenv("TEST_EMPTY", "")
+	t.Setenv("TEST_INT", " 42 ")
+	t.Setenv("TEST_BOOL", "TRUE")
+	t.Setenv("TEST_DURATION", "1m30s")
+	t.Setenv("TEST_BAD", "yes")
+
+	// unset and set-but-empty both give the fallback without an error
+	for _, key := range []string{"TEST_UNSET_FOR_SURE", "TEST_EMPTY"} {
+		if n, err := EnvInt(key, 7); n != 7 || err != nil {
+			t.Errorf("EnvInt(%s) = %d, %v; want 7, nil", key, n, err)
+		}
+		if b, err := EnvBool(key, true); !b || err != nil {
+			t.Errorf("EnvBool(%s) = %v, %v; want true, nil", key, b, err)
+		}
+		if d, err := EnvDuration(key, time.Second); d != time.Second || err != nil {
+			t.Errorf("EnvDuration(%s) = %v, %v; want 1s, nil", key, d, err)
+		}
+	}
+
+	if n, err := EnvInt("TEST_INT", 0); n != 42 || err != nil {
+		t.Errorf("EnvInt = %d, %v; want 42", n, err)
+	}
+	if b, err := EnvBool("TEST_BOOL", false); !b || err != nil {
+		t.Errorf("EnvBool = %v, %v; want true", b, err)
+	}
+	if d, err := EnvDuration("TEST_DURATION", 0); d != 90*time.Second || err != nil {
+		t.Errorf("EnvDuration = %v, %v; want 1m30s", d, err)
+	}
+
+	// malformed: the fallback comes back with an error naming the variable and the value
+	_, errInt := EnvInt("TEST_BAD", 1)
+	_, errBool := EnvBool("TEST_BAD", false)
+	_, errDur := EnvDuration("TEST_BAD", time.Second)
+	for _, err := range []error{errInt, errBool, errDur} {
+		if err == nil || !strings.Contains(err.Error(), `TEST_BAD="yes"`) {
+			t.Errorf("malformed value: error = %v, want it to name TEST_BAD and the value", err)
+		}
+	}
+	if n, _ := EnvInt("TEST_BAD", 1); n != 1 {
+		t.Errorf("EnvInt on a malformed value returned %d, want the fallback", n)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	opts, err := parseFlags(nil)
+	if err != nil || opts.Name != "go_learning" || opts.Port != 8080 || opts.Verbose || len(opts.Tags) != 0 {
+		t.Fatalf("defaults: %+v, %v", opts, err)
+	}
+
+	opts, err = parseFlags([]string{"-name", "api", "-port=3000", "-verbose", "-tags", "go, backend", "-tags", "api", "rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Name != "api" || opts.Port != 3000 || !opts.Verbose {
+		t.Errorf("got %+v", opts)
+	}
+	if want := []string{"go", "backend", "api"}; !slices.Equal(opts.Tags, want) {
+		t.Errorf("Tags = %v, want %v", opts.Tags, want)
+	}
+	if opts.Tags.String() != "go,backend,api" {
+		t.Errorf("Tags.String() = %q", opts.Tags.String())
+	}
+
+	bad := []struct {
+		args    []string
+		errPart string
+	}{
+		{[]string{"-port", "abc"}, "invalid value"},
+		{[]string{"-unknown"}, "not defined"},
+		{[]string{"-tags", "go,,api"}, "empty item"},
+		{[]string{"-name"}, "needs an argument"},
+		{[]string{"-verbose=maybe"}, "invalid boolean"},
+	}
+	for _, tt := range bad {
+		if _, err := parseFlags(tt.args); err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("parseFlags(%q) error = %v, want it to contain %q", tt.args, err, tt.errPart)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LoadJSONFile reads path and decodes it into a T
+func LoadJSONFile[T any](path string) (T, error) {
+	var v T
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return v, fmt.Errorf("load %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("load %s: %w", path, err)
+	}
+	return v, nil
+}
+
+// SaveJSONFileAtomic writes v to path so that readers (and crashes) only ever see
+// the old file or the complete new one, never a half-written file:
+//  1. marshal first, so a marshal error never touches the disk
+//  2. write to a temp file in the SAME directory (rename is only atomic within one filesystem)
+//  3. fsync the temp file so the data is really on disk
+//  4. rename it over the original (atomic on POSIX systems)
+//  5. fsync the directory so the rename itself survives a power cut
+func SaveJSONFileAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+
+	// keep the permissions of the existing file, default to rw-r--r--
+	perm := fs.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	tmpName := tmp.Name()
+	// if anything below fails, don't leave the temp file behind
+	success := false
+	defer func() {
+		if !success {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return fmt.Errorf("save %s: writing temp file: %w", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("save %s: syncing temp file: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("save %s: %w", path, err)
+	}
+	success = true
+
+	// syncing a directory is not supported everywhere (e.g. Windows), so it is best effort
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// Settings is what the demo remembers between runs
+type Settings struct {
+	RunCount int       `json:"run_count"`
+	LastRun  time.Time `json:"last_run"`
+	Theme    string    `json:"theme"`
+}
+
+func main() {
+	fmt.Println("Learning atomic file writes with JSON and os")
+
+	// 1. Persist settings between runs in the user's config directory
+	//    (~/.config on Linux, ~/Library/Application Support on macOS, %AppData% on Windows)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		fmt.Println("Error finding config dir:", err)
+		return
+	}
+	settingsPath := filepath.Join(configDir, "go_learning", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0o755); err != nil {
+		fmt.Println("Error creating config dir:", err)
+		return
+	}
+
+	settings, err := LoadJSONFile[Settings](settingsPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		fmt.Println("First run, no settings file yet")
+		settings = Settings{Theme: "dark"}
+	case err != nil:
+		fmt.Println("Settings file is broken, starting fresh:", err)
+		settings = Settings{Theme: "dark"}
+	default:
+		fmt.Printf("Welcome back! Runs so far: %d, last run: %s\n", settings.RunCount, settings.LastRun.Format(time.RFC1123))
+	}
+	settings.RunCount++
+	settings.LastRun = time.Now()
+	if err := SaveJSONFileAtomic(settingsPath, settings); err != nil {
+		fmt.Println("Error saving settings:", err)
+		return
+	}
+	fmt.Println("Saved settings to", settingsPath, "(run again to see the counter grow)")
+
+	// The rest of the demo works in a throwaway directory
+	dir, err := os.MkdirTemp("", "atomic-demo-*")
+	if err != nil {
+		fmt.Println("Error creating temp dir:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+
+	if err := SaveJSONFileAtomic(path, Settings{Theme: "light"}); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// 2. A crash in the middle of a save leaves a partial temp file behind.
+	//    The real file is untouched, because it is only replaced by rename.
+	partial := filepath.Join(dir, ".config.json.tmp-crashed")
+	if err := os.WriteFile(partial, []byte(`{"run_count": 4, "the`), 0o644); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	loaded, err := LoadJSONFile[Settings](path)
+	fmt.Printf("After simulated crash: theme=%q err=%v\n", loaded.Theme, err)
+
+	// 3. A value that can't be marshaled (channels have no JSON form) fails BEFORE touching the file
+	err = SaveJSONFileAtomic(path, map[string]any{"broken": make(chan int)})
+	fmt.Println("Marshal error:", err)
+	loaded, err = LoadJSONFile[Settings](path)
+	fmt.Printf("Original still intact: theme=%q err=%v\n", loaded.Theme, err)
+
+	// 4. Concurrent saves: each goroutine uses its own temp file, so the result is
+	//    always one complete version (the last rename wins), never a mix
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := SaveJSONFileAtomic(path, Settings{RunCount: n, Theme: fmt.Sprintf("theme-%d", n)}); err != nil {
+				fmt.Println("Concurrent save error:", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	loaded, err = LoadJSONFile[Settings](path)
+	fmt.Printf("After 10 concurrent saves: run_count=%d theme=%q err=%v\n", loaded.RunCount, loaded.Theme, err)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Permissions preserved:", info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Print("Files in the directory:")
+	for _, e := range entries {
+		fmt.Print(" ", e.Name())
+	}
+	fmt.Println(" (only the simulated crash leftover, no temp files from our saves)")
+}
+
+// os.WriteFile truncates the file first and then writes: a crash in between leaves an empty or partial file.
+// Write to a temp file + rename is the standard fix used by editors, databases and package managers.
+// The temp file must be in the same directory, rename across filesystems is a copy, not atomic.
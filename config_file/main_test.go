@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSaveAndLoadJSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+	if _, err := LoadJSONFile[Settings](path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing file: %v, want fs.ErrNotExist", err)
+	}
+	want := Settings{RunCount: 3, Theme: "dark"}
+	if err := SaveJSONFileAtomic(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadJSONFile[Settings](path)
+	if err != nil || got != want {
+		t.Fatalf("LoadJSONFile = %+v, %v; want %+v", got, err, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o644 {
+		t.Errorf("new file mode = %v, %v; want 0644", info.Mode().Perm(), err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"run_count":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJSONFile[Settings](path); err == nil || !strings.Contains(err.Error(), path) {
+		t.Errorf("broken file: %v, want an error naming the path", err)
+	}
+}
+
+func TestSaveJSONFileAtomicKeepsPermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveJSONFileAtomic(path, Settings{Theme: "light"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+// a crash mid-write leaves a partial temp file next to the config; the config itself is untouched
+func TestPartialTempFileLeftBehind(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	if err := SaveJSONFileAtomic(path, Settings{RunCount: 1, Theme: "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	leftover := filepath.Join(dir, ".settings.json.tmp-123")
+	if err := os.WriteFile(leftover, []byte(`{"run_count": 2, "th`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := LoadJSONFile[Settings](path); err != nil || got.RunCount != 1 {
+		t.Fatalf("with a leftover temp file: %+v, %v", got, err)
+	}
+	// the next save works and doesn't trip over the leftover
+	if err := SaveJSONFileAtomic(path, Settings{RunCount: 2, Theme: "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := LoadJSONFile[Settings](path); got.RunCount != 2 {
+		t.Fatalf("after saving again: %+v", got)
+	}
+}
+
+func TestSaveJSONFileAtomicMarshalErrorKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	if err := SaveJSONFileAtomic(path, Settings{RunCount: 7, Theme: "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	if err := SaveJSONFileAtomic(path, map[string]any{"bad": make(chan int)}); err == nil {
+		t.Fatal("marshaling a channel succeeded")
+	}
+	after, _ := os.ReadFile(path)
+	if string(after) != string(before) {
+		t.Fatalf("the original changed:\n%s\n->\n%s", before, after)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files in the directory, want only the config", len(entries))
+	}
+}
+
+func TestSaveJSONFileAtomicConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		wg.Go(func() {
+			errs <- SaveJSONFileAtomic(path, Settings{RunCount: i, Theme: fmt.Sprint("theme-", i)})
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	// whoever renamed last wins, but the file is always one complete document
+	got, err := LoadJSONFile[Settings](path)
+	if err != nil || got.Theme != fmt.Sprint("theme-", got.RunCount) {
+		t.Fatalf("after concurrent saves: %+v, %v", got, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files in the directory, temp files were left behind", len(entries))
+	}
+}
+
+func TestSaveJSONFileAtomicReadOnlyDir(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "settings.json")
+	if err := SaveJSONFileAtomic(path, Settings{RunCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+	if err := SaveJSONFileAtomic(path, Settings{RunCount: 2}); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("save into a read-only directory: %v, want fs.ErrPermission", err)
+	}
+	if got, _ := LoadJSONFile[Settings](path); got.RunCount != 1 {
+		t.Errorf("the original changed to %+v", got)
+	}
+}
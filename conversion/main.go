@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Celsius and Fahrenheit are named types: same underlying type (float64)
+// but the compiler will not let you mix them up without an explicit conversion
+type Celsius float64
+type Fahrenheit float64
+
+// package level variables so the compiler can't optimize the timed loops away
+var (
+	sink      string
+	sinkBytes []byte
+)
+
+func CToF(c Celsius) Fahrenheit {
+	return Fahrenheit(c*9/5 + 32)
+}
+
+// ToInt converts values commonly found in map[string]interface{} (decoded JSON,
+// config files) to an int. float64 is accepted only when it has no fraction,
+// so 2.5 is an error instead of silently becoming 2.
+func ToInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		if n > math.MaxInt || n < math.MinInt {
+			return 0, fmt.Errorf("to int: %d overflows int", n)
+		}
+		return int(n), nil
+	case float64:
+		if n != math.Trunc(n) || math.IsInf(n, 0) || math.IsNaN(n) {
+			return 0, fmt.Errorf("to int: %v is not a whole number", n)
+		}
+		if n >= math.MaxInt || n < math.MinInt { // float64(math.MaxInt) rounds up to 2^63
+			return 0, fmt.Errorf("to int: %v overflows int", n)
+		}
+		return int(n), nil
+	case string:
+		i, err := strconv.Atoi(n)
+		if err != nil {
+			return 0, fmt.Errorf("to int: %w", err)
+		}
+		return i, nil
+	case nil:
+		return 0, fmt.Errorf("to int: value is nil")
+	default:
+		return 0, fmt.Errorf("to int: unsupported type %T (value %v)", v, v)
+	}
+}
+
+func main() {
+	fmt.Println("Learning type conversion and type assertion in Go")
+
+	// Go never converts types implicitly, you always write T(v)
+
+	// int <-> float: float to int TRUNCATES toward zero, it does not round
+	f := 9.99
+	fmt.Println("int(9.99) =", int(f), "| int(-9.99) =", int(-f))
+	fmt.Println("math.Round first:", int(math.Round(f)))
+	count := 7
+	fmt.Println("float64(7)/2 =", float64(count)/2, "| 7/2 (integer division) =", count/2)
+
+	// int <-> string: string(65) does NOT give "65"!
+	// it interprets the number as a Unicode code point (go vet warns about this)
+	code := 65
+	fmt.Println("string(rune(65)) =", string(rune(code))) // "A"
+	fmt.Println("strconv.Itoa(65) =", strconv.Itoa(code)) // "65"
+	fmt.Println("fmt.Sprint(65) =", fmt.Sprint(code))     // "65", slower but works for any type
+
+	// []byte <-> string: each conversion COPIES the data
+	// (strings are immutable, byte slices are not, so they can't share memory safely)
+	data := []byte("hello")
+	s := string(data)
+	data[0] = 'j'
+	fmt.Println("string after changing the bytes:", s, "| bytes:", string(data))
+
+	payload := make([]byte, 64*1024)
+	const iterations = 2000
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		sink = string(payload) // copies 64KB every time
+	}
+	withCopy := time.Since(start)
+	start = time.Now()
+	for i := 0; i < iterations; i++ {
+		sinkBytes = payload // no conversion, just copies the slice header
+	}
+	withoutCopy := time.Since(start)
+	fmt.Printf("%d x string([]byte 64KB): %v vs no conversion: %v\n", iterations, withCopy, withoutCopy)
+
+	// Named types
+	boiling := Celsius(100)
+	fmt.Printf("%.0f°C = %.0f°F\n", boiling, CToF(boiling))
+	// CToF(212) works because 212 is an untyped constant,
+	// but a float64 variable needs Celsius(x)
+	raw := 37.0
+	fmt.Printf("%.1f°C = %.1f°F\n", raw, CToF(Celsius(raw)))
+
+	// Type assertion: getting the concrete value out of an interface
+	var anything interface{} = "Go"
+	str := anything.(string) // panics if anything is not a string
+	fmt.Println("asserted string:", str)
+
+	// comma-ok form never panics
+	if n, ok := anything.(int); ok {
+		fmt.Println("it is an int:", n)
+	} else {
+		fmt.Println("not an int, got zero value:", n)
+	}
+
+	// the single-value form panics on the wrong type
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Println("Recovered from panic:", r)
+			}
+		}()
+		_ = anything.(int)
+	}()
+
+	// errors vs panics: ToInt returns an error for every bad input instead of panicking
+	inputs := []interface{}{42, int64(7), 3.0, 2.5, "123", "12a", true, nil, []int{1}}
+	for _, in := range inputs {
+		n, err := ToInt(in)
+		if err != nil {
+			fmt.Println("  error:", err)
+			continue
+		}
+		fmt.Printf("  ToInt(%#v) = %d\n", in, n)
+	}
+}
+
+// T(v) converts between compatible types (numbers, named types, string/[]byte/[]rune)
+// v.(T) asserts the dynamic type inside an interface
+// v, ok := x.(T) is the safe form, the single value form panics
+// Use strconv for number <-> string, never string(int)
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestToInt(t *testing.T) {
+	ok := []struct {
+		in   interface{}
+		want int
+	}{
+		{42, 42},
+		{-7, -7},
+		{int64(1 << 40), 1 << 40},
+		{int64(math.MinInt64), math.MinInt},
+		{float64(3), 3},
+		{-2.0, -2},
+		{1e15, 1_000_000_000_000_000},
+		{"123", 123},
+		{"-5", -5},
+	}
+	for _, tt := range ok {
+		if got, err := ToInt(tt.in); err != nil || got != tt.want {
+			t.Errorf("ToInt(%#v) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	bad := []struct {
+		in      interface{}
+		errPart string
+	}{
+		{2.5, "not a whole number"},
+		{math.NaN(), "not a whole number"},
+		{math.Inf(1), "not a whole number"},
+		{math.Pow(2, 63), "overflows int"},
+		{"12abc", "invalid syntax"},
+		{"", "invalid syntax"},
+		{"99999999999999999999", "out of range"},
+		{nil, "value is nil"},
+		{true, "unsupported type bool"},
+		{int32(5), "unsupported type int32"},
+		{[]int{1}, "unsupported type []int"},
+		{map[string]interface{}{}, "unsupported type map[string]interface {}"},
+	}
+	for _, tt := range bad {
+		_, err := ToInt(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("ToInt(%#v) error = %v, want it to mention %q", tt.in, err, tt.errPart)
+		}
+	}
+}
+
+func TestCToF(t *testing.T) {
+	for _, tt := range []struct {
+		c Celsius
+		f Fahrenheit
+	}{{0, 32}, {100, 212}, {-40, -40}, {37, 98.6}} {
+		if got := CToF(tt.c); math.Abs(float64(got-tt.f)) > 1e-9 {
+			t.Errorf("CToF(%v) = %v, want %v", tt.c, got, tt.f)
+		}
+	}
+}
+
+var benchPayload = make([]byte, 64*1024)
+
+func BenchmarkBytesToString(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		sink = string(benchPayload)
+	}
+}
+
+func BenchmarkStringToBytes(b *testing.B) {
+	s := string(benchPayload)
+	b.ReportAllocs()
+	for b.Loop() {
+		sinkBytes = []byte(s)
+	}
+}
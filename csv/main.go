@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteCSV writes a header line followed by rows.
+// encoding/csv quotes fields containing commas, quotes or newlines for us.
+func WriteCSV(path string, headers []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("write csv %s: %w", path, err)
+	}
+	w := csv.NewWriter(file)
+	if err := w.Write(headers); err != nil {
+		file.Close()
+		return fmt.Errorf("write csv %s: %w", path, err)
+	}
+	for i, row := range rows {
+		if len(row) != len(headers) {
+			file.Close()
+			return fmt.Errorf("write csv %s: row %d has %d fields, want %d", path, i+1, len(row), len(headers))
+		}
+		if err := w.Write(row); err != nil {
+			file.Close()
+			return fmt.Errorf("write csv %s: %w", path, err)
+		}
+	}
+	w.Flush() // the writer is buffered: nothing reaches the file until Flush
+	if err := w.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("write csv %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write csv %s: %w", path, err)
+	}
+	return nil
+}
+
+// RowError describes a row that could not be converted.
+// Line is the line in the file where the row starts (quoted fields can span lines).
+type RowError struct {
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %q: %v", e.Line, e.Column, e.Err)
+}
+
+// dateLayouts are tried in order for time.Time fields
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// ReadCSVInto maps each row to a T using `csv:"column_name"` tags on T's fields.
+// The first row must be the header. Rows that fail to convert are reported as
+// RowErrors and skipped, the rest of the file is still read.
+// The returned error is only for problems with the whole file (unreadable, missing columns).
+func ReadCSVInto[T any](r io.Reader) ([]T, []RowError, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("read csv: %s is not a struct", t)
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // check the field count ourselves, per row
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read csv: reading header: %w", err)
+	}
+	columnIndex := make(map[string]int, len(header))
+	for i, name := range header {
+		columnIndex[strings.TrimSpace(name)] = i
+	}
+
+	// map struct field index -> column index, every tagged column must exist
+	type mapping struct {
+		field  int
+		column int
+		name   string
+	}
+	var mappings []mapping
+	var missing []string
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("csv")
+		if name == "" || name == "-" {
+			continue
+		}
+		col, ok := columnIndex[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		mappings = append(mappings, mapping{field: i, column: col, name: name})
+	}
+	if len(missing) > 0 {
+		return nil, nil, fmt.Errorf("read csv: missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	var items []T
+	var rowErrs []RowError
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line := parseErr.StartLine
+				if line == 0 {
+					line = parseErr.Line
+				}
+				rowErrs = append(rowErrs, RowError{Line: line, Err: parseErr.Err})
+				continue
+			}
+			return items, rowErrs, fmt.Errorf("read csv: %w", err)
+		}
+		// FieldPos is only valid after a successful Read, it panics when there is no record
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			rowErrs = append(rowErrs, RowError{Line: line, Err: fmt.Errorf("has %d fields, header has %d", len(record), len(header))})
+			continue
+		}
+
+		var item T
+		v := reflect.ValueOf(&item).Elem()
+		ok := true
+		for _, m := range mappings {
+			if err := setField(v.Field(m.field), record[m.column]); err != nil {
+				rowErrs = append(rowErrs, RowError{Line: line, Column: m.name, Err: err})
+				ok = false
+			}
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	return items, rowErrs, nil
+}
+
+// setField converts the text of one cell into the field's type
+func setField(field reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		if raw == "" {
+			return nil // empty cell -> zero time
+		}
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				field.Set(reflect.ValueOf(t))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid date %q", raw)
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+type User struct {
+	ID      int       `csv:"id"`
+	Name    string    `csv:"name"`
+	Email   string    `csv:"email"`
+	Age     int       `csv:"age"`
+	Active  bool      `csv:"active"`
+	Score   float64   `csv:"score"`
+	Joined  time.Time `csv:"joined"`
+	Comment string    // no tag: not read from the file
+}
+
+func main() {
+	fmt.Println("Learning CSV reading and writing in Go")
+
+	dir, err := os.MkdirTemp("", "csv-demo-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.csv")
+
+	headers := []string{"id", "name", "email", "age", "active", "score", "joined", "extra"}
+	rows := [][]string{
+		{"1", "Rishabh Gupta", "rishabh@example.com", "23", "true", "91.5", "2024-03-10", "ignored"},
+		{"2", "Gupta, Rishabh", "comma@example.com", "24", "false", "77", "2024-01-02T15:04:05Z", ""}, // comma inside a field
+		{"3", "Multi\nLine", "newline@example.com", "25", "1", "60.25", "", ""},                       // newline inside a field
+		{"4", "Sanchay \"The Gopher\" Roy", "quote@example.com", "22", "t", "88", "2023-12-31", ""},   // quotes inside a field
+		{"5", "Bad Age", "bad@example.com", "twenty", "yes", "x", "31/12/2023", ""},                   // conversion failures
+	}
+	if err := WriteCSV(path, headers, rows); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("File contents:")
+	fmt.Print(string(raw))
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer file.Close()
+
+	users, rowErrs, err := ReadCSVInto[User](file)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("\nDecoded users:")
+	for _, u := range users {
+		fmt.Printf("  %d %-28q age=%d active=%v score=%.2f joined=%s\n", u.ID, u.Name, u.Age, u.Active, u.Score, u.Joined.Format("2006-01-02"))
+	}
+	fmt.Println("Row errors:")
+	for _, e := range rowErrs {
+		fmt.Println(" ", e)
+	}
+
+	// a row with the wrong number of fields
+	ragged := "id,name,email,age,active,score,joined\n1,A,a@example.com,20,true,1,2024-01-01\n2,B\n"
+	users, rowErrs, err = ReadCSVInto[User](strings.NewReader(ragged))
+	fmt.Println("\nRagged file:", len(users), "users,", rowErrs, err)
+
+	// a file without the columns we need
+	_, _, err = ReadCSVInto[User](strings.NewReader("id,name\n1,A\n"))
+	fmt.Println("Missing columns:", err)
+}
+
+// encoding/csv handles quoting: "a,b" stays one field, "" inside quotes is a literal quote,
+// and quoted fields may contain newlines (so a "row" can span several lines).
+// csv.Writer is buffered, always call Flush and check Error.
+// Reflection (reflect package) lets one function fill any struct using its tags,
+// the same trick encoding/json uses.
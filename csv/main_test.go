@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const csvHeader = "id,name,email,age,active,score,joined\n"
+
+func TestWriteAndReadCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	headers := []string{"id", "name", "email", "age", "active", "score", "joined", "extra"}
+	rows := [][]string{
+		{"1", "Gupta, Rishabh", "comma@example.com", "24", "false", "77", "2024-01-02T15:04:05Z", "ignored"},
+		{"2", "Multi\nLine", "newline@example.com", "25", "1", "60.25", "", ""},
+		{"3", `Sanchay "The Gopher" Roy`, "quote@example.com", "22", "t", "88", "2023-12-31", ""},
+	}
+	if err := WriteCSV(path, headers, rows); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	users, rowErrs, err := ReadCSVInto[User](file)
+	if err != nil || len(rowErrs) != 0 || len(users) != 3 {
+		t.Fatalf("got %d users, %v, %v", len(users), rowErrs, err)
+	}
+	want := []User{
+		{ID: 1, Name: "Gupta, Rishabh", Email: "comma@example.com", Age: 24, Score: 77, Joined: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{ID: 2, Name: "Multi\nLine", Email: "newline@example.com", Age: 25, Active: true, Score: 60.25},
+		{ID: 3, Name: `Sanchay "The Gopher" Roy`, Email: "quote@example.com", Age: 22, Active: true, Score: 88, Joined: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for i := range want {
+		if users[i] != want[i] {
+			t.Errorf("user %d = %+v, want %+v", i, users[i], want[i])
+		}
+	}
+
+	if err := WriteCSV(path, []string{"a", "b"}, [][]string{{"1"}}); err == nil || !strings.Contains(err.Error(), "row 1 has 1 fields") {
+		t.Errorf("short row: %v", err)
+	}
+}
+
+func TestReadCSVIntoRowErrors(t *testing.T) {
+	input := csvHeader +
+		"1,A,a@example.com,20,true,1.5,2024-01-01\n" + // line 2, fine
+		"2,\"Multi\nLine\",b@example.com,twenty,yes,x,31/12/2023\n" + // lines 3-4, four bad cells
+		"3,C\n" + // line 5, too few fields
+		"4,D,d@example.com,40,false,2,2024-01-01,extra\n" + // line 6, too many
+		"5,E,e@example.com,50,false,3,\n" // line 7, empty date is the zero time
+	users, rowErrs, err := ReadCSVInto[User](strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 5 || !users[1].Joined.IsZero() {
+		t.Errorf("users = %+v", users)
+	}
+	want := []string{
+		`line 3, column "age": invalid integer "twenty"`,
+		`line 3, column "active": invalid boolean "yes"`,
+		`line 3, column "score": invalid number "x"`,
+		`line 3, column "joined": invalid date "31/12/2023"`,
+		`line 5: has 2 fields, header has 7`,
+		`line 6: has 8 fields, header has 7`,
+	}
+	if len(rowErrs) != len(want) {
+		t.Fatalf("row errors = %v, want %d", rowErrs, len(want))
+	}
+	for i, w := range want {
+		if rowErrs[i].Error() != w {
+			t.Errorf("row error %d = %q, want %q", i, rowErrs[i], w)
+		}
+	}
+}
+
+// a bare quote used to reach reader.FieldPos(0) before the error check, which panics
+func TestReadCSVIntoParseErrors(t *testing.T) {
+	input := csvHeader +
+		"1,A,a@example.com,20,true,1,2024-01-01\n" +
+		"\"x\"y,z\n" + // line 3: extraneous quote
+		"2,B\"ad,b@example.com,20,true,1,2024-01-01\n" + // line 4: bare quote
+		"3,C,c@example.com,30,true,1,2024-01-01\n"
+	users, rowErrs, err := ReadCSVInto[User](strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 3 {
+		t.Errorf("users = %+v", users)
+	}
+	if len(rowErrs) != 2 || rowErrs[0].Line != 3 || rowErrs[1].Line != 4 {
+		t.Fatalf("row errors = %v, want lines 3 and 4", rowErrs)
+	}
+	if !strings.Contains(rowErrs[0].Error(), "extraneous") || !strings.Contains(rowErrs[1].Error(), "bare") {
+		t.Errorf("row errors = %v", rowErrs)
+	}
+}
+
+func TestReadCSVIntoFileErrors(t *testing.T) {
+	_, _, err := ReadCSVInto[User](strings.NewReader("id,name\n1,A\n"))
+	if err == nil || !strings.Contains(err.Error(), "missing columns: email, age, active, score, joined") {
+		t.Errorf("missing columns: %v", err)
+	}
+	if _, _, err := ReadCSVInto[User](strings.NewReader("")); err == nil || !strings.Contains(err.Error(), "reading header") {
+		t.Errorf("empty file: %v", err)
+	}
+	if _, _, err := ReadCSVInto[string](strings.NewReader(csvHeader)); err == nil {
+		t.Error("a non-struct type was accepted")
+	}
+	// extra columns in the header are fine, and the order doesn't matter
+	users, rowErrs, err := ReadCSVInto[User](strings.NewReader("notes,joined,score,active,age,email,name,id\nhi,,1,false,3,c@x.io,C,9\n"))
+	if err != nil || len(rowErrs) != 0 || len(users) != 1 || users[0].ID != 9 || users[0].Name != "C" {
+		t.Errorf("reordered header: %+v, %v, %v", users, rowErrs, err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk on fire") }
+
+func TestReadCSVIntoReadError(t *testing.T) {
+	if _, _, err := ReadCSVInto[User](failingReader{}); err == nil || !strings.Contains(err.Error(), "disk on fire") {
+		t.Errorf("got %v", err)
+	}
+}
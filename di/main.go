@@ -0,0 +1,373 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Dependency injection: a type gets what it needs as constructor parameters instead of
+// creating it itself. Wiring by hand is usually enough in Go:
+//
+//	cfg := NewConfig(); log := NewLogger(cfg); store := NewMemoryStorage(); svc := NewUserService(log, store)
+//
+// When the graph grows, a container can do that wiring: each constructor is registered once,
+// and the container calls them in the right order by looking at the parameter types.
+// (This is what go.uber.org/dig does, google/wire does it with generated code instead.)
+
+var (
+	ErrMissingProvider = errors.New("no provider")
+	ErrCycle           = errors.New("dependency cycle")
+	ErrBadConstructor  = errors.New("bad constructor")
+)
+
+type Lifetime int
+
+const (
+	Singleton Lifetime = iota // built once, the same value is shared
+	Transient                 // built again every time it's needed
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+type provider struct {
+	ctor     reflect.Value
+	lifetime Lifetime
+
+	mu    sync.Mutex // held while a Singleton is built, so it is built only once
+	built bool
+	value reflect.Value
+}
+
+// Container's mu only guards the providers map. Constructors run without it,
+// so a constructor may use the container itself (Invoke from inside a constructor).
+type Container struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]*provider
+}
+
+func NewContainer() *Container {
+	return &Container{providers: make(map[reflect.Type]*provider)}
+}
+
+// Provide registers ctor, a function returning T or (T, error). Its parameters are
+// resolved from other providers. T is the exact return type, so a constructor that
+// returns an interface (DataStorage) provides the interface, not the concrete type.
+func (c *Container) Provide(ctor any, lifetime Lifetime) error {
+	v := reflect.ValueOf(ctor)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("provide %T: %w: not a function", ctor, ErrBadConstructor)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("provide %s: %w: must return T or (T, error)", t, ErrBadConstructor)
+	}
+	out := t.Out(0)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, dup := c.providers[out]; dup {
+		return fmt.Errorf("provide %s: %w: %s already has a provider", t, ErrBadConstructor, out)
+	}
+	c.providers[out] = &provider{ctor: v, lifetime: lifetime}
+	return nil
+}
+
+// Invoke resolves fn's parameters and calls it. If fn returns an error, Invoke returns it.
+func (c *Container) Invoke(fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fmt.Errorf("invoke %T: not a function", fn)
+	}
+	args, err := c.resolveArgs(v.Type())
+	if err != nil {
+		return fmt.Errorf("invoke %s: %w", v.Type(), err)
+	}
+	out := v.Call(args) // without the lock: fn may use the container again
+	if len(out) > 0 && out[len(out)-1].Type() == errorType && !out[len(out)-1].IsNil() {
+		return out[len(out)-1].Interface().(error)
+	}
+	return nil
+}
+
+// resolveArgs checks the whole graph below fn's parameters first, so a cycle or a missing
+// provider is reported before any constructor runs, then builds the parameters
+func (c *Container) resolveArgs(fnType reflect.Type) ([]reflect.Value, error) {
+	c.mu.Lock()
+	for i := range fnType.NumIn() {
+		if err := c.check(fnType.In(i), nil); err != nil {
+			c.mu.Unlock()
+			return nil, err
+		}
+	}
+	c.mu.Unlock()
+
+	args := make([]reflect.Value, fnType.NumIn())
+	for i := range args {
+		v, err := c.build(fnType.In(i))
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// check walks the providers below t, with c.mu held. chain holds the types on the way
+// down, so finding t in it again means a cycle.
+func (c *Container) check(t reflect.Type, chain []reflect.Type) error {
+	for _, seen := range chain {
+		if seen == t {
+			return fmt.Errorf("%w: %s", ErrCycle, formatChain(append(chain, t)))
+		}
+	}
+	p, ok := c.providers[t]
+	if !ok {
+		if len(chain) == 0 {
+			return fmt.Errorf("%w for %s", ErrMissingProvider, t)
+		}
+		return fmt.Errorf("%w for %s (needed by %s)", ErrMissingProvider, t, formatChain(chain))
+	}
+	chain = append(chain, t)
+	for i := range p.ctor.Type().NumIn() {
+		if err := c.check(p.ctor.Type().In(i), chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// build returns a value of type t, whose graph check has accepted. A Singleton holds only
+// its own provider's lock while it is built: the unlock is deferred, so a panicking
+// constructor doesn't leave it locked, and two goroutines needing it wait for one build.
+// A constructor that Invokes its own type through the container is a cycle check can't
+// see, and waits forever.
+func (c *Container) build(t reflect.Type) (reflect.Value, error) {
+	c.mu.Lock()
+	p := c.providers[t]
+	c.mu.Unlock()
+	if p.lifetime == Singleton {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.built {
+			return p.value, nil
+		}
+	}
+	ctorType := p.ctor.Type()
+	args := make([]reflect.Value, ctorType.NumIn())
+	for i := range args {
+		v, err := c.build(ctorType.In(i))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = v
+	}
+	out := p.ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("build %s: %w", t, out[1].Interface().(error))
+	}
+	if p.lifetime == Singleton {
+		p.value, p.built = out[0], true
+	}
+	return out[0], nil
+}
+
+func formatChain(chain []reflect.Type) string {
+	names := make([]string, len(chain))
+	for i, t := range chain {
+		names[i] = t.String()
+	}
+	return strings.Join(names, " -> ")
+}
+
+// ----------------------------------------------------------------------------
+// The services being wired
+
+type Config struct {
+	AppName string
+	Debug   bool
+}
+
+func NewConfig() Config { return Config{AppName: "users-api", Debug: true} }
+
+type Logger struct {
+	prefix string
+	w      io.Writer
+}
+
+func NewLogger(cfg Config) *Logger {
+	return &Logger{prefix: "[" + cfg.AppName + "] ", w: io.Discard}
+}
+
+func (l *Logger) Printf(format string, args ...any) {
+	fmt.Fprintf(l.w, l.prefix+format+"\n", args...)
+}
+
+// DataStorage is what UserService needs, any implementation can be plugged in
+type DataStorage interface {
+	Save(key, value string) error
+	Load(key string) (string, bool)
+}
+
+type MemoryStorage struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+// NewMemoryStorage returns the interface, so the container provides DataStorage
+func NewMemoryStorage() DataStorage {
+	return &MemoryStorage{data: make(map[string]string)}
+}
+
+func (m *MemoryStorage) Save(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *MemoryStorage) Load(key string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.data[key]
+	return v, ok
+}
+
+type UserService struct {
+	log   *Logger
+	store DataStorage
+}
+
+func NewUserService(log *Logger, store DataStorage) (*UserService, error) {
+	if store == nil {
+		return nil, errors.New("user service needs a storage")
+	}
+	return &UserService{log: log, store: store}, nil
+}
+
+func (s *UserService) Register(id, name string) error {
+	s.log.Printf("register %s", id)
+	return s.store.Save("user:"+id, name)
+}
+
+func (s *UserService) Name(id string) (string, bool) {
+	return s.store.Load("user:" + id)
+}
+
+// RequestID is transient: every consumer gets a new one
+type RequestID string
+
+var requestCounter atomic.Int64
+
+func NewRequestID() RequestID {
+	return RequestID(fmt.Sprintf("req-%d", requestCounter.Add(1)))
+}
+
+// Handlers is the HTTP layer at the top of the graph
+type Handlers struct {
+	users *UserService
+	log   *Logger
+}
+
+func NewHandlers(users *UserService, log *Logger) *Handlers {
+	return &Handlers{users: users, log: log}
+}
+
+func (h *Handlers) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		name, ok := h.users.Name(r.PathValue("id"))
+		if !ok {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintln(w, name)
+	})
+	return mux
+}
+
+// Two types that need each other, to show cycle detection
+type ServiceA struct{ b *ServiceB }
+type ServiceB struct{ a *ServiceA }
+
+func NewServiceA(b *ServiceB) *ServiceA { return &ServiceA{b: b} }
+func NewServiceB(a *ServiceA) *ServiceB { return &ServiceB{a: a} }
+
+func main() {
+	fmt.Println("Learning dependency injection with a small container in Go")
+
+	// by hand: fine for small programs, the order is up to you
+	cfg := NewConfig()
+	log := NewLogger(cfg)
+	manual, _ := NewUserService(log, NewMemoryStorage())
+	manual.Register("1", "Rishabh")
+	name, _ := manual.Name("1")
+	fmt.Println("Wired by hand:", name)
+
+	// with the container: register in any order, it figures out the rest
+	c := NewContainer()
+	for _, ctor := range []any{NewHandlers, NewUserService, NewMemoryStorage, NewLogger, NewConfig} {
+		if err := c.Provide(ctor, Singleton); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+	c.Provide(NewRequestID, Transient)
+
+	err := c.Invoke(func(h *Handlers, users *UserService) error {
+		if err := users.Register("42", "Gopher"); err != nil {
+			return err
+		}
+		for _, path := range []string{"/users/42", "/users/7"} {
+			rec := httptest.NewRecorder()
+			h.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			fmt.Printf("  GET %-9s -> %d %s", path, rec.Code, rec.Body.String())
+		}
+		return nil
+	})
+	fmt.Println("  Invoke:", err)
+
+	// singletons are shared, transients are new each time
+	c.Invoke(func(l1 *Logger, h *Handlers, s DataStorage, svc *UserService, r1, r2 RequestID) {
+		fmt.Println("  same *Logger everywhere:", l1 == h.log && l1 == svc.log)
+		fmt.Println("  same DataStorage:", s == svc.store)
+		fmt.Println("  transient RequestIDs:", r1, r2)
+	})
+
+	// errors that a hand-written wiring would only show as a nil pointer panic
+	fmt.Println("Errors")
+	c2 := NewContainer()
+	c2.Provide(NewUserService, Singleton)
+	c2.Provide(NewLogger, Singleton) // Config is missing
+	err = c2.Invoke(func(*UserService) {})
+	fmt.Println("  ", err, "| is ErrMissingProvider:", errors.Is(err, ErrMissingProvider))
+
+	c3 := NewContainer()
+	c3.Provide(NewServiceA, Singleton)
+	c3.Provide(NewServiceB, Singleton)
+	err = c3.Invoke(func(*ServiceA) {})
+	fmt.Println("  ", err, "| is ErrCycle:", errors.Is(err, ErrCycle))
+
+	c4 := NewContainer()
+	c4.Provide(func() DataStorage { return nil }, Singleton)
+	c4.Provide(NewUserService, Singleton)
+	c4.Provide(NewLogger, Singleton)
+	c4.Provide(NewConfig, Singleton)
+	fmt.Println("  ", c4.Invoke(func(*UserService) {}))
+
+	fmt.Println("  ", c.Provide(NewConfig, Singleton))
+	fmt.Println("  ", c.Provide("not a func", Singleton))
+	fmt.Println("  ", c.Provide(func() (int, string) { return 0, "" }, Singleton))
+	fmt.Println("  ", c.Invoke(func(*ServiceA) {}))
+}
+
+// Constructors that take their dependencies as parameters are the real win, with or without a container.
+// Accept interfaces (DataStorage), so tests and demos can plug in another implementation.
+// A container resolves by type: a cycle or a missing provider becomes an error naming the whole chain.
+// Reflection moves these checks to run time, google/wire does them at compile time instead.
+// For small programs, wiring by hand in main is simpler and easier to read.
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func provideAll(t *testing.T, c *Container, lifetime Lifetime, ctors ...any) {
+	t.Helper()
+	for _, ctor := range ctors {
+		if err := c.Provide(ctor, lifetime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWiring(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewHandlers, NewUserService, NewMemoryStorage, NewLogger, NewConfig)
+	err := c.Invoke(func(h *Handlers, users *UserService) error {
+		if err := users.Register("42", "Gopher"); err != nil {
+			return err
+		}
+		rec := httptest.NewRecorder()
+		h.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != "Gopher\n" {
+			t.Errorf("GET /users/42: %d %q", rec.Code, rec.Body.String())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSingletonIdentity(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewHandlers, NewUserService, NewMemoryStorage, NewLogger, NewConfig)
+	var first *Logger
+	c.Invoke(func(l *Logger, h *Handlers, svc *UserService) {
+		if l != h.log || l != svc.log {
+			t.Error("the *Logger singleton is not shared")
+		}
+		first = l
+	})
+	c.Invoke(func(l *Logger) {
+		if l != first {
+			t.Error("a second Invoke built a new *Logger")
+		}
+	})
+}
+
+func TestTransient(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Transient, NewRequestID, NewLogger)
+	provideAll(t, c, Singleton, NewConfig)
+	c.Invoke(func(r1, r2 RequestID, l1, l2 *Logger) {
+		if r1 == r2 || l1 == l2 {
+			t.Errorf("transients were shared: %s %s, %p %p", r1, r2, l1, l2)
+		}
+	})
+}
+
+// a constructor returning an interface provides the interface, any implementation fits
+func TestInterfaceProvider(t *testing.T) {
+	c := NewContainer()
+	fake := &MemoryStorage{data: map[string]string{"user:1": "from the fake"}}
+	provideAll(t, c, Singleton, func() DataStorage { return fake }, NewUserService, NewLogger, NewConfig)
+	err := c.Invoke(func(svc *UserService, s DataStorage) {
+		if s != DataStorage(fake) {
+			t.Error("DataStorage is not the provided value")
+		}
+		if name, _ := svc.Name("1"); name != "from the fake" {
+			t.Errorf("Name = %q", name)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the concrete type was not registered, only the interface
+	if err := c.Invoke(func(*MemoryStorage) {}); !errors.Is(err, ErrMissingProvider) {
+		t.Errorf("*MemoryStorage: %v", err)
+	}
+}
+
+func TestMissingProvider(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewUserService, NewLogger, NewMemoryStorage)
+	err := c.Invoke(func(*UserService) {})
+	if !errors.Is(err, ErrMissingProvider) {
+		t.Fatalf("got %v", err)
+	}
+	if want := "no provider for main.Config (needed by *main.UserService -> *main.Logger)"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q\ndoes not name the chain %q", err, want)
+	}
+}
+
+func TestCycle(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewServiceA, NewServiceB)
+	err := c.Invoke(func(*ServiceA) {})
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("got %v", err)
+	}
+	if want := "*main.ServiceA -> *main.ServiceB -> *main.ServiceA"; !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q\ndoes not show the cycle %q", err, want)
+	}
+	// a type needing itself is the shortest cycle
+	c2 := NewContainer()
+	provideAll(t, c2, Singleton, func(RequestID) RequestID { return "" })
+	if err := c2.Invoke(func(RequestID) {}); !errors.Is(err, ErrCycle) {
+		t.Errorf("self cycle: %v", err)
+	}
+}
+
+func TestConstructorError(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, func() DataStorage { return nil }, NewUserService, NewLogger, NewConfig)
+	err := c.Invoke(func(*UserService) {})
+	if err == nil || !strings.Contains(err.Error(), "build *main.UserService: user service needs a storage") {
+		t.Errorf("got %v", err)
+	}
+	boom := errors.New("boom")
+	if err := c.Invoke(func(Config) error { return boom }); err != boom {
+		t.Errorf("fn's own error: %v", err)
+	}
+}
+
+func TestBadConstructors(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewConfig)
+	for _, ctor := range []any{
+		"not a func",
+		func() {},
+		func() (int, string) { return 0, "" },
+		func() (int, error, error) { return 0, nil, nil },
+		NewConfig, // duplicate
+	} {
+		if err := c.Provide(ctor, Singleton); !errors.Is(err, ErrBadConstructor) {
+			t.Errorf("Provide(%T) = %v", ctor, err)
+		}
+	}
+	if err := c.Invoke(42); err == nil {
+		t.Error("Invoke(42) succeeded")
+	}
+}
+
+func TestPanickingConstructorUnlocks(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, func() Config { panic("bad config") }, NewRequestID)
+	func() {
+		defer func() {
+			if v := recover(); v != "bad config" {
+				t.Errorf("recovered %v", v)
+			}
+		}()
+		c.Invoke(func(Config) {})
+	}()
+	// the container must still work after the panic
+	done := make(chan error, 1)
+	go func() { done <- c.Invoke(func(RequestID) {}) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the container is still locked after a constructor panicked")
+	}
+}
+
+func TestInvokeInsideInvoke(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewConfig)
+	err := c.Invoke(func(cfg Config) error {
+		return c.Invoke(func(again Config) {
+			if again != cfg {
+				t.Error("different Config")
+			}
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a constructor that gets its own dependencies from the container, while the container is building
+func TestConstructorUsesTheContainer(t *testing.T) {
+	c := NewContainer()
+	provideAll(t, c, Singleton, NewConfig, NewLogger, NewMemoryStorage, func() (*UserService, error) {
+		var svc *UserService
+		err := c.Invoke(func(log *Logger, store DataStorage) (err error) {
+			svc, err = NewUserService(log, store)
+			return err
+		})
+		return svc, err
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Invoke(func(svc *UserService, log *Logger) {
+			if svc.log != log {
+				t.Error("the constructor got another *Logger")
+			}
+		})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("a constructor using the container deadlocked")
+	}
+}
+
+func TestSingletonBuiltOnce(t *testing.T) {
+	c := NewContainer()
+	var builds atomic.Int32
+	provideAll(t, c, Singleton, NewConfig, func(cfg Config) *Logger {
+		builds.Add(1)
+		time.Sleep(time.Millisecond) // give the others time to ask for it too
+		return NewLogger(cfg)
+	})
+	loggers := make([]*Logger, 20)
+	var wg sync.WaitGroup
+	for i := range loggers {
+		wg.Go(func() {
+			if err := c.Invoke(func(l *Logger) { loggers[i] = l }); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if builds.Load() != 1 {
+		t.Errorf("*Logger built %d times", builds.Load())
+	}
+	for _, l := range loggers {
+		if l != loggers[0] {
+			t.Fatal("different *Logger values")
+		}
+	}
+}
+
+// nothing is built when part of the graph is missing
+func TestCheckBeforeBuild(t *testing.T) {
+	c := NewContainer()
+	built := false
+	provideAll(t, c, Singleton, func() Config { built = true; return Config{} }, NewUserService, NewLogger)
+	if err := c.Invoke(func(Config, *UserService) {}); !errors.Is(err, ErrMissingProvider) {
+		t.Fatalf("got %v", err)
+	}
+	if built {
+		t.Error("Config was built before the missing DataStorage was found")
+	}
+}
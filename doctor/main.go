@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// "It doesn't work on my machine" usually means the machine, not the code: an old Go,
+// a port already taken, a read-only home directory. A -doctor flag checks all of that up
+// front and says what to do about each problem, instead of failing later with a vague error.
+//
+// The checks live in a registry, so a feature that needs something (a database driver,
+// a directory) registers its own check next to its code, and the doctor learns about it.
+
+const MinGoVersion = "1.22" // range over int, ServeMux patterns with methods
+
+type Check struct {
+	Name     string
+	Run      func() error // nil: passed
+	Hint     string       // what to do when it fails
+	Optional bool         // a failure is a warning, it doesn't change the exit code
+}
+
+type Registry struct {
+	Timeout time.Duration // per check, a hung DNS lookup must not hang the doctor. 0: no limit
+	checks  []Check
+}
+
+// Register adds c, the same name twice is a programming mistake
+func (r *Registry) Register(c Check) {
+	for _, existing := range r.checks {
+		if existing.Name == c.Name {
+			panic("doctor: check registered twice: " + c.Name)
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+type Status string
+
+const (
+	Pass Status = "ok"
+	Fail Status = "FAIL"
+	Warn Status = "warn"
+)
+
+type Result struct {
+	Check Check
+	Err   error
+}
+
+func (res Result) Status() Status {
+	switch {
+	case res.Err == nil:
+		return Pass
+	case res.Check.Optional:
+		return Warn
+	}
+	return Fail
+}
+
+// runOne turns a panic or a timeout into a failed check, one bad check can't stop the report
+func (r *Registry) runOne(c Check) (err error) {
+	done := make(chan error, 1) // buffered: a check that times out can still finish and leave
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("check panicked: %v", v)
+			}
+		}()
+		done <- c.Run()
+	}()
+	if r.Timeout <= 0 {
+		return <-done
+	}
+	select {
+	case err = <-done:
+		return err
+	case <-time.After(r.Timeout):
+		return fmt.Errorf("no answer after %v", r.Timeout)
+	}
+}
+
+// Run runs every check in order, writes the report to w and returns the exit code:
+// 1 if a required check failed, 0 otherwise
+func (r *Registry) Run(w io.Writer) int {
+	width := 0
+	for _, c := range r.checks {
+		width = max(width, len(c.Name))
+	}
+	counts := map[Status]int{}
+	for _, c := range r.checks {
+		res := Result{Check: c, Err: r.runOne(c)}
+		st := res.Status()
+		counts[st]++
+		if st == Pass {
+			fmt.Fprintf(w, "  [%-4s] %s\n", st, c.Name)
+			continue
+		}
+		fmt.Fprintf(w, "  [%-4s] %-*s  %v\n", st, width, c.Name, res.Err)
+		if c.Hint != "" {
+			fmt.Fprintf(w, "         %-*s  hint: %s\n", width, "", c.Hint)
+		}
+	}
+	fmt.Fprintf(w, "  %d passed, %d failed, %d warnings\n", counts[Pass], counts[Fail], counts[Warn])
+	if counts[Fail] > 0 {
+		return 1
+	}
+	return 0
+}
+
+// ----------------------------------------------------------------------------
+// The checks themselves
+
+// parseGoVersion reads "go1.22.3", "go1.23rc1" or "devel go1.24-abcdef ..." as major, minor
+func parseGoVersion(v string) (major, minor int, err error) {
+	if f := strings.Fields(v); len(f) > 1 && f[0] == "devel" {
+		v = f[1]
+	}
+	rest, ok := strings.CutPrefix(v, "go")
+	if !ok {
+		return 0, 0, fmt.Errorf("can't read Go version %q", v)
+	}
+	if end := strings.IndexFunc(rest, func(r rune) bool { return r != '.' && (r < '0' || r > '9') }); end >= 0 {
+		rest = rest[:end] // "1.23rc1" -> "1.23", "1.24-abcdef" -> "1.24"
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("can't read Go version %q", v)
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err := errors.Join(err1, err2); err != nil {
+		return 0, 0, fmt.Errorf("can't read Go version %q: %w", v, err)
+	}
+	return major, minor, nil
+}
+
+func checkGoVersion(have, want string) error {
+	hMaj, hMin, err := parseGoVersion(have)
+	if err != nil {
+		return err
+	}
+	wMaj, wMin, err := parseGoVersion("go" + want)
+	if err != nil {
+		return err
+	}
+	if hMaj < wMaj || (hMaj == wMaj && hMin < wMin) {
+		return fmt.Errorf("%s is older than go%s", have, want)
+	}
+	return nil
+}
+
+// checkWritable creates dir if needed, then writes and removes a file in it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, "doctor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("ok"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func checkPortFree(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
+
+func checkResolve(host string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err == nil && len(addrs) == 0 {
+		err = fmt.Errorf("%s resolved to no addresses", host)
+	}
+	return err
+}
+
+func checkGoBinary() error {
+	path, err := exec.LookPath("go")
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command(path, "env", "GOROOT").Output()
+	if err != nil {
+		return fmt.Errorf("%s is there but doesn't run: %w", path, err)
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return fmt.Errorf("%s reports no GOROOT", path)
+	}
+	return nil
+}
+
+// checkColor follows https://no-color.org and the usual TERM=dumb, and needs stdout to be a terminal
+func checkColor() error {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return errors.New("NO_COLOR is set")
+	}
+	if t := os.Getenv("TERM"); t == "" || t == "dumb" {
+		return fmt.Errorf("TERM=%q", t)
+	}
+	fi, err := os.Stdout.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return errors.New("stdout is not a terminal (a pipe or a file)")
+	}
+	return nil
+}
+
+// dataDir is where the lessons keep config and progress
+func dataDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "go_learning")
+}
+
+func defaultChecks() *Registry {
+	r := &Registry{Timeout: 5 * time.Second}
+	r.Register(Check{
+		Name: "go version",
+		Run:  func() error { return checkGoVersion(runtime.Version(), MinGoVersion) },
+		Hint: "install Go " + MinGoVersion + " or newer from https://go.dev/dl/",
+	})
+	r.Register(Check{
+		Name: "data directory writable",
+		Run:  func() error { return checkWritable(dataDir()) },
+		Hint: "check the permissions of " + dataDir() + ", or set XDG_CONFIG_HOME to a writable directory",
+	})
+	r.Register(Check{
+		Name: "port 8080 free",
+		Run:  func() error { return checkPortFree(":8080") },
+		Hint: "stop whatever listens there (lsof -i :8080), the HTTP lessons serve on it",
+	})
+	r.Register(Check{
+		Name: "localhost resolves",
+		Run:  func() error { return checkResolve("localhost", 2*time.Second) },
+		Hint: "add \"127.0.0.1 localhost\" to /etc/hosts",
+	})
+	r.Register(Check{
+		Name:     "go binary on PATH",
+		Run:      checkGoBinary,
+		Hint:     "only needed to run the lessons from source, add $(go env GOROOT)/bin to PATH",
+		Optional: true,
+	})
+	r.Register(Check{
+		Name:     "terminal colors",
+		Run:      checkColor,
+		Hint:     "output works without colors, it's just plainer",
+		Optional: true,
+	})
+	return r
+}
+
+func main() {
+	doctor := flag.Bool("doctor", false, "check this machine and exit, non-zero if a required check fails")
+	flag.Parse()
+
+	if *doctor {
+		fmt.Println("Checking the environment")
+		os.Exit(defaultChecks().Run(os.Stdout)) // os.Exit skips defers, there are none here
+	}
+
+	fmt.Println("Learning an environment self-check in Go")
+
+	fmt.Println("Go versions against go" + MinGoVersion + ":")
+	for _, v := range []string{runtime.Version(), "go1.22.0", "go1.21.13", "go1.23rc1", "devel go1.24-abcdef Tue Jan 1", "go2.0", "gopher"} {
+		fmt.Printf("  %-32q %v\n", v, checkGoVersion(v, MinGoVersion))
+	}
+
+	// a registry with checks injected to fail in every way, instead of breaking the machine
+	fmt.Println("A report with every kind of result:")
+	r := &Registry{Timeout: 50 * time.Millisecond}
+	r.Register(Check{Name: "passes", Run: func() error { return nil }})
+	r.Register(Check{Name: "data directory writable", Run: func() error { return checkWritable("/proc/doctor") }, Hint: "pick a writable directory"})
+	r.Register(Check{Name: "sqlite driver", Run: func() error { return errors.New(`sql: unknown driver "sqlite"`) }, Hint: "build with the sqlite tag", Optional: true})
+	r.Register(Check{Name: "panics", Run: func() error { panic("index out of range") }})
+	r.Register(Check{Name: "hangs", Run: func() error { time.Sleep(time.Second); return nil }, Hint: "a slow network"})
+	fmt.Println("  exit code:", r.Run(os.Stdout))
+
+	fmt.Println("Only optional checks fail:")
+	r = &Registry{Timeout: time.Second}
+	r.Register(Check{Name: "passes", Run: func() error { return nil }})
+	r.Register(Check{Name: "terminal colors", Run: func() error { return errors.New("NO_COLOR is set") }, Optional: true})
+	fmt.Println("  exit code:", r.Run(os.Stdout))
+
+	func() {
+		defer func() { fmt.Println("Registering a name twice:", recover()) }()
+		r.Register(Check{Name: "passes"})
+	}()
+	fmt.Println("Run with -doctor to check this machine")
+}
+
+// Check the environment up front and say what to do, a hint beats a stack trace two minutes later.
+// Keep the checks in a registry, features add their own check next to their code.
+// Required failures set a non-zero exit code, optional ones are warnings: scripts can rely on $?.
+// A panicking or hanging check becomes a failed check, the report always finishes.
+// Test a doctor by injecting failing checks, not by breaking the machine it runs on.
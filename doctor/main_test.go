@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pass() error { return nil }
+
+func failWith(msg string) func() error {
+	return func() error { return errors.New(msg) }
+}
+
+func run(r *Registry) (string, int) {
+	var sb strings.Builder
+	code := r.Run(&sb)
+	return sb.String(), code
+}
+
+func TestReport(t *testing.T) {
+	r := &Registry{Timeout: 50 * time.Millisecond}
+	r.Register(Check{Name: "passes", Run: pass})
+	r.Register(Check{Name: "config dir", Run: failWith("permission denied"), Hint: "chmod it"})
+	r.Register(Check{Name: "sqlite driver", Run: failWith(`unknown driver "sqlite"`), Hint: "build with -tags sqlite", Optional: true})
+	r.Register(Check{Name: "no hint", Run: failWith("broken")})
+	r.Register(Check{Name: "panics", Run: func() error { panic("index out of range") }})
+	block := make(chan struct{})
+	defer close(block)
+	r.Register(Check{Name: "hangs", Run: func() error { <-block; return nil }, Hint: "a slow network"})
+	r.Register(Check{Name: "also passes", Run: pass})
+
+	got, code := run(r)
+	want := `  [ok  ] passes
+  [FAIL] config dir     permission denied
+                        hint: chmod it
+  [warn] sqlite driver  unknown driver "sqlite"
+                        hint: build with -tags sqlite
+  [FAIL] no hint        broken
+  [FAIL] panics         check panicked: index out of range
+  [FAIL] hangs          no answer after 50ms
+                        hint: a slow network
+  [ok  ] also passes
+  2 passed, 4 failed, 1 warnings
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if code != 1 {
+		t.Errorf("exit code %d", code)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		code   int
+	}{
+		{"nothing registered", nil, 0},
+		{"all pass", []Check{{Name: "a", Run: pass}, {Name: "b", Run: pass}}, 0},
+		{"only optional failures", []Check{{Name: "a", Run: pass}, {Name: "b", Run: failWith("x"), Optional: true}}, 0},
+		{"one required failure", []Check{{Name: "a", Run: failWith("x")}, {Name: "b", Run: pass}}, 1},
+		{"a required panic", []Check{{Name: "a", Run: func() error { panic(nil) }}}, 1},
+		{"a nil Run", []Check{{Name: "a"}}, 1},
+		{"an optional panic", []Check{{Name: "a", Run: func() error { panic("x") }, Optional: true}}, 0},
+	}
+	for _, tt := range tests {
+		r := &Registry{Timeout: time.Second}
+		for _, c := range tt.checks {
+			r.Register(c)
+		}
+		if out, code := run(r); code != tt.code {
+			t.Errorf("%s: exit %d, want %d\n%s", tt.name, code, tt.code, out)
+		}
+	}
+}
+
+// every check runs, even after one fails, in the order they were registered
+func TestAllChecksRun(t *testing.T) {
+	var order []string
+	r := &Registry{Timeout: time.Second}
+	for _, name := range []string{"a", "b", "c"} {
+		r.Register(Check{Name: name, Run: func() error { order = append(order, name); return errors.New("no") }})
+	}
+	run(r)
+	if strings.Join(order, "") != "abc" {
+		t.Errorf("ran %v", order)
+	}
+}
+
+func TestNoTimeout(t *testing.T) {
+	r := &Registry{} // zero Timeout: wait for the check
+	r.Register(Check{Name: "slow", Run: func() error { time.Sleep(20 * time.Millisecond); return nil }})
+	if out, code := run(r); code != 0 {
+		t.Errorf("exit %d\n%s", code, out)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	r := &Registry{}
+	r.Register(Check{Name: "a", Run: pass})
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic")
+		}
+	}()
+	r.Register(Check{Name: "a", Run: pass})
+}
+
+func TestGoVersion(t *testing.T) {
+	tests := []struct {
+		have string
+		ok   bool
+	}{
+		{runtime.Version(), true},
+		{"go1.22", true},
+		{"go1.22.0", true},
+		{"go1.22.10", true},
+		{"go1.23rc1", true},
+		{"devel go1.24-abcdef Tue Jan 1 10:00:00 2025 +0000", true},
+		{"go2.0", true},
+		{"go1.21.13", false},
+		{"go1.9", false},
+		{"go1.22rc1", true}, // a release candidate counts as its version
+		{"gopher", false},
+		{"go1", false},
+		{"go1.x", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := checkGoVersion(tt.have, "1.22"); (err == nil) != tt.ok {
+			t.Errorf("%q: %v", tt.have, err)
+		}
+	}
+	// numbers, not strings: 1.10 is newer than 1.9
+	if maj, min, err := parseGoVersion("go1.10.3"); maj != 1 || min != 10 || err != nil {
+		t.Errorf("go1.10.3: %d.%d %v", maj, min, err)
+	}
+	if err := checkGoVersion("go1.10", "1.9"); err != nil {
+		t.Errorf("1.10 against 1.9: %v", err)
+	}
+}
+
+func TestCheckWritable(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new", "nested")
+	if err := checkWritable(dir); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left behind %v", entries)
+	}
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0o644)
+	if err := checkWritable(filepath.Join(file, "sub")); err == nil {
+		t.Error("a directory under a file is writable")
+	}
+	if os.Geteuid() != 0 { // root writes anywhere
+		ro := t.TempDir()
+		os.Chmod(ro, 0o555)
+		t.Cleanup(func() { os.Chmod(ro, 0o755) })
+		if err := checkWritable(ro); err == nil {
+			t.Error("a read-only directory is writable")
+		}
+	}
+}
+
+func TestCheckPortFree(t *testing.T) {
+	if err := checkPortFree("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := checkPortFree(l.Addr().String()); err == nil {
+		t.Errorf("%s is in use but reported free", l.Addr())
+	}
+}
+
+func TestCheckResolve(t *testing.T) {
+	if err := checkResolve("localhost", 2*time.Second); err != nil {
+		t.Errorf("localhost: %v", err)
+	}
+	if err := checkResolve("no-such-host.invalid", 2*time.Second); err == nil {
+		t.Error(".invalid resolved")
+	}
+}
+
+func TestCheckColor(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("NO_COLOR", "")
+	if err := checkColor(); err == nil || err.Error() != "NO_COLOR is set" {
+		t.Errorf("NO_COLOR set but empty: %v", err) // no-color.org: present counts, whatever the value
+	}
+	os.Unsetenv("NO_COLOR")
+	for _, term := range []string{"", "dumb"} {
+		t.Setenv("TERM", term)
+		if err := checkColor(); err == nil {
+			t.Errorf("TERM=%q: no error", term)
+		}
+	}
+	t.Setenv("TERM", "xterm")
+	// go test's stdout is a pipe or a file
+	if err := checkColor(); err == nil || !strings.Contains(err.Error(), "not a terminal") {
+		t.Errorf("under go test: %v", err)
+	}
+}
+
+func TestDefaultChecks(t *testing.T) {
+	r := defaultChecks()
+	var names []string
+	for _, c := range r.checks {
+		names = append(names, c.Name)
+		if c.Run == nil || c.Hint == "" {
+			t.Errorf("%s: no Run or no Hint", c.Name)
+		}
+	}
+	want := "go version, data directory writable, port 8080 free, localhost resolves, go binary on PATH, terminal colors"
+	if strings.Join(names, ", ") != want {
+		t.Errorf("checks %v", names)
+	}
+	if r.Timeout <= 0 {
+		t.Error("no timeout for the real checks")
+	}
+}
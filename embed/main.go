@@ -0,0 +1,151 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing/fstest"
+)
+
+// The //go:embed line must be directly above the variable, with no blank line in between.
+// Paths are relative to this file's directory and are read at COMPILE time:
+// the files become part of the binary, so it runs anywhere without them.
+
+//go:embed seed_users.json
+var seedUsersJSON []byte // a single file as []byte (or string)
+
+//go:embed seed_users.json static
+var files embed.FS // a read-only file system with the file and the whole static directory
+
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Age   int    `json:"age"`
+}
+
+// ErrInvalidSeed is returned when the seed file can't be used
+var ErrInvalidSeed = errors.New("invalid seed data")
+
+// UserStore holds users in memory
+type UserStore struct {
+	users map[int]User
+}
+
+// NewUserStore loads the seed users from name inside fsys.
+// Taking an fs.FS instead of using the embedded files directly means a test
+// (or the demo) can pass another file system with broken data.
+func NewUserStore(fsys fs.FS, name string) (*UserStore, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("load seed users: %w", err)
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("load seed users from %s: %w: %v", name, ErrInvalidSeed, err)
+	}
+	store := &UserStore{users: make(map[int]User, len(users))}
+	for i, u := range users {
+		if u.ID <= 0 || u.Name == "" || u.Email == "" {
+			return nil, fmt.Errorf("load seed users from %s: %w: user #%d needs id, name and email", name, ErrInvalidSeed, i+1)
+		}
+		if _, dup := store.users[u.ID]; dup {
+			return nil, fmt.Errorf("load seed users from %s: %w: duplicate id %d", name, ErrInvalidSeed, u.ID)
+		}
+		store.users[u.ID] = u
+	}
+	return store, nil
+}
+
+// All returns the users sorted by ID
+func (s *UserStore) All() []User {
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+// staticHandler serves the embedded static directory like a normal file server.
+// fs.Sub strips the "static/" prefix so /index.html maps to static/index.html.
+func staticHandler() (http.Handler, error) {
+	sub, err := fs.Sub(files, "static")
+	if err != nil {
+		return nil, err
+	}
+	return http.FileServer(http.FS(sub)), nil
+}
+
+func main() {
+	fmt.Println("Learning go:embed in Go")
+
+	// 1. a single embedded file
+	fmt.Printf("seed_users.json is embedded: %d bytes\n", len(seedUsersJSON))
+
+	// 2. an embed.FS directory
+	entries, err := fs.ReadDir(files, "static")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Print("Embedded static files:")
+	for _, e := range entries {
+		fmt.Print(" ", e.Name())
+	}
+	fmt.Println()
+	fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			fmt.Println("  walk:", path)
+		}
+		return err
+	})
+
+	// 3. embedded JSON loaded into the store at startup
+	store, err := NewUserStore(files, "seed_users.json")
+	if err != nil {
+		// invalid seed data is a bug in the build: stop right away with a clear message
+		fmt.Fprintln(os.Stderr, "startup failed:", err)
+		os.Exit(1)
+	}
+	for _, u := range store.All() {
+		fmt.Printf("  %d %-14s %s\n", u.ID, u.Name, u.Email)
+	}
+
+	// 4. the same loader with broken fixtures from an in-memory file system
+	broken := fstest.MapFS{
+		"bad_json.json":  {Data: []byte(`[{"id": 1, "name": "A", `)},
+		"missing.json":   {Data: []byte(`[{"id": 1, "name": "A"}]`)},
+		"duplicate.json": {Data: []byte(`[{"id": 1, "name": "A", "email": "a@x.com"}, {"id": 1, "name": "B", "email": "b@x.com"}]`)},
+	}
+	for _, name := range []string{"bad_json.json", "missing.json", "duplicate.json", "not_there.json"} {
+		_, err := NewUserStore(broken, name)
+		fmt.Printf("  %-15s -> %v (ErrInvalidSeed: %v)\n", name, err, errors.Is(err, ErrInvalidSeed))
+	}
+
+	// 5. serving embedded files over HTTP (no server needed, httptest records the response)
+	handler, err := staticHandler()
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, path := range []string{"/", "/style.css", "/seed_users.json"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		fmt.Printf("  GET %-17s -> %d %s, %d bytes\n", path, rec.Code, rec.Header().Get("Content-Type"), len(body))
+	}
+}
+
+// go:embed works with string, []byte or embed.FS variables at package level.
+// Files are read at compile time: after editing seed_users.json, run/build again to see the change.
+// Files starting with . or _ are skipped in directories unless you use the all: prefix.
+// embed.FS implements fs.FS, so it works with fs.ReadFile, fs.WalkDir, http.FS and template.ParseFS.
+// Accept an fs.FS in your functions, then tests can pass fstest.MapFS or os.DirFS.
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedSeedLoads(t *testing.T) {
+	store, err := NewUserStore(files, "seed_users.json")
+	if err != nil {
+		t.Fatalf("the embedded seed file is broken: %v", err)
+	}
+	users := store.All()
+	if len(users) == 0 {
+		t.Fatal("no seed users")
+	}
+	for i, u := range users {
+		if i > 0 && u.ID <= users[i-1].ID {
+			t.Errorf("All() is not sorted by ID: %v", users)
+		}
+	}
+
+	// the []byte and the embed.FS hold the same file
+	fromFS, err := fs.ReadFile(files, "seed_users.json")
+	if err != nil || !bytes.Equal(fromFS, seedUsersJSON) {
+		t.Errorf("embed.FS and []byte differ: %v", err)
+	}
+	var raw []User
+	if err := json.Unmarshal(seedUsersJSON, &raw); err != nil || len(raw) != len(users) {
+		t.Errorf("%d users in the JSON, %d in the store: %v", len(raw), len(users), err)
+	}
+}
+
+func TestNewUserStoreBrokenFixtures(t *testing.T) {
+	fixtures := fstest.MapFS{
+		"bad_json.json":  {Data: []byte(`[{"id": 1, "name": "A", `)},
+		"not_array.json": {Data: []byte(`{"id": 1}`)},
+		"no_email.json":  {Data: []byte(`[{"id": 1, "name": "A"}]`)},
+		"zero_id.json":   {Data: []byte(`[{"name": "A", "email": "a@x.com"}]`)},
+		"duplicate.json": {Data: []byte(`[{"id": 1, "name": "A", "email": "a@x.com"}, {"id": 1, "name": "B", "email": "b@x.com"}]`)},
+		"empty.json":     {Data: []byte(`[]`)},
+	}
+	tests := []struct {
+		name    string
+		invalid bool   // ErrInvalidSeed
+		wantMsg string // part of the message that says what to fix
+	}{
+		{"bad_json.json", true, "bad_json.json"},
+		{"not_array.json", true, "cannot unmarshal"},
+		{"no_email.json", true, "user #1 needs id, name and email"},
+		{"zero_id.json", true, "user #1"},
+		{"duplicate.json", true, "duplicate id 1"},
+		{"not_there.json", false, "not_there.json"},
+	}
+	for _, tt := range tests {
+		_, err := NewUserStore(fixtures, tt.name)
+		if err == nil || errors.Is(err, ErrInvalidSeed) != tt.invalid || !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+	if _, err := NewUserStore(fixtures, "not_there.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("a missing file should wrap fs.ErrNotExist: %v", err)
+	}
+	store, err := NewUserStore(fixtures, "empty.json")
+	if err != nil || len(store.All()) != 0 {
+		t.Errorf("empty list: %v", err)
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	h, err := staticHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, _ := files.ReadFile("static/index.html")
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+		body        []byte
+	}{
+		{"/", http.StatusOK, "text/html; charset=utf-8", index},
+		{"/style.css", http.StatusOK, "text/css; charset=utf-8", nil},
+		{"/seed_users.json", http.StatusNotFound, "", nil}, // outside static/, fs.Sub hides it
+		{"/../seed_users.json", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("GET %s: Content-Type %q", tt.path, rec.Header().Get("Content-Type"))
+		}
+		if body, _ := io.ReadAll(rec.Body); tt.body != nil && !bytes.Equal(body, tt.body) {
+			t.Errorf("GET %s: body differs from the embedded file", tt.path)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Hard-coded demo data is always the same three users. A generator can make as many
+// as a demo or a load test needs, and with a fixed seed it makes the SAME ones on every run,
+// so a bug seen with seed 42 can be reproduced with seed 42.
+
+//go:embed words/first_names.txt
+var firstNamesTxt string
+
+//go:embed words/last_names.txt
+var lastNamesTxt string
+
+var emailDomains = []string{"example.com", "example.org", "mail.test"}
+
+type User struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	Role    string    `json:"role"`
+	Age     int       `json:"age"`
+	Created time.Time `json:"created"`
+}
+
+// Student has the same shape as in the json lesson
+type Student struct {
+	StudentID int    `json:"student_id"`
+	FullName  string `json:"full_name"`
+	Age       int    `json:"age"`
+	IsActive  bool   `json:"is_active"`
+}
+
+// Job is a unit of work for the concurrency lessons
+type Job struct {
+	ID       int
+	Name     string
+	Duration time.Duration // how long a worker should pretend to work
+}
+
+type weighted struct {
+	value  string
+	weight int
+}
+
+// most users are plain users, like in a real app
+var roleWeights = []weighted{{"user", 80}, {"moderator", 15}, {"admin", 5}}
+
+var jobKinds = []string{"resize-image", "send-email", "build-report", "sync-inventory", "charge-card"}
+
+type Generator struct {
+	// Created times fall in [Start, End). They are fixed dates, not time.Now(),
+	// or the output would change from one run to the next.
+	Start, End time.Time
+
+	r      *rand.Rand
+	first  []string
+	last   []string
+	emails map[string]bool
+	nextID int
+}
+
+// NewGenerator returns a generator whose output depends only on seed
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		Start:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		r:      rand.New(rand.NewPCG(uint64(seed), 0)),
+		first:  strings.Fields(firstNamesTxt),
+		last:   strings.Fields(lastNamesTxt),
+		emails: make(map[string]bool),
+		nextID: 1,
+	}
+}
+
+func (g *Generator) pick(words []string) string {
+	return words[g.r.IntN(len(words))]
+}
+
+func (g *Generator) pickWeighted(items []weighted) string {
+	total := 0
+	for _, it := range items {
+		total += it.weight
+	}
+	n := g.r.IntN(total)
+	for _, it := range items {
+		if n < it.weight {
+			return it.value
+		}
+		n -= it.weight
+	}
+	return items[len(items)-1].value // not reached
+}
+
+// email derives an address from the name. There are only 40x40 names, so the same
+// one comes up again: add a number until the address hasn't been used yet.
+func (g *Generator) email(first, last string) string {
+	local := strings.ToLower(first + "." + last)
+	domain := g.pick(emailDomains)
+	addr := local + "@" + domain
+	for n := 2; g.emails[addr]; n++ {
+		addr = fmt.Sprintf("%s%d@%s", local, n, domain)
+	}
+	g.emails[addr] = true
+	return addr
+}
+
+func (g *Generator) User() User {
+	first, last := g.pick(g.first), g.pick(g.last)
+	u := User{
+		ID:      g.nextID,
+		Name:    first + " " + last,
+		Email:   g.email(first, last),
+		Role:    g.pickWeighted(roleWeights),
+		Age:     18 + g.r.IntN(50),
+		Created: g.created(),
+	}
+	g.nextID++
+	return u
+}
+
+// created picks a time in [Start, End). An empty or reversed range gives Start:
+// Int64N panics when its argument isn't positive.
+func (g *Generator) created() time.Time {
+	t := g.Start
+	if span := g.End.Sub(g.Start); span > 0 {
+		t = t.Add(time.Duration(g.r.Int64N(int64(span))))
+	}
+	return t.Truncate(time.Second)
+}
+
+func (g *Generator) Users(n int) []User {
+	users := make([]User, n)
+	for i := range users {
+		users[i] = g.User()
+	}
+	return users
+}
+
+func (g *Generator) Students(n int) []Student {
+	students := make([]Student, n)
+	for i := range students {
+		students[i] = Student{
+			StudentID: g.nextID,
+			FullName:  g.pick(g.first) + " " + g.pick(g.last),
+			Age:       17 + g.r.IntN(10),
+			IsActive:  g.r.IntN(10) < 8,
+		}
+		g.nextID++
+	}
+	return students
+}
+
+// Jobs returns n jobs that take between minDur and maxDur each.
+// The bounds may come in either order, a negative one counts as 0.
+func (g *Generator) Jobs(n int, minDur, maxDur time.Duration) []Job {
+	if maxDur < minDur {
+		minDur, maxDur = maxDur, minDur
+	}
+	minDur, maxDur = max(minDur, 0), max(maxDur, 0)
+	jobs := make([]Job, n)
+	for i := range jobs {
+		jobs[i] = Job{
+			ID:       g.nextID,
+			Name:     g.pick(jobKinds),
+			Duration: minDur + time.Duration(g.r.Int64N(int64(maxDur-minDur)+1)),
+		}
+		g.nextID++
+	}
+	return jobs
+}
+
+func main() {
+	fmt.Println("Learning to generate fake data in Go")
+
+	g := NewGenerator(42)
+	for _, u := range g.Users(5) {
+		fmt.Printf("  %2d %-18s %-32s %-9s %2d %s\n", u.ID, u.Name, u.Email, u.Role, u.Age, u.Created.Format(time.DateOnly))
+	}
+
+	// same seed, same data
+	a, b := NewGenerator(7).Users(1000), NewGenerator(7).Users(1000)
+	fmt.Println("Same seed gives the same 1000 users:", reflect.DeepEqual(a, b))
+	c := NewGenerator(8).Users(1000)
+	fmt.Println("Another seed gives other users:     ", !reflect.DeepEqual(a, c))
+
+	// 10k users from 1600 name combinations: emails still unique
+	users := NewGenerator(1).Users(10_000)
+	seen := make(map[string]bool, len(users))
+	for _, u := range users {
+		seen[u.Email] = true
+	}
+	fmt.Printf("Unique emails: %d of %d\n", len(seen), len(users))
+
+	// the role split should be close to the weights
+	counts := map[string]int{}
+	oldest, newest := users[0].Created, users[0].Created
+	for _, u := range users {
+		counts[u.Role]++
+		if u.Created.Before(oldest) {
+			oldest = u.Created
+		}
+		if u.Created.After(newest) {
+			newest = u.Created
+		}
+	}
+	fmt.Println("Roles:")
+	for _, w := range roleWeights {
+		fmt.Printf("  %-9s want %2d%%  got %5.2f%%\n", w.value, w.weight, 100*float64(counts[w.value])/float64(len(users)))
+	}
+	fmt.Println("Created between", oldest.Format(time.DateOnly), "and", newest.Format(time.DateOnly))
+
+	// the other shapes
+	students := g.Students(2)
+	data, _ := json.MarshalIndent(students, "", "  ")
+	fmt.Println("Students:", string(data))
+
+	jobs := g.Jobs(10, 10*time.Millisecond, 50*time.Millisecond)
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Duration < jobs[j].Duration })
+	fmt.Println("Jobs, shortest first:")
+	for _, j := range jobs[:3] {
+		fmt.Printf("  #%d %-14s %s\n", j.ID, j.Name, j.Duration.Round(time.Millisecond))
+	}
+}
+
+// Seed the generator, never rely on the global random source: a fixed seed makes a failing run repeatable.
+// Keep the wordlists in files and //go:embed them, the binary still works on its own.
+// Uniqueness needs memory: remember what was handed out and change the value on a clash.
+// Weighted choice: pick a number below the total weight and walk the list subtracting weights.
+// Use fixed dates for the time range, time.Now() would make the output differ on every run.
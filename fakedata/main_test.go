@@ -0,0 +1,144 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeterministic(t *testing.T) {
+	a, b := NewGenerator(42), NewGenerator(42)
+	if !reflect.DeepEqual(a.Users(500), b.Users(500)) {
+		t.Error("same seed, different users")
+	}
+	if !reflect.DeepEqual(a.Students(50), b.Students(50)) {
+		t.Error("same seed, different students")
+	}
+	if !reflect.DeepEqual(a.Jobs(50, time.Millisecond, time.Second), b.Jobs(50, time.Millisecond, time.Second)) {
+		t.Error("same seed, different jobs")
+	}
+	if reflect.DeepEqual(NewGenerator(1).Users(50), NewGenerator(2).Users(50)) {
+		t.Error("seeds 1 and 2 gave the same users")
+	}
+	// pinned: a change to the wordlists or the draw order shows up here
+	want := User{ID: 1, Name: "Sara Yadav", Email: "sara.yadav@example.com", Role: "user", Age: 27,
+		Created: time.Date(2024, 12, 7, 10, 27, 7, 0, time.UTC)}
+	if got := NewGenerator(42).User(); got != want {
+		t.Errorf("first user of seed 42 = %+v, want %+v", got, want)
+	}
+}
+
+func TestUniqueEmails(t *testing.T) {
+	users := NewGenerator(1).Users(10_000)
+	seen := make(map[string]int, len(users))
+	for _, u := range users {
+		if prev, dup := seen[u.Email]; dup {
+			t.Fatalf("users %d and %d share %s", prev, u.ID, u.Email)
+		}
+		seen[u.Email] = u.ID
+		first, last, _ := strings.Cut(strings.ToLower(u.Name), " ")
+		if !strings.HasPrefix(u.Email, first+"."+last) || !strings.Contains(u.Email, "@") {
+			t.Fatalf("email %s doesn't come from %s", u.Email, u.Name)
+		}
+	}
+}
+
+func TestUserFields(t *testing.T) {
+	g := NewGenerator(3)
+	for i, u := range g.Users(2000) {
+		if u.ID != i+1 {
+			t.Fatalf("user %d has ID %d", i, u.ID)
+		}
+		if u.Age < 18 || u.Age > 67 {
+			t.Errorf("%s: age %d", u.Name, u.Age)
+		}
+		if u.Created.Before(g.Start) || !u.Created.Before(g.End) || u.Created.Nanosecond() != 0 {
+			t.Errorf("%s: created %s", u.Name, u.Created)
+		}
+	}
+}
+
+func TestRoleDistribution(t *testing.T) {
+	const n = 20_000
+	counts := map[string]int{}
+	for _, u := range NewGenerator(9).Users(n) {
+		counts[u.Role]++
+	}
+	for _, w := range roleWeights {
+		got := 100 * float64(counts[w.value]) / n
+		if math.Abs(got-float64(w.weight)) > 1.5 {
+			t.Errorf("%s: %.2f%%, want %d%% ± 1.5", w.value, got, w.weight)
+		}
+	}
+	if len(counts) != len(roleWeights) {
+		t.Errorf("roles %v", counts)
+	}
+}
+
+func TestCreatedRange(t *testing.T) {
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"empty", day, day},
+		{"reversed", day, day.Add(-time.Hour)},
+		{"under a second", day, day.Add(time.Millisecond)},
+	}
+	for _, tt := range tests {
+		g := NewGenerator(1)
+		g.Start, g.End = tt.start, tt.end
+		for _, u := range g.Users(20) {
+			if !u.Created.Equal(day) {
+				t.Errorf("%s: created %s, want %s", tt.name, u.Created, day)
+			}
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name             string
+		minDur, maxDur   time.Duration
+		wantMin, wantMax time.Duration
+	}{
+		{"range", 10 * ms, 50 * ms, 10 * ms, 50 * ms},
+		{"equal", 5 * ms, 5 * ms, 5 * ms, 5 * ms},
+		{"reversed", 50 * ms, 10 * ms, 10 * ms, 50 * ms},
+		{"zero", 0, 0, 0, 0},
+		{"negative", -ms, 2 * ms, 0, 2 * ms},
+		{"both negative", -2 * ms, -ms, 0, 0},
+	}
+	for _, tt := range tests {
+		jobs := NewGenerator(5).Jobs(500, tt.minDur, tt.maxDur)
+		lo, hi := jobs[0].Duration, jobs[0].Duration
+		for _, j := range jobs {
+			lo, hi = min(lo, j.Duration), max(hi, j.Duration)
+			if j.Name == "" {
+				t.Errorf("%s: job %d has no name", tt.name, j.ID)
+			}
+		}
+		if lo < tt.wantMin || hi > tt.wantMax {
+			t.Errorf("%s: durations in [%s, %s], want within [%s, %s]", tt.name, lo, hi, tt.wantMin, tt.wantMax)
+		}
+	}
+}
+
+func TestIDsShared(t *testing.T) {
+	g := NewGenerator(1)
+	u := g.User()
+	s := g.Students(2)
+	j := g.Jobs(1, 0, time.Second)
+	if u.ID != 1 || s[0].StudentID != 2 || s[1].StudentID != 3 || j[0].ID != 4 {
+		t.Errorf("IDs %d %d %d %d", u.ID, s[0].StudentID, s[1].StudentID, j[0].ID)
+	}
+}
+
+func BenchmarkUsers(b *testing.B) {
+	for b.Loop() {
+		NewGenerator(1).Users(1000)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// copyOptions holds the settings changed by CopyOption functions
+type copyOptions struct {
+	overwrite     bool
+	progress      func(copied, total int64)
+	progressEvery int64
+}
+
+// CopyOption is a "functional option": a function that changes the settings.
+// New options can be added later without changing CopyFile's signature.
+type CopyOption func(*copyOptions)
+
+// WithOverwrite lets CopyFile replace an existing destination file
+func WithOverwrite() CopyOption {
+	return func(o *copyOptions) { o.overwrite = true }
+}
+
+// WithProgress calls fn while copying (at most once per progress interval) and once at the end
+func WithProgress(fn func(copied, total int64)) CopyOption {
+	return func(o *copyOptions) { o.progress = fn }
+}
+
+// WithProgressEvery changes how many bytes are copied between two progress calls (default 1MB)
+func WithProgressEvery(n int64) CopyOption {
+	return func(o *copyOptions) {
+		if n > 0 {
+			o.progressEvery = n
+		}
+	}
+}
+
+// progressWriter counts the bytes going through it and reports them every `every` bytes
+type progressWriter struct {
+	w        io.Writer
+	total    int64
+	copied   int64
+	reported int64
+	every    int64
+	fn       func(copied, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.copied += int64(n)
+	if p.fn != nil && p.copied-p.reported >= p.every {
+		p.reported = p.copied
+		p.fn(p.copied, p.total)
+	}
+	return n, err
+}
+
+func newCopyOptions(opts []CopyOption) copyOptions {
+	o := copyOptions{progressEvery: 1 << 20}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// CopyFile copies src to dst keeping the permission bits of src.
+// It refuses to replace an existing dst unless WithOverwrite is given.
+// A failed copy never leaves a partial dst: a new dst is removed, and an existing one
+// is only replaced (by renaming a temp file over it) once the copy is complete.
+func CopyFile(dst, src string, opts ...CopyOption) (written int64, err error) {
+	o := newCopyOptions(opts)
+
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("copy %s: %w", src, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("copy %s: %w", src, err)
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("copy %s: not a regular file", src)
+	}
+
+	// copying a file onto itself with O_TRUNC would empty it before reading
+	if dstInfo, err := os.Stat(dst); err == nil && os.SameFile(info, dstInfo) {
+		return 0, fmt.Errorf("copy %s to %s: same file", src, dst)
+	}
+
+	// without overwrite: O_EXCL fails if dst exists. With overwrite: a temp file in dst's directory
+	// (rename only works inside one filesystem), so the old dst survives a failed copy
+	var out *os.File
+	if o.overwrite {
+		out, err = os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*.tmp")
+	} else {
+		out, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	}
+	if err != nil {
+		return 0, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	pw := &progressWriter{w: out, total: info.Size(), every: o.progressEvery, fn: o.progress}
+	written, err = io.Copy(pw, in)
+	if err != nil {
+		return written, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+	if o.progress != nil && pw.reported != written {
+		o.progress(written, info.Size()) // final call, so the bar always reaches 100%
+	}
+	// OpenFile's permission is reduced by the umask and ignored for existing files, so set it explicitly
+	if err = out.Chmod(info.Mode().Perm()); err != nil {
+		return written, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+	if err = out.Close(); err != nil {
+		return written, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+	}
+	if o.overwrite {
+		if err = os.Rename(out.Name(), dst); err != nil {
+			return written, fmt.Errorf("copy %s to %s: %w", src, dst, err)
+		}
+	}
+	return written, nil
+}
+
+// rename is a variable so the demo can pretend src and dst are on different devices
+var rename = os.Rename
+
+// MoveFile renames src to dst. os.Rename can't move files between filesystems
+// (the error is EXDEV, "invalid cross-device link"), in that case the file is copied and src deleted.
+// Like CopyFile it refuses to replace an existing dst unless WithOverwrite is given, on both paths:
+// os.Rename itself would silently replace it.
+func MoveFile(dst, src string, opts ...CopyOption) error {
+	if !newCopyOptions(opts).overwrite {
+		// a dst created between this check and the rename is still replaced, os has no "rename
+		// unless it exists"; the copy fallback's O_EXCL does catch it
+		if _, err := os.Lstat(dst); err == nil {
+			return fmt.Errorf("move %s to %s: %w", src, dst, fs.ErrExist)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("move %s to %s: %w", src, dst, err)
+		}
+	}
+	err := rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return fmt.Errorf("move %s to %s: %w", src, dst, err)
+	}
+	if _, err := CopyFile(dst, src, opts...); err != nil {
+		return fmt.Errorf("move %s to %s: %w", src, dst, err)
+	}
+	if err := os.Remove(src); err != nil {
+		return fmt.Errorf("move %s to %s: removing source: %w", src, dst, err)
+	}
+	return nil
+}
+
+// SHA256File hashes path in small chunks, so even huge files use little memory
+func SHA256File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("sha256 %s: %w", path, err)
+	}
+	defer file.Close()
+
+	h := sha256.New() // a hash.Hash is an io.Writer
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("sha256 %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// progressBar prints "[#####.....]  50%" on one line, \r moves back to the start of the line
+func progressBar(copied, total int64) {
+	const width = 30
+	percent := 100
+	if total > 0 {
+		percent = int(copied * 100 / total)
+	}
+	done := width * percent / 100
+	fmt.Printf("\r  [%s%s] %3d%% %5.1f MB", strings.Repeat("#", done), strings.Repeat(".", width-done), percent, float64(copied)/(1<<20))
+	if copied >= total {
+		fmt.Println()
+	}
+}
+
+func main() {
+	fmt.Println("Learning file copy, move and checksums in Go")
+
+	dir, err := os.MkdirTemp("", "copy-demo-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	// a generated 10MB file
+	src := filepath.Join(dir, "big.bin")
+	data := make([]byte, 10<<20)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	if err := os.WriteFile(src, data, 0o640); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	// 1. copy with a progress bar
+	dst := filepath.Join(dir, "copy.bin")
+	written, err := CopyFile(dst, src, WithProgress(progressBar))
+	fmt.Println("Copied bytes:", written, err)
+
+	srcSum, _ := SHA256File(src)
+	dstSum, _ := SHA256File(dst)
+	fmt.Println("Checksums match:", srcSum == dstSum, srcSum[:16]+"...")
+	againSum, _ := SHA256File(src)
+	fmt.Println("Checksum is stable:", srcSum == againSum)
+	if info, err := os.Stat(dst); err == nil {
+		fmt.Println("Mode preserved:", info.Mode().Perm())
+	}
+
+	// 2. dst exists: refused without WithOverwrite
+	_, err = CopyFile(dst, src)
+	fmt.Println("Without overwrite:", err, "| is ErrExist:", errors.Is(err, fs.ErrExist))
+	calls := 0
+	_, err = CopyFile(dst, src, WithOverwrite(), WithProgressEvery(4<<20), WithProgress(func(copied, total int64) { calls++ }))
+	fmt.Println("With overwrite:", err, "| progress calls for 10MB every 4MB:", calls)
+	_, err = CopyFile(src, src, WithOverwrite())
+	fmt.Println("Onto itself:", err)
+
+	// 3. move: same filesystem is a plain rename
+	moved := filepath.Join(dir, "moved.bin")
+	fmt.Println("Move (rename):", MoveFile(moved, dst))
+	fmt.Println("Move onto an existing file:", MoveFile(src, moved))
+
+	// 4. pretend the rename crossed devices, to force the copy + delete path
+	rename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	crossed := filepath.Join(dir, "crossed.bin")
+	err = MoveFile(crossed, moved)
+	rename = os.Rename
+	_, statErr := os.Stat(moved)
+	crossedSum, _ := SHA256File(crossed)
+	fmt.Println("Move (copy fallback):", err, "| source removed:", errors.Is(statErr, fs.ErrNotExist), "| same content:", crossedSum == srcSum)
+
+	_, err = CopyFile(filepath.Join(dir, "x"), filepath.Join(dir, "missing.bin"))
+	fmt.Println("Missing source:", err)
+}
+
+// io.Copy streams with a 32KB buffer, so copying or hashing a 10GB file doesn't need 10GB of memory.
+// Wrapping an io.Writer (progressWriter) is an easy way to watch the data flowing through.
+// os.Rename is atomic but only inside one filesystem, moving across devices means copy + delete.
+// Functional options (opts ...CopyOption) keep the common call short: CopyFile(dst, src).
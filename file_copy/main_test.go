@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, data []byte, perm os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, data, perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil { // WriteFile's mode is reduced by the umask
+		t.Fatal(err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src.sh"), filepath.Join(dir, "dst.sh")
+	data := bytes.Repeat([]byte("gopher\n"), 1000)
+	writeFile(t, src, data, 0o750)
+
+	n, err := CopyFile(dst, src)
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("CopyFile = %d, %v", n, err)
+	}
+	got, _ := os.ReadFile(dst)
+	if !bytes.Equal(got, data) {
+		t.Error("the copy differs from the source")
+	}
+	if info, _ := os.Stat(dst); info.Mode().Perm() != 0o750 {
+		t.Errorf("mode = %v, want 0750", info.Mode().Perm())
+	}
+}
+
+func TestCopyFileOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeFile(t, src, []byte("new"), 0o644)
+	writeFile(t, dst, []byte("old content"), 0o600)
+
+	if _, err := CopyFile(dst, src); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("without WithOverwrite: %v, want fs.ErrExist", err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "old content" {
+		t.Fatalf("a refused copy changed dst to %q", got)
+	}
+
+	if _, err := CopyFile(dst, src, WithOverwrite()); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(dst)
+	info, _ := os.Stat(dst)
+	if string(got) != "new" || info.Mode().Perm() != 0o644 {
+		t.Errorf("after overwrite: %q with mode %v", got, info.Mode().Perm())
+	}
+
+	// onto itself, even with overwrite, must not truncate the file
+	if _, err := CopyFile(src, src, WithOverwrite()); err == nil {
+		t.Error("copying a file onto itself succeeded")
+	}
+	if got, _ := os.ReadFile(src); string(got) != "new" {
+		t.Errorf("copying onto itself changed the file to %q", got)
+	}
+}
+
+// a copy that fails halfway leaves the old dst as it was, and no temp file next to it
+func TestCopyFileOverwriteFailure(t *testing.T) {
+	const src = "/proc/self/mem" // a regular file whose first read fails with EIO
+	if _, err := os.Stat(src); err != nil {
+		t.Skip("needs /proc")
+	}
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "dst")
+	writeFile(t, dst, []byte("old content"), 0o600)
+	if _, err := CopyFile(dst, src, WithOverwrite()); err == nil {
+		t.Fatal("copying /proc/self/mem succeeded")
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "old content" {
+		t.Errorf("a failed overwrite changed dst to %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("left behind: %v", entries)
+	}
+}
+
+func TestCopyFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := CopyFile(filepath.Join(dir, "dst"), filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing source: %v", err)
+	}
+	if _, err := CopyFile(filepath.Join(dir, "dst"), dir); err == nil {
+		t.Error("copying a directory succeeded")
+	}
+}
+
+func TestCopyFileProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "big")
+	const size = 10<<20 + 123 // not a multiple of the interval, so there is a final call
+	writeFile(t, src, bytes.Repeat([]byte{'x'}, size), 0o644)
+
+	var calls []int64
+	n, err := CopyFile(filepath.Join(dir, "copy"), src, WithProgressEvery(1<<20), WithProgress(func(copied, total int64) {
+		if total != size {
+			t.Errorf("total = %d, want %d", total, size)
+		}
+		calls = append(calls, copied)
+	}))
+	if err != nil || n != size {
+		t.Fatalf("CopyFile = %d, %v", n, err)
+	}
+	// at most one call per MB, plus the final one at 100%
+	if len(calls) < 2 || len(calls) > size/(1<<20)+1 {
+		t.Errorf("%d progress calls for %d MB", len(calls), size>>20)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i]-calls[i-1] < 1<<20 && i != len(calls)-1 {
+			t.Errorf("calls %d and %d are only %d bytes apart", i-1, i, calls[i]-calls[i-1])
+		}
+	}
+	if calls[len(calls)-1] != size {
+		t.Errorf("last progress call at %d, want %d", calls[len(calls)-1], size)
+	}
+
+	// small file, default interval: just the final call
+	small := filepath.Join(dir, "small")
+	writeFile(t, small, []byte("hi"), 0o644)
+	calls = nil
+	if _, err := CopyFile(filepath.Join(dir, "small-copy"), small, WithProgress(func(c, _ int64) { calls = append(calls, c) })); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[0] != 2 {
+		t.Errorf("small file progress calls = %v, want [2]", calls)
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeFile(t, src, []byte("payload"), 0o640)
+	if err := MoveFile(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("source still exists: %v", err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "payload" {
+		t.Errorf("dst = %q", got)
+	}
+}
+
+// an existing dst is refused without WithOverwrite and replaced with it,
+// the same whether the move is a rename or a copy
+func TestMoveFileOntoExisting(t *testing.T) {
+	for _, crossDevice := range []bool{false, true} {
+		if crossDevice {
+			rename = func(oldpath, newpath string) error {
+				return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+			}
+			t.Cleanup(func() { rename = os.Rename })
+		}
+		dir := t.TempDir()
+		src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+		writeFile(t, src, []byte("new"), 0o600)
+		writeFile(t, dst, []byte("old"), 0o600)
+
+		if err := MoveFile(dst, src); !errors.Is(err, fs.ErrExist) {
+			t.Errorf("cross device %v, without overwrite: %v, want fs.ErrExist", crossDevice, err)
+		}
+		if got, _ := os.ReadFile(dst); string(got) != "old" {
+			t.Errorf("cross device %v: a refused move changed dst to %q", crossDevice, got)
+		}
+		if _, err := os.Stat(src); err != nil {
+			t.Errorf("cross device %v: a refused move removed src: %v", crossDevice, err)
+		}
+
+		if err := MoveFile(dst, src, WithOverwrite()); err != nil {
+			t.Fatalf("cross device %v, with overwrite: %v", crossDevice, err)
+		}
+		if got, _ := os.ReadFile(dst); string(got) != "new" {
+			t.Errorf("cross device %v: dst = %q after overwrite", crossDevice, got)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+			t.Errorf("cross device %v: left behind %v", crossDevice, entries)
+		}
+	}
+}
+
+func TestMoveFileCrossDevice(t *testing.T) {
+	// pretend src and dst are on different filesystems, so the copy path runs
+	renames := 0
+	rename = func(oldpath, newpath string) error {
+		renames++
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	t.Cleanup(func() { rename = os.Rename })
+
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	writeFile(t, src, []byte("across devices"), 0o600)
+	if err := MoveFile(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	if renames != 1 {
+		t.Errorf("rename called %d times", renames)
+	}
+	if _, err := os.Stat(src); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("source still exists after the copy fallback: %v", err)
+	}
+	got, _ := os.ReadFile(dst)
+	info, _ := os.Stat(dst)
+	if string(got) != "across devices" || info.Mode().Perm() != 0o600 {
+		t.Errorf("dst = %q with mode %v", got, info.Mode().Perm())
+	}
+
+	// any other rename error is returned as is, no copy
+	rename = func(string, string) error { return fs.ErrPermission }
+	writeFile(t, src, []byte("x"), 0o600)
+	if err := MoveFile(filepath.Join(dir, "other"), src); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("got %v, want fs.ErrPermission", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other")); !errors.Is(err, fs.ErrNotExist) {
+		t.Error("a non-EXDEV rename error still copied the file")
+	}
+}
+
+func TestSHA256File(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data")
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	writeFile(t, path, data, 0o644)
+
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+	for range 3 { // stable across calls
+		if got, err := SHA256File(path); err != nil || got != want {
+			t.Fatalf("SHA256File = %s, %v; want %s", got, err, want)
+		}
+	}
+
+	empty := filepath.Join(dir, "empty")
+	writeFile(t, empty, nil, 0o644)
+	if got, _ := SHA256File(empty); got != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("empty file = %s", got)
+	}
+
+	copied := filepath.Join(dir, "copy")
+	if _, err := CopyFile(copied, path); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := SHA256File(copied); got != want {
+		t.Error("the copy hashes differently")
+	}
+	if _, err := SHA256File(filepath.Join(dir, "missing")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: %v", err)
+	}
+}
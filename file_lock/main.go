@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithTempFile creates a temp file, passes it to fn and always closes and deletes it,
+// even when fn returns an error or panics (deferred calls run while a panic unwinds).
+func WithTempFile(pattern string, fn func(f *os.File) error) error {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return fmt.Errorf("temp file %s: %w", pattern, err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close() // defers run last-in first-out: close, then remove
+	return fn(f)
+}
+
+// lockCounter makes every lock token unique, even for FileMutexes in the same process
+var lockCounter atomic.Int64
+
+// FileMutex is an advisory lock between processes built on a lock file.
+// Creating a file with O_CREATE|O_EXCL is atomic: exactly one caller succeeds, the others get ErrExist.
+// The file holds "<pid> <unix nano> <counter>" so a lock left behind by a crashed process
+// can be recognised by its age and stolen.
+// Stealing and unlocking are serialised by a second, short-lived guard file (Path + ".guard").
+type FileMutex struct {
+	Path         string
+	StaleAfter   time.Duration // a lock older than this is considered abandoned, 0 = never
+	PollInterval time.Duration // how often Lock retries, defaults to 10ms
+	Warn         func(msg string)
+
+	token string // content we wrote, empty when not held
+}
+
+// NewFileMutex returns a FileMutex for path with stale locks stolen after staleAfter
+func NewFileMutex(path string, staleAfter time.Duration) *FileMutex {
+	return &FileMutex{Path: path, StaleAfter: staleAfter, PollInterval: 10 * time.Millisecond}
+}
+
+// Lock blocks until the lock is taken or ctx is done
+func (m *FileMutex) Lock(ctx context.Context) error {
+	if m.token != "" {
+		return fmt.Errorf("lock %s: already held", m.Path)
+	}
+	poll := m.PollInterval
+	if poll <= 0 {
+		poll = 10 * time.Millisecond
+	}
+	for {
+		token := newLockToken()
+		err := m.tryCreate(token)
+		if err == nil {
+			m.token = token
+			return nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("lock %s: %w", m.Path, err)
+		}
+		if m.stealIfStale(ctx) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lock %s: %w", m.Path, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+// Unlock removes the lock file, but only if it is still ours (it may have been stolen).
+// The check and the remove happen under the guard, so a stealer can't swap the file in between.
+func (m *FileMutex) Unlock() error {
+	if m.token == "" {
+		return fmt.Errorf("unlock %s: not held", m.Path)
+	}
+	token := m.token
+	m.token = ""
+	release, err := m.acquireGuard(context.Background())
+	if err != nil {
+		return fmt.Errorf("unlock %s: %w", m.Path, err)
+	}
+	current, err := os.ReadFile(m.Path)
+	switch {
+	case err != nil:
+	case string(current) != token:
+		err = errors.New("lock was stolen")
+	default:
+		err = os.Remove(m.Path)
+	}
+	if relErr := release(); err == nil {
+		err = relErr
+	}
+	if err != nil {
+		return fmt.Errorf("unlock %s: %w", m.Path, err)
+	}
+	return nil
+}
+
+// newLockToken is "<pid> <unix nano> <counter>", unique across processes and FileMutexes,
+// so comparing the content of the lock file tells whose lock it is
+func newLockToken() string {
+	return fmt.Sprintf("%d %d %d", os.Getpid(), time.Now().UnixNano(), lockCounter.Add(1))
+}
+
+func (m *FileMutex) tryCreate(token string) error {
+	return createLockFile(m.Path, token)
+}
+
+// createLockFile creates path with O_EXCL and writes token into it
+func createLockFile(path, token string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(token); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// readLockFile returns the content of a lock file and when it was taken. A crash between
+// the O_EXCL create and the write leaves an empty file, and a waiter may read one being
+// written: without a valid token the file's modification time is the age, and pid is 0.
+// Content and time come from the same open file, so they describe the same lock.
+func readLockFile(path string) (content string, pid int, created time.Time, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, time.Time{}, err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return "", 0, time.Time{}, err
+	}
+	pid, created, ok := parseLockToken(string(b))
+	if !ok {
+		info, err := f.Stat()
+		if err != nil {
+			return "", 0, time.Time{}, err
+		}
+		pid, created = 0, info.ModTime()
+	}
+	return string(b), pid, created, nil
+}
+
+// replaceLockFile puts token in place of the lock file at path, but only if it still holds
+// stale, the lock judged to be abandoned (same content and same age, an empty file is only
+// told apart from another by its age). The new token is renamed over the old file instead
+// of removing it first: the path always names a lock, so no O_EXCL creator can slip in.
+func replaceLockFile(path, stale string, staleCreated time.Time, token string) bool {
+	current, _, created, err := readLockFile(path)
+	if err != nil || current != stale || !created.Equal(staleCreated) {
+		return false
+	}
+	tmp := fmt.Sprintf("%s.steal-%d-%d", path, os.Getpid(), lockCounter.Add(1))
+	if err := os.WriteFile(tmp, []byte(token), 0o644); err != nil {
+		os.Remove(tmp)
+		return false
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return false
+	}
+	return true
+}
+
+// guardStaleAfter: the guard is only held around a read and a rename or remove,
+// one that is older than this was left by a process that crashed in between
+const guardStaleAfter = time.Second
+
+// guardSettle is how long a waiter that took over a stale guard waits before checking it still has it
+const guardSettle = time.Millisecond
+
+var errGuardLost = errors.New("guard was taken over while held")
+
+// acquireGuard takes a second lock file next to the lock. Stealing and unlocking both
+// replace or remove a lock file someone else may be looking at, so they take turns.
+// Creating a lock with O_EXCL doesn't need the guard, it only succeeds when there is no file.
+// The guard holds a token like the lock, a stale one is taken over the same way, and
+// release reports errGuardLost when a holder paused for longer than guardStaleAfter
+// finds its guard was taken over.
+func (m *FileMutex) acquireGuard(ctx context.Context) (release func() error, err error) {
+	guard := m.Path + ".guard"
+	for {
+		token := newLockToken()
+		err := createLockFile(guard, token)
+		if err == nil {
+			return guardRelease(guard, token), nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if stale, _, created, err := readLockFile(guard); err == nil && time.Since(created) > guardStaleAfter {
+			// read back a moment after the rename: of two waiters that replaced the same
+			// stale guard at once only the last one finds its own token
+			if replaceLockFile(guard, stale, created, token) {
+				time.Sleep(guardSettle)
+				if current, _, _, err := readLockFile(guard); err == nil && current == token {
+					return guardRelease(guard, token), nil
+				}
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+// guardRelease removes the guard only while it still holds token
+func guardRelease(guard, token string) func() error {
+	return func() error {
+		current, _, _, err := readLockFile(guard)
+		if err != nil || current != token {
+			return errGuardLost
+		}
+		return os.Remove(guard)
+	}
+}
+
+// stealIfStale takes over the lock when its file is older than StaleAfter and
+// reports whether it did
+func (m *FileMutex) stealIfStale(ctx context.Context) bool {
+	if m.StaleAfter <= 0 {
+		return false
+	}
+	content, pid, created, err := readLockFile(m.Path)
+	if err != nil || time.Since(created) < m.StaleAfter {
+		return false // gone already, or still fresh: try again on the next poll
+	}
+	if !m.takeOver(ctx, content, created) {
+		return false
+	}
+	warn := m.Warn
+	if warn == nil {
+		warn = func(msg string) { fmt.Fprintln(os.Stderr, "warning:", msg) }
+	}
+	holder := fmt.Sprintf("pid %d", pid)
+	if pid == 0 {
+		holder = "a crashed locker (no token in the file)"
+	}
+	warn(fmt.Sprintf("stole stale lock %s held by %s since %s", m.Path, holder, created.Format(time.TimeOnly)))
+	return true
+}
+
+// takeOver replaces the lock file with our own, but only if it is still the lock judged
+// to be abandoned. Between that judgement and now the lock may have been stolen, released
+// or taken anew, and a fresh lock must never be touched.
+func (m *FileMutex) takeOver(ctx context.Context, stale string, staleCreated time.Time) bool {
+	release, err := m.acquireGuard(ctx)
+	if err != nil {
+		return false
+	}
+	token := newLockToken()
+	replaced := replaceLockFile(m.Path, stale, staleCreated, token)
+	if err := release(); err != nil {
+		// someone else held the guard too and may have replaced the lock as well:
+		// the lock is only ours if it still holds our token
+		if current, _, _, err := readLockFile(m.Path); err != nil || current != token {
+			return false
+		}
+	}
+	if replaced {
+		m.token = token
+	}
+	return replaced
+}
+
+func parseLockToken(s string) (pid int, created time.Time, ok bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 {
+		return 0, time.Time{}, false
+	}
+	pid, err1 := strconv.Atoi(fields[0])
+	nanos, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, time.Time{}, false
+	}
+	return pid, time.Unix(0, nanos), true
+}
+
+// appendLineSlowly writes a line in several small writes, so without a lock
+// lines from different writers could be mixed together
+func appendLineSlowly(path, line string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, word := range strings.Fields(line) {
+		if _, err := f.WriteString(word + " "); err != nil {
+			f.Close()
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := f.WriteString("\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func main() {
+	fmt.Println("Learning temp files and file locking in Go")
+
+	// 1. temp file cleaned up after an error and after a panic
+	var tempName string
+	err := WithTempFile("demo-*.txt", func(f *os.File) error {
+		tempName = f.Name()
+		f.WriteString("scratch data")
+		return errors.New("something went wrong")
+	})
+	_, statErr := os.Stat(tempName)
+	fmt.Println("After error:", err, "| removed:", errors.Is(statErr, fs.ErrNotExist))
+
+	func() {
+		defer func() { fmt.Println("Recovered panic:", recover()) }()
+		WithTempFile("demo-*.txt", func(f *os.File) error {
+			tempName = f.Name()
+			panic("boom")
+		})
+	}()
+	_, statErr = os.Stat(tempName)
+	fmt.Println("After panic removed:", errors.Is(statErr, fs.ErrNotExist))
+
+	dir, err := os.MkdirTemp("", "lock-demo-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	lockPath := filepath.Join(dir, "app.lock")
+	logPath := filepath.Join(dir, "app.log")
+
+	// 2. 5 writers, each with its own FileMutex as if they were separate processes
+	var wg sync.WaitGroup
+	var inside, maxInside atomic.Int32
+	for w := 1; w <= 5; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			m := NewFileMutex(lockPath, time.Minute)
+			for i := 1; i <= 4; i++ {
+				if err := m.Lock(context.Background()); err != nil {
+					fmt.Println("Error:", err)
+					return
+				}
+				n := inside.Add(1)
+				for cur := maxInside.Load(); n > cur && !maxInside.CompareAndSwap(cur, n); cur = maxInside.Load() {
+				}
+				writeErr := appendLineSlowly(logPath, fmt.Sprintf("writer %d line %d end", w, i))
+				inside.Add(-1)
+				if err := m.Unlock(); err != nil {
+					fmt.Println("Error:", err)
+				}
+				if writeErr != nil {
+					fmt.Println("Error:", writeErr)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	lines, broken := 0, 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines++
+		if len(strings.Fields(scanner.Text())) != 5 {
+			broken++
+		}
+	}
+	f.Close()
+	fmt.Printf("Log has %d lines, %d mixed up, max writers holding the lock at once: %d\n", lines, broken, maxInside.Load())
+
+	// 3. waiting for a held lock gives up when the context expires
+	holder := NewFileMutex(lockPath, time.Minute)
+	holder.Lock(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err = NewFileMutex(lockPath, time.Minute).Lock(ctx)
+	cancel()
+	fmt.Println("Lock while held:", err, "| is DeadlineExceeded:", errors.Is(err, context.DeadlineExceeded))
+	holder.Unlock()
+
+	// 4. a crashed process left its lock behind 10 minutes ago
+	old := fmt.Sprintf("%d %d 1", 99999, time.Now().Add(-10*time.Minute).UnixNano())
+	if err := os.WriteFile(lockPath, []byte(old), 0o644); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	m := NewFileMutex(lockPath, 5*time.Minute)
+	m.Warn = func(msg string) { fmt.Println("Warning:", msg) }
+	start := time.Now()
+	err = m.Lock(context.Background())
+	fmt.Println("Lock after stale holder:", err, "in", time.Since(start).Round(time.Millisecond))
+	fmt.Println("Unlock:", m.Unlock())
+
+	// 5. a lock younger than StaleAfter is respected
+	holder.Lock(context.Background())
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	fmt.Println("Fresh lock not stolen:", m.Lock(ctx))
+	cancel()
+	holder.Unlock()
+}
+
+// O_CREATE|O_EXCL is the portable building block for lock files: create fails if the file exists.
+// Advisory means only programs that use the same lock respect it, nothing stops other writers.
+// A crashed holder never deletes its lock file, so store who/when inside it and steal old locks.
+// On Unix, syscall.Flock is an alternative that the OS releases automatically when the process dies.
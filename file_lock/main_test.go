@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithTempFileCleansUp(t *testing.T) {
+	var name string
+	err := WithTempFile("test-*.txt", func(f *os.File) error {
+		name = f.Name()
+		return errors.New("fn failed")
+	})
+	if err == nil || err.Error() != "fn failed" {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := os.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("after an error the file is still there: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the panic was swallowed")
+			}
+		}()
+		WithTempFile("test-*.txt", func(f *os.File) error {
+			name = f.Name()
+			panic("boom")
+		})
+	}()
+	if _, err := os.Stat(name); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("after a panic the file is still there: %v", err)
+	}
+}
+
+// every locker has its own FileMutex, as if they were separate processes
+func TestFileMutexMutualExclusion(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	var inside, overlaps, total atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			m := NewFileMutex(lockPath, time.Minute)
+			m.PollInterval = time.Millisecond
+			for range 10 {
+				if err := m.Lock(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+				if inside.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(100 * time.Microsecond)
+				total.Add(1)
+				inside.Add(-1)
+				if err := m.Unlock(); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	if overlaps.Load() != 0 || total.Load() != 80 {
+		t.Fatalf("%d overlaps in %d critical sections", overlaps.Load(), total.Load())
+	}
+	if _, err := os.Stat(lockPath); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+}
+
+func TestFileMutexMisuse(t *testing.T) {
+	m := NewFileMutex(filepath.Join(t.TempDir(), "app.lock"), 0)
+	if err := m.Unlock(); err == nil {
+		t.Error("Unlock without Lock succeeded")
+	}
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Lock(context.Background()); err == nil || !strings.Contains(err.Error(), "already held") {
+		t.Errorf("second Lock: %v", err)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeStaleLock(t *testing.T, path string, age time.Duration) string {
+	t.Helper()
+	token := fmt.Sprintf("%d %d 1", 99999, time.Now().Add(-age).UnixNano())
+	if err := os.WriteFile(path, []byte(token), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestFileMutexStaleRecovery(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	writeStaleLock(t, lockPath, 10*time.Minute)
+
+	// StaleAfter 0: never stolen
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewFileMutex(lockPath, 0).Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("StaleAfter 0: %v, want DeadlineExceeded", err)
+	}
+	// younger than StaleAfter: respected
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewFileMutex(lockPath, time.Hour).Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("fresh enough lock: %v, want DeadlineExceeded", err)
+	}
+
+	var warnings []string
+	m := NewFileMutex(lockPath, 5*time.Minute)
+	m.Warn = func(msg string) { warnings = append(warnings, msg) }
+	if err := m.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "pid 99999") {
+		t.Errorf("warnings = %q", warnings)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(lockPath))
+	if len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
+
+// many waiters find the same stale lock at once: exactly one of them may take it over
+func TestFileMutexStaleStolenOnce(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	writeStaleLock(t, lockPath, time.Hour)
+	var steals, holding, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			m := NewFileMutex(lockPath, time.Minute)
+			m.PollInterval = time.Millisecond
+			m.Warn = func(string) { steals.Add(1) }
+			if err := m.Lock(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			if holding.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			time.Sleep(time.Millisecond)
+			holding.Add(-1)
+			if err := m.Unlock(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if steals.Load() != 1 || overlaps.Load() != 0 {
+		t.Fatalf("%d steals, %d overlaps; want 1 and 0", steals.Load(), overlaps.Load())
+	}
+}
+
+// the stale lock was replaced by a fresh one after we judged it stale:
+// taking over must leave the fresh lock alone
+func TestTakeOverLeavesFreshLockAlone(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	stale := writeStaleLock(t, lockPath, time.Hour)
+
+	fresh := NewFileMutex(lockPath, time.Minute)
+	thief := NewFileMutex(lockPath, time.Minute)
+	thief.Warn = func(string) {}
+	if err := thief.Lock(context.Background()); err != nil { // takes over the stale lock
+		t.Fatal(err)
+	}
+	if err := thief.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	late := NewFileMutex(lockPath, time.Minute)
+	_, staleCreated, _ := parseLockToken(stale)
+	if late.takeOver(context.Background(), stale, staleCreated) {
+		t.Fatal("took over a lock that is no longer the stale one")
+	}
+	if err := fresh.Unlock(); err != nil {
+		t.Fatalf("the fresh holder lost its lock: %v", err)
+	}
+}
+
+// a slow holder whose lock was stolen must not remove the thief's lock on Unlock
+func TestUnlockAfterStealKeepsThiefLock(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	slow := NewFileMutex(lockPath, 0)
+	if err := slow.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	thief := NewFileMutex(lockPath, time.Nanosecond) // every lock looks stale to it
+	thief.Warn = func(string) {}
+	time.Sleep(time.Millisecond)
+	if err := thief.Lock(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := slow.Unlock(); err == nil || !strings.Contains(err.Error(), "stolen") {
+		t.Fatalf("slow Unlock: %v, want a stolen error", err)
+	}
+	if err := thief.Unlock(); err != nil {
+		t.Fatalf("the thief lost its lock: %v", err)
+	}
+}
+
+func TestAcquireGuardStale(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	guard := lockPath + ".guard"
+	if err := os.WriteFile(guard, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewFileMutex(lockPath, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := m.acquireGuard(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("a fresh guard: %v, want DeadlineExceeded", err)
+	}
+
+	old := time.Now().Add(-2 * guardStaleAfter)
+	if err := os.Chtimes(guard, old, old); err != nil {
+		t.Fatal(err)
+	}
+	release, err := m.acquireGuard(context.Background())
+	if err != nil {
+		t.Fatalf("a crashed holder's guard: %v", err)
+	}
+	release()
+	if _, err := os.Stat(guard); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("guard left behind: %v", err)
+	}
+}
+
+// a crash between the O_EXCL create and the write leaves an empty lock file:
+// its age comes from the file's modification time
+func TestFileMutexEmptyLockFile(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	if err := os.WriteFile(lockPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// just created, maybe still being written: respected
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewFileMutex(lockPath, time.Minute).Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("a fresh empty lock: %v, want DeadlineExceeded", err)
+	}
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	m := NewFileMutex(lockPath, time.Minute)
+	m.Warn = func(msg string) { warnings = append(warnings, msg) }
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Lock(ctx); err != nil {
+		t.Fatalf("an old empty lock: %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "no token") {
+		t.Errorf("warnings = %q", warnings)
+	}
+	if err := m.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// a holder paused past guardStaleAfter has its guard taken over, and finds out on release
+func TestGuardTakenOverFromPausedHolder(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	guard := lockPath + ".guard"
+	paused := writeStaleLock(t, guard, 2*guardStaleAfter)
+
+	m := NewFileMutex(lockPath, time.Minute)
+	release, err := m.acquireGuard(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := guardRelease(guard, paused)(); !errors.Is(err, errGuardLost) {
+		t.Errorf("the paused holder's release: %v, want errGuardLost", err)
+	}
+	if _, err := os.Stat(guard); err != nil {
+		t.Fatalf("the paused holder removed the new guard: %v", err)
+	}
+	if err := release(); err != nil {
+		t.Errorf("release: %v", err)
+	}
+	if _, err := os.Stat(guard); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("guard left behind: %v", err)
+	}
+}
+
+// many waiters find the same stale guard: they still hold it one at a time
+func TestStaleGuardTakenOverOnce(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "app.lock")
+	writeStaleLock(t, lockPath+".guard", 2*guardStaleAfter)
+	var holding, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			release, err := NewFileMutex(lockPath, time.Minute).acquireGuard(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if holding.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			time.Sleep(2 * time.Millisecond)
+			holding.Add(-1)
+			if err := release(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if overlaps.Load() != 0 {
+		t.Fatalf("%d overlaps", overlaps.Load())
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// FindOptions filters the files returned by FindFiles. Zero values mean "no filter".
+type FindOptions struct {
+	Pattern       string          // glob matched against the file name, e.g. "*_test.go"
+	Extensions    []string        // e.g. []string{".go", ".md"}, case-insensitive
+	MinSize       int64           // bytes
+	MaxSize       int64           // bytes, 0 = no limit
+	ModifiedAfter time.Time       // only files changed after this time
+	MaxDepth      int             // 1 = only files directly in root, 0 = no limit
+	Ignore        map[string]bool // directory names to skip entirely, like ".git"
+}
+
+// FileMatch is one file found by FindFiles
+type FileMatch struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// WalkError collects the problems hit during a walk (unreadable directories, ...)
+// so one bad entry does not abort the whole search
+type WalkError struct {
+	Errors []error
+}
+
+func (e *WalkError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d error(s) while walking: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+func (e *WalkError) Unwrap() []error {
+	return e.Errors
+}
+
+// FindFiles walks root and returns the files matching opts.
+// filepath.WalkDir does not follow symlinks, so a symlink loop can't make it run forever.
+// When some entries could not be read, the matches found so far are returned
+// together with a *WalkError.
+func FindFiles(root string, opts FindOptions) ([]FileMatch, error) {
+	if opts.Pattern != "" {
+		if _, err := filepath.Match(opts.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("find files: bad pattern %q: %w", opts.Pattern, err)
+		}
+	}
+
+	var matches []FileMatch
+	var walkErrs []error
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err // root itself is unusable, nothing to collect
+			}
+			walkErrs = append(walkErrs, err)
+			return nil // keep walking the rest of the tree
+		}
+
+		depth := 0
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." {
+			depth = strings.Count(rel, string(filepath.Separator)) + 1
+		}
+
+		if d.IsDir() {
+			if path != root && opts.Ignore[d.Name()] {
+				return filepath.SkipDir
+			}
+			if opts.MaxDepth > 0 && depth >= opts.MaxDepth {
+				return filepath.SkipDir // files inside would be deeper than allowed
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil // symlinks, sockets, devices...
+		}
+
+		if opts.Pattern != "" {
+			if ok, _ := filepath.Match(opts.Pattern, d.Name()); !ok {
+				return nil
+			}
+		}
+		if len(opts.Extensions) > 0 && !hasExtension(d.Name(), opts.Extensions) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			walkErrs = append(walkErrs, err)
+			return nil
+		}
+		if info.Size() < opts.MinSize || (opts.MaxSize > 0 && info.Size() > opts.MaxSize) {
+			return nil
+		}
+		if !opts.ModifiedAfter.IsZero() && !info.ModTime().After(opts.ModifiedAfter) {
+			return nil
+		}
+		matches = append(matches, FileMatch{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("find files in %s: %w", root, err)
+	}
+	if len(walkErrs) > 0 {
+		return matches, &WalkError{Errors: walkErrs}
+	}
+	return matches, nil
+}
+
+func hasExtension(name string, exts []string) bool {
+	ext := filepath.Ext(name)
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSyntheticTree creates nested dirs, a symlink loop and an unreadable directory
+func buildSyntheticTree(root string) error {
+	files := map[string]string{
+		"main.go":             "package main",
+		"README.md":           "# readme",
+		"pkg/util.go":         "package pkg // a bit longer file",
+		"pkg/deep/deeper.go":  "package deep",
+		".git/config":         "[core]",
+		"locked/secret.go":    "package locked",
+		"pkg/deep/notes.TXT":  "notes",
+		"pkg/deep/empty.go":   "",
+		"assets/logo.png.bak": "binary",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	// pkg/deep/loop -> pkg: following it would loop forever
+	if err := os.Symlink(filepath.Join(root, "pkg"), filepath.Join(root, "pkg", "deep", "loop")); err != nil {
+		return err
+	}
+	return os.Chmod(filepath.Join(root, "locked"), 0o000)
+}
+
+// Table aligns columns with text/tabwriter, a shorter version of the Table in the textutil
+// lesson (lessons are separate main packages, so it can't be imported)
+type Table struct {
+	headers  []string
+	rows     [][]string
+	MaxWidth int // longer cells are cut and end with "…", 0 = no limit
+}
+
+func (t *Table) SetHeaders(headers ...string) {
+	t.headers = headers
+}
+
+// AddRow adds one row, any value is formatted with %v
+func (t *Table) AddRow(values ...any) {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = fmt.Sprint(v)
+		if n := []rune(row[i]); t.MaxWidth > 0 && len(n) > t.MaxWidth {
+			row[i] = string(n[:t.MaxWidth-1]) + "…"
+		}
+	}
+	t.rows = append(t.rows, row)
+}
+
+// Render writes the headers, a line of dashes and the rows to w
+func (t *Table) Render(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(t.headers) > 0 {
+		fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+		dashes := make([]string, len(t.headers))
+		for i, h := range t.headers {
+			dashes[i] = strings.Repeat("-", max(utf8.RuneCountInString(h), 3))
+		}
+		fmt.Fprintln(tw, strings.Join(dashes, "\t"))
+	}
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func printTable(matches []FileMatch, root string) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Size != matches[j].Size {
+			return matches[i].Size > matches[j].Size
+		}
+		return matches[i].Path < matches[j].Path
+	})
+	table := &Table{MaxWidth: 50}
+	table.SetHeaders("FILE", "BYTES", "MODIFIED")
+	var total int64
+	for _, m := range matches {
+		rel, err := filepath.Rel(root, m.Path)
+		if err != nil {
+			rel = m.Path
+		}
+		table.AddRow(rel, m.Size, m.ModTime.Format("2006-01-02 15:04"))
+		total += m.Size
+	}
+	table.Render(os.Stdout)
+	fmt.Printf("%d files, %d bytes\n", len(matches), total)
+}
+
+func main() {
+	root := flag.String("root", "..", "directory to search for .go files")
+	flag.Parse()
+
+	fmt.Println("Learning directory walking with filepath.WalkDir")
+
+	// 1. all .go files in the repository, biggest first
+	// a *WalkError means the walk finished with some entries skipped, whether or not
+	// anything matched; any other error means there are no results at all
+	matches, err := FindFiles(*root, FindOptions{Extensions: []string{".go"}, Ignore: map[string]bool{".git": true, ".idea": true}})
+	var walkErr *WalkError
+	if err != nil && !errors.As(err, &walkErr) {
+		fmt.Println("Error:", err)
+		return
+	}
+	if walkErr != nil {
+		fmt.Println("Some entries could not be read:", walkErr)
+	}
+	fmt.Println("Go files in", *root)
+	printTable(matches, *root)
+
+	// 2. a synthetic tree with the tricky cases
+	dir, err := os.MkdirTemp("", "find-demo-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	defer os.Chmod(filepath.Join(dir, "locked"), 0o755) // runs first, so RemoveAll can delete it
+	if err := buildSyntheticTree(dir); err != nil {
+		fmt.Println("Error building tree:", err)
+		return
+	}
+
+	searches := []struct {
+		name string
+		opts FindOptions
+	}{
+		{"everything except .git", FindOptions{Ignore: map[string]bool{".git": true}}},
+		{"*.go with at least 1 byte", FindOptions{Pattern: "*.go", MinSize: 1}},
+		{"max depth 2", FindOptions{MaxDepth: 2, Ignore: map[string]bool{".git": true}}},
+		{".txt (any case)", FindOptions{Extensions: []string{".txt"}}},
+		{"modified in the future", FindOptions{ModifiedAfter: time.Now().Add(time.Hour)}},
+	}
+	for _, s := range searches {
+		matches, err := FindFiles(dir, s.opts)
+		var walkErr *WalkError
+		if err != nil && !errors.As(err, &walkErr) {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("\n%s:\n", s.name)
+		printTable(matches, dir)
+		if walkErr != nil {
+			// as root the locked directory is still readable, so this only shows for normal users
+			fmt.Println("  collected errors:", walkErr)
+		}
+	}
+
+	if _, err := FindFiles(dir, FindOptions{Pattern: "[abc"}); err != nil {
+		fmt.Println("\nBad pattern:", err)
+	}
+	if _, err := FindFiles(filepath.Join(dir, "missing"), FindOptions{}); err != nil {
+		fmt.Println("Missing root:", err)
+	}
+}
+
+// filepath.WalkDir (Go 1.16+) is faster than filepath.Walk because it doesn't call os.Stat
+// for every entry, call d.Info() only when you need size or modification time.
+// Return filepath.SkipDir from the callback to skip a directory, any other error stops the walk.
+// Symlinks are reported but not followed, so loops are not a problem.
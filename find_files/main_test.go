@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tree builds the synthetic tree and returns its root; the locked directory
+// is made readable again before t.TempDir cleans up
+func tree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	t.Cleanup(func() { os.Chmod(filepath.Join(root, "locked"), 0o755) })
+	if err := buildSyntheticTree(root); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func relPaths(t *testing.T, root string, matches []FileMatch) []string {
+	t.Helper()
+	var out []string
+	for _, m := range matches {
+		rel, err := filepath.Rel(root, m.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(rel))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// the locked directory is readable for root, so what it contributes depends on who runs the test
+func lockedReadable() bool { return os.Getuid() == 0 }
+
+func TestFindFiles(t *testing.T) {
+	root := tree(t)
+	withLocked := func(paths ...string) []string {
+		if lockedReadable() {
+			paths = append(paths, "locked/secret.go")
+			sort.Strings(paths)
+		}
+		return paths
+	}
+	tests := []struct {
+		name string
+		opts FindOptions
+		want []string
+	}{
+		{"everything except .git", FindOptions{Ignore: map[string]bool{".git": true}},
+			withLocked("README.md", "assets/logo.png.bak", "main.go", "pkg/deep/deeper.go", "pkg/deep/empty.go", "pkg/deep/notes.TXT", "pkg/util.go")},
+		{"glob and min size", FindOptions{Pattern: "*.go", MinSize: 1, Ignore: map[string]bool{"locked": true}},
+			[]string{"main.go", "pkg/deep/deeper.go", "pkg/util.go"}},
+		{"max size", FindOptions{Extensions: []string{".go"}, MaxSize: 12, Ignore: map[string]bool{"locked": true}},
+			[]string{"main.go", "pkg/deep/deeper.go", "pkg/deep/empty.go"}},
+		{"extension, any case", FindOptions{Extensions: []string{".txt"}},
+			[]string{"pkg/deep/notes.TXT"}},
+		{"several extensions", FindOptions{Extensions: []string{".md", ".bak"}},
+			[]string{"README.md", "assets/logo.png.bak"}},
+		{"depth 1", FindOptions{MaxDepth: 1},
+			[]string{"README.md", "main.go"}},
+		{"depth 2", FindOptions{MaxDepth: 2, Ignore: map[string]bool{".git": true, "locked": true}},
+			[]string{"README.md", "assets/logo.png.bak", "main.go", "pkg/util.go"}},
+		{"modified in the future", FindOptions{ModifiedAfter: time.Now().Add(time.Hour)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := FindFiles(root, tt.opts)
+			var walkErr *WalkError
+			if err != nil && !errors.As(err, &walkErr) {
+				t.Fatal(err)
+			}
+			if got := relPaths(t, root, matches); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// the loop symlink is reported as a symlink, never followed, so the walk ends
+func TestFindFilesSymlinkLoopTerminates(t *testing.T) {
+	root := tree(t)
+	done := make(chan []FileMatch, 1)
+	go func() {
+		matches, _ := FindFiles(root, FindOptions{Pattern: "deeper.go"})
+		done <- matches
+	}()
+	select {
+	case matches := <-done:
+		if len(matches) != 1 {
+			t.Errorf("deeper.go found %d times, the loop was followed", len(matches))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the walk did not finish")
+	}
+}
+
+func TestFindFilesCollectsErrors(t *testing.T) {
+	if lockedReadable() {
+		t.Skip("root can read the locked directory")
+	}
+	root := tree(t)
+	matches, err := FindFiles(root, FindOptions{Extensions: []string{".go"}})
+	var walkErr *WalkError
+	if !errors.As(err, &walkErr) || len(walkErr.Errors) != 1 || !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("err = %v, want one collected permission error", err)
+	}
+	// the rest of the tree was still walked
+	if len(matches) != 4 {
+		t.Errorf("%d matches next to the error, want 4", len(matches))
+	}
+	// nothing matches, but the walk error is still a *WalkError and not a fatal one
+	matches, err = FindFiles(root, FindOptions{Pattern: "nothing-*"})
+	if !errors.As(err, &walkErr) || matches != nil {
+		t.Errorf("no matches: %v, %v", matches, err)
+	}
+}
+
+func TestFindFilesFatalErrors(t *testing.T) {
+	root := tree(t)
+	if _, err := FindFiles(root, FindOptions{Pattern: "[abc"}); !errors.Is(err, filepath.ErrBadPattern) {
+		t.Errorf("bad pattern: %v", err)
+	}
+	matches, err := FindFiles(filepath.Join(root, "missing"), FindOptions{})
+	var walkErr *WalkError
+	if !errors.Is(err, fs.ErrNotExist) || errors.As(err, &walkErr) || matches != nil {
+		t.Errorf("missing root: %v, %v; want a plain fs.ErrNotExist", matches, err)
+	}
+}
+
+func TestTable(t *testing.T) {
+	table := &Table{MaxWidth: 8}
+	table.SetHeaders("FILE", "BYTES")
+	table.AddRow("main.go", 12)
+	table.AddRow("a/very/long/path.go", 1234567)
+	table.AddRow("héllo wörld", 0)
+	var b strings.Builder
+	if err := table.Render(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"FILE      BYTES\n" +
+		"----      -----\n" +
+		"main.go   12\n" +
+		"a/very/…  1234567\n" +
+		"héllo w…  0\n"
+	if b.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", b.String(), want)
+	}
+}
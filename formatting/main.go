@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Point struct {
+	X, Y int
+}
+
+// FormattingExamples walks through the fmt verbs and flags
+func FormattingExamples() {
+	p := Point{1, 2}
+	fmt.Println("\nGeneral verbs")
+	fmt.Printf("%%v   %v\n", p)  // default format
+	fmt.Printf("%%+v  %+v\n", p) // with field names
+	fmt.Printf("%%#v  %#v\n", p) // Go syntax
+	fmt.Printf("%%T   %T\n", p)  // the type
+	fmt.Printf("%%v   %v\n", []any{nil, true, 3.5, "go"})
+	fmt.Printf("%%%%   %%\n") // a literal percent sign
+
+	fmt.Println("\nStrings")
+	fmt.Printf("%%s   %s\n", "hello")
+	fmt.Printf("%%q   %q\n", "tab\there \"quoted\"") // quoted and escaped, safe to log
+	fmt.Printf("%%x   %x\n", "hi")                   // hex bytes
+	fmt.Printf("%% x  % x\n", "hi")                  // hex with spaces
+	fmt.Printf("%%c   %c  %%U %U\n", 'G', 'G')       // character and Unicode code point
+
+	fmt.Println("\nNumbers")
+	fmt.Printf("%%d %d  %%b %b  %%o %o  %%x %x  %%X %X  %%#x %#x\n", 255, 255, 255, 255, 255, 255)
+	fmt.Printf("%%f %f  %%.2f %.2f  %%e %e  %%g %g\n", 3.14159, 3.14159, 314159.0, 0.000001)
+	fmt.Printf("%%+d %+d  %%08.3f %08.3f\n", 42, 3.14159) // always show the sign, zero padding
+
+	fmt.Println("\nWidth and precision")
+	fmt.Printf("[%6d] [%-6d] [%06d]\n", 42, 42, 42)     // right, left, zero padded
+	fmt.Printf("[%8.2f] [%-8.2f]\n", 3.14159, 3.14159)  // width 8, 2 decimals
+	fmt.Printf("[%.3s] [%10.3s]\n", "golang", "golang") // precision on a string cuts it
+	fmt.Printf("[%*d] [%-*d]\n", 5, 42, 5, 42)          // width from an argument
+
+	fmt.Println("\nArgument indexes")
+	fmt.Printf("%[2]s %[1]s\n", "world", "hello")          // pick arguments by position
+	fmt.Printf("%d in hex is %[1]x and octal %[1]o\n", 64) // reuse the same argument
+
+	fmt.Println("\nMistakes are printed, not panicked")
+	// go vet reports these mistakes when the format is a constant,
+	// the formats are in variables here only so the demo can show what happens at run time
+	wrongType, missing, extra := "%d\n", "%d %d\n", "%d"
+	fmt.Printf(wrongType, "text")         // %!d(string=text)
+	fmt.Printf(missing, 1)                // %!d(MISSING)
+	fmt.Println(fmt.Sprintf(extra, 1, 2)) // %!(EXTRA int=2)
+}
+
+func main() {
+	fmt.Println("Learning fmt formatting in Go")
+	FormattingExamples()
+
+	fmt.Println("\nSprintf vs strings.Builder")
+	s := fmt.Sprintf("%s is %d years old", "Rishabh", 23) // one formatted string
+	var sb strings.Builder                                // many pieces: build them up
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&sb, "[%d]", i) // Fprintf writes into any io.Writer, Builder is one
+	}
+	fmt.Println(s, sb.String())
+}
+
+// %v default, %+v field names, %#v Go syntax, %T type, %q quoted, %x hex.
+// Width and precision: %8.2f (width 8, 2 decimals), %-8s (left aligned), %08d (zero padded).
+// %[n]d picks the n-th argument. Sprintf returns a string, Fprintf writes to an io.Writer.
+// For aligned columns use text/tabwriter, the Table in the textutil lesson wraps it.
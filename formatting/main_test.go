@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update and checkGolden are a shorter version of the golden helper in the textutil lesson
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name.golden; -update rewrites the file first
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n")); !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (go test main.go main_test.go -update rewrites it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// captureStdout runs f with os.Stdout going into a pipe and returns what it printed
+func captureStdout(t *testing.T, f func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	t.Cleanup(func() { os.Stdout = stdout })
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	f()
+	w.Close()
+	os.Stdout = stdout
+	return <-out
+}
+
+// The whole walkthrough is compared with testdata/main.golden: a change in how fmt prints
+// any of the verbs shows up in the diff
+func TestGolden(t *testing.T) {
+	checkGolden(t, "main", captureStdout(t, main))
+}
+
+// a few lines checked on their own, so the golden file can't quietly be updated to nonsense
+func TestVerbs(t *testing.T) {
+	out := captureStdout(t, FormattingExamples)
+	for _, line := range []string{
+		"%v   {1 2}",
+		"%+v  {X:1 Y:2}",
+		"%#v  main.Point{X:1, Y:2}",
+		"%T   main.Point",
+		`%q   "tab\there \"quoted\""`,
+		"%x   6869",
+		"% x  68 69",
+		"[    42] [42    ] [000042]",
+		"[    3.14] [3.14    ]",
+		"[gol] [       gol]",
+		"[   42] [42   ]",
+		"hello world",
+		"64 in hex is 40 and octal 100",
+		"%!d(string=text)",
+		"1 %!d(MISSING)",
+		"1%!(EXTRA int=2)",
+	} {
+		if !bytes.Contains(out, []byte("\n"+line+"\n")) {
+			t.Errorf("no line %q", line)
+		}
+	}
+}
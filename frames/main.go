@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing/iotest"
+)
+
+// A toy wire protocol: every message is sent as "<length>:<bytes>", e.g. "5:hello2:go".
+// The length prefix lets the reader know where a message ends even if it contains
+// newlines or ':' itself, which a line based protocol can't handle.
+
+// MaxFrameSize guards against a corrupted or malicious length like "99999999999:"
+// that would make us wait for (or allocate) gigabytes
+const MaxFrameSize = 1 << 16
+
+// maxPrefixDigits is how many digits the length of MaxFrameSize needs
+var maxPrefixDigits = len(strconv.Itoa(MaxFrameSize))
+
+var (
+	ErrBadPrefix     = errors.New("bad length prefix")
+	ErrFrameTooLarge = errors.New("frame too large")
+	ErrPartialFrame  = errors.New("partial frame at end of input")
+)
+
+// SplitFrames is a bufio.SplitFunc for the protocol.
+// The Scanner calls it with everything buffered so far. It returns:
+//   - advance: how many bytes to consume
+//   - token: the message, or nil to ask for more data
+//   - err: to stop scanning
+//
+// Returning (0, nil, nil) means "not enough data yet, read more and call me again",
+// that's how a frame split across several reads is handled.
+func SplitFrames(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) == 0 {
+		return 0, nil, nil // nothing left (at EOF the Scanner just stops)
+	}
+	colon := bytes.IndexByte(data, ':')
+	if colon < 0 {
+		if len(data) > maxPrefixDigits {
+			return 0, nil, fmt.Errorf("%w: no ':' in %q", ErrBadPrefix, data[:maxPrefixDigits+1])
+		}
+		if atEOF {
+			return 0, nil, fmt.Errorf("%w: %q", ErrPartialFrame, data)
+		}
+		return 0, nil, nil // the prefix itself is still arriving
+	}
+	size, err := strconv.Atoi(string(data[:colon]))
+	if err != nil || size < 0 || colon == 0 {
+		return 0, nil, fmt.Errorf("%w: %q", ErrBadPrefix, data[:colon])
+	}
+	if size > MaxFrameSize {
+		return 0, nil, fmt.Errorf("%w: %d bytes, max %d", ErrFrameTooLarge, size, MaxFrameSize)
+	}
+	end := colon + 1 + size
+	if len(data) < end {
+		if atEOF {
+			return 0, nil, fmt.Errorf("%w: want %d bytes, have %d", ErrPartialFrame, size, len(data)-colon-1)
+		}
+		return 0, nil, nil // the body is still arriving
+	}
+	return end, data[colon+1 : end], nil
+}
+
+// newFrameScanner returns a Scanner whose buffer can hold the biggest allowed frame
+func newFrameScanner(r io.Reader) *bufio.Scanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 4096), MaxFrameSize+maxPrefixDigits+1)
+	s.Split(SplitFrames)
+	return s
+}
+
+// ParseFrames reads all frames from r.
+// On an error it returns the frames parsed before it, so the caller can see how far it got.
+func ParseFrames(r io.Reader) ([]string, error) {
+	var frames []string
+	s := newFrameScanner(r)
+	for s.Scan() {
+		frames = append(frames, s.Text()) // Text copies, s.Bytes() is reused by the next Scan
+	}
+	if err := s.Err(); err != nil {
+		return frames, fmt.Errorf("parse frame %d: %w", len(frames)+1, err)
+	}
+	return frames, nil
+}
+
+// WriteFrame writes msg with its length prefix
+func WriteFrame(w io.Writer, msg string) error {
+	if len(msg) > MaxFrameSize {
+		return fmt.Errorf("write frame: %w: %d bytes, max %d", ErrFrameTooLarge, len(msg), MaxFrameSize)
+	}
+	if _, err := fmt.Fprintf(w, "%d:%s", len(msg), msg); err != nil {
+		return fmt.Errorf("write frame: %w", err)
+	}
+	return nil
+}
+
+func main() {
+	fmt.Println("Learning bufio.Scanner split functions in Go")
+
+	// 1. encode and decode
+	var buf bytes.Buffer
+	messages := []string{"hello", "", "multi\nline", "a:b:c", "héllo wörld"}
+	for _, m := range messages {
+		WriteFrame(&buf, m)
+	}
+	wire := buf.String()
+	fmt.Printf("On the wire: %q\n", wire)
+	frames, err := ParseFrames(strings.NewReader(wire))
+	fmt.Printf("Parsed: %q err: %v\n", frames, err)
+
+	// 2. the same input one byte per Read: every frame is split across reads
+	frames, err = ParseFrames(iotest.OneByteReader(strings.NewReader(wire)))
+	fmt.Printf("One byte at a time: %d frames, same result: %v, err: %v\n", len(frames), fmt.Sprint(frames) == fmt.Sprint(messages), err)
+
+	// 3. broken input
+	bad := []string{
+		"5:hello3:go",                      // trailing partial body
+		"5:hello12",                        // trailing partial prefix
+		"x:hello",                          // not a number
+		"-1:oops",                          // negative
+		":hello",                           // empty prefix
+		"1234567890",                       // never ends with ':'
+		fmt.Sprintf("%d:", MaxFrameSize+1), // over the guard
+	}
+	for _, in := range bad {
+		frames, err := ParseFrames(iotest.OneByteReader(strings.NewReader(in)))
+		fmt.Printf("  %-12q -> %q, %v\n", in, frames, err)
+	}
+	err = WriteFrame(io.Discard, strings.Repeat("x", MaxFrameSize+1))
+	fmt.Println("  WriteFrame too large:", err, "| errors.Is ErrFrameTooLarge:", errors.Is(err, ErrFrameTooLarge))
+
+	// 4. producer and consumer connected by a pipe
+	fmt.Println("\nProducer -> io.Pipe -> consumer")
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 1; i <= 5; i++ {
+			if err := WriteFrame(pw, fmt.Sprintf("job %d\nwith a newline inside", i)); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
+	s := newFrameScanner(pr)
+	for s.Scan() {
+		fmt.Printf("  consumer got %q\n", s.Text())
+	}
+	fmt.Println("  consumer done, err:", s.Err())
+}
+
+// A SplitFunc gets the buffered data and returns (advance, token, err).
+// Return (0, nil, nil) to ask for more data: that's how tokens split across reads work.
+// Check atEOF to report leftover partial data instead of silently dropping it.
+// Always limit sizes read from the wire (Scanner.Buffer sets the max token size).
+// Test parsers with iotest.OneByteReader, real networks deliver data in random chunks.
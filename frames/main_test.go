@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var sampleMessages = []string{"hello", "", "multi\nline", "a:b:c", "héllo wörld", strings.Repeat("x", MaxFrameSize)}
+
+func encode(t *testing.T, msgs []string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		if err := WriteFrame(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	wire := encode(t, sampleMessages)
+	readers := map[string]func(io.Reader) io.Reader{
+		"whole":    func(r io.Reader) io.Reader { return r },
+		"one byte": iotest.OneByteReader,
+		"half":     iotest.HalfReader,
+		"data+EOF": iotest.DataErrReader,
+	}
+	for name, wrap := range readers {
+		got, err := ParseFrames(wrap(strings.NewReader(wire)))
+		if err != nil || !slices.Equal(got, sampleMessages) {
+			t.Errorf("%s: %d frames, %v", name, len(got), err)
+		}
+	}
+}
+
+func TestParseFramesErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+		err  error
+	}{
+		{"5:hello3:go", []string{"hello"}, ErrPartialFrame},
+		{"5:hello12", []string{"hello"}, ErrPartialFrame},
+		{"x:hello", nil, ErrBadPrefix},
+		{"-1:oops", nil, ErrBadPrefix},
+		{":hello", nil, ErrBadPrefix},
+		{"+5:hello", []string{"hello"}, nil}, // Atoi accepts a sign
+		{"2:hi 3:bad", []string{"hi"}, ErrBadPrefix},
+		{"1234567890", nil, ErrBadPrefix},
+		{"5:hello123456", []string{"hello"}, ErrBadPrefix}, // corrupted prefix mid-stream
+		{fmt.Sprintf("%d:", MaxFrameSize+1), nil, ErrFrameTooLarge},
+		{"99999999999999999999:x", nil, ErrBadPrefix}, // overflows int
+		{"", nil, nil},
+	}
+	for _, tt := range tests {
+		for _, oneByte := range []bool{false, true} {
+			var r io.Reader = strings.NewReader(tt.in)
+			if oneByte {
+				r = iotest.OneByteReader(r)
+			}
+			got, err := ParseFrames(r)
+			if !slices.Equal(got, tt.want) || !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Errorf("%q (one byte %v): %q, %v; want %q, %v", tt.in, oneByte, got, err, tt.want, tt.err)
+			}
+		}
+	}
+}
+
+func TestParseFramesReadError(t *testing.T) {
+	boom := errors.New("connection reset")
+	r := io.MultiReader(strings.NewReader("2:ok3:ha"), iotest.ErrReader(boom))
+	got, err := ParseFrames(r)
+	if !slices.Equal(got, []string{"ok"}) || !errors.Is(err, boom) {
+		t.Errorf("%q, %v", got, err)
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, "a:b"); err != nil || buf.String() != "3:a:b" {
+		t.Errorf("%q, %v", buf.String(), err)
+	}
+	if err := WriteFrame(io.Discard, strings.Repeat("x", MaxFrameSize+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("too large: %v", err)
+	}
+	pr, pw := io.Pipe()
+	pr.Close()
+	if err := WriteFrame(pw, "x"); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("closed pipe: %v", err)
+	}
+}
+
+func FuzzSplitFrames(f *testing.F) {
+	f.Add("5:hello3:a:b")
+	f.Add("5:hel")
+	f.Add("x:")
+	f.Fuzz(func(t *testing.T, in string) {
+		got, err := ParseFrames(iotest.OneByteReader(strings.NewReader(in)))
+		if err != nil {
+			return
+		}
+		// whatever parsed cleanly must survive an encode and parse again ("05:" and "+5:" parse but encode as "5:")
+		again, err := ParseFrames(strings.NewReader(encode(t, got)))
+		if err != nil || !slices.Equal(again, got) {
+			t.Errorf("%q: %q re-parses as %q, %v", in, got, again, err)
+		}
+	})
+}
@@ -1,6 +1,12 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 func increment(x int) int {
 	return x + 1
@@ -15,4 +21,195 @@ func main() {
 	x := 20
 	result := increment(x)
 	fmt.Println("Incremented value:", result)
+
+	RecursionExamples()
+}
+
+// ---------------------------------------------------------------------------
+// Recursion
+// A recursive function is a function that calls itself.
+// Every recursive function needs:
+//  1. a base case (when to stop)
+//  2. a recursive case (a smaller version of the same problem)
+// Go has no tail-call optimization, so every call adds a new stack frame.
+// Goroutine stacks grow dynamically but are capped (1 GB on 64-bit), so
+// very deep recursion still crashes with "goroutine stack exceeds limit".
+// ---------------------------------------------------------------------------
+
+// ErrMaxDepth is returned when a depth-limited recursion goes too deep.
+var ErrMaxDepth = errors.New("maximum recursion depth exceeded")
+
+// maxDepth is the configured limit used by the depth-limited examples
+const maxDepth = 1000
+
+// Node is a directory-tree-like structure: a name, a size and children
+type Node struct {
+	Name     string
+	Size     int
+	Children []*Node
+}
+
+// flattenTreeRecursive returns every path in the tree using recursion
+func flattenTreeRecursive(n *Node) []string {
+	var paths []string
+	var walk func(n *Node, prefix string)
+	walk = func(n *Node, prefix string) {
+		if n == nil { // base case
+			return
+		}
+		path := prefix + "/" + n.Name
+		paths = append(paths, path)
+		for _, child := range n.Children {
+			walk(child, path) // recursive case
+		}
+	}
+	walk(n, "")
+	return paths
 }
+
+// flattenTreeIterative returns the same paths as flattenTreeRecursive but
+// uses an explicit stack (a slice) instead of the call stack
+func flattenTreeIterative(n *Node) []string {
+	if n == nil {
+		return nil
+	}
+	type item struct {
+		node *Node
+		path string
+	}
+	var paths []string
+	stack := []item{{node: n, path: "/" + n.Name}}
+	for len(stack) > 0 {
+		// pop from the top of the stack
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		paths = append(paths, top.path)
+
+		// push children in reverse so the first child is visited first,
+		// which keeps the output order identical to the recursive version
+		for i := len(top.node.Children) - 1; i >= 0; i-- {
+			child := top.node.Children[i]
+			stack = append(stack, item{node: child, path: top.path + "/" + child.Name})
+		}
+	}
+	return paths
+}
+
+// totalSize sums sizes in the classic recursive way
+func totalSize(n *Node) int {
+	if n == nil {
+		return 0
+	}
+	sum := n.Size
+	for _, child := range n.Children {
+		sum += totalSize(child)
+	}
+	return sum
+}
+
+// sumAccumulator is a "tail-call style" sum: the running total is passed
+// down as an argument so the recursive call is the last thing that happens.
+// Go does NOT optimize this, but the style is easy to convert to a loop.
+func sumAccumulator(nums []int, acc int) int {
+	if len(nums) == 0 {
+		return acc
+	}
+	return sumAccumulator(nums[1:], acc+nums[0])
+}
+
+// isEven and isOdd call each other (mutual recursion)
+func isEven(n int) bool {
+	if n == 0 {
+		return true
+	}
+	return isOdd(n - 1)
+}
+
+func isOdd(n int) bool {
+	if n == 0 {
+		return false
+	}
+	return isEven(n - 1)
+}
+
+// depthLimited walks the tree but gives up with ErrMaxDepth instead of
+// blowing the stack when the tree is deeper than limit
+func depthLimited(n *Node, depth, limit int) (int, error) {
+	if n == nil {
+		return 0, nil
+	}
+	if depth > limit {
+		return 0, fmt.Errorf("node %q at depth %d: %w", n.Name, depth, ErrMaxDepth)
+	}
+	count := 1
+	for _, child := range n.Children {
+		c, err := depthLimited(child, depth+1, limit)
+		if err != nil {
+			return 0, err
+		}
+		count += c
+	}
+	return count, nil
+}
+
+// buildChain creates a tree that is just one long chain of the given depth
+func buildChain(depth int) *Node {
+	root := &Node{Name: "d0", Size: 1}
+	current := root
+	for i := 1; i < depth; i++ {
+		child := &Node{Name: fmt.Sprintf("d%d", i), Size: 1}
+		current.Children = []*Node{child}
+		current = child
+	}
+	return root
+}
+
+func RecursionExamples() {
+	fmt.Println("\nLearning recursion")
+
+	root := &Node{Name: "home", Size: 1, Children: []*Node{
+		{Name: "docs", Size: 4, Children: []*Node{
+			{Name: "resume.pdf", Size: 120},
+			{Name: "notes.txt", Size: 3},
+		}},
+		{Name: "code", Size: 4, Children: []*Node{
+			{Name: "main.go", Size: 2},
+		}},
+	}}
+
+	fmt.Println("Recursive traversal:")
+	fmt.Println(strings.Join(flattenTreeRecursive(root), "\n"))
+	fmt.Println("Iterative traversal (explicit stack):")
+	fmt.Println(strings.Join(flattenTreeIterative(root), "\n"))
+	fmt.Println("Total size:", totalSize(root))
+
+	fmt.Println("Accumulator sum of 1..5:", sumAccumulator([]int{1, 2, 3, 4, 5}, 0))
+	fmt.Println("isEven(10):", isEven(10), "isOdd(7):", isOdd(7))
+
+	// depth-limited recursion near and past the limit
+	if count, err := depthLimited(buildChain(maxDepth), 1, maxDepth); err != nil {
+		fmt.Println("Unexpected error:", err)
+	} else {
+		fmt.Println("Chain at the limit visited", count, "nodes")
+	}
+	if _, err := depthLimited(buildChain(maxDepth+1), 1, maxDepth); errors.Is(err, ErrMaxDepth) {
+		fmt.Println("Chain past the limit:", err)
+	}
+
+	// compare both flatten implementations on a deep chain
+	deep := buildChain(maxDepth)
+	start := time.Now()
+	recursivePaths := flattenTreeRecursive(deep)
+	recursiveTime := time.Since(start)
+	start = time.Now()
+	iterativePaths := flattenTreeIterative(deep)
+	iterativeTime := time.Since(start)
+	fmt.Printf("Recursive flatten: %d paths in %v\n", len(recursivePaths), recursiveTime)
+	fmt.Printf("Iterative flatten: %d paths in %v\n", len(iterativePaths), iterativeTime)
+	fmt.Println("Both give the same result:", slices.Equal(recursivePaths, iterativePaths))
+}
+
+// Recursion vs iteration
+// Recursion is natural for tree-shaped data (folders, JSON, HTML).
+// An explicit stack gives the same result without growing the call stack,
+// which is safer when the input depth is controlled by a user.
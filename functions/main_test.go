@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func sampleTree() *Node {
+	return &Node{Name: "home", Size: 1, Children: []*Node{
+		{Name: "docs", Size: 4, Children: []*Node{
+			{Name: "resume.pdf", Size: 120},
+			{Name: "notes.txt", Size: 3},
+		}},
+		{Name: "code", Size: 4, Children: []*Node{{Name: "main.go", Size: 2}}},
+	}}
+}
+
+func TestFlattenParity(t *testing.T) {
+	tests := []struct {
+		name string
+		root *Node
+	}{
+		{"nil", nil},
+		{"single", &Node{Name: "a"}},
+		{"sample", sampleTree()},
+		{"chain at the limit", buildChain(maxDepth)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, it := flattenTreeRecursive(tt.root), flattenTreeIterative(tt.root)
+			if !slices.Equal(rec, it) {
+				t.Errorf("recursive %v\niterative %v", rec, it)
+			}
+		})
+	}
+
+	want := []string{"/home", "/home/docs", "/home/docs/resume.pdf", "/home/docs/notes.txt", "/home/code", "/home/code/main.go"}
+	if got := flattenTreeIterative(sampleTree()); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestDepthLimited(t *testing.T) {
+	count, err := depthLimited(buildChain(maxDepth), 1, maxDepth)
+	if err != nil || count != maxDepth {
+		t.Fatalf("at the limit: got %d, %v; want %d, nil", count, err, maxDepth)
+	}
+	if _, err := depthLimited(buildChain(maxDepth+1), 1, maxDepth); !errors.Is(err, ErrMaxDepth) {
+		t.Fatalf("one past the limit: got %v, want ErrMaxDepth", err)
+	}
+}
+
+func TestSmallHelpers(t *testing.T) {
+	if got := totalSize(sampleTree()); got != 134 {
+		t.Errorf("totalSize = %d, want 134", got)
+	}
+	if got := sumAccumulator([]int{1, 2, 3, 4, 5}, 0); got != 15 {
+		t.Errorf("sumAccumulator = %d, want 15", got)
+	}
+	if !isEven(10) || isEven(7) || !isOdd(7) || isOdd(0) {
+		t.Error("isEven/isOdd disagree with n%2")
+	}
+}
+
+func BenchmarkFlattenRecursive(b *testing.B) {
+	deep := buildChain(maxDepth)
+	for b.Loop() {
+		flattenTreeRecursive(deep)
+	}
+}
+
+func BenchmarkFlattenIterative(b *testing.B) {
+	deep := buildChain(maxDepth)
+	for b.Loop() {
+		flattenTreeIterative(deep)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A test is a function that calls your code and checks the answer. `go test` finds them
+// (func TestXxx(t *testing.T) in *_test.go files), runs them and says which ones failed.
+// With -json it prints one JSON event per line instead of text, so a program can read it:
+//
+//	{"Action":"run","Package":"p","Test":"TestAdd"}
+//	{"Action":"pass","Package":"p","Test":"TestAdd","Elapsed":0.01}
+//	{"Action":"pass","Package":"p","Elapsed":0.3}
+//
+// This lesson reads that stream and turns it into a short report.
+
+var (
+	ErrNoGo        = errors.New("the go command is not on PATH")
+	ErrInterrupted = errors.New("the test stream ended early")
+)
+
+// Event is one line of `go test -json` (see `go doc test2json`).
+// Build failures come as "build-output" and "build-fail" events that have ImportPath instead of Package.
+type Event struct {
+	Time        time.Time
+	Action      string // start, run, pause, cont, pass, fail, skip, bench, output, build-output, build-fail
+	Package     string
+	Test        string
+	Elapsed     float64 // seconds
+	Output      string
+	FailedBuild string // on a package "fail": the package whose build failed
+	ImportPath  string // on build-output and build-fail
+}
+
+type Status string
+
+const (
+	Pass        Status = "ok"
+	Fail        Status = "FAIL"
+	Skip        Status = "skip" // no test files
+	BuildFailed Status = "build failed"
+	Running     Status = "no result" // the stream stopped before the package finished
+)
+
+type PackageResult struct {
+	Name                    string
+	Status                  Status
+	Elapsed                 time.Duration
+	Passed, Failed, Skipped int
+	FailedTests             []string
+	Output                  []string // the build errors, or the output of the failed tests
+}
+
+type Summary struct {
+	Packages []*PackageResult // in the order they started
+	Elapsed  time.Duration    // first event to last event
+}
+
+func (s *Summary) Failed() bool {
+	for _, p := range s.Packages {
+		if p.Status != Pass && p.Status != Skip {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads a `go test -json` stream to the end. It returns the summary so far together with
+// ErrInterrupted when a line is cut off or a package never reported a result, so a killed
+// `go test` still shows what had finished.
+func Parse(r io.Reader) (*Summary, error) {
+	s := &Summary{}
+	byName := map[string]*PackageResult{}
+	pkg := func(name string) *PackageResult {
+		p, ok := byName[name]
+		if !ok {
+			p = &PackageResult{Name: name, Status: Running}
+			byName[name] = p
+			s.Packages = append(s.Packages, p)
+		}
+		return p
+	}
+	buildOutput := map[string][]string{} // ImportPath -> compiler messages
+	testOutput := map[string][]string{}  // package + "\x00" + test -> its output, kept until it passes
+	var first, last time.Time
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024) // one test can print a very long line
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return s, fmt.Errorf("%w: line %d: %v", ErrInterrupted, lineNo, err)
+		}
+		if !ev.Time.IsZero() {
+			if first.IsZero() {
+				first = ev.Time
+			}
+			last = ev.Time
+		}
+
+		switch ev.Action {
+		case "build-output":
+			buildOutput[ev.ImportPath] = append(buildOutput[ev.ImportPath], ev.Output)
+			continue
+		case "build-fail":
+			continue // the package's own "fail" event follows, with FailedBuild set
+		}
+		if ev.Package == "" {
+			continue
+		}
+		p := pkg(ev.Package)
+		key := ev.Package + "\x00" + ev.Test
+
+		if ev.Test == "" { // the package itself
+			switch ev.Action {
+			case "pass":
+				p.Status = Pass
+			case "skip":
+				p.Status = Skip
+			case "fail":
+				p.Status = Fail
+				if ev.FailedBuild != "" {
+					p.Status = BuildFailed
+					p.Output = append(p.Output, buildOutput[ev.FailedBuild]...)
+				}
+			}
+			if ev.Action == "pass" || ev.Action == "fail" || ev.Action == "skip" {
+				p.Elapsed = time.Duration(ev.Elapsed * float64(time.Second))
+			}
+			continue
+		}
+
+		switch ev.Action {
+		case "output":
+			testOutput[key] = append(testOutput[key], ev.Output)
+		case "pass":
+			p.Passed++
+			delete(testOutput, key)
+		case "skip":
+			p.Skipped++
+			delete(testOutput, key)
+		case "fail":
+			p.Failed++
+			p.FailedTests = append(p.FailedTests, ev.Test)
+			for _, out := range testOutput[key] {
+				if !strings.HasPrefix(out, "=== ") { // drop the === RUN/PAUSE/CONT frames
+					p.Output = append(p.Output, out)
+				}
+			}
+			delete(testOutput, key)
+		}
+	}
+	s.Elapsed = last.Sub(first)
+	if err := scanner.Err(); err != nil {
+		return s, fmt.Errorf("%w: %v", ErrInterrupted, err)
+	}
+	for _, p := range s.Packages {
+		if p.Status == Running {
+			return s, fmt.Errorf("%w: no result for %s", ErrInterrupted, p.Name)
+		}
+	}
+	return s, nil
+}
+
+// Render writes the report: one line per package, then the failed tests and what they printed
+func Render(w io.Writer, s *Summary) {
+	width := 0
+	for _, p := range s.Packages {
+		width = max(width, len(p.Name))
+	}
+	var passed, failed int
+	for _, p := range s.Packages {
+		passed += p.Passed
+		failed += p.Failed
+		counts := fmt.Sprintf("%d passed", p.Passed)
+		if p.Failed > 0 {
+			counts += fmt.Sprintf(", %d failed", p.Failed)
+		}
+		if p.Skipped > 0 {
+			counts += fmt.Sprintf(", %d skipped", p.Skipped)
+		}
+		switch p.Status {
+		case Skip:
+			counts = "no tests"
+		case BuildFailed:
+			counts = "did not compile"
+		case Running:
+			counts = "stopped before it finished"
+		}
+		fmt.Fprintf(w, "  %-14s %-*s  %-26s %v\n", "["+string(p.Status)+"]", width, p.Name, counts, p.Elapsed.Round(time.Millisecond))
+		for _, name := range p.FailedTests {
+			fmt.Fprintf(w, "      failed: %s\n", name)
+		}
+		for _, out := range p.Output {
+			fmt.Fprintf(w, "      | %s\n", strings.TrimRight(out, "\n"))
+		}
+	}
+	fmt.Fprintf(w, "  %d packages, %d tests passed, %d failed, in %v\n", len(s.Packages), passed, failed, s.Elapsed.Round(time.Millisecond))
+}
+
+// RunGoTest runs `go test -count=1 -json args...` in dir and parses what it prints.
+// A failing test makes go test exit with status 1, that is a result, not an error here.
+func RunGoTest(ctx context.Context, dir string, args ...string) (*Summary, error) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		return nil, fmt.Errorf("%w: install Go from https://go.dev/dl/", ErrNoGo)
+	}
+	cmd := exec.CommandContext(ctx, goBin, append([]string{"test", "-count=1", "-json"}, args...)...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	summary, parseErr := Parse(stdout)
+	if parseErr != nil {
+		io.Copy(io.Discard, stdout) // let go test finish writing before Wait closes the pipe
+	}
+	waitErr := cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) && exitErr.ExitCode() == 1 && summary.Failed() {
+		waitErr = nil // tests failed, the summary says which
+	}
+	if waitErr != nil && len(summary.Packages) == 0 {
+		// nothing ran at all: a bad flag, no such file; stderr says why
+		return summary, fmt.Errorf("go test: %w: %s", waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return summary, errors.Join(parseErr, waitErr)
+}
+
+// ----------------------------------------------------------------------------
+// Canned streams, what go test prints in each situation
+
+const passingStream = `{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example/strings"}
+{"Time":"2026-01-01T10:00:00.1Z","Action":"run","Package":"example/strings","Test":"TestTitleCase"}
+{"Time":"2026-01-01T10:00:00.1Z","Action":"output","Package":"example/strings","Test":"TestTitleCase","Output":"=== RUN   TestTitleCase\n"}
+{"Time":"2026-01-01T10:00:00.1Z","Action":"pass","Package":"example/strings","Test":"TestTitleCase","Elapsed":0}
+{"Time":"2026-01-01T10:00:00.2Z","Action":"run","Package":"example/strings","Test":"TestSplit"}
+{"Time":"2026-01-01T10:00:00.2Z","Action":"skip","Package":"example/strings","Test":"TestSplit","Elapsed":0}
+{"Time":"2026-01-01T10:00:00.3Z","Action":"pass","Package":"example/strings","Elapsed":0.3}
+{"Time":"2026-01-01T10:00:00.3Z","Action":"start","Package":"example/docs"}
+{"Time":"2026-01-01T10:00:00.3Z","Action":"output","Package":"example/docs","Output":"?   \texample/docs\t[no test files]\n"}
+{"Time":"2026-01-01T10:00:00.3Z","Action":"skip","Package":"example/docs","Elapsed":0}
+`
+
+const failingStream = `{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example/maps"}
+{"Time":"2026-01-01T10:00:00Z","Action":"run","Package":"example/maps","Test":"TestMerge"}
+{"Time":"2026-01-01T10:00:00Z","Action":"run","Package":"example/maps","Test":"TestMerge/empty"}
+{"Time":"2026-01-01T10:00:00Z","Action":"output","Package":"example/maps","Test":"TestMerge/empty","Output":"=== RUN   TestMerge/empty\n"}
+{"Time":"2026-01-01T10:00:00Z","Action":"output","Package":"example/maps","Test":"TestMerge/empty","Output":"    main_test.go:12: Merge(nil, nil) = map[], want nil\n"}
+{"Time":"2026-01-01T10:00:00Z","Action":"fail","Package":"example/maps","Test":"TestMerge/empty","Elapsed":0}
+{"Time":"2026-01-01T10:00:00Z","Action":"fail","Package":"example/maps","Test":"TestMerge","Elapsed":0}
+{"Time":"2026-01-01T10:00:00.1Z","Action":"run","Package":"example/maps","Test":"TestKeys"}
+{"Time":"2026-01-01T10:00:00.1Z","Action":"pass","Package":"example/maps","Test":"TestKeys","Elapsed":0}
+{"Time":"2026-01-01T10:00:00.2Z","Action":"fail","Package":"example/maps","Elapsed":0.2}
+`
+
+// since Go 1.24 compiler errors are events too, before that they only went to stderr
+const buildFailStream = `{"ImportPath":"example/broken [example/broken.test]","Action":"build-output","Output":"# example/broken [example/broken.test]\n"}
+{"ImportPath":"example/broken [example/broken.test]","Action":"build-output","Output":"./main.go:2:14: undefined: x\n"}
+{"ImportPath":"example/broken [example/broken.test]","Action":"build-fail"}
+{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example/broken"}
+{"Time":"2026-01-01T10:00:00Z","Action":"output","Package":"example/broken","Output":"FAIL\texample/broken [build failed]\n"}
+{"Time":"2026-01-01T10:00:00Z","Action":"fail","Package":"example/broken","Elapsed":0,"FailedBuild":"example/broken [example/broken.test]"}
+`
+
+// go test killed halfway: the last line is cut off and the package never finished
+const interruptedStream = `{"Time":"2026-01-01T10:00:00Z","Action":"start","Package":"example/slow"}
+{"Time":"2026-01-01T10:00:00Z","Action":"run","Package":"example/slow","Test":"TestQuick"}
+{"Time":"2026-01-01T10:00:00.5Z","Action":"pass","Package":"example/slow","Test":"TestQuick","Elapsed":0.5}
+{"Time":"2026-01-01T10:00:00.5Z","Action":"run","Package":"example/slow","Te`
+
+func main() {
+	verify := flag.String("verify", "", "run the tests of this lesson directory (e.g. ../strings) and report")
+	flag.Parse()
+
+	if *verify != "" {
+		fmt.Println("Verifying your environment by running the tests in", *verify)
+		fmt.Println("(a test calls the lesson's code and checks the answer, if they pass, Go works here)")
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		// lessons are single files, so name them: go test main.go main_test.go
+		files, _ := filepath.Glob(filepath.Join(*verify, "*.go"))
+		for i := range files {
+			files[i] = filepath.Base(files[i])
+		}
+		slices.Sort(files)
+		summary, err := RunGoTest(ctx, *verify, files...)
+		if summary != nil {
+			Render(os.Stdout, summary)
+		}
+		if err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if summary.Failed() {
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println("Learning to read go test -json in Go")
+	for _, demo := range []struct{ name, stream string }{
+		{"Everything passes:", passingStream},
+		{"A subtest fails:", failingStream},
+		{"The package does not compile:", buildFailStream},
+		{"go test was killed halfway:", interruptedStream},
+	} {
+		fmt.Println(demo.name)
+		summary, err := Parse(strings.NewReader(demo.stream))
+		Render(os.Stdout, summary)
+		if err != nil {
+			fmt.Println("  Error:", err, "| interrupted:", errors.Is(err, ErrInterrupted))
+		}
+	}
+
+	fmt.Println("Without the go command:")
+	os.Setenv("PATH", "")
+	_, err := RunGoTest(context.Background(), ".")
+	fmt.Println("  Error:", err, "| ErrNoGo:", errors.Is(err, ErrNoGo))
+	fmt.Println("Run with -verify ../strings to run a lesson's tests for real")
+}
+
+// go test -json prints one event per line: start, run, output, then pass/fail/skip per test and per package.
+// Read it line by line with bufio.Scanner, a cut-off last line means the run was interrupted, keep what came before.
+// Compile errors are build-output/build-fail events, and the package's fail event names them in FailedBuild.
+// Keep a test's output until it passes or fails, then only failures need showing.
+// go test exits 1 when a test fails: that's a result to report, not an error running it.
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePassing(t *testing.T) {
+	s, err := Parse(strings.NewReader(passingStream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Packages) != 2 || s.Failed() {
+		t.Fatalf("got %d packages, failed %v", len(s.Packages), s.Failed())
+	}
+	str, docs := s.Packages[0], s.Packages[1]
+	if str.Name != "example/strings" || str.Status != Pass || str.Passed != 1 || str.Skipped != 1 || str.Elapsed != 300*time.Millisecond {
+		t.Errorf("strings: %+v", str)
+	}
+	if docs.Status != Skip || docs.Passed != 0 {
+		t.Errorf("docs: %+v", docs)
+	}
+	if s.Elapsed != 300*time.Millisecond {
+		t.Errorf("Elapsed = %v", s.Elapsed)
+	}
+}
+
+func TestParseFailing(t *testing.T) {
+	s, err := Parse(strings.NewReader(failingStream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := s.Packages[0]
+	if p.Status != Fail || !s.Failed() || p.Passed != 1 || p.Failed != 2 {
+		t.Errorf("%+v", p)
+	}
+	if want := []string{"TestMerge/empty", "TestMerge"}; !slices.Equal(p.FailedTests, want) {
+		t.Errorf("FailedTests = %q, want %q", p.FailedTests, want)
+	}
+	// only what the failing test printed, without the === RUN frame
+	if want := []string{"    main_test.go:12: Merge(nil, nil) = map[], want nil\n"}; !slices.Equal(p.Output, want) {
+		t.Errorf("Output = %q, want %q", p.Output, want)
+	}
+}
+
+func TestParseBuildFailure(t *testing.T) {
+	s, err := Parse(strings.NewReader(buildFailStream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := s.Packages[0]
+	if len(s.Packages) != 1 || p.Status != BuildFailed || !s.Failed() {
+		t.Fatalf("%d packages, %+v", len(s.Packages), p)
+	}
+	if len(p.Output) != 2 || !strings.Contains(p.Output[1], "undefined: x") {
+		t.Errorf("compiler output = %q", p.Output)
+	}
+}
+
+func TestParseInterrupted(t *testing.T) {
+	tests := []struct {
+		name, stream string
+	}{
+		{"cut-off line", interruptedStream},
+		{"no package result", strings.Join(strings.Split(passingStream, "\n")[:6], "\n")},
+		{"not JSON", "go: cannot find main module\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(strings.NewReader(tt.stream))
+			if !errors.Is(err, ErrInterrupted) {
+				t.Fatalf("err = %v, want ErrInterrupted", err)
+			}
+			if s == nil {
+				t.Fatal("no partial summary")
+			}
+		})
+	}
+
+	// what finished before the cut is kept
+	s, _ := Parse(strings.NewReader(interruptedStream))
+	if len(s.Packages) != 1 || s.Packages[0].Passed != 1 || s.Packages[0].Status != Running || !s.Failed() {
+		t.Errorf("partial summary: %+v", s.Packages[0])
+	}
+}
+
+func TestParseQuirks(t *testing.T) {
+	long := strings.Repeat("x", 200_000) // longer than bufio.Scanner's default 64KB line
+	stream := "\n" + `{"Action":"start","Package":"p"}` + "\n\n" +
+		`{"Action":"output","Package":"p","Test":"TestBig","Output":"` + long + `\n"}` + "\n" +
+		`{"Action":"fail","Package":"p","Test":"TestBig"}` + "\n" +
+		`{"Action":"output","Package":"p","Test":"TestQuiet","Output":"noise\n"}` + "\n" +
+		`{"Action":"pass","Package":"p","Test":"TestQuiet"}` + "\n" +
+		`{"Action":"fail","Package":"p","Elapsed":1.5}` + "\n"
+	s, err := Parse(strings.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := s.Packages[0]
+	if p.Elapsed != 1500*time.Millisecond || len(p.Output) != 1 || len(p.Output[0]) != len(long)+1 {
+		t.Errorf("Elapsed %v, %d output lines", p.Elapsed, len(p.Output))
+	}
+	if s.Elapsed != 0 {
+		t.Errorf("no timestamps, Elapsed = %v", s.Elapsed)
+	}
+}
+
+func TestRender(t *testing.T) {
+	s, _ := Parse(strings.NewReader(failingStream))
+	var out strings.Builder
+	Render(&out, s)
+	want := `  [FAIL]         example/maps  1 passed, 2 failed         200ms
+      failed: TestMerge/empty
+      failed: TestMerge
+      |     main_test.go:12: Merge(nil, nil) = map[], want nil
+  1 packages, 1 tests passed, 2 failed, in 200ms
+`
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRunGoTestWithoutGo(t *testing.T) {
+	t.Setenv("PATH", "")
+	if _, err := RunGoTest(context.Background(), t.TempDir()); !errors.Is(err, ErrNoGo) {
+		t.Errorf("err = %v, want ErrNoGo", err)
+	}
+}
+
+func TestRunGoTest(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("needs the go command")
+	}
+	if testing.Short() {
+		t.Skip("builds test binaries")
+	}
+	write := func(dir, name, src string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const mainSrc = "package main\n\nfunc add(a, b int) int { return a + b }\n\nfunc main() {}\n"
+	ctx := context.Background()
+
+	t.Run("a failing test is a result", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "main.go", mainSrc)
+		write(dir, "main_test.go", `package main
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if add(2, 2) != 4 {
+		t.Error("2+2")
+	}
+}
+
+func TestWrong(t *testing.T) { t.Error("always fails") }
+`)
+		s, err := RunGoTest(ctx, dir, "main.go", "main_test.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := s.Packages[0]
+		if p.Status != Fail || p.Passed != 1 || !slices.Equal(p.FailedTests, []string{"TestWrong"}) {
+			t.Errorf("%+v", p)
+		}
+	})
+
+	t.Run("compile error", func(t *testing.T) {
+		dir := t.TempDir()
+		write(dir, "main.go", "package main\n\nfunc main() { x }\n")
+		write(dir, "main_test.go", "package main\n")
+		s, err := RunGoTest(ctx, dir, "main.go", "main_test.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := s.Packages[0]; p.Status != BuildFailed || !strings.Contains(strings.Join(p.Output, ""), "undefined: x") {
+			t.Errorf("%+v", p)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		s, err := RunGoTest(ctx, t.TempDir(), "missing.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p := s.Packages[0]; p.Status != BuildFailed {
+			t.Errorf("%+v", p)
+		}
+	})
+
+	t.Run("nothing ran", func(t *testing.T) {
+		// no files and no go.mod: go test stops before printing a single event
+		s, err := RunGoTest(ctx, t.TempDir())
+		if err == nil || !strings.Contains(err.Error(), "go.mod") || len(s.Packages) != 0 {
+			t.Errorf("err = %v, want go test's complaint about go.mod", err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SHA256Hex returns the SHA-256 digest of data as 64 lowercase hex characters
+func SHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data) // [32]byte
+	return hex.EncodeToString(sum[:])
+}
+
+// HMACSHA256 signs data with key. Unlike a plain hash, nobody without the key
+// can compute (or forge) the result. JWTs (HS256) and webhook signatures use this.
+func HMACSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data) // writing to a hash never returns an error
+	return mac.Sum(nil)
+}
+
+// ConstantTimeEqual compares two secrets in a time that doesn't depend on where they differ
+func ConstantTimeEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// Base64URLEncode uses the URL-safe alphabet (- and _ instead of + and /) without "=" padding,
+// the form used in JWTs and URLs
+func Base64URLEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Base64URLDecode accepts URL-safe base64 with or without "=" padding
+func Base64URLDecode(s string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("base64url decode %q: %w", s, err)
+	}
+	return data, nil
+}
+
+// naiveEqual is what == does on strings: stop at the first different byte.
+// It returns how many bytes it looked at, to show why that leaks information.
+func naiveEqual(a, b []byte) (bool, int) {
+	if len(a) != len(b) {
+		return false, 0
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false, i + 1
+		}
+	}
+	return true, len(a)
+}
+
+// CryptoBasicsExamples walks through hashes, HMACs and encodings
+func CryptoBasicsExamples() {
+	fmt.Println("\nSHA-256 known answers")
+	vectors := []struct{ input, want string }{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+	}
+	for _, v := range vectors {
+		got := SHA256Hex([]byte(v.input))
+		fmt.Printf("  %-5q %s match=%v\n", v.input, got, got == v.want)
+	}
+	fmt.Println("  one letter changed:", SHA256Hex([]byte("abd"))[:16]+"... (completely different)")
+
+	fmt.Println("\nHMAC-SHA256 (RFC 4231 test case 2)")
+	mac := HMACSHA256([]byte("Jefe"), []byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	fmt.Println(" ", hex.EncodeToString(mac), "match:", hex.EncodeToString(mac) == want)
+	other := HMACSHA256([]byte("wrong key"), []byte("what do ya want for nothing?"))
+	fmt.Println("  with another key:", hex.EncodeToString(other)[:16]+"...")
+
+	fmt.Println("\nWhy not compare MACs with ==")
+	// An attacker sends guesses and measures the response time. With an early-exit compare,
+	// a guess with the right first byte takes a tiny bit longer, so the MAC can be found
+	// byte by byte (256 tries per byte) instead of 2^256 tries.
+	guesses := [][]byte{
+		append([]byte{mac[0] ^ 0xff}, mac[1:]...),                  // first byte wrong
+		append(append([]byte{}, mac[:16]...), make([]byte, 16)...), // first 16 bytes right
+		mac,
+	}
+	for _, g := range guesses {
+		ok, examined := naiveEqual(mac, g)
+		fmt.Printf("  naive: equal=%-5v bytes examined=%-2d | constant time: equal=%v, always examines all %d\n",
+			ok, examined, ConstantTimeEqual(mac, g), len(mac))
+	}
+	fmt.Println("  hmac.Equal does the same as ConstantTimeEqual:", hmac.Equal(mac, guesses[2]))
+
+	fmt.Println("\nEncodings")
+	data := []byte{0xfb, 0xff, 0xfe, 'h', 'i'}
+	fmt.Println("  hex:           ", hex.EncodeToString(data))
+	fmt.Println("  base64 std:    ", base64.StdEncoding.EncodeToString(data)) // + and / break URLs
+	fmt.Println("  base64 url raw:", Base64URLEncode(data))
+	for _, s := range []string{"-__-aGk", "-__-aGk=", "a", "not base64!"} {
+		decoded, err := Base64URLDecode(s)
+		fmt.Printf("  decode %-12q -> %x %v\n", s, decoded, err)
+	}
+	for _, n := range []int{0, 1, 2, 3, 4} {
+		b := []byte(strings.Repeat("x", n))
+		back, err := Base64URLDecode(Base64URLEncode(b))
+		padded := base64.URLEncoding.EncodeToString(b)
+		back2, err2 := Base64URLDecode(padded)
+		fmt.Printf("  %d bytes: round trip %v, padded %-8q round trip %v\n", n, string(back) == string(b) && err == nil, padded, string(back2) == string(b) && err2 == nil)
+	}
+}
+
+func main() {
+	fmt.Println("Learning hashing, HMAC and encoding in Go")
+	CryptoBasicsExamples()
+}
+
+// A hash (SHA-256) is a fingerprint: same input -> same output, any change -> totally different output.
+// It's not encryption, it can't be reversed, and it proves nothing about WHO made it. HMAC adds a key for that.
+// Compare secrets (MACs, tokens, password hashes) with hmac.Equal or subtle.ConstantTimeCompare, never ==.
+// Hex and base64 are encodings, not security: anyone can decode them.
+// Never store passwords with plain SHA-256, use bcrypt/scrypt/argon2 (golang.org/x/crypto).
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSHA256Hex(t *testing.T) {
+	// FIPS 180-2 examples
+	tests := []struct{ in, want string }{
+		{"", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"abcdbcdecdefdefgefghfghighijhijkijkljklmklmnlmnomnopnopq", "248d6a61d20638b8e5c026930c3e6039a33ce45964ff2167f6ecedd419db06c1"},
+		{strings.Repeat("a", 1_000_000), "cdc76e5c9914fb9281a1c7e284d73e67f1809a48a497200e046d39ccc7112cd0"},
+	}
+	for _, tt := range tests {
+		if got := SHA256Hex([]byte(tt.in)); got != tt.want {
+			t.Errorf("SHA256Hex(%.10q...) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// RFC 4231 test cases 1, 2 and 6 (a key longer than the block size)
+func TestHMACSHA256(t *testing.T) {
+	tests := []struct {
+		key, data []byte
+		want      string
+	}{
+		{bytes.Repeat([]byte{0x0b}, 20), []byte("Hi There"), "b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7"},
+		{[]byte("Jefe"), []byte("what do ya want for nothing?"), "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{bytes.Repeat([]byte{0xaa}, 131), []byte("Test Using Larger Than Block-Size Key - Hash Key First"), "60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54"},
+	}
+	for _, tt := range tests {
+		if got := hex.EncodeToString(HMACSHA256(tt.key, tt.data)); got != tt.want {
+			t.Errorf("HMACSHA256(%x...) = %s, want %s", tt.key[:4], got, tt.want)
+		}
+	}
+}
+
+func TestConstantTimeEqual(t *testing.T) {
+	mac := HMACSHA256([]byte("k"), []byte("data"))
+	flipped := bytes.Clone(mac)
+	flipped[len(flipped)-1] ^= 1
+	tests := []struct {
+		name string
+		a, b []byte
+		want bool
+	}{
+		{"same", mac, bytes.Clone(mac), true},
+		{"last byte differs", mac, flipped, false},
+		{"shorter", mac, mac[:31], false},
+		{"both empty", nil, []byte{}, true},
+	}
+	for _, tt := range tests {
+		if got := ConstantTimeEqual(tt.a, tt.b); got != tt.want || got != hmac.Equal(tt.a, tt.b) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// the naive compare stops early, that's the timing leak
+	if ok, examined := naiveEqual(mac, flipped); ok || examined != len(mac) {
+		t.Errorf("naiveEqual(last byte differs) = %v, %d", ok, examined)
+	}
+	first := bytes.Clone(mac)
+	first[0] ^= 1
+	if _, examined := naiveEqual(mac, first); examined != 1 {
+		t.Errorf("naiveEqual looked at %d bytes, want 1", examined)
+	}
+}
+
+func TestBase64URLRoundTrip(t *testing.T) {
+	for n := range 8 { // every padding length: 0, 1 and 2 "=" signs
+		data := bytes.Repeat([]byte{0xfb, 0xff, 0xfe}, 3)[:n]
+		raw := Base64URLEncode(data)
+		if strings.ContainsAny(raw, "=+/") {
+			t.Errorf("%d bytes: %q has padding or a non URL-safe character", n, raw)
+		}
+		padded := base64.URLEncoding.EncodeToString(data)
+		for _, s := range []string{raw, padded} {
+			back, err := Base64URLDecode(s)
+			if err != nil || !bytes.Equal(back, data) {
+				t.Errorf("%d bytes: decode %q = %x, %v", n, s, back, err)
+			}
+		}
+	}
+	if got := Base64URLEncode([]byte{0xfb, 0xff, 0xfe, 'h', 'i'}); got != "-__-aGk" {
+		t.Errorf("known value: %q", got)
+	}
+}
+
+func TestBase64URLDecodeRejects(t *testing.T) {
+	for _, s := range []string{"a", "not base64!", "+/8=", "a=b"} {
+		if b, err := Base64URLDecode(s); err == nil {
+			t.Errorf("Base64URLDecode(%q) = %x, want an error", s, b)
+		}
+	}
+}
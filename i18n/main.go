@@ -0,0 +1,313 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Messages shown to people live in a catalog, one JSON file per language, embedded in
+// the binary. Code refers to a message by key and says which language it wants.
+// A key missing in a language falls back to English, so a half-translated catalog still
+// shows something readable.
+
+const DefaultLang = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// message is either one text, or one text per plural form ("one", "other")
+type message struct {
+	text  string
+	forms map[string]string
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &m.text); err == nil {
+		return nil
+	}
+	return json.Unmarshal(data, &m.forms)
+}
+
+type Catalog map[string]map[string]message // lang -> key -> message
+
+func LoadCatalog(fsys fs.FS) (Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := Catalog{}
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		msgs := map[string]message{}
+		if err := json.Unmarshal(data, &msgs); err != nil {
+			return nil, fmt.Errorf("load %s: %w", f, err)
+		}
+		c[strings.TrimSuffix(path.Base(f), ".json")] = msgs
+	}
+	if _, ok := c[DefaultLang]; !ok {
+		return nil, fmt.Errorf("load catalog: no %s.json, nothing to fall back to", DefaultLang)
+	}
+	return c, nil
+}
+
+// pluralForm picks the form for n. Languages differ: English says "0 users",
+// Hindi uses the singular form for 0 and 1.
+func pluralForm(lang string, n int) string {
+	switch lang {
+	case "hi":
+		if n == 0 || n == 1 {
+			return "one"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+func (c Catalog) lookup(lang, key string, n int) (string, bool) {
+	m, ok := c[lang][key]
+	if !ok {
+		return "", false
+	}
+	if m.forms == nil {
+		return m.text, true
+	}
+	s, ok := m.forms[pluralForm(lang, n)]
+	if !ok {
+		s, ok = m.forms["other"]
+	}
+	return s, ok
+}
+
+// T formats key in lang, falling back to English and then to the key itself.
+// For plural messages the first argument is the count.
+func (c Catalog) T(lang, key string, args ...any) string {
+	n := 0
+	if len(args) > 0 {
+		n, _ = args[0].(int)
+	}
+	s, ok := c.lookup(lang, key, n)
+	if !ok {
+		s, ok = c.lookup(DefaultLang, key, n)
+	}
+	if !ok {
+		return key // visible in the UI and easy to grep for, better than an empty string
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+func (c Catalog) Languages() []string {
+	langs := make([]string, 0, len(c))
+	for l := range c {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ----------------------------------------------------------------------------
+// Choosing the language
+
+// ParseAcceptLanguage turns "hi-IN,hi;q=0.9,en;q=0.8" into tags sorted by q, highest first.
+// q=0 means "not this one" and is dropped.
+func ParseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				continue
+			}
+			q = parsed
+		}
+		if name == "" || q == 0 {
+			continue
+		}
+		tags = append(tags, tag{strings.ToLower(name), q})
+	}
+	slices.SortStableFunc(tags, func(a, b tag) int { // stable: equal q keeps the client's order
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Match returns the first wanted language the catalog has. "hi-IN" matches "hi", "*" matches anything.
+func (c Catalog) Match(wanted ...string) string {
+	for _, w := range wanted {
+		if w == "*" {
+			return DefaultLang
+		}
+		base, _, _ := strings.Cut(w, "-")
+		if _, ok := c[base]; ok {
+			return base
+		}
+	}
+	return DefaultLang
+}
+
+// langFromEnv reads LANG, which looks like "hi_IN.UTF-8"
+func langFromEnv() string {
+	l, _, _ := strings.Cut(os.Getenv("LANG"), ".")
+	l, _, _ = strings.Cut(l, "_")
+	return strings.ToLower(l)
+}
+
+// ----------------------------------------------------------------------------
+// Consumers: API errors and validation
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ErrorEnvelope struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+func validateUser(c Catalog, lang, name, email string) []FieldError {
+	var errs []FieldError
+	if len([]rune(name)) < 2 {
+		errs = append(errs, FieldError{"name", c.T(lang, "validation.too_short", "name", 2)})
+	}
+	if email == "" {
+		errs = append(errs, FieldError{"email", c.T(lang, "validation.required", "email")})
+	}
+	return errs
+}
+
+func routes(c Catalog) http.Handler {
+	mux := http.NewServeMux()
+	lang := func(r *http.Request) string { return c.Match(ParseAcceptLanguage(r.Header.Get("Accept-Language"))...) }
+	writeJSON := func(w http.ResponseWriter, r *http.Request, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Language", lang(r))
+		w.Header().Set("Vary", "Accept-Language") // caches must keep one copy per language
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		writeJSON(w, r, http.StatusNotFound, ErrorEnvelope{Error: c.T(lang(r), "error.not_found", id)})
+	})
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Name, Email string }
+		json.NewDecoder(r.Body).Decode(&in)
+		if errs := validateUser(c, lang(r), in.Name, in.Email); errs != nil {
+			writeJSON(w, r, http.StatusUnprocessableEntity, ErrorEnvelope{Error: c.T(lang(r), "validation.failed", len(errs)), Fields: errs})
+			return
+		}
+		writeJSON(w, r, http.StatusInternalServerError, ErrorEnvelope{Error: c.T(lang(r), "error.internal")})
+	})
+	return mux
+}
+
+// usedKeys lists every key the code above passes to T. checkCatalog fails the start
+// when one is missing from English, so a typo shows up at once, not in front of a user.
+var usedKeys = []string{
+	"menu.title", "menu.choose", "error.not_found", "error.internal",
+	"validation.required", "validation.too_short", "validation.failed", "users.count",
+}
+
+func checkCatalog(c Catalog) error {
+	var errs []error
+	for _, k := range usedKeys {
+		if _, ok := c[DefaultLang][k]; !ok {
+			errs = append(errs, fmt.Errorf("key %q is used but missing from %s.json", k, DefaultLang))
+		}
+	}
+	for _, lang := range c.Languages() {
+		for k := range c[lang] {
+			if _, ok := c[DefaultLang][k]; !ok {
+				errs = append(errs, fmt.Errorf("%s.json has %q, which %s.json doesn't", lang, k, DefaultLang))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func main() {
+	langFlag := flag.String("lang", "", "language for the output (default: from $LANG)")
+	flag.Parse()
+
+	catalog, err := LoadCatalog(localeFiles)
+	if err == nil {
+		err = checkCatalog(catalog)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	cliLang := catalog.Match(*langFlag, langFromEnv())
+
+	fmt.Println("Learning message localization in Go")
+	fmt.Printf("Languages %v, this run uses %q (-lang, then $LANG)\n", catalog.Languages(), cliLang)
+	fmt.Println(catalog.T(cliLang, "menu.title"))
+	fmt.Println(catalog.T(cliLang, "menu.choose", 12))
+
+	fmt.Println("Same keys in both languages:")
+	for _, lang := range []string{"en", "hi"} {
+		fmt.Printf("  %s: %s | %s | %s | %s\n", lang, catalog.T(lang, "menu.title"),
+			catalog.T(lang, "users.count", 0), catalog.T(lang, "users.count", 1), catalog.T(lang, "users.count", 5))
+	}
+	fmt.Println("  hi, missing key, falls back to English:", catalog.T("hi", "error.internal"))
+	fmt.Println("  a key nobody defined:", catalog.T("en", "error.typo"))
+
+	fmt.Println("Accept-Language:")
+	for _, h := range []string{"hi-IN,hi;q=0.9,en;q=0.8", "fr-CH, fr;q=0.9, en;q=0.5", "en;q=0.3, hi;q=0.7", "hi;q=0, *", "de", ""} {
+		fmt.Printf("  %-28q -> %v -> %s\n", h, ParseAcceptLanguage(h), catalog.Match(ParseAcceptLanguage(h)...))
+	}
+
+	h := routes(catalog)
+	for _, al := range []string{"en-US", "hi-IN,en;q=0.5"} {
+		for _, req := range []*http.Request{
+			httptest.NewRequest("GET", "/api/users/42", nil),
+			httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"R"}`)),
+			httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Rishabh","email":"r@example.com"}`)),
+		} {
+			req.Header.Set("Accept-Language", al)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			fmt.Printf("  %-15s %s %-14s -> %d [%s] %s", al, req.Method, req.URL.Path, rec.Code, rec.Header().Get("Content-Language"), rec.Body.String())
+		}
+	}
+}
+
+// Keep the texts in one embedded file per language, the code only knows keys.
+// Fall back to English for a missing key, and to the key itself as a last resort, never an empty string.
+// Plural rules differ by language ("0 users" vs "0 उपयोगकर्ता" uses the singular): pick the form per language.
+// Accept-Language is a list with q-values: sort by q, match "hi-IN" to "hi", send Content-Language and Vary.
+// Check at startup that every key the code uses exists in English, a typo then fails at startup, not in front of a user.
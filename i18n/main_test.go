@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func loadCatalog(t *testing.T) Catalog {
+	t.Helper()
+	c, err := LoadCatalog(localeFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{}},
+		{"hi", []string{"hi"}},
+		{"hi-IN,hi;q=0.9,en;q=0.8", []string{"hi-in", "hi", "en"}},
+		{"en;q=0.3, hi;q=0.7", []string{"hi", "en"}},
+		{"fr-CH, fr;q=0.9, en;q=0.5", []string{"fr-ch", "fr", "en"}},
+		{"en;q=0.5, hi;q=0.5, de", []string{"de", "en", "hi"}}, // equal q keeps the client's order
+		{"hi;q=0, en", []string{"en"}},                         // q=0 means "not this one"
+		{"hi;q=abc, en;q=2, de;q=-1, fr;q=1", []string{"fr"}},  // malformed q: ignore the tag
+		{" , ;q=0.5,EN-us", []string{"en-us"}},
+		{"*", []string{"*"}},
+	}
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	c := loadCatalog(t)
+	tests := []struct {
+		wanted []string
+		want   string
+	}{
+		{nil, "en"},
+		{[]string{"hi-in"}, "hi"},
+		{[]string{"fr-ch", "fr", "hi"}, "hi"},
+		{[]string{"de", "en", "hi"}, "en"},
+		{[]string{"*", "hi"}, "en"},
+		{[]string{"", "hi"}, "hi"}, // an empty -lang flag falls through to $LANG
+		{[]string{"de"}, "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.wanted...); got != tt.want {
+			t.Errorf("Match(%q) = %q, want %q", tt.wanted, got, tt.want)
+		}
+	}
+}
+
+func TestLangFromEnv(t *testing.T) {
+	for env, want := range map[string]string{"hi_IN.UTF-8": "hi", "en_US": "en", "C": "c", "": ""} {
+		t.Setenv("LANG", env)
+		if got := langFromEnv(); got != want {
+			t.Errorf("LANG=%q: %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	c := loadCatalog(t)
+	tests := []struct {
+		lang, key string
+		args      []any
+		want      string
+	}{
+		{"en", "error.not_found", []any{42}, "User 42 was not found"},
+		{"hi", "error.not_found", []any{42}, "उपयोगकर्ता 42 नहीं मिला"},
+		{"hi", "error.internal", nil, "Something went wrong, please try again"}, // not translated yet
+		{"de", "menu.title", nil, "Go Learning Menu"},                           // language not there at all
+		{"hi", "error.typo", nil, "error.typo"},                                 // nowhere: the key itself
+	}
+	for _, tt := range tests {
+		if got := c.T(tt.lang, tt.key, tt.args...); got != tt.want {
+			t.Errorf("T(%s, %s) = %q, want %q", tt.lang, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestPlural(t *testing.T) {
+	c := loadCatalog(t)
+	tests := []struct {
+		lang string
+		n    int
+		want string
+	}{
+		{"en", 0, "0 users"},
+		{"en", 1, "1 user"},
+		{"en", 2, "2 users"},
+		{"en", -1, "-1 users"},
+		{"hi", 0, "0 उपयोगकर्ता"},
+		{"hi", 1, "1 उपयोगकर्ता"},
+		{"hi", 5, "5 उपयोगकर्ता"},
+	}
+	for _, tt := range tests {
+		if got := c.T(tt.lang, "users.count", tt.n); got != tt.want {
+			t.Errorf("%s %d: %q, want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+	// the forms chosen differ even where the words don't
+	for n, want := range map[int][2]string{0: {"other", "one"}, 1: {"one", "one"}, 2: {"other", "other"}} {
+		if en, hi := pluralForm("en", n), pluralForm("hi", n); en != want[0] || hi != want[1] {
+			t.Errorf("%d: en %s hi %s, want %v", n, en, hi, want)
+		}
+	}
+	if got := c.T("hi", "validation.failed", 0); got != "0 फ़ील्ड अमान्य है" {
+		t.Errorf("hi, 0 fields: %q", got)
+	}
+
+	// a message with only "other" uses it for every count
+	fsys := fstest.MapFS{"locales/en.json": {Data: []byte(`{"x": {"other": "%d things"}}`)}}
+	c, err := LoadCatalog(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.T("en", "x", 1); got != "1 things" {
+		t.Errorf("only other: %q", got)
+	}
+}
+
+func TestLoadCatalogErrors(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no en.json": {"locales/hi.json": {Data: []byte(`{}`)}},
+		"bad json":   {"locales/en.json": {Data: []byte(`{"a": `)}},
+		"bad value":  {"locales/en.json": {Data: []byte(`{"a": 3}`)}},
+	}
+	for name, fsys := range tests {
+		if _, err := LoadCatalog(fsys); err == nil {
+			t.Errorf("%s: loaded", name)
+		}
+	}
+}
+
+// keysInCode finds the string literal passed as the key to every T call in main.go
+func keysInCode(t *testing.T) []string {
+	t.Helper()
+	f, err := parser.ParseFile(token.NewFileSet(), "main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "T" {
+			return true
+		}
+		lit, ok := call.Args[1].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		key, _ := strconv.Unquote(lit.Value)
+		keys = append(keys, key)
+		return true
+	})
+	return keys
+}
+
+// every key the code passes to T is in English and in usedKeys, so checkCatalog covers it
+func TestKeysInCodeExist(t *testing.T) {
+	c := loadCatalog(t)
+	keys := keysInCode(t)
+	if len(keys) < 8 {
+		t.Fatalf("found only %d T calls: %v", len(keys), keys)
+	}
+	known := map[string]bool{"error.typo": true} // the demo's deliberate miss
+	for _, k := range keys {
+		if known[k] {
+			continue
+		}
+		if _, ok := c[DefaultLang][k]; !ok {
+			t.Errorf("%q is used but missing from en.json", k)
+		}
+		if !slices.Contains(usedKeys, k) {
+			t.Errorf("%q is used but not in usedKeys", k)
+		}
+	}
+	if err := checkCatalog(c); err != nil {
+		t.Error(err)
+	}
+}
+
+// a translation takes the same arguments as the English text, or Sprintf prints %!d(MISSING)
+func TestTranslationsTakeTheSameArgs(t *testing.T) {
+	c := loadCatalog(t)
+	verbs := func(m message) []string {
+		texts := []string{m.text}
+		if m.forms != nil {
+			texts = nil
+		}
+		for _, s := range m.forms {
+			texts = append(texts, s)
+		}
+		var out []string
+		for _, s := range texts {
+			var vs []string
+			for i := strings.Index(s, "%"); i >= 0 && i+1 < len(s); i = strings.Index(s, "%") {
+				vs = append(vs, s[i:i+2])
+				s = s[i+2:]
+			}
+			out = append(out, strings.Join(vs, " "))
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+	for _, lang := range c.Languages() {
+		for k, m := range c[lang] {
+			en := verbs(c[DefaultLang][k])
+			if got := verbs(m); len(en) != 1 || !slices.Equal(got, en) {
+				t.Errorf("%s %s: verbs %q, English %q", lang, k, got, en)
+			}
+		}
+	}
+}
+
+func TestCheckCatalog(t *testing.T) {
+	c := Catalog{"en": {"menu.title": {text: "x"}}, "hi": {"only.hindi": {text: "y"}}}
+	err := checkCatalog(c)
+	if err == nil || !strings.Contains(err.Error(), `"menu.choose" is used but missing`) ||
+		!strings.Contains(err.Error(), `hi.json has "only.hindi"`) {
+		t.Errorf("checkCatalog = %v", err)
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	h := routes(loadCatalog(t))
+	tests := []struct {
+		method, target, body, acceptLang string
+		code                             int
+		lang                             string
+		want                             ErrorEnvelope
+	}{
+		{"GET", "/api/users/42", "", "en-US", 404, "en", ErrorEnvelope{Error: "User 42 was not found"}},
+		{"GET", "/api/users/42", "", "de, hi;q=0.5", 404, "hi", ErrorEnvelope{Error: "उपयोगकर्ता 42 नहीं मिला"}},
+		{"GET", "/api/users/42", "", "", 404, "en", ErrorEnvelope{Error: "User 42 was not found"}},
+		{"POST", "/api/users", `{"name":"R"}`, "en", 422, "en", ErrorEnvelope{Error: "2 fields are invalid", Fields: []FieldError{
+			{"name", "name must be at least 2 characters"}, {"email", "email is required"}}}},
+		{"POST", "/api/users", `{"name":"Rishabh"}`, "hi-IN,en;q=0.5", 422, "hi", ErrorEnvelope{Error: "1 फ़ील्ड अमान्य है", Fields: []FieldError{
+			{"email", "email आवश्यक है"}}}},
+		{"POST", "/api/users", `{"name":"Rishabh","email":"r@example.com"}`, "hi", 500, "hi", ErrorEnvelope{Error: "Something went wrong, please try again"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Accept-Language", tt.acceptLang)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		var got ErrorEnvelope
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if rec.Code != tt.code || rec.Header().Get("Content-Language") != tt.lang ||
+			got.Error != tt.want.Error || !slices.Equal(got.Fields, tt.want.Fields) {
+			t.Errorf("%s %s [%s]: %d [%s] %s", tt.method, tt.target, tt.acceptLang, rec.Code, rec.Header().Get("Content-Language"), rec.Body)
+		}
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("%s %s: no Vary", tt.method, tt.target)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInvalidUUID is returned by ParseUUID for malformed input
+var ErrInvalidUUID = errors.New("invalid UUID")
+
+// NewUUIDv4 returns a random UUID (RFC 4122 version 4) like "9b2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b3c".
+// 122 of the 128 bits are random, so two UUIDs colliding is practically impossible.
+func NewUUIDv4() string {
+	var b [16]byte
+	rand.Read(b[:])             // crypto/rand.Read never fails on supported platforms (Go 1.24+)
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4: the 13th hex digit is always 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variant RFC 4122: the 17th hex digit is 8, 9, a or b
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// ParseUUID checks that s is a UUID in the canonical 8-4-4-4-12 form (either case)
+func ParseUUID(s string) error {
+	if len(s) != 36 {
+		return fmt.Errorf("%w %q: length %d, want 36", ErrInvalidUUID, s, len(s))
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return fmt.Errorf("%w %q: expected '-' at position %d", ErrInvalidUUID, s, i)
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+				return fmt.Errorf("%w %q: %q at position %d is not hex", ErrInvalidUUID, s, r, i)
+			}
+		}
+	}
+	return nil
+}
+
+// crockford is Crockford's base32 alphabet: no I, L, O or U, so IDs are easy to read aloud
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator keeps the last timestamp and random part to make ULIDs monotonic
+var ulidGenerator struct {
+	mu     sync.Mutex
+	lastMs uint64
+	last   [10]byte // 80 random bits
+}
+
+// NewULID returns a 26 character ULID: 48 bits of milliseconds + 80 random bits.
+// ULIDs sort by creation time as plain strings. Within the same millisecond the random
+// part is incremented by one instead of regenerated, so the order is still strictly increasing.
+func NewULID() string {
+	g := &ulidGenerator
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMs {
+		// same millisecond (or the clock went back): last + 1
+		ms = g.lastMs
+		if !increment(g.last[:]) {
+			ms++ // all 80 bits overflowed (practically never): move to the next millisecond
+		}
+	} else {
+		rand.Read(g.last[:])
+	}
+	g.lastMs = ms
+
+	var b [16]byte
+	b[0], b[1], b[2], b[3], b[4], b[5] = byte(ms>>40), byte(ms>>32), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms)
+	copy(b[6:], g.last[:])
+	return encodeCrockford(b)
+}
+
+// increment adds 1 to a big-endian number, false when it overflowed to zero
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeCrockford writes 128 bits as 26 base32 characters (26*5 = 130 bits, the top 2 are zero)
+func encodeCrockford(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59 // shift the whole 128-bit number right by 5
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// Sequence hands out increasing int IDs, for code that needs small numeric keys
+type Sequence struct {
+	n atomic.Int64
+}
+
+func (s *Sequence) Next() int64 { return s.n.Add(1) }
+
+// timeBasedID is the old approach: IDs from the clock collide whenever two are made close together
+func timeBasedID() int64 {
+	return time.Now().Unix() % 10000
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func main() {
+	fmt.Println("Learning to generate unique IDs in Go")
+
+	// the problem
+	seen := make(map[int64]bool)
+	collisions := 0
+	for i := 0; i < 1000; i++ {
+		id := timeBasedID()
+		if seen[id] {
+			collisions++
+		}
+		seen[id] = true
+	}
+	fmt.Println("time.Now().Unix()%10000: 1000 IDs,", collisions, "collisions")
+
+	// UUID v4
+	u := NewUUIDv4()
+	fmt.Println("UUID v4:", u, "| matches v4 format:", uuidPattern.MatchString(u))
+	const n = 100_000
+	uuids := make(map[string]bool, n)
+	badFormat := 0
+	for i := 0; i < n; i++ {
+		id := NewUUIDv4()
+		if !uuidPattern.MatchString(id) {
+			badFormat++
+		}
+		uuids[id] = true
+	}
+	fmt.Printf("%d UUIDs: %d unique, %d with a bad format\n", n, len(uuids), badFormat)
+
+	for _, s := range []string{u, strings.ToUpper(u), "not-a-uuid", "9b2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b3", "9b2e4c1a_3f5d-4e8b-a1c2-7d9e0f1a2b3c", "zb2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b3c"} {
+		fmt.Printf("  ParseUUID(%q): %v\n", s, ParseUUID(s))
+	}
+
+	// ULID
+	fmt.Println("ULID:", NewULID())
+	ulids := make([]string, n)
+	for i := range ulids {
+		ulids[i] = NewULID() // many in the same millisecond
+	}
+	unique := make(map[string]bool, n)
+	for _, id := range ulids {
+		unique[id] = true
+	}
+	fmt.Printf("%d ULIDs: %d unique, already in sorted order: %v\n", n, len(unique), sort.StringsAreSorted(ulids))
+	fmt.Println("  first:", ulids[0], "last:", ulids[n-1], "(time prefix first, so string order is creation order)")
+
+	// concurrent generation is safe too
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	concurrent := make(map[string]bool)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			local := make([]string, 1000)
+			for i := range local {
+				local[i] = NewULID()
+			}
+			mu.Lock()
+			for _, id := range local {
+				concurrent[id] = true
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	fmt.Println("8 goroutines x 1000 ULIDs:", len(concurrent), "unique")
+
+	var seq Sequence
+	fmt.Println("Sequence:", seq.Next(), seq.Next(), seq.Next())
+}
+
+// UUID v4: random, no coordination needed, but not sortable and bad for database index locality.
+// ULID (and UUID v7): timestamp first, so they sort by creation time and insert at the end of an index.
+// Always use crypto/rand for IDs that must be unguessable, math/rand is predictable.
+// In real projects use a library: github.com/google/uuid or github.com/oklog/ulid.
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`) // 128 bits: the first character is at most 7
+
+func TestUUIDv4Format(t *testing.T) {
+	const n = 100_000
+	seen := make(map[string]bool, n)
+	for range n {
+		id := NewUUIDv4()
+		if !uuidPattern.MatchString(id) {
+			t.Fatalf("%q is not a v4 UUID", id)
+		}
+		if err := ParseUUID(id); err != nil {
+			t.Fatalf("ParseUUID(%q): %v", id, err)
+		}
+		seen[id] = true
+	}
+	if len(seen) != n {
+		t.Errorf("%d unique out of %d", len(seen), n)
+	}
+}
+
+func TestParseUUIDRejects(t *testing.T) {
+	good := "9b2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b3c"
+	if err := ParseUUID(good); err != nil {
+		t.Fatal(err)
+	}
+	if err := ParseUUID(strings.ToUpper(good)); err != nil {
+		t.Errorf("upper case: %v", err)
+	}
+	for _, s := range []string{
+		"",
+		"not-a-uuid",
+		good[:35],
+		good + "0",
+		"9b2e4c1a_3f5d-4e8b-a1c2-7d9e0f1a2b3c",
+		"9b2e4c1a-3f5d-4e8b-a1c27-d9e0f1a2b3c",
+		"zb2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b3c",
+		"{9b2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2b}",
+		"9b2e4c1a3f5d4e8ba1c27d9e0f1a2b3c0000",
+		"9b2e4c1a-3f5d-4e8b-a1c2-7d9e0f1a2bé", // 36 bytes, but not hex
+	} {
+		if err := ParseUUID(s); !errors.Is(err, ErrInvalidUUID) {
+			t.Errorf("ParseUUID(%q) = %v, want ErrInvalidUUID", s, err)
+		}
+	}
+}
+
+func TestULIDOrderAndFormat(t *testing.T) {
+	const n = 100_000
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = NewULID() // many share a millisecond: the random part counts up
+	}
+	for i, id := range ids {
+		if !ulidPattern.MatchString(id) {
+			t.Fatalf("%q is not a ULID", id)
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Fatalf("not strictly increasing: %q then %q", ids[i-1], id)
+		}
+	}
+
+	// the first 10 characters are the time in milliseconds
+	before := time.Now().UnixMilli()
+	id := NewULID()
+	if ms := decodeTime(t, id); ms < before-1 || ms > time.Now().UnixMilli()+1000 {
+		t.Errorf("%s carries %d ms, now is %d", id, ms, before)
+	}
+}
+
+func decodeTime(t *testing.T, id string) int64 {
+	t.Helper()
+	var ms int64
+	for _, c := range id[:10] {
+		ms = ms<<5 | int64(strings.IndexRune(crockford, c))
+	}
+	return ms
+}
+
+func TestULIDConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	results := make([][]string, 8)
+	for g := range results {
+		wg.Go(func() {
+			for range 2000 {
+				results[g] = append(results[g], NewULID())
+			}
+		})
+	}
+	wg.Wait()
+	all := slices.Concat(results...)
+	for _, ids := range results {
+		if !slices.IsSorted(ids) {
+			t.Error("one goroutine's ULIDs are out of order")
+		}
+	}
+	slices.Sort(all)
+	if len(slices.Compact(all)) != 8*2000 {
+		t.Error("duplicate ULIDs across goroutines")
+	}
+}
+
+func TestEncodeCrockford(t *testing.T) {
+	var zero, ones [16]byte
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	if got := encodeCrockford(zero); got != strings.Repeat("0", 26) {
+		t.Errorf("zero: %s", got)
+	}
+	if got := encodeCrockford(ones); got != "7"+strings.Repeat("Z", 25) {
+		t.Errorf("max: %s", got)
+	}
+	one := [16]byte{15: 1}
+	if got := encodeCrockford(one); got != strings.Repeat("0", 25)+"1" {
+		t.Errorf("one: %s", got)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	b := []byte{0x00, 0xff, 0xff}
+	if !increment(b) || !slices.Equal(b, []byte{0x01, 0x00, 0x00}) {
+		t.Errorf("carry: %x", b)
+	}
+	b = []byte{0xff, 0xff}
+	if increment(b) || !slices.Equal(b, []byte{0, 0}) {
+		t.Errorf("overflow: %x", b)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	var seq Sequence
+	var wg sync.WaitGroup
+	got := make([]int64, 1000)
+	for i := range got {
+		wg.Go(func() { got[i] = seq.Next() })
+	}
+	wg.Wait()
+	slices.Sort(got)
+	for i, v := range got {
+		if v != int64(i+1) {
+			t.Fatalf("sorted IDs %v... are not 1..1000", got[:5])
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// "Accept interfaces, return structs":
+//   - a function asks for the smallest interface that has what it calls
+//   - a constructor returns the concrete type, callers get all its methods and its docs
+// The interface belongs to the CONSUMER. Go interfaces are satisfied implicitly,
+// so the consumer can declare exactly the methods it needs, next to the code that needs them.
+
+// ----------------------------------------------------------------------------
+// Before: one wide interface
+
+// DataStorage is what a storage package often starts with: everything in one place.
+// Anything that needs to load one key now depends on six methods, and a test fake must write all six.
+type DataStorage interface {
+	Save(key, value string) error
+	Load(key string) (string, error)
+	Delete(key string) error
+	List(prefix string) ([]string, error)
+	Len() int
+	Close() error
+}
+
+// ----------------------------------------------------------------------------
+// After: small role interfaces, composed where a caller really needs more
+
+type Reader interface {
+	Load(key string) (string, error)
+}
+
+type Writer interface {
+	Save(key, value string) error
+	Delete(key string) error
+}
+
+type Lister interface {
+	List(prefix string) ([]string, error)
+}
+
+// Storage is the full set, used only by code that really needs all of it (the conformance check)
+type Storage interface {
+	Reader
+	Writer
+	Lister
+}
+
+var ErrNotFound = errors.New("not found")
+
+// MemoryStorage and FileStorage are returned as structs, not as Storage
+type MemoryStorage struct {
+	mu   sync.RWMutex
+	data map[string]string
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{data: make(map[string]string)}
+}
+
+func (m *MemoryStorage) Save(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value
+	return nil
+}
+
+func (m *MemoryStorage) Load(key string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.data[key]
+	if !ok {
+		return "", fmt.Errorf("load %q: %w", key, ErrNotFound)
+	}
+	return v, nil
+}
+
+func (m *MemoryStorage) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, key)
+	return nil
+}
+
+func (m *MemoryStorage) List(prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var keys []string
+	for k := range m.data {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+func (m *MemoryStorage) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.data)
+}
+
+func (m *MemoryStorage) Close() error { return nil }
+
+// FileStorage keeps one file per key in a directory
+type FileStorage struct {
+	dir string
+}
+
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("file storage: %w", err)
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+func (f *FileStorage) path(key string) string {
+	return filepath.Join(f.dir, strings.ReplaceAll(key, "/", "_"))
+}
+
+func (f *FileStorage) Save(key, value string) error {
+	return os.WriteFile(f.path(key), []byte(value), 0o644)
+}
+
+func (f *FileStorage) Load(key string) (string, error) {
+	data, err := os.ReadFile(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("load %q: %w", key, ErrNotFound)
+	}
+	return string(data), err
+}
+
+func (f *FileStorage) Delete(key string) error {
+	err := os.Remove(f.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (f *FileStorage) List(prefix string) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			keys = append(keys, e.Name())
+		}
+	}
+	return keys, nil // ReadDir is already sorted by name
+}
+
+// Compile-time checks: the build fails if a backend stops satisfying a role.
+// Nothing is allocated, the nil pointer only carries the type.
+var (
+	_ Storage     = (*MemoryStorage)(nil)
+	_ Storage     = (*FileStorage)(nil)
+	_ DataStorage = (*MemoryStorage)(nil) // FileStorage has no Len/Close and doesn't need them
+)
+
+// checkStorage is the conformance check every backend goes through. It only needs Storage.
+func checkStorage(s Storage) error {
+	if err := s.Save("user:1", "Rishabh"); err != nil {
+		return err
+	}
+	if v, err := s.Load("user:1"); err != nil || v != "Rishabh" {
+		return fmt.Errorf("load after save: %q, %v", v, err)
+	}
+	if _, err := s.Load("user:404"); !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("missing key: want ErrNotFound, got %v", err)
+	}
+	s.Save("user:2", "Sanchay")
+	s.Save("order:1", "book")
+	if keys, _ := s.List("user:"); !slices.Equal(keys, []string{"user:1", "user:2"}) {
+		return fmt.Errorf("list: %v", keys)
+	}
+	if err := s.Delete("user:1"); err != nil {
+		return err
+	}
+	if _, err := s.Load("user:1"); !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("load after delete: %v", err)
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// The consumer declares what it needs
+
+// userGetter is all UserService calls. It's unexported and lives here, next to its only user.
+type userGetter interface {
+	Load(key string) (string, error)
+}
+
+type UserService struct {
+	store userGetter
+}
+
+func NewUserService(store userGetter) *UserService {
+	return &UserService{store: store}
+}
+
+func (s *UserService) Greeting(id int) (string, error) {
+	name, err := s.store.Load(fmt.Sprintf("user:%d", id))
+	if err != nil {
+		return "", fmt.Errorf("greeting for %d: %w", id, err)
+	}
+	return "Hello, " + name + "!", nil
+}
+
+// fakeUsers is the whole test fake: one method instead of six
+type fakeUsers map[string]string
+
+func (f fakeUsers) Load(key string) (string, error) {
+	if v, ok := f[key]; ok {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+// even a plain function can be the dependency, like http.HandlerFunc
+type loaderFunc func(key string) (string, error)
+
+func (f loaderFunc) Load(key string) (string, error) { return f(key) }
+
+// ----------------------------------------------------------------------------
+// The nil interface gotcha
+
+type ValidationError struct{ Field string }
+
+func (e *ValidationError) Error() string { return "invalid " + e.Field }
+
+// validateBad returns a typed nil pointer as an error. An interface holding
+// (type=*ValidationError, value=nil) is NOT a nil interface.
+func validateBad(name string) error {
+	var verr *ValidationError
+	if name == "" {
+		verr = &ValidationError{Field: "name"}
+	}
+	return verr // never nil, even when verr is
+}
+
+// validateGood returns a literal nil when there's no error
+func validateGood(name string) error {
+	if name == "" {
+		return &ValidationError{Field: "name"}
+	}
+	return nil
+}
+
+func InterfaceDesignExamples() {
+	fmt.Println("Conformance check on every backend:")
+	dir, err := os.MkdirTemp("", "storage")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	files, err := NewFileStorage(dir)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, s := range []Storage{NewMemoryStorage(), files} {
+		fmt.Printf("  %-21T %v\n", s, checkStorage(s))
+	}
+
+	fmt.Println("UserService with three kinds of dependency:")
+	mem := NewMemoryStorage()
+	mem.Save("user:1", "Rishabh")
+	for _, store := range []userGetter{
+		mem,                         // the real store
+		fakeUsers{"user:1": "Fake"}, // a one-method fake
+		loaderFunc(func(string) (string, error) { return "", errors.New("db down") }),
+	} {
+		msg, err := NewUserService(store).Greeting(1)
+		fmt.Printf("  %-20T %q %v\n", store, msg, err)
+	}
+
+	// a narrow interface hides the other methods: through userGetter, Save doesn't exist
+	var g userGetter = mem
+	_, canWrite := g.(Writer) // a type assertion can still ask, on purpose
+	fmt.Println("userGetter holding *MemoryStorage is also a Writer:", canWrite)
+
+	fmt.Println("nil interface vs nil pointer:")
+	err = validateBad("Rishabh")
+	fmt.Printf("  validateBad(ok):  err != nil is %v, but it holds a %T that is nil: %v\n", err != nil, err, err == (*ValidationError)(nil))
+	err = validateGood("Rishabh")
+	fmt.Printf("  validateGood(ok): err != nil is %v\n", err != nil)
+	var ve *ValidationError
+	fmt.Println("  if err != nil { ... } runs for it, and errors.As even finds a (nil) *ValidationError in it:", errors.As(validateBad("Rishabh"), &ve), ve)
+}
+
+func main() {
+	fmt.Println("Learning interface design in Go")
+	InterfaceDesignExamples()
+}
+
+// Accept the smallest interface that has what you call, return the concrete struct.
+// Declare interfaces where they are USED, unexported if only one package needs them.
+// Small interfaces compose (type Storage interface { Reader; Writer; Lister }) and make fakes tiny.
+// var _ Iface = (*T)(nil) turns "T no longer implements Iface" into a build error.
+// An interface is nil only when both its type and value are nil: return a literal nil, never a typed nil pointer.
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// the roles each backend must keep satisfying, checked by the compiler
+var (
+	_ Reader     = (*MemoryStorage)(nil)
+	_ Writer     = (*MemoryStorage)(nil)
+	_ Lister     = (*MemoryStorage)(nil)
+	_ Reader     = (*FileStorage)(nil)
+	_ Writer     = (*FileStorage)(nil)
+	_ Lister     = (*FileStorage)(nil)
+	_ userGetter = (*MemoryStorage)(nil)
+	_ userGetter = (*FileStorage)(nil)
+	_ userGetter = fakeUsers(nil)
+	_ userGetter = loaderFunc(nil)
+)
+
+func TestConformance(t *testing.T) {
+	files, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Storage{NewMemoryStorage(), files} {
+		if err := checkStorage(s); err != nil {
+			t.Errorf("%T: %v", s, err)
+		}
+	}
+}
+
+func TestNewFileStorageError(t *testing.T) {
+	files, _ := NewFileStorage(t.TempDir())
+	files.Save("taken", "a file, not a directory")
+	if _, err := NewFileStorage(files.path("taken") + "/sub"); err == nil {
+		t.Error("no error for a directory under a file")
+	}
+}
+
+func TestUserService(t *testing.T) {
+	dbDown := errors.New("db down")
+	tests := []struct {
+		name    string
+		store   userGetter
+		want    string
+		wantErr error
+	}{
+		{"fake", fakeUsers{"user:1": "Rishabh"}, "Hello, Rishabh!", nil},
+		{"missing", fakeUsers{}, "", ErrNotFound},
+		{"func", loaderFunc(func(key string) (string, error) { return "key " + key, nil }), "Hello, key user:1!", nil},
+		{"failing", loaderFunc(func(string) (string, error) { return "", dbDown }), "", dbDown},
+	}
+	for _, tt := range tests {
+		got, err := NewUserService(tt.store).Greeting(1)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: %q, %v; want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// the gotcha itself: a nil *ValidationError in an error is not a nil error
+func TestNilInterface(t *testing.T) {
+	err := validateBad("Rishabh")
+	if err == nil {
+		t.Fatal("the typed nil compared equal to nil, the gotcha is gone")
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve != nil {
+		t.Errorf("errors.As: %v", ve)
+	}
+	if err := validateGood("Rishabh"); err != nil {
+		t.Errorf("validateGood(ok) = %v", err)
+	}
+	for _, validate := range []func(string) error{validateBad, validateGood} {
+		if err := validate(""); err == nil || err.Error() != "invalid name" {
+			t.Errorf("empty name: %v", err)
+		}
+	}
+}
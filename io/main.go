@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// io.Reader and io.Writer are the two most used interfaces in Go:
+//
+//	type Reader interface { Read(p []byte) (n int, err error) }
+//	type Writer interface { Write(p []byte) (n int, err error) }
+//
+// Files, network connections, HTTP bodies, buffers, hashes and gzip streams all
+// implement them, so small wrappers like the ones below work with all of them.
+
+// CountingWriter passes writes to W and counts the bytes that were written.
+// The count is atomic so another goroutine can read it while writing goes on (e.g. for metrics).
+type CountingWriter struct {
+	W io.Writer
+	n atomic.Int64
+}
+
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.W.Write(p)
+	c.n.Add(int64(n)) // count what was really written, even on error
+	return n, err
+}
+
+// Count returns the number of bytes written so far
+func (c *CountingWriter) Count() int64 { return c.n.Load() }
+
+// CountingReader passes reads to R and counts the bytes that were read
+type CountingReader struct {
+	R io.Reader
+	n atomic.Int64
+}
+
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.R.Read(p)
+	c.n.Add(int64(n)) // Read can return data AND io.EOF in the same call
+	return n, err
+}
+
+// Count returns the number of bytes read so far
+func (c *CountingReader) Count() int64 { return c.n.Load() }
+
+// ErrTooManyLines is returned by LineLimitReader when the input has more lines than allowed
+var ErrTooManyLines = errors.New("too many lines")
+
+// LineLimitReader reads from R and fails with ErrTooManyLines as soon as
+// line Max+1 starts. Unlike io.LimitReader, which silently stops, the caller
+// finds out that the input was too big.
+type LineLimitReader struct {
+	R     io.Reader
+	Max   int
+	lines int
+	err   error
+}
+
+func (l *LineLimitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.R.Read(p)
+	for i, b := range p[:n] {
+		if l.lines == l.Max {
+			// a byte after the last allowed newline: hand out what came before it, then fail
+			l.err = fmt.Errorf("line limit %d: %w", l.Max, ErrTooManyLines)
+			if i == 0 {
+				return 0, l.err
+			}
+			return i, nil
+		}
+		if b == '\n' {
+			l.lines++
+		}
+	}
+	return n, err
+}
+
+// chunkWriter writes at most size bytes per call, like a slow network connection
+type chunkWriter struct {
+	w    io.Writer
+	size int
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n, err := c.w.Write(p[:min(c.size, len(p))])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// oneByteReader returns one byte per Read call, the worst case a reader has to handle
+type oneByteReader struct{ r io.Reader }
+
+func (o oneByteReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return o.r.Read(p[:1])
+}
+
+// uploadWithHash "uploads" r to dst and computes its SHA-256 at the same time.
+// io.TeeReader copies everything that is read from r into the hash,
+// so the data is read only once, even if r is a network stream that can't be rewound.
+func uploadWithHash(dst io.Writer, r io.Reader) (int64, string, error) {
+	h := sha256.New()
+	n, err := io.Copy(dst, io.TeeReader(r, h))
+	if err != nil {
+		return n, "", fmt.Errorf("upload: %w", err)
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func IOExamples() {
+	fmt.Println("\nCountingWriter and CountingReader")
+	var buf bytes.Buffer
+	cw := &CountingWriter{W: &buf}
+	fmt.Fprintf(cw, "hello %s\n", "gopher") // anything that takes an io.Writer works with it
+	chunked := chunkWriter{w: cw, size: 3}
+	chunked.Write([]byte("written 3 bytes at a time\n"))
+	fmt.Println("  written:", cw.Count(), "bytes, buffer has:", buf.Len())
+
+	cr := &CountingReader{R: oneByteReader{strings.NewReader("read one byte per call")}}
+	data, err := io.ReadAll(cr)
+	fmt.Printf("  read %q: %d bytes counted, err %v\n", data, cr.Count(), err)
+
+	fmt.Println("\nLineLimitReader")
+	for _, input := range []string{"a\nb\nc\n", "a\nb\nc\nd\n", "a\nb\nc"} {
+		lr := &LineLimitReader{R: oneByteReader{strings.NewReader(input)}, Max: 3}
+		got, err := io.ReadAll(lr)
+		fmt.Printf("  %-12q -> %q, err %v\n", input, got, err)
+	}
+	// io.LimitReader stops quietly after N bytes, the caller can't tell the input was cut
+	limited, _ := io.ReadAll(io.LimitReader(strings.NewReader("0123456789"), 4))
+	fmt.Printf("  io.LimitReader(10 bytes, 4): %q, no error\n", limited)
+
+	fmt.Println("\nio.TeeReader: hash while uploading")
+	upload := &CountingWriter{W: io.Discard} // pretend io.Discard is the server
+	n, sum, err := uploadWithHash(upload, strings.NewReader(strings.Repeat("file content ", 1000)))
+	fmt.Printf("  uploaded %d bytes (counted %d), sha256 %s..., err %v\n", n, upload.Count(), sum[:16], err)
+
+	fmt.Println("\nio.MultiWriter: stdout and a log file")
+	logPath := filepath.Join(os.TempDir(), "io_lesson.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.Remove(logPath)
+	both := io.MultiWriter(os.Stdout, logFile) // every write goes to both, in order
+	fmt.Fprintln(both, "  this line is on stdout AND in", filepath.Base(logPath))
+	logFile.Close()
+	saved, _ := os.ReadFile(logPath)
+	fmt.Printf("  the log file has %d bytes\n", len(saved))
+
+	fmt.Println("\nio.Pipe: a producer goroutine and a consumer")
+	pr, pw := io.Pipe()
+	start := time.Now()
+	go func() {
+		for i := 1; i <= 3; i++ {
+			// Write blocks until the reader has taken all the data: there is no buffer inside a pipe
+			fmt.Fprintf(pw, "event %d\n", i)
+			fmt.Printf("  producer: event %d taken after %s\n", i, time.Since(start).Round(10*time.Millisecond))
+		}
+		pw.Close() // the reader gets io.EOF (CloseWithError(err) would hand it err instead)
+	}()
+	scanner := bufio.NewScanner(pr)
+	for scanner.Scan() {
+		fmt.Println("  consumer:", scanner.Text())
+		time.Sleep(50 * time.Millisecond) // a slow consumer slows the producer down too
+	}
+	fmt.Println("  consumer done, err:", scanner.Err())
+}
+
+func main() {
+	fmt.Println("Learning io.Reader and io.Writer composition in Go")
+	IOExamples()
+}
+
+// Read may return fewer bytes than asked for, and data together with io.EOF: always use n first.
+// Wrap a Reader/Writer to add behaviour (counting, limits, hashing) without changing the code that uses it.
+// io.TeeReader: read once, copy to a second writer. io.MultiWriter: one write, many destinations.
+// io.LimitReader stops silently, write your own reader when going over the limit should be an error.
+// io.Pipe connects a writer goroutine to a reader synchronously, with no buffer in between.
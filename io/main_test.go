@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestCountingWriterChunked(t *testing.T) {
+	msg := []byte("written a few bytes at a time\n")
+	for size := 1; size <= len(msg)+1; size++ {
+		var buf bytes.Buffer
+		cw := &CountingWriter{W: &buf}
+		n, err := chunkWriter{w: cw, size: size}.Write(msg)
+		if err != nil || n != len(msg) || cw.Count() != int64(len(msg)) || buf.String() != string(msg) {
+			t.Errorf("chunks of %d: n=%d count=%d err=%v", size, n, cw.Count(), err)
+		}
+	}
+}
+
+// failAfter accepts limit bytes, then fails
+type failAfter struct {
+	limit int
+	buf   bytes.Buffer
+}
+
+func (f *failAfter) Write(p []byte) (int, error) {
+	room := f.limit - f.buf.Len()
+	if len(p) <= room {
+		return f.buf.Write(p)
+	}
+	f.buf.Write(p[:room])
+	return room, errors.New("disk full")
+}
+
+func TestCountingWriterCountsPartialWrites(t *testing.T) {
+	cw := &CountingWriter{W: &failAfter{limit: 5}}
+	n, err := cw.Write([]byte("0123456789"))
+	if err == nil || n != 5 || cw.Count() != 5 {
+		t.Errorf("n=%d count=%d err=%v, want 5 bytes counted and an error", n, cw.Count(), err)
+	}
+}
+
+func TestCountingWriterConcurrentCount(t *testing.T) {
+	cw := &CountingWriter{W: io.Discard}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for range 1000 {
+				cw.Write([]byte("abc"))
+				_ = cw.Count() // read while others write, -race must stay quiet
+			}
+		})
+	}
+	wg.Wait()
+	if cw.Count() != 8*1000*3 {
+		t.Errorf("Count() = %d", cw.Count())
+	}
+}
+
+func TestCountingReader(t *testing.T) {
+	const text = "read one byte per call"
+	readers := map[string]io.Reader{
+		"one byte":        iotest.OneByteReader(strings.NewReader(text)),
+		"half":            iotest.HalfReader(strings.NewReader(text)),
+		"data with EOF":   iotest.DataErrReader(strings.NewReader(text)),
+		"own one byte":    oneByteReader{strings.NewReader(text)},
+		"whole at a time": strings.NewReader(text),
+	}
+	for name, r := range readers {
+		cr := &CountingReader{R: r}
+		data, err := io.ReadAll(cr)
+		if err != nil || string(data) != text || cr.Count() != int64(len(text)) {
+			t.Errorf("%s: %q, count %d, %v", name, data, cr.Count(), err)
+		}
+	}
+	boom := errors.New("boom")
+	cr := &CountingReader{R: io.MultiReader(strings.NewReader("abc"), iotest.ErrReader(boom))}
+	if _, err := io.ReadAll(cr); !errors.Is(err, boom) || cr.Count() != 3 {
+		t.Errorf("count %d, err %v", cr.Count(), err)
+	}
+}
+
+func TestLineLimitReader(t *testing.T) {
+	tests := []struct {
+		input   string
+		max     int
+		want    string
+		tooMany bool
+	}{
+		{"a\nb\nc\n", 3, "a\nb\nc\n", false},
+		{"a\nb\nc", 3, "a\nb\nc", false}, // no final newline, still 3 lines
+		{"a\nb\nc\nd\n", 3, "a\nb\nc\n", true},
+		{"a\nb\nc\n\n", 3, "a\nb\nc\n", true}, // an empty 4th line is a line too
+		{"", 0, "", false},
+		{"x", 0, "", true},
+		{strings.Repeat("line\n", 1000), 1000, strings.Repeat("line\n", 1000), false},
+	}
+	for _, tt := range tests {
+		for _, wrap := range []func(io.Reader) io.Reader{
+			func(r io.Reader) io.Reader { return r },
+			iotest.OneByteReader,
+		} {
+			lr := &LineLimitReader{R: wrap(strings.NewReader(tt.input)), Max: tt.max}
+			got, err := io.ReadAll(lr)
+			if string(got) != tt.want || errors.Is(err, ErrTooManyLines) != tt.tooMany {
+				t.Errorf("%.20q max %d: got %.20q, %v", tt.input, tt.max, got, err)
+			}
+			if tt.tooMany {
+				if _, err2 := lr.Read(make([]byte, 10)); !errors.Is(err2, ErrTooManyLines) {
+					t.Errorf("%.20q: the error did not stick: %v", tt.input, err2)
+				}
+			}
+		}
+	}
+}
+
+func TestUploadWithHash(t *testing.T) {
+	content := strings.Repeat("file content ", 1000)
+	var dst bytes.Buffer
+	n, sum, err := uploadWithHash(&dst, iotest.HalfReader(strings.NewReader(content)))
+	want := sha256.Sum256([]byte(content))
+	if err != nil || n != int64(len(content)) || sum != hex.EncodeToString(want[:]) || dst.String() != content {
+		t.Errorf("n=%d sum=%s err=%v", n, sum, err)
+	}
+	if _, _, err := uploadWithHash(io.Discard, iotest.ErrReader(io.ErrUnexpectedEOF)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// a pipe has no buffer: Write returns only after a reader took the data
+func TestPipeBlocks(t *testing.T) {
+	pr, pw := io.Pipe()
+	written := make(chan error, 1)
+	go func() {
+		_, err := fmt.Fprint(pw, "hello")
+		written <- err
+	}()
+	select {
+	case <-written:
+		t.Fatal("Write returned before anyone read")
+	case <-time.After(50 * time.Millisecond):
+	}
+	buf := make([]byte, 3)
+	if n, _ := io.ReadFull(pr, buf); n != 3 {
+		t.Fatalf("read %d bytes", n)
+	}
+	select {
+	case <-written:
+		t.Fatal("Write returned with 2 bytes still unread")
+	case <-time.After(50 * time.Millisecond):
+	}
+	rest := make([]byte, 2)
+	io.ReadFull(pr, rest)
+	if err := <-written; err != nil || string(buf)+string(rest) != "hello" {
+		t.Errorf("%q%q, %v", buf, rest, err)
+	}
+
+	// the writer's error reaches the reader, and closing the reader fails the writer
+	boom := errors.New("producer failed")
+	pw.CloseWithError(boom)
+	if _, err := pr.Read(buf); !errors.Is(err, boom) {
+		t.Errorf("Read after CloseWithError: %v", err)
+	}
+	pr2, pw2 := io.Pipe()
+	pr2.Close()
+	if _, err := pw2.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("Write after the reader closed: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Since Go 1.23, `for x := range f` works when f is a function of the form
+//
+//	func(yield func(T) bool)
+//
+// The loop body becomes yield: f calls it once per value, and yield returns false
+// when the loop did break/return, so f must stop. iter.Seq[T] is the name of that type.
+
+// ----------------------------------------------------------------------------
+// A store that hands out iterators
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[int]User
+}
+
+func NewUserStore(users ...User) *UserStore {
+	s := &UserStore{users: make(map[int]User)}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *UserStore) Put(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[u.ID] = u
+}
+
+// List returns every user sorted by ID, as a new slice
+func (s *UserStore) List() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.SortedFunc(maps.Values(s.users), func(a, b User) int { return cmp.Compare(a.ID, b.ID) })
+}
+
+// All yields the users sorted by ID. The users are copied under the read lock
+// when iteration starts and yielded after unlocking: the loop body may be slow
+// or even call Put, and holding the lock while yielding would block (or deadlock) it.
+func (s *UserStore) All() iter.Seq[User] {
+	return s.AllSorted("id")
+}
+
+// AllSorted is All ordered by "id", "name" or "age"
+func (s *UserStore) AllSorted(by string) iter.Seq[User] {
+	return func(yield func(User) bool) {
+		users := s.List()
+		switch by {
+		case "name":
+			slices.SortStableFunc(users, func(a, b User) int { return cmp.Compare(a.Name, b.Name) })
+		case "age":
+			slices.SortStableFunc(users, func(a, b User) int { return cmp.Compare(a.Age, b.Age) })
+		}
+		for _, u := range users {
+			if !yield(u) {
+				return // the loop broke out, stop here
+			}
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Helpers over iter.Seq. Nothing runs until someone ranges over the result.
+
+func Map[T, U any](seq iter.Seq[T], f func(T) U) iter.Seq[U] {
+	return func(yield func(U) bool) {
+		for v := range seq {
+			if !yield(f(v)) {
+				return
+			}
+		}
+	}
+}
+
+func Filter[T any](seq iter.Seq[T], keep func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v := range seq {
+			if keep(v) && !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func Take[T any](seq iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if n <= 0 {
+			return
+		}
+		i := 0
+		for v := range seq {
+			if !yield(v) {
+				return
+			}
+			i++
+			if i == n {
+				return // returning from the range body makes seq's yield return false
+			}
+		}
+	}
+}
+
+// counting yields 1, 2, 3... forever, fine as long as someone stops it
+func counting(calls *int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 1; ; i++ {
+			*calls++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// The same pipeline with channels, as in the goroutines lessons
+
+func mapChan[T, U any](in <-chan T, f func(T) U) <-chan U {
+	out := make(chan U)
+	go func() {
+		defer close(out)
+		for v := range in {
+			out <- f(v)
+		}
+	}()
+	return out
+}
+
+func filterChan[T any](in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			if keep(v) {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// ----------------------------------------------------------------------------
+// Consumers that never build the full slice of output
+
+func writeCSV(out io.Writer, users iter.Seq[User]) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"id", "name", "age"})
+	for u := range users {
+		if err := w.Write([]string{strconv.Itoa(u.ID), u.Name, strconv.Itoa(u.Age)}); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeNDJSON(w io.Writer, users iter.Seq[User]) error {
+	enc := json.NewEncoder(w) // one JSON object per line
+	for u := range users {
+		if err := enc.Encode(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func IteratorExamples() {
+	store := NewUserStore(
+		User{3, "Sanchay", 22}, User{1, "Rishabh", 23}, User{2, "Alice", 30},
+		User{4, "Bob", 17}, User{5, "Maya", 41},
+	)
+
+	fmt.Println("All, by ID:")
+	for u := range store.All() {
+		fmt.Printf("  %d %s\n", u.ID, u.Name)
+	}
+	fmt.Print("AllSorted(\"age\"):")
+	for u := range store.AllSorted("age") {
+		fmt.Print(" ", u.Name)
+	}
+	fmt.Println()
+
+	adults := Filter(store.AllSorted("name"), func(u User) bool { return u.Age >= 18 })
+	names := Map(adults, func(u User) string { return u.Name })
+	fmt.Println("First 2 adult names:", slices.Collect(Take(names, 2)))
+
+	// early break: the infinite sequence stops as soon as the loop does
+	calls := 0
+	for n := range counting(&calls) {
+		if n == 3 {
+			break
+		}
+	}
+	fmt.Println("break after 3 -> the iterator ran", calls, "times")
+	calls = 0
+	evens := Filter(counting(&calls), func(n int) bool { return n%2 == 0 })
+	fmt.Println("Take(evens, 3):", slices.Collect(Take(evens, 3)), "-> source ran", calls, "times")
+
+	// the snapshot: writes during the loop don't show up in it, and don't deadlock
+	seen := 0
+	for u := range store.All() {
+		seen++
+		store.Put(User{ID: 100 + u.ID, Name: "new"})
+	}
+	fmt.Println("Put during iteration: saw", seen, "users, store now has", len(store.List()))
+
+	fmt.Println("Same as List:", slices.Equal(slices.Collect(store.All()), store.List()))
+
+	// the channel version: same result, but two goroutines and a channel send per value,
+	// and breaking early would leave the goroutines blocked forever (a leak)
+	in := make(chan User)
+	go func() {
+		defer close(in)
+		for _, u := range store.List()[:5] {
+			in <- u
+		}
+	}()
+	var fromChan []string
+	for name := range mapChan(filterChan(in, func(u User) bool { return u.Age >= 18 }), func(u User) string { return u.Name }) {
+		fromChan = append(fromChan, name)
+	}
+	fromSeq := slices.Collect(Map(Filter(Take(store.All(), 5), func(u User) bool { return u.Age >= 18 }), func(u User) string { return u.Name }))
+	fmt.Println("Channels:", fromChan, "| iter.Seq:", fromSeq)
+
+	fmt.Println("CSV export:")
+	if err := writeCSV(os.Stdout, Take(store.All(), 3)); err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("NDJSON of adults:")
+	if err := writeNDJSON(os.Stdout, Take(Filter(store.All(), func(u User) bool { return u.Age >= 18 }), 3)); err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+func main() {
+	fmt.Println("Learning iterators (range over func) in Go")
+	IteratorExamples()
+}
+
+// An iter.Seq[T] is just func(yield func(T) bool), range calls it with the loop body as yield.
+// Always check yield's result and return when it's false, or break in the caller panics.
+// Copy under the lock, yield after unlocking: the loop body can be slow or write to the same store.
+// Map/Filter/Take are lazy, nothing runs until a range or slices.Collect pulls values.
+// Compared to a channel pipeline: no goroutines, no leak on early break, and much cheaper per value.
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func sampleStore() *UserStore {
+	return NewUserStore(
+		User{3, "Sanchay", 22}, User{1, "Rishabh", 23}, User{2, "Alice", 30},
+		User{4, "Bob", 17}, User{5, "Maya", 41}, User{6, "Alice", 17},
+	)
+}
+
+func ids(users []User) []int {
+	out := make([]int, len(users))
+	for i, u := range users {
+		out[i] = u.ID
+	}
+	return out
+}
+
+func TestAllMatchesList(t *testing.T) {
+	s := sampleStore()
+	if got, want := slices.Collect(s.All()), s.List(); !slices.Equal(got, want) {
+		t.Errorf("All %v, List %v", got, want)
+	}
+	if got := slices.Collect(NewUserStore().All()); len(got) != 0 {
+		t.Errorf("empty store: %v", got)
+	}
+}
+
+func TestAllSorted(t *testing.T) {
+	s := sampleStore()
+	tests := map[string][]int{
+		"id":      {1, 2, 3, 4, 5, 6},
+		"name":    {2, 6, 4, 5, 1, 3}, // the two Alices stay in ID order
+		"age":     {4, 6, 3, 1, 2, 5},
+		"unknown": {1, 2, 3, 4, 5, 6},
+	}
+	for by, want := range tests {
+		if got := ids(slices.Collect(s.AllSorted(by))); !slices.Equal(got, want) {
+			t.Errorf("AllSorted(%q) = %v, want %v", by, got, want)
+		}
+	}
+}
+
+// stopsAt ranges over seq, breaks after n values and returns how many it saw.
+// Without the yield check in seq, the break would panic.
+func stopsAt[T any](seq iter.Seq[T], n int) int {
+	seen := 0
+	for range seq {
+		seen++
+		if seen == n {
+			break
+		}
+	}
+	return seen
+}
+
+func TestEarlyBreak(t *testing.T) {
+	s := sampleStore()
+	if n := stopsAt(s.All(), 2); n != 2 {
+		t.Errorf("All: %d", n)
+	}
+	calls := 0
+	if n := stopsAt(counting(&calls), 3); n != 3 || calls != 3 {
+		t.Errorf("counting: saw %d, the source ran %d times", n, calls)
+	}
+	calls = 0
+	evens := Filter(counting(&calls), func(n int) bool { return n%2 == 0 })
+	squares := Map(evens, func(n int) int { return n * n })
+	if got := slices.Collect(Take(squares, 3)); !slices.Equal(got, []int{4, 16, 36}) || calls != 6 {
+		t.Errorf("Take(3): %v, source ran %d times", got, calls)
+	}
+	// breaking out of a Take before it is done must stop the source too
+	calls = 0
+	if n := stopsAt(Take(Map(counting(&calls), func(n int) int { return n }), 10), 2); n != 2 || calls != 2 {
+		t.Errorf("break inside Take: saw %d, source ran %d times", n, calls)
+	}
+	for _, n := range []int{0, -1} {
+		calls = 0
+		if got := slices.Collect(Take(counting(&calls), n)); len(got) != 0 || calls != 0 {
+			t.Errorf("Take(%d): %v, source ran %d times", n, got, calls)
+		}
+	}
+}
+
+func TestLazy(t *testing.T) {
+	calls := 0
+	seq := Take(Map(counting(&calls), func(n int) int { return n }), 5)
+	if calls != 0 {
+		t.Errorf("building the pipeline ran the source %d times", calls)
+	}
+	first, second := slices.Collect(seq), slices.Collect(seq) // a Seq can be ranged over again
+	if calls != 10 || !slices.Equal(first, second) {
+		t.Errorf("two collects ran the source %d times, want 10", calls)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := sampleStore()
+	var got []int
+	for u := range s.All() {
+		got = append(got, u.ID)
+		s.Put(User{ID: 100 + u.ID, Name: "new"}) // would deadlock if All held the lock
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5, 6}) || len(s.List()) != 12 {
+		t.Errorf("saw %v, store has %d", got, len(s.List()))
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	s := sampleStore()
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range 1000 {
+			s.Put(User{ID: 1000 + i, Name: "w"})
+		}
+	})
+	for range 50 {
+		prev := 0
+		for u := range s.All() {
+			if u.ID <= prev {
+				t.Fatalf("out of order: %d after %d", u.ID, prev)
+			}
+			prev = u.ID
+		}
+	}
+	wg.Wait()
+	if n := len(slices.Collect(s.All())); n != 1006 {
+		t.Errorf("%d users after the writes", n)
+	}
+}
+
+func TestChannelsMatch(t *testing.T) {
+	users := sampleStore().List()
+	in := make(chan User)
+	go func() {
+		defer close(in)
+		for _, u := range users {
+			in <- u
+		}
+	}()
+	adult := func(u User) bool { return u.Age >= 18 }
+	name := func(u User) string { return u.Name }
+	var fromChan []string
+	for n := range mapChan(filterChan(in, adult), name) {
+		fromChan = append(fromChan, n)
+	}
+	fromSeq := slices.Collect(Map(Filter(slices.Values(users), adult), name))
+	if !slices.Equal(fromChan, fromSeq) || len(fromSeq) != 4 {
+		t.Errorf("channels %v, iter.Seq %v", fromChan, fromSeq)
+	}
+}
+
+func TestExports(t *testing.T) {
+	s := sampleStore()
+	var sb strings.Builder
+	if err := writeCSV(&sb, Take(s.All(), 2)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "id,name,age\n1,Rishabh,23\n2,Alice,30\n"; sb.String() != want {
+		t.Errorf("CSV %q, want %q", sb.String(), want)
+	}
+	sb.Reset()
+	if err := writeNDJSON(&sb, Filter(s.All(), func(u User) bool { return u.Age < 18 })); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"id\":4,\"name\":\"Bob\",\"age\":17}\n{\"id\":6,\"name\":\"Alice\",\"age\":17}\n"; sb.String() != want {
+		t.Errorf("NDJSON %q, want %q", sb.String(), want)
+	}
+}
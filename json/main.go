@@ -1,8 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Student struct {
@@ -58,6 +70,1051 @@ func main() {
 	}
 	fmt.Printf("Dynamic struct using map interfaces: %v\n", result)
 
+	NumberPrecisionExamples()
+	CustomMarshalExamples()
+	StreamingExamples()
+	TagExamples()
+	EnvelopeExamples()
+	DiffPatchExamples()
+	ValidationExamples()
+}
+
+// ---------------------------------------------------------------------------
+// Number precision
+// Decoding into interface{} turns every JSON number into float64.
+// float64 has 53 bits of precision, so integers above 2^53 (9007199254740992)
+// are silently rounded: a real bug with database IDs and snowflake IDs.
+// Decoder.UseNumber keeps numbers as json.Number (the original text) instead.
+// ---------------------------------------------------------------------------
+
+// DecodePreservingNumbers decodes a JSON object keeping numbers as json.Number
+func DecodePreservingNumbers(data []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var result map[string]interface{}
+	if err := dec.Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding with UseNumber: %w", err)
+	}
+	return result, nil
+}
+
+// AsInt64 converts a decoded JSON value to int64 without losing precision
+func AsInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("as int64: %q is not an integer", n.String())
+		}
+		return i, nil
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("as int64: %v has a fraction", n)
+		}
+		if n >= 1<<53 || n <= -(1<<53) {
+			return 0, fmt.Errorf("as int64: %v is beyond 2^53 and may already be rounded, decode with UseNumber", n)
+		}
+		return int64(n), nil
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	default:
+		return 0, fmt.Errorf("as int64: unsupported type %T", v)
+	}
+}
+
+// AsFloat converts a decoded JSON value to float64
+func AsFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("as float: %q is not a number", n.String())
+		}
+		return f, nil
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	default:
+		return 0, fmt.Errorf("as float: unsupported type %T", v)
+	}
+}
+
+func NumberPrecisionExamples() {
+	fmt.Println("\nLearning JSON number precision")
+
+	data := []byte(`{"id":9007199254740993,"price":19.99,"name":"Laptop"}`)
+
+	// plain Unmarshal: id becomes float64 and is rounded
+	var plain map[string]interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		fmt.Printf("Error while unmarshaling: %v\n", err)
+		return
+	}
+	fmt.Printf("Unmarshal:  id = %s (type %T)\n", strconv.FormatFloat(plain["id"].(float64), 'f', -1, 64), plain["id"])
+
+	// UseNumber: id keeps every digit
+	precise, err := DecodePreservingNumbers(data)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("UseNumber:  id = %s (type %T)\n", precise["id"], precise["id"])
+
+	id, err := AsInt64(precise["id"])
+	fmt.Println("AsInt64(json.Number):", id, err, "| exact:", id == 9007199254740993)
+	if _, err := AsInt64(plain["id"]); err != nil {
+		fmt.Println("AsInt64(float64):", err)
+	}
+	price, err := AsFloat(precise["price"])
+	fmt.Println("AsFloat(price):", price, err)
+	if _, err := AsInt64(precise["price"]); err != nil {
+		fmt.Println("AsInt64(price):", err)
+	}
+	if _, err := AsFloat(precise["name"]); err != nil {
+		fmt.Println("AsFloat(name):", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Custom marshaling
+// Any type with a MarshalJSON() ([]byte, error) method controls how it is encoded,
+// and UnmarshalJSON([]byte) error controls how it is decoded.
+// Wrapper types let us change the format of one field without touching the rest.
+// ---------------------------------------------------------------------------
+
+const dateLayout = "2006-01-02"
+
+// DateOnly is a date without time, encoded as "2006-01-02".
+// The zero value is encoded as null instead of "0001-01-01".
+type DateOnly struct {
+	time.Time
+}
+
+func (d DateOnly) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.Format(dateLayout))
+}
+
+// UnmarshalJSON accepts "2006-01-02", null and "" (both mean "no date")
+func (d *DateOnly) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = DateOnly{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("date must be a string like %q, got %s", dateLayout, data)
+	}
+	if s == "" {
+		*d = DateOnly{}
+		return nil
+	}
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %q, expected format %s", s, dateLayout)
+	}
+	*d = DateOnly{t}
+	return nil
+}
+
+// JSONDuration encodes a time.Duration as "1h30m0s" instead of nanoseconds (5400000000000)
+type JSONDuration time.Duration
+
+func (d JSONDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON accepts duration strings like "1h30m" and null (zero duration)
+func (d *JSONDuration) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*d = 0
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1h30m\", got %s", data)
+	}
+	if s == "" {
+		return errors.New("duration must not be empty")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*d = JSONDuration(parsed)
+	return nil
+}
+
+type Course struct {
+	Title      string       `json:"title"`
+	Student    Student      `json:"student"`
+	EnrolledAt DateOnly     `json:"enrolled_at"`
+	Duration   JSONDuration `json:"duration"`
+}
+
+// UnmarshalJSON decodes the custom fields separately so a bad value
+// produces an error that names the JSON field, e.g. `field "enrolled_at": invalid date ...`
+func (c *Course) UnmarshalJSON(data []byte) error {
+	type plainCourse Course // same fields, but no UnmarshalJSON method (avoids infinite recursion)
+	aux := struct {
+		*plainCourse
+		EnrolledAt json.RawMessage `json:"enrolled_at"`
+		Duration   json.RawMessage `json:"duration"`
+	}{plainCourse: (*plainCourse)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if aux.EnrolledAt != nil {
+		if err := c.EnrolledAt.UnmarshalJSON(aux.EnrolledAt); err != nil {
+			return fmt.Errorf("field %q: %w", "enrolled_at", err)
+		}
+	}
+	if aux.Duration != nil {
+		if err := c.Duration.UnmarshalJSON(aux.Duration); err != nil {
+			return fmt.Errorf("field %q: %w", "duration", err)
+		}
+	}
+	return nil
+}
+
+func CustomMarshalExamples() {
+	fmt.Println("\nLearning custom JSON marshaling")
+
+	course := Course{
+		Title:      "Backend with Go",
+		Student:    Student{StudentID: 1, FullName: "Rishabh Gupta", Age: 23, IsActive: true},
+		EnrolledAt: DateOnly{time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+		Duration:   JSONDuration(90 * time.Minute),
+	}
+	encoded := MustPretty(course)
+	fmt.Println("Encoded course:")
+	fmt.Println(encoded)
+
+	var decoded Course
+	if err := json.Unmarshal([]byte(encoded), &decoded); err != nil {
+		fmt.Printf("Error while unmarshaling: %v\n", err)
+		return
+	}
+	fmt.Printf("Round trip: enrolled %s for %v\n", decoded.EnrolledAt.Format(dateLayout), time.Duration(decoded.Duration))
+
+	// zero value date is encoded as null, not "0001-01-01"
+	zero, err := MarshalCompact(Course{Title: "Not started"})
+	if err != nil {
+		fmt.Printf("Error while marshaling: %v\n", err)
+		return
+	}
+	fmt.Println("Zero value course:", zero)
+
+	inputs := []string{
+		`{"title":"null date","enrolled_at":null,"duration":"45m"}`,
+		`{"title":"empty date","enrolled_at":"","duration":"2h"}`,
+		`{"title":"bad date","enrolled_at":"10-03-2024","duration":"1h"}`,
+		`{"title":"bad duration","enrolled_at":"2024-03-10","duration":"90 minutes"}`,
+		`{"title":"number duration","duration":5400}`,
+	}
+	for _, input := range inputs {
+		var c Course
+		if err := json.Unmarshal([]byte(input), &c); err != nil {
+			fmt.Println("  error:", err)
+			continue
+		}
+		fmt.Printf("  %s -> enrolled zero: %v, duration: %v\n", c.Title, c.EnrolledAt.IsZero(), time.Duration(c.Duration))
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Streaming
+// json.Unmarshal needs the whole document in memory.
+// json.Decoder reads from an io.Reader token by token, so a huge array
+// can be processed one element at a time with constant memory.
+// ---------------------------------------------------------------------------
+
+// errStopStream is used by the demo callback to stop decoding early
+var errStopStream = errors.New("stop requested")
+
+// DecodeStudentsStream decodes a JSON array of students one element at a time,
+// calling fn for each. It stops at the first decode error or the first error
+// returned by fn, and returns how many students were passed to fn.
+func DecodeStudentsStream(r io.Reader, fn func(Student) error) (int, error) {
+	dec := json.NewDecoder(r)
+
+	// the first token must be the opening bracket of the array
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, fmt.Errorf("reading opening bracket: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return 0, fmt.Errorf("expected a JSON array, got %v", tok)
+	}
+
+	count := 0
+	for dec.More() {
+		var s Student
+		if err := dec.Decode(&s); err != nil {
+			return count, fmt.Errorf("decoding element %d: %w", count, err)
+		}
+		if err := fn(s); err != nil {
+			return count, fmt.Errorf("callback at element %d: %w", count, err)
+		}
+		count++
+	}
+
+	// and the last token must be the closing bracket
+	if _, err := dec.Token(); err != nil {
+		return count, fmt.Errorf("reading closing bracket: %w", err)
+	}
+	return count, nil
+}
+
+// EncodeStudentsStream writes students as a JSON array, one element at a time.
+// next returns the next student and false when there are no more.
+func EncodeStudentsStream(w io.Writer, next func() (Student, bool)) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	for i := 0; ; i++ {
+		s, ok := next()
+		if !ok {
+			break
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		// Encode adds a newline after every value, which is valid whitespace inside an array
+		if err := enc.Encode(s); err != nil {
+			return fmt.Errorf("encoding element %d: %w", i, err)
+		}
+	}
+	_, err := io.WriteString(w, "]")
+	return err
+}
+
+// pipeStudents encodes total generated students in one goroutine and decodes them
+// with fn in this one, connected by an io.Pipe.
+// Nothing is buffered, so the full array never exists in memory at once.
+func pipeStudents(total int, fn func(Student) error) (int, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		i := 0
+		err := EncodeStudentsStream(pw, func() (Student, bool) {
+			if i == total {
+				return Student{}, false
+			}
+			i++
+			return Student{StudentID: i, FullName: fmt.Sprintf("Student %d", i), Age: 18 + i%10, IsActive: i%2 == 0}, true
+		})
+		pw.CloseWithError(err) // a nil error closes normally (reader gets io.EOF)
+	}()
+
+	count, err := DecodeStudentsStream(pr, fn)
+	// When decoding stops early nobody reads the rest, and the writer would block
+	// on its next Write forever. Closing the read side makes that Write fail instead.
+	pr.CloseWithError(err)
+	return count, err
+}
+
+func StreamingExamples() {
+	fmt.Println("\nLearning streaming JSON")
+
+	const total = 10000
+
+	active, ageSum := 0, 0
+	count, err := pipeStudents(total, func(s Student) error {
+		if s.IsActive {
+			active++
+		}
+		ageSum += s.Age
+		return nil
+	})
+	if err != nil {
+		fmt.Println("Error while streaming:", err)
+		return
+	}
+	fmt.Printf("Streamed %d students, %d active, average age %.1f\n", count, active, float64(ageSum)/float64(count))
+
+	// Compare: how big would the whole document be for Unmarshal?
+	var doc strings.Builder
+	i := 0
+	if err := EncodeStudentsStream(&doc, func() (Student, bool) {
+		if i == total {
+			return Student{}, false
+		}
+		i++
+		return Student{StudentID: i, FullName: fmt.Sprintf("Student %d", i), Age: 18 + i%10}, true
+	}); err != nil {
+		fmt.Println("Error while encoding:", err)
+		return
+	}
+	fmt.Printf("Memory note: Unmarshal would hold the whole %d KB document plus a slice of %d students,\n", doc.Len()/1024, total)
+	fmt.Println("the decoder above only ever held one Student and a small read buffer.")
+
+	// Stopping early: the callback returns an error
+	count, err = DecodeStudentsStream(strings.NewReader(doc.String()), func(s Student) error {
+		if s.StudentID == 3 {
+			return errStopStream
+		}
+		return nil
+	})
+	fmt.Println("Early stop after", count, "students:", err, "| is errStopStream:", errors.Is(err, errStopStream))
+
+	// A malformed element in the middle of the stream
+	malformed := `[{"student_id":1,"full_name":"A"},{"student_id":"two","full_name":"B"},{"student_id":3}]`
+	count, err = DecodeStudentsStream(strings.NewReader(malformed), func(Student) error { return nil })
+	fmt.Println("Malformed stream decoded", count, "students, then:", err)
+
+	if _, err := DecodeStudentsStream(strings.NewReader(`{"student_id":1}`), func(Student) error { return nil }); err != nil {
+		fmt.Println("Not an array:", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Struct tags
+// `json:"name,option"` controls the key name and a few options:
+//   -           never encode/decode this field
+//   omitempty   skip the field when it holds its zero value
+//   string      encode a number or bool as a JSON string
+// ---------------------------------------------------------------------------
+
+// MarshalCompact encodes v on a single line
+func MarshalCompact(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// MustPretty encodes v with two-space indentation and panics on error.
+// Only use it for values you control (demo data), never for user input.
+func MustPretty(v any) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("MustPretty: %v", err))
+	}
+	return string(data)
+}
+
+type Audit struct {
+	CreatedBy string `json:"created_by"`
+	Version   int    `json:"version"`
+}
+
+type Supplier struct {
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+type Product struct {
+	ID       int64    `json:"id,string"`          // big IDs as strings so JavaScript doesn't lose precision
+	Name     string   `json:"name"`               // renamed key
+	Price    float64  `json:"price,omitempty"`    // pitfall: a free product (0) disappears
+	InStock  bool     `json:"in_stock,omitempty"` // pitfall: false disappears, clients can't tell "no" from "unknown"
+	Discount *float64 `json:"discount,omitempty"` // pointer: nil is omitted, 0 is kept
+	Tags     []string `json:"tags,omitempty"`     // nil or empty slice is omitted
+	Password string   `json:"-"`                  // never leaves the server
+	Dash     string   `json:"-,"`                 // the key is literally "-"
+	Audit             // embedded struct: its fields are flattened into Product
+	Supplier Supplier `json:"supplier"` // named field: nested object
+	Notes    string   // no tag: the Go field name is the key
+	internal string   // unexported: invisible to encoding/json
+}
+
+func TagExamples() {
+	fmt.Println("\nLearning JSON struct tags")
+
+	zero := 0.0
+	product := Product{
+		ID:       9007199254740993,
+		Name:     "Free sticker",
+		Price:    0,
+		InStock:  false,
+		Discount: &zero,
+		Password: "secret",
+		Dash:     "dash",
+		Audit:    Audit{CreatedBy: "rishabh", Version: 2},
+		Supplier: Supplier{Name: "Gopher Co", Country: "IN"},
+		Notes:    "limited",
+		internal: "hidden",
+	}
+	got, err := MarshalCompact(product)
+	if err != nil {
+		fmt.Printf("Error while marshaling: %v\n", err)
+		return
+	}
+	want := `{"id":"9007199254740993","name":"Free sticker","discount":0,"-":"dash","created_by":"rishabh","version":2,"supplier":{"name":"Gopher Co","country":"IN"},"Notes":"limited"}`
+	fmt.Println("Encoded product:", got)
+	fmt.Println("Matches expected output:", got == want)
+	fmt.Println("Pretty:")
+	fmt.Println(MustPretty(product.Supplier))
+
+	// Unmarshal matches keys case-insensitively ("NAME" fills Name),
+	// ignores unknown keys, and ignores "-" and unexported fields
+	input := `{"id":"42","NAME":"Mug","In_Stock":true,"password":"hacked","internal":"x","created_by":"admin","unknown":1}`
+	var decoded Product
+	if err := json.Unmarshal([]byte(input), &decoded); err != nil {
+		fmt.Printf("Error while unmarshaling: %v\n", err)
+		return
+	}
+	fmt.Printf("Decoded: ID=%d Name=%q InStock=%v Password=%q internal=%q CreatedBy=%q\n",
+		decoded.ID, decoded.Name, decoded.InStock, decoded.Password, decoded.internal, decoded.CreatedBy)
+
+	// the ,string option also means the input MUST be a string
+	if err := json.Unmarshal([]byte(`{"id":42}`), &decoded); err != nil {
+		fmt.Println("Error with ,string option:", err)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Polymorphic messages
+// Events are often sent as {"type":"user_created","payload":{...}}.
+// json.RawMessage delays decoding the payload until we have read "type"
+// and know which struct to decode it into (two-stage decoding).
+// ---------------------------------------------------------------------------
+
+type Envelope struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type UserCreated struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+type UserDeleted struct {
+	UserID int    `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// messageRegistry maps a type name to a factory returning a pointer to decode into
+var messageRegistry = map[string]func() interface{}{
+	"user_created": func() interface{} { return &UserCreated{} },
+	"user_deleted": func() interface{} { return &UserDeleted{} },
+}
+
+// ErrUnknownMessageType is returned for a type that is not in the registry
+type ErrUnknownMessageType struct {
+	Type       string
+	Registered []string
+}
+
+func (e *ErrUnknownMessageType) Error() string {
+	return fmt.Sprintf("unknown message type %q (registered: %s)", e.Type, strings.Join(e.Registered, ", "))
+}
+
+func registeredTypes() []string {
+	names := make([]string, 0, len(messageRegistry))
+	for name := range messageRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DecodeEnvelope returns the concrete payload, e.g. *UserCreated
+func DecodeEnvelope(data []byte) (interface{}, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("decoding envelope: %w", err)
+	}
+	factory, ok := messageRegistry[env.Type]
+	if !ok {
+		return nil, &ErrUnknownMessageType{Type: env.Type, Registered: registeredTypes()}
+	}
+	payload := factory()
+	if err := json.Unmarshal(env.Payload, payload); err != nil {
+		return nil, fmt.Errorf("decoding %q payload: %w", env.Type, err)
+	}
+	return payload, nil
+}
+
+// EncodeEnvelope wraps payload in an envelope with the given type
+func EncodeEnvelope(msgType string, payload any) ([]byte, error) {
+	if _, ok := messageRegistry[msgType]; !ok {
+		return nil, &ErrUnknownMessageType{Type: msgType, Registered: registeredTypes()}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %q payload: %w", msgType, err)
+	}
+	return json.Marshal(Envelope{Type: msgType, Payload: raw})
+}
+
+func EnvelopeExamples() {
+	fmt.Println("\nLearning polymorphic JSON with json.RawMessage")
+
+	created, err := EncodeEnvelope("user_created", UserCreated{UserID: 7, Email: "rishabh@example.com"})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	deleted, err := EncodeEnvelope("user_deleted", UserDeleted{UserID: 3, Reason: "requested"})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Encoded:", string(created))
+	fmt.Println("Encoded:", string(deleted))
+
+	messages := [][]byte{
+		created,
+		deleted,
+		[]byte(`{"type":"user_banned","payload":{}}`),
+		[]byte(`{"type":"user_created","payload":{"user_id":"seven"}}`),
+		[]byte(`{"type":`),
+	}
+	for _, msg := range messages {
+		payload, err := DecodeEnvelope(msg)
+		var unknown *ErrUnknownMessageType
+		switch {
+		case errors.As(err, &unknown):
+			fmt.Println("  unknown type:", unknown.Type, "->", err)
+			continue
+		case err != nil:
+			fmt.Println("  error:", err)
+			continue
+		}
+
+		// a type switch picks the concrete payload
+		switch p := payload.(type) {
+		case *UserCreated:
+			fmt.Printf("  user %d created with email %s\n", p.UserID, p.Email)
+		case *UserDeleted:
+			fmt.Printf("  user %d deleted (%s)\n", p.UserID, p.Reason)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Diff and merge patch
+// Diff compares two documents structurally (key order and whitespace don't matter).
+// ApplyMergePatch implements RFC 7386: the patch looks like the document,
+// keys in the patch replace keys in the document and null deletes a key.
+// This is what a PATCH endpoint usually accepts.
+// ---------------------------------------------------------------------------
+
+// Change is one difference found by Diff
+type Change struct {
+	Path string      // like users[2].email
+	Kind string      // "added", "removed" or "changed"
+	Old  interface{} // nil for "added"
+	New  interface{} // nil for "removed"
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case "added":
+		return fmt.Sprintf("+ %s: %v", c.Path, c.New)
+	case "removed":
+		return fmt.Sprintf("- %s: %v", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %v -> %v", c.Path, c.Old, c.New)
+	}
+}
+
+// decodeAny decodes any JSON value, keeping numbers exact
+func decodeAny(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+var identifierKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// joinPath appends an object key: users -> users.email, or users["first name"] for unusual keys
+func joinPath(path, key string) string {
+	if !identifierKey.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Diff reports every added, removed or changed path between a and b.
+// Object keys are visited in sorted order so the output is always the same.
+func Diff(a, b []byte) ([]Change, error) {
+	left, err := decodeAny(a)
+	if err != nil {
+		return nil, fmt.Errorf("diff: decoding first document: %w", err)
+	}
+	right, err := decodeAny(b)
+	if err != nil {
+		return nil, fmt.Errorf("diff: decoding second document: %w", err)
+	}
+	var changes []Change
+	diffValues("", left, right, &changes)
+	return changes, nil
+}
+
+func diffValues(path string, a, b interface{}, changes *[]Change) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, seen := av[k]; !seen {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			aChild, inA := av[k]
+			bChild, inB := bv[k]
+			switch {
+			case !inA:
+				*changes = append(*changes, Change{Path: joinPath(path, k), Kind: "added", New: bChild})
+			case !inB:
+				*changes = append(*changes, Change{Path: joinPath(path, k), Kind: "removed", Old: aChild})
+			default:
+				diffValues(joinPath(path, k), aChild, bChild, changes)
+			}
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(av):
+				*changes = append(*changes, Change{Path: elemPath, Kind: "added", New: bv[i]})
+			case i >= len(bv):
+				*changes = append(*changes, Change{Path: elemPath, Kind: "removed", Old: av[i]})
+			default:
+				diffValues(elemPath, av[i], bv[i], changes)
+			}
+		}
+		return
+	}
+	// scalars, or values whose type changed (object -> string, ...)
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "$" // the whole document
+		}
+		*changes = append(*changes, Change{Path: path, Kind: "changed", Old: a, New: b})
+	}
+}
+
+// ApplyMergePatch applies an RFC 7386 JSON merge patch to doc.
+// Arrays are replaced as a whole, they are never merged element by element.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeAny(doc)
+	if err != nil {
+		return nil, fmt.Errorf("merge patch: decoding document: %w", err)
+	}
+	p, err := decodeAny(patch)
+	if err != nil {
+		return nil, fmt.Errorf("merge patch: decoding patch: %w", err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch // not an object: the patch value replaces the target
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key) // null means "remove this key"
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+func DiffPatchExamples() {
+	fmt.Println("\nLearning JSON diff and merge patch")
+
+	before := []byte(`{
+		"team": "backend",
+		"settings": {"theme": "dark", "notifications": true},
+		"users": [
+			{"id": 1, "email": "a@example.com"},
+			{"id": 2, "email": "b@example.com"},
+			{"id": 3, "email": "c@example.com"}
+		]
+	}`)
+	after := []byte(`{
+		"users": [
+			{"id": 1, "email": "a@example.com"},
+			{"id": 2, "email": "b@example.com"},
+			{"id": 3, "email": "carol@example.com", "admin": true}
+		],
+		"settings": {"theme": "light"},
+		"team name": "backend"
+	}`)
+	changes, err := Diff(before, after)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Diff:")
+	for _, c := range changes {
+		fmt.Println(" ", c)
+	}
+
+	// Merge patch: change the theme, delete notifications, add a key,
+	// and replace the whole tags array
+	doc := []byte(`{"name":"Rishabh","settings":{"theme":"dark","notifications":true},"tags":["go","api"]}`)
+	patch := []byte(`{"settings":{"theme":"light","notifications":null,"language":"en"},"tags":["go"]}`)
+	patched, err := ApplyMergePatch(doc, patch)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Document:", string(doc))
+	fmt.Println("Patch:   ", string(patch))
+	fmt.Println("Result:  ", string(patched))
+
+	// a patch that is not an object replaces the whole document
+	replaced, err := ApplyMergePatch(doc, []byte(`"gone"`))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Non-object patch:", string(replaced))
+}
+
+// ---------------------------------------------------------------------------
+// Validation with struct tags
+// Struct tags are just strings, any package can read them with reflection.
+// ValidateJSON reads rules like `validate:"required,min=2,max=64,email,oneof=admin user"`
+// and reports every violation at once using the JSON field names clients know.
+// ---------------------------------------------------------------------------
+
+// FieldError is one validation failure
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. address.city or skills[1].name
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+var validateEmailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+// ValidateJSON unmarshals data into target (a pointer to a struct) and checks
+// its validate tags. It returns nil when everything is valid.
+// Pointer fields that are nil count as "absent": only "required" applies to them.
+func ValidateJSON(data []byte, target any) []FieldError {
+	if err := json.Unmarshal(data, target); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return []FieldError{{Field: typeErr.Field, Rule: "type", Message: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}}
+		}
+		return []FieldError{{Field: "", Rule: "json", Message: err.Error()}}
+	}
+	return validateValue(reflect.ValueOf(target), "")
+}
+
+// jsonFieldName returns the key encoding/json uses for the field ("" when skipped)
+func jsonFieldName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// validateValue walks structs, slices and pointers looking for tagged fields
+func validateValue(v reflect.Value, path string) []FieldError {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var errs []FieldError
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			// embedded struct without a json name: its fields are flattened
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				errs = append(errs, validateValue(v.Field(i), path)...)
+				continue
+			}
+			name := jsonFieldName(field)
+			if name == "" {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			fieldErrs := checkRules(v.Field(i), field.Tag.Get("validate"), fieldPath)
+			errs = append(errs, fieldErrs...)
+			if len(fieldErrs) == 0 {
+				errs = append(errs, validateValue(v.Field(i), fieldPath)...)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			errs = append(errs, validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return errs
+}
+
+// checkRules applies the comma separated rules of one field
+func checkRules(v reflect.Value, tag, path string) []FieldError {
+	if tag == "" {
+		return nil
+	}
+	rules := strings.Split(tag, ",")
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if slices.Contains(rules, "required") {
+				return []FieldError{{Field: path, Rule: "required", Message: "is required"}}
+			}
+			return nil // absent optional field: nothing else to check
+		}
+		v = v.Elem()
+	} else if slices.Contains(rules, "required") && v.IsZero() {
+		// for non-pointer fields "required" means "not the zero value",
+		// so 0 and false can't be required values: use a pointer for those
+		return []FieldError{{Field: path, Rule: "required", Message: "is required"}}
+	}
+
+	var errs []FieldError
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			// already handled above
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("invalid rule %q", rule)})
+				continue
+			}
+			size, unit, ok := measure(v)
+			if !ok {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("rule %q not supported for %s", name, v.Kind())})
+				continue
+			}
+			if name == "min" && size < limit {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("must be at least %s%s", arg, unit)})
+			}
+			if name == "max" && size > limit {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("must be at most %s%s", arg, unit)})
+			}
+		case "email":
+			if v.Kind() != reflect.String || !validateEmailPattern.MatchString(v.String()) {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: "must be a valid email address"})
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			if !slices.Contains(allowed, fmt.Sprint(v.Interface())) {
+				errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("must be one of [%s]", strings.Join(allowed, ", "))})
+			}
+		default:
+			errs = append(errs, FieldError{Field: path, Rule: name, Message: fmt.Sprintf("unknown rule %q", name)})
+		}
+	}
+	return errs
+}
+
+// measure returns what min/max compare against: the length of strings
+// (in characters), slices and maps, or the value of numbers
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
+
+type Address struct {
+	City    string `json:"city" validate:"required"`
+	Pincode string `json:"pincode" validate:"min=6,max=6"`
+}
+
+type Skill struct {
+	Name  string `json:"name" validate:"required,max=20"`
+	Level int    `json:"level" validate:"min=1,max=5"`
+}
+
+type CreateUserRequest struct {
+	Name    string   `json:"name" validate:"required,min=2,max=64"`
+	Email   string   `json:"email" validate:"required,email"`
+	Role    string   `json:"role" validate:"required,oneof=admin user"`
+	Age     *int     `json:"age" validate:"min=13,max=120"` // optional: nil is fine, but if sent it must be valid
+	Address *Address `json:"address" validate:"required"`
+	Skills  []Skill  `json:"skills" validate:"max=3"`
+}
+
+func ValidationExamples() {
+	fmt.Println("\nLearning validation with struct tags and reflection")
+
+	inputs := []string{
+		`{"name":"Rishabh","email":"rishabh@example.com","role":"admin","address":{"city":"Delhi","pincode":"110001"},"skills":[{"name":"Go","level":4}]}`,
+		`{"name":"R","email":"not-an-email","role":"root","age":7,"address":{"pincode":"11"},"skills":[{"name":"Go","level":9},{"level":1}]}`,
+		`{"name":"Rishabh","email":"rishabh@example.com","role":"user","skills":[{"name":"a","level":1},{"name":"b","level":1},{"name":"c","level":1},{"name":"d","level":1}]}`,
+		`{"name":"Rishabh","age":"twenty"}`,
+	}
+	for i, input := range inputs {
+		var req CreateUserRequest
+		errs := ValidateJSON([]byte(input), &req)
+		if len(errs) == 0 {
+			fmt.Printf("Request %d is valid: %s (%s)\n", i+1, req.Name, req.Address.City)
+			continue
+		}
+		fmt.Printf("Request %d has %d problem(s):\n", i+1, len(errs))
+		for _, e := range errs {
+			fmt.Printf("  %-16s %-11s %s\n", e.Field, "["+e.Rule+"]", e.Message)
+		}
+	}
 }
 
 /* JSON - JavaScript Object Notation Lightweight format for exchanging data (text-based, human-readable). Used for client server interaction for data transfer Go provides encoding/json package for marshaling and unmarshaling Marshaling - Converting Go objects to JSON format Unmarshaling - Converting JSON data to Go objects */
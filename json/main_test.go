@@ -0,0 +1,661 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateOnly(t *testing.T) {
+	date := DateOnly{time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name string
+		in   DateOnly
+		want string
+	}{
+		{"valid", date, `"2024-03-10"`},
+		{"zero is null", DateOnly{}, `null`},
+	}
+	for _, tt := range tests {
+		got, err := json.Marshal(tt.in)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: Marshal = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+
+	decode := []struct {
+		in       string
+		wantZero bool
+		wantErr  string
+	}{
+		{`"2024-03-10"`, false, ""},
+		{`null`, true, ""},
+		{`""`, true, ""},
+		{`"10-03-2024"`, false, `invalid date "10-03-2024"`},
+		{`"2024-02-30"`, false, "invalid date"},
+		{`20240310`, false, "date must be a string"},
+	}
+	for _, tt := range decode {
+		d := date // decoding null or "" must reset an existing value
+		err := json.Unmarshal([]byte(tt.in), &d)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || d.IsZero() != tt.wantZero {
+			t.Errorf("Unmarshal(%s) = %v, %v; zero want %v", tt.in, d, err, tt.wantZero)
+		}
+		if !tt.wantZero && !d.Equal(date.Time) {
+			t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, d, date)
+		}
+	}
+}
+
+func TestJSONDuration(t *testing.T) {
+	for _, tt := range []struct {
+		in   JSONDuration
+		want string
+	}{
+		{JSONDuration(90 * time.Minute), `"1h30m0s"`},
+		{JSONDuration(1500 * time.Millisecond), `"1.5s"`},
+		{0, `"0s"`},
+	} {
+		got, err := json.Marshal(tt.in)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Marshal(%v) = %s, %v; want %s", time.Duration(tt.in), got, err, tt.want)
+		}
+	}
+
+	decode := []struct {
+		in      string
+		want    time.Duration
+		wantErr string
+	}{
+		{`"1h30m"`, 90 * time.Minute, ""},
+		{`"250ms"`, 250 * time.Millisecond, ""},
+		{`null`, 0, ""},
+		{`""`, 0, "must not be empty"},
+		{`"90 minutes"`, 0, `invalid duration "90 minutes"`},
+		{`5400`, 0, "duration must be a string"},
+	}
+	for _, tt := range decode {
+		d := JSONDuration(time.Hour)
+		err := json.Unmarshal([]byte(tt.in), &d)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Unmarshal(%s) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || time.Duration(d) != tt.want {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v", tt.in, time.Duration(d), err, tt.want)
+		}
+	}
+}
+
+func TestCourseRoundTrip(t *testing.T) {
+	course := Course{
+		Title:      "Backend with Go",
+		Student:    Student{StudentID: 1, FullName: "Rishabh Gupta", Age: 23, IsActive: true},
+		EnrolledAt: DateOnly{time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+		Duration:   JSONDuration(90 * time.Minute),
+	}
+	data, err := json.Marshal(course)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"title":"Backend with Go","student":{"student_id":1,"full_name":"Rishabh Gupta","age":23,"is_active":true},"enrolled_at":"2024-03-10","duration":"1h30m0s"}`
+	if string(data) != want {
+		t.Fatalf("Marshal =\n%s\nwant\n%s", data, want)
+	}
+	var back Course
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.Title != course.Title || back.Student != course.Student || !back.EnrolledAt.Equal(course.EnrolledAt.Time) || back.Duration != course.Duration {
+		t.Errorf("round trip: got %+v, want %+v", back, course)
+	}
+
+	zero, err := json.Marshal(Course{})
+	if err != nil || !strings.Contains(string(zero), `"enrolled_at":null`) {
+		t.Errorf("zero course = %s, %v; want enrolled_at null", zero, err)
+	}
+}
+
+func TestCourseErrorsNameTheField(t *testing.T) {
+	tests := []struct{ in, field string }{
+		{`{"enrolled_at":"10-03-2024"}`, `field "enrolled_at"`},
+		{`{"enrolled_at":true}`, `field "enrolled_at"`},
+		{`{"duration":"soon"}`, `field "duration"`},
+		{`{"duration":""}`, `field "duration"`},
+	}
+	for _, tt := range tests {
+		var c Course
+		if err := json.Unmarshal([]byte(tt.in), &c); err == nil || !strings.Contains(err.Error(), tt.field) {
+			t.Errorf("Unmarshal(%s) error = %v, want it to name %s", tt.in, err, tt.field)
+		}
+	}
+}
+
+func generateStudents(n int) func() (Student, bool) {
+	i := 0
+	return func() (Student, bool) {
+		if i == n {
+			return Student{}, false
+		}
+		i++
+		return Student{StudentID: i, FullName: "S", Age: 20}, true
+	}
+}
+
+func TestStudentsStreamRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 1, 500} {
+		var buf strings.Builder
+		if err := EncodeStudentsStream(&buf, generateStudents(n)); err != nil {
+			t.Fatal(err)
+		}
+		var all []Student
+		if err := json.Unmarshal([]byte(buf.String()), &all); err != nil || len(all) != n {
+			t.Fatalf("n=%d: the stream is not a valid array: %d students, %v", n, len(all), err)
+		}
+		next := 1
+		count, err := DecodeStudentsStream(strings.NewReader(buf.String()), func(s Student) error {
+			if s.StudentID != next {
+				t.Errorf("got student %d, want %d", s.StudentID, next)
+			}
+			next++
+			return nil
+		})
+		if err != nil || count != n {
+			t.Errorf("n=%d: decoded %d, %v", n, count, err)
+		}
+	}
+}
+
+func TestDecodeStudentsStreamMalformed(t *testing.T) {
+	tests := []struct {
+		in        string
+		wantCount int
+		errPart   string
+	}{
+		{`[{"student_id":1},{"student_id":"two"},{"student_id":3}]`, 1, "decoding element 1"},
+		{`[{"student_id":1},{"student_id":2},{"student_id":`, 2, "decoding element 2"},
+		{`[{"student_id":1}`, 1, "decoding element 1"}, // More() is true at EOF, so the next Decode reports it
+		{`{"student_id":1}`, 0, "expected a JSON array"},
+		{``, 0, "opening bracket"},
+	}
+	for _, tt := range tests {
+		count, err := DecodeStudentsStream(strings.NewReader(tt.in), func(Student) error { return nil })
+		if count != tt.wantCount || err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("%s: %d, %v; want %d and an error with %q", tt.in, count, err, tt.wantCount, tt.errPart)
+		}
+	}
+}
+
+func TestDecodeStudentsStreamEarlyStop(t *testing.T) {
+	var buf strings.Builder
+	if err := EncodeStudentsStream(&buf, generateStudents(100)); err != nil {
+		t.Fatal(err)
+	}
+	seen := 0
+	count, err := DecodeStudentsStream(strings.NewReader(buf.String()), func(s Student) error {
+		seen++
+		if s.StudentID == 3 {
+			return errStopStream
+		}
+		return nil
+	})
+	if !errors.Is(err, errStopStream) || count != 2 || seen != 3 {
+		t.Errorf("got count %d after %d callbacks, %v; want 2, 3, errStopStream", count, seen, err)
+	}
+	if !strings.Contains(err.Error(), "element 2") {
+		t.Errorf("error %q does not name the element", err)
+	}
+}
+
+// Stopping early must not leave the encoding goroutine blocked on the pipe
+func TestPipeStudentsEarlyStopDoesNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for range 20 {
+		count, err := pipeStudents(10_000, func(s Student) error {
+			if s.StudentID == 5 {
+				return errStopStream
+			}
+			return nil
+		})
+		if !errors.Is(err, errStopStream) || count != 4 {
+			t.Fatalf("got %d, %v", count, err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines before, %d after: the writers are stuck", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	count, err := pipeStudents(1000, func(Student) error { return nil })
+	if err != nil || count != 1000 {
+		t.Fatalf("full run: %d, %v", count, err)
+	}
+}
+
+func TestProductTags(t *testing.T) {
+	zero := 0.0
+	product := Product{
+		ID:       9007199254740993,
+		Name:     "Free sticker",
+		Discount: &zero,
+		Password: "secret",
+		Dash:     "dash",
+		Audit:    Audit{CreatedBy: "rishabh", Version: 2},
+		Supplier: Supplier{Name: "Gopher Co", Country: "IN"},
+		Notes:    "limited",
+		internal: "hidden",
+	}
+	got, err := MarshalCompact(product)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no price, in_stock or tags (omitempty), no password or internal, id as a string,
+	// Audit flattened, Supplier nested, Notes under its Go name
+	want := `{"id":"9007199254740993","name":"Free sticker","discount":0,"-":"dash","created_by":"rishabh","version":2,"supplier":{"name":"Gopher Co","country":"IN"},"Notes":"limited"}`
+	if got != want {
+		t.Fatalf("MarshalCompact =\n%s\nwant\n%s", got, want)
+	}
+
+	// the pitfall the other way round: non-zero values come back
+	product.Price, product.InStock, product.Discount, product.Tags = 2.5, true, nil, []string{"go"}
+	got, _ = MarshalCompact(product)
+	for _, part := range []string{`"price":2.5`, `"in_stock":true`, `"tags":["go"]`} {
+		if !strings.Contains(got, part) {
+			t.Errorf("%s is missing %s", got, part)
+		}
+	}
+	if strings.Contains(got, "discount") {
+		t.Errorf("a nil *float64 with omitempty was encoded: %s", got)
+	}
+}
+
+func TestProductTagsDecode(t *testing.T) {
+	input := `{"id":"42","NAME":"Mug","In_Stock":true,"password":"hacked","internal":"x","created_by":"admin","unknown":1,"-":"d"}`
+	var p Product
+	if err := json.Unmarshal([]byte(input), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 42 || p.Name != "Mug" || !p.InStock || p.CreatedBy != "admin" || p.Dash != "d" {
+		t.Errorf("decoded %+v", p)
+	}
+	if p.Password != "" || p.internal != "" {
+		t.Errorf("Password %q, internal %q: both must stay empty", p.Password, p.internal)
+	}
+	if err := json.Unmarshal([]byte(`{"id":42}`), &p); err == nil {
+		t.Error("a bare number for a ,string field was accepted")
+	}
+}
+
+func TestMarshalHelpers(t *testing.T) {
+	if got, err := MarshalCompact(map[string]int{"b": 2, "a": 1}); err != nil || got != `{"a":1,"b":2}` {
+		t.Errorf("MarshalCompact = %s, %v", got, err)
+	}
+	if _, err := MarshalCompact(make(chan int)); err == nil {
+		t.Error("MarshalCompact of a channel succeeded")
+	}
+	if got := MustPretty(Supplier{"Gopher Co", "IN"}); got != "{\n  \"name\": \"Gopher Co\",\n  \"country\": \"IN\"\n}" {
+		t.Errorf("MustPretty =\n%s", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("MustPretty of a channel did not panic")
+		}
+	}()
+	MustPretty(make(chan int))
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	data, err := EncodeEnvelope("user_created", UserCreated{UserID: 7, Email: "a@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"type":"user_created","payload":{"user_id":7,"email":"a@example.com"}}` {
+		t.Errorf("EncodeEnvelope = %s", data)
+	}
+	payload, err := DecodeEnvelope(data)
+	if created, ok := payload.(*UserCreated); err != nil || !ok || *created != (UserCreated{7, "a@example.com"}) {
+		t.Errorf("DecodeEnvelope = %#v, %v", payload, err)
+	}
+
+	data, err = EncodeEnvelope("user_deleted", UserDeleted{UserID: 3, Reason: "requested"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err = DecodeEnvelope(data)
+	if deleted, ok := payload.(*UserDeleted); err != nil || !ok || *deleted != (UserDeleted{3, "requested"}) {
+		t.Errorf("DecodeEnvelope = %#v, %v", payload, err)
+	}
+}
+
+func TestEnvelopeUnknownType(t *testing.T) {
+	_, decodeErr := DecodeEnvelope([]byte(`{"type":"user_banned","payload":{}}`))
+	_, encodeErr := EncodeEnvelope("user_banned", struct{}{})
+	for _, err := range []error{decodeErr, encodeErr} {
+		var unknown *ErrUnknownMessageType
+		if !errors.As(err, &unknown) {
+			t.Fatalf("error %v is not an *ErrUnknownMessageType", err)
+		}
+		if unknown.Type != "user_banned" || strings.Join(unknown.Registered, ",") != "user_created,user_deleted" {
+			t.Errorf("got %+v", unknown)
+		}
+		if !strings.Contains(err.Error(), "user_created, user_deleted") {
+			t.Errorf("error %q does not list the registered types", err)
+		}
+	}
+}
+
+func TestEnvelopeMalformed(t *testing.T) {
+	// the envelope itself is broken: first stage
+	_, err := DecodeEnvelope([]byte(`{"type":`))
+	if err == nil || !strings.Contains(err.Error(), "decoding envelope") {
+		t.Errorf("broken envelope: %v", err)
+	}
+	// the envelope is fine, the payload isn't: second stage, names the type
+	_, err = DecodeEnvelope([]byte(`{"type":"user_created","payload":{"user_id":"seven"}}`))
+	var typeErr *json.UnmarshalTypeError
+	if err == nil || !strings.Contains(err.Error(), `decoding "user_created" payload`) || !errors.As(err, &typeErr) {
+		t.Errorf("broken payload: %v", err)
+	}
+	_, err = DecodeEnvelope([]byte(`{"type":"user_deleted"}`))
+	if err == nil || !strings.Contains(err.Error(), `"user_deleted"`) {
+		t.Errorf("missing payload: %v", err)
+	}
+	if _, err := EncodeEnvelope("user_created", make(chan int)); err == nil || !strings.Contains(err.Error(), `"user_created" payload`) {
+		t.Errorf("unencodable payload: %v", err)
+	}
+}
+
+func TestDecodePreservingNumbers(t *testing.T) {
+	data := []byte(`{"id":9007199254740993,"price":19.99,"name":"Laptop","nested":{"big":-9223372036854775808}}`)
+
+	// the bug: plain Unmarshal rounds the ID to the nearest float64
+	var plain map[string]interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain["id"].(float64) != 9007199254740992 {
+		t.Fatalf("plain Unmarshal gave %v, expected the rounded 9007199254740992", plain["id"])
+	}
+	if _, err := AsInt64(plain["id"]); err == nil {
+		t.Error("AsInt64 accepted a float64 beyond 2^53")
+	}
+
+	// the fix
+	m, err := DecodePreservingNumbers(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := AsInt64(m["id"]); err != nil || id != 9007199254740993 {
+		t.Errorf("id = %d, %v; want exactly 9007199254740993", id, err)
+	}
+	if big, err := AsInt64(m["nested"].(map[string]interface{})["big"]); err != nil || big != math.MinInt64 {
+		t.Errorf("nested big = %d, %v", big, err)
+	}
+	if price, err := AsFloat(m["price"]); err != nil || price != 19.99 {
+		t.Errorf("price = %v, %v", price, err)
+	}
+
+	if _, err := DecodePreservingNumbers([]byte(`[1, 2]`)); err == nil {
+		t.Error("an array decoded into a map")
+	}
+	if _, err := DecodePreservingNumbers([]byte(`{"id":`)); err == nil {
+		t.Error("truncated input decoded")
+	}
+}
+
+func TestAsInt64AndAsFloat(t *testing.T) {
+	for _, tt := range []struct {
+		in   interface{}
+		want int64
+	}{
+		{json.Number("42"), 42},
+		{json.Number("-9007199254740993"), -9007199254740993},
+		{float64(1 << 52), 1 << 52},
+		{7, 7},
+		{int64(8), 8},
+	} {
+		if got, err := AsInt64(tt.in); err != nil || got != tt.want {
+			t.Errorf("AsInt64(%#v) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		in      interface{}
+		errPart string
+	}{
+		{json.Number("1.5"), "not an integer"},
+		{json.Number("99999999999999999999"), "not an integer"},
+		{2.5, "has a fraction"},
+		{float64(1 << 53), "beyond 2^53"},
+		{"42", "unsupported type string"},
+		{nil, "unsupported type <nil>"},
+		{true, "unsupported type bool"},
+	} {
+		if _, err := AsInt64(tt.in); err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("AsInt64(%#v) error = %v, want %q", tt.in, err, tt.errPart)
+		}
+	}
+
+	for _, tt := range []struct {
+		in   interface{}
+		want float64
+	}{
+		{json.Number("19.99"), 19.99},
+		{json.Number("1e3"), 1000},
+		{2.5, 2.5},
+		{3, 3},
+		{int64(-4), -4},
+	} {
+		if got, err := AsFloat(tt.in); err != nil || got != tt.want {
+			t.Errorf("AsFloat(%#v) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []interface{}{json.Number("abc"), "1.5", nil, []int{1}} {
+		if _, err := AsFloat(in); err == nil {
+			t.Errorf("AsFloat(%#v) succeeded", in)
+		}
+	}
+}
+
+func changeStrings(changes []Change) []string {
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		out[i] = c.String()
+	}
+	return out
+}
+
+func TestDiff(t *testing.T) {
+	a := []byte(`{"team":"backend","settings":{"theme":"dark","beta":true},"users":[{"email":"a@x.io"},{"email":"b@x.io"},{"email":"c@x.io"}],"first name":"R","n":1}`)
+	b := []byte(`{"n":1.0,"settings":{"theme":"light"},"users":[{"email":"a@x.io"},{"email":"b@x.io"},{"email":"c@y.io"},{"email":"d@x.io"}],"team":"backend","region":"in","first name":"Rishabh"}`)
+	want := []string{
+		`~ ["first name"]: R -> Rishabh`,
+		`~ n: 1 -> 1.0`, // numbers are compared as written, UseNumber keeps the text
+		`+ region: in`,
+		`- settings.beta: true`,
+		`~ settings.theme: dark -> light`,
+		`~ users[2].email: c@x.io -> c@y.io`,
+		`+ users[3]: map[email:d@x.io]`,
+	}
+	// map iteration is random, so run it a few times: the order must never change
+	for range 20 {
+		changes, err := Diff(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := changeStrings(changes); !slices.Equal(got, want) {
+			t.Fatalf("Diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{`{"a":1}`, `{"a":1}`, nil},
+		{` { "a" : [1, 2] } `, `{"a":[1,2]}`, nil},
+		{`{"a":[1,2,3]}`, `{"a":[1]}`, []string{"- a[1]: 2", "- a[2]: 3"}},
+		{`{"a":{"b":1}}`, `{"a":"flat"}`, []string{"~ a: map[b:1] -> flat"}},
+		{`{"a":null}`, `{"a":0}`, []string{"~ a: <nil> -> 0"}},
+		{`1`, `2`, []string{"~ $: 1 -> 2"}},
+		{`[{"x":1}]`, `[{"x":2}]`, []string{"~ [0].x: 1 -> 2"}},
+	}
+	for _, tt := range tests {
+		changes, err := Diff([]byte(tt.a), []byte(tt.b))
+		if got := changeStrings(changes); err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("Diff(%s, %s) = %q, %v; want %q", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+
+	if _, err := Diff([]byte(`{`), []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "first document") {
+		t.Errorf("bad first document: %v", err)
+	}
+	if _, err := Diff([]byte(`{}`), []byte(`nope`)); err == nil || !strings.Contains(err.Error(), "second document") {
+		t.Errorf("bad second document: %v", err)
+	}
+}
+
+// the examples from RFC 7386, appendix A
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// arrays are atomic: the patch array replaces, it is not merged by index
+		{`{"tags":["go","api"],"n":9007199254740993}`, `{"tags":["rust"]}`, `{"n":9007199254740993,"tags":["rust"]}`},
+	}
+	for _, tt := range tests {
+		got, err := ApplyMergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("ApplyMergePatch(%s, %s) = %s, %v; want %s", tt.doc, tt.patch, got, err, tt.want)
+		}
+	}
+	if _, err := ApplyMergePatch([]byte(`{`), []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "decoding document") {
+		t.Errorf("bad document: %v", err)
+	}
+	if _, err := ApplyMergePatch([]byte(`{}`), []byte(`{"a":}`)); err == nil || !strings.Contains(err.Error(), "decoding patch") {
+		t.Errorf("bad patch: %v", err)
+	}
+}
+
+func fieldErrorStrings(errs []FieldError) []string {
+	out := make([]string, len(errs))
+	for i, e := range errs {
+		out[i] = e.Field + " " + e.Rule
+	}
+	return out
+}
+
+func TestValidateJSONRules(t *testing.T) {
+	type rules struct {
+		Required string  `json:"required" validate:"required"`
+		Min      string  `json:"min_len" validate:"min=2"`
+		Max      int     `json:"max_num" validate:"max=10"`
+		Email    string  `json:"email" validate:"email"`
+		OneOf    string  `json:"role" validate:"oneof=admin user"`
+		Both     string  `json:"both" validate:"min=2,email"`
+		Score    float64 `json:"score" validate:"min=0.5"`
+		Unicode  string  `json:"unicode" validate:"max=3"` // counts characters, not bytes
+		Unknown  string  `json:"unknown" validate:"uppercase"`
+		BadLimit string  `json:"bad_limit" validate:"min=two"`
+		NoLength bool    `json:"no_length" validate:"max=1"`
+	}
+	valid := `{"required":"x","min_len":"ab","max_num":10,"email":"a@b.io","role":"user","both":"a@b.io","score":0.5,"unicode":"日本語"}`
+
+	var r rules
+	got := fieldErrorStrings(ValidateJSON([]byte(valid), &r))
+	// the three broken tags always report
+	if want := []string{"unknown uppercase", "bad_limit min", "no_length max"}; !slices.Equal(got, want) {
+		t.Fatalf("valid input: got %q, want %q", got, want)
+	}
+
+	invalid := `{"min_len":"a","max_num":11,"email":"nope","role":"root","both":"x","score":0.4,"unicode":"日本語!"}`
+	r = rules{}
+	got = fieldErrorStrings(ValidateJSON([]byte(invalid), &r))
+	want := []string{"required required", "min_len min", "max_num max", "email email", "role oneof",
+		"both min", "both email", "score min", "unicode max", "unknown uppercase", "bad_limit min", "no_length max"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("invalid input:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestValidateJSONCreateUserRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"valid", `{"name":"Rishabh","email":"r@example.com","role":"admin","address":{"city":"Delhi","pincode":"110001"},"skills":[{"name":"Go","level":4}]}`, []string{}},
+		{"nil pointer is absent", `{"name":"Rishabh","email":"r@example.com","role":"user","address":{"city":"Delhi","pincode":"110001"}}`, []string{}},
+		{"pointer set and invalid", `{"name":"Rishabh","email":"r@example.com","role":"user","age":7,"address":{"city":"Delhi","pincode":"110001"}}`, []string{"age min"}},
+		{"required pointer missing", `{"name":"Rishabh","email":"r@example.com","role":"user"}`, []string{"address required"}},
+		{"nested struct", `{"name":"Rishabh","email":"r@example.com","role":"user","address":{"pincode":"11"}}`, []string{"address.city required", "address.pincode min"}},
+		{"slice of structs", `{"name":"Rishabh","email":"r@example.com","role":"user","address":{"city":"D","pincode":"110001"},"skills":[{"name":"Go","level":9},{"level":1}]}`, []string{"skills[0].level max", "skills[1].name required"}},
+		{"too many items", `{"name":"Rishabh","email":"r@example.com","role":"user","address":{"city":"D","pincode":"110001"},"skills":[{"name":"a","level":1},{"name":"b","level":1},{"name":"c","level":1},{"name":"d","level":1}]}`, []string{"skills max"}},
+		{"everything wrong", `{"name":"R","email":"x","role":"root","age":200}`, []string{"name min", "email email", "role oneof", "age max", "address required"}},
+		{"wrong type", `{"age":"twenty"}`, []string{"age type"}},
+		{"not JSON", `{"name":`, []string{" json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req CreateUserRequest
+			got := fieldErrorStrings(ValidateJSON([]byte(tt.input), &req))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateJSONFieldNames(t *testing.T) {
+	type Inner struct {
+		Code string `json:"code" validate:"required"`
+	}
+	type Outer struct {
+		Inner           // embedded: flattened, errors use "code"
+		GoName   string `validate:"required"` // no json tag: the Go name
+		Renamed  string `json:"renamed_field,omitempty" validate:"required"`
+		Skipped  string `json:"-" validate:"required"`
+		private  string `validate:"required"`
+		Optional *Inner `json:"optional"` // nil: its inner rules don't run
+	}
+	var o Outer
+	got := fieldErrorStrings(ValidateJSON([]byte(`{}`), &o))
+	if want := []string{"code required", "GoName required", "renamed_field required"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	_ = o.private
+
+	o = Outer{}
+	got = fieldErrorStrings(ValidateJSON([]byte(`{"code":"x","GoName":"g","renamed_field":"r","optional":{}}`), &o))
+	if want := []string{"optional.code required"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// There are several ways to send a JSON list from a handler. They all produce the same bytes,
+// but they differ in allocations and in how long they hold locks.
+// main times them with a plain loop; go test main.go main_test.go -bench . -benchmem does it properly.
+
+type User struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	Role    string    `json:"role"`
+	Created time.Time `json:"created"`
+}
+
+type UsersResponse struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+type UserStore struct {
+	mu    sync.RWMutex
+	users []User
+}
+
+func NewUserStore(n int) *UserStore {
+	s := &UserStore{users: make([]User, n)}
+	roles := []string{"user", "user", "user", "moderator", "admin"}
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range s.users {
+		id := strconv.Itoa(i + 1)
+		s.users[i] = User{ID: i + 1, Name: "User " + id, Email: "user" + id + "@example.com",
+			Role: roles[i%len(roles)], Created: created.Add(time.Duration(i) * time.Minute)}
+	}
+	return s
+}
+
+// snapshot copies the slice header under the lock. The users are values and the store
+// only appends, so the copy is safe to encode after unlocking.
+func (s *UserStore) snapshot() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[:len(s.users):len(s.users)]
+}
+
+// ----------------------------------------------------------------------------
+// The strategies
+
+// marshal builds the whole body in a new []byte, then writes it
+func marshal(s *UserStore, w http.ResponseWriter) {
+	users := s.snapshot()
+	data, err := json.Marshal(UsersResponse{Users: users, Total: len(users)})
+	if err != nil {
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// encoder writes with json.Encoder. It still builds the whole body first (in a buffer
+// the json package reuses), so nothing is written yet when Encode fails and a 500 is still possible.
+func encoder(s *UserStore, w http.ResponseWriter) {
+	users := s.snapshot()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(UsersResponse{Users: users, Total: len(users)}); err != nil {
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+	}
+}
+
+var bufPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// pooled encodes into a buffer taken from a sync.Pool, the buffer's memory is reused between requests
+func pooled(s *UserStore, w http.ResponseWriter) {
+	users := s.snapshot()
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() < 1<<20 { // don't keep huge buffers around forever
+			buf.Reset()
+			bufPool.Put(buf)
+		}
+	}()
+	if err := json.NewEncoder(buf).Encode(UsersResponse{Users: users, Total: len(users)}); err != nil {
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// marshalInLock is the mistake: the read lock is held during the whole encoding,
+// so a writer (a new signup) waits for every in-flight list request
+func marshalInLock(s *UserStore, w http.ResponseWriter) {
+	s.mu.RLock()
+	data, err := json.Marshal(UsersResponse{Users: s.users, Total: len(s.users)})
+	s.mu.RUnlock()
+	if err != nil {
+		http.Error(w, "encode failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleGetUsers uses json.Encoder: in the table below it allocates almost nothing at every size.
+// The pooled buffer does as well until a body outgrows its 1 MiB cap, then it allocates like Marshal.
+func handleGetUsers(s *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { encoder(s, w) }
+}
+
+// ----------------------------------------------------------------------------
+
+// discardWriter is a ResponseWriter that throws the body away, so the benchmark
+// measures the encoding and not a recorder growing its buffer
+type discardWriter struct{ h http.Header }
+
+func (d *discardWriter) Header() http.Header         { return d.h }
+func (d *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+type strategy struct {
+	name string
+	fn   func(*UserStore, http.ResponseWriter)
+}
+
+var strategies = []strategy{
+	{"json.Marshal", marshal},
+	{"json.Encoder", encoder},
+	{"pooled buffer", pooled},
+	{"marshal in lock", marshalInLock},
+}
+
+// startWriter keeps taking the write lock from another goroutine, like signups arriving
+// during list requests. stop ends it and returns how long a write waited on average.
+func startWriter(s *UserStore) (stop func() time.Duration) {
+	quit := make(chan struct{})
+	var writes, waited int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			t0 := time.Now()
+			s.mu.Lock()
+			waited += int64(time.Since(t0))
+			writes++
+			s.mu.Unlock()
+			time.Sleep(50 * time.Microsecond)
+		}
+	}()
+	return func() time.Duration {
+		close(quit)
+		<-done
+		if writes == 0 {
+			return 0
+		}
+		return time.Duration(waited / writes)
+	}
+}
+
+type timing struct {
+	nsPerOp, bytesPerOp, allocsPerOp int64
+	writeWait                        time.Duration
+}
+
+// measure calls fn from GOMAXPROCS goroutines for d while a writer runs. Per call it's the
+// wall time divided by the calls, like b.RunParallel, and the allocations from runtime.MemStats.
+func measure(s *UserStore, fn func(*UserStore, http.ResponseWriter), d time.Duration) timing {
+	var calls atomic.Int64
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	stop := startWriter(s)
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range runtime.GOMAXPROCS(0) {
+		wg.Go(func() {
+			w := &discardWriter{h: http.Header{}}
+			for time.Since(start) < d {
+				fn(s, w)
+				calls.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	wait := stop()
+	runtime.ReadMemStats(&after)
+	n := max(calls.Load(), 1)
+	return timing{
+		nsPerOp:     int64(elapsed) / n,
+		bytesPerOp:  int64(after.TotalAlloc-before.TotalAlloc) / n,
+		allocsPerOp: int64(after.Mallocs-before.Mallocs) / n,
+		writeWait:   wait,
+	}
+}
+
+func main() {
+	fmt.Println("Learning to compare JSON encoding strategies in Go")
+	benchtime := flag.Duration("benchtime", 200*time.Millisecond, "time per benchmark")
+	flag.Parse()
+
+	// all strategies must send the same body
+	s := NewUserStore(3)
+	var bodies []string
+	for _, st := range strategies {
+		rec := httptest.NewRecorder()
+		st.fn(s, rec)
+		bodies = append(bodies, string(bytes.TrimSpace(rec.Body.Bytes())))
+	}
+	same := true
+	for _, b := range bodies[1:] {
+		same = same && b == bodies[0]
+	}
+	fmt.Println("Same body from every strategy:", same)
+
+	// go test -bench would use 100k users too, this keeps the demo fast
+	for _, n := range []int{10, 1_000, 10_000} {
+		s := NewUserStore(n)
+		fmt.Printf("\n%d users (GOMAXPROCS=%d, 1 writer)\n", n, runtime.GOMAXPROCS(0))
+		fmt.Printf("  %-16s %12s %12s %10s %14s\n", "strategy", "ns/op", "B/op", "allocs/op", "writer waits")
+		for _, st := range strategies {
+			r := measure(s, st.fn, *benchtime)
+			fmt.Printf("  %-16s %12d %12d %10d %14s\n", st.name, r.nsPerOp, r.bytesPerOp, r.allocsPerOp,
+				r.writeWait.Round(time.Microsecond))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handleGetUsers(NewUserStore(2)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	fmt.Printf("\nGET /users -> %d %s", rec.Code, rec.Body.String())
+}
+
+// Measure before switching: go test -bench with -benchmem shows the time and the garbage made.
+// json.Encoder on a ResponseWriter is not streaming: it builds the whole value, then writes it.
+// A sync.Pool of buffers saves the big allocation per request, until a body is bigger than what you agree to keep.
+// Copy what you need under the lock and encode after unlocking, encoding is the slow part.
+// Parallel benchmarks (b.RunParallel) show lock contention that a single goroutine never hits.
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSameBody(t *testing.T) {
+	for _, n := range []int{0, 1, 50} {
+		s := NewUserStore(n)
+		want, err := json.Marshal(UsersResponse{Users: s.users, Total: n})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, st := range strategies {
+			rec := httptest.NewRecorder()
+			st.fn(s, rec)
+			if got := strings.TrimSuffix(rec.Body.String(), "\n"); got != string(want) {
+				t.Errorf("%s, %d users: body differs\n got %.120s\nwant %.120s", st.name, n, got, want)
+			}
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("%s: %d %q", st.name, rec.Code, rec.Header().Get("Content-Type"))
+			}
+		}
+	}
+}
+
+// a reused buffer must not carry the previous response
+func TestPooledReuse(t *testing.T) {
+	big, small := NewUserStore(100), NewUserStore(1)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			for i := range 50 {
+				s, n := big, 100
+				if i%2 == 1 {
+					s, n = small, 1
+				}
+				rec := httptest.NewRecorder()
+				pooled(s, rec)
+				var resp UsersResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Total != n || len(resp.Users) != n {
+					t.Errorf("%d users: total %d, %d users, %v", n, resp.Total, len(resp.Users), err)
+					return
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestSnapshot(t *testing.T) {
+	s := NewUserStore(2)
+	snap := s.snapshot()
+	s.mu.Lock()
+	s.users = append(s.users, User{ID: 3})
+	s.mu.Unlock()
+	// the full slice expression means an append to the snapshot can't write into the store's array
+	snap = append(snap, User{ID: 99})
+	if len(snap) != 3 || s.users[2].ID != 3 {
+		t.Errorf("snapshot %v, store %v", snap, s.users)
+	}
+}
+
+func TestHandleGetUsers(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleGetUsers(NewUserStore(2)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	want := `{"users":[{"id":1,"name":"User 1","email":"user1@example.com","role":"user","created":"2024-01-01T00:00:00Z"},` +
+		`{"id":2,"name":"User 2","email":"user2@example.com","role":"user","created":"2024-01-01T00:01:00Z"}],"total":2}` + "\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("%d %s", rec.Code, rec.Body)
+	}
+}
+
+// go test main.go main_test.go -bench . -benchmem
+func BenchmarkStrategies(b *testing.B) {
+	for _, n := range []int{10, 1_000, 100_000} {
+		s := NewUserStore(n)
+		for _, st := range strategies {
+			b.Run(fmt.Sprintf("%s/%d", strings.ReplaceAll(st.name, " ", "_"), n), func(b *testing.B) {
+				b.ReportAllocs()
+				w := &discardWriter{h: http.Header{}}
+				for b.Loop() {
+					st.fn(s, w)
+				}
+			})
+		}
+	}
+}
+
+// the table main prints: every strategy in parallel while a writer keeps taking the lock
+func BenchmarkWithWriter(b *testing.B) {
+	for _, n := range []int{10, 1_000, 10_000} {
+		s := NewUserStore(n)
+		for _, st := range strategies {
+			b.Run(fmt.Sprintf("%s/%d", strings.ReplaceAll(st.name, " ", "_"), n), func(b *testing.B) {
+				b.ReportAllocs()
+				stop := startWriter(s)
+				b.RunParallel(func(pb *testing.PB) {
+					w := &discardWriter{h: http.Header{}}
+					for pb.Next() {
+						st.fn(s, w)
+					}
+				})
+				b.ReportMetric(float64(stop()), "write-wait-ns")
+			})
+		}
+	}
+}
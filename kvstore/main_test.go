@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// open opens path and closes the store at the end of the test, crashed or not
+func open(t *testing.T, path string, opts ...Option) *KV {
+	t.Helper()
+	kv, err := Open(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { kv.Close() })
+	return kv
+}
+
+func wantValues(t *testing.T, kv *KV, want map[string]string) {
+	t.Helper()
+	if kv.Len() != len(want) {
+		t.Errorf("Len() = %d, want %d", kv.Len(), len(want))
+	}
+	for k, v := range want {
+		if got, ok := kv.Get(k); !ok || got != v {
+			t.Errorf("Get(%q) = %q, %v; want %q", k, got, ok, v)
+		}
+	}
+}
+
+// codecs runs a test against each record format, the recovery rules are the same
+var codecs = []struct {
+	name  string
+	codec codec
+	opts  []Option
+}{
+	{"json", jsonCodec{}, nil},
+	{"binary", binaryCodec{}, []Option{WithBinaryCodec()}},
+}
+
+func TestCrashAndReopen(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.wal")
+			want := map[string]string{}
+			steps := []func(kv *KV) error{
+				func(kv *KV) error { want["user:1"] = "Rishabh"; return kv.Set("user:1", "Rishabh") },
+				func(kv *KV) error { want["user:2"] = "Sanchay"; return kv.Set("user:2", "Sanchay") },
+				func(kv *KV) error { want["user:1"] = "Rishabh Gupta"; return kv.Set("user:1", "Rishabh Gupta") },
+				func(kv *KV) error { delete(want, "user:2"); return kv.Delete("user:2") },
+				func(kv *KV) error { return kv.Delete("never-set") },
+				func(kv *KV) error { want["empty"] = ""; return kv.Set("empty", "") },
+				func(kv *KV) error { want["multi\nline"] = "a\nb"; return kv.Set("multi\nline", "a\nb") },
+				func(kv *KV) error {
+					want["ünïcödé 🙂"] = "नमस्ते"
+					return kv.Set("ünïcödé 🙂", "नमस्ते")
+				},
+			}
+			for i, step := range steps {
+				kv := open(t, path, c.opts...) // the previous one is never closed: a crash after every step
+				if err := step(kv); err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+				wantValues(t, open(t, path, c.opts...), want)
+			}
+		})
+	}
+}
+
+// writeRecords writes a record per key and returns the file size before the last one
+func writeRecords(t *testing.T, path string, opts []Option, keys ...string) (beforeLast int64) {
+	t.Helper()
+	kv := open(t, path, opts...)
+	for _, k := range keys {
+		beforeLast = fileSize(path)
+		if err := kv.Set(k, "value of "+k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kv.Close()
+	return beforeLast
+}
+
+func TestTornLastRecord(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.wal")
+			writeRecords(t, path, c.opts, "a", "b")
+			size := fileSize(path)
+
+			rec, _ := c.codec.encode(record{Op: "set", Key: "c", Value: "value of c"})
+			for cut := 1; cut < len(rec); cut++ {
+				appendBytes(t, path, rec[:cut])
+				kv := open(t, path, c.opts...)
+				wantValues(t, kv, map[string]string{"a": "value of a", "b": "value of b"})
+				if got := fileSize(path); got != size {
+					t.Fatalf("cut at %d: file is %d bytes after Open, want %d", cut, got, size)
+				}
+				kv.Close()
+			}
+			// new records go right after the last good one
+			kv := open(t, path, c.opts...)
+			kv.Set("c", "value of c")
+			kv.Close()
+			wantValues(t, open(t, path, c.opts...), map[string]string{"a": "value of a", "b": "value of b", "c": "value of c"})
+		})
+	}
+}
+
+func TestCorruptLastRecord(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.wal")
+			lastStart := writeRecords(t, path, c.opts, "a", "b")
+			data, _ := os.ReadFile(path)
+			end := len(data)
+			if c.name == "json" {
+				end-- // a flipped newline is the torn case above
+			}
+			for i := int(lastStart); i < end; i++ { // every byte of the last record
+				damaged := bytes.Clone(data)
+				damaged[i] ^= 0x01
+				os.WriteFile(path, damaged, 0o644)
+				kv, err := Open(path, c.opts...)
+				if err != nil {
+					t.Fatalf("byte %d: %v", i, err)
+				}
+				wantValues(t, kv, map[string]string{"a": "value of a"})
+				kv.Close()
+				if got := fileSize(path); got != lastStart {
+					t.Fatalf("byte %d: %d bytes left, want %d", i, got, lastStart)
+				}
+			}
+		})
+	}
+}
+
+// damage anywhere before the last record is ErrCorrupt, and the file is left as it was
+func TestCorruptionInTheMiddle(t *testing.T) {
+	// what to do to the fourth of five records: flip the byte at offset (from its end when
+	// negative), or when length is set, write the length field of a binary record
+	type damage struct {
+		name   string
+		offset int
+		length func(rest int) uint32 // rest: the bytes after the length field
+	}
+	damages := map[string][]damage{
+		"json": {
+			{name: "checksum", offset: 3},
+			{name: "json", offset: 20},
+			{name: "newline", offset: -1}, // the last two records become one broken line
+		},
+		"binary": {
+			{name: "checksum", offset: 3},
+			{name: "payload", offset: 12},
+			// a length that reaches exactly the end of the file, or past it: without a look
+			// inside, the last two records pass for one damaged or torn record
+			{name: "length to EOF", length: func(rest int) uint32 { return uint32(rest) }},
+			{name: "length past EOF", length: func(rest int) uint32 { return uint32(rest + 100) }},
+			{name: "length short", length: func(int) uint32 { return 3 }},
+			{name: "length huge", length: func(int) uint32 { return 1 << 31 }},
+		},
+	}
+	for _, c := range codecs {
+		for _, d := range damages[c.name] {
+			t.Run(c.name+"/"+d.name, func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "data.wal")
+				writeRecords(t, path, c.opts, "key0", "key1", "key2")
+				start := fileSize(path)
+				writeRecords(t, path, c.opts, "key3")
+				end := fileSize(path)
+				writeRecords(t, path, c.opts, "key4")
+				data, _ := os.ReadFile(path)
+				switch {
+				case d.length != nil:
+					at := int(start) + 4
+					binary.BigEndian.PutUint32(data[at:], d.length(len(data)-at-4))
+				case d.offset >= 0:
+					data[int(start)+d.offset] ^= 0xff
+				default:
+					data[int(end)+d.offset] ^= 0xff
+				}
+				os.WriteFile(path, data, 0o644)
+				if _, err := Open(path, c.opts...); !errors.Is(err, ErrCorrupt) {
+					t.Fatalf("Open = %v, want ErrCorrupt", err)
+				}
+				if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+					t.Error("Open changed a corrupt file, the evidence is gone")
+				}
+			})
+		}
+	}
+}
+
+// find is what tells one torn record from a damaged one with good records behind it
+func TestFindRecord(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			rec, _ := c.codec.encode(record{Op: "del", Key: "k"})
+			tests := []struct {
+				data []byte
+				want int
+			}{
+				{nil, -1},
+				{rec, 0},
+				{rec[:len(rec)-1], -1},
+				{rec[1:], -1},
+				{append([]byte("xyz"), rec...), 3},
+				{make([]byte, 64), -1}, // zeros: an empty payload has a zero checksum, but no op
+			}
+			for _, tt := range tests {
+				if got := c.codec.find(tt.data); got != tt.want {
+					t.Errorf("find(%q) = %d, want %d", tt.data, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	for _, c := range codecs {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.wal")
+			kv := open(t, path, c.opts...)
+			for i := range 200 {
+				kv.Set(fmt.Sprint("key", i%10), fmt.Sprint(i))
+			}
+			kv.Delete("key0")
+			before := fileSize(path)
+			if err := kv.Compact(); err != nil {
+				t.Fatal(err)
+			}
+			after := fileSize(path)
+			if after >= before/10 {
+				t.Errorf("Compact: %d -> %d bytes", before, after)
+			}
+			want := map[string]string{}
+			for i := 1; i < 10; i++ {
+				want[fmt.Sprint("key", i)] = fmt.Sprint(190 + i)
+			}
+			wantValues(t, kv, want)
+
+			// writes after Compact land in the new file
+			kv.Set("after", "compact")
+			want["after"] = "compact"
+			wantValues(t, open(t, path, c.opts...), want) // crash, reopen
+
+			matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".*tmp*"))
+			if len(matches) != 0 {
+				t.Errorf("temp files left: %v", matches)
+			}
+		})
+	}
+}
+
+func TestClosed(t *testing.T) {
+	kv := open(t, filepath.Join(t.TempDir(), "data.wal"))
+	kv.Set("a", "1")
+	if err := kv.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := kv.Set("b", "2"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Set: %v", err)
+	}
+	if err := kv.Delete("a"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Delete: %v", err)
+	}
+	if err := kv.Compact(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Compact: %v", err)
+	}
+	if err := kv.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Close twice: %v", err)
+	}
+	if v, _ := kv.Get("a"); v != "1" {
+		t.Error("reads stop working after Close")
+	}
+}
+
+func TestDataStorage(t *testing.T) {
+	for _, s := range []DataStorage{
+		&MemoryStorage{data: map[string]string{}},
+		open(t, filepath.Join(t.TempDir(), "data.wal")),
+	} {
+		if err := checkDataStorage(s); err != nil {
+			t.Errorf("%T: %v", s, err)
+		}
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.wal")
+	kv := open(t, path)
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Go(func() {
+			for i := range 25 {
+				kv.Set(fmt.Sprintf("w%d:%d", w, i), "v")
+				kv.Get("w0:0")
+			}
+		})
+	}
+	wg.Wait()
+	if kv.Len() != 200 || open(t, path).Len() != 200 {
+		t.Errorf("Len %d, after reopen %d", kv.Len(), open(t, path).Len())
+	}
+}
+
+func appendBytes(t *testing.T, path string, b []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
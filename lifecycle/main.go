@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// A real program runs several long-lived parts: a database pool, an HTTP server,
+// a scheduler, a metrics sink... They must start in order (the server needs the database)
+// and stop in the REVERSE order (stop taking requests before closing the database).
+// App keeps that order in one place.
+
+// ErrStopTimeout is returned for a component whose stop didn't finish in time
+var ErrStopTimeout = errors.New("stop timed out")
+
+const (
+	stateStarting int32 = iota
+	stateRunning
+	stateStopping
+)
+
+type component struct {
+	name  string
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+type probe struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type App struct {
+	StopTimeout time.Duration // per component
+	DrainDelay  time.Duration // time between "not ready" and stopping anything, for load balancers to notice
+	Log         func(format string, args ...any)
+
+	components []component
+	probes     []probe
+	failures   chan error
+	state      atomic.Int32
+}
+
+func NewApp(stopTimeout time.Duration) *App {
+	return &App{
+		StopTimeout: stopTimeout,
+		Log:         func(format string, args ...any) { fmt.Printf("  "+format+"\n", args...) },
+		failures:    make(chan error, 1),
+	}
+}
+
+// Add registers a component. start must return once the component is running
+// (start goroutines inside it, don't block), stop must make it finish.
+// stop may be nil for components that have nothing to clean up.
+func (a *App) Add(name string, start, stop func(ctx context.Context) error) {
+	a.components = append(a.components, component{name: name, start: start, stop: stop})
+}
+
+// AddProbe registers a check that must pass for the app to be ready, e.g. a database ping.
+// Probes run on every /readyz request, so they must be quick.
+func (a *App) AddProbe(name string, check func(ctx context.Context) error) {
+	a.probes = append(a.probes, probe{name: name, check: check})
+}
+
+// Fail is called by a running component that broke (e.g. the HTTP server stopped serving).
+// Run then stops everything. Only the first failure is kept.
+func (a *App) Fail(name string, err error) {
+	select {
+	case a.failures <- fmt.Errorf("%s failed: %w", name, err):
+	default:
+	}
+}
+
+// Run starts every component in order, waits for SIGINT/SIGTERM, a component failure
+// or ctx to be cancelled, then stops the started components in reverse order.
+// The returned error joins the reason for stopping (nil for a normal shutdown) and any stop errors.
+func (a *App) Run(ctx context.Context) error {
+	ctx, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	var cause error
+	started := 0
+	for _, c := range a.components {
+		a.Log("starting %s", c.name)
+		if err := c.start(ctx); err != nil {
+			cause = fmt.Errorf("start %s: %w", c.name, err)
+			a.Log("%v, not starting the rest", cause)
+			break
+		}
+		started++
+	}
+
+	if cause == nil {
+		a.Log("all %d components running", started)
+		a.state.Store(stateRunning)
+		select {
+		case <-ctx.Done():
+			a.Log("shutting down: %v", context.Cause(ctx))
+		case cause = <-a.failures:
+			a.Log("shutting down: %v", cause)
+		}
+	}
+
+	// Not ready any more, but still serving: load balancers take a few seconds to notice a
+	// failing /readyz, and requests they send meanwhile must still be answered.
+	wasRunning := a.state.Swap(stateStopping) == stateRunning
+	if wasRunning && a.DrainDelay > 0 {
+		a.Log("not ready, draining for %s", a.DrainDelay)
+		time.Sleep(a.DrainDelay)
+	}
+
+	errs := []error{cause}
+	for i := started - 1; i >= 0; i-- {
+		if err := a.stopOne(a.components[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...) // nil when every error is nil
+}
+
+// stopOne gives c its own timeout. A stop that ignores its context is abandoned after
+// the timeout, so one stuck component can't keep the whole process from exiting.
+func (a *App) stopOne(c component) error {
+	if c.stop == nil {
+		return nil
+	}
+	a.Log("stopping %s", c.name)
+	// a fresh context: the run context is already cancelled at this point
+	ctx, cancel := context.WithTimeout(context.Background(), a.StopTimeout)
+	defer cancel()
+	done := make(chan error, 1) // buffered, so a stop that finishes after the timeout doesn't block forever
+	go func() { done <- c.stop(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("stop %s: %w", c.name, err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stop %s: %w after %s", c.name, ErrStopTimeout, a.StopTimeout)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Health endpoints
+
+// HandleHealth adds the two endpoints an orchestrator like Kubernetes asks:
+//   - /healthz (liveness): is the process alive? Always 200, a failure here means "restart me"
+//   - /readyz (readiness): should I get traffic? 503 while starting, when a probe fails,
+//     and from the moment shutdown begins
+func (a *App) HandleHealth(mux *http.ServeMux) {
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		switch a.state.Load() {
+		case stateStarting:
+			http.Error(w, "starting", http.StatusServiceUnavailable)
+			return
+		case stateStopping:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+		defer cancel()
+		var failed []string
+		for _, p := range a.probes {
+			if err := p.check(ctx); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", p.name, err))
+			}
+		}
+		if failed != nil {
+			http.Error(w, strings.Join(failed, "; "), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ready")
+	})
+}
+
+// ----------------------------------------------------------------------------
+// Components used in the demo
+
+// fakeComponent starts and stops after a delay and can be told to fail
+func fakeComponent(startErr error, stopDelay time.Duration) (func(context.Context) error, func(context.Context) error) {
+	start := func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return startErr
+	}
+	stop := func(ctx context.Context) error {
+		select {
+		case <-time.After(stopDelay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return start, stop
+}
+
+// httpComponent runs h (or an "ok" handler when h is nil) on a free port,
+// and sends the address on listening (when not nil) once it accepts connections
+func httpComponent(a *App, h http.Handler, listening chan<- string) (func(context.Context) error, func(context.Context) error) {
+	if h == nil {
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, "ok") })
+	}
+	srv := &http.Server{Handler: h}
+	start := func(ctx context.Context) error {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err // e.g. port already in use: fail the start, not later
+		}
+		if listening != nil {
+			listening <- ln.Addr().String()
+		}
+		a.Log("http listening on %s", ln.Addr())
+		go func() {
+			if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				a.Fail("http", err)
+			}
+		}()
+		return nil
+	}
+	stop := func(ctx context.Context) error {
+		return srv.Shutdown(ctx) // finishes in-flight requests, or gives up when ctx ends
+	}
+	return start, stop
+}
+
+// schedulerComponent ticks in a goroutine until stopped
+func schedulerComponent(a *App, failAfter int) (func(context.Context) error, func(context.Context) error) {
+	quit := make(chan struct{})
+	var wg sync.WaitGroup
+	start := func(ctx context.Context) error {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(20 * time.Millisecond)
+			defer ticker.Stop()
+			for n := 1; ; n++ {
+				select {
+				case <-quit:
+					return
+				case <-ticker.C:
+					if n == failAfter {
+						a.Fail("scheduler", errors.New("job store unreachable"))
+						return
+					}
+				}
+			}
+		}()
+		return nil
+	}
+	stop := func(ctx context.Context) error {
+		close(quit)
+		wg.Wait()
+		return nil
+	}
+	return start, stop
+}
+
+func main() {
+	fmt.Println("Learning graceful startup and shutdown in Go")
+
+	fmt.Println("\n1. Normal run, stopped by SIGINT")
+	app := NewApp(time.Second)
+	start, stop := fakeComponent(nil, 10*time.Millisecond)
+	app.Add("database", start, stop)
+	start, stop = httpComponent(app, nil, nil)
+	app.Add("http", start, stop)
+	start, stop = schedulerComponent(app, 0)
+	app.Add("scheduler", start, stop)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		syscall.Kill(os.Getpid(), syscall.SIGINT) // what Ctrl+C in the terminal does
+	}()
+	fmt.Println("  Run returned:", app.Run(context.Background()))
+
+	fmt.Println("\n2. A component fails to start")
+	app = NewApp(time.Second)
+	start, stop = fakeComponent(nil, 10*time.Millisecond)
+	app.Add("database", start, stop)
+	start, stop = fakeComponent(errors.New("connection refused"), 0)
+	app.Add("cache", start, stop)
+	start, stop = httpComponent(app, nil, nil)
+	app.Add("http", start, stop)
+	fmt.Println("  Run returned:", app.Run(context.Background()))
+
+	fmt.Println("\n3. A component fails while running, and one stop hangs")
+	app = NewApp(100 * time.Millisecond)
+	start, stop = fakeComponent(nil, 10*time.Millisecond)
+	app.Add("database", start, stop)
+	app.Add("metrics", func(ctx context.Context) error { return nil }, func(ctx context.Context) error {
+		select {} // a stop that never returns and ignores ctx
+	})
+	start, stop = schedulerComponent(app, 3)
+	app.Add("scheduler", start, stop)
+	began := time.Now()
+	err := app.Run(context.Background())
+	fmt.Println("  Run returned:", strings.ReplaceAll(err.Error(), "\n", "\n               ")) // Join puts one error per line
+	fmt.Println("  is ErrStopTimeout:", errors.Is(err, ErrStopTimeout), "| finished in", time.Since(began).Round(10*time.Millisecond))
+
+	fmt.Println("\n4. Readiness: 503 while starting, while a probe fails, and as soon as shutdown begins")
+	app = NewApp(time.Second)
+	app.DrainDelay = 200 * time.Millisecond
+	app.Log = func(string, ...any) {} // only the view from outside this time
+	var dbUp atomic.Bool
+	dbUp.Store(true)
+	app.AddProbe("database", func(ctx context.Context) error {
+		if !dbUp.Load() {
+			return errors.New("ping failed")
+		}
+		return nil
+	})
+	mux := http.NewServeMux()
+	app.HandleHealth(mux)
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprintln(w, "slow done")
+	})
+	listening := make(chan string, 1)
+	start, stop = fakeComponent(nil, 10*time.Millisecond)
+	app.Add("database", start, stop)
+	start, stop = httpComponent(app, mux, listening)
+	app.Add("http", start, stop)
+	app.Add("cache warmup", func(ctx context.Context) error { time.Sleep(100 * time.Millisecond); return nil }, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	runDone := make(chan error)
+	go func() { runDone <- app.Run(ctx) }()
+
+	addr := <-listening
+	get := func(path string) string {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return "no answer"
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Sprintf("%d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	fmt.Println("  warming up:      /healthz", get("/healthz"), "| /readyz", get("/readyz"))
+	for !strings.HasPrefix(get("/readyz"), "200") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	fmt.Println("  started:         /readyz", get("/readyz"))
+	dbUp.Store(false)
+	fmt.Println("  database down:   /readyz", get("/readyz"))
+	dbUp.Store(true)
+
+	slow := make(chan string)
+	go func() { slow <- get("/slow") }()
+	time.Sleep(50 * time.Millisecond) // /slow is in flight now
+	began = time.Now()
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	fmt.Println("  shutdown begun:  /healthz", get("/healthz"), "| /readyz", get("/readyz"))
+	fmt.Println("  in-flight /slow:", <-slow)
+	fmt.Println("  Run returned:", <-runDone, "after", time.Since(began).Round(50*time.Millisecond))
+	fmt.Println("  after exit:      /readyz", get("/readyz"))
+}
+
+// Start in order, stop in reverse order, and only stop what was really started.
+// signal.NotifyContext turns Ctrl+C / SIGTERM (sent by Docker and Kubernetes) into a cancelled context.
+// Every stop gets its own timeout, a stuck component must not block the whole shutdown.
+// errors.Join collects the reason for stopping and every stop error into one error.
+// Flip /readyz to 503 first and wait a drain delay, THEN Shutdown, which finishes in-flight requests.
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// recorder logs what every component did, in order
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.events)
+}
+
+func (r *recorder) component(name string, startErr, stopErr error) (func(context.Context) error, func(context.Context) error) {
+	return func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		}, func(context.Context) error {
+			r.add("stop " + name)
+			return stopErr
+		}
+}
+
+func newTestApp(stopTimeout time.Duration) *App {
+	a := NewApp(stopTimeout)
+	a.Log = func(string, ...any) {}
+	return a
+}
+
+func TestStartOrderAndReverseStop(t *testing.T) {
+	rec := &recorder{}
+	a := newTestApp(time.Second)
+	for _, name := range []string{"db", "http", "scheduler"} {
+		start, stop := rec.component(name, nil, nil)
+		a.Add(name, start, stop)
+	}
+	a.Add("no-stop", func(context.Context) error { rec.add("start no-stop"); return nil }, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+	waitFor(t, func() bool { return len(rec.get()) == 4 })
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v, want nil for a normal shutdown", err)
+	}
+	want := []string{"start db", "start http", "start scheduler", "start no-stop", "stop scheduler", "stop http", "stop db"}
+	if got := rec.get(); !slices.Equal(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestFailingStart(t *testing.T) {
+	rec := &recorder{}
+	a := newTestApp(time.Second)
+	refused := errors.New("connection refused")
+	for _, c := range []struct {
+		name string
+		err  error
+	}{{"db", nil}, {"http", nil}, {"cache", refused}, {"scheduler", nil}} {
+		start, stop := rec.component(c.name, c.err, nil)
+		a.Add(c.name, start, stop)
+	}
+	err := a.Run(context.Background())
+	if !errors.Is(err, refused) || !strings.Contains(err.Error(), "start cache") {
+		t.Errorf("Run = %v", err)
+	}
+	// the failed one isn't stopped (it never started), later ones never start, earlier ones stop
+	want := []string{"start db", "start http", "start cache", "stop http", "stop db"}
+	if got := rec.get(); !slices.Equal(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestHangingStopTimesOut(t *testing.T) {
+	rec := &recorder{}
+	a := newTestApp(50 * time.Millisecond)
+	start, stop := rec.component("db", nil, nil)
+	a.Add("db", start, stop)
+	block := make(chan struct{})
+	defer close(block)
+	a.Add("metrics", func(context.Context) error { return nil }, func(context.Context) error {
+		<-block // ignores its context
+		return nil
+	})
+	start, stop = rec.component("http", nil, nil)
+	a.Add("http", start, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	began := time.Now()
+	err := a.Run(ctx)
+	if !errors.Is(err, ErrStopTimeout) || !strings.Contains(err.Error(), "stop metrics") {
+		t.Errorf("Run = %v", err)
+	}
+	if d := time.Since(began); d > time.Second {
+		t.Errorf("Run took %v with a 50ms stop timeout", d)
+	}
+	// the components around the stuck one still stopped
+	if got, want := rec.get(), []string{"start db", "start http", "stop http", "stop db"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestStopErrorsJoined(t *testing.T) {
+	rec := &recorder{}
+	a := newTestApp(time.Second)
+	errA, errB := errors.New("flush failed"), errors.New("close failed")
+	start, stop := rec.component("a", nil, errA)
+	a.Add("a", start, stop)
+	start, stop = rec.component("b", nil, errB)
+	a.Add("b", start, stop)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := a.Run(ctx)
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Run = %v, want both stop errors", err)
+	}
+}
+
+func TestComponentFailure(t *testing.T) {
+	a := newTestApp(time.Second)
+	start, stop := schedulerComponent(a, 2)
+	a.Add("scheduler", start, stop)
+	err := a.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "scheduler failed: job store unreachable") {
+		t.Errorf("Run = %v", err)
+	}
+	// only the first failure is kept, later ones don't block
+	a.Fail("x", errors.New("one"))
+	a.Fail("y", errors.New("two"))
+}
+
+func TestSignalStops(t *testing.T) {
+	a := newTestApp(time.Second)
+	started := make(chan struct{})
+	a.Add("c", func(context.Context) error { close(started); return nil }, nil)
+	done := make(chan error)
+	go func() { done <- a.Run(context.Background()) }()
+	<-started // Run listens for signals before starting anything
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SIGTERM did not stop Run")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	a := newTestApp(time.Second)
+	a.DrainDelay = 100 * time.Millisecond
+	var dbErr error
+	var mu sync.Mutex
+	a.AddProbe("database", func(context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return dbErr
+	})
+	mux := http.NewServeMux()
+	a.HandleHealth(mux)
+	get := func(path string) string {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		return strings.TrimSpace(http.StatusText(rec.Code) + " " + string(body))
+	}
+
+	if got := get("/readyz"); got != "Service Unavailable starting" {
+		t.Errorf("before Run: %q", got)
+	}
+	if got := get("/healthz"); got != "OK ok" {
+		t.Errorf("healthz: %q", got)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+	waitFor(t, func() bool { return get("/readyz") == "OK ready" })
+
+	mu.Lock()
+	dbErr = errors.New("ping failed")
+	mu.Unlock()
+	if got := get("/readyz"); got != "Service Unavailable database: ping failed" {
+		t.Errorf("probe failing: %q", got)
+	}
+	mu.Lock()
+	dbErr = nil
+	mu.Unlock()
+
+	cancel()
+	waitFor(t, func() bool { return get("/readyz") == "Service Unavailable shutting down" })
+	select {
+	case <-done:
+		t.Error("Run returned before the drain delay")
+	default:
+	}
+	if got := get("/healthz"); got != "OK ok" {
+		t.Errorf("healthz while draining: %q", got)
+	}
+	<-done
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// the request in the spec: shutdown starts, /readyz flips to 503 while the listener is still
+// open, and a request already in flight on another route still completes
+func TestDrainKeepsServing(t *testing.T) {
+	a := newTestApp(5 * time.Second)
+	a.DrainDelay = 300 * time.Millisecond
+	entered, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	a.HandleHealth(mux)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+		io.WriteString(w, "done")
+	})
+	listening := make(chan string, 1)
+	start, stop := httpComponent(a, mux, listening)
+	a.Add("http", start, stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	base := "http://" + <-listening
+	get := func(path string) (int, string, error) {
+		resp, err := http.Get(base + path)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body)), nil
+	}
+	waitFor(t, func() bool { code, _, _ := get("/readyz"); return code == http.StatusOK })
+
+	type result struct {
+		code int
+		body string
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		code, body, err := get("/slow")
+		slow <- result{code, body, err}
+	}()
+	<-entered
+	cancel()
+
+	// draining: not ready, but the listener is open and health still passes
+	waitFor(t, func() bool { code, _, _ := get("/readyz"); return code == http.StatusServiceUnavailable })
+	if code, body, err := get("/readyz"); err != nil || body != "shutting down" {
+		t.Errorf("readyz while draining: %d %q %v", code, body, err)
+	}
+	if code, _, err := get("/healthz"); err != nil || code != http.StatusOK {
+		t.Errorf("healthz while draining: %d %v", code, err)
+	}
+
+	// the in-flight request outlives the drain delay and Shutdown waits for it
+	time.Sleep(a.DrainDelay)
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned %v with a request in flight", err)
+	default:
+	}
+	close(release)
+	if r := <-slow; r.err != nil || r.code != http.StatusOK || r.body != "done" {
+		t.Errorf("in-flight request: %+v", r)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run = %v", err)
+	}
+	if _, _, err := get("/healthz"); err == nil {
+		t.Error("still serving after Run returned")
+	}
+}
+
+func TestStartingUntilAllStarted(t *testing.T) {
+	a := newTestApp(time.Second)
+	mux := http.NewServeMux()
+	a.HandleHealth(mux)
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		return rec.Code
+	}
+	a.Add("db", func(context.Context) error { return nil }, nil)
+	unblock := make(chan struct{})
+	a.Add("cache", func(context.Context) error { <-unblock; return nil }, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- a.Run(ctx) }()
+	time.Sleep(20 * time.Millisecond) // db is up, cache is still starting
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Errorf("with cache starting: %d", code)
+	}
+	close(unblock)
+	waitFor(t, func() bool { return readyz() == http.StatusOK })
+	cancel()
+	<-done
+}
+
+// a failed start was never ready, so there's nothing to drain
+func TestNoDrainAfterFailedStart(t *testing.T) {
+	a := newTestApp(time.Second)
+	a.DrainDelay = time.Hour
+	a.Add("db", func(context.Context) error { return errors.New("refused") }, nil)
+	done := make(chan error, 1)
+	go func() { done <- a.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Run = nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run waited out the drain delay after a failed start")
+	}
+}
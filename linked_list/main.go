@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ----------------------------------------------------------------------------
+// Singly linked list: every node points to the next one
+// ----------------------------------------------------------------------------
+
+// Node is one element of a List
+type Node[T any] struct {
+	Value T
+	next  *Node[T]
+}
+
+// Next returns the following node, nil at the end of the list
+func (n *Node[T]) Next() *Node[T] { return n.next }
+
+// List is a singly linked list. The zero value is an empty list ready to use.
+type List[T any] struct {
+	head *Node[T]
+	len  int
+}
+
+// Push adds v at the front in O(1)
+func (l *List[T]) Push(v T) *Node[T] {
+	l.head = &Node[T]{Value: v, next: l.head}
+	l.len++
+	return l.head
+}
+
+// Pop removes and returns the front value, ok is false for an empty list
+func (l *List[T]) Pop() (v T, ok bool) {
+	if l.head == nil {
+		return v, false
+	}
+	n := l.head
+	l.head = n.next
+	n.next = nil // the removed node no longer points into the list
+	l.len--
+	return n.Value, true
+}
+
+// InsertAfter adds v right after node (a node of this list) in O(1)
+func (l *List[T]) InsertAfter(node *Node[T], v T) *Node[T] {
+	n := &Node[T]{Value: v, next: node.next}
+	node.next = n
+	l.len++
+	return n
+}
+
+// Remove unlinks node from the list. It's O(n): a singly linked node doesn't know
+// its predecessor, so we walk from the head. Returns false if node isn't in the list.
+func (l *List[T]) Remove(node *Node[T]) bool {
+	// p points at the pointer that points to the current node (first &l.head, then &prev.next),
+	// so removing the head is not a special case
+	for p := &l.head; *p != nil; p = &(*p).next {
+		if *p == node {
+			*p = node.next
+			node.next = nil
+			l.len--
+			return true
+		}
+	}
+	return false
+}
+
+// Reverse turns the list around in place, no new nodes are allocated
+func (l *List[T]) Reverse() {
+	var prev *Node[T]
+	cur := l.head
+	for cur != nil {
+		next := cur.next // remember the rest of the list
+		cur.next = prev  // flip the pointer
+		prev, cur = cur, next
+	}
+	l.head = prev
+}
+
+func (l *List[T]) Front() *Node[T] { return l.head }
+func (l *List[T]) Len() int        { return l.len }
+
+// Values copies the values into a slice, front first
+func (l *List[T]) Values() []T {
+	values := make([]T, 0, l.len)
+	for n := l.head; n != nil; n = n.next {
+		values = append(values, n.Value)
+	}
+	return values
+}
+
+// ----------------------------------------------------------------------------
+// Doubly linked list: nodes point both ways, so removing a known node is O(1)
+// ----------------------------------------------------------------------------
+
+// Element is one element of a DList
+type Element[T any] struct {
+	Value      T
+	prev, next *Element[T]
+	list       *DList[T] // which list the element belongs to, nil after removal
+}
+
+func (e *Element[T]) Next() *Element[T] { return e.next }
+func (e *Element[T]) Prev() *Element[T] { return e.prev }
+
+// DList is a doubly linked list. The zero value is an empty list ready to use.
+type DList[T any] struct {
+	head, tail *Element[T]
+	len        int
+}
+
+func (l *DList[T]) PushFront(v T) *Element[T] {
+	e := &Element[T]{Value: v, list: l}
+	l.linkFront(e)
+	return e
+}
+
+func (l *DList[T]) PushBack(v T) *Element[T] {
+	e := &Element[T]{Value: v, list: l, prev: l.tail}
+	if l.tail != nil {
+		l.tail.next = e
+	} else {
+		l.head = e
+	}
+	l.tail = e
+	l.len++
+	return e
+}
+
+// Remove unlinks e in O(1) and returns its value. Removing an element of
+// another list (or one already removed) does nothing.
+func (l *DList[T]) Remove(e *Element[T]) T {
+	if e.list == l {
+		l.unlink(e)
+		e.list = nil
+	}
+	return e.Value
+}
+
+// MoveToFront moves e to the front of the list
+func (l *DList[T]) MoveToFront(e *Element[T]) {
+	if e.list != l || l.head == e {
+		return
+	}
+	l.unlink(e)
+	l.linkFront(e)
+}
+
+func (l *DList[T]) Front() *Element[T] { return l.head }
+func (l *DList[T]) Back() *Element[T]  { return l.tail }
+func (l *DList[T]) Len() int           { return l.len }
+
+func (l *DList[T]) Values() []T {
+	values := make([]T, 0, l.len)
+	for e := l.head; e != nil; e = e.next {
+		values = append(values, e.Value)
+	}
+	return values
+}
+
+func (l *DList[T]) linkFront(e *Element[T]) {
+	e.prev = nil
+	e.next = l.head
+	if l.head != nil {
+		l.head.prev = e
+	} else {
+		l.tail = e
+	}
+	l.head = e
+	l.len++
+}
+
+func (l *DList[T]) unlink(e *Element[T]) {
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		l.head = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		l.tail = e.prev
+	}
+	e.prev, e.next = nil, nil
+	l.len--
+}
+
+// ----------------------------------------------------------------------------
+// LRU cache: map for O(1) lookup + doubly linked list for O(1) "recently used" order
+// ----------------------------------------------------------------------------
+
+var ErrInvalidCapacity = errors.New("capacity must be at least 1")
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// LRUCache keeps at most Cap() items, when full it evicts the Least Recently Used one.
+// The front of the list is the most recently used entry, the back the least.
+// It is not safe for concurrent use, wrap it in a mutex when goroutines share it.
+type LRUCache[K comparable, V any] struct {
+	capacity int
+	items    map[K]*Element[entry[K, V]]
+	order    DList[entry[K, V]]
+	onEvict  func(key K, value V)
+}
+
+// NewLRUCache creates a cache, onEvict (may be nil) is called for every evicted entry
+func NewLRUCache[K comparable, V any](capacity int, onEvict func(key K, value V)) (*LRUCache[K, V], error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("new LRU cache with capacity %d: %w", capacity, ErrInvalidCapacity)
+	}
+	return &LRUCache[K, V]{capacity: capacity, items: make(map[K]*Element[entry[K, V]], capacity), onEvict: onEvict}, nil
+}
+
+// Get returns the value and marks it as most recently used
+func (c *LRUCache[K, V]) Get(key K) (V, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.value, true
+}
+
+// Put adds or updates key, evicting the least recently used entry when the cache is full
+func (c *LRUCache[K, V]) Put(key K, value V) {
+	if e, ok := c.items[key]; ok {
+		e.Value.value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	if c.order.Len() == c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.key)
+		if c.onEvict != nil {
+			c.onEvict(oldest.Value.key, oldest.Value.value)
+		}
+	}
+	c.items[key] = c.order.PushFront(entry[K, V]{key: key, value: value})
+}
+
+func (c *LRUCache[K, V]) Len() int { return c.order.Len() }
+func (c *LRUCache[K, V]) Cap() int { return c.capacity }
+
+// Keys returns the keys from most to least recently used
+func (c *LRUCache[K, V]) Keys() []K {
+	keys := make([]K, 0, c.order.Len())
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.key)
+	}
+	return keys
+}
+
+func main() {
+	fmt.Println("Learning linked lists and an LRU cache with pointers")
+
+	// singly linked list
+	var empty List[int]
+	empty.Reverse()
+	fmt.Println("Reversed empty list:", empty.Values())
+
+	var one List[int]
+	one.Push(1)
+	one.Reverse()
+	fmt.Println("Reversed one element:", one.Values())
+
+	var l List[string]
+	c := l.Push("c")
+	l.Push("a")
+	l.InsertAfter(l.Front(), "b") // a b c
+	l.InsertAfter(c, "d")         // a b c d
+	fmt.Println("List:", l.Values(), "len", l.Len())
+	l.Reverse()
+	fmt.Println("Reversed:", l.Values())
+	fmt.Println("Remove head d:", l.Remove(l.Front()), l.Values())
+	fmt.Println("Remove middle b:", l.Remove(l.Front().Next()), l.Values())
+	fmt.Println("Remove tail a:", l.Remove(l.Front().Next()), l.Values())
+	fmt.Println("Remove a node twice:", l.Remove(c), l.Remove(c), l.Values())
+	v, ok := l.Pop()
+	fmt.Printf("Pop on empty list: %q %v\n", v, ok)
+
+	// doubly linked list
+	var d DList[int]
+	for i := 1; i <= 5; i++ {
+		d.PushBack(i)
+	}
+	d.Remove(d.Front())        // head
+	d.Remove(d.Back())         // tail
+	d.Remove(d.Front().Next()) // middle
+	fmt.Println("Doubly linked after removing head, tail, middle:", d.Values())
+	d.MoveToFront(d.Back())
+	fmt.Println("After MoveToFront(back):", d.Values())
+
+	// LRU cache
+	cache, _ := NewLRUCache(3, func(key string, value int) {
+		fmt.Printf("  evicted %s=%d\n", key, value)
+	})
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+	cache.Get("a")    // a is now the most recently used, b the least
+	cache.Put("d", 4) // evicts b
+	cache.Put("c", 30)
+	cache.Put("e", 5) // evicts a
+	_, ok = cache.Get("b")
+	fmt.Println("b still cached:", ok)
+	fmt.Println("Keys, most recent first:", cache.Keys(), "len", cache.Len(), "cap", cache.Cap())
+
+	_, err := NewLRUCache[string, int](0, nil)
+	fmt.Println("Zero capacity:", err)
+}
+
+// A linked list is a chain of nodes connected by pointers: inserting or removing a known node
+// only changes a few pointers, no elements are shifted like in a slice.
+// But finding the n-th element means walking n nodes, and nodes are scattered in memory,
+// so for most jobs a slice is faster. The standard library has a doubly linked list in container/list.
+// The LRU cache is the classic case where a list wins: the map finds the node, the list keeps the order.
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	var l List[int]
+	if v, ok := l.Pop(); ok || v != 0 {
+		t.Errorf("Pop on empty = %d, %v", v, ok)
+	}
+	l.Reverse()
+	three := l.Push(3)
+	l.Push(1)
+	l.InsertAfter(l.Front(), 2)
+	four := l.InsertAfter(three, 4)
+	if got := l.Values(); !slices.Equal(got, []int{1, 2, 3, 4}) || l.Len() != 4 {
+		t.Fatalf("Values() = %v, Len %d", got, l.Len())
+	}
+	l.Reverse()
+	if got := l.Values(); !slices.Equal(got, []int{4, 3, 2, 1}) {
+		t.Fatalf("reversed: %v", got)
+	}
+
+	tests := []struct {
+		name   string
+		node   *Node[int]
+		wantOK bool
+		want   []int
+	}{
+		{"head", four, true, []int{3, 2, 1}},
+		{"again", four, false, []int{3, 2, 1}},
+		{"middle", l.Front().Next().Next(), true, []int{3, 1}}, // the node holding 2
+		{"other list", (&List[int]{}).Push(9), false, []int{3, 1}},
+		{"tail", l.Front().Next().Next().Next(), true, []int{3}}, // the node holding 1
+	}
+	for _, tt := range tests {
+		if ok := l.Remove(tt.node); ok != tt.wantOK || !slices.Equal(l.Values(), tt.want) || l.Len() != len(tt.want) {
+			t.Errorf("Remove %s: %v, list %v (len %d), want %v, %v", tt.name, ok, l.Values(), l.Len(), tt.wantOK, tt.want)
+		}
+	}
+	if four.Next() != nil {
+		t.Error("a removed node still points into the list")
+	}
+	if v, ok := l.Pop(); !ok || v != 3 || l.Len() != 0 || l.Front() != nil {
+		t.Errorf("Pop last = %d, %v; len %d", v, ok, l.Len())
+	}
+}
+
+// checkLinks walks the list both ways, every prev must mirror a next
+func checkLinks[T comparable](t *testing.T, l *DList[T], want []T) {
+	t.Helper()
+	if got := l.Values(); !slices.Equal(got, want) || l.Len() != len(want) {
+		t.Fatalf("Values() = %v (len %d), want %v", got, l.Len(), want)
+	}
+	var back []T
+	for e := l.Back(); e != nil; e = e.Prev() {
+		back = append(back, e.Value)
+	}
+	slices.Reverse(back)
+	if !slices.Equal(back, want) {
+		t.Fatalf("walking back gives %v, forward %v", back, want)
+	}
+	if len(want) == 0 && (l.Front() != nil || l.Back() != nil) {
+		t.Fatal("empty list still has a head or tail")
+	}
+}
+
+func TestDList(t *testing.T) {
+	var l DList[int]
+	checkLinks(t, &l, nil)
+	e2 := l.PushBack(2)
+	l.PushFront(1)
+	e3 := l.PushBack(3)
+	checkLinks(t, &l, []int{1, 2, 3})
+
+	l.MoveToFront(e3)
+	checkLinks(t, &l, []int{3, 1, 2})
+	l.MoveToFront(e3) // already there
+	checkLinks(t, &l, []int{3, 1, 2})
+
+	if v := l.Remove(e2); v != 2 {
+		t.Errorf("Remove returned %d", v)
+	}
+	checkLinks(t, &l, []int{3, 1})
+	l.Remove(e2) // twice: nothing happens
+	var other DList[int]
+	stranger := other.PushBack(9)
+	l.Remove(stranger)
+	l.MoveToFront(stranger)
+	checkLinks(t, &l, []int{3, 1})
+	checkLinks(t, &other, []int{9})
+
+	l.Remove(l.Front())
+	l.Remove(l.Back())
+	checkLinks(t, &l, nil)
+}
+
+func TestLRUCache(t *testing.T) {
+	if _, err := NewLRUCache[string, int](0, nil); !errors.Is(err, ErrInvalidCapacity) {
+		t.Errorf("capacity 0: %v", err)
+	}
+
+	var evicted []string
+	c, err := NewLRUCache(2, func(key string, value int) { evicted = append(evicted, key) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a")     // b is now the least recently used
+	c.Put("c", 3)  // evicts b
+	c.Put("a", 10) // an update, no eviction
+	if !slices.Equal(evicted, []string{"b"}) {
+		t.Errorf("evicted %v, want [b]", evicted)
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("b is still cached")
+	}
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Errorf("Get(a) = %d, %v", v, ok)
+	}
+	if got := c.Keys(); !slices.Equal(got, []string{"a", "c"}) || c.Len() != 2 || c.Cap() != 2 {
+		t.Errorf("Keys() = %v, Len %d, Cap %d", got, c.Len(), c.Cap())
+	}
+
+	// a nil callback is allowed
+	quiet, _ := NewLRUCache[int, int](1, nil)
+	quiet.Put(1, 1)
+	quiet.Put(2, 2)
+	if got := quiet.Keys(); !slices.Equal(got, []int{2}) {
+		t.Errorf("Keys() = %v", got)
+	}
+}
+
+func BenchmarkLRUCache(b *testing.B) {
+	c, _ := NewLRUCache[int, int](1024, nil)
+	i := 0
+	for b.Loop() {
+		c.Put(i%2048, i)
+		c.Get((i / 2) % 2048)
+		i++
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	URL         string
+	Method      string
+	Body        []byte // sent as JSON when not empty
+	Concurrency int
+	Duration    time.Duration
+	Timeout     time.Duration // per request
+}
+
+// Result is everything a run measured
+type Result struct {
+	Elapsed         time.Duration
+	Latencies       []time.Duration // successful AND failed HTTP responses, sorted
+	OK              int
+	HTTPErrors      map[int]int    // the server answered with status >= 400
+	TransportErrors map[string]int // no answer at all: refused, timeout, reset...
+}
+
+func (r *Result) Total() int {
+	n := r.OK
+	for _, c := range r.HTTPErrors {
+		n += c
+	}
+	for _, c := range r.TransportErrors {
+		n += c
+	}
+	return n
+}
+
+// Percentile uses the nearest-rank method on the sorted latencies: the smallest value
+// with at least p% of the values at or below it, the ceil(p/100*n)-th one
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(r.Latencies)))) - 1
+	return r.Latencies[min(max(i, 0), len(r.Latencies)-1)]
+}
+
+// workerStats is filled by one worker without locks, then merged at the end
+type workerStats struct {
+	latencies       []time.Duration
+	ok              int
+	httpErrors      map[int]int
+	transportErrors map[string]int
+}
+
+// classify names a transport error, so the report can tell a dead server from a slow one
+func classify(err error) string {
+	var ne net.Error
+	var oe *net.OpError
+	switch {
+	case errors.As(err, &ne) && ne.Timeout():
+		return "timeout"
+	case errors.As(err, &oe) && oe.Op == "dial":
+		return "connect failed"
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), strings.Contains(err.Error(), "reset by peer"):
+		return "connection closed"
+	}
+	return "other"
+}
+
+// the wait after a transport error doubles from minBackoff up to maxBackoff,
+// and starts over with the next answer from the server
+const (
+	minBackoff = 10 * time.Millisecond
+	maxBackoff = 500 * time.Millisecond
+)
+
+// Run sends requests from cfg.Concurrency goroutines until cfg.Duration is over or ctx is cancelled
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	if cfg.Concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be at least 1, got %d", cfg.Concurrency)
+	}
+	if cfg.Method == "" {
+		cfg.Method = http.MethodGet
+	}
+	if _, err := http.NewRequest(cfg.Method, cfg.URL, nil); err != nil {
+		return nil, fmt.Errorf("bad url: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	// one Transport shared by all workers, with enough idle connections to keep one per worker.
+	// Without this, every request would open a new TCP connection and we'd measure handshakes.
+	transport := &http.Transport{
+		MaxIdleConns:        cfg.Concurrency,
+		MaxIdleConnsPerHost: cfg.Concurrency, // the default is 2
+		IdleConnTimeout:     30 * time.Second,
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, Timeout: cfg.Timeout}
+
+	stats := make([]*workerStats, cfg.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := range stats {
+		s := &workerStats{httpErrors: map[int]int{}, transportErrors: map[string]int{}}
+		stats[w] = s
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var backoff time.Duration
+			for ctx.Err() == nil {
+				var body io.Reader
+				if len(cfg.Body) > 0 {
+					body = bytes.NewReader(cfg.Body)
+				}
+				req, _ := http.NewRequestWithContext(ctx, cfg.Method, cfg.URL, body)
+				if body != nil {
+					req.Header.Set("Content-Type", "application/json")
+				}
+				t0 := time.Now()
+				resp, err := client.Do(req)
+				if err == nil {
+					_, err = io.Copy(io.Discard, resp.Body) // read everything, or the connection can't be reused
+					resp.Body.Close()
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return // cut off by the end of the run, not the server's fault
+					}
+					s.transportErrors[classify(err)]++
+					// a dead server fails in microseconds; don't spin on it
+					backoff = min(max(2*backoff, minBackoff), maxBackoff)
+					select {
+					case <-ctx.Done():
+					case <-time.After(backoff):
+					}
+					continue
+				}
+				backoff = 0
+				s.latencies = append(s.latencies, time.Since(t0))
+				if resp.StatusCode >= 400 {
+					s.httpErrors[resp.StatusCode]++
+				} else {
+					s.ok++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	res := &Result{Elapsed: time.Since(start), HTTPErrors: map[int]int{}, TransportErrors: map[string]int{}}
+	for _, s := range stats {
+		res.Latencies = append(res.Latencies, s.latencies...)
+		res.OK += s.ok
+		for k, v := range s.httpErrors {
+			res.HTTPErrors[k] += v
+		}
+		for k, v := range s.transportErrors {
+			res.TransportErrors[k] += v
+		}
+	}
+	sort.Slice(res.Latencies, func(i, j int) bool { return res.Latencies[i] < res.Latencies[j] })
+	return res, nil
+}
+
+// PrintReport writes a summary and a histogram with buckets that double in size
+func PrintReport(w io.Writer, r *Result) {
+	total := r.Total()
+	fmt.Fprintf(w, "Requests:   %d in %s (%.1f req/s)\n", total, r.Elapsed.Round(time.Millisecond), float64(total)/r.Elapsed.Seconds())
+	if total == 0 {
+		return
+	}
+	httpErrs, transportErrs := total-r.OK, 0
+	for _, c := range r.TransportErrors {
+		transportErrs += c
+	}
+	httpErrs -= transportErrs
+	fmt.Fprintf(w, "OK:         %d\n", r.OK)
+	fmt.Fprintf(w, "HTTP errors: %d %v\n", httpErrs, r.HTTPErrors)
+	fmt.Fprintf(w, "Transport errors: %d %v\n", transportErrs, r.TransportErrors)
+	fmt.Fprintf(w, "Error rate: %.1f%%\n", 100*float64(total-r.OK)/float64(total))
+	if len(r.Latencies) == 0 {
+		return
+	}
+	var sum time.Duration
+	for _, l := range r.Latencies {
+		sum += l
+	}
+	fmt.Fprintf(w, "Latency:    min %s  mean %s  max %s\n", r.Latencies[0].Round(time.Microsecond),
+		(sum / time.Duration(len(r.Latencies))).Round(time.Microsecond), r.Latencies[len(r.Latencies)-1].Round(time.Microsecond))
+	fmt.Fprintf(w, "            p50 %s  p90 %s  p99 %s\n", r.Percentile(50).Round(time.Microsecond),
+		r.Percentile(90).Round(time.Microsecond), r.Percentile(99).Round(time.Microsecond))
+
+	// buckets: < 1ms, < 2ms, < 4ms, ...
+	limit := time.Millisecond
+	i := 0
+	for i < len(r.Latencies) {
+		n := 0
+		for i < len(r.Latencies) && r.Latencies[i] < limit {
+			n++
+			i++
+		}
+		if n > 0 {
+			bar := strings.Repeat("#", max(1, n*40/len(r.Latencies)))
+			fmt.Fprintf(w, "  < %-7s %6d %s\n", limit, n, bar)
+		}
+		limit *= 2
+	}
+}
+
+// demoServer answers in 1-20ms, fails 5% with 500 and drops 2% of the connections
+func demoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(1+rand.IntN(20)) * time.Millisecond)
+		switch n := rand.IntN(100); {
+		case n < 5:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case n < 7:
+			// close the connection without an answer, the client sees a transport error
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+		default:
+			fmt.Fprintln(w, `{"ok":true}`)
+		}
+	}))
+}
+
+func main() {
+	fmt.Println("Learning to load test an HTTP server in Go")
+	url := flag.String("url", "", "URL to test (empty: start a demo server)")
+	concurrency := flag.Int("c", 10, "concurrent workers")
+	duration := flag.Duration("d", time.Second, "how long to run")
+	method := flag.String("method", http.MethodGet, "HTTP method")
+	body := flag.String("body", "", "JSON request body")
+	flag.Parse()
+
+	target := *url
+	if target == "" {
+		srv := demoServer()
+		defer srv.Close()
+		target = srv.URL
+		fmt.Println("Demo server at", target, "(1-20ms, 5% HTTP 500, 2% dropped connections)")
+	}
+
+	// Ctrl+C stops early and still prints the report
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res, err := Run(ctx, Config{
+		URL: target, Method: *method, Body: []byte(*body),
+		Concurrency: *concurrency, Duration: *duration, Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	PrintReport(os.Stdout, res)
+	if *url != "" {
+		return
+	}
+
+	// the dropped connections didn't show up above: http.Transport quietly retries an
+	// idempotent request (GET) when a reused connection closes. A POST is never retried.
+	fmt.Println("\nSame server with POST:")
+	res, _ = Run(ctx, Config{URL: target, Method: http.MethodPost, Body: []byte(`{"name":"load"}`), Concurrency: *concurrency, Duration: *duration / 2})
+	PrintReport(os.Stdout, res)
+
+	// nothing listening: every request is a transport error, and there are no latencies
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	deadAddr := ln.Addr().String()
+	ln.Close()
+	res, _ = Run(context.Background(), Config{URL: "http://" + deadAddr, Concurrency: 2, Duration: 50 * time.Millisecond})
+	fmt.Println("\nAgainst a closed port:")
+	PrintReport(os.Stdout, res)
+
+	_, err = Run(context.Background(), Config{URL: target, Concurrency: 0, Duration: time.Second})
+	fmt.Println("\nConcurrency 0:", err)
+}
+
+// Share one http.Transport and raise MaxIdleConnsPerHost, or you measure TCP handshakes.
+// Always read and close the response body, that's what lets a connection be reused.
+// An HTTP 500 is an answer (the server is up), a refused or dropped connection is not: report them apart.
+// Averages hide the slow requests, look at p90/p99 and the histogram.
+// Per-worker stats merged at the end need no locks while the test is running.
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func ms(values ...int) []time.Duration {
+	out := make([]time.Duration, len(values))
+	for i, v := range values {
+		out[i] = time.Duration(v) * time.Millisecond
+	}
+	return out
+}
+
+func TestPercentile(t *testing.T) {
+	ten := &Result{Latencies: ms(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)}
+	tests := []struct {
+		r    *Result
+		p    float64
+		want time.Duration
+	}{
+		{ten, 50, 5 * time.Millisecond}, // ceil(5)=5th
+		{ten, 90, 9 * time.Millisecond},
+		{ten, 91, 10 * time.Millisecond}, // ceil(9.1)=10th
+		{ten, 99, 10 * time.Millisecond},
+		{ten, 100, 10 * time.Millisecond},
+		{ten, 10, 1 * time.Millisecond},
+		{ten, 11, 2 * time.Millisecond},
+		{ten, 0, 1 * time.Millisecond}, // clamped to the first
+		{&Result{Latencies: ms(1, 2, 3, 4)}, 50, 2 * time.Millisecond},
+		{&Result{Latencies: ms(1, 2, 3, 4)}, 51, 3 * time.Millisecond},
+		{&Result{Latencies: ms(1, 2, 3, 4)}, 75, 3 * time.Millisecond},
+		{&Result{Latencies: ms(7)}, 99, 7 * time.Millisecond},
+		{&Result{}, 50, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Percentile(tt.p); got != tt.want {
+			t.Errorf("p%v of %d values = %v, want %v", tt.p, len(tt.r.Latencies), got, tt.want)
+		}
+	}
+	// with 1000 values p99 must be the 990th, not the 991st or the max
+	var thousand Result
+	for i := 1; i <= 1000; i++ {
+		thousand.Latencies = append(thousand.Latencies, time.Duration(i))
+	}
+	if got := thousand.Percentile(99); got != 990 {
+		t.Errorf("p99 of 1..1000 = %d, want 990", got)
+	}
+}
+
+// slowServer waits delay per request and answers 500 to every failEvery-th request.
+// It isn't started yet, so the caller can still configure it.
+func slowServer(t *testing.T, delay time.Duration, failEvery int64, served *atomic.Int64) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := served.Add(1)
+		time.Sleep(delay)
+		if failEvery > 0 && n%failEvery == 0 {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintln(w, `{"ok":true}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRunCountsAndLatency(t *testing.T) {
+	var served atomic.Int64
+	srv := slowServer(t, 5*time.Millisecond, 4, &served)
+	var conns atomic.Int64
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	res, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 4, Duration: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := res.Total()
+	// requests cut off at the end reached the server but aren't counted
+	if total == 0 || int64(total) > served.Load() || served.Load()-int64(total) > 4 {
+		t.Errorf("counted %d, the server saw %d", total, served.Load())
+	}
+	// 4 workers at >= 5ms per request for 300ms: at most 240
+	if total > 240 {
+		t.Errorf("%d requests is more than the latency allows", total)
+	}
+	if len(res.TransportErrors) != 0 || len(res.HTTPErrors) != 1 || res.HTTPErrors[500] == 0 {
+		t.Errorf("errors: http %v, transport %v", res.HTTPErrors, res.TransportErrors)
+	}
+	if fails := res.HTTPErrors[500]; fails < total/4-4 || fails > total/4+4 {
+		t.Errorf("%d of %d failed, want about a quarter", fails, total)
+	}
+	if len(res.Latencies) != total || res.Latencies[0] < 5*time.Millisecond || res.Percentile(50) < 5*time.Millisecond {
+		t.Errorf("%d latencies, min %v, p50 %v", len(res.Latencies), res.Latencies[0], res.Percentile(50))
+	}
+	if c := conns.Load(); c > 4 {
+		t.Errorf("%d connections for 4 workers, they should be reused", c)
+	}
+}
+
+func TestRunTransportErrors(t *testing.T) {
+	// nothing listening
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	dead := "http://" + ln.Addr().String()
+	ln.Close()
+	res, err := Run(context.Background(), Config{URL: dead, Concurrency: 2, Duration: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TransportErrors["connect failed"] == 0 || res.OK != 0 || len(res.HTTPErrors) != 0 || len(res.Latencies) != 0 {
+		t.Errorf("closed port: %+v", res)
+	}
+	// backing off 10, 20, 40, 80...ms: a handful of attempts per worker, not thousands
+	res, _ = Run(context.Background(), Config{URL: dead, Concurrency: 2, Duration: 200 * time.Millisecond})
+	if n := res.TransportErrors["connect failed"]; n == 0 || n > 12 {
+		t.Errorf("%d connect attempts in 200ms", n)
+	}
+
+	// slower than the per-request timeout
+	var served atomic.Int64
+	slow := slowServer(t, 200*time.Millisecond, 0, &served)
+	slow.Start()
+	res, _ = Run(context.Background(), Config{URL: slow.URL, Concurrency: 2, Duration: 150 * time.Millisecond, Timeout: 20 * time.Millisecond})
+	if res.TransportErrors["timeout"] == 0 || res.OK != 0 {
+		t.Errorf("timeouts: %+v", res)
+	}
+
+	// connections dropped without an answer, on a POST which is never retried
+	drop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			conn.Close()
+		}
+	}))
+	defer drop.Close()
+	res, _ = Run(context.Background(), Config{URL: drop.URL, Method: http.MethodPost, Body: []byte(`{}`), Concurrency: 2, Duration: 50 * time.Millisecond})
+	if res.TransportErrors["connection closed"] == 0 || res.OK != 0 {
+		t.Errorf("dropped: %+v", res)
+	}
+}
+
+// the status line came but the body didn't: that's a failure, not an OK
+func TestRunBodyReadErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte(`{"ok":`))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler) // drops the connection without logging
+	}))
+	defer srv.Close()
+	res, err := Run(context.Background(), Config{URL: srv.URL, Concurrency: 2, Duration: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TransportErrors["connection closed"] == 0 || res.OK != 0 || len(res.Latencies) != 0 {
+		t.Errorf("cut-off bodies: %+v", res)
+	}
+}
+
+func TestRunSendsBody(t *testing.T) {
+	var bad atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPut || string(body) != `{"name":"load"}` || r.Header.Get("Content-Type") != "application/json" {
+			bad.Add(1)
+		}
+	}))
+	defer srv.Close()
+	res, err := Run(context.Background(), Config{URL: srv.URL, Method: http.MethodPut, Body: []byte(`{"name":"load"}`), Concurrency: 3, Duration: 50 * time.Millisecond})
+	if err != nil || res.OK == 0 || bad.Load() != 0 {
+		t.Errorf("%v, ok %d, bad %d", err, res.OK, bad.Load())
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	var served atomic.Int64
+	srv := slowServer(t, time.Millisecond, 0, &served)
+	srv.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res, err := Run(ctx, Config{URL: srv.URL, Concurrency: 4, Duration: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Run took %v after ctx was cancelled", d)
+	}
+	if res.OK == 0 || len(res.TransportErrors) != 0 {
+		t.Errorf("the cancel must not count as errors: %+v", res.TransportErrors)
+	}
+}
+
+func TestRunConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{URL: "http://x", Concurrency: 0, Duration: time.Second},
+		{URL: "http://x", Concurrency: -1, Duration: time.Second},
+		{URL: "://no-scheme", Concurrency: 1, Duration: time.Second},
+		{URL: "http://x", Method: "BAD METHOD", Concurrency: 1, Duration: time.Second},
+	} {
+		if _, err := Run(context.Background(), cfg); err == nil {
+			t.Errorf("%+v: no error", cfg)
+		}
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	res := &Result{
+		Elapsed:         time.Second,
+		Latencies:       ms(0, 0, 1, 1, 1, 3, 3, 3, 3, 20),
+		OK:              7,
+		HTTPErrors:      map[int]int{500: 3},
+		TransportErrors: map[string]int{"timeout": 2},
+	}
+	var buf bytes.Buffer
+	PrintReport(&buf, res)
+	want := `Requests:   12 in 1s (12.0 req/s)
+OK:         7
+HTTP errors: 3 map[500:3]
+Transport errors: 2 map[timeout:2]
+Error rate: 41.7%
+Latency:    min 0s  mean 3.5ms  max 20ms
+            p50 1ms  p90 3ms  p99 20ms
+  < 1ms          2 ########
+  < 2ms          3 ############
+  < 4ms          4 ################
+  < 32ms         1 ####
+`
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	buf.Reset()
+	PrintReport(&buf, &Result{Elapsed: time.Second})
+	if !strings.HasPrefix(buf.String(), "Requests:   0 in 1s") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("empty result: %q", buf.String())
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fmt.Println is fine while learning, but a program that runs for days needs logs that
+// can be filtered by level and searched by field. log/slog (Go 1.21) does that:
+// every line is a message plus key=value attributes, written as text or as JSON.
+
+// ----------------------------------------------------------------------------
+// First, how you'd build a logger yourself
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelError
+)
+
+var levelNames = [...]string{"DEBUG", "INFO", "ERROR"}
+
+// SimpleLogger writes "LEVEL prefix message" lines and drops the ones below its minimum level
+type SimpleLogger struct {
+	mu     *sync.Mutex // one Write per line, shared with the children since they write to the same w
+	w      io.Writer
+	min    Level
+	prefix string
+}
+
+func NewSimpleLogger(w io.Writer, minLevel Level) *SimpleLogger {
+	return &SimpleLogger{mu: &sync.Mutex{}, w: w, min: minLevel}
+}
+
+// With returns a child logger that adds prefix to every line and shares the writer
+func (l *SimpleLogger) With(prefix string) *SimpleLogger {
+	return &SimpleLogger{mu: l.mu, w: l.w, min: l.min, prefix: l.prefix + prefix + " "}
+}
+
+func (l *SimpleLogger) log(level Level, format string, args ...any) {
+	if level < l.min {
+		return
+	}
+	line := fmt.Sprintf("%-5s %s%s\n", levelNames[level], l.prefix, fmt.Sprintf(format, args...))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, line)
+}
+
+func (l *SimpleLogger) Debugf(format string, args ...any) { l.log(LevelDebug, format, args...) }
+func (l *SimpleLogger) Infof(format string, args ...any)  { l.log(LevelInfo, format, args...) }
+func (l *SimpleLogger) Errorf(format string, args ...any) { l.log(LevelError, format, args...) }
+
+// It works, but the fields are buried in the message text. A log search can't ask for
+// "every line where user_id=42". That's what slog adds, so that's the one to use.
+
+// ----------------------------------------------------------------------------
+// The slog setup the rest of the program would share
+
+// New returns a logger writing lines of at least level to w.
+// format is "json" or "text", anything else is treated as "text".
+// Request IDs stored with WithRequestID are added to every line logged with a context.
+func New(level slog.Level, format string, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	if format == "json" {
+		h = slog.NewJSONHandler(w, opts)
+	} else {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{h})
+}
+
+// ParseFlags reads the -log-level and -log-format values
+func ParseFlags(level, format string) (slog.Level, string, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil { // accepts "debug", "INFO", "warn+1"...
+		return 0, "", fmt.Errorf("log level %q: %w", level, err)
+	}
+	if format != "text" && format != "json" {
+		return 0, "", fmt.Errorf("log format %q: must be text or json", format)
+	}
+	return l, format, nil
+}
+
+type ctxKey struct{}
+
+// WithRequestID stores id in ctx, every log call given ctx will carry it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Module returns a child logger for one part of the program
+func Module(l *slog.Logger, name string) *slog.Logger {
+	return l.With("module", name)
+}
+
+// contextHandler wraps another handler and copies the request ID from the context
+// into the record. Code deep down only needs the ctx, not a logger passed around.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// With and WithGroup must be wrapped too, or a child logger would lose the request IDs
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// the global logger, for code that can't get one passed in
+var std atomic.Pointer[slog.Logger]
+
+func init() {
+	std.Store(New(slog.LevelInfo, "text", os.Stderr))
+}
+
+func L() *slog.Logger { return std.Load() }
+
+// SetDefault replaces the global logger, and slog's own default (used by slog.Info and the log package)
+func SetDefault(l *slog.Logger) {
+	std.Store(l)
+	slog.SetDefault(l)
+}
+
+// ----------------------------------------------------------------------------
+// Users of the logger
+
+var nextRequestID atomic.Int64
+
+// requestLogger gives each request an ID and logs it when the handler is done
+func requestLogger(next http.Handler) http.Handler {
+	log := Module(L(), "http")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = fmt.Sprintf("req-%d", nextRequestID.Add(1))
+		}
+		ctx := WithRequestID(r.Context(), id)
+		start := time.Now()
+		next.ServeHTTP(w, r.WithContext(ctx))
+		log.InfoContext(ctx, "request", "method", r.Method, "path", r.URL.Path, "took", time.Since(start).Round(time.Microsecond))
+	})
+}
+
+func getUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	// the store logs with the request's ctx, so its line gets the same request_id
+	Module(L(), "store").DebugContext(r.Context(), "lookup", "user_id", id)
+	if id != "42" {
+		Module(L(), "store").WarnContext(r.Context(), "user not found", "user_id", id)
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	fmt.Fprintln(w, `{"id":42}`)
+}
+
+func main() {
+	fmt.Println("Learning structured logging with log/slog in Go")
+	levelFlag := flag.String("log-level", "debug", "lowest level to log: debug, info, warn, error")
+	formatFlag := flag.String("log-format", "text", "text or json")
+	flag.Parse()
+
+	fmt.Println("\nA hand-made logger, level INFO:")
+	simple := NewSimpleLogger(os.Stdout, LevelInfo)
+	simple.Debugf("not shown, below INFO")
+	simple.Infof("server starting on port %d", 8080)
+	simple.With("[db]").Errorf("connection refused")
+
+	level, format, err := ParseFlags(*levelFlag, *formatFlag)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	SetDefault(New(level, format, os.Stdout))
+
+	fmt.Printf("\nslog, level %s, format %s:\n", level, format)
+	L().Info("server starting", "port", 8080)
+	Module(L(), "scheduler").Debug("next run", "job", "cleanup", "at", "09:00")
+	slog.Warn("the slog package functions use the same logger after SetDefault")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", getUser)
+	h := requestLogger(mux)
+	for _, path := range []string{"/users/42", "/users/7"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// what a test would check: capture the output in a buffer
+	fmt.Println("\nLevel filtering (WARN):")
+	var buf bytes.Buffer
+	l := New(slog.LevelWarn, "text", &buf)
+	l.Debug("dropped")
+	l.Info("dropped")
+	l.Warn("kept")
+	l.Error("kept")
+	fmt.Printf("  %d lines written, 4 logged\n", strings.Count(buf.String(), "\n"))
+
+	fmt.Println("JSON fields:")
+	buf.Reset()
+	l = Module(New(slog.LevelInfo, "json", &buf), "billing")
+	l.InfoContext(WithRequestID(context.Background(), "abc"), "charged", "amount", 9.99)
+	var fields map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, k := range []string{"time", "level", "msg", "module", "request_id", "amount"} {
+		fmt.Printf("  %-10s = %v\n", k, fields[k])
+	}
+
+	_, _, err = ParseFlags("loud", "text")
+	fmt.Println("\nBad level:", err)
+	_, _, err = ParseFlags("info", "xml")
+	fmt.Println("Bad format:", err)
+}
+
+// Log key/value attributes, not values formatted into the message: they can be searched.
+// Use JSON in production (a log system parses it), text for reading in a terminal.
+// Child loggers (l.With) add fields once instead of repeating them on every call.
+// Put per-request values in the context and let a wrapping Handler add them to every line.
+// Make the level a flag: debug while chasing a bug, info or warn the rest of the time.
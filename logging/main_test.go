@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lines decodes one JSON object per line
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("not JSON: %q: %v", line, err)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func TestLevelFiltering(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  []string
+	}{
+		{slog.LevelDebug, []string{"DEBUG", "INFO", "WARN", "ERROR"}},
+		{slog.LevelInfo, []string{"INFO", "WARN", "ERROR"}},
+		{slog.LevelWarn, []string{"WARN", "ERROR"}},
+		{slog.LevelError + 1, nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		l := New(tt.level, "json", &buf)
+		l.Debug("d")
+		l.Info("i")
+		l.Warn("w")
+		l.Error("e")
+		var got []string
+		for _, m := range lines(t, &buf) {
+			got = append(got, m["level"].(string))
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("level %s: logged %v, want %v", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestJSONFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := Module(New(slog.LevelInfo, "json", &buf), "billing")
+	ctx := WithRequestID(context.Background(), "abc")
+	l.InfoContext(ctx, "charged", "amount", 9.99, "user_id", 42)
+	l.Info("no context")
+
+	got := lines(t, &buf)
+	if len(got) != 2 {
+		t.Fatalf("%d lines", len(got))
+	}
+	want := map[string]any{"level": "INFO", "msg": "charged", "module": "billing", "request_id": "abc", "amount": 9.99, "user_id": 42.0}
+	for k, v := range want {
+		if got[0][k] != v {
+			t.Errorf("%s = %v, want %v", k, got[0][k], v)
+		}
+	}
+	if _, ok := got[0]["time"]; !ok {
+		t.Error("no time field")
+	}
+	if _, ok := got[1]["request_id"]; ok || got[1]["module"] != "billing" {
+		t.Errorf("line without a context: %v", got[1])
+	}
+}
+
+func TestChildLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(slog.LevelDebug, "json", &buf)
+	store := Module(root, "store")
+	ctx := WithRequestID(context.Background(), "req-7")
+	store.With("table", "users").DebugContext(ctx, "lookup")
+	store.WithGroup("db").InfoContext(ctx, "slow query", "ms", 250)
+	root.Info("root")
+
+	got := lines(t, &buf)
+	if got[0]["module"] != "store" || got[0]["table"] != "users" || got[0]["request_id"] != "req-7" {
+		t.Errorf("With: %v", got[0])
+	}
+	// the group holds the attributes added after it, the module stays outside
+	if got[1]["module"] != "store" || got[1]["db"] == nil {
+		t.Errorf("WithGroup: %v", got[1])
+	} else if db := got[1]["db"].(map[string]any); db["ms"] != 250.0 || db["request_id"] != "req-7" {
+		t.Errorf("WithGroup db: %v", db)
+	}
+	if _, ok := got[2]["module"]; ok {
+		t.Errorf("the parent got the child's module: %v", got[2])
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	var buf bytes.Buffer
+	for _, format := range []string{"text", "xml"} { // anything but json is text
+		buf.Reset()
+		Module(New(slog.LevelInfo, format, &buf), "cron").Info("tick", "job", "cleanup")
+		if got := buf.String(); !strings.Contains(got, `level=INFO msg=tick module=cron job=cleanup`) {
+			t.Errorf("%s: %q", format, got)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		level, format string
+		want          slog.Level
+		wantErr       bool
+	}{
+		{"debug", "text", slog.LevelDebug, false},
+		{"INFO", "json", slog.LevelInfo, false},
+		{"warn+1", "text", slog.LevelWarn + 1, false},
+		{"error", "json", slog.LevelError, false},
+		{"loud", "text", 0, true},
+		{"info", "xml", 0, true},
+		{"", "text", 0, true},
+	}
+	for _, tt := range tests {
+		level, format, err := ParseFlags(tt.level, tt.format)
+		if (err != nil) != tt.wantErr || (err == nil && (level != tt.want || format != tt.format)) {
+			t.Errorf("ParseFlags(%q, %q) = %v, %q, %v", tt.level, tt.format, level, format, err)
+		}
+	}
+}
+
+func TestSimpleLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSimpleLogger(&buf, LevelInfo)
+	l.Debugf("hidden %d", 1)
+	l.Infof("port %d", 8080)
+	l.With("[db]").With("[users]").Errorf("refused")
+	want := "INFO  port 8080\nERROR [db] [users] refused\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestSimpleLoggerConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSimpleLogger(&buf, LevelDebug)
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			for range 50 {
+				l.With("[worker]").Infof("a fairly long line so that mixed writes would show")
+			}
+		})
+	}
+	wg.Wait()
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != 1000 {
+		t.Fatalf("%d lines", len(got))
+	}
+	for _, line := range got {
+		if line != "INFO  [worker] a fairly long line so that mixed writes would show" {
+			t.Fatalf("mixed line %q", line)
+		}
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	old := L()
+	SetDefault(New(slog.LevelDebug, "json", &buf))
+	t.Cleanup(func() { SetDefault(old) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", getUser)
+	h := requestLogger(mux)
+	r := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	r.Header.Set("X-Request-ID", "from-client")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+
+	got := lines(t, &buf)
+	if len(got) != 5 {
+		t.Fatalf("%d lines: %v", len(got), got)
+	}
+	// the store's lines and the request line of the first request share the client's ID
+	for i, want := range []struct{ module, msg, level string }{
+		{"store", "lookup", "DEBUG"}, {"store", "user not found", "WARN"}, {"http", "request", "INFO"},
+	} {
+		if got[i]["module"] != want.module || got[i]["msg"] != want.msg || got[i]["level"] != want.level || got[i]["request_id"] != "from-client" {
+			t.Errorf("line %d: %v", i, got[i])
+		}
+	}
+	id, _ := got[4]["request_id"].(string)
+	if !strings.HasPrefix(id, "req-") || got[3]["request_id"] != id || got[4]["path"] != "/users/42" {
+		t.Errorf("second request: %v %v", got[3], got[4])
+	}
+}
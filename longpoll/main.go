@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Polling every second is wasteful when nothing changes and slow when something does.
+// Long polling: the client asks "anything newer than version 5?" and the server holds the
+// request open until there is, or until a timeout. The answer comes the moment a write happens.
+//
+// sync.Cond can't wait on a context, so waiters here get a channel that is closed on the next
+// write: closing wakes every receiver at once, and it can sit in a select next to ctx.Done().
+
+type User struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+type UserStore struct {
+	mu      sync.Mutex
+	users   map[int]User
+	version int         // +1 on every write, never goes back
+	changed map[int]int // user ID -> version of its last change
+	notify  chan struct{}
+	nextID  int
+	waiting atomic.Int32 // handlers blocked right now, to see that none leak
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[int]User{}, changed: map[int]int{}, notify: make(chan struct{}), nextID: 1}
+}
+
+// bump records a change to u. The caller holds the lock.
+func (s *UserStore) bump(u User) {
+	s.version++
+	s.changed[u.ID] = s.version
+	close(s.notify) // wakes everyone waiting on the old channel
+	s.notify = make(chan struct{})
+}
+
+func (s *UserStore) Create(name string) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := User{ID: s.nextID, Name: name}
+	s.nextID++
+	s.users[u.ID] = u
+	s.bump(u)
+	return u
+}
+
+func (s *UserStore) Delete(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; ok {
+		delete(s.users, id)
+		s.bump(User{ID: id, Deleted: true})
+	}
+}
+
+// Since returns the users changed after version since, the current version, and a channel
+// that is closed on the next write. All three come from one lock, so a write can't slip in
+// between "nothing new" and "start waiting".
+func (s *UserStore) Since(since int) ([]User, int, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if since > s.version {
+		since = 0 // the client has a version from before a restart: send everything
+	}
+	var out []User
+	for id, v := range s.changed {
+		if v > since {
+			u, ok := s.users[id]
+			if !ok {
+				u = User{ID: id, Deleted: true}
+			}
+			out = append(out, u)
+		}
+	}
+	slices.SortFunc(out, func(a, b User) int { return a.ID - b.ID })
+	return out, s.version, s.notify
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+type UpdatesHandler struct {
+	Store   *UserStore
+	Timeout time.Duration
+	After   func(time.Duration) <-chan time.Time // nil is time.After, the demo uses a fake clock
+}
+
+// ServeHTTP: GET /api/users/updates?since=<version>
+func (h *UpdatesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.Atoi(r.URL.Query().Get("since"))
+	if err != nil || since < 0 {
+		http.Error(w, "since must be a version number, 0 for everything", http.StatusBadRequest)
+		return
+	}
+	after := h.After
+	if after == nil {
+		after = time.After
+	}
+	timeout := after(h.Timeout) // one deadline for the whole request, not per wake-up
+	h.Store.waiting.Add(1)
+	defer h.Store.waiting.Add(-1)
+	for {
+		users, version, changed := h.Store.Since(since)
+		if len(users) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"version": version, "users": users})
+			return
+		}
+		select {
+		case <-changed:
+			// loop: look again, the write may have been one we already had
+		case <-timeout:
+			w.WriteHeader(http.StatusNoContent) // nothing new, ask again with the same version
+			return
+		case <-r.Context().Done():
+			return // the client went away, stop waiting and free the goroutine
+		}
+	}
+}
+
+// fakeClock hands out timers that fire only when Fire is called
+type fakeClock struct {
+	mu     sync.Mutex
+	timers []chan time.Time
+}
+
+func (c *fakeClock) After(time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, ch)
+	return ch
+}
+
+func (c *fakeClock) Fire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.timers {
+		ch <- time.Time{}
+	}
+	c.timers = nil
+}
+
+func waitUntil(cond func() bool) {
+	for !cond() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func main() {
+	fmt.Println("Learning long polling in Go")
+
+	store := NewUserStore()
+	clock := &fakeClock{}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/users/updates", &UpdatesHandler{Store: store, Timeout: 30 * time.Second, After: clock.After})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	poll := func(ctx context.Context, since int) (int, string, error) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/users/updates?since=%d", srv.URL, since), nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), nil
+	}
+
+	store.Create("Rishabh")
+	store.Create("Sanchay")
+
+	fmt.Println("1. Something newer exists: answered at once")
+	start := time.Now()
+	code, body, _ := poll(context.Background(), 0)
+	fmt.Printf("  since=0 -> %d in %v %s", code, time.Since(start).Round(time.Millisecond), body)
+
+	fmt.Println("2. Nothing newer: the request waits until a write")
+	start = time.Now()
+	go func() {
+		waitUntil(func() bool { return store.waiting.Load() == 1 })
+		time.Sleep(50 * time.Millisecond)
+		store.Create("Alice")
+	}()
+	code, body, _ = poll(context.Background(), 2)
+	fmt.Printf("  since=2 -> %d after %v %s", code, time.Since(start).Round(10*time.Millisecond), body)
+
+	fmt.Println("3. Many pollers, one write wakes them all")
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, body, _ := poll(context.Background(), 3)
+			results[i] = fmt.Sprint(code, " ", len(body) > 0)
+		}()
+	}
+	waitUntil(func() bool { return store.waiting.Load() == 5 })
+	store.Delete(1)
+	wg.Wait()
+	fmt.Println("  results:", results)
+	_, body, _ = poll(context.Background(), 3)
+	fmt.Print("  a deleted user is reported too: ", body)
+
+	fmt.Println("4. Timeout (30s on a fake clock): 204")
+	done := make(chan string)
+	go func() {
+		code, body, _ := poll(context.Background(), 4)
+		done <- fmt.Sprintf("%d %q", code, body)
+	}()
+	waitUntil(func() bool { return store.waiting.Load() == 1 })
+	clock.Fire()
+	fmt.Println("  since=4 ->", <-done)
+
+	fmt.Println("5. The client disconnects while waiting")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _, err := poll(ctx, 4)
+		done <- fmt.Sprint("canceled: ", errors.Is(err, context.Canceled))
+	}()
+	waitUntil(func() bool { return store.waiting.Load() == 1 })
+	cancel()
+	fmt.Println("  client got:", <-done)
+	waitUntil(func() bool { return store.waiting.Load() == 0 })
+	fmt.Println("  handlers still waiting on the server:", store.waiting.Load())
+
+	code, body, _ = poll(context.Background(), 99)
+	fmt.Printf("6. A version the server never had (restart) gets everything: %d %s", code, body)
+	code, body, _ = poll(context.Background(), -1)
+	fmt.Printf("7. since=-1 -> %d %s", code, body)
+}
+
+// Return at once when there is news, otherwise hold the request until a write or the timeout.
+// Read the changes AND the wake-up channel under one lock, or a write between the two is missed.
+// A closed channel wakes every waiter at once and works in a select, sync.Cond does neither with a context.
+// Watch r.Context().Done(): a client that hangs up must not leave a goroutine waiting 30 seconds.
+// 204 on timeout, the client simply asks again with the same version.
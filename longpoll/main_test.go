@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type updates struct {
+	Version int    `json:"version"`
+	Users   []User `json:"users"`
+}
+
+type testServer struct {
+	store *UserStore
+	clock *fakeClock
+	url   string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{store: NewUserStore(), clock: &fakeClock{}}
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/users/updates", &UpdatesHandler{Store: ts.store, Timeout: 30 * time.Second, After: ts.clock.After})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	ts.url = srv.URL
+	return ts
+}
+
+func (ts *testServer) poll(ctx context.Context, since int) (int, updates, error) {
+	req, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/users/updates?since=%d", ts.url, since), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, updates{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	var u updates
+	if len(body) > 0 {
+		json.Unmarshal(body, &u)
+	}
+	return resp.StatusCode, u, nil
+}
+
+// eventually polls cond, waitUntil from main without the chance of hanging the test run
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func names(users []User) []string {
+	out := []string{}
+	for _, u := range users {
+		out = append(out, u.Name)
+	}
+	return out
+}
+
+func TestImmediate(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.Create("Rishabh")
+	ts.store.Create("Sanchay")
+	tests := []struct {
+		since int
+		want  []string
+	}{
+		{0, []string{"Rishabh", "Sanchay"}},
+		{1, []string{"Sanchay"}},
+		{99, []string{"Rishabh", "Sanchay"}}, // a version from before a restart: everything
+	}
+	for _, tt := range tests {
+		code, u, err := ts.poll(context.Background(), tt.since)
+		if err != nil || code != 200 || u.Version != 2 || !slices.Equal(names(u.Users), tt.want) {
+			t.Errorf("since=%d: %d %+v %v", tt.since, code, u, err)
+		}
+	}
+	if ts.store.waiting.Load() != 0 {
+		t.Error("a handler is still waiting")
+	}
+}
+
+func TestWakeOnWrite(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.Create("Rishabh")
+	type result struct {
+		code int
+		u    updates
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, u, err := ts.poll(context.Background(), 1)
+		done <- result{code, u, err}
+	}()
+	eventually(t, "the poller to wait", func() bool { return ts.store.waiting.Load() == 1 })
+	select {
+	case r := <-done:
+		t.Fatalf("answered before any write: %+v", r)
+	case <-time.After(20 * time.Millisecond):
+	}
+	ts.store.Create("Alice")
+	select {
+	case r := <-done:
+		if r.err != nil || r.code != 200 || r.u.Version != 2 || !slices.Equal(names(r.u.Users), []string{"Alice"}) {
+			t.Errorf("woken: %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the write didn't wake the poller")
+	}
+}
+
+func TestOneWriteWakesAll(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.Create("Rishabh")
+	const pollers = 10
+	results := make([]updates, pollers)
+	var wg sync.WaitGroup
+	for i := range pollers {
+		wg.Go(func() {
+			code, u, err := ts.poll(context.Background(), 1)
+			if err != nil || code != 200 {
+				t.Errorf("poller %d: %d %v", i, code, err)
+			}
+			results[i] = u
+		})
+	}
+	eventually(t, "every poller to wait", func() bool { return ts.store.waiting.Load() == pollers })
+	ts.store.Delete(1)
+	wg.Wait()
+	for i, u := range results {
+		if u.Version != 2 || len(u.Users) != 1 || u.Users[0] != (User{ID: 1, Deleted: true}) {
+			t.Errorf("poller %d: %+v", i, u)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.Create("Rishabh")
+	done := make(chan int, 1)
+	go func() {
+		code, _, _ := ts.poll(context.Background(), 1)
+		done <- code
+	}()
+	eventually(t, "the poller to wait", func() bool { return ts.store.waiting.Load() == 1 })
+	ts.clock.Fire() // 30 seconds pass
+	select {
+	case code := <-done:
+		if code != http.StatusNoContent {
+			t.Errorf("timeout: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fake timeout didn't end the request")
+	}
+	eventually(t, "the handler to return", func() bool { return ts.store.waiting.Load() == 0 })
+}
+
+// without an After the handler waits on the real clock
+func TestRealClock(t *testing.T) {
+	h := &UpdatesHandler{Store: NewUserStore(), Timeout: 20 * time.Millisecond}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?since=0", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("timeout: %d", rec.Code)
+	}
+}
+
+func TestClientDisconnect(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.Create("Rishabh")
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	const pollers = 5
+	for range pollers {
+		go func() {
+			_, _, err := ts.poll(ctx, 1)
+			errc <- err
+		}()
+	}
+	eventually(t, "the pollers to wait", func() bool { return ts.store.waiting.Load() == pollers })
+	cancel()
+	for range pollers {
+		if err := <-errc; !errors.Is(err, context.Canceled) {
+			t.Errorf("client: %v", err)
+		}
+	}
+	// no timer fired and nothing was written: only the disconnect can have freed them
+	eventually(t, "the handlers to stop waiting", func() bool { return ts.store.waiting.Load() == 0 })
+}
+
+func TestBadSince(t *testing.T) {
+	h := &UpdatesHandler{Store: NewUserStore(), Timeout: time.Second, After: time.After}
+	for _, q := range []string{"", "?since=", "?since=-1", "?since=abc", "?since=1.5"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: %d", q, rec.Code)
+		}
+	}
+}
+
+func TestStoreVersion(t *testing.T) {
+	s := NewUserStore()
+	users, version, ch := s.Since(0)
+	if users != nil || version != 0 {
+		t.Fatalf("empty store: %v %d", users, version)
+	}
+	a := s.Create("A")
+	select {
+	case <-ch:
+	default:
+		t.Error("Create didn't close the channel")
+	}
+	s.Create("B")
+	s.Delete(a.ID)
+	s.Delete(a.ID) // already gone: no change
+	users, version, _ = s.Since(0)
+	want := []User{{ID: 1, Deleted: true}, {ID: 2, Name: "B"}}
+	if version != 3 || !slices.Equal(users, want) {
+		t.Errorf("Since(0) = %v %d, want %v 3", users, version, want)
+	}
+	if users, _, _ := s.Since(2); !slices.Equal(users, []User{{ID: 1, Deleted: true}}) {
+		t.Errorf("Since(2) = %v", users)
+	}
+	if users, _, _ := s.Since(3); users != nil {
+		t.Errorf("Since(3) = %v", users)
+	}
+}
+
+func TestConcurrentWritesAndPolls(t *testing.T) {
+	ts := newTestServer(t)
+	const writes = 50
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range writes {
+			ts.store.Create(fmt.Sprint("user", i))
+		}
+	})
+	// each poller follows the versions until it has seen every write
+	for range 5 {
+		wg.Go(func() {
+			seen := map[int]bool{}
+			for since := 0; len(seen) < writes; {
+				code, u, err := ts.poll(context.Background(), since)
+				if err != nil || code != 200 || u.Version <= since {
+					t.Errorf("since=%d: %d %+v %v", since, code, u, err)
+					return
+				}
+				for _, user := range u.Users {
+					seen[user.ID] = true
+				}
+				since = u.Version
+			}
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// OrderedMap is a map that remembers insertion order.
+// Go's built-in map iterates in random order on purpose, so when output order
+// matters (config printouts, JSON you want to diff) keep the keys in a slice too.
+type OrderedMap[K comparable, V any] struct {
+	keys   []K
+	values map[K]V
+}
+
+func NewOrderedMap[K comparable, V any]() *OrderedMap[K, V] {
+	return &OrderedMap[K, V]{values: make(map[K]V)}
+}
+
+// Set adds or updates a key. Updating keeps the key's original position.
+func (m *OrderedMap[K, V]) Set(key K, value V) {
+	if _, exists := m.values[key]; !exists {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value and whether the key was present (comma-ok like a normal map)
+func (m *OrderedMap[K, V]) Get(key K) (V, bool) {
+	v, ok := m.values[key]
+	return v, ok
+}
+
+// Delete removes a key. Setting it again later appends it at the end.
+func (m *OrderedMap[K, V]) Delete(key K) {
+	if _, exists := m.values[key]; !exists {
+		return
+	}
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k K) bool { return k == key })
+}
+
+// Keys returns a copy of the keys in insertion order
+func (m *OrderedMap[K, V]) Keys() []K {
+	return slices.Clone(m.keys)
+}
+
+func (m *OrderedMap[K, V]) Len() int {
+	return len(m.keys)
+}
+
+// Point is used as a map key: structs are valid keys when all their fields are comparable
+type Point struct {
+	X, Y int
+}
+
+func main() {
+	fmt.Println("Learning maps in depth")
+
+	// 1. nil maps: reading is fine, writing panics
+	var nilMap map[string]int
+	fmt.Println("Reading from nil map:", nilMap["missing"], "len:", len(nilMap))
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Println("Recovered from panic:", r)
+			}
+		}()
+		nilMap["boom"] = 1 // panics: assignment to entry in nil map
+	}()
+	// fix: always initialize with make or a literal
+	initialized := make(map[string]int)
+	initialized["ok"] = 1
+	fmt.Println("Initialized map:", initialized)
+
+	// 2. iteration order is random (Go randomizes it on purpose)
+	config := map[string]string{"host": "localhost", "port": "8080", "env": "dev", "debug": "true", "region": "ap-south-1"}
+	for run := 1; run <= 2; run++ {
+		var order []string
+		for key := range config {
+			order = append(order, key)
+		}
+		fmt.Printf("Run %d order: %v\n", run, order)
+	}
+	// for stable output, sort the keys first
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	fmt.Println("Sorted keys:", keys)
+
+	// 3. deleting during iteration is allowed in Go
+	scores := map[string]int{"a": 10, "b": 45, "c": 72, "d": 5}
+	for name, score := range scores {
+		if score < 40 {
+			delete(scores, name)
+		}
+	}
+	fmt.Println("After deleting low scores:", scores)
+
+	// 4. struct keys
+	visited := map[Point]bool{}
+	visited[Point{1, 2}] = true
+	visited[Point{3, 4}] = true
+	fmt.Println("Visited (1,2):", visited[Point{1, 2}], "Visited (5,5):", visited[Point{5, 5}])
+
+	// 5. counting word frequencies
+	text := "go is fun and go is fast and go is simple"
+	freq := make(map[string]int)
+	for _, word := range strings.Fields(text) {
+		freq[word]++ // missing keys start at the zero value, so this just works
+	}
+	fmt.Println("Word frequency:", freq) // fmt prints maps with sorted keys
+
+	// 6. OrderedMap keeps insertion order, so this prints the same every run
+	settings := NewOrderedMap[string, string]()
+	settings.Set("host", "localhost")
+	settings.Set("port", "8080")
+	settings.Set("env", "dev")
+	settings.Set("debug", "true")
+	settings.Set("port", "9090") // update keeps position
+	settings.Delete("env")
+	settings.Set("env", "prod") // re-inserted at the end
+	fmt.Println("Ordered config:")
+	for _, key := range settings.Keys() {
+		value, _ := settings.Get(key)
+		fmt.Printf("  %s = %s\n", key, value)
+	}
+	if _, ok := settings.Get("missing"); !ok {
+		fmt.Println("Key 'missing' not found, total keys:", settings.Len())
+	}
+}
+
+// Maps are reference types: passing a map to a function lets it modify the original
+// The zero value of a map is nil, and writing to nil map panics
+// Map keys must be comparable (no slices, maps or functions as keys)
+// Never rely on iteration order, sort keys or keep an ordered index
+// Maps are not safe for concurrent writes, use sync.Mutex or sync.Map
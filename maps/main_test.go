@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap[string, int]()
+	if m.Len() != 0 || len(m.Keys()) != 0 {
+		t.Fatal("a new map is not empty")
+	}
+	if _, ok := m.Get("missing"); ok {
+		t.Fatal("Get on an empty map reported a key")
+	}
+
+	m.Set("host", 1)
+	m.Set("port", 2)
+	m.Set("debug", 3)
+	if want := []string{"host", "port", "debug"}; !slices.Equal(m.Keys(), want) {
+		t.Fatalf("Keys = %v, want %v", m.Keys(), want)
+	}
+
+	// updating keeps the position
+	m.Set("host", 10)
+	if v, ok := m.Get("host"); !ok || v != 10 {
+		t.Fatalf("Get(host) = %d, %v; want 10, true", v, ok)
+	}
+	if want := []string{"host", "port", "debug"}; !slices.Equal(m.Keys(), want) {
+		t.Fatalf("after an update Keys = %v, want %v", m.Keys(), want)
+	}
+
+	// delete, then reinsert goes to the end
+	m.Delete("host")
+	if _, ok := m.Get("host"); ok || m.Len() != 2 {
+		t.Fatalf("after Delete: Get ok = %v, Len = %d", ok, m.Len())
+	}
+	m.Set("host", 11)
+	if want := []string{"port", "debug", "host"}; !slices.Equal(m.Keys(), want) {
+		t.Fatalf("delete then reinsert: Keys = %v, want %v", m.Keys(), want)
+	}
+
+	// deleting a missing key is a no-op
+	m.Delete("nope")
+	if m.Len() != 3 {
+		t.Fatalf("Delete of a missing key changed Len to %d", m.Len())
+	}
+
+	// Keys returns a copy
+	keys := m.Keys()
+	keys[0] = "changed"
+	if m.Keys()[0] != "port" {
+		t.Fatal("changing the slice from Keys changed the map")
+	}
+}
+
+func TestOrderedMapStructKeys(t *testing.T) {
+	m := NewOrderedMap[Point, string]()
+	m.Set(Point{1, 2}, "a")
+	m.Set(Point{0, 0}, "origin")
+	m.Set(Point{1, 2}, "b")
+	if v, _ := m.Get(Point{1, 2}); v != "b" || m.Len() != 2 {
+		t.Fatalf("Get(Point{1, 2}) = %q, Len = %d; want \"b\", 2", v, m.Len())
+	}
+	if want := []Point{{1, 2}, {0, 0}}; !slices.Equal(m.Keys(), want) {
+		t.Fatalf("Keys = %v, want %v", m.Keys(), want)
+	}
+}
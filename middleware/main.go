@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Middleware composes, and the order matters in ways the compiler can't see:
+//   - recovery inside auth: a panic in auth kills the connection instead of returning a 500
+//   - rate limit before request ID: the 429s in the logs have no ID to search for
+//   - role check without auth: there is no user to check, every request is "anonymous"
+//
+// So every middleware says what it needs: Requires lists middleware that must run BEFORE it
+// (further out), MustPrecede lists middleware that must run AFTER it. The chain is checked
+// when a route is registered, and a wrong order stops the program at startup with a message
+// that says what is wrong and why, not with a subtle bug in production.
+
+type Middleware func(http.Handler) http.Handler
+
+type NamedMiddleware struct {
+	Name        string
+	Requires    []string // must come earlier in the chain, and must be there
+	MustPrecede []string // must come later, if they are there at all. "*" means everything else.
+	Why         string   // goes into the error message
+	Wrap        Middleware
+}
+
+var ErrMisordered = errors.New("middleware order")
+
+// Chain is an ordered list of middleware, outermost first: Chain{a, b, c} runs a, then b, then c,
+// then the handler. Build one with NewChain, which checks it.
+type Chain []NamedMiddleware
+
+// NewChain checks every constraint and returns all violations at once, numbered from 1
+func NewChain(mws ...NamedMiddleware) (Chain, error) {
+	pos := map[string]int{}
+	var errs []error
+	for i, m := range mws {
+		if j, dup := pos[m.Name]; dup {
+			errs = append(errs, fmt.Errorf("%w: %s is in the chain twice (#%d and #%d)", ErrMisordered, m.Name, j+1, i+1))
+			continue
+		}
+		pos[m.Name] = i
+	}
+	for i, m := range mws {
+		for _, req := range m.Requires {
+			j, ok := pos[req]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%w: %s (#%d) requires %s, which is not in the chain: %s",
+					ErrMisordered, m.Name, i+1, req, m.Why))
+			case j > i:
+				errs = append(errs, fmt.Errorf("%w: %s (#%d) requires %s to run before it, but %s is #%d: %s",
+					ErrMisordered, m.Name, i+1, req, req, j+1, m.Why))
+			}
+		}
+		for _, after := range m.MustPrecede {
+			if after == "*" {
+				if i > 0 {
+					errs = append(errs, fmt.Errorf("%w: %s must be first, but is #%d after %s: %s",
+						ErrMisordered, m.Name, i+1, mws[0].Name, m.Why))
+				}
+				continue
+			}
+			if j, ok := pos[after]; ok && j < i {
+				errs = append(errs, fmt.Errorf("%w: %s (#%d) must run before %s, but %s is #%d: %s",
+					ErrMisordered, m.Name, i+1, after, after, j+1, m.Why))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return Chain(mws), nil
+}
+
+// Then wraps h, last middleware innermost
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i].Wrap(h)
+	}
+	return h
+}
+
+func (c Chain) Names() []string {
+	names := make([]string, len(c))
+	for i, m := range c {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// ----------------------------------------------------------------------------
+// Router: global middleware from Use, per-route middleware after it, checked together
+
+type RouteInfo struct {
+	Pattern string   `json:"pattern"`
+	Chain   []string `json:"chain"`
+}
+
+type Router struct {
+	mux *http.ServeMux
+
+	mu     sync.RWMutex // guards global and routes: Use and Handle may run on different goroutines
+	global []NamedMiddleware
+	routes []RouteInfo
+}
+
+func NewRouter() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Use adds middleware for every route. It panics after the first Handle: routes already
+// registered would silently miss it.
+func (r *Router) Use(mws ...NamedMiddleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.routes) > 0 {
+		panic("middleware: Use after Handle, the routes registered so far would not get it")
+	}
+	r.global = append(r.global, mws...)
+}
+
+// Handle registers h behind the global middleware and then mws. A chain that breaks a
+// constraint panics, like http.ServeMux does with a bad pattern: fix the code, don't handle it.
+func (r *Router) Handle(pattern string, h http.Handler, mws ...NamedMiddleware) {
+	// one lock for reading global and adding the route, so a Use can't land in between
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	chain, err := NewChain(append(slices.Clone(r.global), mws...)...)
+	if err != nil {
+		panic(fmt.Sprintf("route %q: %v", pattern, err))
+	}
+	r.mux.Handle(pattern, chain.Then(h))
+	r.routes = append(r.routes, RouteInfo{Pattern: pattern, Chain: chain.Names()})
+}
+
+func (r *Router) Routes() []RouteInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.routes)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// DebugHandler serves the effective chain of every route, in registration order
+func (r *Router) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Routes())
+	})
+}
+
+// ----------------------------------------------------------------------------
+// The middleware, each with its constraints next to it
+
+type ctxKey int
+
+const (
+	requestIDKey ctxKey = iota
+	userKey
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+var Recovery = NamedMiddleware{
+	Name:        "recovery",
+	MustPrecede: []string{"*"},
+	Why:         "a panic in any middleware outside it is not recovered",
+	Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if v := recover(); v != nil {
+					fmt.Printf("    log: panic in %s: %v\n", r.URL.Path, v)
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	},
+}
+
+var nextRequestID atomic.Int64
+
+var RequestID = NamedMiddleware{
+	Name: "requestid",
+	Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := "req-" + strconv.FormatInt(nextRequestID.Add(1), 10)
+			w.Header().Set("X-Request-ID", id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+		})
+	},
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+var Logging = NamedMiddleware{
+	Name:     "logging",
+	Requires: []string{"requestid"},
+	Why:      "every log line carries the request ID",
+	Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			fmt.Printf("    log: %s %s %s -> %d\n", requestIDFrom(r.Context()), r.Method, r.URL.Path, rec.status)
+		})
+	},
+}
+
+var CORS = NamedMiddleware{
+	Name:        "cors",
+	MustPrecede: []string{"auth", "ratelimit"},
+	Why:         "preflight OPTIONS requests carry no credentials and must not count against a limit",
+	Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	},
+}
+
+// RateLimit allows limit requests in total, enough to show a 429
+func RateLimit(limit int64) NamedMiddleware {
+	var used atomic.Int64
+	return NamedMiddleware{
+		Name:        "ratelimit",
+		Requires:    []string{"requestid"},
+		MustPrecede: []string{"auth"},
+		Why:         "a 429 must carry a request ID, and checking credentials is the expensive part a limit protects",
+		Wrap: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if used.Add(1) > limit {
+					writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "slow down", "request_id": requestIDFrom(r.Context())})
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		},
+	}
+}
+
+// Auth: X-User/X-Role stand in for a verified token
+var Auth = NamedMiddleware{
+	Name:     "auth",
+	Requires: []string{"requestid"},
+	Why:      "a rejected login is logged with its request ID",
+	Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, role := r.Header.Get("X-User"), r.Header.Get("X-Role")
+			if user == "" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required", "request_id": requestIDFrom(r.Context())})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey, [2]string{user, role})))
+		})
+	},
+}
+
+func RequireRole(role string) NamedMiddleware {
+	return NamedMiddleware{
+		Name:     "role:" + role,
+		Requires: []string{"auth"},
+		Why:      "without auth there is no user whose role could be checked",
+		Wrap: func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				u, _ := r.Context().Value(userKey).([2]string)
+				if u[1] != role {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": role + " only"})
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		},
+	}
+}
+
+func main() {
+	fmt.Println("Learning middleware ordering in Go")
+
+	fmt.Println("1. Chains that break a rule, with the reason")
+	bad := []struct {
+		name string
+		mws  []NamedMiddleware
+	}{
+		{"auth before recovery", []NamedMiddleware{RequestID, Auth, Recovery}},
+		{"rate limit before request ID", []NamedMiddleware{Recovery, RateLimit(10), RequestID}},
+		{"role check without auth", []NamedMiddleware{Recovery, RequestID, RequireRole("admin")}},
+		{"cors after auth", []NamedMiddleware{Recovery, RequestID, Auth, CORS}},
+		{"logging twice", []NamedMiddleware{Recovery, RequestID, Logging, Logging}},
+		{"everything wrong", []NamedMiddleware{RequireRole("admin"), Auth, Logging, Recovery}},
+	}
+	for _, b := range bad {
+		_, err := NewChain(b.mws...)
+		fmt.Printf("  %s (is ErrMisordered: %v):\n", b.name, errors.Is(err, ErrMisordered))
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Println("    " + line)
+		}
+	}
+	good, err := NewChain(Recovery, RequestID, Logging, CORS, RateLimit(10), Auth, RequireRole("admin"))
+	fmt.Println("  the right order:", good.Names(), err)
+
+	fmt.Println("2. The router checks every route when it is registered")
+	r := NewRouter()
+	r.Use(Recovery, RequestID, Logging, CORS, RateLimit(7))
+	r.Handle("GET /api/users", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, []string{"rishabh", "sanchay"})
+	}))
+	r.Handle("GET /api/admin/stats", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]int{"users": 2})
+	}), Auth, RequireRole("admin"))
+	r.Handle("GET /api/boom", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("nil map write")
+	}))
+	r.Handle("GET /api/debug/middleware", r.DebugHandler(), Auth, RequireRole("admin"))
+	func() {
+		defer func() { fmt.Println("  panic:", recover()) }()
+		r.Handle("GET /api/reports", http.NotFoundHandler(), RequireRole("admin"), Auth)
+	}()
+	func() {
+		defer func() { fmt.Println("  panic:", recover()) }()
+		r.Use(Auth)
+	}()
+
+	fmt.Println("3. Requests through the chains")
+	do := func(method, target, user, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		if user != "" {
+			req.Header.Set("X-User", user)
+			req.Header.Set("X-Role", role)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		body, _ := io.ReadAll(rec.Result().Body)
+		if len(body) > 70 {
+			body = append(body[:70], "..."...)
+		}
+		fmt.Printf("  %-7s %-24s %-6s -> %d %s %s\n", method, target, role, rec.Code,
+			rec.Header().Get("X-Request-ID"), strings.TrimSpace(string(body)))
+		return rec
+	}
+	do("GET", "/api/users", "", "")
+	do("GET", "/api/admin/stats", "", "")
+	do("GET", "/api/admin/stats", "bob", "user")
+	do("GET", "/api/admin/stats", "root", "admin")
+	do("GET", "/api/boom", "", "")
+	rec := do("GET", "/api/debug/middleware", "root", "admin")
+	do("GET", "/api/users", "", "")
+	do("GET", "/api/users", "", "")
+
+	fmt.Println("4. GET /api/debug/middleware, the effective chain per route:")
+	var listed []RouteInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, ri := range listed {
+		fmt.Printf("  %-28s %s\n", ri.Pattern, strings.Join(ri.Chain, " -> "))
+	}
+	fmt.Println("  matches what was registered:", slices.EqualFunc(listed, r.Routes(), func(a, b RouteInfo) bool {
+		return a.Pattern == b.Pattern && slices.Equal(a.Chain, b.Chain)
+	}))
+}
+
+// The order of middleware is part of the program's correctness, write the rules down next to each one.
+// Requires: must be in the chain and further out. MustPrecede: if present, must be further in.
+// Check the whole chain when a route is registered and panic, a wrong order is a bug, not a runtime error.
+// Report every violation at once, with positions and the reason, so one run shows all that is wrong.
+// Expose the effective chain per route: "which middleware does this endpoint really run?" has an answer.
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+const (
+	whyRecovery  = "a panic in any middleware outside it is not recovered"
+	whyLogging   = "every log line carries the request ID"
+	whyCORS      = "preflight OPTIONS requests carry no credentials and must not count against a limit"
+	whyRateLimit = "a 429 must carry a request ID, and checking credentials is the expensive part a limit protects"
+	whyAuth      = "a rejected login is logged with its request ID"
+	whyRole      = "without auth there is no user whose role could be checked"
+)
+
+func names(mws []NamedMiddleware) []string {
+	out := []string{}
+	for _, m := range mws {
+		out = append(out, m.Name)
+	}
+	return out
+}
+
+func TestValidChains(t *testing.T) {
+	chains := [][]NamedMiddleware{
+		{},
+		{Recovery},
+		{RequestID},
+		{Recovery, RequestID, Logging, CORS, RateLimit(10), Auth, RequireRole("admin")},
+		{CORS, RequestID, Auth},             // recovery only has to be first when it's there
+		{Recovery, RequestID, RateLimit(1)}, // MustPrecede only applies to what is there
+		{RequestID, Auth, RequireRole("admin"), RequireRole("ops")},
+	}
+	for _, mws := range chains {
+		c, err := NewChain(mws...)
+		if err != nil {
+			t.Errorf("%v: %v", names(mws), err)
+			continue
+		}
+		if !slices.Equal(c.Names(), names(mws)) {
+			t.Errorf("Names() = %v, want %v", c.Names(), names(mws))
+		}
+	}
+}
+
+// every violation is reported, in chain order, with the positions and the reason
+func TestInvalidChains(t *testing.T) {
+	tests := []struct {
+		name string
+		mws  []NamedMiddleware
+		want []string
+	}{
+		{"auth before recovery", []NamedMiddleware{RequestID, Auth, Recovery}, []string{
+			"recovery must be first, but is #3 after requestid: " + whyRecovery,
+		}},
+		{"rate limit before request ID", []NamedMiddleware{Recovery, RateLimit(10), RequestID}, []string{
+			"ratelimit (#2) requires requestid to run before it, but requestid is #3: " + whyRateLimit,
+		}},
+		{"rate limit after auth", []NamedMiddleware{Recovery, RequestID, Auth, RateLimit(10)}, []string{
+			"ratelimit (#4) must run before auth, but auth is #3: " + whyRateLimit,
+		}},
+		{"role check without auth", []NamedMiddleware{Recovery, RequestID, RequireRole("admin")}, []string{
+			"role:admin (#3) requires auth, which is not in the chain: " + whyRole,
+		}},
+		{"cors after auth", []NamedMiddleware{Recovery, RequestID, Auth, CORS}, []string{
+			"cors (#4) must run before auth, but auth is #3: " + whyCORS,
+		}},
+		{"logging twice", []NamedMiddleware{Recovery, RequestID, Logging, Logging}, []string{
+			"logging is in the chain twice (#3 and #4)",
+		}},
+		{"everything wrong", []NamedMiddleware{RequireRole("admin"), Auth, Logging, Recovery}, []string{
+			"role:admin (#1) requires auth to run before it, but auth is #2: " + whyRole,
+			"auth (#2) requires requestid, which is not in the chain: " + whyAuth,
+			"logging (#3) requires requestid, which is not in the chain: " + whyLogging,
+			"recovery must be first, but is #4 after role:admin: " + whyRecovery,
+		}},
+	}
+	for _, tt := range tests {
+		c, err := NewChain(tt.mws...)
+		if c != nil || !errors.Is(err, ErrMisordered) {
+			t.Errorf("%s: %v, %v", tt.name, c, err)
+			continue
+		}
+		var want []string
+		for _, w := range tt.want {
+			want = append(want, "middleware order: "+w)
+		}
+		if got := strings.Split(err.Error(), "\n"); !slices.Equal(got, want) {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, want)
+		}
+	}
+}
+
+// trace is a middleware that records when it runs, to check the order Then builds
+func trace(name string, log *[]string) NamedMiddleware {
+	return NamedMiddleware{Name: name, Wrap: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*log = append(*log, name+" in")
+			next.ServeHTTP(w, r)
+			*log = append(*log, name+" out")
+		})
+	}}
+}
+
+func TestThenOrder(t *testing.T) {
+	var log []string
+	c, err := NewChain(trace("a", &log), trace("b", &log), trace("c", &log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Then(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { log = append(log, "handler") })).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	want := []string{"a in", "b in", "c in", "handler", "c out", "b out", "a out"}
+	if !slices.Equal(log, want) {
+		t.Errorf("got %v, want %v", log, want)
+	}
+}
+
+func ok(w http.ResponseWriter, r *http.Request) { writeJSON(w, http.StatusOK, "ok") }
+
+func newTestRouter(limit int64) *Router {
+	r := NewRouter()
+	r.Use(Recovery, RequestID, Logging, CORS, RateLimit(limit))
+	r.Handle("GET /api/users", http.HandlerFunc(ok))
+	r.Handle("GET /api/admin/stats", http.HandlerFunc(ok), Auth, RequireRole("admin"))
+	r.Handle("GET /api/boom", http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("nil map write") }))
+	r.Handle("GET /api/debug/middleware", r.DebugHandler(), Auth, RequireRole("admin"))
+	return r
+}
+
+func do(r http.Handler, method, target, user, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Role", role)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouterRejectsBadRoutes(t *testing.T) {
+	r := newTestRouter(100)
+	before := r.Routes()
+	func() {
+		defer func() {
+			msg := fmt.Sprint(recover())
+			want := `route "GET /api/reports": middleware order: role:admin (#6) requires auth to run before it, but auth is #7: ` + whyRole
+			if msg != want {
+				t.Errorf("panic %q\nwant  %q", msg, want)
+			}
+		}()
+		r.Handle("GET /api/reports", http.HandlerFunc(ok), RequireRole("admin"), Auth)
+	}()
+	// the global middleware is checked with the route's: a per-route ratelimit comes after a global auth
+	func() {
+		defer func() {
+			if msg := fmt.Sprint(recover()); !strings.Contains(msg, "ratelimit (#4) must run before auth, but auth is #3") {
+				t.Errorf("panic %q", msg)
+			}
+		}()
+		r2 := NewRouter()
+		r2.Use(Recovery, RequestID, Auth)
+		r2.Handle("GET /", http.HandlerFunc(ok), RateLimit(1))
+	}()
+	if !slices.EqualFunc(r.Routes(), before, equalRoute) {
+		t.Error("a rejected route was recorded")
+	}
+	if rec := do(r, "GET", "/api/reports", "root", "admin"); rec.Code != http.StatusNotFound {
+		t.Errorf("a rejected route is served: %d", rec.Code)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Use after Handle didn't panic")
+			}
+		}()
+		r.Use(Auth)
+	}()
+}
+
+// Use racing Handle: either Use wins and every route gets the middleware, or it panics.
+// Run with -race, the old Use read routes without the lock.
+func TestUseRacesHandle(t *testing.T) {
+	for range 50 {
+		r := NewRouter()
+		var wg sync.WaitGroup
+		var used atomic.Bool
+		wg.Go(func() {
+			defer func() { recover() }()
+			r.Use(Recovery)
+			used.Store(true)
+		})
+		wg.Go(func() { r.Handle("GET /a", http.HandlerFunc(ok)) })
+		wg.Go(func() { r.Routes() })
+		wg.Wait()
+		routes := r.Routes()
+		if len(routes) != 1 || used.Load() != slices.Equal(routes[0].Chain, []string{"recovery"}) {
+			t.Fatalf("Use ran: %v, routes %+v", used.Load(), routes)
+		}
+	}
+}
+
+func equalRoute(a, b RouteInfo) bool {
+	return a.Pattern == b.Pattern && slices.Equal(a.Chain, b.Chain)
+}
+
+func TestDebugEndpoint(t *testing.T) {
+	r := newTestRouter(100)
+	if rec := do(r, "GET", "/api/debug/middleware", "bob", "user"); rec.Code != http.StatusForbidden {
+		t.Errorf("as a user: %d", rec.Code)
+	}
+	rec := do(r, "GET", "/api/debug/middleware", "root", "admin")
+	var got []RouteInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil || rec.Code != 200 {
+		t.Fatalf("%d %s", rec.Code, rec.Body)
+	}
+	global := []string{"recovery", "requestid", "logging", "cors", "ratelimit"}
+	admin := append(slices.Clone(global), "auth", "role:admin")
+	want := []RouteInfo{
+		{"GET /api/users", global},
+		{"GET /api/admin/stats", admin},
+		{"GET /api/boom", global},
+		{"GET /api/debug/middleware", admin},
+	}
+	if !slices.EqualFunc(got, want, equalRoute) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+	if !slices.EqualFunc(got, r.Routes(), equalRoute) {
+		t.Error("the endpoint and Routes() disagree")
+	}
+}
+
+// the order each rule asks for shows in the responses
+func TestRequestsThroughTheChain(t *testing.T) {
+	r := newTestRouter(100)
+	tests := []struct {
+		method, target, user, role string
+		code                       int
+	}{
+		{"GET", "/api/users", "", "", 200},
+		{"GET", "/api/admin/stats", "", "", 401},
+		{"GET", "/api/admin/stats", "bob", "user", 403},
+		{"GET", "/api/admin/stats", "root", "admin", 200},
+		{"GET", "/api/boom", "", "", 500},            // recovered, with the headers set outside
+		{"OPTIONS", "/api/admin/stats", "", "", 405}, // no OPTIONS route
+		{"GET", "/api/missing", "", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := do(r, tt.method, tt.target, tt.user, tt.role)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s as %q: %d, want %d", tt.method, tt.target, tt.role, rec.Code, tt.code)
+		}
+		if tt.code != http.StatusNotFound && tt.code != 405 && !strings.HasPrefix(rec.Header().Get("X-Request-ID"), "req-") {
+			t.Errorf("%s %s: no request ID", tt.method, tt.target)
+		}
+	}
+}
+
+// the ratelimit runs after requestid, so even a 429 can be found in the logs
+func TestRateLimitHasARequestID(t *testing.T) {
+	r := newTestRouter(2)
+	do(r, "GET", "/api/users", "", "")
+	do(r, "GET", "/api/users", "", "")
+	rec := do(r, "GET", "/api/users", "", "")
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusTooManyRequests || body["request_id"] == "" || body["request_id"] != rec.Header().Get("X-Request-ID") {
+		t.Errorf("%d %v %v", rec.Code, body, rec.Header())
+	}
+}
+
+// cors runs before ratelimit and auth: a preflight needs no login and costs no quota
+func TestPreflight(t *testing.T) {
+	r := NewRouter()
+	r.Use(Recovery, RequestID, CORS, RateLimit(1))
+	r.Handle("/api/admin/stats", http.HandlerFunc(ok), Auth, RequireRole("admin"))
+	for range 3 {
+		if rec := do(r, "OPTIONS", "/api/admin/stats", "", ""); rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("preflight: %d %v", rec.Code, rec.Header())
+		}
+	}
+	if rec := do(r, "GET", "/api/admin/stats", "root", "admin"); rec.Code != 200 {
+		t.Errorf("first real request after the preflights: %d", rec.Code)
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -30,21 +33,515 @@ import (
 // 	fmt.Println("Final Counter:", counter)
 // }
 
-var counter int
-var mu sync.Mutex
+// Counter is implemented 4 different ways below, every one is safe for concurrent use
+type Counter interface {
+	Inc()
+	Add(n int64)
+	Value() int64
+}
+
+// MutexCounter: the classic, a mutex around a plain int
+type MutexCounter struct {
+	mu sync.Mutex
+	n  int64
+}
+
+func (c *MutexCounter) Inc() { c.Add(1) }
+
+func (c *MutexCounter) Add(n int64) {
+	c.mu.Lock() // Lock the mutex before entering the critical section
+	c.n += n
+	c.mu.Unlock() // Unlock the mutex after leaving the critical section
+}
+
+func (c *MutexCounter) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}
+
+// AtomicCounter: a single CPU instruction, no lock at all.
+// Only works for simple values (numbers, pointers), not for updating several fields together.
+type AtomicCounter struct {
+	n atomic.Int64
+}
+
+func (c *AtomicCounter) Inc()         { c.n.Add(1) }
+func (c *AtomicCounter) Add(n int64)  { c.n.Add(n) }
+func (c *AtomicCounter) Value() int64 { return c.n.Load() }
+
+// ChannelCounter: one owner goroutine holds the value, everyone else sends it messages.
+// "Don't communicate by sharing memory, share memory by communicating."
+// Close must be called to stop the owner goroutine.
+type ChannelCounter struct {
+	adds  chan int64
+	reads chan chan int64
+	done  chan struct{}
+}
+
+func NewChannelCounter() *ChannelCounter {
+	c := &ChannelCounter{adds: make(chan int64), reads: make(chan chan int64), done: make(chan struct{})}
+	go func() {
+		var n int64 // only this goroutine touches n, so no lock is needed
+		for {
+			select {
+			case delta := <-c.adds:
+				n += delta
+			case reply := <-c.reads:
+				reply <- n
+			case <-c.done:
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func (c *ChannelCounter) Inc()        { c.Add(1) }
+func (c *ChannelCounter) Add(n int64) { c.adds <- n }
+
+func (c *ChannelCounter) Value() int64 {
+	reply := make(chan int64)
+	c.reads <- reply
+	return <-reply
+}
+
+func (c *ChannelCounter) Close() { close(c.done) }
+
+// shard is padded to 64 bytes (a CPU cache line) so two shards never share a cache line,
+// otherwise cores would still fight over the same line ("false sharing")
+type shard struct {
+	n atomic.Int64
+	_ [56]byte
+}
 
-func increment() {
+// ShardedCounter spreads the writes over several atomics and adds them up on read.
+// Writes are cheaper under heavy contention, reads are more expensive.
+type ShardedCounter struct {
+	shards []shard
+}
 
-	for i := 0; i < 1000; i++ {
-		mu.Lock() // Lock the mutex before entering the critical section
-		counter++
-		mu.Unlock() // Unlock the mutex after leaving the critical section
+func NewShardedCounter(shards int) *ShardedCounter {
+	if shards < 1 {
+		shards = 1
 	}
+	return &ShardedCounter{shards: make([]shard, shards)}
 }
-func main() {
-	for i := 0; i < 5; i++ {
-		go increment()
+
+func (c *ShardedCounter) Inc() { c.Add(1) }
+
+func (c *ShardedCounter) Add(n int64) {
+	c.shards[rand.IntN(len(c.shards))].n.Add(n) // a random shard: goroutines rarely pick the same one
+}
+
+func (c *ShardedCounter) Value() int64 {
+	var total int64
+	for i := range c.shards {
+		total += c.shards[i].n.Load()
+	}
+	return total
+}
+
+// hammer increments c from goroutines goroutines, perGoroutine times each,
+// and waits for all of them with a WaitGroup (no time.Sleep guessing)
+func hammer(c Counter, goroutines, perGoroutine int) time.Duration {
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				c.Inc()
+			}
+		}()
+	}
+	wg.Wait() // returns only when every goroutine called Done
+	return time.Since(start)
+}
+
+// CounterExamples checks every Counter gives the exact result and compares their speed
+func CounterExamples() {
+	fmt.Println("\nFour concurrency-safe counters")
+
+	const total = 1 << 18 // increments per run, split between the goroutines
+	allExact := true
+	fmt.Printf("%-10s %10s %10s %10s %10s\n", "goroutines", "mutex", "atomic", "channel", "sharded")
+	for _, goroutines := range []int{1, 4, 16, 64} {
+		counters := []Counter{&MutexCounter{}, &AtomicCounter{}, NewChannelCounter(), NewShardedCounter(16)}
+		fmt.Printf("%-10d", goroutines)
+		for _, c := range counters {
+			elapsed := hammer(c, goroutines, total/goroutines)
+			mark := ""
+			if c.Value() != total {
+				mark = fmt.Sprintf(" WRONG %d", c.Value())
+				allExact = false
+			}
+			fmt.Printf(" %10s%s", elapsed.Round(time.Microsecond), mark)
+			if cc, ok := c.(*ChannelCounter); ok {
+				cc.Close()
+			}
+		}
+		fmt.Println()
+	}
+	if allExact {
+		fmt.Printf("every counter reached exactly %d\n", total)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Deadlocks: transferring money between two accounts that each have a mutex
+// ----------------------------------------------------------------------------
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrSameAccount       = errors.New("cannot transfer to the same account")
+	ErrInvalidAmount     = errors.New("amount must be positive")
+)
+
+// Account has its own mutex, so transfers between different accounts can run in parallel
+type Account struct {
+	ID      int
+	mu      sync.Mutex
+	balance int
+}
+
+func (a *Account) Balance() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.balance
+}
+
+func checkTransfer(from, to *Account, amount int) error {
+	if from == to {
+		return ErrSameAccount
+	}
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// move does the actual transfer, both accounts must already be locked
+func move(from, to *Account, amount int) error {
+	if from.balance < amount {
+		return fmt.Errorf("transfer %d from account %d (balance %d): %w", amount, from.ID, from.balance, ErrInsufficientFunds)
+	}
+	from.balance -= amount
+	to.balance += amount
+	return nil
+}
+
+// transferBroken locks "from" and then "to". When A->B and B->A run at the same time,
+// the first holds A and waits for B, the second holds B and waits for A: both wait forever.
+// pause widens the window between the two locks so the demo deadlocks reliably.
+func transferBroken(from, to *Account, amount int, pause time.Duration) error {
+	if err := checkTransfer(from, to, amount); err != nil {
+		return err
+	}
+	from.mu.Lock()
+	defer from.mu.Unlock()
+	time.Sleep(pause)
+	to.mu.Lock()
+	defer to.mu.Unlock()
+	return move(from, to, amount)
+}
+
+// Transfer always locks the account with the smaller ID first.
+// With one global order, a cycle of goroutines waiting on each other is impossible.
+func Transfer(from, to *Account, amount int) error {
+	if err := checkTransfer(from, to, amount); err != nil {
+		return err
+	}
+	first, second := from, to
+	if second.ID < first.ID {
+		first, second = second, first
 	}
-	time.Sleep(time.Second)
-	fmt.Println("Final Counter:", counter)
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+	return move(from, to, amount)
 }
+
+// TransferTryLock is the alternative when no order exists: take the first lock,
+// only TRY the second, and if it is busy release everything, wait a random moment and retry.
+// The random backoff stops two goroutines from retrying in lockstep forever (livelock).
+func TransferTryLock(from, to *Account, amount int) error {
+	if err := checkTransfer(from, to, amount); err != nil {
+		return err
+	}
+	backoff := time.Microsecond
+	for {
+		from.mu.Lock()
+		if to.mu.TryLock() {
+			err := move(from, to, amount)
+			to.mu.Unlock()
+			from.mu.Unlock()
+			return err
+		}
+		from.mu.Unlock()
+		time.Sleep(backoff + rand.N(backoff))
+		if backoff < time.Millisecond {
+			backoff *= 2
+		}
+	}
+}
+
+func totalBalance(accounts []*Account) int {
+	total := 0
+	for _, a := range accounts {
+		total += a.Balance()
+	}
+	return total
+}
+
+// runRandomTransfers runs n transfers between random accounts from many goroutines
+// and returns how many failed because of insufficient funds
+func runRandomTransfers(accounts []*Account, n int, transfer func(from, to *Account, amount int) error) int {
+	var wg sync.WaitGroup
+	var insufficient atomic.Int64
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			from := accounts[rand.IntN(len(accounts))]
+			to := accounts[rand.IntN(len(accounts))]
+			err := transfer(from, to, rand.IntN(300)+1)
+			if errors.Is(err, ErrInsufficientFunds) {
+				insufficient.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(insufficient.Load())
+}
+
+// TransferExamples shows the deadlock, the two fixes and checks that no money is created or lost
+func TransferExamples() {
+	fmt.Println("\nBank transfers and deadlocks")
+
+	// 1. the broken version, watched by a watchdog so the demo doesn't hang.
+	// The two stuck goroutines can never be stopped, they stay blocked until the program exits.
+	a, b := &Account{ID: 1, balance: 100}, &Account{ID: 2, balance: 100}
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() { defer wg.Done(); transferBroken(a, b, 10, 10*time.Millisecond) }()
+		go func() { defer wg.Done(); transferBroken(b, a, 10, 10*time.Millisecond) }()
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		fmt.Println("broken transfer finished (got lucky this time)")
+	case <-time.After(500 * time.Millisecond):
+		fmt.Println("watchdog: opposing transfers made no progress in 500ms -> deadlock")
+	}
+
+	// 2. errors
+	c, d := &Account{ID: 3, balance: 50}, &Account{ID: 4}
+	fmt.Println("overdraw:", Transfer(c, d, 80))
+	fmt.Println("same account:", Transfer(c, c, 10))
+	fmt.Println("negative amount:", Transfer(c, d, -5))
+
+	// 3. 10k concurrent random transfers, the total must stay the same
+	for _, version := range []struct {
+		name     string
+		transfer func(from, to *Account, amount int) error
+	}{
+		{"ordered locking", Transfer},
+		{"TryLock + backoff", TransferTryLock},
+	} {
+		accounts := make([]*Account, 10)
+		for i := range accounts {
+			accounts[i] = &Account{ID: i + 1, balance: 1000}
+		}
+		before := totalBalance(accounts)
+		start := time.Now()
+		insufficient := runRandomTransfers(accounts, 10000, version.transfer)
+		after := totalBalance(accounts)
+		fmt.Printf("%-18s total before=%d after=%d conserved=%v, %d rejected for insufficient funds, %s\n",
+			version.name+":", before, after, before == after, insufficient, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// ----------------------------------------------------------------------------
+// sync.RWMutex: a cache that many goroutines read and few write
+// ----------------------------------------------------------------------------
+
+// CacheStats counts how the cache was used
+type CacheStats struct {
+	Hits         int64 // value was already cached
+	Misses       int64 // value was not cached (computed it or waited for someone computing it)
+	Computations int64 // times a compute function actually ran
+}
+
+// inflight is a computation in progress, the waiters block on done
+type inflight[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache is a map guarded by a sync.RWMutex: any number of readers at the same time (RLock),
+// or one writer alone (Lock). Good when reads are much more common than writes.
+type Cache[K comparable, V any] struct {
+	mu       sync.RWMutex
+	data     map[K]V
+	inflight map[K]*inflight[V] // keys being computed right now by GetOrCompute
+
+	hits, misses, computations atomic.Int64
+}
+
+func NewCache[K comparable, V any]() *Cache[K, V] {
+	return &Cache[K, V]{data: make(map[K]V), inflight: make(map[K]*inflight[V])}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock() // readers don't block each other
+	defer c.mu.RUnlock()
+	v, ok := c.data[key]
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	return v, ok
+}
+
+func (c *Cache[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[key] = value
+}
+
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.data, key)
+}
+
+func (c *Cache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.data)
+}
+
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Computations: c.computations.Load()}
+}
+
+// GetOrCompute returns the cached value or computes it with fn.
+// When many goroutines miss the same key at once, only the first one runs fn,
+// the others wait for its result instead of all hitting the database together
+// (the "thundering herd"). Errors are returned to every waiter but not cached.
+func (c *Cache[K, V]) GetOrCompute(key K, fn func() (V, error)) (value V, err error) {
+	// fast path: a read lock is enough for a hit
+	c.mu.RLock()
+	v, ok := c.data[key]
+	c.mu.RUnlock()
+	if ok {
+		c.hits.Add(1)
+		return v, nil
+	}
+
+	// slow path: check again under the write lock, someone may have filled it in between
+	c.mu.Lock()
+	if v, ok := c.data[key]; ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return v, nil
+	}
+	c.misses.Add(1)
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock() // never wait while holding the lock, the computing goroutine needs it
+		<-call.done
+		return call.value, call.err
+	}
+	call := &inflight[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock() // fn may be slow, run it without holding the lock
+
+	// store the result and wake the waiters even if fn panics, otherwise they'd wait forever
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("compute %v: panic: %v", key, r)
+		}
+		c.mu.Lock()
+		if call.err == nil {
+			c.data[key] = call.value
+		}
+		delete(c.inflight, key)
+		c.mu.Unlock()
+		close(call.done)                  // closing a channel wakes every goroutine waiting on it
+		value, err = call.value, call.err // named results: the only way to change what a recovered call returns
+	}()
+	c.computations.Add(1)
+	call.value, call.err = fn()
+	return call.value, call.err
+}
+
+// CacheExamples shows the RWMutex cache and the in-flight deduplication
+func CacheExamples() {
+	fmt.Println("\nRWMutex cache with GetOrCompute")
+
+	cache := NewCache[string, int]()
+	cache.Set("answer", 42)
+	v, ok := cache.Get("answer")
+	fmt.Println("Get answer:", v, ok)
+	cache.Delete("answer")
+	_, ok = cache.Get("answer")
+	fmt.Println("after Delete:", ok, "len:", cache.Len())
+
+	// 100 goroutines ask for the same missing key, the "database" is slow
+	var wg sync.WaitGroup
+	var wrong atomic.Int64
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.GetOrCompute("user:1", func() (int, error) {
+				time.Sleep(50 * time.Millisecond) // pretend to query a database
+				return 7, nil
+			})
+			if err != nil || v != 7 {
+				wrong.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("100 concurrent GetOrCompute: %+v, wrong results: %d\n", cache.Stats(), wrong.Load())
+
+	// errors are not cached, the next call tries again
+	_, err := cache.GetOrCompute("user:2", func() (int, error) { return 0, errors.New("database down") })
+	fmt.Println("failed compute:", err)
+	v, err = cache.GetOrCompute("user:2", func() (int, error) { return 8, nil })
+	fmt.Println("retry:", v, err)
+	_, err = cache.GetOrCompute("user:3", func() (int, error) { panic("bug in loader") })
+	fmt.Println("panicking compute:", err)
+	fmt.Printf("final stats: %+v, len: %d\n", cache.Stats(), cache.Len())
+}
+
+func main() {
+	fmt.Println("Learning sync.Mutex and friends in Go")
+	CounterExamples()
+	TransferExamples()
+	CacheExamples()
+}
+
+// sync.Mutex: Lock/Unlock around the code that touches shared data, keep the critical section short.
+// sync/atomic: fastest for a single number, but can't protect several values at once.
+// Channel owner goroutine: no locks, but every operation is a message, so it's the slowest here.
+// Sharding trades slower reads for faster writes when many goroutines write at the same time.
+// Wait for goroutines with sync.WaitGroup, never with time.Sleep: sleep is a guess, Wait is a guarantee.
+// Run with `go run -race main.go` to let the race detector check the code.
+// Deadlock needs a cycle: goroutine 1 holds A and waits for B while goroutine 2 holds B and waits for A.
+// Break the cycle by always taking locks in the same global order (e.g. by ID),
+// or with TryLock + release + random backoff when there is no natural order.
+// Go only reports "all goroutines are asleep - deadlock!" when EVERY goroutine is stuck,
+// a partial deadlock like the demo's just hangs silently.
+// sync.RWMutex: RLock for readers (many at once), Lock for writers (alone). Never upgrade RLock to Lock,
+// release the read lock first and re-check after taking the write lock.
+// golang.org/x/sync/singleflight implements the same "compute once, share the result" idea.
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var counterImpls = []struct {
+	name string
+	new  func() Counter
+}{
+	{"mutex", func() Counter { return &MutexCounter{} }},
+	{"atomic", func() Counter { return &AtomicCounter{} }},
+	{"channel", func() Counter { return NewChannelCounter() }},
+	{"sharded", func() Counter { return NewShardedCounter(8) }},
+}
+
+func closeCounter(c Counter) {
+	if cc, ok := c.(*ChannelCounter); ok {
+		cc.Close()
+	}
+}
+
+// run with -race: every implementation must reach the exact total
+func TestCountersExact(t *testing.T) {
+	for _, impl := range counterImpls {
+		t.Run(impl.name, func(t *testing.T) {
+			c := impl.new()
+			defer closeCounter(c)
+			const goroutines, each = 50, 200
+			var wg sync.WaitGroup
+			for i := range goroutines {
+				wg.Go(func() {
+					for range each {
+						if i%2 == 0 {
+							c.Inc()
+						} else {
+							c.Add(3)
+						}
+					}
+				})
+			}
+			wg.Wait()
+			if want := int64(goroutines/2*each + goroutines/2*each*3); c.Value() != want {
+				t.Errorf("Value() = %d, want %d", c.Value(), want)
+			}
+			c.Add(-c.Value())
+			if c.Value() != 0 {
+				t.Errorf("after adding the negative: %d", c.Value())
+			}
+		})
+	}
+}
+
+func TestHammer(t *testing.T) {
+	c := &AtomicCounter{}
+	hammer(c, 7, 1000)
+	if c.Value() != 7000 {
+		t.Errorf("Value() = %d, want 7000", c.Value())
+	}
+}
+
+func TestShardedCounterAtLeastOneShard(t *testing.T) {
+	c := NewShardedCounter(0)
+	c.Add(5)
+	if len(c.shards) != 1 || c.Value() != 5 {
+		t.Errorf("%d shards, Value() = %d", len(c.shards), c.Value())
+	}
+}
+
+func BenchmarkCounters(b *testing.B) {
+	for _, goroutines := range []int{1, 4, 16, 64} {
+		for _, impl := range counterImpls {
+			b.Run(fmt.Sprintf("%s/goroutines=%d", impl.name, goroutines), func(b *testing.B) {
+				c := impl.new()
+				defer closeCounter(c)
+				b.SetParallelism(goroutines) // RunParallel starts goroutines*GOMAXPROCS of them
+				b.RunParallel(func(pb *testing.PB) {
+					for pb.Next() {
+						c.Inc()
+					}
+				})
+			})
+		}
+	}
+}
+
+func TestTransferErrors(t *testing.T) {
+	a, b := &Account{ID: 1, balance: 50}, &Account{ID: 2}
+	for _, transfer := range []func(from, to *Account, amount int) error{Transfer, TransferTryLock} {
+		if err := transfer(a, b, 80); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("overdraw: %v", err)
+		}
+		if err := transfer(a, a, 10); !errors.Is(err, ErrSameAccount) {
+			t.Errorf("same account: %v", err)
+		}
+		for _, amount := range []int{0, -5} {
+			if err := transfer(a, b, amount); !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("amount %d: %v", amount, err)
+			}
+		}
+		if a.Balance() != 50 || b.Balance() != 0 {
+			t.Fatalf("a failed transfer moved money: %d, %d", a.Balance(), b.Balance())
+		}
+	}
+	if err := Transfer(a, b, 50); err != nil || a.Balance() != 0 || b.Balance() != 50 {
+		t.Errorf("exact balance: %v, %d, %d", err, a.Balance(), b.Balance())
+	}
+}
+
+// run with -race: 10k concurrent random transfers never create or lose money
+func TestTransferConservesMoney(t *testing.T) {
+	for _, version := range []struct {
+		name     string
+		transfer func(from, to *Account, amount int) error
+	}{
+		{"ordered", Transfer},
+		{"trylock", TransferTryLock},
+	} {
+		t.Run(version.name, func(t *testing.T) {
+			accounts := make([]*Account, 10)
+			for i := range accounts {
+				accounts[i] = &Account{ID: i + 1, balance: 1000}
+			}
+			done := make(chan int)
+			go func() { done <- runRandomTransfers(accounts, 10000, version.transfer) }()
+			select {
+			case <-done:
+			case <-time.After(30 * time.Second):
+				t.Fatal("transfers made no progress in 30s, deadlock?")
+			}
+			if total := totalBalance(accounts); total != 10000 {
+				t.Errorf("total = %d, want 10000", total)
+			}
+			for _, a := range accounts {
+				if a.Balance() < 0 {
+					t.Errorf("account %d went negative: %d", a.ID, a.Balance())
+				}
+			}
+		})
+	}
+}
+
+// opposing transfers A->B and B->A at the same time, many rounds, must all finish
+func TestOpposingTransfersDontDeadlock(t *testing.T) {
+	for _, transfer := range []func(from, to *Account, amount int) error{Transfer, TransferTryLock} {
+		a, b := &Account{ID: 1, balance: 1000}, &Account{ID: 2, balance: 1000}
+		done := make(chan struct{})
+		go func() {
+			var wg sync.WaitGroup
+			for range 500 {
+				wg.Go(func() { transfer(a, b, 1) })
+				wg.Go(func() { transfer(b, a, 1) })
+			}
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			t.Fatal("opposing transfers deadlocked")
+		}
+		if a.Balance() != 1000 || b.Balance() != 1000 {
+			t.Errorf("balances %d, %d, want 1000 each", a.Balance(), b.Balance())
+		}
+	}
+}
+
+func TestCacheBasics(t *testing.T) {
+	c := NewCache[string, int]()
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("empty cache returned a value")
+	}
+	c.Set("a", 1)
+	c.Set("a", 2)
+	if v, ok := c.Get("a"); !ok || v != 2 || c.Len() != 1 {
+		t.Errorf("Get(a) = %d, %v; Len %d", v, ok, c.Len())
+	}
+	c.Delete("a")
+	c.Delete("missing")
+	if _, ok := c.Get("a"); ok || c.Len() != 0 {
+		t.Error("Delete left the key")
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 1, Misses: 2}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+// 100 goroutines miss the same key at once: fn runs exactly once and everyone gets its value
+func TestGetOrComputeRunsOnce(t *testing.T) {
+	c := NewCache[string, int]()
+	var calls atomic.Int64
+	release := make(chan struct{})
+	var started, wg sync.WaitGroup
+	results := make([]int, 100)
+	for i := range results {
+		started.Add(1)
+		wg.Go(func() {
+			started.Done()
+			v, err := c.GetOrCompute("k", func() (int, error) {
+				calls.Add(1)
+				<-release // hold the computation until every goroutine has asked
+				return 7, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		})
+	}
+	started.Wait()
+	time.Sleep(20 * time.Millisecond) // let them reach GetOrCompute, then finish the one computation
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("fn ran %d times, want 1", calls.Load())
+	}
+	for i, v := range results {
+		if v != 7 {
+			t.Fatalf("goroutine %d got %d", i, v)
+		}
+	}
+	st := c.Stats()
+	if st.Computations != 1 || st.Hits+st.Misses != 100 {
+		t.Errorf("Stats() = %+v", st)
+	}
+	if v, err := c.GetOrCompute("k", func() (int, error) { panic("must not run") }); err != nil || v != 7 {
+		t.Errorf("cached: %d, %v", v, err)
+	}
+}
+
+func TestGetOrComputeErrorsAndPanics(t *testing.T) {
+	c := NewCache[string, int]()
+	boom := errors.New("boom")
+	if _, err := c.GetOrCompute("k", func() (int, error) { return 0, boom }); !errors.Is(err, boom) {
+		t.Errorf("err = %v", err)
+	}
+	if c.Len() != 0 {
+		t.Error("an error was cached")
+	}
+	if v, err := c.GetOrCompute("k", func() (int, error) { return 3, nil }); err != nil || v != 3 {
+		t.Errorf("retry: %d, %v", v, err)
+	}
+
+	_, err := c.GetOrCompute("p", func() (int, error) { panic("bug") })
+	if err == nil {
+		t.Fatal("a panic returned no error")
+	}
+	// the panic cleared the in-flight entry, the next call computes instead of waiting forever
+	done := make(chan int)
+	go func() {
+		v, _ := c.GetOrCompute("p", func() (int, error) { return 4, nil })
+		done <- v
+	}()
+	select {
+	case v := <-done:
+		if v != 4 {
+			t.Errorf("after the panic: %d", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetOrCompute hung after a panicking fn")
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Byte sizes using iota: each line shifts 10 more bits (1 KB = 1024 bytes)
+const (
+	_  = iota // ignore the first value (0)
+	KB = 1 << (10 * iota)
+	MB
+	GB
+	TB
+	PB
+	EB
+)
+
+// ErrOverflow is returned by SafeAdd when the result does not fit in an int32
+var ErrOverflow = errors.New("integer overflow")
+
+// AlmostEqual compares floats with a tolerance, because 0.1+0.2 != 0.3 in binary floating point
+func AlmostEqual(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+// SafeAdd adds two int32 values and reports overflow instead of silently wrapping around
+func SafeAdd(a, b int32) (int32, error) {
+	if (b > 0 && a > math.MaxInt32-b) || (b < 0 && a < math.MinInt32-b) {
+		return 0, fmt.Errorf("%d + %d: %w", a, b, ErrOverflow)
+	}
+	return a + b, nil
+}
+
+// FormatBytes turns a byte count into a human readable size like "1.5 MB".
+// Negative sizes keep their sign, e.g. a shrinking file "-2.0 KB".
+func FormatBytes(n int64) string {
+	if n < 0 {
+		if n == math.MinInt64 { // -n would overflow
+			return "-8.0 EB"
+		}
+		return "-" + FormatBytes(-n)
+	}
+	switch {
+	case n >= EB:
+		return fmt.Sprintf("%.1f EB", float64(n)/EB)
+	case n >= PB:
+		return fmt.Sprintf("%.1f PB", float64(n)/PB)
+	case n >= TB:
+		return fmt.Sprintf("%.1f TB", float64(n)/TB)
+	case n >= GB:
+		return fmt.Sprintf("%.1f GB", float64(n)/GB)
+	case n >= MB:
+		return fmt.Sprintf("%.1f MB", float64(n)/MB)
+	case n >= KB:
+		return fmt.Sprintf("%.1f KB", float64(n)/KB)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// factorial uses math/big because 21! already does not fit in int64
+func factorial(n int64) *big.Int {
+	result := big.NewInt(1)
+	for i := int64(2); i <= n; i++ {
+		result.Mul(result, big.NewInt(i))
+	}
+	return result
+}
+
+func main() {
+	fmt.Println("Learning numbers and floating point in Go")
+
+	// Floating point pitfall
+	a, b := 0.1, 0.2
+	sum := a + b
+	fmt.Println("0.1 + 0.2 =", sum)
+	fmt.Println("0.1 + 0.2 == 0.3:", sum == 0.3)
+	fmt.Println("AlmostEqual(0.1+0.2, 0.3, 1e-9):", AlmostEqual(sum, 0.3, 1e-9))
+	// money should be stored in the smallest unit (paise/cents) as integers
+	paise := 1999 + 1
+	fmt.Println("Price in paise:", paise, "=", float64(paise)/100, "rupees")
+
+	// Integer overflow wraps around silently in Go (no panic!)
+	var counter int32 = math.MaxInt32
+	counter++
+	fmt.Println("MaxInt32 + 1 wraps to:", counter)
+	var small uint8 = 0
+	small--
+	fmt.Println("uint8(0) - 1 wraps to:", small)
+
+	if result, err := SafeAdd(math.MaxInt32-1, 1); err == nil {
+		fmt.Println("SafeAdd(MaxInt32-1, 1) =", result)
+	}
+	if _, err := SafeAdd(math.MaxInt32, 1); err != nil {
+		fmt.Println("SafeAdd error:", err)
+	}
+	if _, err := SafeAdd(math.MinInt32, -1); err != nil {
+		fmt.Println("SafeAdd error:", err)
+	}
+
+	// Conversions between sizes can also lose data
+	big64 := int64(math.MaxInt32) + 10
+	fmt.Println("int64 -> int32 truncates:", big64, "->", int32(big64))
+	price := 3.99
+	fmt.Println("float -> int drops the fraction:", int(price), int(-price))
+
+	// big.Int has no size limit
+	fmt.Println("20! =", factorial(20)) // still fits in int64
+	fmt.Println("50! =", factorial(50)) // does not
+	fmt.Println("digits in 100!:", len(factorial(100).String()))
+
+	// Formatting with width and precision
+	pi := math.Pi
+	fmt.Printf("%%f: %f | %%.2f: %.2f | %%8.3f: [%8.3f] | %%-8.3f: [%-8.3f]\n", pi, pi, pi, pi)
+	fmt.Printf("%%e: %e | %%g: %g\n", 1234567.891, 1234567.891)
+	fmt.Printf("%%05d: %05d | %%x: %x | %%b: %b | %%o: %o\n", 42, 255, 5, 8)
+	fmt.Println("FormatFloat 'f' 3:", strconv.FormatFloat(pi, 'f', 3, 64))
+	fmt.Println("FormatFloat -1 (shortest that round-trips):", strconv.FormatFloat(sum, 'f', -1, 64))
+
+	// Special float values
+	zero := 0.0
+	fmt.Println("1/0 =", 1/zero, "| -1/0 =", -1/zero, "| 0/0 =", zero/zero)
+	fmt.Println("NaN == NaN:", math.NaN() == math.NaN(), "use math.IsNaN:", math.IsNaN(zero/zero))
+
+	// Byte sizes
+	for _, size := range []int64{0, 512, 1536, 5 * MB, 3*GB + 512*MB, 2 * TB, math.MaxInt64, -2048} {
+		fmt.Printf("FormatBytes(%d) = %s\n", size, FormatBytes(size))
+	}
+}
+
+// int size depends on the platform (64 bit on most machines), int32/int64 are fixed
+// Integer overflow wraps around, it is never an error at runtime
+// float64 cannot represent most decimals exactly: compare with a tolerance, store money as integers
+// math/big gives arbitrary precision at the cost of speed and allocations
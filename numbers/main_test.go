@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAlmostEqual(t *testing.T) {
+	tests := []struct {
+		a, b, eps float64
+		want      bool
+	}{
+		{0.1 + 0.2, 0.3, 1e-9, true},
+		{1, 1, 0, true},
+		{1, 1.1, 0.05, false},
+		{1, 1.1, 0.2, true},
+		{-0.5, 0.5, 1, true},
+		{math.NaN(), math.NaN(), 1, false},
+		{math.Inf(1), 1e308, 1e300, false},
+	}
+	for _, tt := range tests {
+		if got := AlmostEqual(tt.a, tt.b, tt.eps); got != tt.want {
+			t.Errorf("AlmostEqual(%v, %v, %v) = %v, want %v", tt.a, tt.b, tt.eps, got, tt.want)
+		}
+	}
+}
+
+func TestSafeAdd(t *testing.T) {
+	ok := []struct{ a, b, want int32 }{
+		{1, 2, 3},
+		{math.MaxInt32 - 1, 1, math.MaxInt32},
+		{math.MinInt32 + 1, -1, math.MinInt32},
+		{math.MaxInt32, math.MinInt32, -1},
+		{math.MaxInt32, 0, math.MaxInt32},
+		{-5, 3, -2},
+	}
+	for _, tt := range ok {
+		if got, err := SafeAdd(tt.a, tt.b); err != nil || got != tt.want {
+			t.Errorf("SafeAdd(%d, %d) = %d, %v; want %d", tt.a, tt.b, got, err, tt.want)
+		}
+	}
+	overflow := []struct{ a, b int32 }{
+		{math.MaxInt32, 1},
+		{1, math.MaxInt32},
+		{math.MinInt32, -1},
+		{math.MaxInt32, math.MaxInt32},
+		{math.MinInt32, math.MinInt32},
+	}
+	for _, tt := range overflow {
+		if _, err := SafeAdd(tt.a, tt.b); !errors.Is(err, ErrOverflow) {
+			t.Errorf("SafeAdd(%d, %d) error = %v, want ErrOverflow", tt.a, tt.b, err)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		in   int64
+		want string
+	}{
+		{0, "0 B"},
+		{1, "1 B"},
+		{KB - 1, "1023 B"},
+		{KB, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{MB, "1.0 MB"},
+		{5 * GB / 2, "2.5 GB"},
+		{TB, "1.0 TB"},
+		{PB, "1.0 PB"},
+		{EB, "1.0 EB"},
+		{math.MaxInt32, "2.0 GB"},
+		{math.MaxInt64, "8.0 EB"},
+		{-2048, "-2.0 KB"},
+		{-1, "-1 B"},
+		{math.MinInt64, "-8.0 EB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.in); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFactorial(t *testing.T) {
+	if got := factorial(20).String(); got != "2432902008176640000" {
+		t.Errorf("20! = %s", got)
+	}
+	// 21! no longer fits in an int64
+	if got := factorial(21); got.IsInt64() || got.String() != "51090942171709440000" {
+		t.Errorf("21! = %s", got)
+	}
+	if got := factorial(0).String(); got != "1" {
+		t.Errorf("0! = %s", got)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// API docs written by hand drift away from the code. Here the routes are registered together
+// with their request and response types, and the OpenAPI document is generated from that
+// registry with reflection: add a field or a route and the docs follow.
+
+// ErrorEnvelope is the body of every error response
+type ErrorEnvelope struct {
+	Error string `json:"error" validate:"required"`
+	Code  string `json:"code,omitempty"`
+}
+
+type Route struct {
+	Method   string
+	Path     string // with {params}, like http.ServeMux patterns
+	Summary  string
+	Request  reflect.Type // nil when there is no body
+	Response reflect.Type // nil when there is no body
+	Status   int          // success status
+}
+
+// Router registers handlers on a ServeMux and remembers what it registered
+type Router struct {
+	Title   string
+	Version string
+	mux     *http.ServeMux
+	routes  []Route
+}
+
+func NewRouter(title, version string) *Router {
+	rt := &Router{Title: title, Version: version, mux: http.NewServeMux()}
+	rt.mux.HandleFunc("GET /api/openapi.json", rt.serveSpec)
+	rt.mux.HandleFunc("GET /api/docs", rt.serveDocs)
+	return rt
+}
+
+// Handle registers h for "METHOD path". req and resp are zero values of the body types,
+// or nil when there is no body. Only their types are used.
+func (rt *Router) Handle(method, path, summary string, status int, req, resp any, h http.HandlerFunc) {
+	r := Route{Method: method, Path: path, Summary: summary, Status: status,
+		Request: reflect.TypeOf(req), Response: reflect.TypeOf(resp)} // TypeOf(nil) is nil
+	rt.routes = append(rt.routes, r)
+	rt.mux.HandleFunc(method+" "+path, h)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) { rt.mux.ServeHTTP(w, r) }
+
+// ----------------------------------------------------------------------------
+// Go types to JSON Schema
+
+type schemaBuilder struct {
+	components map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		s := b.schema(t.Elem())
+		if _, isRef := s["$ref"]; isRef {
+			s = map[string]any{"allOf": []any{s}} // OpenAPI 3.0 ignores keys next to a $ref
+		}
+		s["nullable"] = true // a nil pointer is encoded as null
+		return s
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		// named structs go to components once and are referenced, which also stops recursive types
+		if _, done := b.components[t.Name()]; !done {
+			b.components[t.Name()] = nil // placeholder, in case the struct refers to itself
+			b.components[t.Name()] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Struct:
+		return b.structSchema(t)
+	}
+	return map[string]any{} // any value
+}
+
+// structSchema follows encoding/json's rules for names, and the validate tag for constraints
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := b.schema(f.Type)
+		for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+			key, val, _ := strings.Cut(rule, "=")
+			n, _ := strconv.Atoi(val)
+			switch {
+			case key == "required":
+				required = append(required, name)
+			case (key == "min" || key == "max") && f.Type.Kind() == reflect.String:
+				s[key+"Length"] = n
+			case key == "min":
+				s["minimum"] = n
+			case key == "max":
+				s["maximum"] = n
+			}
+		}
+		props[name] = s
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// ----------------------------------------------------------------------------
+// The document
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// GenerateOpenAPI builds an OpenAPI 3.0 document from the registered routes.
+// encoding/json sorts map keys, so the same routes always give the same bytes.
+func (rt *Router) GenerateOpenAPI() ([]byte, error) {
+	b := &schemaBuilder{components: map[string]any{}}
+	errorResp := map[string]any{
+		"description": "Error",
+		"content":     map[string]any{"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(ErrorEnvelope{}))}},
+	}
+	paths := map[string]map[string]any{}
+	for _, r := range rt.routes {
+		success := map[string]any{"description": http.StatusText(r.Status)}
+		if r.Response != nil {
+			success["content"] = map[string]any{"application/json": map[string]any{"schema": b.schema(r.Response)}}
+		}
+		op := map[string]any{
+			"summary":     r.Summary,
+			"operationId": operationID(r),
+			"responses":   map[string]any{strconv.Itoa(r.Status): success, "default": errorResp},
+		}
+		var params []any
+		for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+			params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if r.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": b.schema(r.Request)}},
+			}
+		}
+		if paths[r.Path] == nil {
+			paths[r.Path] = map[string]any{}
+		}
+		paths[r.Path][strings.ToLower(r.Method)] = op
+	}
+	doc := map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": rt.Title, "version": rt.Version},
+		"paths":      paths,
+		"components": map[string]any{"schemas": b.components},
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// operationID: "GET /api/users/{id}" -> "getApiUsersId"
+func operationID(r Route) string {
+	id := strings.ToLower(r.Method)
+	for _, part := range strings.FieldsFunc(r.Path, func(c rune) bool { return c == '/' || c == '{' || c == '}' }) {
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+func (rt *Router) serveSpec(w http.ResponseWriter, r *http.Request) {
+	doc, err := rt.GenerateOpenAPI()
+	if err != nil {
+		http.Error(w, "could not build the document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!doctype html>
+<html><head><title>{{.Title}}</title></head>
+<body>
+<h1>{{.Title}} {{.Version}}</h1>
+<p>Machine-readable: <a href="/api/openapi.json">/api/openapi.json</a></p>
+<table>
+{{range .Routes}}<tr><td><b>{{.Method}}</b></td><td><code>{{.Path}}</code></td><td>{{.Summary}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+func (rt *Router) serveDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	docsPage.Execute(w, map[string]any{"Title": rt.Title, "Version": rt.Version, "Routes": rt.routes})
+}
+
+// ----------------------------------------------------------------------------
+// The API being documented
+
+type User struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Manager   *User     `json:"manager,omitempty"` // refers to its own type
+	password  string    // unexported: never in JSON, never in the docs
+}
+
+type CreateUserRequest struct {
+	Name  string `json:"name" validate:"required,min=2,max=50"`
+	Email string `json:"email" validate:"required"`
+	Age   int    `json:"age" validate:"min=13,max=130"`
+	Debug bool   `json:"-"`
+}
+
+type UsersResponse struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+func notImplemented(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotImplemented)
+	json.NewEncoder(w).Encode(ErrorEnvelope{Error: "not in this demo"})
+}
+
+func main() {
+	fmt.Println("Learning to generate OpenAPI docs from Go types")
+
+	rt := NewRouter("Users API", "1.0.0")
+	rt.Handle("GET", "/api/users", "List users", 200, nil, UsersResponse{}, notImplemented)
+	rt.Handle("POST", "/api/users", "Create a user", 201, CreateUserRequest{}, User{}, notImplemented)
+	rt.Handle("GET", "/api/users/{id}", "Get one user", 200, nil, User{}, notImplemented)
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	var doc struct {
+		Paths      map[string]map[string]any
+		Components struct{ Schemas map[string]json.RawMessage }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("GET /api/openapi.json -> %d, %d bytes\n", rec.Code, rec.Body.Len())
+	for _, p := range slices.Sorted(maps.Keys(doc.Paths)) {
+		fmt.Println("  path", p, slices.Sorted(maps.Keys(doc.Paths[p])))
+	}
+	fmt.Println("  schemas", slices.Sorted(maps.Keys(doc.Components.Schemas)))
+	fmt.Println("CreateUserRequest schema:")
+	pretty, _ := json.MarshalIndent(doc.Components.Schemas["CreateUserRequest"], "  ", "  ")
+	fmt.Println(" ", string(pretty))
+
+	// the same registry gives the same bytes, one more route changes only its own part
+	before, _ := rt.GenerateOpenAPI()
+	again, _ := rt.GenerateOpenAPI()
+	fmt.Println("Generated twice, identical:", string(before) == string(again))
+	rt.Handle("DELETE", "/api/users/{id}", "Delete a user", 204, nil, nil, notImplemented)
+	after, _ := rt.GenerateOpenAPI()
+	fmt.Printf("After adding DELETE: %d -> %d bytes, new operation present: %v\n",
+		len(before), len(after), strings.Contains(string(after), `"operationId": "deleteApiUsersId"`))
+
+	rec = httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest("GET", "/api/docs", nil))
+	fmt.Printf("GET /api/docs -> %d %s, %d routes listed\n", rec.Code, rec.Header().Get("Content-Type"), strings.Count(rec.Body.String(), "<tr>"))
+}
+
+// Register each route WITH its request and response types, then the docs come from the code.
+// reflect reads the same json tags encoding/json uses: names, omitempty, "-" and unexported fields.
+// Named structs go to components/schemas and are referenced with $ref, that also handles recursive types.
+// encoding/json sorts map keys, so the document is stable and can be compared with a saved copy.
+// Validation tags (required, min, max) become required/minLength/minimum, one source of truth for both.
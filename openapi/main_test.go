@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// update and checkGolden are a shorter version of the golden helper in the textutil lesson
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name.golden; -update rewrites the file first
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n")); !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (go test main.go main_test.go -update rewrites it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func usersAPI() *Router {
+	rt := NewRouter("Users API", "1.0.0")
+	rt.Handle("GET", "/api/users", "List users", 200, nil, UsersResponse{}, notImplemented)
+	rt.Handle("POST", "/api/users", "Create a user", 201, CreateUserRequest{}, User{}, notImplemented)
+	rt.Handle("GET", "/api/users/{id}", "Get one user", 200, nil, User{}, notImplemented)
+	return rt
+}
+
+func decode(t *testing.T, doc []byte) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(doc, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// at walks a decoded document: at(doc, "paths", "/api/users", "get")
+func at(v any, keys ...string) any {
+	for _, k := range keys {
+		m, _ := v.(map[string]any)
+		v = m[k]
+	}
+	return v
+}
+
+func TestDocumentFixture(t *testing.T) {
+	doc, err := usersAPI().GenerateOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "users_api", doc)
+	if again, _ := usersAPI().GenerateOpenAPI(); string(again) != string(doc) {
+		t.Error("two generations differ")
+	}
+}
+
+// a new route adds its operation and nothing else moves
+func TestAddRoute(t *testing.T) {
+	rt := usersAPI()
+	before, _ := rt.GenerateOpenAPI()
+	rt.Handle("DELETE", "/api/users/{id}", "Delete a user", 204, nil, nil, notImplemented)
+	after, _ := rt.GenerateOpenAPI()
+
+	a, b := decode(t, before), decode(t, after)
+	op, _ := at(b, "paths", "/api/users/{id}", "delete").(map[string]any)
+	if op == nil {
+		t.Fatalf("no delete operation:\n%s", after)
+	}
+	if op["operationId"] != "deleteApiUsersId" || op["summary"] != "Delete a user" || op["requestBody"] != nil {
+		t.Errorf("delete: %v", op)
+	}
+	if at(op, "responses", "204", "description") != "No Content" || at(op, "responses", "204", "content") != nil {
+		t.Errorf("204 response: %v", at(op, "responses"))
+	}
+	delete(at(b, "paths", "/api/users/{id}").(map[string]any), "delete")
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("adding a route changed other parts of the document:\n%s", after)
+	}
+
+	// a route with a new type adds its schema too
+	type Ping struct {
+		OK bool `json:"ok"`
+	}
+	rt.Handle("GET", "/api/ping", "Ping", 200, nil, Ping{}, notImplemented)
+	doc, _ := rt.GenerateOpenAPI()
+	if at(decode(t, doc), "components", "schemas", "Ping", "properties", "ok", "type") != "boolean" {
+		t.Errorf("Ping schema missing:\n%s", doc)
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	b := &schemaBuilder{components: map[string]any{}}
+	s := b.structSchema(reflect.TypeOf(CreateUserRequest{}))
+	props := s["properties"].(map[string]any)
+	want := map[string]map[string]any{
+		"name":  {"type": "string", "minLength": 2, "maxLength": 50},
+		"email": {"type": "string"},
+		"age":   {"type": "integer", "minimum": 13, "maximum": 130},
+	}
+	if len(props) != len(want) {
+		t.Errorf("properties %v, Debug has json:\"-\"", props)
+	}
+	for name, w := range want {
+		if !reflect.DeepEqual(props[name], w) {
+			t.Errorf("%s: %v, want %v", name, props[name], w)
+		}
+	}
+	if !reflect.DeepEqual(s["required"], []string{"name", "email"}) {
+		t.Errorf("required %v", s["required"])
+	}
+
+	type Untagged struct {
+		Plain  string
+		hidden int
+	}
+	if s := b.structSchema(reflect.TypeOf(Untagged{})); len(s["properties"].(map[string]any)) != 1 || s["required"] != nil {
+		t.Errorf("untagged: %v", s)
+	}
+}
+
+func TestSchemaTypes(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{"", `{"type":"string"}`},
+		{true, `{"type":"boolean"}`},
+		{int8(0), `{"type":"integer"}`},
+		{uint64(0), `{"type":"integer"}`},
+		{1.5, `{"type":"number"}`},
+		{[]int{}, `{"items":{"type":"integer"},"type":"array"}`},
+		{[2]string{}, `{"items":{"type":"string"},"type":"array"}`},
+		{map[string]bool{}, `{"additionalProperties":{"type":"boolean"},"type":"object"}`},
+		{new(string), `{"nullable":true,"type":"string"}`},
+		{new(User), `{"allOf":[{"$ref":"#/components/schemas/User"}],"nullable":true}`},
+		{struct{ X int }{}, `{"properties":{"X":{"type":"integer"}},"type":"object"}`},
+		{new(any), `{"nullable":true}`},
+	}
+	for _, tt := range tests {
+		b := &schemaBuilder{components: map[string]any{}}
+		got, _ := json.Marshal(b.schema(reflect.TypeOf(tt.v)))
+		if string(got) != tt.want {
+			t.Errorf("%T: %s, want %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestRecursiveType(t *testing.T) {
+	b := &schemaBuilder{components: map[string]any{}}
+	b.schema(reflect.TypeOf(User{}))
+	user, _ := b.components["User"].(map[string]any)
+	if user == nil {
+		t.Fatal("User not in components")
+	}
+	props := user["properties"].(map[string]any)
+	manager, _ := json.Marshal(props["manager"])
+	if string(manager) != `{"allOf":[{"$ref":"#/components/schemas/User"}],"nullable":true}` {
+		t.Errorf("manager: %s", manager)
+	}
+	if _, ok := props["password"]; ok {
+		t.Error("unexported field documented")
+	}
+	if created, _ := json.Marshal(props["created_at"]); string(created) != `{"format":"date-time","type":"string"}` {
+		t.Errorf("created_at: %s", created)
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	tests := map[Route]string{
+		{Method: "GET", Path: "/api/users"}:                   "getApiUsers",
+		{Method: "DELETE", Path: "/api/users/{id}"}:           "deleteApiUsersId",
+		{Method: "POST", Path: "/api/users/{id}/roles/{rid}"}: "postApiUsersIdRolesRid",
+	}
+	for r, want := range tests {
+		if got := operationID(r); got != want {
+			t.Errorf("%s %s: %q, want %q", r.Method, r.Path, got, want)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	rt := usersAPI()
+	rt.Handle("GET", "/api/search", "Find <users> & more", 200, nil, nil, notImplemented)
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/api/openapi.json")
+	doc, _ := rt.GenerateOpenAPI()
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/json" || rec.Body.String() != string(doc) {
+		t.Errorf("openapi.json: %d %s", rec.Code, rec.Header())
+	}
+
+	rec = get("/api/docs")
+	body := rec.Body.String()
+	if rec.Code != 200 || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") || strings.Count(body, "<tr>") != 4 {
+		t.Errorf("docs: %d\n%s", rec.Code, body)
+	}
+	if !strings.Contains(body, "Find &lt;users&gt; &amp; more") {
+		t.Errorf("summary not escaped:\n%s", body)
+	}
+
+	// the registered handlers are reachable, with their error envelope
+	rec = get("/api/users/7")
+	var env ErrorEnvelope
+	if rec.Code != http.StatusNotImplemented || json.Unmarshal(rec.Body.Bytes(), &env) != nil || env.Error == "" {
+		t.Errorf("route: %d %s", rec.Code, rec.Body)
+	}
+}
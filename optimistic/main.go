@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Two people open the same user, both edit it, both save. Without a check the second save
+// silently throws away the first one (a lost update). Optimistic concurrency: every user
+// carries a version, a write says which version it was based on, and the store refuses the
+// write if the version moved on in the meantime. Nobody holds a lock while editing.
+
+var (
+	ErrNotFound        = errors.New("user not found")
+	ErrVersionMismatch = errors.New("version mismatch")
+)
+
+type User struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Version int    `json:"version"` // +1 on every successful update
+}
+
+type UserStore struct {
+	mu    sync.RWMutex
+	users map[int]User
+}
+
+func NewUserStore(users ...User) *UserStore {
+	s := &UserStore{users: make(map[int]User)}
+	for _, u := range users {
+		u.Version = 1
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *UserStore) Get(id int) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+// Update applies change to user id if its version is still expected (or expected is 0, "any version").
+// The compare and the swap happen under one write lock, so two updates based on the same
+// version can't both pass the check. On a mismatch the current user is returned with the error.
+func (s *UserStore) Update(id, expected int, change func(*User)) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	if expected != 0 && u.Version != expected {
+		return u, fmt.Errorf("user %d is at version %d, not %d: %w", id, u.Version, expected, ErrVersionMismatch)
+	}
+	version := u.Version
+	change(&u)
+	u.ID, u.Version = id, version+1 // change can't move the user or pick its version
+	s.users[id] = u
+	return u, nil
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+type Server struct {
+	store  *UserStore
+	Strict bool // writes without If-Match get 428 instead of "last write wins"
+}
+
+func etag(u User) string { return `"` + strconv.Itoa(u.Version) + `"` }
+
+// parseIfMatch reads If-Match: "3". It returns 0 for a missing header or "*", which match any version.
+func parseIfMatch(r *http.Request) (version int, present bool, err error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		return 0, false, nil
+	}
+	if h == "*" {
+		return 0, true, nil
+	}
+	unquoted, ok := strings.CutPrefix(h, `"`)
+	unquoted, ok2 := strings.CutSuffix(unquoted, `"`)
+	version, err = strconv.Atoi(unquoted)
+	if !ok || !ok2 || err != nil || version < 1 {
+		return 0, true, errors.New(`If-Match must be a quoted version, like "3"`)
+	}
+	return version, true, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	u, err := s.store.Get(id)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("ETag", etag(u)) // the client sends this back as If-Match
+	writeJSON(w, http.StatusOK, u)
+}
+
+// handleUpdate serves PUT (replace name and email) and PATCH (change only the fields sent)
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.PathValue("id"))
+	expected, present, err := parseIfMatch(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if !present && s.Strict {
+		writeJSON(w, http.StatusPreconditionRequired, map[string]string{"error": "send If-Match with the ETag from GET"})
+		return
+	}
+	var in struct {
+		Name  *string `json:"name"`
+		Email *string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	if r.Method == http.MethodPut && (in.Name == nil || in.Email == nil) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "PUT needs name and email, use PATCH for a partial update"})
+		return
+	}
+
+	u, err := s.store.Update(id, expected, func(u *User) {
+		if in.Name != nil {
+			u.Name = *in.Name
+		}
+		if in.Email != nil {
+			u.Email = *in.Email
+		}
+	})
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrVersionMismatch):
+		// the current version tells the client it has to GET again, merge, and retry
+		w.Header().Set("ETag", etag(u))
+		writeJSON(w, http.StatusPreconditionFailed, map[string]any{"error": "the user was changed by someone else", "current_version": u.Version})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+	default:
+		w.Header().Set("ETag", etag(u))
+		writeJSON(w, http.StatusOK, u)
+	}
+}
+
+func (s *Server) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users/{id}", s.handleGet)
+	mux.HandleFunc("PUT /api/users/{id}", s.handleUpdate)
+	mux.HandleFunc("PATCH /api/users/{id}", s.handleUpdate)
+	return mux
+}
+
+func main() {
+	fmt.Println("Learning optimistic concurrency with versions and ETags in Go")
+
+	store := NewUserStore(User{ID: 1, Name: "Rishabh", Email: "rishabh@example.com"})
+	srv := &Server{store: store}
+	h := srv.Routes()
+	do := func(method, target, ifMatch, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		fmt.Printf("  %-5s If-Match=%-5s -> %d ETag=%-5s %s", method, ifMatch, rec.Code, rec.Header().Get("ETag"), rec.Body.String())
+	}
+
+	fmt.Println("Read, then write with the version that was read:")
+	do("GET", "/api/users/1", "", "")
+	do("PATCH", "/api/users/1", `"1"`, `{"name":"Rishabh G"}`)
+	do("PUT", "/api/users/1", `"2"`, `{"name":"Rishabh","email":"r@example.com"}`)
+
+	fmt.Println("A write based on an old version:")
+	do("PATCH", "/api/users/1", `"1"`, `{"email":"stale@example.com"}`)
+	do("PATCH", "/api/users/1", `"3"`, `{"email":"fresh@example.com"}`)
+	do("PATCH", "/api/users/1", `*`, `{"name":"Anyone"}`)
+	do("PATCH", "/api/users/1", `abc`, `{"name":"Bad"}`)
+
+	fmt.Println("No If-Match, lenient then strict:")
+	do("PATCH", "/api/users/1", "", `{"name":"Last write wins"}`)
+	srv.Strict = true
+	do("PATCH", "/api/users/1", "", `{"name":"Refused"}`)
+
+	// The race: both goroutines read the same version, both try to write.
+	// With the check inside the lock exactly one of them succeeds, every time.
+	fmt.Println("Two updates racing from the same version (100 rounds):")
+	wins := map[int]int{}
+	for range 100 {
+		u, _ := store.Get(1)
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = store.Update(1, u.Version, func(u *User) { u.Name = fmt.Sprint("writer", i) })
+			}()
+		}
+		wg.Wait()
+		ok := 0
+		for _, err := range errs {
+			if err == nil {
+				ok++
+			}
+		}
+		wins[ok]++
+	}
+	fmt.Println("  rounds by number of successful writers:", wins)
+
+	// the same race without versions: both writes succeed and one of them is lost without a trace
+	u, _ := store.Get(1)
+	var wg sync.WaitGroup
+	for _, field := range []string{"name", "email"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stale := u // both read before either writes
+			if field == "name" {
+				stale.Name = "New Name"
+			} else {
+				stale.Email = "new@example.com"
+			}
+			store.Update(1, 0, func(cur *User) { *cur = stale }) // save the whole object, no check
+		}()
+	}
+	wg.Wait()
+	u, _ = store.Get(1)
+	fmt.Printf("Without a version check: name=%q email=%q (one of the two changes is gone)\n", u.Name, u.Email)
+}
+
+// Every write says which version it started from, the store refuses it when the version moved on.
+// Compare and write under the SAME lock, a check in the handler followed by a write is still a race.
+// 412 with the current version: the client refetches, merges its change and tries again.
+// The version doubles as the ETag, a client uses GET's ETag as the next If-Match.
+// 428 in strict mode makes clients opt in, otherwise a missing If-Match means "last write wins".
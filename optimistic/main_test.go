@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newStore() *UserStore {
+	return NewUserStore(User{ID: 1, Name: "Rishabh", Email: "rishabh@example.com"})
+}
+
+func do(h http.Handler, method, target, ifMatch, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdate(t *testing.T) {
+	s := newStore()
+	u, err := s.Update(1, 1, func(u *User) { u.Name = "A"; u.ID = 99; u.Version = 50 })
+	if err != nil || u.Name != "A" || u.ID != 1 || u.Version != 2 {
+		t.Errorf("change moved the user or picked the version: %+v %v", u, err)
+	}
+	s = newStore()
+	u, err = s.Update(1, 1, func(u *User) { u.Name = "A" })
+	if err != nil || u.Version != 2 {
+		t.Fatalf("Update: %+v %v", u, err)
+	}
+	cur, err := s.Update(1, 1, func(u *User) { u.Name = "stale" })
+	if !errors.Is(err, ErrVersionMismatch) || cur.Name != "A" || cur.Version != 2 {
+		t.Errorf("stale update: %+v %v", cur, err)
+	}
+	if u, err := s.Update(1, 0, func(u *User) { u.Name = "any" }); err != nil || u.Version != 3 {
+		t.Errorf("any version: %+v %v", u, err)
+	}
+	if _, err := s.Update(2, 1, func(*User) {}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing user: %v", err)
+	}
+	if got, _ := s.Get(1); got.Name != "any" || got.Version != 3 {
+		t.Errorf("stored %+v", got)
+	}
+}
+
+// many writers start from the same version: the check is inside the lock, so exactly one wins
+func TestRacingCAS(t *testing.T) {
+	const writers = 50
+	for round := range 20 {
+		s := newStore()
+		start := make(chan struct{})
+		wins := make([]bool, writers)
+		var wg sync.WaitGroup
+		for i := range writers {
+			wg.Go(func() {
+				<-start
+				_, err := s.Update(1, 1, func(u *User) { u.Name = fmt.Sprint("writer", i) })
+				if err != nil && !errors.Is(err, ErrVersionMismatch) {
+					t.Errorf("writer %d: %v", i, err)
+				}
+				wins[i] = err == nil
+			})
+		}
+		close(start)
+		wg.Wait()
+		winner := -1
+		for i, won := range wins {
+			if won {
+				if winner >= 0 {
+					t.Fatalf("round %d: writers %d and %d both succeeded", round, winner, i)
+				}
+				winner = i
+			}
+		}
+		u, _ := s.Get(1)
+		if winner < 0 || u.Version != 2 || u.Name != fmt.Sprint("writer", winner) {
+			t.Fatalf("round %d: winner %d, stored %+v", round, winner, u)
+		}
+	}
+}
+
+// read, change, write back, retry on a mismatch: no increment is ever lost
+func TestNoLostUpdates(t *testing.T) {
+	s := newStore()
+	const workers, each = 20, 50
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for range each {
+				for {
+					u, _ := s.Get(1)
+					n, _ := strconv.Atoi(u.Email)
+					_, err := s.Update(1, u.Version, func(u *User) { u.Email = strconv.Itoa(n + 1) })
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrVersionMismatch) {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+	// the seed email isn't a number, so the first update counts from 0
+	u, _ := s.Get(1)
+	if u.Email != strconv.Itoa(workers*each) || u.Version != 1+workers*each {
+		t.Errorf("counter %s at version %d, want %d", u.Email, u.Version, workers*each)
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header           string
+		version          int
+		present, invalid bool
+	}{
+		{"", 0, false, false},
+		{"*", 0, true, false},
+		{`"3"`, 3, true, false},
+		{` "12" `, 12, true, false},
+		{"3", 0, true, true},
+		{`"3`, 0, true, true},
+		{`"abc"`, 0, true, true},
+		{`"0"`, 0, true, true},
+		{`"-1"`, 0, true, true},
+		{`W/"3"`, 0, true, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("PATCH", "/", nil)
+		r.Header.Set("If-Match", tt.header)
+		v, present, err := parseIfMatch(r)
+		if v != tt.version || present != tt.present || (err != nil) != tt.invalid {
+			t.Errorf("%q: %d %v %v", tt.header, v, present, err)
+		}
+	}
+}
+
+func TestGetETag(t *testing.T) {
+	h := (&Server{store: newStore()}).Routes()
+	do(h, "PATCH", "/api/users/1", `"1"`, `{"name":"B"}`)
+	rec := do(h, "GET", "/api/users/1", "", "")
+	var u User
+	json.Unmarshal(rec.Body.Bytes(), &u)
+	if rec.Code != 200 || u.Version != 2 || rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("GET: %d %s %s", rec.Code, rec.Header().Get("ETag"), rec.Body)
+	}
+	if rec := do(h, "GET", "/api/users/9", "", ""); rec.Code != 404 || rec.Header().Get("ETag") != "" {
+		t.Errorf("missing: %d %s", rec.Code, rec.Header())
+	}
+}
+
+func TestHandleUpdate(t *testing.T) {
+	tests := []struct {
+		name, method, ifMatch, body string
+		strict                      bool
+		code                        int
+		etag                        string
+	}{
+		{"patch", "PATCH", `"1"`, `{"name":"B"}`, false, 200, `"2"`},
+		{"put", "PUT", `"1"`, `{"name":"B","email":"b@example.com"}`, false, 200, `"2"`},
+		{"put, partial", "PUT", `"1"`, `{"name":"B"}`, false, 400, ""},
+		{"stale", "PATCH", `"7"`, `{"name":"B"}`, false, 412, `"1"`},
+		{"any", "PATCH", "*", `{"name":"B"}`, true, 200, `"2"`},
+		{"bad header", "PATCH", "1", `{"name":"B"}`, false, 400, ""},
+		{"bad body", "PATCH", `"1"`, `{name`, false, 400, ""},
+		{"lenient, no header", "PATCH", "", `{"name":"B"}`, false, 200, `"2"`},
+		{"strict, no header", "PATCH", "", `{"name":"B"}`, true, 428, ""},
+	}
+	for _, tt := range tests {
+		h := (&Server{store: newStore(), Strict: tt.strict}).Routes()
+		rec := do(h, tt.method, "/api/users/1", tt.ifMatch, tt.body)
+		if rec.Code != tt.code || rec.Header().Get("ETag") != tt.etag {
+			t.Errorf("%s: %d ETag %q %s, want %d %q", tt.name, rec.Code, rec.Header().Get("ETag"), rec.Body, tt.code, tt.etag)
+		}
+	}
+
+	h := (&Server{store: newStore()}).Routes()
+	if rec := do(h, "PATCH", "/api/users/9", `"1"`, `{"name":"B"}`); rec.Code != 404 {
+		t.Errorf("missing user: %d", rec.Code)
+	}
+	do(h, "PATCH", "/api/users/1", `"1"`, `{"email":"new@example.com"}`)
+	rec := do(h, "GET", "/api/users/1", "", "")
+	if !strings.Contains(rec.Body.String(), `"name":"Rishabh"`) {
+		t.Errorf("PATCH of the email changed the name: %s", rec.Body)
+	}
+}
+
+// a 412 carries what the client needs to refetch and retry
+func TestPreconditionFailedBody(t *testing.T) {
+	h := (&Server{store: newStore()}).Routes()
+	do(h, "PATCH", "/api/users/1", `"1"`, `{"name":"first"}`)
+	rec := do(h, "PATCH", "/api/users/1", `"1"`, `{"name":"second"}`)
+	var body struct {
+		Error          string `json:"error"`
+		CurrentVersion int    `json:"current_version"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != 412 || body.CurrentVersion != 2 || body.Error == "" {
+		t.Fatalf("412: %d %s", rec.Code, rec.Body)
+	}
+	rec = do(h, "PATCH", "/api/users/1", rec.Header().Get("ETag"), `{"name":"second"}`)
+	if rec.Code != 200 || !strings.Contains(rec.Body.String(), `"version":3`) {
+		t.Errorf("retry with the new ETag: %d %s", rec.Code, rec.Body)
+	}
+}
+
+// the same race through HTTP: every client PATCHes with the ETag from one GET
+func TestRacingRequests(t *testing.T) {
+	h := (&Server{store: newStore(), Strict: true}).Routes()
+	etag := do(h, "GET", "/api/users/1", "", "").Header().Get("ETag")
+	const clients = 20
+	codes := make([]int, clients)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Go(func() {
+			codes[i] = do(h, "PATCH", "/api/users/1", etag, fmt.Sprintf(`{"name":"client%d"}`, i)).Code
+		})
+	}
+	wg.Wait()
+	count := map[int]int{}
+	for _, c := range codes {
+		count[c]++
+	}
+	if count[200] != 1 || count[412] != clients-1 {
+		t.Errorf("status counts %v", count)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// A PATCH body only has the fields the client wants to change:
+//
+//	{"age": 0}          set age to 0
+//	{"nickname": null}  clear the nickname
+//	{}                  change nothing
+//
+// With a plain int field, "age": 0 and a missing "age" both decode to 0.
+// That's three states (absent, null, value) and a normal Go field only has one.
+
+// ----------------------------------------------------------------------------
+// Optional[T]
+
+// Optional holds a T that may be absent, explicitly null, or set
+type Optional[T any] struct {
+	value T
+	set   bool // the field was in the JSON (as a value or as null)
+	null  bool
+}
+
+func Some[T any](v T) Optional[T] { return Optional[T]{value: v, set: true} }
+
+func Null[T any]() Optional[T] { return Optional[T]{set: true, null: true} }
+
+func (o *Optional[T]) Set(v T) { *o = Some(v) }
+
+func (o *Optional[T]) SetNull() { *o = Null[T]() }
+
+// Get returns the value and whether there is one (false when absent or null)
+func (o Optional[T]) Get() (T, bool) { return o.value, o.set && !o.null }
+
+func (o Optional[T]) IsSet() bool  { return o.set }
+func (o Optional[T]) IsNull() bool { return o.set && o.null }
+
+// IsZero makes `json:",omitzero"` (Go 1.24) leave out an absent field when marshaling
+func (o Optional[T]) IsZero() bool { return !o.set }
+
+// UnmarshalJSON is only called when the key is in the JSON, that's how absent stays unset
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		o.SetNull()
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Set(v)
+	return nil
+}
+
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if v, ok := o.Get(); ok {
+		return json.Marshal(v)
+	}
+	return []byte("null"), nil
+}
+
+func (o Optional[T]) String() string {
+	switch {
+	case !o.set:
+		return "absent"
+	case o.null:
+		return "null"
+	}
+	return fmt.Sprintf("%v", o.value)
+}
+
+// ----------------------------------------------------------------------------
+// The other two ways, for comparison
+
+// pointers: nil means "not there", but absent and null both give nil
+type pointerUpdate struct {
+	Age      *int    `json:"age"`
+	Nickname *string `json:"nickname"`
+}
+
+// sql.NullString-style: made for database columns. Valid=false covers both absent and null,
+// and encoding/json doesn't know the type, so it marshals as {"String":"","Valid":false}.
+type nullUpdate struct {
+	Age      sql.NullInt64  `json:"age"`
+	Nickname sql.NullString `json:"nickname"`
+}
+
+// ----------------------------------------------------------------------------
+// The PATCH endpoint
+
+var ErrInvalid = errors.New("invalid update")
+
+type User struct {
+	ID       int     `json:"id"`
+	Name     string  `json:"name"`
+	Age      int     `json:"age"`
+	Nickname *string `json:"nickname"` // null in JSON when there is none
+}
+
+type UpdateUserRequest struct {
+	Name     Optional[string] `json:"name,omitzero"`
+	Age      Optional[int]    `json:"age,omitzero"`
+	Nickname Optional[string] `json:"nickname,omitzero"`
+}
+
+// Apply changes only the fields that were sent
+func (req UpdateUserRequest) Apply(u *User) error {
+	if req.Name.IsNull() {
+		return fmt.Errorf("%w: name can't be null", ErrInvalid)
+	}
+	if req.Age.IsNull() {
+		return fmt.Errorf("%w: age can't be null", ErrInvalid)
+	}
+	if name, ok := req.Name.Get(); ok {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("%w: name can't be empty", ErrInvalid)
+		}
+		u.Name = name
+	}
+	if age, ok := req.Age.Get(); ok {
+		if age < 0 {
+			return fmt.Errorf("%w: age can't be negative", ErrInvalid)
+		}
+		u.Age = age
+	}
+	if req.Nickname.IsNull() {
+		u.Nickname = nil
+	} else if nick, ok := req.Nickname.Get(); ok {
+		u.Nickname = &nick
+	}
+	return nil
+}
+
+type UserStore struct {
+	mu    sync.Mutex
+	users map[int]User
+}
+
+func (s *UserStore) Update(id int, req UpdateUserRequest) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, fmt.Errorf("user %d not found", id)
+	}
+	// apply to a copy, so a failed update changes nothing
+	if err := req.Apply(&u); err != nil {
+		return User{}, err
+	}
+	s.users[id] = u
+	return u, nil
+}
+
+func handlePatchUser(s *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var id int
+		if _, err := fmt.Sscan(r.PathValue("id"), &id); err != nil {
+			http.Error(w, "bad id", http.StatusBadRequest)
+			return
+		}
+		var req UpdateUserRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			http.Error(w, "bad body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := s.Update(id, req)
+		if errors.Is(err, ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(u)
+	}
+}
+
+func main() {
+	fmt.Println("Learning optional fields in Go")
+
+	bodies := []string{`{}`, `{"age": 0, "nickname": null}`, `{"age": 31, "nickname": "rg"}`}
+
+	fmt.Println("Optional[T] sees all three states:")
+	for _, b := range bodies {
+		var req UpdateUserRequest
+		json.Unmarshal([]byte(b), &req)
+		fmt.Printf("  %-32s age=%-6s nickname=%s\n", b, req.Age, req.Nickname)
+	}
+
+	fmt.Println("*T pointers can't tell absent from null:")
+	for _, b := range bodies {
+		var req pointerUpdate
+		json.Unmarshal([]byte(b), &req)
+		nick := "nil"
+		if req.Nickname != nil {
+			nick = *req.Nickname
+		}
+		age := "nil"
+		if req.Age != nil {
+			age = fmt.Sprint(*req.Age)
+		}
+		fmt.Printf("  %-32s age=%-6s nickname=%s\n", b, age, nick)
+	}
+
+	fmt.Println("sql.Null types don't even decode from plain JSON:")
+	var nu nullUpdate
+	err := json.Unmarshal([]byte(bodies[2]), &nu)
+	fmt.Println("  unmarshal:", err)
+	data, _ := json.Marshal(nullUpdate{Nickname: sql.NullString{String: "rg", Valid: true}})
+	fmt.Println("  marshal:  ", string(data))
+
+	// Optional marshals back the way it came in
+	var req UpdateUserRequest
+	req.Age.Set(0)
+	req.Nickname.SetNull()
+	data, _ = json.Marshal(req)
+	fmt.Println("Marshal Optional, name absent:", string(data))
+
+	fmt.Println("PATCH /users/1:")
+	nick := "rishi"
+	store := &UserStore{users: map[int]User{1: {ID: 1, Name: "Rishabh", Age: 23, Nickname: &nick}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /users/{id}", handlePatchUser(store))
+	for _, b := range []string{
+		`{}`,
+		`{"age": 0}`,
+		`{"nickname": null}`,
+		`{"name": "Rishabh Gupta", "nickname": "rg"}`,
+		`{"name": null}`,
+		`{"age": -1, "name": "X"}`,
+		`{"age": "old"}`,
+		`{"email": "a@b.c"}`,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/users/1", strings.NewReader(b)))
+		fmt.Printf("  %-44s -> %d %s", b, rec.Code, rec.Body.String())
+	}
+}
+
+// A plain field can't tell "not sent" from "sent as the zero value", a PATCH needs to.
+// *T gives two states (nil or a value): enough for "not sent", not for "set to null".
+// sql.NullString is for database columns, it has no JSON methods and also has only two states.
+// Optional[T] gets three: UnmarshalJSON is never called for a missing key, and "null" is seen as is.
+// Use omitzero with an IsZero method to leave absent fields out when marshaling.
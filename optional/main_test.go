@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// state is what every Optional[T] has, whatever T is
+type state interface {
+	IsSet() bool
+	IsNull() bool
+	String() string
+}
+
+func TestOptionalStates(t *testing.T) {
+	fields := map[string]func(UpdateUserRequest) state{
+		"name":     func(r UpdateUserRequest) state { return r.Name },
+		"age":      func(r UpdateUserRequest) state { return r.Age },
+		"nickname": func(r UpdateUserRequest) state { return r.Nickname },
+	}
+	tests := []struct {
+		field, body string
+		set, null   bool
+		str         string
+	}{
+		{"name", `{}`, false, false, "absent"},
+		{"name", `{"name":null}`, true, true, "null"},
+		{"name", `{"name":""}`, true, false, ""},
+		{"age", `{}`, false, false, "absent"},
+		{"age", `{"age":null}`, true, true, "null"},
+		{"age", `{"age":0}`, true, false, "0"},
+		{"nickname", `{"age":3}`, false, false, "absent"},
+		{"nickname", `{"nickname":null}`, true, true, "null"},
+		{"nickname", `{"nickname":"rg"}`, true, false, "rg"},
+	}
+	for _, tt := range tests {
+		var req UpdateUserRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		f := fields[tt.field](req)
+		if f.IsSet() != tt.set || f.IsNull() != tt.null || f.String() != tt.str {
+			t.Errorf("%s in %s: set=%v null=%v %q, want set=%v null=%v %q",
+				tt.field, tt.body, f.IsSet(), f.IsNull(), f.String(), tt.set, tt.null, tt.str)
+		}
+	}
+}
+
+func TestOptionalGet(t *testing.T) {
+	var o Optional[int]
+	if v, ok := o.Get(); ok || v != 0 || !o.IsZero() {
+		t.Errorf("absent: %v %v", v, ok)
+	}
+	o.Set(0)
+	if v, ok := o.Get(); !ok || v != 0 || o.IsZero() {
+		t.Errorf("set to 0: %v %v", v, ok)
+	}
+	o.SetNull()
+	if _, ok := o.Get(); ok || !o.IsNull() || o.IsZero() {
+		t.Errorf("null: %v", o)
+	}
+	o.Set(5) // setting after null clears the null
+	if v, ok := o.Get(); !ok || v != 5 || o.IsNull() {
+		t.Errorf("set after null: %v %v", v, ok)
+	}
+}
+
+func TestOptionalBadJSON(t *testing.T) {
+	for _, body := range []string{`{"age":"old"}`, `{"age":1.5}`, `{"name":3}`} {
+		var req UpdateUserRequest
+		if err := json.Unmarshal([]byte(body), &req); err == nil {
+			t.Errorf("%s: no error", body)
+		}
+	}
+}
+
+func TestOptionalMarshal(t *testing.T) {
+	for _, body := range []string{`{}`, `{"age":0}`, `{"name":"x","nickname":null}`, `{"name":"","age":31,"nickname":"rg"}`} {
+		var req UpdateUserRequest
+		if err := json.Unmarshal([]byte(body), &req); err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(req)
+		if err != nil || string(data) != body {
+			t.Errorf("round trip of %s: %s, %v", body, data, err)
+		}
+	}
+	// outside a struct with omitzero, absent has to be written as something
+	if data, _ := json.Marshal([]Optional[int]{{}, Null[int](), Some(2)}); string(data) != "[null,null,2]" {
+		t.Errorf("slice: %s", data)
+	}
+}
+
+// the other two ways lose information: that's the point of the lesson
+func TestPointersAndNullTypes(t *testing.T) {
+	var absent, null pointerUpdate
+	json.Unmarshal([]byte(`{}`), &absent)
+	json.Unmarshal([]byte(`{"nickname":null}`), &null)
+	if absent != null {
+		t.Errorf("pointers told absent from null: %+v %+v", absent, null)
+	}
+	var nu nullUpdate
+	if err := json.Unmarshal([]byte(`{"nickname":"rg"}`), &nu); err == nil {
+		t.Error("sql.NullString decoded a plain JSON string")
+	}
+}
+
+func ptr(s string) *string { return &s }
+
+func TestApply(t *testing.T) {
+	base := User{ID: 1, Name: "Rishabh", Age: 23, Nickname: ptr("rishi")}
+	tests := []struct {
+		body    string
+		want    User
+		wantErr bool
+	}{
+		{`{}`, base, false},
+		{`{"age":0}`, User{ID: 1, Name: "Rishabh", Age: 0, Nickname: ptr("rishi")}, false},
+		{`{"nickname":null}`, User{ID: 1, Name: "Rishabh", Age: 23}, false},
+		{`{"nickname":""}`, User{ID: 1, Name: "Rishabh", Age: 23, Nickname: ptr("")}, false},
+		{`{"name":"RG","age":31,"nickname":"rg"}`, User{ID: 1, Name: "RG", Age: 31, Nickname: ptr("rg")}, false},
+		{`{"name":null}`, base, true},
+		{`{"age":null}`, base, true},
+		{`{"name":"  "}`, base, true},
+		{`{"age":-1}`, base, true},
+		{`{"age":40,"name":""}`, base, true},
+	}
+	for _, tt := range tests {
+		var req UpdateUserRequest
+		if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+			t.Fatal(err)
+		}
+		store := &UserStore{users: map[int]User{1: base}}
+		got, err := store.Update(1, req)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("%s: err %v, want ErrInvalid", tt.body, err)
+			}
+			got = store.users[1] // a failed update changes nothing
+		} else if err != nil {
+			t.Errorf("%s: %v", tt.body, err)
+		}
+		if !sameUser(got, tt.want) || !sameUser(store.users[1], tt.want) {
+			t.Errorf("%s: got %+v, stored %+v, want %+v", tt.body, got, store.users[1], tt.want)
+		}
+	}
+}
+
+func sameUser(a, b User) bool {
+	if (a.Nickname == nil) != (b.Nickname == nil) || (a.Nickname != nil && *a.Nickname != *b.Nickname) {
+		return false
+	}
+	a.Nickname, b.Nickname = nil, nil
+	return a == b
+}
+
+func TestPatchHandler(t *testing.T) {
+	store := &UserStore{users: map[int]User{1: {ID: 1, Name: "Rishabh", Age: 23, Nickname: ptr("rishi")}}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PATCH /users/{id}", handlePatchUser(store))
+	tests := []struct {
+		path, body string
+		status     int
+		want       string
+	}{
+		{"/users/1", `{}`, 200, `{"id":1,"name":"Rishabh","age":23,"nickname":"rishi"}`},
+		{"/users/1", `{"age":0}`, 200, `{"id":1,"name":"Rishabh","age":0,"nickname":"rishi"}`},
+		{"/users/1", `{"nickname":null}`, 200, `{"id":1,"name":"Rishabh","age":0,"nickname":null}`},
+		{"/users/1", `{"name":"RG","nickname":"rg"}`, 200, `{"id":1,"name":"RG","age":0,"nickname":"rg"}`},
+		{"/users/1", `{"name":null}`, 400, "invalid update: name can't be null"},
+		{"/users/1", `{"age":"old"}`, 400, "bad body"},
+		{"/users/1", `{"email":"a@b.c"}`, 400, `unknown field "email"`},
+		{"/users/x", `{}`, 400, "bad id"},
+		{"/users/2", `{}`, 404, "user 2 not found"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("PATCH %s %s: %d %s, want %d %s", tt.path, tt.body, rec.Code, rec.Body, tt.status, tt.want)
+		}
+	}
+}
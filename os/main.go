@@ -1,17 +1,185 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// maxLineLength is the longest line ReadFileLines accepts.
+// bufio.Scanner's default limit is 64KB and it fails with "token too long" above that.
+const maxLineLength = 10 * 1024 * 1024
+
+// WriteFileString creates (or truncates) path and writes content to it
+func WriteFileString(path, content string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	// Close can fail too (data is flushed on close), so its error matters for writes
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// AppendLine adds line plus a newline at the end of path, creating the file if needed.
+// O_APPEND makes every write go to the current end of the file,
+// O_CREATE creates it if missing and O_WRONLY opens it for writing only.
+func AppendLine(path, line string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("append %s: %w", path, err)
+	}
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("append %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("append %s: %w", path, err)
+	}
+	return nil
+}
+
+// ReadFileLines returns every line of path without the trailing newlines
+func ReadFileLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	defer file.Close() // read only, so the Close error can be ignored
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength) // start at 64KB, grow up to 10MB
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return lines, nil
+}
+
+// FileExists reports whether path exists. Errors other than "not found"
+// (like permission denied) are returned instead of being treated as "missing".
+func FileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return false, fmt.Errorf("stat %s: %w", path, err)
+}
+
+// FileSize returns the size of path in bytes
+func FileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("size %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return 0, fmt.Errorf("size %s: is a directory", path)
+	}
+	return info.Size(), nil
+}
+
 func main() {
-	// Read write and create package
-	file, err := os.Create("user.txt")
+	fmt.Println("Learning the os package: create, write, append and read files")
+
+	// work in a temporary directory so the demo never leaves files behind
+	dir, err := os.MkdirTemp("", "os-demo-*")
 	if err != nil {
-		fmt.Println("Error while creating file", err.Error())
+		fmt.Println("Error while creating temp dir:", err)
+		return
+	}
+	defer os.RemoveAll(dir) // clean up everything at the end
+	path := filepath.Join(dir, "user.txt")
+
+	// create and write
+	if err := WriteFileString(path, "This is my first file using OS package\n"); err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
-	defer file.Close()
-	file.WriteString("This is my first file using OS package")
 	fmt.Println("File created succesfully!")
+
+	// append
+	for _, line := range []string{"Name: Rishabh Gupta", "Language: Go"} {
+		if err := AppendLine(path, line); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+	}
+
+	// read back
+	lines, err := ReadFileLines(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for i, line := range lines {
+		fmt.Printf("%d: %s\n", i+1, line)
+	}
+
+	exists, err := FileExists(path)
+	fmt.Println("Exists:", exists, err)
+	size, err := FileSize(path)
+	fmt.Println("Size in bytes:", size, err)
+
+	// missing file: the wrapped error still matches fs.ErrNotExist
+	missing := filepath.Join(dir, "missing.txt")
+	if _, err := ReadFileLines(missing); errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("Missing file:", err)
+	}
+	exists, err = FileExists(missing)
+	fmt.Println("Missing exists:", exists, err)
+
+	// very long line (1MB), more than the scanner's default 64KB limit
+	longPath := filepath.Join(dir, "long.txt")
+	if err := WriteFileString(longPath, strings.Repeat("x", 1024*1024)+"\nshort\n"); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	longLines, err := ReadFileLines(longPath)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Long file lines:", len(longLines), "first line length:", len(longLines[0]))
+
+	// permission error: a read-only directory
+	// (root ignores permissions, so this only fails for normal users)
+	readOnly := filepath.Join(dir, "readonly")
+	if err := os.Mkdir(readOnly, 0o555); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	err = WriteFileString(filepath.Join(readOnly, "nope.txt"), "data")
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		fmt.Println("Permission error:", err)
+	case err != nil:
+		fmt.Println("Error:", err)
+	default:
+		fmt.Println("Write in read-only dir succeeded (running as root?)")
+	}
+	os.Chmod(readOnly, 0o755) // so RemoveAll can clean it up
 }
+
+// os.Create      -> create or truncate a file for writing
+// os.Open        -> open for reading only
+// os.OpenFile    -> full control with flags (O_APPEND, O_CREATE, O_WRONLY, O_RDWR, O_TRUNC, O_EXCL) and permissions
+// os.ReadFile / os.WriteFile -> whole file at once, fine for small files
+// bufio.Scanner  -> line by line, memory friendly for big files
+// Always check errors, and wrap them with the path: fmt.Errorf("read %s: %w", path, err)
+// %w keeps the original error so errors.Is(err, fs.ErrNotExist) still works
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWriteAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "user.txt")
+	if err := WriteFileString(path, "first\n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"second", "third"} {
+		if err := AppendLine(path, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines, err := ReadFileLines(path)
+	if want := []string{"first", "second", "third"}; err != nil || !slices.Equal(lines, want) {
+		t.Fatalf("ReadFileLines = %q, %v; want %q", lines, err, want)
+	}
+
+	// WriteFileString truncates
+	if err := WriteFileString(path, "only"); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := ReadFileLines(path); !slices.Equal(lines, []string{"only"}) {
+		t.Errorf("after rewriting: %q", lines)
+	}
+	if size, err := FileSize(path); err != nil || size != 4 {
+		t.Errorf("FileSize = %d, %v; want 4", size, err)
+	}
+
+	// AppendLine creates a missing file
+	created := filepath.Join(filepath.Dir(path), "log.txt")
+	if err := AppendLine(created, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := FileExists(created); !exists || err != nil {
+		t.Errorf("FileExists after AppendLine = %v, %v", exists, err)
+	}
+}
+
+func TestMissingFiles(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := ReadFileLines(missing); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("ReadFileLines: %v, want a wrapped fs.ErrNotExist naming the path", err)
+	}
+	if _, err := FileSize(missing); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("FileSize: %v, want fs.ErrNotExist", err)
+	}
+	if exists, err := FileExists(missing); exists || err != nil {
+		t.Errorf("FileExists = %v, %v; want false, nil", exists, err)
+	}
+	if _, err := FileSize(t.TempDir()); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("FileSize of a directory: %v", err)
+	}
+}
+
+func TestNotADirectory(t *testing.T) {
+	// a regular file used as a directory fails for root too
+	file := filepath.Join(t.TempDir(), "file")
+	if err := WriteFileString(file, "x"); err != nil {
+		t.Fatal(err)
+	}
+	inside := filepath.Join(file, "child.txt")
+	if err := WriteFileString(inside, "data"); err == nil || !strings.Contains(err.Error(), inside) {
+		t.Errorf("WriteFileString: %v", err)
+	}
+	if err := AppendLine(inside, "data"); err == nil || !strings.Contains(err.Error(), inside) {
+		t.Errorf("AppendLine: %v", err)
+	}
+	if exists, err := FileExists(inside); exists || err == nil {
+		t.Errorf("FileExists = %v, %v; an ENOTDIR is an error, not \"missing\"", exists, err)
+	}
+}
+
+func TestReadOnlyDirectory(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root ignores directory permissions")
+	}
+	dir := filepath.Join(t.TempDir(), "readonly")
+	if err := os.Mkdir(dir, 0o555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+	path := filepath.Join(dir, "nope.txt")
+	if err := WriteFileString(path, "data"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("WriteFileString: %v, want fs.ErrPermission", err)
+	}
+	if err := AppendLine(path, "data"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("AppendLine: %v, want fs.ErrPermission", err)
+	}
+}
+
+func TestReadFileLinesLongLines(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("x", 1<<20) // well over the scanner's default 64KB
+	path := filepath.Join(dir, "long.txt")
+	if err := WriteFileString(path, long+"\nshort\n"); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := ReadFileLines(path)
+	if err != nil || len(lines) != 2 || lines[0] != long || lines[1] != "short" {
+		t.Fatalf("got %d lines, %v", len(lines), err)
+	}
+
+	tooLong := filepath.Join(dir, "too-long.txt")
+	if err := WriteFileString(tooLong, strings.Repeat("y", maxLineLength+1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFileLines(tooLong); err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("a line over maxLineLength: %v, want a token too long error", err)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// "Save the user, then publish user.created" has a gap: crash between the two and the
+// event is lost forever, publish first and a failed save leaves an event about nothing.
+// The transactional outbox closes the gap: the event is written in the SAME locked section
+// as the change itself, into an outbox. A separate relay reads the outbox in order,
+// publishes, and only then marks the event as published. A relay that dies simply starts
+// again from the first unmarked event.
+//
+// That gives at-least-once delivery: a crash after publishing but before marking sends that
+// event again. Consumers make it effectively once by remembering the last Seq they applied.
+//
+// The outbox file is one JSON record per line, like the kvstore lesson's log (without its checksums):
+//
+//	{"op":"event","event":{"seq":1,"type":"user.created","user_id":1,"name":"Rishabh"}}
+//	{"op":"published","seq":1}
+
+var (
+	ErrOutOfOrder = errors.New("events must be marked published in order")
+	ErrClosed     = errors.New("outbox closed")
+)
+
+type Event struct {
+	Seq    int64  `json:"seq"` // gap-free and increasing, in commit order
+	Type   string `json:"type"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name,omitempty"`
+}
+
+type record struct {
+	Op    string `json:"op"` // "event" or "published"
+	Event *Event `json:"event,omitempty"`
+	Seq   int64  `json:"seq,omitempty"`
+}
+
+// logFile is the part of *os.File the outbox uses, so a test can make Sync fail
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+type Outbox struct {
+	mu        sync.Mutex
+	events    []Event // every event since the file was created, events[i].Seq == i+1
+	published int64   // highest Seq marked published
+	log       logFile
+	size      int64 // bytes of complete records in the file
+}
+
+// OpenOutbox replays the file at path, creating it if needed.
+// A last line without its newline is a write cut short by a crash: it was never
+// acknowledged, so it's dropped. Any other line that doesn't parse is an error.
+func OpenOutbox(path string) (*Outbox, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open outbox: %w", err)
+	}
+	o := &Outbox{log: f}
+	br := bufio.NewReader(f) // not a Scanner, a long name would go past its 64 KiB line limit
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			// cut the torn line off, or the next record would be glued onto it
+			if len(b) > 0 {
+				if err := f.Truncate(o.size); err != nil {
+					f.Close()
+					return nil, fmt.Errorf("open outbox: dropping the torn last line: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("open outbox: %w", err)
+		}
+		var r record
+		if err := json.Unmarshal(b, &r); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("open outbox: line %d: %w", line, err)
+		}
+		switch {
+		case r.Op == "event" && r.Event != nil:
+			o.events = append(o.events, *r.Event)
+		case r.Op == "published":
+			o.published = r.Seq
+		}
+		o.size += int64(len(b))
+	}
+	return o, nil
+}
+
+// write appends r and fsyncs it. When either fails the record may be in the file anyway,
+// so it's cut off again: left there, a retried Append would store its Seq a second time.
+func (o *Outbox) write(r record) error {
+	if o.log == nil {
+		return ErrClosed
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	_, err = o.log.Write(line)
+	if err == nil {
+		err = o.log.Sync() // on disk before the caller's change counts as done
+	}
+	if err != nil {
+		if terr := o.log.Truncate(o.size); terr != nil {
+			// the file can't be put back: stop writing rather than replay a record nobody got
+			o.log.Close()
+			o.log = nil
+			return errors.Join(err, terr)
+		}
+		return err
+	}
+	o.size += int64(len(line))
+	return nil
+}
+
+// Append gives e the next Seq and stores it. UserStore calls it while holding its own lock,
+// so Seq order is exactly the order the changes were made in.
+func (o *Outbox) Append(e Event) (Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	e.Seq = int64(len(o.events)) + 1
+	if err := o.write(record{Op: "event", Event: &e}); err != nil {
+		return Event{}, fmt.Errorf("append %s: %w", e.Type, err)
+	}
+	o.events = append(o.events, e)
+	return e, nil
+}
+
+// Pending returns up to limit unpublished events, oldest first
+func (o *Outbox) Pending(limit int) []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	rest := o.events[o.published:]
+	return append([]Event(nil), rest[:min(limit, len(rest))]...)
+}
+
+// MarkPublished accepts only the next Seq: a relay can't skip an event or mark one twice
+func (o *Outbox) MarkPublished(seq int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if seq != o.published+1 {
+		return fmt.Errorf("mark %d after %d: %w", seq, o.published, ErrOutOfOrder)
+	}
+	if err := o.write(record{Op: "published", Seq: seq}); err != nil {
+		return fmt.Errorf("mark %d: %w", seq, err)
+	}
+	o.published = seq
+	return nil
+}
+
+func (o *Outbox) Unpublished() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events) - int(o.published)
+}
+
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.log == nil {
+		return nil
+	}
+	err := o.log.Close()
+	o.log = nil
+	return err
+}
+
+// ----------------------------------------------------------------------------
+// The store writes the change and its event under one lock
+
+type User struct {
+	ID   int
+	Name string
+}
+
+type UserStore struct {
+	mu     sync.Mutex
+	users  map[int]User
+	nextID int
+	outbox *Outbox
+}
+
+// NewUserStore rebuilds the users from the outbox's events. A real store keeps its own
+// data (see the kvstore lesson), here the outbox already holds every change.
+func NewUserStore(outbox *Outbox) *UserStore {
+	s := &UserStore{users: map[int]User{}, nextID: 1, outbox: outbox}
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	for _, e := range outbox.events {
+		switch e.Type {
+		case "user.created", "user.updated":
+			s.users[e.UserID] = User{ID: e.UserID, Name: e.Name}
+		case "user.deleted":
+			delete(s.users, e.UserID)
+		}
+		s.nextID = max(s.nextID, e.UserID+1)
+	}
+	return s
+}
+
+// Create appends the event first: if that fails the user is not created either
+func (s *UserStore) Create(name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := User{ID: s.nextID, Name: name}
+	if _, err := s.outbox.Append(Event{Type: "user.created", UserID: u.ID, Name: name}); err != nil {
+		return User{}, err
+	}
+	s.nextID++
+	s.users[u.ID] = u
+	return u, nil
+}
+
+func (s *UserStore) Rename(id int, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("rename user %d: not found", id)
+	}
+	if _, err := s.outbox.Append(Event{Type: "user.updated", UserID: id, Name: name}); err != nil {
+		return err
+	}
+	s.users[id] = User{ID: id, Name: name}
+	return nil
+}
+
+func (s *UserStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return nil
+	}
+	if _, err := s.outbox.Append(Event{Type: "user.deleted", UserID: id}); err != nil {
+		return err
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Relay
+
+// Relay moves events from the outbox to publish, one at a time and in order.
+// If publish fails the event stays unmarked and is tried again on the next tick.
+func Relay(ctx context.Context, o *Outbox, every time.Duration, publish func(Event) error) error {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		for _, e := range o.Pending(50) {
+			if err := publish(e); err != nil {
+				break // keep the order: nothing after a failed event goes out before it
+			}
+			if ctx.Err() != nil {
+				return ctx.Err() // killed after publishing, before marking: e goes out again later
+			}
+			if err := o.MarkPublished(e.Seq); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Consumer is idempotent: it remembers the last Seq it applied and skips anything older
+type Consumer struct {
+	mu        sync.Mutex
+	lastSeq   int64
+	received  int
+	applied   []Event
+	outOfSeq  int
+	names     map[int]string
+	failUntil time.Time // pretend to be down until then
+}
+
+func (c *Consumer) Handle(e Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.failUntil) {
+		return errors.New("consumer unavailable")
+	}
+	c.received++
+	if e.Seq <= c.lastSeq {
+		return nil // a redelivery: already applied, acknowledge and move on
+	}
+	if e.Seq != c.lastSeq+1 {
+		c.outOfSeq++ // never happens with one in-order relay
+	}
+	c.lastSeq = e.Seq
+	c.applied = append(c.applied, e)
+	switch e.Type {
+	case "user.created", "user.updated":
+		c.names[e.UserID] = e.Name
+	case "user.deleted":
+		delete(c.names, e.UserID)
+	}
+	return nil
+}
+
+func waitUntil(cond func() bool) {
+	for !cond() {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func main() {
+	fmt.Println("Learning the transactional outbox pattern in Go")
+
+	dir, err := os.MkdirTemp("", "outbox")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "outbox.log")
+
+	outbox, err := OpenOutbox(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	store := NewUserStore(outbox)
+	consumer := &Consumer{names: map[int]string{}}
+
+	// the relay gets killed right after publishing event 8, before it can mark it
+	ctx, kill := context.WithCancel(context.Background())
+	relayDone := make(chan error)
+	go func() {
+		relayDone <- Relay(ctx, outbox, 2*time.Millisecond, func(e Event) error {
+			err := consumer.Handle(e)
+			if e.Seq == 8 {
+				kill()
+			}
+			return err
+		})
+	}()
+
+	names := []string{"Rishabh", "Sanchay", "Alice", "Bob", "Maya", "Omar", "Priya", "Leo"}
+	for i, n := range names {
+		u, _ := store.Create(n)
+		if i%3 == 2 {
+			store.Rename(u.ID, n+" Jr.")
+		}
+		if i%4 == 3 {
+			store.Delete(u.ID - 1)
+		}
+		time.Sleep(time.Millisecond) // writes spread out, the relay runs between them
+	}
+	fmt.Println("Relay stopped:", <-relayDone)
+	fmt.Printf("After phase 1: %d unpublished, consumer received %d, applied %d\n",
+		outbox.Unpublished(), consumer.received, len(consumer.applied))
+
+	// "restart the process": close the file and open it again, the state comes from disk
+	outbox.Close()
+	outbox, err = OpenOutbox(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer outbox.Close()
+	fmt.Printf("Reopened: %d events in the file, first unpublished is #%d\n",
+		len(outbox.events), outbox.Pending(1)[0].Seq)
+
+	// the consumer is down for a moment after the restart: the relay keeps retrying, in order
+	consumer.failUntil = time.Now().Add(20 * time.Millisecond)
+	store = NewUserStore(outbox)
+	ctx, kill = context.WithCancel(context.Background())
+	go func() { relayDone <- Relay(ctx, outbox, 2*time.Millisecond, consumer.Handle) }()
+	for _, n := range []string{"Zara", "Ivan", "Neha"} {
+		store.Create(n)
+	}
+	waitUntil(func() bool { return outbox.Unpublished() == 0 })
+	kill()
+	<-relayDone
+
+	total := len(outbox.events)
+	fmt.Printf("After phase 2: %d events, consumer received %d (one redelivery), applied %d\n",
+		total, consumer.received, len(consumer.applied))
+	fmt.Println("  every event applied exactly once, in order:", len(consumer.applied) == total && consumer.outOfSeq == 0)
+	fmt.Println("  consumer's view of the names:", consumer.names)
+
+	fmt.Println("Marking out of order:", outbox.MarkPublished(3))
+}
+
+// Write the event in the same locked section as the change: both happen, or neither does.
+// A separate relay publishes in order and marks each event only AFTER the publish succeeded.
+// A crash between publish and mark sends the event again: delivery is at-least-once.
+// Consumers remember the last Seq they applied, so a redelivery is skipped: effectively once.
+// A failed publish stops the batch, later events never overtake an earlier one.
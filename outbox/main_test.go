@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func openTemp(t *testing.T) (*Outbox, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	o, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o, path
+}
+
+func reopen(t *testing.T, o *Outbox, path string) *Outbox {
+	t.Helper()
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	o, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func seqs(events []Event) []int64 {
+	out := []int64{}
+	for _, e := range events {
+		out = append(out, e.Seq)
+	}
+	return out
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// concurrent writers: Seq is gap-free, and replaying the events in Seq order gives the store's state
+func TestOrdering(t *testing.T) {
+	o, _ := openTemp(t)
+	s := NewUserStore(o)
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Go(func() {
+			for i := range 10 {
+				u, err := s.Create(fmt.Sprintf("w%d-%d", w, i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if i%2 == 0 {
+					s.Rename(u.ID, u.Name+"!")
+				}
+				if i%5 == 4 {
+					s.Delete(u.ID)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	events := o.Pending(1000)
+	if len(events) != 80+40+16 {
+		t.Fatalf("%d events", len(events))
+	}
+	for i, e := range events {
+		if e.Seq != int64(i+1) {
+			t.Fatalf("event %d has Seq %d", i, e.Seq)
+		}
+	}
+	// a user's own events are in the order they were made: created, then updated, then deleted
+	stage := map[int]int{}
+	order := map[string]int{"user.created": 1, "user.updated": 2, "user.deleted": 3}
+	for _, e := range events {
+		if order[e.Type] <= stage[e.UserID] {
+			t.Errorf("user %d: %s after stage %d", e.UserID, e.Type, stage[e.UserID])
+		}
+		stage[e.UserID] = order[e.Type]
+	}
+	if rebuilt := NewUserStore(o); !equalUsers(rebuilt.users, s.users) {
+		t.Errorf("replaying the events gives %d users, the store has %d", len(rebuilt.users), len(s.users))
+	}
+}
+
+func equalUsers(a, b map[int]User) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id, u := range a {
+		if b[id] != u {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMarkPublished(t *testing.T) {
+	o, path := openTemp(t)
+	for i := range 5 {
+		o.Append(Event{Type: "user.created", UserID: i + 1})
+	}
+	for _, seq := range []int64{0, 2, 5, -1} {
+		if err := o.MarkPublished(seq); !errors.Is(err, ErrOutOfOrder) {
+			t.Errorf("mark %d first: %v", seq, err)
+		}
+	}
+	for seq := int64(1); seq <= 3; seq++ {
+		if err := o.MarkPublished(seq); err != nil {
+			t.Fatalf("mark %d: %v", seq, err)
+		}
+	}
+	if err := o.MarkPublished(3); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("mark 3 twice: %v", err)
+	}
+	if got := seqs(o.Pending(10)); !slices.Equal(got, []int64{4, 5}) || o.Unpublished() != 2 {
+		t.Errorf("pending %v", got)
+	}
+	if got := seqs(o.Pending(1)); !slices.Equal(got, []int64{4}) {
+		t.Errorf("pending with limit 1: %v", got)
+	}
+
+	// the marks are on disk: a reopened outbox continues from 4
+	o = reopen(t, o, path)
+	if got := seqs(o.Pending(10)); !slices.Equal(got, []int64{4, 5}) {
+		t.Errorf("after reopen: pending %v", got)
+	}
+	if err := o.MarkPublished(3); !errors.Is(err, ErrOutOfOrder) {
+		t.Errorf("mark 3 after reopen: %v", err)
+	}
+	if e, err := o.Append(Event{Type: "user.created", UserID: 6}); err != nil || e.Seq != 6 {
+		t.Errorf("append after reopen: %+v %v", e, err)
+	}
+}
+
+func TestPendingIsACopy(t *testing.T) {
+	o, _ := openTemp(t)
+	o.Append(Event{Type: "user.created", UserID: 1, Name: "A"})
+	p := o.Pending(10)
+	p[0].Name = "changed"
+	if o.Pending(10)[0].Name != "A" {
+		t.Error("Pending returned the outbox's own slice")
+	}
+}
+
+// the relay is killed after publishing an event and before marking it; after a restart from
+// the file that event goes out again, and the idempotent consumer applies everything once
+func TestCrashRestart(t *testing.T) {
+	o, path := openTemp(t)
+	s := NewUserStore(o)
+	for i := range 10 {
+		s.Create(fmt.Sprint("user", i))
+	}
+	consumer := &Consumer{names: map[int]string{}}
+
+	ctx, kill := context.WithCancel(context.Background())
+	err := Relay(ctx, o, time.Millisecond, func(e Event) error {
+		err := consumer.Handle(e)
+		if e.Seq == 6 {
+			kill()
+		}
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("relay: %v", err)
+	}
+	if o.Unpublished() != 5 || consumer.received != 6 {
+		t.Fatalf("after the crash: %d unpublished, %d received", o.Unpublished(), consumer.received)
+	}
+
+	o = reopen(t, o, path)
+	s = NewUserStore(o)
+	if len(s.users) != 10 || s.nextID != 11 {
+		t.Errorf("store rebuilt with %d users, next ID %d", len(s.users), s.nextID)
+	}
+	s.Create("after restart")
+
+	ctx, kill = context.WithCancel(context.Background())
+	defer kill()
+	done := make(chan error, 1)
+	go func() { done <- Relay(ctx, o, time.Millisecond, consumer.Handle) }()
+	eventually(t, "the outbox to drain", func() bool { return o.Unpublished() == 0 })
+	kill()
+	<-done
+
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+	if got := seqs(consumer.applied); !slices.Equal(got, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}) {
+		t.Errorf("applied %v", got)
+	}
+	if consumer.received != 12 || consumer.outOfSeq != 0 {
+		t.Errorf("received %d (want 11 + one redelivery), %d out of sequence", consumer.received, consumer.outOfSeq)
+	}
+	if len(consumer.names) != 11 || consumer.names[11] != "after restart" {
+		t.Errorf("consumer's names %v", consumer.names)
+	}
+}
+
+// a failing publish keeps the event unmarked, and nothing after it overtakes it
+func TestPublishFailure(t *testing.T) {
+	o, _ := openTemp(t)
+	for i := range 5 {
+		o.Append(Event{Type: "user.created", UserID: i + 1})
+	}
+	var mu sync.Mutex
+	var sent []int64
+	var failures atomic.Int64
+	publish := func(e Event) error {
+		if e.Seq == 3 && failures.Add(1) <= 3 {
+			return errors.New("broker down")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, e.Seq)
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Relay(ctx, o, time.Millisecond, publish) }()
+	eventually(t, "the outbox to drain", func() bool { return o.Unpublished() == 0 })
+	cancel()
+	<-done
+	if !slices.Equal(sent, []int64{1, 2, 3, 4, 5}) || failures.Load() != 4 {
+		t.Errorf("sent %v after %d attempts at 3", sent, failures.Load())
+	}
+}
+
+// when the event can't be written the change doesn't happen either
+func TestAppendFailureCancelsTheChange(t *testing.T) {
+	o, _ := openTemp(t)
+	s := NewUserStore(o)
+	u, _ := s.Create("Rishabh")
+	o.Close()
+	if _, err := s.Create("Sanchay"); !errors.Is(err, ErrClosed) {
+		t.Errorf("create: %v", err)
+	}
+	if err := s.Rename(u.ID, "R"); !errors.Is(err, ErrClosed) {
+		t.Errorf("rename: %v", err)
+	}
+	if err := s.Delete(u.ID); !errors.Is(err, ErrClosed) {
+		t.Errorf("delete: %v", err)
+	}
+	if len(s.users) != 1 || s.users[u.ID].Name != "Rishabh" || s.nextID != 2 {
+		t.Errorf("store changed without its event: %v next %d", s.users, s.nextID)
+	}
+	if err := o.MarkPublished(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("mark on a closed outbox: %v", err)
+	}
+	if err := o.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	o, _ := openTemp(t)
+	s := NewUserStore(o)
+	if err := s.Rename(9, "X"); err == nil {
+		t.Error("rename of a missing user")
+	}
+	if err := s.Delete(9); err != nil || o.Unpublished() != 0 {
+		t.Errorf("delete of a missing user: %v, %d events", err, o.Unpublished())
+	}
+}
+
+func TestOpenOutboxErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.log")
+	os.WriteFile(bad, []byte(`{"op":"event","event":{"seq":1,"type":"user.created","user_id":1}}`+"\n{not json\n"), 0o644)
+	if _, err := OpenOutbox(bad); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("corrupt line: %v", err)
+	}
+	if _, err := OpenOutbox(filepath.Join(dir, "missing", "outbox.log")); err == nil {
+		t.Error("no error for a missing directory")
+	}
+}
+
+func TestOpenOutboxTornLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	good := `{"op":"event","event":{"seq":1,"type":"user.created","user_id":1,"name":"A"}}` + "\n"
+	os.WriteFile(path, []byte(good+`{"op":"event","event":{"seq":2,"ty`), 0o644)
+	o, err := OpenOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	if got := seqs(o.Pending(10)); !slices.Equal(got, []int64{1}) {
+		t.Errorf("pending after a torn line: %v", got)
+	}
+	// the next record starts on its own line, not after the torn bytes
+	if e, err := o.Append(Event{Type: "user.created", UserID: 2, Name: "B"}); err != nil || e.Seq != 2 {
+		t.Fatalf("append: %+v %v", e, err)
+	}
+	o = reopen(t, o, path)
+	if got := seqs(o.Pending(10)); !slices.Equal(got, []int64{1, 2}) {
+		t.Errorf("after reopening: %v", got)
+	}
+}
+
+// a record longer than bufio.Scanner's 64 KiB limit replays like any other
+func TestOpenOutboxLongLine(t *testing.T) {
+	o, path := openTemp(t)
+	name := strings.Repeat("x", 100_000)
+	if _, err := o.Append(Event{Type: "user.created", UserID: 1, Name: name}); err != nil {
+		t.Fatal(err)
+	}
+	o = reopen(t, o, path)
+	if got := o.Pending(1); len(got) != 1 || got[0].Name != name {
+		t.Errorf("long event not replayed: %d events", len(got))
+	}
+}
+
+// failSync makes the next Sync fail, after Write has already put the bytes in the file
+type failSync struct {
+	logFile
+	fail bool
+}
+
+func (f *failSync) Sync() error {
+	if f.fail {
+		f.fail = false
+		return errors.New("sync: input/output error")
+	}
+	return f.logFile.Sync()
+}
+
+func TestSyncFailure(t *testing.T) {
+	o, path := openTemp(t)
+	s := NewUserStore(o)
+	s.Create("Rishabh")
+	log := &failSync{logFile: o.log, fail: true}
+	o.log = log
+	if _, err := s.Create("Sanchay"); err == nil {
+		t.Fatal("create with a failing sync")
+	}
+	if _, err := s.Create("Sanchay"); err != nil {
+		t.Fatalf("create after the sync failure: %v", err)
+	}
+	if err := o.MarkPublished(1); err != nil {
+		t.Fatal(err)
+	}
+	// the failed record was cut off, so Seq 2 is in the file once
+	o.log = log.logFile
+	o = reopen(t, o, path)
+	if got := seqs(o.Pending(10)); !slices.Equal(got, []int64{2}) {
+		t.Errorf("after reopening: %v", got)
+	}
+	if e := o.Pending(1); len(e) != 1 || e[0].Name != "Sanchay" || e[0].UserID != 2 {
+		t.Errorf("event 2: %+v", e)
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	c := &Consumer{names: map[int]string{}}
+	events := []Event{
+		{Seq: 1, Type: "user.created", UserID: 1, Name: "A"},
+		{Seq: 2, Type: "user.updated", UserID: 1, Name: "B"},
+		{Seq: 2, Type: "user.updated", UserID: 1, Name: "B"}, // redelivered
+		{Seq: 1, Type: "user.created", UserID: 1, Name: "A"}, // very late
+		{Seq: 3, Type: "user.deleted", UserID: 1},
+	}
+	for _, e := range events {
+		if err := c.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(c.applied) != 3 || c.received != 5 || len(c.names) != 0 || c.outOfSeq != 0 {
+		t.Errorf("%+v", c)
+	}
+	c.failUntil = time.Now().Add(time.Hour)
+	if err := c.Handle(Event{Seq: 4}); err == nil || c.received != 5 {
+		t.Error("a down consumer accepted an event")
+	}
+}
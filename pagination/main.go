@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A paginated response that says where the next page is saves every client from
+// building URLs by hand (and getting the filters wrong). The links are built from
+// the request itself: same path, same filters and sort, only "page" changes.
+
+// LinkBuilder makes absolute URLs for responses.
+// Behind a reverse proxy the request arrives as http://10.0.0.5:8080, but the client used
+// https://api.example.com. The proxy says so in X-Forwarded-Proto/Host. Those headers can be
+// sent by anyone, so they are only trusted when TrustProxy is set (i.e. the proxy strips them).
+type LinkBuilder struct {
+	TrustProxy bool
+}
+
+// base returns scheme://host as the client saw it
+func (b LinkBuilder) base(r *http.Request) string {
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if b.TrustProxy {
+		if p := r.Header.Get("X-Forwarded-Proto"); p == "http" || p == "https" {
+			scheme = p
+		}
+		if h := r.Header.Get("X-Forwarded-Host"); h != "" {
+			host, _, _ = strings.Cut(h, ",") // "client-facing, next-proxy": the first one is the client's
+			host = strings.TrimSpace(host)
+		}
+	}
+	return scheme + "://" + host
+}
+
+// Path returns an absolute URL for path, without a query
+func (b LinkBuilder) Path(r *http.Request, path string) string {
+	return b.base(r) + path
+}
+
+// WithQuery returns the request's URL with key set to value and every other parameter kept
+func (b LinkBuilder) WithQuery(r *http.Request, key, value string) string {
+	q := r.URL.Query() // a copy, the request isn't changed
+	q.Set(key, value)
+	return b.base(r) + r.URL.Path + "?" + q.Encode() // Encode sorts the keys, so URLs are stable
+}
+
+// Self returns the request's own URL
+func (b LinkBuilder) Self(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return b.base(r) + r.URL.Path
+	}
+	return b.base(r) + r.URL.Path + "?" + r.URL.Query().Encode()
+}
+
+// ----------------------------------------------------------------------------
+
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"` // left out on the last page, not pointing past the end
+	Prev string `json:"prev,omitempty"` // left out on the first page
+}
+
+type User struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Role  string            `json:"role"`
+	Links map[string]string `json:"links"`
+}
+
+type UsersResponse struct {
+	Users []User `json:"users"`
+	Page  int    `json:"page"`
+	Pages int    `json:"pages"`
+	Total int    `json:"total"`
+	Links Links  `json:"links"`
+}
+
+var allUsers = func() []User {
+	names := []string{"Rishabh", "Sanchay", "Alice", "Bob", "Maya", "Omar", "Priya", "Leo", "Zara", "Ivan", "Neha"}
+	users := make([]User, len(names))
+	for i, n := range names {
+		role := "user"
+		if i%4 == 0 {
+			role = "admin"
+		}
+		users[i] = User{ID: i + 1, Name: n, Role: role}
+	}
+	return users
+}()
+
+func badRequest(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+func queryInt(q url.Values, key string, def, lo, hi int) (int, error) {
+	s := q.Get(key)
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("%s must be a number from %d to %d", key, lo, hi)
+	}
+	return n, nil
+}
+
+// handleListUsers: GET /api/users?role=&sort=name|id&page=&per_page=
+func handleListUsers(lb LinkBuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		page, err := queryInt(q, "page", 1, 1, 1_000_000)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+		perPage, err := queryInt(q, "per_page", 5, 1, 100)
+		if err != nil {
+			badRequest(w, err.Error())
+			return
+		}
+
+		var users []User
+		for _, u := range allUsers {
+			if role := q.Get("role"); role == "" || u.Role == role {
+				users = append(users, u)
+			}
+		}
+		if q.Get("sort") == "name" {
+			slices.SortFunc(users, func(a, b User) int { return strings.Compare(a.Name, b.Name) })
+		}
+
+		pages := max(1, (len(users)+perPage-1)/perPage)
+		resp := UsersResponse{Page: page, Pages: pages, Total: len(users), Users: []User{}}
+		if start := (page - 1) * perPage; start < len(users) {
+			resp.Users = users[start:min(start+perPage, len(users))]
+		}
+		for i := range resp.Users {
+			u := resp.Users[i] // a copy: don't write links into allUsers
+			u.Links = map[string]string{"self": lb.Path(r, "/api/users/"+strconv.Itoa(u.ID))}
+			resp.Users[i] = u
+		}
+
+		resp.Links.Self = lb.Self(r)
+		if page < pages {
+			resp.Links.Next = lb.WithQuery(r, "page", strconv.Itoa(page+1))
+		}
+		if page > 1 {
+			// a page past the end links back to the last real page
+			resp.Links.Prev = lb.WithQuery(r, "page", strconv.Itoa(min(page-1, pages)))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func handleGetUser(lb LinkBuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil || id < 1 || id > len(allUsers) {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		u := allUsers[id-1]
+		u.Links = map[string]string{"self": lb.Self(r), "collection": lb.Path(r, "/api/users")}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(u)
+	}
+}
+
+func main() {
+	fmt.Println("Learning pagination links in Go")
+
+	lb := LinkBuilder{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", handleListUsers(lb))
+	mux.HandleFunc("GET /api/users/{id}", handleGetUser(lb))
+
+	get := func(h http.Handler, target string, headers map[string]string) (int, []byte) {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.Bytes()
+	}
+	showLinks := func(target string) {
+		code, body := get(mux, target, nil)
+		var resp UsersResponse
+		json.Unmarshal(body, &resp)
+		names := make([]string, len(resp.Users))
+		for i, u := range resp.Users {
+			names[i] = u.Name
+		}
+		fmt.Printf("GET %s -> %d page %d/%d %v\n", target, code, resp.Page, resp.Pages, names)
+		fmt.Printf("  self %s\n  next %q\n  prev %q\n", resp.Links.Self, resp.Links.Next, resp.Links.Prev)
+	}
+
+	showLinks("/api/users")                                // first page: no prev
+	showLinks("/api/users?page=2&sort=name&per_page=4")    // middle: filters and sort kept
+	showLinks("/api/users?page=3&sort=name&per_page=4")    // last: no next
+	showLinks("/api/users?role=admin")                     // one page only
+	showLinks("/api/users?page=9")                         // past the end: empty, prev to the last page
+	code, body := get(mux, "/api/users?per_page=500", nil) // out of range
+	fmt.Printf("GET /api/users?per_page=500 -> %d %s", code, body)
+
+	_, body = get(mux, "/api/users/3", nil)
+	fmt.Printf("GET /api/users/3 -> %s", body)
+
+	// behind a proxy
+	fwd := map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}
+	for _, trust := range []bool{false, true} {
+		m := http.NewServeMux()
+		m.HandleFunc("GET /api/users", handleListUsers(LinkBuilder{TrustProxy: trust}))
+		_, body := get(m, "/api/users?page=1", fwd)
+		var resp UsersResponse
+		json.Unmarshal(body, &resp)
+		fmt.Printf("TrustProxy=%-5v next: %s\n", trust, resp.Links.Next)
+	}
+}
+
+// Build links from the request: copy r.URL.Query(), change only "page", keep every filter and sort.
+// url.Values.Encode escapes values and sorts keys, so the same page always gets the same URL.
+// Leave "next" out on the last page, a client loops "while next != empty" and must stop.
+// X-Forwarded-Proto/Host can be forged by anyone, only honor them when a trusted proxy sets them.
+// Every resource links to itself, so a client never has to know how IDs turn into paths.
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func request(target string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func TestLinkBuilderBase(t *testing.T) {
+	secure := request("/", nil)
+	secure.TLS = &tls.ConnectionState{}
+	tests := []struct {
+		name  string
+		trust bool
+		r     *http.Request
+		want  string
+	}{
+		{"plain", false, request("/", nil), "http://example.com"},
+		{"tls", false, secure, "https://example.com"},
+		{"untrusted headers", false, request("/", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"}), "http://example.com"},
+		{"trusted", true, request("/", map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}), "https://api.example.com"},
+		{"proxy chain", true, request("/", map[string]string{"X-Forwarded-Host": " api.example.com , internal:8080"}), "http://api.example.com"},
+		{"odd scheme", true, request("/", map[string]string{"X-Forwarded-Proto": "javascript"}), "http://example.com"},
+		{"trusted, no headers", true, secure, "https://example.com"},
+	}
+	for _, tt := range tests {
+		if got := (LinkBuilder{TrustProxy: tt.trust}).base(tt.r); got != tt.want {
+			t.Errorf("%s: %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLinkBuilder(t *testing.T) {
+	var lb LinkBuilder
+	r := request("/api/users?sort=name&role=a%20b&page=2", nil)
+	if got, want := lb.Self(r), "http://example.com/api/users?page=2&role=a+b&sort=name"; got != want {
+		t.Errorf("Self = %q, want %q", got, want)
+	}
+	if got, want := lb.WithQuery(r, "page", "3"), "http://example.com/api/users?page=3&role=a+b&sort=name"; got != want {
+		t.Errorf("WithQuery = %q, want %q", got, want)
+	}
+	if r.URL.Query().Get("page") != "2" {
+		t.Error("WithQuery changed the request")
+	}
+	if got, want := lb.WithQuery(request("/api/users", nil), "page", "2"), "http://example.com/api/users?page=2"; got != want {
+		t.Errorf("WithQuery without a query = %q, want %q", got, want)
+	}
+	if got, want := lb.Self(request("/api/users", nil)), "http://example.com/api/users"; got != want {
+		t.Errorf("Self without a query = %q, want %q", got, want)
+	}
+	if got, want := lb.Path(r, "/api/users/7"), "http://example.com/api/users/7"; got != want {
+		t.Errorf("Path = %q, want %q", got, want)
+	}
+}
+
+func newMux(lb LinkBuilder) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", handleListUsers(lb))
+	mux.HandleFunc("GET /api/users/{id}", handleGetUser(lb))
+	return mux
+}
+
+func list(t *testing.T, mux http.Handler, target string, headers map[string]string) UsersResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, request(target, headers))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", target, rec.Code, rec.Body)
+	}
+	var resp UsersResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestListLinks(t *testing.T) {
+	mux := newMux(LinkBuilder{})
+	const base = "http://example.com/api/users"
+	tests := []struct {
+		target string
+		want   Links
+		first  string // first user on the page
+	}{
+		{"/api/users?sort=name&per_page=4", Links{
+			Self: base + "?per_page=4&sort=name",
+			Next: base + "?page=2&per_page=4&sort=name",
+		}, "Alice"},
+		{"/api/users?page=2&sort=name&per_page=4", Links{
+			Self: base + "?page=2&per_page=4&sort=name",
+			Next: base + "?page=3&per_page=4&sort=name",
+			Prev: base + "?page=1&per_page=4&sort=name",
+		}, "Maya"},
+		{"/api/users?page=3&sort=name&per_page=4", Links{
+			Self: base + "?page=3&per_page=4&sort=name",
+			Prev: base + "?page=2&per_page=4&sort=name",
+		}, "Rishabh"},
+		{"/api/users?role=admin", Links{Self: base + "?role=admin"}, "Rishabh"},
+		{"/api/users", Links{Self: base, Next: base + "?page=2"}, "Rishabh"},
+	}
+	for _, tt := range tests {
+		resp := list(t, mux, tt.target, nil)
+		if resp.Links != tt.want {
+			t.Errorf("%s:\n got %+v\nwant %+v", tt.target, resp.Links, tt.want)
+		}
+		if len(resp.Users) == 0 || resp.Users[0].Name != tt.first {
+			t.Errorf("%s: users %v", tt.target, resp.Users)
+		}
+	}
+}
+
+// following next from the first page visits every user once and stops
+func TestFollowNext(t *testing.T) {
+	mux := newMux(LinkBuilder{})
+	seen := map[int]bool{}
+	target, pages := "/api/users?per_page=3", 0
+	for target != "" {
+		resp := list(t, mux, target, nil)
+		for _, u := range resp.Users {
+			if seen[u.ID] {
+				t.Fatalf("user %d twice", u.ID)
+			}
+			seen[u.ID] = true
+		}
+		target = ""
+		if resp.Links.Next != "" {
+			target = resp.Links.Next[len("http://example.com"):]
+		}
+		if pages++; pages > 10 {
+			t.Fatal("next never ran out")
+		}
+	}
+	if len(seen) != len(allUsers) || pages != 4 {
+		t.Errorf("%d users over %d pages", len(seen), pages)
+	}
+}
+
+func TestPastTheEnd(t *testing.T) {
+	resp := list(t, newMux(LinkBuilder{}), "/api/users?page=9&per_page=4", nil)
+	want := Links{
+		Self: "http://example.com/api/users?page=9&per_page=4",
+		Prev: "http://example.com/api/users?page=3&per_page=4",
+	}
+	if len(resp.Users) != 0 || resp.Links != want || resp.Pages != 3 {
+		t.Errorf("%+v", resp)
+	}
+}
+
+func TestUserLinks(t *testing.T) {
+	fwd := map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "api.example.com"}
+	resp := list(t, newMux(LinkBuilder{TrustProxy: true}), "/api/users?page=2", fwd)
+	if resp.Links.Next != "https://api.example.com/api/users?page=3" {
+		t.Errorf("next behind the proxy: %q", resp.Links.Next)
+	}
+	for _, u := range resp.Users {
+		if want := "https://api.example.com/api/users/" + strconv.Itoa(u.ID); u.Links["self"] != want || len(u.Links) != 1 {
+			t.Errorf("user %d links %v, want self %q", u.ID, u.Links, want)
+		}
+	}
+	for _, u := range allUsers {
+		if u.Links != nil {
+			t.Fatalf("the handler wrote links into allUsers: %+v", u)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newMux(LinkBuilder{}).ServeHTTP(rec, request("/api/users/3?fields=name", nil))
+	var u User
+	json.Unmarshal(rec.Body.Bytes(), &u)
+	if u.Name != "Alice" || u.Links["self"] != "http://example.com/api/users/3?fields=name" || u.Links["collection"] != "http://example.com/api/users" {
+		t.Errorf("single user: %s", rec.Body)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	mux := newMux(LinkBuilder{})
+	for target, want := range map[string]int{
+		"/api/users?page=0":       400,
+		"/api/users?page=x":       400,
+		"/api/users?per_page=101": 400,
+		"/api/users?per_page=0":   400,
+		"/api/users/0":            404,
+		"/api/users/12":           404,
+		"/api/users/abc":          404,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, request(target, nil))
+		if rec.Code != want {
+			t.Errorf("%s: %d, want %d", target, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUsage is returned by a command when its arguments are wrong, the REPL then prints the usage line
+var ErrUsage = errors.New("usage error")
+
+// errQuit stops the REPL loop
+var errQuit = errors.New("quit")
+
+// Command is one playground command. The dispatcher is just a map of these,
+// so adding a command means adding one entry, no switch to edit.
+type Command struct {
+	Name    string
+	Usage   string // e.g. "fib <n>"
+	Help    string
+	MinArgs int
+	MaxArgs int // -1 = no limit
+	Run     func(args []string, w io.Writer) error
+}
+
+// Commands maps a command name to its Command
+type Commands map[string]Command
+
+// Register adds c, registering the same name twice is a programming mistake
+func (cs Commands) Register(c Command) {
+	if _, exists := cs[c.Name]; exists {
+		panic("playground: command registered twice: " + c.Name)
+	}
+	cs[c.Name] = c
+}
+
+// SplitArgs splits a command line on spaces, keeping 'single' or "double" quoted text together.
+// A backslash escapes the next character: "say \"hi\"" -> say "hi"
+func SplitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune // 0 when not inside quotes
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'': // no escapes inside single quotes, like the shell
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true // "" is an empty argument, not nothing
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("line ends with a backslash")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// Execute runs one input line
+func (cs Commands) Execute(line string, w io.Writer) error {
+	args, err := SplitArgs(line)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	cmd, ok := cs[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %q, type help to see the commands", args[0])
+	}
+	args = args[1:]
+	if len(args) < cmd.MinArgs || (cmd.MaxArgs >= 0 && len(args) > cmd.MaxArgs) {
+		return fmt.Errorf("%w: %s", ErrUsage, cmd.Usage)
+	}
+	if err := cmd.Run(args, w); err != nil {
+		if errors.Is(err, ErrUsage) {
+			return fmt.Errorf("%w: %s", ErrUsage, cmd.Usage)
+		}
+		return err
+	}
+	return nil
+}
+
+// RunREPL reads commands from r until quit or the end of the input.
+// Errors are printed and the loop continues, one bad command doesn't end the session.
+func RunREPL(r io.Reader, w io.Writer, cs Commands, echo bool) error {
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(w, "playground> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return scanner.Err() // nil at the end of the input (Ctrl+D)
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if echo {
+			fmt.Fprintln(w, line) // scripted input: show what was "typed"
+		}
+		err := cs.Execute(line, w)
+		if errors.Is(err, errQuit) {
+			fmt.Fprintln(w, "bye")
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(w, "error:", err)
+		}
+	}
+}
+
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// parsePositive parses a command argument that must be a number in [1, limit]
+func parsePositive(s string, limit int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > limit {
+		return 0, ErrUsage
+	}
+	return n, nil
+}
+
+// newCommands builds the command table
+func newCommands() Commands {
+	cs := Commands{}
+
+	cs.Register(Command{
+		Name: "password", Usage: "password <length 1-128>", Help: "random password from crypto/rand",
+		MinArgs: 1, MaxArgs: 1,
+		Run: func(args []string, w io.Writer) error {
+			n, err := parsePositive(args[0], 128)
+			if err != nil {
+				return err
+			}
+			const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*-_=+"
+			var sb strings.Builder
+			for i := 0; i < n; i++ {
+				idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+				if err != nil {
+					return err
+				}
+				sb.WriteByte(charset[idx.Int64()])
+			}
+			fmt.Fprintln(w, sb.String())
+			return nil
+		},
+	})
+
+	cs.Register(Command{
+		Name: "validate-email", Usage: "validate-email <address>", Help: "check an email address",
+		MinArgs: 1, MaxArgs: 1,
+		Run: func(args []string, w io.Writer) error {
+			if emailPattern.MatchString(args[0]) && !strings.Contains(args[0], "..") {
+				fmt.Fprintf(w, "%q is valid\n", args[0])
+			} else {
+				fmt.Fprintf(w, "%q is NOT valid\n", args[0])
+			}
+			return nil
+		},
+	})
+
+	cs.Register(Command{
+		Name: "fib", Usage: "fib <n 1-1000>", Help: "n-th Fibonacci number (big.Int, no overflow)",
+		MinArgs: 1, MaxArgs: 1,
+		Run: func(args []string, w io.Writer) error {
+			n, err := parsePositive(args[0], 1000)
+			if err != nil {
+				return err
+			}
+			a, b := big.NewInt(0), big.NewInt(1)
+			for i := 0; i < n; i++ {
+				a.Add(a, b)
+				a, b = b, a
+			}
+			fmt.Fprintln(w, a)
+			return nil
+		},
+	})
+
+	hashes := map[string]func() hash.Hash{"md5": md5.New, "sha1": sha1.New, "sha256": sha256.New}
+	cs.Register(Command{
+		Name: "hash", Usage: "hash <md5|sha1|sha256> <text>", Help: "hex digest of the text",
+		MinArgs: 2, MaxArgs: -1,
+		Run: func(args []string, w io.Writer) error {
+			newHash, ok := hashes[args[0]]
+			if !ok {
+				return ErrUsage
+			}
+			h := newHash()
+			h.Write([]byte(strings.Join(args[1:], " ")))
+			fmt.Fprintln(w, hex.EncodeToString(h.Sum(nil)))
+			return nil
+		},
+	})
+
+	cs.Register(Command{
+		Name: "json-pretty", Usage: "json-pretty <json>", Help: "indent a JSON document",
+		MinArgs: 1, MaxArgs: -1,
+		Run: func(args []string, w io.Writer) error {
+			var out bytes.Buffer
+			if err := json.Indent(&out, []byte(strings.Join(args, " ")), "", "  "); err != nil {
+				return fmt.Errorf("invalid JSON: %w", err)
+			}
+			fmt.Fprintln(w, out.String())
+			return nil
+		},
+	})
+
+	cs.Register(Command{
+		Name: "help", Usage: "help", Help: "list the commands", MaxArgs: 0,
+		Run: func(args []string, w io.Writer) error {
+			names := make([]string, 0, len(cs))
+			for name := range cs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Fprintf(w, "  %-32s %s\n", cs[name].Usage, cs[name].Help)
+			}
+			return nil
+		},
+	})
+
+	cs.Register(Command{
+		Name: "quit", Usage: "quit", Help: "leave the playground", MaxArgs: 0,
+		Run: func(args []string, w io.Writer) error { return errQuit },
+	})
+	return cs
+}
+
+func main() {
+	interactive := flag.Bool("i", false, "read commands from the keyboard instead of running the scripted demo")
+	flag.Parse()
+
+	fmt.Println("Learning to build a small command REPL in Go")
+	commands := newCommands()
+
+	if *interactive {
+		if err := RunREPL(os.Stdin, os.Stdout, commands, false); err != nil {
+			fmt.Println("Error:", err)
+		}
+		return
+	}
+
+	script := `help
+fib 10
+fib 100
+fib abc
+hash sha256 hello
+hash sha512 hello
+validate-email foo@bar.com
+validate-email "not an email"
+json-pretty '{"a":1,"b":[true,null]}'
+json-pretty {"a":1}
+json-pretty '{"broken":'
+password
+say "unterminated
+unknown-command
+quit
+fib 1`
+	if err := RunREPL(strings.NewReader(script), os.Stdout, commands, true); err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("Run with -i to type commands yourself")
+}
+
+// A REPL is a loop: Read a line, Evaluate it, Print the result, Loop.
+// Keeping the commands in a table (map of Command) means help, argument checks and dispatch
+// are written once, and new commands are one Register call.
+// Taking io.Reader/io.Writer instead of os.Stdin/os.Stdout lets the same loop run from a script.
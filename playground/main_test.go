@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "", want: nil},
+		{line: "   ", want: nil},
+		{line: "fib 30", want: []string{"fib", "30"}},
+		{line: "  hash\tsha256   hello  ", want: []string{"hash", "sha256", "hello"}},
+		{line: `validate-email "not an email"`, want: []string{"validate-email", "not an email"}},
+		{line: `json-pretty '{"a": 1}'`, want: []string{"json-pretty", `{"a": 1}`}},
+		{line: `say "" x`, want: []string{"say", "", "x"}},
+		{line: `say \"hi\"`, want: []string{"say", `"hi"`}},
+		{line: `say 'a\b'`, want: []string{"say", `a\b`}}, // no escapes in single quotes
+		{line: `say ab"c d"e`, want: []string{"say", "abc de"}},
+		{line: `say "unterminated`, wantErr: true},
+		{line: `say trailing\`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.line)
+		if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, %v; want %q, error %v", tt.line, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExecuteUsageErrors(t *testing.T) {
+	cs := newCommands()
+	tests := []struct {
+		line, usage string
+	}{
+		{"fib", "fib <n 1-1000>"},
+		{"fib 0", "fib <n 1-1000>"},
+		{"fib abc", "fib <n 1-1000>"},
+		{"fib 1 2", "fib <n 1-1000>"},
+		{"hash sha512 hello", "hash <md5|sha1|sha256> <text>"},
+		{"password 129", "password <length 1-128>"},
+		{"help me", "help"},
+	}
+	for _, tt := range tests {
+		err := cs.Execute(tt.line, &strings.Builder{})
+		if !errors.Is(err, ErrUsage) || !strings.HasSuffix(err.Error(), tt.usage) {
+			t.Errorf("Execute(%q) = %v, want ErrUsage ending in %q", tt.line, err, tt.usage)
+		}
+	}
+	if err := cs.Execute("nope", &strings.Builder{}); err == nil || errors.Is(err, ErrUsage) {
+		t.Errorf("unknown command: %v", err)
+	}
+	if err := cs.Execute("quit", &strings.Builder{}); !errors.Is(err, errQuit) {
+		t.Errorf("quit: %v", err)
+	}
+}
+
+func TestScriptedSession(t *testing.T) {
+	script := `fib 10
+fib 100
+hash sha256 hello
+hash md5 two words
+validate-email foo@bar.com
+validate-email "not an email"
+json-pretty '{"a":1,"b":[true]}'
+json-pretty '{"broken":'
+fib
+
+say "unterminated
+quit
+fib 1`
+	want := `playground> fib 10
+55
+playground> fib 100
+354224848179261915075
+playground> hash sha256 hello
+2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824
+playground> hash md5 two words
+573eb82c528c319f0097158784ff0aed
+playground> validate-email foo@bar.com
+"foo@bar.com" is valid
+playground> validate-email "not an email"
+"not an email" is NOT valid
+playground> json-pretty '{"a":1,"b":[true]}'
+{
+  "a": 1,
+  "b": [
+    true
+  ]
+}
+playground> json-pretty '{"broken":'
+error: invalid JSON: unexpected end of JSON input
+playground> fib
+error: usage error: fib <n 1-1000>
+playground> 
+playground> say "unterminated
+error: unterminated " quote
+playground> quit
+bye
+`
+	var out strings.Builder
+	if err := RunREPL(strings.NewReader(script), &out, newCommands(), true); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("session output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestSessionEndsAtEOF(t *testing.T) {
+	var out strings.Builder
+	if err := RunREPL(strings.NewReader("fib 1"), &out, newCommands(), false); err != nil {
+		t.Fatal(err)
+	}
+	if want := "playground> 1\nplayground> \n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+}
+
+func TestHelpAndPassword(t *testing.T) {
+	cs := newCommands()
+	var out strings.Builder
+	if err := cs.Execute("help", &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(cs) || !slices.IsSorted(lines) {
+		t.Errorf("help should list %d commands in order:\n%s", len(cs), out.String())
+	}
+
+	out.Reset()
+	if err := cs.Execute("password 16", &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSuffix(out.String(), "\n"); len(got) != 16 {
+		t.Errorf("password 16 printed %q", got)
+	}
+}
+
+func TestRegisterTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering fib twice did not panic")
+		}
+	}()
+	newCommands().Register(Command{Name: "fib"})
+}
@@ -1,6 +1,140 @@
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
+
+// SwapInts swaps the values a and b point to. With plain ints the function
+// would only swap its own copies.
+func SwapInts(a, b *int) {
+	*a, *b = *b, *a
+}
+
+// Ptr returns a pointer to a copy of v. Handy for optional fields: &42 or &"text" don't compile,
+// Ptr(42) does.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Deref returns *p, or fallback when p is nil
+func Deref[T any](p *T, fallback T) T {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
+
+type User struct {
+	Name string
+	Age  int
+	Nick *string // optional: nil means "not set", different from ""
+}
+
+// GetName can be called on a nil *User: a method call on a nil pointer is fine,
+// only reading a field through it panics. Checking for nil makes the getter safe.
+func (u *User) GetName() string {
+	if u == nil {
+		return "<nobody>"
+	}
+	return u.Name
+}
+
+// UnsafeName reads the field without checking, so a nil receiver panics
+func (u *User) UnsafeName() string {
+	return u.Name
+}
+
+// newUserValue returns a copy, the User can live on the stack of the caller
+//
+//go:noinline
+func newUserValue(age int) User {
+	return User{Name: "value", Age: age}
+}
+
+// newUserPointer returns a pointer, so the User "escapes" to the heap
+// (a garbage-collected allocation), check with: go build -gcflags=-m main.go
+// noinline on both keeps the comparison fair, inlining could remove the escape
+//
+//go:noinline
+func newUserPointer(age int) *User {
+	return &User{Name: "pointer", Age: age}
+}
+
+// sinks keep the compiler from removing the benchmark loops
+var (
+	sinkValue   User
+	sinkPointer *User
+)
+
+// PointerSemanticsExamples shows what pointers change in everyday code
+func PointerSemanticsExamples() {
+	fmt.Println("\nPointer semantics")
+
+	// swap
+	a, b := 1, 2
+	SwapInts(&a, &b)
+	fmt.Println("After SwapInts:", a, b)
+
+	// new(T) and &T{} both give a pointer to a zero value, &T{...} can also set fields
+	p1 := new(User)
+	p2 := &User{}
+	p3 := &User{Name: "Rishabh", Age: 23}
+	fmt.Printf("new(User)=%+v &User{}=%+v &User{...}=%+v\n", *p1, *p2, *p3)
+
+	// nil receivers
+	var nobody *User
+	fmt.Println("GetName on nil:", nobody.GetName())
+	func() {
+		defer func() { fmt.Println("UnsafeName on nil panicked:", recover()) }()
+		fmt.Println(nobody.UnsafeName())
+	}()
+
+	// Ptr and Deref for optional fields
+	withNick := User{Name: "Rishabh", Nick: Ptr("rishi")}
+	withoutNick := User{Name: "Sanchay"}
+	fmt.Println("Nicks:", Deref(withNick.Nick, "(none)"), Deref(withoutNick.Nick, "(none)"))
+	fmt.Println("Deref of nil *int:", Deref[int](nil, -1), "Ptr(5) points to", *Ptr(5))
+
+	// map of values: m["x"].Age++ doesn't compile, you must copy, change and store back
+	values := map[string]User{"r": {Name: "Rishabh", Age: 23}}
+	u := values["r"]
+	u.Age++
+	values["r"] = u
+	// map of pointers: change the struct directly
+	pointers := map[string]*User{"r": {Name: "Rishabh", Age: 23}}
+	pointers["r"].Age++
+	fmt.Println("Map of values:", values["r"].Age, "map of pointers:", pointers["r"].Age)
+
+	// range gives a copy of each element, so changing it does nothing to the slice
+	users := []User{{Name: "A", Age: 1}, {Name: "B", Age: 2}}
+	for _, u := range users {
+		u.Age += 10
+	}
+	fmt.Println("After range copy:", users[0].Age, users[1].Age)
+	for i := range users {
+		users[i].Age += 10 // index into the slice to change the real element
+	}
+	fmt.Println("After users[i]:", users[0].Age, users[1].Age)
+	first := &users[0] // pointer to a slice element...
+	users = append(users, User{Name: "C"})
+	first.Age = 99 // ...may point to the OLD array after append grows the slice
+	fmt.Println("After append, change through old pointer:", users[0].Age, "(unchanged if the slice was reallocated)")
+
+	// stack vs heap: returning a value vs returning a pointer
+	const n = 5_000_000
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		sinkValue = newUserValue(i)
+	}
+	valueTime := time.Since(start)
+	start = time.Now()
+	for i := 0; i < n; i++ {
+		sinkPointer = newUserPointer(i)
+	}
+	pointerTime := time.Since(start)
+	fmt.Printf("%d calls: return value %s, return pointer (heap) %s\n", n, valueTime.Round(time.Millisecond), pointerTime.Round(time.Millisecond))
+}
 
 func main() {
 	fmt.Println("Pointers in Go")
@@ -19,4 +153,12 @@ func main() {
 	fmt.Println("Address of x:", &x)              // Output: Address of x:
 	fmt.Println("Value of ptr:", ptr)             // Output: Value of ptr:
 	fmt.Println("Value pointed to by ptr:", *ptr) // Output: Value pointed to by ptr: 42
+
+	PointerSemanticsExamples()
 }
+
+// Pass a pointer when the function must change the caller's variable, or the struct is big.
+// A nil pointer can call methods, only dereferencing it panics: check for nil in methods that allow it.
+// Go has no pointer arithmetic, and taking the address of a local variable is safe:
+// the compiler moves it to the heap if it outlives the function (escape analysis).
+// Heap allocations cost more and create work for the garbage collector, values often don't.
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestSwapInts(t *testing.T) {
+	a, b := 1, 2
+	SwapInts(&a, &b)
+	if a != 2 || b != 1 {
+		t.Errorf("got %d, %d", a, b)
+	}
+	SwapInts(&a, &a) // the same variable twice is fine
+	if a != 2 {
+		t.Errorf("self swap changed a to %d", a)
+	}
+}
+
+func TestPtrAndDeref(t *testing.T) {
+	p := Ptr(5)
+	*p = 6 // a copy: changing it must not affect the next call
+	if *Ptr(5) != 5 || Deref(p, -1) != 6 {
+		t.Error("Ptr does not return a fresh copy")
+	}
+	if Deref[int](nil, -1) != -1 || Deref[string](nil, "none") != "none" || Deref[*User](nil, nil) != nil {
+		t.Error("Deref(nil) did not return the fallback")
+	}
+	empty := ""
+	if Deref(&empty, "fallback") != "" {
+		t.Error("Deref of a pointer to the zero value returned the fallback")
+	}
+	if u := (User{Name: "a"}); Deref(Ptr(u), User{}).Name != "a" {
+		t.Error("Deref of a struct pointer")
+	}
+}
+
+func TestNilReceiver(t *testing.T) {
+	var nobody *User
+	if got := nobody.GetName(); got != "<nobody>" {
+		t.Errorf("GetName on nil = %q", got)
+	}
+	if got := (&User{Name: "R"}).GetName(); got != "R" {
+		t.Errorf("GetName = %q", got)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("UnsafeName on nil did not panic")
+		}
+	}()
+	nobody.UnsafeName()
+}
+
+func TestMutationThroughPointers(t *testing.T) {
+	pointers := map[string]*User{"r": {Age: 23}}
+	alias := pointers["r"]
+	pointers["r"].Age++
+	if alias.Age != 24 {
+		t.Errorf("map of pointers: %d, want 24", alias.Age)
+	}
+
+	values := map[string]User{"r": {Age: 23}}
+	u := values["r"]
+	u.Age++
+	if values["r"].Age != 23 {
+		t.Error("changing the copy changed the map")
+	}
+	values["r"] = u
+	if values["r"].Age != 24 {
+		t.Errorf("after storing back: %d", values["r"].Age)
+	}
+
+	users := []*User{{Age: 1}, {Age: 2}}
+	for _, u := range users {
+		u.Age += 10 // the copy is a pointer, so this changes the shared User
+	}
+	if users[0].Age != 11 || users[1].Age != 12 {
+		t.Errorf("slice of pointers: %d, %d", users[0].Age, users[1].Age)
+	}
+}
+
+func TestEscape(t *testing.T) {
+	if n := testing.AllocsPerRun(100, func() { sinkValue = newUserValue(1) }); n != 0 {
+		t.Errorf("returning a value: %v allocations, want 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { sinkPointer = newUserPointer(1) }); n != 1 {
+		t.Errorf("returning a pointer: %v allocations, want 1 (the User escapes)", n)
+	}
+}
+
+func BenchmarkReturnValue(b *testing.B) {
+	for b.Loop() {
+		sinkValue = newUserValue(1)
+	}
+}
+
+// reports 1 alloc/op with -benchmem: the User escaped to the heap
+func BenchmarkReturnPointer(b *testing.B) {
+	for b.Loop() {
+		sinkPointer = newUserPointer(1)
+	}
+}
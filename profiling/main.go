@@ -0,0 +1,275 @@
+package main
+
+import (
+	"compress/gzip"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A profile tells you where the time (or memory) really goes, instead of guessing.
+// Two ways to get one:
+//   - from code: runtime/pprof writes a profile to a file around the part you care about
+//   - from a running server: net/http/pprof serves profiles over HTTP
+// Both files are read with `go tool pprof`.
+
+// CaptureCPU writes a CPU profile to path while fn runs
+func CaptureCPU(path string, fn func()) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cpu profile: %w", err)
+	}
+	if err := rpprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return fmt.Errorf("cpu profile: %w", err) // e.g. another profile is already running
+	}
+	defer func() {
+		// deferred so a panicking fn doesn't leave the profiler running, no other capture could start
+		rpprof.StopCPUProfile()
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("cpu profile: %w", cerr)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// CaptureHeap writes the live heap to path. It runs a GC first so the numbers are up to date.
+func CaptureHeap(path string) error {
+	return writeProfile("heap", path, func(w io.Writer) error {
+		runtime.GC()
+		return rpprof.Lookup("heap").WriteTo(w, 0)
+	})
+}
+
+// DumpGoroutines writes every goroutine's stack as text, what LeakCheck prints on failure
+func DumpGoroutines(w io.Writer) error {
+	return rpprof.Lookup("goroutine").WriteTo(w, 1) // 1: text with counts, 0 would be the binary format
+}
+
+// LeakCheck counts the goroutines now and returns a check to call when the work is done.
+// The check waits up to wait for the count to come back down, goroutines take a moment
+// to exit, and if it doesn't the error carries the dump showing where the extra ones are stuck.
+func LeakCheck(wait time.Duration) func() error {
+	before := runtime.NumGoroutine()
+	return func() error {
+		for deadline := time.Now().Add(wait); runtime.NumGoroutine() > before; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				var dump strings.Builder
+				DumpGoroutines(&dump)
+				return fmt.Errorf("%d goroutines before, %d after:\n%s", before, runtime.NumGoroutine(), dump.String())
+			}
+		}
+		return nil
+	}
+}
+
+func writeProfile(name, path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("%s profile: %w", name, err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%s profile: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("%s profile: %w", name, err)
+	}
+	return nil
+}
+
+// checkProfile does what a test would: the file exists, isn't empty, and is gzip
+// (pprof files are gzipped protobuf, go tool pprof reads them)
+func checkProfile(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() == 0 {
+		return 0, errors.New("empty profile")
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("not a pprof file: %w", err)
+	}
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return 0, fmt.Errorf("not a pprof file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// ----------------------------------------------------------------------------
+// pprof over HTTP
+
+// debugRoutes mounts the pprof handlers under /debug/pprof/. Importing net/http/pprof
+// registers them on http.DefaultServeMux too, so never serve DefaultServeMux publicly.
+func debugRoutes(mux *http.ServeMux, token string) {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/debug/pprof/", pprof.Index) // also serves heap, goroutine, allocs... by name
+	debug.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	debug.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	debug.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/pprof/", requireToken(token, debug))
+}
+
+// requireToken: profiles show function names, command lines and memory, keep them private
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ----------------------------------------------------------------------------
+// Something to profile: fibonacci numbers computed by a worker pool
+
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2) // slow on purpose, it will be the top of the profile
+}
+
+func fibWorkerPool(jobs []int, workers int) []int {
+	results := make([]int, len(jobs))
+	ch := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				results[i] = fib(jobs[i])
+			}
+		}()
+	}
+	for i := range jobs {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	return results
+}
+
+func main() {
+	fmt.Println("Learning to profile Go programs")
+	debugFlag := flag.Bool("debug", false, "serve /debug/pprof on -addr (needs -token)")
+	addr := flag.String("addr", "127.0.0.1:6060", "debug server address")
+	token := flag.String("token", "", "bearer token for /debug/pprof")
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "profiles")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	cpuPath := filepath.Join(dir, "cpu.pprof")
+	var results []int
+	start := time.Now()
+	err = CaptureCPU(cpuPath, func() {
+		jobs := []int{32, 33, 34, 30, 31, 32, 33, 29}
+		results = fibWorkerPool(jobs, 4)
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("fib results:", results, "in", time.Since(start).Round(time.Millisecond))
+
+	heapPath := filepath.Join(dir, "heap.pprof")
+	if err := CaptureHeap(heapPath); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, p := range []string{cpuPath, heapPath} {
+		size, err := checkProfile(p)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		fmt.Printf("Wrote %s (%d bytes)\n", p, size)
+	}
+	fmt.Println("The files are kept, look at them with:")
+	fmt.Println("  go tool pprof -top", cpuPath, "   # fib should be at the top")
+	fmt.Println("  go tool pprof -http=:8081", cpuPath, "   # flame graph in the browser")
+
+	// a second CPU profile can't start while one is running
+	err = CaptureCPU(filepath.Join(dir, "outer.pprof"), func() {
+		fmt.Println("Nested CaptureCPU:", CaptureCPU(filepath.Join(dir, "inner.pprof"), func() {}))
+	})
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// a leaked goroutine, and the dump that shows where it's stuck
+	check := LeakCheck(50 * time.Millisecond)
+	block := make(chan struct{})
+	go func() { <-block }()
+	if err := check(); err != nil {
+		for line := range strings.Lines(err.Error()) {
+			if strings.Contains(line, "main.main.func") {
+				fmt.Print("Goroutine dump finds the leak: ", strings.TrimSpace(line), "\n")
+				break
+			}
+		}
+	}
+	close(block)
+
+	// the HTTP endpoints, with and without the token
+	demoToken := *token
+	if demoToken == "" {
+		demoToken = "s3cret"
+	}
+	mux := http.NewServeMux()
+	debugRoutes(mux, demoToken)
+	for _, auth := range []string{"", "Bearer wrong", "Bearer " + demoToken} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+		req.Header.Set("Authorization", auth)
+		mux.ServeHTTP(rec, req)
+		first, _, _ := strings.Cut(rec.Body.String(), "\n")
+		fmt.Printf("GET /debug/pprof/goroutine (auth %-15q) -> %d %s\n", auth, rec.Code, first)
+	}
+
+	if *debugFlag {
+		if *token == "" {
+			fmt.Println("Error: -debug needs -token")
+			return
+		}
+		fmt.Printf("Serving pprof on http://%s/debug/pprof/, try:\n", *addr)
+		fmt.Printf("  curl -H 'Authorization: Bearer %s' http://%s/debug/pprof/profile?seconds=5 > cpu.pprof\n", *token, *addr)
+		if err := http.ListenAndServe(*addr, mux); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+}
+
+// runtime/pprof.StartCPUProfile/StopCPUProfile around code writes a CPU profile, Lookup("heap") a memory one.
+// net/http/pprof serves the same profiles from a running server, put it behind auth on its own mux.
+// go tool pprof -top or -http reads the file, look for the functions with the most "cum" time.
+// A goroutine dump (Lookup("goroutine") with debug=1) shows where leaked goroutines are blocked.
+// Profile the real workload: a tiny input spends its time in setup, not in the code you care about.
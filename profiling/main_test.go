@@ -0,0 +1,181 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCaptureCPU(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cpu.pprof")
+	var results []int
+	err := CaptureCPU(path, func() { results = fibWorkerPool([]int{25, 26, 27}, 2) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(results, []int{75025, 121393, 196418}) {
+		t.Errorf("fn's results: %v", results)
+	}
+	if size, err := checkProfile(path); err != nil || size == 0 {
+		t.Errorf("checkProfile: %d, %v", size, err)
+	}
+}
+
+func TestCaptureCPUErrors(t *testing.T) {
+	dir := t.TempDir()
+	ran := false
+	if err := CaptureCPU(filepath.Join(dir, "missing", "cpu.pprof"), func() { ran = true }); err == nil || ran {
+		t.Errorf("bad path: %v, fn ran: %v", err, ran)
+	}
+	var inner error
+	outer := CaptureCPU(filepath.Join(dir, "outer.pprof"), func() {
+		inner = CaptureCPU(filepath.Join(dir, "inner.pprof"), func() { ran = true })
+	})
+	if outer != nil || inner == nil || ran {
+		t.Errorf("nested: outer %v, inner %v, inner fn ran: %v", outer, inner, ran)
+	}
+	// the failed start didn't stop the outer profile, and the next capture works again
+	if _, err := checkProfile(filepath.Join(dir, "outer.pprof")); err != nil {
+		t.Error(err)
+	}
+	if err := CaptureCPU(filepath.Join(dir, "again.pprof"), func() {}); err != nil {
+		t.Errorf("after nesting: %v", err)
+	}
+	// a panic in fn still stops the profile
+	func() {
+		defer func() { recover() }()
+		CaptureCPU(filepath.Join(dir, "panic.pprof"), func() { panic("boom") })
+	}()
+	if err := CaptureCPU(filepath.Join(dir, "after-panic.pprof"), func() {}); err != nil {
+		t.Errorf("after a panic: %v", err)
+	}
+}
+
+func TestCaptureHeap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "heap.pprof")
+	if err := CaptureHeap(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkProfile(path); err != nil {
+		t.Error(err)
+	}
+	if err := CaptureHeap(filepath.Join(t.TempDir(), "no", "heap.pprof")); err == nil || !strings.HasPrefix(err.Error(), "heap profile: ") {
+		t.Errorf("bad path: %v", err)
+	}
+}
+
+func TestCheckProfile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	f, _ := os.Create(filepath.Join(dir, "gz"))
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte("anything"))
+	zw.Close()
+	f.Close()
+	good, _ := os.ReadFile(filepath.Join(dir, "gz"))
+
+	tests := []struct {
+		name, path, want string
+	}{
+		{"missing", filepath.Join(dir, "nope"), "no such file"},
+		{"empty", write("empty", nil), "empty profile"},
+		{"not gzip", write("text", []byte("hello")), "not a pprof file"},
+		{"cut short", write("short", good[:len(good)-6]), "not a pprof file"},
+	}
+	for _, tt := range tests {
+		if _, err := checkProfile(tt.path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func parkedForTheDump(block chan struct{}) { <-block }
+
+func TestDumpGoroutines(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	go parkedForTheDump(block)
+	// the goroutine may not have run yet: its stack only shows once it is parked
+	var sb strings.Builder
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		sb.Reset()
+		if err := DumpGoroutines(&sb); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(sb.String(), ".parkedForTheDump+") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dump doesn't show the parked goroutine:\n%s", sb.String())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !strings.HasPrefix(sb.String(), "goroutine profile: total ") {
+		t.Errorf("not a debug=1 dump:\n%.200s", sb.String())
+	}
+}
+
+func TestLeakCheck(t *testing.T) {
+	check := LeakCheck(time.Second)
+	fibWorkerPool([]int{10, 20, 30}, 3)
+	if err := check(); err != nil {
+		t.Errorf("the worker pool leaked: %v", err)
+	}
+
+	check = LeakCheck(50 * time.Millisecond)
+	block := make(chan struct{})
+	defer close(block)
+	go parkedForTheDump(block)
+	err := check()
+	if err == nil || !strings.Contains(err.Error(), ".parkedForTheDump+") {
+		t.Errorf("the parked goroutine isn't reported: %v", err)
+	}
+}
+
+func TestDebugRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	debugRoutes(mux, "s3cret")
+	tests := []struct {
+		path, auth string
+		status     int
+		want       string
+	}{
+		{"/debug/pprof/", "", 401, "unauthorized"},
+		{"/debug/pprof/", "Bearer wrong", 401, "unauthorized"},
+		{"/debug/pprof/heap", "s3cret", 401, "unauthorized"}, // the Bearer prefix is required
+		{"/debug/pprof/", "Bearer s3cret", 200, "goroutine"},
+		{"/debug/pprof/goroutine?debug=1", "Bearer s3cret", 200, "goroutine profile: total"},
+		{"/debug/pprof/cmdline", "Bearer s3cret", 200, os.Args[0]},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Authorization", tt.auth)
+		mux.ServeHTTP(rec, req)
+		if rec.Code != tt.status || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s with %q: %d %.80q", tt.path, tt.auth, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestFib(t *testing.T) {
+	jobs := []int{0, 1, 2, 10, 20, 1}
+	want := []int{0, 1, 1, 55, 6765, 1}
+	for _, workers := range []int{1, 3, 10} {
+		if got := fibWorkerPool(jobs, workers); !slices.Equal(got, want) {
+			t.Errorf("%d workers: %v", workers, got)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters are strings, and every handler ends up with its own strconv calls,
+// its own range checks and its own error messages, and stops at the FIRST bad one.
+// A small helper reads typed values and collects every problem, so the client gets one
+// 400 that lists all of them instead of fixing its request one error at a time.
+
+type FieldError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+type Values struct {
+	q    url.Values
+	errs []FieldError
+}
+
+func Query(r *http.Request) *Values {
+	return &Values{q: r.URL.Query()}
+}
+
+// raw returns the value and whether the client really sent one.
+// "?page=" counts as not sent, that's what an empty form field produces.
+func (v *Values) raw(name string) (string, bool) {
+	vals := v.q[name]
+	if len(vals) > 1 {
+		v.fail(name, "given more than once")
+		return "", false
+	}
+	if len(vals) == 0 || strings.TrimSpace(vals[0]) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(vals[0]), true
+}
+
+func (v *Values) fail(name, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Param: name, Message: fmt.Sprintf(format, args...)})
+}
+
+// Int returns def when the parameter is missing or empty, and records an error
+// (returning def, so the handler can keep going) when it isn't a number from lo to hi.
+func (v *Values) Int(name string, def, lo, hi int) int {
+	s, ok := v.raw(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		v.fail(name, "must be a whole number")
+		return def
+	}
+	if n < lo || n > hi {
+		v.fail(name, "must be from %d to %d", lo, hi)
+		return def
+	}
+	return n
+}
+
+// String returns the value, or allowed[0] when it's missing. With allowed values,
+// anything else is an error; without them any string is accepted and "" is the default.
+func (v *Values) String(name string, allowed ...string) string {
+	def := ""
+	if len(allowed) > 0 {
+		def = allowed[0]
+	}
+	s, ok := v.raw(name)
+	if !ok {
+		return def
+	}
+	if len(allowed) > 0 && !slices.Contains(allowed, s) {
+		v.fail(name, "must be one of %s", strings.Join(allowed, ", "))
+		return def
+	}
+	return s
+}
+
+func (v *Values) Bool(name string, def bool) bool {
+	s, ok := v.raw(name)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(s) // 1, t, true, TRUE, 0, f, false...
+	if err != nil {
+		v.fail(name, "must be true or false")
+		return def
+	}
+	return b
+}
+
+// Time returns the zero time when the parameter is missing, check it with IsZero
+func (v *Values) Time(name, layout string) time.Time {
+	s, ok := v.raw(name)
+	if !ok {
+		return time.Time{}
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		v.fail(name, "must be a time like %s", layout)
+		return time.Time{}
+	}
+	return t
+}
+
+// Err returns every problem found so far, nil when there were none
+func (v *Values) Err() []FieldError {
+	return v.errs
+}
+
+// ----------------------------------------------------------------------------
+// Handlers
+
+type User struct {
+	ID      int       `json:"id"`
+	Name    string    `json:"name"`
+	Active  bool      `json:"active"`
+	Created time.Time `json:"created"`
+}
+
+var users = []User{
+	{1, "Rishabh", true, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)},
+	{2, "Sanchay", true, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+	{3, "Alice", false, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	{4, "Bob", true, time.Date(2024, 9, 20, 0, 0, 0, 0, time.UTC)},
+	{5, "Maya", true, time.Date(2025, 2, 14, 0, 0, 0, 0, time.UTC)},
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// badQuery answers 400 with the full list and reports whether it did
+func badQuery(w http.ResponseWriter, q *Values) bool {
+	if errs := q.Err(); errs != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid query parameters", "fields": errs})
+		return true
+	}
+	return false
+}
+
+// handleGetUsers: GET /api/users?page=&per_page=&sort=id|name
+func handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	q := Query(r)
+	page := q.Int("page", 1, 1, 10_000)
+	perPage := q.Int("per_page", 2, 1, 100)
+	sortBy := q.String("sort", "id", "name")
+	if badQuery(w, q) {
+		return
+	}
+	list := slices.Clone(users)
+	if sortBy == "name" {
+		slices.SortFunc(list, func(a, b User) int { return strings.Compare(a.Name, b.Name) })
+	}
+	start := min((page-1)*perPage, len(list))
+	writeJSON(w, http.StatusOK, list[start:min(start+perPage, len(list))])
+}
+
+// handleSearch: GET /api/users/search?q=&active=&since=2024-01-31&limit=
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := Query(r)
+	text := strings.ToLower(q.String("q"))
+	activeOnly := q.Bool("active", false)
+	since := q.Time("since", time.DateOnly)
+	limit := q.Int("limit", 10, 1, 50)
+	if badQuery(w, q) {
+		return
+	}
+	var found []User
+	for _, u := range users {
+		if strings.Contains(strings.ToLower(u.Name), text) && (!activeOnly || u.Active) &&
+			(since.IsZero() || !u.Created.Before(since)) && len(found) < limit {
+			found = append(found, u)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"count": len(found), "users": found})
+}
+
+// handleSlow: GET /api/slow?ms= waits that long, for trying out timeouts
+func handleSlow(w http.ResponseWriter, r *http.Request) {
+	q := Query(r)
+	ms := q.Int("ms", 100, 0, 5_000)
+	if badQuery(w, q) {
+		return
+	}
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		writeJSON(w, http.StatusOK, map[string]int{"slept_ms": ms})
+	case <-r.Context().Done():
+	}
+}
+
+func main() {
+	fmt.Println("Learning typed query parameter validation in Go")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", handleGetUsers)
+	mux.HandleFunc("GET /api/users/search", handleSearch)
+	mux.HandleFunc("GET /api/slow", handleSlow)
+
+	for _, target := range []string{
+		"/api/users",
+		"/api/users?page=2&sort=name",
+		"/api/users?page=&per_page=", // empty means "not sent": defaults
+		"/api/users?page=0&per_page=abc&sort=age",
+		"/api/users?page=1&page=2",
+		"/api/users/search?q=a&active=true&since=2024-03-01",
+		"/api/users/search?active=maybe&since=yesterday&limit=500",
+		"/api/slow?ms=20",
+		"/api/slow?ms=-5",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		fmt.Printf("GET %s\n  -> %d %s", target, rec.Code, rec.Body.String())
+	}
+
+	// each extractor on its own: what comes back and what gets recorded
+	fmt.Println("Extractors:")
+	cases := []struct {
+		query string
+		get   func(*Values) any
+	}{
+		{"", func(v *Values) any { return v.Int("n", 7, 1, 10) }},
+		{"n=3", func(v *Values) any { return v.Int("n", 7, 1, 10) }},
+		{"n=+3", func(v *Values) any { return v.Int("n", 7, 1, 10) }},
+		{"n=3.5", func(v *Values) any { return v.Int("n", 7, 1, 10) }},
+		{"n=11", func(v *Values) any { return v.Int("n", 7, 1, 10) }},
+		{"s=%20desc%20", func(v *Values) any { return v.String("s", "asc", "desc") }},
+		{"s=DESC", func(v *Values) any { return v.String("s", "asc", "desc") }},
+		{"b=1", func(v *Values) any { return v.Bool("b", false) }},
+		{"b=yes", func(v *Values) any { return v.Bool("b", false) }},
+		{"t=2025-02-30", func(v *Values) any { return v.Time("t", time.DateOnly) }},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/?"+c.query, nil)
+		v := Query(req)
+		got := c.get(v)
+		if t, ok := got.(time.Time); ok && t.IsZero() {
+			got = "zero time"
+		}
+		fmt.Printf("  %-14q -> %-10v errors %v\n", c.query, got, v.Err())
+	}
+}
+
+// Collect every bad parameter and answer once, a fail-fast 400 makes clients fix errors one by one.
+// Treat "?page=" like a missing page: forms send empty fields, and a default is what the user meant.
+// An extractor that fails still returns the default, so the handler reads every param before checking Err.
+// Range checks live next to the parse (Int with lo/hi, String with allowed values), not later in the handler.
+// Repeated params (?page=1&page=2) are ambiguous, reject them instead of silently taking the first.
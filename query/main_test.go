@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func values(query string) *Values {
+	return Query(httptest.NewRequest("GET", "/?"+query, nil))
+}
+
+// errParams lists the params with errors, in the order they were recorded
+func errParams(v *Values) []string {
+	var out []string
+	for _, e := range v.Err() {
+		out = append(out, e.Param)
+	}
+	return out
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+		bad   bool
+	}{
+		{"", 7, false},
+		{"n=", 7, false},
+		{"n=%20%20", 7, false},
+		{"n=3", 3, false},
+		{"n=%203%20", 3, false},
+		{"n=+3", 3, false},
+		{"n=1", 1, false},
+		{"n=10", 10, false},
+		{"n=0", 7, true},
+		{"n=11", 7, true},
+		{"n=-1", 7, true},
+		{"n=3.5", 7, true},
+		{"n=abc", 7, true},
+		{"n=99999999999999999999", 7, true},
+		{"n=3&n=4", 7, true},
+		{"n=&n=", 7, true}, // repeated, even when empty
+		{"other=3", 7, false},
+	}
+	for _, tt := range tests {
+		v := values(tt.query)
+		got := v.Int("n", 7, 1, 10)
+		if got != tt.want || (v.Err() != nil) != tt.bad {
+			t.Errorf("%q: %d %v, want %d bad=%v", tt.query, got, v.Err(), tt.want, tt.bad)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		query   string
+		allowed []string
+		want    string
+		bad     bool
+	}{
+		{"", []string{"asc", "desc"}, "asc", false},
+		{"s=", []string{"asc", "desc"}, "asc", false},
+		{"s=desc", []string{"asc", "desc"}, "desc", false},
+		{"s=%20desc%20", []string{"asc", "desc"}, "desc", false},
+		{"s=DESC", []string{"asc", "desc"}, "asc", true},
+		{"s=age", []string{"asc", "desc"}, "asc", true},
+		{"", nil, "", false},
+		{"s=anything%20at%20all", nil, "anything at all", false},
+		{"s=a&s=b", nil, "", true},
+	}
+	for _, tt := range tests {
+		v := values(tt.query)
+		got := v.String("s", tt.allowed...)
+		if got != tt.want || (v.Err() != nil) != tt.bad {
+			t.Errorf("%q %v: %q %v, want %q bad=%v", tt.query, tt.allowed, got, v.Err(), tt.want, tt.bad)
+		}
+	}
+	v := values("s=x")
+	v.String("s", "id", "name")
+	if v.Err()[0].Message != "must be one of id, name" {
+		t.Errorf("message %q", v.Err()[0].Message)
+	}
+}
+
+func TestBool(t *testing.T) {
+	tests := []struct {
+		query string
+		def   bool
+		want  bool
+		bad   bool
+	}{
+		{"", true, true, false},
+		{"b=", true, true, false},
+		{"b=1", false, true, false},
+		{"b=TRUE", false, true, false},
+		{"b=t", false, true, false},
+		{"b=0", true, false, false},
+		{"b=false", true, false, false},
+		{"b=yes", true, true, true},
+		{"b=2", false, false, true},
+	}
+	for _, tt := range tests {
+		v := values(tt.query)
+		got := v.Bool("b", tt.def)
+		if got != tt.want || (v.Err() != nil) != tt.bad {
+			t.Errorf("%q default %v: %v %v, want %v bad=%v", tt.query, tt.def, got, v.Err(), tt.want, tt.bad)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	tests := []struct {
+		query, layout string
+		want          time.Time
+		bad           bool
+	}{
+		{"", time.DateOnly, time.Time{}, false},
+		{"t=", time.DateOnly, time.Time{}, false},
+		{"t=2024-03-01", time.DateOnly, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), false},
+		{"t=2025-02-30", time.DateOnly, time.Time{}, true},
+		{"t=yesterday", time.DateOnly, time.Time{}, true},
+		{"t=2024-03-01T10:00:00Z", time.DateOnly, time.Time{}, true},
+		{"t=2024-03-01T10:00:00%2B02:00", time.RFC3339, time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		v := values(tt.query)
+		got := v.Time("t", tt.layout)
+		if !got.Equal(tt.want) || (v.Err() != nil) != tt.bad {
+			t.Errorf("%q: %v %v, want %v bad=%v", tt.query, got, v.Err(), tt.want, tt.bad)
+		}
+	}
+}
+
+// every extractor runs, every problem is recorded, in the order the handler asked
+func TestAccumulatesErrors(t *testing.T) {
+	v := values("page=0&per_page=abc&sort=age&active=maybe&since=x&ok=5")
+	v.Int("page", 1, 1, 100)
+	v.Int("per_page", 10, 1, 100)
+	v.String("sort", "id", "name")
+	v.Bool("active", false)
+	v.Time("since", time.DateOnly)
+	if n := v.Int("ok", 1, 1, 10); n != 5 {
+		t.Errorf("a good param after bad ones: %d", n)
+	}
+	if got, want := errParams(v), []string{"page", "per_page", "sort", "active", "since"}; !slices.Equal(got, want) {
+		t.Errorf("errors for %v, want %v", got, want)
+	}
+	if v := values("page=2"); v.Int("page", 1, 1, 9) != 2 || v.Err() != nil {
+		t.Errorf("no errors: Err() = %#v, want nil", v.Err())
+	}
+}
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/users", handleGetUsers)
+	mux.HandleFunc("GET /api/users/search", handleSearch)
+	mux.HandleFunc("GET /api/slow", handleSlow)
+	return mux
+}
+
+type badResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields"`
+}
+
+func TestHandlersReportEveryParam(t *testing.T) {
+	mux := newMux()
+	tests := []struct {
+		target string
+		params []string
+	}{
+		{"/api/users?page=0&per_page=abc&sort=age", []string{"page", "per_page", "sort"}},
+		{"/api/users?page=1&page=2", []string{"page"}},
+		{"/api/users/search?active=maybe&since=yesterday&limit=500", []string{"active", "since", "limit"}},
+		{"/api/users/search?q=a&q=b", []string{"q"}},
+		{"/api/slow?ms=-5", []string{"ms"}},
+		{"/api/slow?ms=5001", []string{"ms"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+		var resp badResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		var got []string
+		for _, f := range resp.Fields {
+			got = append(got, f.Param)
+			if f.Message == "" {
+				t.Errorf("%s: no message for %s", tt.target, f.Param)
+			}
+		}
+		if rec.Code != http.StatusBadRequest || resp.Error != "invalid query parameters" || !slices.Equal(got, tt.params) {
+			t.Errorf("%s: %d %s", tt.target, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	mux := newMux()
+	tests := map[string][]int{
+		"/api/users":                             {1, 2},
+		"/api/users?page=&per_page=":             {1, 2},
+		"/api/users?page=2":                      {3, 4},
+		"/api/users?page=3":                      {5},
+		"/api/users?page=9":                      {},
+		"/api/users?sort=name&per_page=3":        {3, 4, 5},
+		"/api/users?sort=id&per_page=100":        {1, 2, 3, 4, 5},
+		"/api/users?sort=name&page=2&per_page=3": {1, 2},
+	}
+	for target, want := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		var list []User
+		json.Unmarshal(rec.Body.Bytes(), &list)
+		ids := []int{}
+		for _, u := range list {
+			ids = append(ids, u.ID)
+		}
+		if rec.Code != 200 || !slices.Equal(ids, want) {
+			t.Errorf("%s: %d %v, want %v", target, rec.Code, ids, want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	mux := newMux()
+	tests := map[string][]int{
+		"/api/users/search":                           {1, 2, 3, 4, 5},
+		"/api/users/search?q=A":                       {1, 2, 3, 5},
+		"/api/users/search?q=a&active=true":           {1, 2, 5},
+		"/api/users/search?active=1&since=2024-03-05": {2, 4, 5},
+		"/api/users/search?since=2025-01-01":          {5},
+		"/api/users/search?limit=2":                   {1, 2},
+		"/api/users/search?q=&active=&since=&limit=":  {1, 2, 3, 4, 5},
+	}
+	for target, want := range tests {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		var resp struct {
+			Count int
+			Users []User
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		ids := []int{}
+		for _, u := range resp.Users {
+			ids = append(ids, u.ID)
+		}
+		if rec.Code != 200 || resp.Count != len(want) || !slices.Equal(ids, want) {
+			t.Errorf("%s: %d %v, want %v", target, rec.Code, ids, want)
+		}
+	}
+}
+
+func TestSlow(t *testing.T) {
+	mux := newMux()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/slow?ms=0", nil))
+	if rec.Code != 200 || rec.Body.String() != "{\"slept_ms\":0}\n" {
+		t.Errorf("ms=0: %d %s", rec.Code, rec.Body)
+	}
+
+	// a cancelled request returns without waiting the full time, and writes nothing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	start := time.Now()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/slow?ms=5000", nil).WithContext(ctx))
+	if time.Since(start) > time.Second || rec.Body.Len() != 0 {
+		t.Errorf("cancelled: took %v, wrote %q", time.Since(start), rec.Body)
+	}
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A tiny message broker, like a mini Kafka/RabbitMQ/SQS in one process:
+//   - a topic is a named stream of messages
+//   - every consumer group subscribed to a topic gets its own copy of each message
+//   - inside a group, each message goes to ONE member (competing consumers share the work)
+//   - a message must be acked within VisibilityTimeout, otherwise it is delivered again.
+//     That's "at-least-once": nothing is lost, but a consumer may see a message twice,
+//     so processing should be idempotent.
+
+var (
+	ErrNoTopic      = errors.New("no such topic")
+	ErrTopicExists  = errors.New("topic already exists")
+	ErrClosed       = errors.New("broker closed")
+	ErrAlreadyAcked = errors.New("message already acked")
+)
+
+type Message struct {
+	ID    uint64
+	Topic string
+	Body  string
+}
+
+// Delivery is one attempt to hand a message to a group member
+type Delivery struct {
+	Message
+	Group   string
+	Attempt int // 1 the first time, 2+ for redeliveries
+	g       *group
+}
+
+// Ack marks the message as processed by the group. Acking twice returns ErrAlreadyAcked,
+// so a consumer can safely retry an Ack it isn't sure about.
+func (d Delivery) Ack() error {
+	return d.g.ack(d.ID)
+}
+
+type entry struct {
+	msg      Message
+	attempts int
+	deadline time.Time // when an in-flight message becomes visible again
+}
+
+// group holds the queue of one consumer group and the goroutine that feeds its members
+type group struct {
+	name    string
+	timeout time.Duration
+	out     chan Delivery // all members receive from the same channel: that's what makes them compete
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	mu       sync.Mutex
+	queue    []*entry
+	inflight map[uint64]*entry
+}
+
+func newGroup(name string, timeout time.Duration) *group {
+	g := &group{
+		name:     name,
+		timeout:  timeout,
+		out:      make(chan Delivery),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		inflight: make(map[uint64]*entry),
+	}
+	go g.dispatch()
+	return g
+}
+
+func (g *group) enqueue(msg Message) {
+	g.mu.Lock()
+	g.queue = append(g.queue, &entry{msg: msg})
+	g.mu.Unlock()
+	select {
+	case g.wake <- struct{}{}: // a non-blocking nudge, one pending wake-up is enough
+	default:
+	}
+}
+
+// dispatch hands queued messages to members one by one and requeues expired ones
+func (g *group) dispatch() {
+	defer close(g.done)
+	defer close(g.out) // members ranging over the channel stop after shutdown
+
+	// at least minTick: NewTicker panics on a tick <= 0, and a timeout under 4ns would give one
+	ticker := time.NewTicker(max(g.timeout/4, minTick))
+	defer ticker.Stop()
+	for {
+		// the message is moved to in-flight BEFORE it's sent, so a member that acks
+		// right after receiving always finds it there
+		g.mu.Lock()
+		var next *entry
+		if len(g.queue) > 0 {
+			next = g.queue[0]
+			g.queue = g.queue[1:]
+			next.attempts++
+			next.deadline = time.Now().Add(g.timeout)
+			g.inflight[next.msg.ID] = next
+		}
+		g.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-g.wake:
+			case <-ticker.C:
+				g.requeueExpired()
+			case <-g.stop:
+				return
+			}
+			continue
+		}
+
+		d := Delivery{Message: next.msg, Group: g.name, Attempt: next.attempts, g: g}
+		select {
+		case g.out <- d:
+			g.mu.Lock()
+			next.deadline = time.Now().Add(g.timeout) // the timeout starts when a member has it
+			g.mu.Unlock()
+		case <-ticker.C:
+			g.unsend(next)
+			g.requeueExpired()
+		case <-g.stop:
+			return
+		}
+	}
+}
+
+// unsend puts back a message that no member took, so it is first again with the same attempt count.
+// If it is no longer in flight, an older delivery of it was acked meanwhile: it's done.
+func (g *group) unsend(e *entry) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.inflight[e.msg.ID]; !ok {
+		return
+	}
+	delete(g.inflight, e.msg.ID)
+	e.attempts--
+	g.queue = append([]*entry{e}, g.queue...)
+}
+
+func (g *group) requeueExpired() {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var expired []*entry
+	for id, e := range g.inflight {
+		if now.After(e.deadline) {
+			delete(g.inflight, id)
+			expired = append(expired, e)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].msg.ID < expired[j].msg.ID })
+	g.queue = append(g.queue, expired...)
+}
+
+func (g *group) ack(id uint64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.inflight[id]; ok {
+		delete(g.inflight, id)
+		return nil
+	}
+	// a slow consumer acking after the timeout: the message is waiting for redelivery, drop it
+	for i, e := range g.queue {
+		if e.msg.ID == id && e.attempts > 0 {
+			g.queue = append(g.queue[:i], g.queue[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("ack message %d in group %s: %w", id, g.name, ErrAlreadyAcked)
+}
+
+func (g *group) pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.queue) + len(g.inflight)
+}
+
+// DefaultVisibilityTimeout is used when NewBroker gets a timeout <= 0
+const DefaultVisibilityTimeout = 30 * time.Second
+
+// minTick bounds how often a group checks for expired messages, a tiny timeout would busy-loop
+const minTick = time.Millisecond
+
+type Broker struct {
+	VisibilityTimeout time.Duration
+
+	mu     sync.RWMutex
+	topics map[string]map[string]*group // topic -> group name -> group
+	closed bool
+	nextID atomic.Uint64
+}
+
+// NewBroker returns a broker whose messages are redelivered when not acked within
+// visibilityTimeout, a timeout <= 0 means DefaultVisibilityTimeout
+func NewBroker(visibilityTimeout time.Duration) *Broker {
+	if visibilityTimeout <= 0 {
+		visibilityTimeout = DefaultVisibilityTimeout
+	}
+	return &Broker{VisibilityTimeout: visibilityTimeout, topics: make(map[string]map[string]*group)}
+}
+
+func (b *Broker) CreateTopic(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrClosed
+	}
+	if _, ok := b.topics[name]; ok {
+		return fmt.Errorf("create topic %q: %w", name, ErrTopicExists)
+	}
+	b.topics[name] = make(map[string]*group)
+	return nil
+}
+
+// Subscribe joins group on topic. Every call with the same group returns the same channel:
+// run several goroutines reading from it to share the work. The channel is closed by Close.
+// Like most pub/sub systems, a new group only sees messages published after it subscribed.
+func (b *Broker) Subscribe(topic, groupName string) (<-chan Delivery, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrClosed
+	}
+	groups, ok := b.topics[topic]
+	if !ok {
+		return nil, fmt.Errorf("subscribe to %q: %w", topic, ErrNoTopic)
+	}
+	g, ok := groups[groupName]
+	if !ok {
+		g = newGroup(groupName, b.VisibilityTimeout)
+		groups[groupName] = g
+	}
+	return g.out, nil
+}
+
+// Publish copies body to every group of topic and returns the message ID
+func (b *Broker) Publish(topic, body string) (uint64, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return 0, ErrClosed
+	}
+	groups, ok := b.topics[topic]
+	if !ok {
+		return 0, fmt.Errorf("publish to %q: %w", topic, ErrNoTopic)
+	}
+	msg := Message{ID: b.nextID.Add(1), Topic: topic, Body: body}
+	for _, g := range groups {
+		g.enqueue(msg)
+	}
+	return msg.ID, nil
+}
+
+// Close stops new publishes, waits until every message is acked (the consumers must
+// still be running) or ctx is done, then stops the groups and closes their channels.
+func (b *Broker) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	var all []*group
+	for _, groups := range b.topics {
+		for _, g := range groups {
+			all = append(all, g)
+		}
+	}
+	b.mu.Unlock()
+
+	var err error
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		left := 0
+		for _, g := range all {
+			left += g.pending()
+		}
+		if left == 0 {
+			break
+		}
+		select {
+		case <-ticker.C:
+			continue
+		case <-ctx.Done():
+			err = fmt.Errorf("close broker with %d messages not acked: %w", left, ctx.Err())
+		}
+		break
+	}
+	for _, g := range all {
+		close(g.stop)
+		<-g.done
+	}
+	return err
+}
+
+// worker processes deliveries until the channel is closed
+func worker(name string, deliveries <-chan Delivery, process func(Delivery) bool, log *logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for d := range deliveries {
+		if !process(d) {
+			log.printf("%-10s %s attempt %d: no ack", name, d.Body, d.Attempt)
+			continue
+		}
+		if err := d.Ack(); err != nil {
+			log.printf("%-10s %s attempt %d: %v", name, d.Body, d.Attempt, err)
+			continue
+		}
+		log.printf("%-10s %s attempt %d: done", name, d.Body, d.Attempt)
+	}
+}
+
+// logger keeps the lines of concurrent workers from mixing
+type logger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logger) printf(format string, args ...any) {
+	l.mu.Lock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func main() {
+	fmt.Println("Learning a message queue with consumer groups in Go")
+
+	broker := NewBroker(200 * time.Millisecond)
+	broker.CreateTopic("orders")
+	fmt.Println("CreateTopic twice:", broker.CreateTopic("orders"))
+	_, err := broker.Publish("payments", "x")
+	fmt.Println("Publish to a missing topic:", err)
+
+	billing, _ := broker.Subscribe("orders", "billing")
+	shipping, _ := broker.Subscribe("orders", "shipping")
+
+	log := &logger{}
+	var wg sync.WaitGroup
+	var billed sync.Map // what billing handled, to show at-least-once and idempotent processing
+	billingWork := func(d Delivery) bool {
+		if _, dup := billed.LoadOrStore(d.ID, true); dup {
+			return true // already charged: ack again without charging twice
+		}
+		time.Sleep(10 * time.Millisecond)
+		return true
+	}
+	slowOnce := true
+	var slowMu sync.Mutex
+	shippingWork := func(d Delivery) bool {
+		slowMu.Lock()
+		slow := slowOnce && strings.HasSuffix(d.Body, "#3")
+		if slow {
+			slowOnce = false
+		}
+		slowMu.Unlock()
+		if slow {
+			time.Sleep(400 * time.Millisecond) // longer than the visibility timeout: it is requeued, the late Ack removes it again
+		}
+		return true
+	}
+	wg.Add(3)
+	go worker("billing-1", billing, billingWork, log, &wg) // two members compete in billing
+	go worker("billing-2", billing, billingWork, log, &wg)
+	go worker("shipping-1", shipping, shippingWork, log, &wg)
+	// a member that crashes (never acks) the first message it gets
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d, ok := <-shipping
+		if ok {
+			log.printf("%-10s %s attempt %d: crashed, no ack", "shipping-2", d.Body, d.Attempt)
+		}
+	}()
+
+	for i := 1; i <= 5; i++ {
+		broker.Publish("orders", fmt.Sprintf("order #%d", i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err = broker.Close(ctx)
+	wg.Wait()
+	for _, line := range log.lines {
+		fmt.Println(" ", line)
+	}
+	fmt.Println("Close drained everything:", err == nil)
+
+	// Ack idempotency
+	b2 := NewBroker(time.Second)
+	b2.CreateTopic("t")
+	ch, _ := b2.Subscribe("t", "g")
+	b2.Publish("t", "hello")
+	d := <-ch
+	fmt.Println("First Ack:", d.Ack(), "| second Ack:", d.Ack())
+	b2.Close(context.Background())
+	_, err = b2.Publish("t", "after close")
+	fmt.Println("Publish after Close:", err)
+}
+
+// Consumer groups: every group gets every message, members of a group split them.
+// Visibility timeout + redelivery = at-least-once: make processing idempotent (dedupe by ID).
+// Ack is idempotent too, a second Ack returns ErrAlreadyAcked instead of breaking anything.
+// Graceful shutdown: stop accepting, wait for in-flight work (with a deadline), then close channels.
+// Only the goroutine that sends on a channel closes it (here each group's dispatcher).
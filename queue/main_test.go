@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBroker(t *testing.T, timeout time.Duration, groups ...string) (*Broker, map[string]<-chan Delivery) {
+	t.Helper()
+	b := NewBroker(timeout)
+	if err := b.CreateTopic("orders"); err != nil {
+		t.Fatal(err)
+	}
+	chans := make(map[string]<-chan Delivery)
+	for _, g := range groups {
+		ch, err := b.Subscribe("orders", g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chans[g] = ch
+	}
+	return b, chans
+}
+
+func receive(t *testing.T, ch <-chan Delivery) Delivery {
+	t.Helper()
+	select {
+	case d, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return d
+	case <-time.After(2 * time.Second):
+		t.Fatal("no delivery")
+	}
+	return Delivery{}
+}
+
+func TestEveryGroupGetsEveryMessage(t *testing.T) {
+	b, chans := newTestBroker(t, time.Second, "billing", "shipping")
+	for i := range 10 {
+		b.Publish("orders", fmt.Sprint(i))
+	}
+	for name, ch := range chans {
+		var got []string
+		for range 10 {
+			d := receive(t, ch)
+			if d.Group != name || d.Attempt != 1 || d.Topic != "orders" {
+				t.Errorf("%+v", d)
+			}
+			got = append(got, d.Body)
+			d.Ack()
+		}
+		if want := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}; !slices.Equal(got, want) {
+			t.Errorf("%s got %v", name, got)
+		}
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMembersCompete(t *testing.T) {
+	b, chans := newTestBroker(t, time.Second, "billing")
+	const n = 200
+	var mu sync.Mutex
+	perMember := make([]int, 4)
+	seen := make(map[uint64]int)
+	var wg sync.WaitGroup
+	for m := range perMember {
+		wg.Go(func() {
+			for d := range chans["billing"] {
+				mu.Lock()
+				perMember[m]++
+				seen[d.ID]++
+				mu.Unlock()
+				d.Ack()
+				time.Sleep(time.Millisecond) // give the others a chance
+			}
+		})
+	}
+	for i := range n {
+		b.Publish("orders", fmt.Sprint(i))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if len(seen) != n {
+		t.Fatalf("%d distinct messages, want %d", len(seen), n)
+	}
+	for id, c := range seen {
+		if c != 1 {
+			t.Errorf("message %d delivered %d times inside one group", id, c)
+		}
+	}
+	for m, c := range perMember {
+		if c == 0 {
+			t.Errorf("member %d got nothing: %v", m, perMember)
+		}
+	}
+}
+
+func TestRedeliveryAfterSlowConsumer(t *testing.T) {
+	b, chans := newTestBroker(t, 50*time.Millisecond, "shipping")
+	id, _ := b.Publish("orders", "order #1")
+	first := receive(t, chans["shipping"]) // taken but never acked in time
+	second := receive(t, chans["shipping"])
+	if first.ID != id || second.ID != id || first.Attempt != 1 || second.Attempt != 2 {
+		t.Fatalf("first %+v, second %+v", first, second)
+	}
+	// the slow consumer finally acks: the copy waiting for redelivery is gone too
+	if err := first.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Ack(); !errors.Is(err, ErrAlreadyAcked) {
+		t.Errorf("second Ack: %v", err)
+	}
+	select {
+	case d := <-chans["shipping"]:
+		t.Errorf("delivered again after the ack: %+v", d)
+	case <-time.After(150 * time.Millisecond):
+	}
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLateAckRemovesQueuedRedelivery(t *testing.T) {
+	b, chans := newTestBroker(t, 20*time.Millisecond, "g")
+	b.Publish("orders", "x")
+	d := receive(t, chans["g"])
+	// nobody receives: after the timeout the message sits in the queue waiting for a member
+	time.Sleep(80 * time.Millisecond)
+	if err := d.Ack(); err != nil {
+		t.Fatalf("late Ack: %v", err)
+	}
+	// the dispatcher may be blocked offering the acked copy, when it gives up it must not requeue it
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestAckIdempotent(t *testing.T) {
+	b, chans := newTestBroker(t, time.Second, "g")
+	b.Publish("orders", "hello")
+	d := receive(t, chans["g"])
+	if err := d.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		if err := d.Ack(); !errors.Is(err, ErrAlreadyAcked) {
+			t.Errorf("Ack again: %v", err)
+		}
+	}
+	b.Close(context.Background())
+}
+
+func TestCloseDrains(t *testing.T) {
+	b, chans := newTestBroker(t, time.Second, "g")
+	for i := range 20 {
+		b.Publish("orders", fmt.Sprint(i))
+	}
+	acked := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for d := range chans["g"] {
+			time.Sleep(time.Millisecond)
+			if d.Ack() == nil {
+				acked++
+			}
+		}
+	}()
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done // the channel was closed by Close
+	if acked != 20 {
+		t.Errorf("acked %d of 20 before Close returned", acked)
+	}
+	if _, err := b.Publish("orders", "late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Publish after Close: %v", err)
+	}
+	if _, err := b.Subscribe("orders", "new"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Subscribe after Close: %v", err)
+	}
+	if err := b.CreateTopic("x"); !errors.Is(err, ErrClosed) {
+		t.Errorf("CreateTopic after Close: %v", err)
+	}
+}
+
+func TestCloseGivesUp(t *testing.T) {
+	before := runtime.NumGoroutine()
+	b, _ := newTestBroker(t, time.Second, "nobody-reads")
+	b.Publish("orders", "stuck")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close = %v, want DeadlineExceeded", err)
+	}
+	// the dispatchers stopped even though messages were left
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left, %d before", n, before)
+	}
+}
+
+func TestTopicErrors(t *testing.T) {
+	b, _ := newTestBroker(t, time.Second)
+	if err := b.CreateTopic("orders"); !errors.Is(err, ErrTopicExists) {
+		t.Errorf("CreateTopic twice: %v", err)
+	}
+	if _, err := b.Publish("payments", "x"); !errors.Is(err, ErrNoTopic) {
+		t.Errorf("Publish: %v", err)
+	}
+	if _, err := b.Subscribe("payments", "g"); !errors.Is(err, ErrNoTopic) {
+		t.Errorf("Subscribe: %v", err)
+	}
+	a, _ := b.Subscribe("orders", "g")
+	c, _ := b.Subscribe("orders", "g")
+	if a != c {
+		t.Error("the same group got two channels")
+	}
+	b.Close(context.Background())
+}
+
+func TestTinyTimeouts(t *testing.T) {
+	for _, timeout := range []time.Duration{-time.Second, 0, 1, 3} {
+		b := NewBroker(timeout) // a tick of timeout/4 would be 0 and panic
+		if timeout <= 0 && b.VisibilityTimeout != DefaultVisibilityTimeout {
+			t.Errorf("NewBroker(%v).VisibilityTimeout = %v", timeout, b.VisibilityTimeout)
+		}
+		b.CreateTopic("t")
+		ch, _ := b.Subscribe("t", "g")
+		b.Publish("t", "x")
+		if err := receive(t, ch).Ack(); err != nil {
+			t.Errorf("timeout %v: Ack: %v", timeout, err)
+		}
+		time.Sleep(10 * time.Millisecond) // let the 1ns redeliveries spin a few times
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := b.Close(ctx); err != nil {
+			t.Errorf("timeout %v: %v", timeout, err)
+		}
+		cancel()
+	}
+}
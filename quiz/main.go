@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Question is one multiple-choice question.
+// Answer is the index (into Options) of the correct option.
+type Question struct {
+	Topic   string
+	Text    string
+	Options []string
+	Answer  int
+}
+
+// QuestionResult records how one question went
+type QuestionResult struct {
+	Question string
+	Topic    string
+	Correct  bool
+}
+
+// QuizResult is returned by QuizExamples so callers (and tests) can inspect the score
+type QuizResult struct {
+	Score   int
+	Total   int
+	Answers []QuestionResult
+}
+
+// TopicsToReview lists the topics of wrongly answered questions, without duplicates
+func (r QuizResult) TopicsToReview() []string {
+	var topics []string
+	seen := map[string]bool{}
+	for _, a := range r.Answers {
+		if !a.Correct && !seen[a.Topic] {
+			seen[a.Topic] = true
+			topics = append(topics, a.Topic)
+		}
+	}
+	return topics
+}
+
+var questionBank = []Question{
+	{Topic: "variables", Text: "What is the zero value of an int?", Options: []string{"0", "nil", "undefined", "-1"}, Answer: 0},
+	{Topic: "variables", Text: "Which syntax declares AND initializes a variable inside a function?", Options: []string{"x := 10", "x = 10", "let x = 10", "int x = 10"}, Answer: 0},
+	{Topic: "variables", Text: "What is the zero value of a string?", Options: []string{`""`, "nil", `" "`, "0"}, Answer: 0},
+	{Topic: "types", Text: "What does len(\"Go🚀\") return?", Options: []string{"6", "3", "4", "2"}, Answer: 0},
+	{Topic: "types", Text: "rune is an alias for which type?", Options: []string{"int32", "uint8", "int64", "string"}, Answer: 0},
+	{Topic: "types", Text: "Which of these can NOT be a map key?", Options: []string{"[]int", "string", "int", "struct{X int}"}, Answer: 0},
+	{Topic: "control flow", Text: "How many loop keywords does Go have?", Options: []string{"1 (for)", "2 (for, while)", "3 (for, while, do)", "0"}, Answer: 0},
+	{Topic: "control flow", Text: "Does a Go switch case fall through to the next case by default?", Options: []string{"No", "Yes", "Only for strings", "Only with break"}, Answer: 0},
+	{Topic: "control flow", Text: "In which order do deferred calls run?", Options: []string{"Last in, first out", "First in, first out", "Random", "Alphabetical"}, Answer: 0},
+	{Topic: "control flow", Text: "What does `continue` do inside a for loop?", Options: []string{"Skips to the next iteration", "Exits the loop", "Exits the function", "Restarts the loop"}, Answer: 0},
+}
+
+// QuizExamples runs the quiz with a random seed
+func QuizExamples(r io.Reader, w io.Writer) QuizResult {
+	return runQuiz(r, w, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+}
+
+// runQuiz asks every question, reading answers from r and writing to w.
+// Reading from an io.Reader (not os.Stdin directly) means a strings.Reader
+// can drive the quiz. The rng shuffles the options so answers are not always "a".
+func runQuiz(r io.Reader, w io.Writer, rng *rand.Rand) QuizResult {
+	scanner := bufio.NewScanner(r)
+	result := QuizResult{Total: len(questionBank)}
+
+	for i, q := range questionBank {
+		// shuffle a list of option indexes instead of the options themselves
+		// so we still know where the correct answer ended up
+		order := rng.Perm(len(q.Options))
+
+		fmt.Fprintf(w, "\nQ%d [%s] %s\n", i+1, q.Topic, q.Text)
+		for pos, optionIndex := range order {
+			fmt.Fprintf(w, "  %c) %s\n", 'a'+pos, q.Options[optionIndex])
+		}
+
+		choice, ok := readChoice(scanner, w, len(order))
+		if !ok { // input ended, remaining questions count as wrong
+			fmt.Fprintln(w, "\nNo more input, ending the quiz.")
+			for _, rest := range questionBank[i:] {
+				result.Answers = append(result.Answers, QuestionResult{Question: rest.Text, Topic: rest.Topic})
+			}
+			return result
+		}
+
+		correct := order[choice] == q.Answer
+		if correct {
+			result.Score++
+			fmt.Fprintln(w, "✅ Correct!")
+		} else {
+			fmt.Fprintln(w, "❌ Wrong, the answer is:", q.Options[q.Answer])
+		}
+		result.Answers = append(result.Answers, QuestionResult{Question: q.Text, Topic: q.Topic, Correct: correct})
+	}
+	return result
+}
+
+// readChoice keeps asking until it gets a valid letter (a, b, ...) or number (1, 2, ...).
+// It returns false when the input ends.
+func readChoice(scanner *bufio.Scanner, w io.Writer, options int) (int, bool) {
+	for {
+		fmt.Fprint(w, "Your answer: ")
+		if !scanner.Scan() {
+			return 0, false
+		}
+		input := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if len(input) == 1 && input[0] >= 'a' && int(input[0]-'a') < options {
+			return int(input[0] - 'a'), true
+		}
+		if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= options {
+			return n - 1, true
+		}
+		fmt.Fprintf(w, "Invalid answer %q, type a letter between a and %c\n", input, 'a'+options-1)
+	}
+}
+
+func main() {
+	seed := flag.Uint64("seed", 0, "seed for shuffling the options (0 = random)")
+	flag.Parse()
+
+	fmt.Println("Go basics quiz: variables, types and control flow")
+
+	var result QuizResult
+	if *seed != 0 {
+		result = runQuiz(os.Stdin, os.Stdout, rand.New(rand.NewPCG(*seed, *seed)))
+	} else {
+		result = QuizExamples(os.Stdin, os.Stdout)
+	}
+
+	fmt.Printf("\nScore: %d/%d\n", result.Score, result.Total)
+	if topics := result.TopicsToReview(); len(topics) > 0 {
+		fmt.Println("Topics to review:", strings.Join(topics, ", "))
+	} else {
+		fmt.Println("Perfect score, nothing to review!")
+	}
+}
+
+// Passing io.Reader/io.Writer instead of using os.Stdin/os.Stdout directly is
+// called dependency injection: the same code works with a terminal, a file or a string.
+// Run with a fixed seed to get the same option order every time:
+//   go run main.go -seed 42
+// Or script the answers:
+//   printf 'a\nb\nc\n' | go run main.go
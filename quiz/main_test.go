@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func seeded() *rand.Rand { return rand.New(rand.NewPCG(42, 42)) }
+
+// scriptedAnswers replays the shuffle with the same seed and returns, per question,
+// the letter of the correct option (correct == true) or of a wrong one
+func scriptedAnswers(correct bool) []string {
+	rng := seeded()
+	var answers []string
+	for _, q := range questionBank {
+		order := rng.Perm(len(q.Options))
+		for pos, optionIndex := range order {
+			if (optionIndex == q.Answer) == correct {
+				answers = append(answers, string(rune('a'+pos)))
+				break
+			}
+		}
+	}
+	return answers
+}
+
+func TestQuizAllCorrect(t *testing.T) {
+	input := strings.Join(scriptedAnswers(true), "\n") + "\n"
+	var out strings.Builder
+	result := runQuiz(strings.NewReader(input), &out, seeded())
+	if result.Score != len(questionBank) || result.Total != len(questionBank) {
+		t.Fatalf("score %d/%d, want %d/%d", result.Score, result.Total, len(questionBank), len(questionBank))
+	}
+	if topics := result.TopicsToReview(); len(topics) != 0 {
+		t.Errorf("TopicsToReview = %v, want none", topics)
+	}
+	if strings.Contains(out.String(), "Wrong") {
+		t.Error("output mentions a wrong answer")
+	}
+}
+
+func TestQuizAllWrong(t *testing.T) {
+	input := strings.Join(scriptedAnswers(false), "\n") + "\n"
+	result := runQuiz(strings.NewReader(input), io.Discard, seeded())
+	if result.Score != 0 || len(result.Answers) != len(questionBank) {
+		t.Fatalf("score %d with %d answers, want 0 with %d", result.Score, len(result.Answers), len(questionBank))
+	}
+	for _, a := range result.Answers {
+		if a.Correct {
+			t.Errorf("%q marked correct", a.Question)
+		}
+	}
+	want := []string{"variables", "types", "control flow"}
+	if got := result.TopicsToReview(); !slices.Equal(got, want) {
+		t.Errorf("TopicsToReview = %v, want %v", got, want)
+	}
+}
+
+func TestQuizInvalidInputReprompts(t *testing.T) {
+	answers := scriptedAnswers(true)
+	// garbage before the first real answer, numbers are accepted too
+	first := int(answers[0][0]-'a') + 1
+	answers[0] = "x\n\n99\nab\n" + string(rune('0'+first))
+	var out strings.Builder
+	result := runQuiz(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out, seeded())
+	if result.Score != len(questionBank) {
+		t.Fatalf("score %d, want %d", result.Score, len(questionBank))
+	}
+	if n := strings.Count(out.String(), "Invalid answer"); n != 4 {
+		t.Errorf("%d reprompts, want 4\n%s", n, out.String())
+	}
+}
+
+func TestQuizInputEndsEarly(t *testing.T) {
+	answers := scriptedAnswers(true)[:3]
+	var out strings.Builder
+	result := runQuiz(strings.NewReader(strings.Join(answers, "\n")), &out, seeded())
+	if result.Score != 3 || len(result.Answers) != len(questionBank) {
+		t.Fatalf("score %d with %d answers, want 3 with %d", result.Score, len(result.Answers), len(questionBank))
+	}
+	if !strings.Contains(out.String(), "No more input") {
+		t.Error("output does not say the input ended")
+	}
+}
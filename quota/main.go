@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A rate limiter protects the server from bursts ("10 requests per second per IP").
+// A quota is a business rule ("1000 requests per month on the free plan"): it counts per
+// user, over a calendar month, and has to survive restarts or a restart would be a free reset.
+
+const Unlimited = -1
+
+var ErrQuotaExceeded = errors.New("monthly quota exceeded")
+
+// Limits per role
+var Limits = map[string]int{"admin": Unlimited, "user": 1000}
+
+type usage struct {
+	Month string `json:"month"` // "2025-01", a count from an older month is worth nothing
+	Count int    `json:"count"`
+}
+
+type Status struct {
+	Used      int       `json:"used"`
+	Limit     int       `json:"limit"`     // -1: unlimited
+	Remaining int       `json:"remaining"` // -1: unlimited
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+type QuotaStore struct {
+	Now func() time.Time // the clock, replaced in the demo
+
+	mu    sync.Mutex
+	path  string
+	usage map[string]usage
+}
+
+// OpenQuotaStore loads the counts saved at path, a missing file is an empty store
+func OpenQuotaStore(path string) (*QuotaStore, error) {
+	s := &QuotaStore{Now: time.Now, path: path, usage: map[string]usage{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open quota store: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.usage); err != nil {
+		return nil, fmt.Errorf("open quota store %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the counts to disk. Take already saves after every count, this is for
+// callers that want to be sure, e.g. on shutdown.
+func (s *QuotaStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.save()
+}
+
+// save writes a temp file and renames it over the old one, so a crash leaves the old or
+// the new file, never half of one. Callers hold s.mu, which also keeps two saves from
+// sharing the temp file.
+func (s *QuotaStore) save() error {
+	data, err := json.Marshal(s.usage)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("save quota store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("save quota store: %w", err)
+	}
+	return nil
+}
+
+// current returns user's usage for this month. A count from an earlier month is replaced
+// here, under the same lock as the increment, so the rollover can't lose or double a request.
+func (s *QuotaStore) current(user string, now time.Time) usage {
+	month := now.UTC().Format("2006-01")
+	u := s.usage[user]
+	if u.Month != month {
+		u = usage{Month: month}
+	}
+	return u
+}
+
+func status(u usage, limit int, now time.Time) Status {
+	t := now.UTC()
+	st := Status{Used: u.Count, Limit: limit, Remaining: Unlimited,
+		ResetsAt: time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)} // Date normalizes month 13
+	if limit != Unlimited {
+		st.Remaining = max(limit-u.Count, 0)
+	}
+	return st
+}
+
+// Take counts one request for user and saves the count before returning, so a crash right
+// after can't hand the request back. It returns ErrQuotaExceeded without counting, or the
+// save error after undoing the count: a request that isn't on disk isn't served.
+func (s *QuotaStore) Take(user, role string) (Status, error) {
+	limit, ok := Limits[role]
+	if !ok {
+		limit = 0 // unknown roles get nothing, not everything
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	u := s.current(user, now)
+	if limit != Unlimited && u.Count >= limit {
+		return status(u, limit, now), ErrQuotaExceeded
+	}
+	prev, had := s.usage[user]
+	u.Count++
+	s.usage[user] = u
+	if err := s.save(); err != nil {
+		if had {
+			s.usage[user] = prev
+		} else {
+			delete(s.usage, user)
+		}
+		return status(s.current(user, now), limit, now), err
+	}
+	return status(u, limit, now), nil
+}
+
+// Peek returns the status without counting
+func (s *QuotaStore) Peek(user, role string) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	return status(s.current(user, now), Limits[role], now)
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// identity: X-User/X-Role stand in for the claims an auth middleware would have checked
+func identity(r *http.Request) (user, role string) {
+	return r.Header.Get("X-User"), r.Header.Get("X-Role")
+}
+
+func quotaMiddleware(s *QuotaStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, role := identity(r)
+		if user == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+			return
+		}
+		st, err := s.Take(user, role)
+		if err != nil && !errors.Is(err, ErrQuotaExceeded) {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "quota store unavailable"})
+			return
+		}
+		if st.Limit != Unlimited {
+			w.Header().Set("X-Quota-Limit", strconv.Itoa(st.Limit))
+			w.Header().Set("X-Quota-Remaining", strconv.Itoa(st.Remaining))
+		}
+		if errors.Is(err, ErrQuotaExceeded) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(st.ResetsAt.Sub(s.Now()).Seconds())))
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"error": err.Error(), "quota": st})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func routes(s *QuotaStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/users", quotaMiddleware(s, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []string{"rishabh", "sanchay"})
+	})))
+	// checking your usage doesn't use it up
+	mux.HandleFunc("GET /api/me/usage", func(w http.ResponseWriter, r *http.Request) {
+		user, role := identity(r)
+		if user == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "login required"})
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Peek(user, role))
+	})
+	return mux
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func main() {
+	fmt.Println("Learning per-user monthly quotas in Go")
+
+	dir, err := os.MkdirTemp("", "quota")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "quota.json")
+
+	clock := &fakeClock{now: time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)}
+	store, err := OpenQuotaStore(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	store.Now = clock.Now
+	h := routes(store)
+	do := func(target, user, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-User", user)
+		req.Header.Set("X-Role", role)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 20 goroutines x 40 requests: the count must be exactly 800, go run -race stays quiet
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 40 {
+				do("/api/users", "alice", "user")
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Print("alice after 800 concurrent requests: ", do("/api/me/usage", "alice", "user").Body.String())
+
+	fmt.Println("At the limit:")
+	for i := 801; i <= 1001; i++ {
+		rec := do("/api/users", "alice", "user")
+		if i >= 999 {
+			fmt.Printf("  request %d -> %d remaining=%s\n", i, rec.Code, rec.Header().Get("X-Quota-Remaining"))
+		}
+		if rec.Code == http.StatusTooManyRequests {
+			fmt.Printf("  Retry-After: %ss %s", rec.Header().Get("Retry-After"), rec.Body.String())
+		}
+	}
+	for range 1500 {
+		do("/api/users", "root", "admin")
+	}
+	fmt.Print("admin after 1500: ", do("/api/me/usage", "root", "admin").Body.String())
+	fmt.Println("unknown role:", do("/api/users", "mallory", "superuser").Code)
+
+	fmt.Println("Reopened (every count was saved, a restart is not a free reset):")
+	store, err = OpenQuotaStore(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	store.Now = clock.Now
+	h = routes(store)
+	fmt.Println("  alice:", do("/api/users", "alice", "user").Code)
+
+	fmt.Println("Month rollover at midnight UTC:")
+	clock.Set(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	fmt.Println("  alice on Feb 1:", do("/api/users", "alice", "user").Code)
+	fmt.Print("  usage: ", do("/api/me/usage", "alice", "user").Body.String())
+	clock.Set(time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC))
+	fmt.Println("  in December it resets at:", store.Peek("alice", "user").ResetsAt.Format(time.DateOnly))
+}
+
+// A quota is per user and per calendar month, a rate limiter is per client and per second: keep both.
+// Store the month next to the count, an old month's count is simply treated as zero.
+// Check, roll over and increment under ONE lock: concurrent requests get exact totals, never 1001.
+// Persist every count before serving (write a temp file and rename it), or every deploy or crash hands out a fresh quota.
+// On 429 say how much was used, the limit and when it resets, plus Retry-After for programs.
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var jan31 = time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+
+func openTemp(t *testing.T, clock *fakeClock) (*QuotaStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "quota.json")
+	return reopen(t, path, clock), path
+}
+
+func reopen(t *testing.T, path string, clock *fakeClock) *QuotaStore {
+	t.Helper()
+	s, err := OpenQuotaStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Now = clock.Now
+	return s
+}
+
+func do(h http.Handler, target, user, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Header.Set("X-User", user)
+	req.Header.Set("X-Role", role)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// exact totals under go test -race: every request counted once, none past the limit
+func TestConcurrentCounts(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, _ := openTemp(t, clock)
+	h := routes(s)
+	var wg sync.WaitGroup
+	codes := make(chan int, 30*(40+10))
+	for range 30 {
+		wg.Go(func() {
+			for range 40 {
+				codes <- do(h, "/api/users", "alice", "user").Code
+			}
+		})
+		wg.Go(func() {
+			for range 10 {
+				codes <- do(h, "/api/users", "bob", "user").Code
+			}
+		})
+	}
+	wg.Wait()
+	close(codes)
+	count := map[int]int{}
+	for c := range codes {
+		count[c]++
+	}
+	// alice asked 1200 times: exactly 1000 served, bob's 300 all served
+	if count[200] != 1300 || count[429] != 200 {
+		t.Errorf("status counts %v", count)
+	}
+	if a, b := s.Peek("alice", "user"), s.Peek("bob", "user"); a.Used != 1000 || a.Remaining != 0 || b.Used != 300 {
+		t.Errorf("alice %+v, bob %+v", a, b)
+	}
+}
+
+func TestBoundary(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, _ := openTemp(t, clock)
+	for range 999 {
+		s.Take("alice", "user")
+	}
+	h := routes(s)
+	rec := do(h, "/api/users", "alice", "user")
+	if rec.Code != 200 || rec.Header().Get("X-Quota-Remaining") != "0" || rec.Header().Get("X-Quota-Limit") != "1000" {
+		t.Fatalf("request 1000: %d %v", rec.Code, rec.Header())
+	}
+	rec = do(h, "/api/users", "alice", "user")
+	var body struct {
+		Error string
+		Quota Status
+	}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	want := Status{Used: 1000, Limit: 1000, Remaining: 0, ResetsAt: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+	if rec.Code != http.StatusTooManyRequests || body.Error != ErrQuotaExceeded.Error() || body.Quota != want {
+		t.Errorf("request 1001: %d %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "3600" {
+		t.Errorf("Retry-After %q", got)
+	}
+	// a refused request isn't counted
+	if st := s.Peek("alice", "user"); st.Used != 1000 {
+		t.Errorf("after the 429: %+v", st)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, _ := openTemp(t, clock)
+	for range 1500 {
+		if _, err := s.Take("root", "admin"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := s.Peek("root", "admin"); st.Used != 1500 || st.Limit != Unlimited || st.Remaining != Unlimited {
+		t.Errorf("admin %+v", st)
+	}
+	rec := do(routes(s), "/api/users", "root", "admin")
+	if rec.Code != 200 || rec.Header().Get("X-Quota-Limit") != "" {
+		t.Errorf("admin: %d %v", rec.Code, rec.Header())
+	}
+	if _, err := s.Take("mallory", "superuser"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("unknown role: %v", err)
+	}
+	if rec := do(routes(s), "/api/users", "", "admin"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("no user: %d", rec.Code)
+	}
+}
+
+func TestRollover(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, _ := openTemp(t, clock)
+	for range 1000 {
+		s.Take("alice", "user")
+	}
+	clock.Set(time.Date(2025, 1, 31, 23, 59, 59, 0, time.UTC))
+	if _, err := s.Take("alice", "user"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("a second before midnight: %v", err)
+	}
+	clock.Set(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	// before any request in February the old count already reads as zero
+	if st := s.Peek("alice", "user"); st.Used != 0 || st.ResetsAt != time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Feb 1 peek: %+v", st)
+	}
+	if st, err := s.Take("alice", "user"); err != nil || st.Used != 1 || st.Remaining != 999 {
+		t.Errorf("Feb 1: %+v %v", st, err)
+	}
+
+	tests := []struct {
+		now, resets time.Time
+	}{
+		{time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// 23:30 in New York on Jan 31 is already February in UTC
+		{time.Date(2025, 1, 31, 23, 30, 0, 0, time.FixedZone("EST", -5*3600)), time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if st := status(usage{}, 1000, tt.now); !st.ResetsAt.Equal(tt.resets) {
+			t.Errorf("%v: resets at %v, want %v", tt.now, st.ResetsAt, tt.resets)
+		}
+	}
+}
+
+// the counts survive a reopen with no explicit Save: a restart is not a free reset
+func TestReopen(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, path := openTemp(t, clock)
+	h := routes(s)
+	for range 1000 {
+		do(h, "/api/users", "alice", "user")
+	}
+	do(h, "/api/users", "bob", "user")
+
+	s = reopen(t, path, clock)
+	h = routes(s)
+	if rec := do(h, "/api/users", "alice", "user"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("alice after reopen: %d", rec.Code)
+	}
+	if st := s.Peek("bob", "user"); st.Used != 1 {
+		t.Errorf("bob after reopen: %+v", st)
+	}
+	// the saved month still rolls over
+	clock.Set(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	if rec := do(h, "/api/users", "alice", "user"); rec.Code != 200 {
+		t.Errorf("alice in February: %d", rec.Code)
+	}
+	if st := reopen(t, path, clock).Peek("alice", "user"); st.Used != 1 {
+		t.Errorf("February count after another reopen: %+v", st)
+	}
+}
+
+// a count that can't be saved isn't kept and the request isn't served
+func TestSaveFailure(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, path := openTemp(t, clock)
+	s.Take("alice", "user")
+	s.path = filepath.Join(path, "not-a-dir", "quota.json")
+	if _, err := s.Take("alice", "user"); err == nil {
+		t.Fatal("Take saved to a missing directory")
+	}
+	if _, err := s.Take("carol", "user"); err == nil {
+		t.Fatal("Take saved to a missing directory")
+	}
+	if rec := do(routes(s), "/api/users", "alice", "user"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("handler: %d", rec.Code)
+	}
+	if a, c := s.Peek("alice", "user"), s.Peek("carol", "user"); a.Used != 1 || c.Used != 0 {
+		t.Errorf("counts kept without a save: alice %+v, carol %+v", a, c)
+	}
+}
+
+func TestUsageEndpoint(t *testing.T) {
+	clock := &fakeClock{now: jan31}
+	s, _ := openTemp(t, clock)
+	h := routes(s)
+	for range 3 {
+		do(h, "/api/users", "alice", "user")
+	}
+	for range 2 { // looking doesn't count
+		rec := do(h, "/api/me/usage", "alice", "user")
+		var st Status
+		json.Unmarshal(rec.Body.Bytes(), &st)
+		want := Status{Used: 3, Limit: 1000, Remaining: 997, ResetsAt: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)}
+		if rec.Code != 200 || st != want {
+			t.Errorf("usage: %d %s", rec.Code, rec.Body)
+		}
+	}
+	if rec := do(h, "/api/me/usage", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous usage: %d", rec.Code)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("{not json"), 0o644)
+	if _, err := OpenQuotaStore(bad); err == nil {
+		t.Error("corrupt file opened")
+	}
+	if _, err := OpenQuotaStore(dir); err == nil {
+		t.Error("a directory opened")
+	}
+	s, err := OpenQuotaStore(filepath.Join(dir, "missing.json"))
+	if err != nil || len(s.usage) != 0 {
+		t.Errorf("missing file: %v", err)
+	}
+}
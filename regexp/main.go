@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Validators
+// Patterns are compiled ONCE at package level with MustCompile.
+// MustCompile panics on a bad pattern, which is fine here because the patterns
+// are constants written by us: a typo crashes the program at startup, not later.
+var (
+	// local part: letters, digits and . _ % + - (so plus addressing works)
+	// domain: labels separated by dots, ending in a TLD of 2+ letters
+	emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9](?:[a-zA-Z0-9\-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9\-]*[a-zA-Z0-9])?)*\.[a-zA-Z]{2,}$`)
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	// each octet is 0-255 without leading zeros
+	ipv4Pattern = regexp.MustCompile(`^((25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])\.){3}(25[0-5]|2[0-4][0-9]|1[0-9]{2}|[1-9]?[0-9])$`)
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email")
+	ErrInvalidUUID  = errors.New("invalid UUID")
+	ErrInvalidSlug  = errors.New("invalid slug")
+	ErrInvalidIPv4  = errors.New("invalid IPv4 address")
+)
+
+// ValidateEmail accepts "user+tag@mail.example.com" style addresses.
+// It does NOT accept: missing TLD ("a@b"), spaces, consecutive dots (".."),
+// quoted local parts or IP-literal domains. Good enough for sign-up forms,
+// the only real check is sending a confirmation mail.
+func ValidateEmail(s string) error {
+	if !emailPattern.MatchString(s) || strings.Contains(s, "..") {
+		return fmt.Errorf("%w: %q", ErrInvalidEmail, s)
+	}
+	return nil
+}
+
+func ValidateUUID(s string) error {
+	if !uuidPattern.MatchString(s) {
+		return fmt.Errorf("%w: %q", ErrInvalidUUID, s)
+	}
+	return nil
+}
+
+// ValidateSlug accepts lowercase words separated by single hyphens, like "learning-go-101"
+func ValidateSlug(s string) error {
+	if !slugPattern.MatchString(s) {
+		return fmt.Errorf("%w: %q", ErrInvalidSlug, s)
+	}
+	return nil
+}
+
+func ValidateIPv4(s string) error {
+	if !ipv4Pattern.MatchString(s) {
+		return fmt.Errorf("%w: %q", ErrInvalidIPv4, s)
+	}
+	return nil
+}
+
+func main() {
+	fmt.Println("Learning regular expressions in Go")
+
+	// Compile returns an error, use it for patterns that come from users/config
+	if _, err := regexp.Compile(`[a-z`); err != nil {
+		fmt.Println("Compile error:", err)
+	}
+
+	// Capture groups: FindStringSubmatch returns the full match followed by each group
+	datePattern := regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+	match := datePattern.FindStringSubmatch("released on 2024-03-10")
+	fmt.Println("Full match:", match[0], "Year:", match[1], "Month:", match[2], "Day:", match[3])
+
+	// Named groups (?P<name>...) make the code readable
+	logLine := `192.168.1.20 - - [10/Mar/2024:14:47:31 +0000] "GET /api/users?page=2 HTTP/1.1" 200 512`
+	accessLog := regexp.MustCompile(`^(?P<ip>\S+) \S+ \S+ \[(?P<time>[^\]]+)\] "(?P<method>[A-Z]+) (?P<path>\S+) [^"]+" (?P<status>\d{3}) (?P<bytes>\d+)$`)
+	groups := accessLog.FindStringSubmatch(logLine)
+	if groups == nil {
+		fmt.Println("Log line did not match")
+		return
+	}
+	for i, name := range accessLog.SubexpNames() {
+		if name != "" {
+			fmt.Printf("  %-6s = %s\n", name, groups[i])
+		}
+	}
+
+	// FindAllStringSubmatch finds every match, -1 means no limit
+	query := "page=2&sort=name&order=asc"
+	pairs := regexp.MustCompile(`(\w+)=(\w+)`).FindAllStringSubmatch(query, -1)
+	for _, pair := range pairs {
+		fmt.Printf("key %s -> value %s\n", pair[1], pair[2])
+	}
+
+	// ReplaceAllStringFunc transforms every match, here masking digits of card numbers
+	masked := regexp.MustCompile(`\d{4}-\d{4}-\d{4}-(\d{4})`).ReplaceAllStringFunc("card 1234-5678-9012-3456 used", func(m string) string {
+		return "****-****-****-" + m[len(m)-4:]
+	})
+	fmt.Println("Masked:", masked)
+
+	// Validators
+	emails := []string{
+		"rishabh@example.com",
+		"rishabh+newsletter@example.com", // plus addressing: accepted
+		"first.last@mail.example.co.in",  // subdomains: accepted
+		"rishabh@example",                // missing TLD: rejected
+		"rishabh@@example.com",           // double @: rejected
+		"rishabh@example..com",           // consecutive dots: rejected
+		"rishabh @example.com",           // space: rejected
+		"a@b.c",                          // 1-letter TLD: rejected
+	}
+	for _, email := range emails {
+		if err := ValidateEmail(email); err != nil {
+			fmt.Println("  ✗", err)
+		} else {
+			fmt.Println("  ✓", email)
+		}
+	}
+	fmt.Println("UUID:", ValidateUUID("3f2504e0-4f89-11d3-9a0c-0305e82c3301"), ValidateUUID("not-a-uuid"))
+	fmt.Println("Slug:", ValidateSlug("learning-go-101"), ValidateSlug("Learning--Go"))
+	fmt.Println("IPv4:", ValidateIPv4("192.168.1.20"), ValidateIPv4("256.1.1.1"))
+
+	// Compile once vs compile per call
+	const iterations = 20000
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		regexp.MustCompile(emailPattern.String()).MatchString("rishabh@example.com")
+	}
+	perCall := time.Since(start)
+	start = time.Now()
+	for i := 0; i < iterations; i++ {
+		emailPattern.MatchString("rishabh@example.com")
+	}
+	once := time.Since(start)
+	fmt.Printf("Compile per call: %v, compile once: %v (%.0fx faster)\n", perCall, once, float64(perCall)/float64(once))
+}
+
+// Go's regexp uses RE2 syntax: no backreferences or lookarounds,
+// but matching is guaranteed linear time (no catastrophic backtracking).
+// Use raw strings `...` for patterns so you don't need to double every backslash.
+// A *regexp.Regexp is safe for concurrent use, so compile once and share it.
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+// The email cases document exactly what the pattern accepts
+func TestValidateEmail(t *testing.T) {
+	valid := []string{
+		"rishabh@example.com",
+		"user+tag@example.com", // plus addressing
+		"first.last@mail.example.co.uk",
+		"a_b%c-d@sub-domain.example.io",
+		"UPPER@EXAMPLE.COM",
+		"x@y.dev",
+	}
+	invalid := []string{
+		"",
+		"plainaddress",
+		"a@b",                    // missing TLD
+		"a@b.c",                  // one-letter TLD
+		"a@example.123",          // numeric TLD
+		"a..b@example.com",       // consecutive dots
+		"a@example..com",         // consecutive dots in the domain
+		"a b@example.com",        // space
+		"@example.com",           // empty local part
+		"a@-example.com",         // label starting with a hyphen
+		"a@example-.com",         // label ending with a hyphen
+		"\"quoted\"@example.com", // quoted local parts are not supported
+		"a@[127.0.0.1]",          // nor IP literals
+		"a@b@example.com",
+	}
+	for _, s := range valid {
+		if err := ValidateEmail(s); err != nil {
+			t.Errorf("ValidateEmail(%q) = %v, want nil", s, err)
+		}
+	}
+	for _, s := range invalid {
+		if err := ValidateEmail(s); !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("ValidateEmail(%q) = %v, want ErrInvalidEmail", s, err)
+		}
+	}
+}
+
+func TestValidators(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		sentinel error
+		valid    []string
+		invalid  []string
+	}{
+		{"UUID", ValidateUUID, ErrInvalidUUID,
+			[]string{"123e4567-e89b-12d3-a456-426614174000", "123E4567-E89B-12D3-A456-426614174000"},
+			[]string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400", "g23e4567-e89b-12d3-a456-426614174000"}},
+		{"Slug", ValidateSlug, ErrInvalidSlug,
+			[]string{"learning-go-101", "go", "a1"},
+			[]string{"", "Learning-Go", "double--hyphen", "-leading", "trailing-", "with space", "under_score"}},
+		{"IPv4", ValidateIPv4, ErrInvalidIPv4,
+			[]string{"0.0.0.0", "127.0.0.1", "192.168.1.254", "255.255.255.255"},
+			[]string{"", "256.0.0.1", "1.2.3", "1.2.3.4.5", "01.2.3.4", "1.2.3.-4", "a.b.c.d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, s := range tt.valid {
+				if err := tt.validate(s); err != nil {
+					t.Errorf("%q: %v, want nil", s, err)
+				}
+			}
+			for _, s := range tt.invalid {
+				if err := tt.validate(s); !errors.Is(err, tt.sentinel) {
+					t.Errorf("%q: %v, want %v", s, err, tt.sentinel)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEmailCompileOnce(b *testing.B) {
+	for b.Loop() {
+		emailPattern.MatchString("rishabh@example.com")
+	}
+}
+
+func BenchmarkEmailCompilePerCall(b *testing.B) {
+	for b.Loop() {
+		regexp.MustCompile(emailPattern.String()).MatchString("rishabh@example.com")
+	}
+}
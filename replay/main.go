@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Code that calls other HTTP services is hard to test: the service may be slow, down,
+// cost money or need secrets. A "VCR" records real responses once into a file
+// (a cassette) and replays them later, with no network at all.
+// It plugs into http.Client as its Transport (an http.RoundTripper), so the code
+// under test doesn't change.
+
+type Mode int
+
+const (
+	ModeRecord Mode = iota // do real requests and save them
+	ModeReplay             // answer from the cassette only
+)
+
+// ErrNoMatch is returned in replay mode for a request that isn't in the cassette
+var ErrNoMatch = errors.New("no recorded interaction matches")
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// SensitiveHeaders are never written to a cassette, cassettes end up in git
+var SensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// MatchOptions decides when a request is "the same" as a recorded one
+type MatchOptions struct {
+	IgnoreHeaders []string // volatile headers like X-Request-Id or Date
+	IgnoreHost    bool     // replay against a different base URL than the one recorded
+	JSONBody      bool     // compare bodies as JSON values, so key order and spacing don't matter
+}
+
+type Recorder struct {
+	Mode      Mode
+	Cassette  *Cassette
+	Match     MatchOptions
+	Transport http.RoundTripper // used in record mode, http.DefaultTransport if nil
+
+	mu   sync.Mutex
+	used []bool // each interaction is replayed once, so the same request twice can get two answers
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	rec := RecordedRequest{Method: req.Method, URL: req.URL.String(), Headers: sanitize(req.Header), Body: string(body)}
+
+	if r.Mode == ModeReplay {
+		return r.replay(req, rec)
+	}
+
+	// a RoundTripper must not change the caller's request, so send a copy with a fresh body
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.Cassette.Interactions = append(r.Cassette.Interactions, Interaction{
+		Request:  rec,
+		Response: RecordedResponse{Status: resp.StatusCode, Headers: sanitize(resp.Header), Body: string(respBody)},
+	})
+	r.mu.Unlock()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody)) // the caller still gets the full body
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, rec RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.used) != len(r.Cassette.Interactions) {
+		r.used = make([]bool, len(r.Cassette.Interactions))
+	}
+	closest, closestDiff := -1, []string(nil)
+	for i, in := range r.Cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		diff := r.diff(in.Request, rec)
+		if len(diff) == 0 {
+			r.used[i] = true
+			return &http.Response{
+				StatusCode: in.Response.Status,
+				Status:     fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+				Header:     in.Response.Headers.Clone(),
+				Body:       io.NopCloser(strings.NewReader(in.Response.Body)),
+				Request:    req,
+				Proto:      "HTTP/1.1", ProtoMajor: 1, ProtoMinor: 1,
+			}, nil
+		}
+		if closest == -1 || len(diff) < len(closestDiff) {
+			closest, closestDiff = i, diff
+		}
+	}
+	if closest == -1 {
+		return nil, fmt.Errorf("%w for %s %s: no unused interactions left", ErrNoMatch, rec.Method, rec.URL)
+	}
+	return nil, fmt.Errorf("%w for %s %s, closest is #%d:\n  %s", ErrNoMatch, rec.Method, rec.URL, closest+1, strings.Join(closestDiff, "\n  "))
+}
+
+// diff lists the differences between a recorded request and a new one, empty means they match
+func (r *Recorder) diff(want, got RecordedRequest) []string {
+	var d []string
+	if want.Method != got.Method {
+		d = append(d, fmt.Sprintf("method: recorded %s, got %s", want.Method, got.Method))
+	}
+	wantURL, gotURL := want.URL, got.URL
+	if r.Match.IgnoreHost {
+		wantURL, gotURL = stripHost(wantURL), stripHost(gotURL)
+	}
+	if wantURL != gotURL {
+		d = append(d, fmt.Sprintf("url: recorded %s, got %s", wantURL, gotURL))
+	}
+	keys := map[string]bool{}
+	for k := range want.Headers {
+		keys[k] = true
+	}
+	for k := range got.Headers {
+		keys[k] = true
+	}
+	var names []string
+	for k := range keys {
+		if !containsFold(r.Match.IgnoreHeaders, k) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		if !reflect.DeepEqual(want.Headers[k], got.Headers[k]) {
+			d = append(d, fmt.Sprintf("header %s: recorded %q, got %q", k, want.Headers[k], got.Headers[k]))
+		}
+	}
+	if !r.bodiesEqual(want.Body, got.Body) {
+		d = append(d, fmt.Sprintf("body: recorded %s, got %s", want.Body, got.Body))
+	}
+	return d
+}
+
+func (r *Recorder) bodiesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if !r.Match.JSONBody {
+		return false
+	}
+	var va, vb any
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false // not JSON: only an exact match counts
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func stripHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	u.Scheme, u.Host = "", ""
+	return u.String()
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitize copies h without the sensitive headers
+func sanitize(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range SensitiveHeaders {
+		out.Del(name)
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func SaveCassette(path string, c *Cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save cassette %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("save cassette %s: %w", path, err)
+	}
+	return nil
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load cassette %s: %w", path, err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("load cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// ----------------------------------------------------------------------------
+// The code under test: a small API client
+
+type UserClient struct {
+	BaseURL string
+	Token   string
+	HTTP    *http.Client
+	nextID  int
+}
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func (c *UserClient) do(method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	c.nextID++
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-Request-Id", fmt.Sprintf("req-%d", c.nextID)) // different on every run
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: status %d", method, path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *UserClient) GetUser(id int) (User, error) {
+	var u User
+	err := c.do(http.MethodGet, fmt.Sprintf("/users/%d", id), nil, &u)
+	return u, err
+}
+
+func (c *UserClient) CreateUser(body any) (User, error) {
+	var u User
+	err := c.do(http.MethodPost, "/users", body, &u)
+	return u, err
+}
+
+func main() {
+	fmt.Println("Learning to record and replay HTTP calls in Go")
+
+	// the "real" downstream service, only needed while recording
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc123")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/users/1":
+			fmt.Fprint(w, `{"id":1,"name":"Rishabh"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/users":
+			var u User
+			json.NewDecoder(r.Body).Decode(&u)
+			fmt.Fprintf(w, `{"id":2,"name":%q}`, u.Name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	dir, _ := os.MkdirTemp("", "replay-*")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "users.json")
+
+	// 1. record
+	recorder := &Recorder{Mode: ModeRecord, Cassette: &Cassette{}}
+	client := &UserClient{BaseURL: api.URL, Token: "s3cret", HTTP: &http.Client{Transport: recorder}}
+	u1, err1 := client.GetUser(1)
+	u2, err2 := client.CreateUser(map[string]any{"name": "Gopher", "role": "admin"})
+	api.Close()
+	fmt.Println("Recorded:", u1, err1, "|", u2, err2)
+	if err := SaveCassette(path, recorder.Cassette); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	saved, _ := os.ReadFile(path)
+	fmt.Println("Cassette has Authorization:", bytes.Contains(saved, []byte("s3cret")), "| Set-Cookie:", bytes.Contains(saved, []byte("abc123")))
+
+	// 2. replay: the server is gone, and the base URL is a different one
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	newReplayer := func() *Recorder {
+		return &Recorder{Mode: ModeReplay, Cassette: cassette, Match: MatchOptions{
+			IgnoreHeaders: []string{"X-Request-Id"}, IgnoreHost: true, JSONBody: true,
+		}}
+	}
+	client = &UserClient{BaseURL: "http://users.internal", Token: "whatever", HTTP: &http.Client{Transport: newReplayer()}}
+	client.nextID = 100 // different request IDs than during the recording
+	u1, err1 = client.GetUser(1)
+	// a struct marshals with keys in another order than the recorded map: still the same JSON
+	u2, err2 = client.CreateUser(struct {
+		Role string `json:"role"`
+		Name string `json:"name"`
+	}{"admin", "Gopher"})
+	fmt.Println("Replayed:", u1, err1, "|", u2, err2)
+
+	// 3. an unexpected request fails with a diff
+	client.HTTP.Transport = newReplayer()
+	_, err = client.CreateUser(map[string]any{"name": "Gopher", "role": "user"})
+	fmt.Println("Unexpected request:", err)
+	fmt.Println("is ErrNoMatch:", errors.Is(err, ErrNoMatch))
+	client.GetUser(1)          // uses up the recorded GET
+	_, err = client.GetUser(1) // recorded once, so the second call has nothing left
+	fmt.Println("Same call twice:", err)
+}
+
+// Replace the Transport of http.Client, the code under test doesn't know it's recorded.
+// Never save secrets: drop Authorization, cookies and API keys before writing a cassette.
+// Match loosely where values change every run (request IDs, dates, JSON key order).
+// A miss should explain itself: show the closest recorded request and what differs.
+// Re-record cassettes when the real API changes, or the tests keep passing against old behaviour.
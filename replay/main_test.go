@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// usersAPI is the downstream service, it answers like the demo's and counts its calls
+func usersAPI(t *testing.T, calls *int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc123")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/users/1":
+			fmt.Fprint(w, `{"id":1,"name":"Rishabh"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/users":
+			var u User
+			json.NewDecoder(r.Body).Decode(&u)
+			fmt.Fprintf(w, `{"id":2,"name":%q}`, u.Name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// recordCassette records a GET and a POST against a live server
+func recordCassette(t *testing.T) *Cassette {
+	t.Helper()
+	calls := 0
+	api := usersAPI(t, &calls)
+	rec := &Recorder{Mode: ModeRecord, Cassette: &Cassette{}}
+	client := &UserClient{BaseURL: api.URL, Token: "s3cret", HTTP: &http.Client{Transport: rec}}
+	if u, err := client.GetUser(1); err != nil || u != (User{1, "Rishabh"}) {
+		t.Fatalf("GetUser while recording: %v, %v", u, err)
+	}
+	// the real server must still get the body, the recorder read it
+	if u, err := client.CreateUser(map[string]any{"name": "Gopher", "role": "admin"}); err != nil || u != (User{2, "Gopher"}) {
+		t.Fatalf("CreateUser while recording: %v, %v", u, err)
+	}
+	if calls != 2 || len(rec.Cassette.Interactions) != 2 {
+		t.Fatalf("%d calls, %d interactions", calls, len(rec.Cassette.Interactions))
+	}
+	return rec.Cassette
+}
+
+func replayer(c *Cassette) *Recorder {
+	return &Recorder{Mode: ModeReplay, Cassette: c, Match: MatchOptions{
+		IgnoreHeaders: []string{"X-Request-Id"}, IgnoreHost: true, JSONBody: true,
+	}}
+}
+
+func TestRecord(t *testing.T) {
+	c := recordCassette(t)
+	post := c.Interactions[1]
+	if post.Request.Method != http.MethodPost || !strings.HasSuffix(post.Request.URL, "/users") {
+		t.Errorf("request %+v", post.Request)
+	}
+	if post.Request.Body != `{"name":"Gopher","role":"admin"}` || post.Response.Body != `{"id":2,"name":"Gopher"}` {
+		t.Errorf("bodies %q -> %q", post.Request.Body, post.Response.Body)
+	}
+	if post.Response.Status != http.StatusOK || post.Response.Headers.Get("Content-Type") != "application/json" {
+		t.Errorf("response %+v", post.Response)
+	}
+	if post.Request.Headers.Get("X-Request-Id") != "req-2" {
+		t.Errorf("request headers %v", post.Request.Headers)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	c := recordCassette(t)
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := SaveCassette(path, c); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, secret := range []string{"s3cret", "abc123", "Authorization", "Set-Cookie"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the cassette contains %q:\n%s", secret, data)
+		}
+	}
+	h := http.Header{"Authorization": {"x"}, "Cookie": {"x"}, "X-Api-Key": {"x"}}
+	if got := sanitize(h); got != nil {
+		t.Errorf("sanitize left %v", got)
+	}
+	if h.Get("Authorization") != "x" {
+		t.Error("sanitize changed the caller's headers")
+	}
+}
+
+func TestReplayHit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	if err := SaveCassette(path, recordCassette(t)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no server at all, another host, another token, other request IDs
+	client := &UserClient{BaseURL: "http://users.internal", Token: "other", HTTP: &http.Client{Transport: replayer(c)}, nextID: 100}
+	if u, err := client.GetUser(1); err != nil || u != (User{1, "Rishabh"}) {
+		t.Errorf("GetUser: %v, %v", u, err)
+	}
+	// the same JSON with the keys in another order
+	body := struct {
+		Role string `json:"role"`
+		Name string `json:"name"`
+	}{"admin", "Gopher"}
+	if u, err := client.CreateUser(body); err != nil || u != (User{2, "Gopher"}) {
+		t.Errorf("CreateUser: %v, %v", u, err)
+	}
+}
+
+func TestReplayResponse(t *testing.T) {
+	c := &Cassette{Interactions: []Interaction{{
+		Request:  RecordedRequest{Method: "GET", URL: "http://x/teapot"},
+		Response: RecordedResponse{Status: 418, Headers: http.Header{"X-Brewed": {"yes"}}, Body: "short and stout"},
+	}}}
+	resp, err := (&http.Client{Transport: replayer(c)}).Get("http://x/teapot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 418 || resp.Status != "418 I'm a teapot" || resp.Header.Get("X-Brewed") != "yes" || string(body) != "short and stout" {
+		t.Errorf("%s %v %q", resp.Status, resp.Header, body)
+	}
+	resp.Header.Set("X-Brewed", "changed")
+	if c.Interactions[0].Response.Headers.Get("X-Brewed") != "yes" {
+		t.Error("changing a replayed response changed the cassette")
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	c := recordCassette(t)
+	client := &UserClient{BaseURL: "http://users.internal", HTTP: &http.Client{Transport: replayer(c)}}
+	_, err := client.CreateUser(map[string]any{"name": "Gopher", "role": "user"})
+	if !errors.Is(err, ErrNoMatch) {
+		t.Fatalf("got %v", err)
+	}
+	want := `no recorded interaction matches for POST http://users.internal/users, closest is #2:
+  body: recorded {"name":"Gopher","role":"admin"}, got {"name":"Gopher","role":"user"}`
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error:\n%v\nwant it to contain:\n%s", err, want)
+	}
+
+	_, err = client.GetUser(7)
+	if !strings.Contains(err.Error(), "url: recorded /users/1, got /users/7") {
+		t.Errorf("url diff: %v", err)
+	}
+}
+
+func TestReplayOnce(t *testing.T) {
+	client := &UserClient{BaseURL: "http://users.internal", HTTP: &http.Client{Transport: replayer(recordCassette(t))}}
+	if _, err := client.GetUser(1); err != nil {
+		t.Fatal(err)
+	}
+	client.CreateUser(map[string]any{"name": "Gopher", "role": "admin"})
+	_, err := client.GetUser(1)
+	if !errors.Is(err, ErrNoMatch) || !strings.Contains(err.Error(), "no unused interactions left") {
+		t.Errorf("third call: %v", err)
+	}
+}
+
+func TestMatchOptions(t *testing.T) {
+	recorded := RecordedRequest{Method: "POST", URL: "http://a/x?q=1", Headers: http.Header{"Date": {"Mon"}}, Body: `{"a":1,"b":[1,2]}`}
+	tests := []struct {
+		name  string
+		match MatchOptions
+		got   RecordedRequest
+		diffs int
+	}{
+		{"identical", MatchOptions{}, recorded, 0},
+		{"other host", MatchOptions{}, RecordedRequest{Method: "POST", URL: "http://b/x?q=1", Headers: recorded.Headers, Body: recorded.Body}, 1},
+		{"other host ignored", MatchOptions{IgnoreHost: true}, RecordedRequest{Method: "POST", URL: "https://b/x?q=1", Headers: recorded.Headers, Body: recorded.Body}, 0},
+		{"other query", MatchOptions{IgnoreHost: true}, RecordedRequest{Method: "POST", URL: "http://a/x?q=2", Headers: recorded.Headers, Body: recorded.Body}, 1},
+		{"volatile header", MatchOptions{}, RecordedRequest{Method: "POST", URL: recorded.URL, Headers: http.Header{"Date": {"Tue"}}, Body: recorded.Body}, 1},
+		{"volatile header ignored", MatchOptions{IgnoreHeaders: []string{"date"}}, RecordedRequest{Method: "POST", URL: recorded.URL, Body: recorded.Body}, 0},
+		{"json order", MatchOptions{}, RecordedRequest{Method: "POST", URL: recorded.URL, Headers: recorded.Headers, Body: `{"b":[1,2],"a":1}`}, 1},
+		{"json order ignored", MatchOptions{JSONBody: true}, RecordedRequest{Method: "POST", URL: recorded.URL, Headers: recorded.Headers, Body: ` {"b":[1,2], "a":1}`}, 0},
+		{"json array order matters", MatchOptions{JSONBody: true}, RecordedRequest{Method: "POST", URL: recorded.URL, Headers: recorded.Headers, Body: `{"a":1,"b":[2,1]}`}, 1},
+		{"everything", MatchOptions{}, RecordedRequest{Method: "PUT", URL: "http://a/y"}, 4},
+	}
+	for _, tt := range tests {
+		r := &Recorder{Match: tt.match}
+		if d := r.diff(recorded, tt.got); len(d) != tt.diffs {
+			t.Errorf("%s: %d differences %q, want %d", tt.name, len(d), d, tt.diffs)
+		}
+	}
+}
+
+func TestLoadCassetteErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadCassette(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing: %v", err)
+	}
+	bad := filepath.Join(dir, "bad.json")
+	os.WriteFile(bad, []byte("{not json"), 0o644)
+	if _, err := LoadCassette(bad); err == nil {
+		t.Error("bad JSON loaded")
+	}
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The shape of gRPC without protobuf: every call is POST /rpc/Service.Method with a JSON
+// request body, and the answer is either the JSON response or a structured error with a code.
+// Methods are plain typed functions, func(ctx, Req) (Resp, error). Generics turn them into
+// handlers on the server and into typed calls on the client, so neither side touches JSON.
+
+// ----------------------------------------------------------------------------
+// Error codes, as in the apperr lesson, plus the two only an RPC layer needs
+
+type Code string
+
+const (
+	NotFound      Code = "not_found"
+	Invalid       Code = "invalid"
+	Conflict      Code = "conflict"
+	Unimplemented Code = "unimplemented" // no such method
+	Internal      Code = "internal"
+)
+
+type Error struct {
+	Code Code   `json:"code"`
+	Msg  string `json:"message"`
+	Err  error  `json:"-"` // the cause, stays on the server
+}
+
+func E(code Code, msg string, wrapped error) error {
+	return &Error{Code: code, Msg: msg, Err: wrapped}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return string(e.Code) + ": " + e.Msg + ": " + e.Err.Error()
+	}
+	return string(e.Code) + ": " + e.Msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
+
+func HTTPStatus(code Code) int {
+	switch code {
+	case NotFound, Unimplemented:
+		return http.StatusNotFound
+	case Invalid:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+type errorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// ----------------------------------------------------------------------------
+// Server
+
+// method is a registered function with the types erased: JSON in, value out
+type method func(ctx context.Context, body []byte) (any, error)
+
+type Server struct {
+	mu      sync.RWMutex
+	methods map[string]method
+}
+
+func NewServer() *Server {
+	return &Server{methods: map[string]method{}}
+}
+
+// Register binds name ("UserService.Get") to fn. A Go method can't have type parameters,
+// so this is a function taking the server. Like http.ServeMux it panics on a bad or
+// duplicate name: that is a bug at startup, not something to handle.
+func Register[Req, Resp any](s *Server, name string, fn func(context.Context, Req) (Resp, error)) {
+	svc, m, ok := strings.Cut(name, ".")
+	if !ok || svc == "" || m == "" || strings.Contains(m, ".") {
+		panic(fmt.Sprintf("rpc: method name %q must look like Service.Method", name))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.methods[name]; dup {
+		panic(fmt.Sprintf("rpc: %s registered twice", name))
+	}
+	s.methods[name] = func(ctx context.Context, body []byte) (any, error) {
+		var req Req
+		if len(bytes.TrimSpace(body)) > 0 { // no body: the zero request, like an empty protobuf message
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.DisallowUnknownFields() // a misspelled field is an error, not a silently ignored one
+			if err := dec.Decode(&req); err != nil {
+				return nil, E(Invalid, fmt.Sprintf("request body for %s: %v", name, err), err)
+			}
+			if dec.More() {
+				return nil, E(Invalid, fmt.Sprintf("request body for %s: more than one JSON value", name), nil)
+			}
+		}
+		return fn(ctx, req)
+	}
+}
+
+// Methods lists what is registered, sorted
+func (s *Server) Methods() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.methods))
+	for n := range s.methods {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP handles POST /rpc/{method}
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("method")
+	s.mu.RLock()
+	m, ok := s.methods[name]
+	s.mu.RUnlock()
+	if !ok {
+		writeError(w, r, E(Unimplemented, fmt.Sprintf("unknown method %q", name), nil))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeError(w, r, E(Invalid, "request body too large", err))
+		return
+	}
+	resp, err := m(r.Context(), body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	code := CodeOf(err)
+	msg := "internal error"
+	var e *Error
+	if code == Internal {
+		// the full error goes to the log, the client only gets the code
+		fmt.Printf("    log: %s: %v\n", r.URL.Path, err)
+	} else if errors.As(err, &e) {
+		msg = e.Msg
+	}
+	writeJSON(w, HTTPStatus(code), errorResponse{&Error{Code: code, Msg: msg}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ----------------------------------------------------------------------------
+// Client
+
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// Call is Register's mirror image. A structured error from the server comes back as *Error
+// with the same code, so CodeOf works the same on both sides of the wire.
+func Call[Req, Resp any](ctx context.Context, c *Client, name string, req Req) (Resp, error) {
+	var resp Resp
+	body, err := json.Marshal(req)
+	if err != nil {
+		return resp, fmt.Errorf("call %s: %w", name, err)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/rpc/"+name, bytes.NewReader(body))
+	if err != nil {
+		return resp, fmt.Errorf("call %s: %w", name, err)
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	hresp, err := c.HTTP.Do(hreq)
+	if err != nil {
+		return resp, fmt.Errorf("call %s: %w", name, err)
+	}
+	defer hresp.Body.Close()
+	data, err := io.ReadAll(hresp.Body)
+	if err != nil {
+		return resp, fmt.Errorf("call %s: %w", name, err)
+	}
+	if hresp.StatusCode != http.StatusOK {
+		var er errorResponse
+		if json.Unmarshal(data, &er) != nil || er.Error == nil {
+			// not our error format, a proxy or a crash in between
+			return resp, E(Internal, fmt.Sprintf("call %s: status %d", name, hresp.StatusCode), nil)
+		}
+		return resp, er.Error
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return resp, fmt.Errorf("call %s: decode response: %w", name, err)
+	}
+	return resp, nil
+}
+
+// ----------------------------------------------------------------------------
+// UserService: the messages, the server side, and the stub a generator would write
+
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type GetUserRequest struct {
+	ID int `json:"id"`
+}
+
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type ListUsersRequest struct {
+	Limit int `json:"limit"` // 0: all
+}
+
+type ListUsersResponse struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+type UserStore struct {
+	mu      sync.Mutex
+	users   map[int]User
+	byEmail map[string]int
+	nextID  int
+	broken  bool // to show an unexpected error
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[int]User{}, byEmail: map[string]int{}, nextID: 1}
+}
+
+func (s *UserStore) Insert(u User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken {
+		return User{}, errors.New("write users.db: disk quota exceeded on /var/lib/app")
+	}
+	if _, dup := s.byEmail[u.Email]; dup {
+		return User{}, fmt.Errorf("insert %s: %w", u.Email, ErrDuplicateEmail)
+	}
+	u.ID = s.nextID
+	s.nextID++
+	s.users[u.ID] = u
+	s.byEmail[u.Email] = u.ID
+	return u, nil
+}
+
+func (s *UserStore) Get(id int) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	return u, ok
+}
+
+func (s *UserStore) List() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+type UserService struct {
+	store *UserStore
+}
+
+func (s *UserService) Get(ctx context.Context, req GetUserRequest) (User, error) {
+	u, ok := s.store.Get(req.ID)
+	if !ok {
+		return User{}, E(NotFound, fmt.Sprintf("user %d not found", req.ID), nil)
+	}
+	return u, nil
+}
+
+func (s *UserService) Create(ctx context.Context, req CreateUserRequest) (User, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return User{}, E(Invalid, "name is required", nil)
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		return User{}, E(Invalid, "email is not valid", err)
+	}
+	u, err := s.store.Insert(User{Name: req.Name, Email: req.Email})
+	if errors.Is(err, ErrDuplicateEmail) {
+		return User{}, E(Conflict, "email already registered", err)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("create user: %w", err) // no code: Internal
+	}
+	return u, nil
+}
+
+func (s *UserService) List(ctx context.Context, req ListUsersRequest) (ListUsersResponse, error) {
+	if req.Limit < 0 {
+		return ListUsersResponse{}, E(Invalid, "limit can't be negative", nil)
+	}
+	users := s.store.List()
+	total := len(users)
+	if req.Limit > 0 && req.Limit < total {
+		users = users[:req.Limit]
+	}
+	return ListUsersResponse{Users: users, Total: total}, nil
+}
+
+// RegisterUserService is what protoc would generate: one line per method, the types
+// come from the method values, a signature mismatch doesn't compile
+func RegisterUserService(s *Server, svc *UserService) {
+	Register(s, "UserService.Get", svc.Get)
+	Register(s, "UserService.Create", svc.Create)
+	Register(s, "UserService.List", svc.List)
+}
+
+// UserServiceClient is the generated-style stub: typed methods, no strings at the call site
+type UserServiceClient struct{ c *Client }
+
+func (u UserServiceClient) Get(ctx context.Context, req GetUserRequest) (User, error) {
+	return Call[GetUserRequest, User](ctx, u.c, "UserService.Get", req)
+}
+
+func (u UserServiceClient) Create(ctx context.Context, req CreateUserRequest) (User, error) {
+	return Call[CreateUserRequest, User](ctx, u.c, "UserService.Create", req)
+}
+
+func (u UserServiceClient) List(ctx context.Context, req ListUsersRequest) (ListUsersResponse, error) {
+	return Call[ListUsersRequest, ListUsersResponse](ctx, u.c, "UserService.List", req)
+}
+
+func main() {
+	fmt.Println("Learning a typed RPC layer over HTTP/JSON in Go")
+
+	store := NewUserStore()
+	rpc := NewServer()
+	RegisterUserService(rpc, &UserService{store: store})
+	mux := http.NewServeMux()
+	mux.Handle("POST /rpc/{method}", rpc)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	fmt.Println("Registered:", rpc.Methods())
+
+	ctx := context.Background()
+	users := UserServiceClient{&Client{BaseURL: srv.URL, HTTP: srv.Client()}}
+
+	fmt.Println("1. Typed round trips through the stub")
+	for _, req := range []CreateUserRequest{{"Rishabh", "rishabh@example.com"}, {"Sanchay", "sanchay@example.com"}, {"Alice", "alice@example.com"}} {
+		u, err := users.Create(ctx, req)
+		fmt.Printf("  Create(%s) -> %+v %v\n", req.Name, u, err)
+	}
+	u, err := users.Get(ctx, GetUserRequest{ID: 2})
+	fmt.Printf("  Get(2) -> %+v %v\n", u, err)
+	list, err := users.List(ctx, ListUsersRequest{Limit: 2})
+	fmt.Printf("  List(limit 2) -> %+v %v\n", list, err)
+
+	fmt.Println("2. Error codes cross the wire")
+	_, err = users.Get(ctx, GetUserRequest{ID: 42})
+	fmt.Printf("  Get(42): %v | CodeOf: %s\n", err, CodeOf(err))
+	_, err = users.Create(ctx, CreateUserRequest{"Other", "rishabh@example.com"})
+	fmt.Printf("  duplicate email: %v | CodeOf: %s\n", err, CodeOf(err))
+	_, err = users.Create(ctx, CreateUserRequest{"Bob", "not-an-email"})
+	fmt.Printf("  bad email: %v | CodeOf: %s\n", err, CodeOf(err))
+	_, err = users.List(ctx, ListUsersRequest{Limit: -1})
+	fmt.Printf("  negative limit: %v | CodeOf: %s\n", err, CodeOf(err))
+	store.broken = true
+	_, err = users.Create(ctx, CreateUserRequest{"Maya", "maya@example.com"})
+	fmt.Printf("  store failure: %v | CodeOf: %s (the cause stayed in the server log)\n", err, CodeOf(err))
+	store.broken = false
+
+	fmt.Println("3. Unknown methods")
+	client := &Client{BaseURL: srv.URL, HTTP: srv.Client()}
+	_, err = Call[GetUserRequest, User](ctx, client, "UserService.Delete", GetUserRequest{ID: 1})
+	fmt.Printf("  UserService.Delete: %v | CodeOf: %s\n", err, CodeOf(err))
+	_, err = Call[GetUserRequest, User](ctx, client, "OrderService.Get", GetUserRequest{ID: 1})
+	fmt.Printf("  OrderService.Get: %v\n", err)
+
+	fmt.Println("4. Malformed bodies, sent by hand")
+	for _, body := range []string{`{"id":`, `{"id":"two"}`, `{"ID":1,"admin":true}`, `{"id":1}{"id":2}`, `[1]`, ``} {
+		resp, err := http.Post(srv.URL+"/rpc/UserService.Get", "application/json", strings.NewReader(body))
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		out, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		fmt.Printf("  %-26q -> %d %s", body, resp.StatusCode, out)
+	}
+	resp, err := http.Get(srv.URL + "/rpc/UserService.Get")
+	if err == nil {
+		resp.Body.Close()
+		fmt.Println("  GET instead of POST ->", resp.StatusCode)
+	}
+
+	fmt.Println("5. Registering the same name twice is a bug, caught at startup")
+	func() {
+		defer func() { fmt.Println("  panic:", recover()) }()
+		Register(rpc, "UserService.Get", func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil })
+	}()
+}
+
+// One URL shape, POST /rpc/Service.Method, JSON in and JSON or a coded error out.
+// Register[Req, Resp] and Call[Req, Resp] keep the types, JSON only exists between them.
+// Decode strictly: unknown fields, trailing data and wrong types are Invalid, not ignored.
+// Send the code and a safe message, rebuild *Error on the client, and CodeOf works on both sides.
+// Generated-style stubs (RegisterUserService, UserServiceClient) keep method names out of calling code.
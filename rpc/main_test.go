@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type testServer struct {
+	store *UserStore
+	rpc   *Server
+	url   string
+	users UserServiceClient
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ts := &testServer{store: NewUserStore(), rpc: NewServer()}
+	RegisterUserService(ts.rpc, &UserService{store: ts.store})
+	mux := http.NewServeMux()
+	mux.Handle("POST /rpc/{method}", ts.rpc)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	ts.url = srv.URL
+	ts.users = UserServiceClient{&Client{BaseURL: srv.URL, HTTP: srv.Client()}}
+	return ts
+}
+
+// post sends body as it is, for what the typed client can't send
+func (ts *testServer) post(t *testing.T, method, body string) (int, errorResponse) {
+	t.Helper()
+	resp, err := http.Post(ts.url+"/rpc/"+method, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var er errorResponse
+	data, _ := io.ReadAll(resp.Body)
+	json.Unmarshal(data, &er)
+	return resp.StatusCode, er
+}
+
+func TestRoundTrips(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	var created []User
+	for _, req := range []CreateUserRequest{{"Rishabh", "rishabh@example.com"}, {"Sanchay", "sanchay@example.com"}, {"रिषभ", "r@例え.jp"}} {
+		u, err := ts.users.Create(ctx, req)
+		if err != nil || u.Name != req.Name || u.Email != req.Email || u.ID != len(created)+1 {
+			t.Fatalf("Create(%+v) = %+v, %v", req, u, err)
+		}
+		created = append(created, u)
+	}
+	if u, err := ts.users.Get(ctx, GetUserRequest{ID: 3}); err != nil || u != created[2] {
+		t.Errorf("Get(3) = %+v, %v", u, err)
+	}
+	tests := []struct {
+		limit int
+		want  []User
+	}{
+		{0, created},
+		{2, created[:2]},
+		{3, created},
+		{10, created},
+	}
+	for _, tt := range tests {
+		list, err := ts.users.List(ctx, ListUsersRequest{Limit: tt.limit})
+		if err != nil || list.Total != 3 || !slices.Equal(list.Users, tt.want) {
+			t.Errorf("List(%d) = %+v, %v", tt.limit, list, err)
+		}
+	}
+}
+
+// a code set on the server comes back as *Error with the same code and message
+func TestErrorCodes(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	ts.users.Create(ctx, CreateUserRequest{"Rishabh", "rishabh@example.com"})
+	tests := []struct {
+		name string
+		call func() error
+		code Code
+		msg  string
+	}{
+		{"missing user", func() error { _, err := ts.users.Get(ctx, GetUserRequest{ID: 42}); return err }, NotFound, "user 42 not found"},
+		{"duplicate email", func() error {
+			_, err := ts.users.Create(ctx, CreateUserRequest{"Other", "rishabh@example.com"})
+			return err
+		}, Conflict, "email already registered"},
+		{"bad email", func() error { _, err := ts.users.Create(ctx, CreateUserRequest{"Bob", "not-an-email"}); return err }, Invalid, "email is not valid"},
+		{"no name", func() error { _, err := ts.users.Create(ctx, CreateUserRequest{" ", "b@example.com"}); return err }, Invalid, "name is required"},
+		{"negative limit", func() error { _, err := ts.users.List(ctx, ListUsersRequest{Limit: -1}); return err }, Invalid, "limit can't be negative"},
+		{"unknown method", func() error {
+			_, err := Call[GetUserRequest, User](ctx, ts.users.c, "UserService.Delete", GetUserRequest{})
+			return err
+		}, Unimplemented, `unknown method "UserService.Delete"`},
+	}
+	for _, tt := range tests {
+		err := tt.call()
+		var e *Error
+		if !errors.As(err, &e) || e.Code != tt.code || e.Msg != tt.msg || CodeOf(err) != tt.code {
+			t.Errorf("%s: %#v", tt.name, err)
+		}
+	}
+}
+
+// an unexpected error reaches the client as Internal, the cause stays on the server
+func TestInternalErrorHidesTheCause(t *testing.T) {
+	ts := newTestServer(t)
+	ts.store.mu.Lock()
+	ts.store.broken = true
+	ts.store.mu.Unlock()
+	_, err := ts.users.Create(context.Background(), CreateUserRequest{"Maya", "maya@example.com"})
+	if CodeOf(err) != Internal || err.Error() != "internal: internal error" {
+		t.Errorf("err = %v", err)
+	}
+	code, er := ts.post(t, "UserService.Create", `{"name":"Maya","email":"maya@example.com"}`)
+	if code != 500 || strings.Contains(fmt.Sprint(er.Error), "disk") {
+		t.Errorf("%d %+v", code, er.Error)
+	}
+}
+
+func TestUnknownMethods(t *testing.T) {
+	ts := newTestServer(t)
+	for _, name := range []string{"UserService.Delete", "OrderService.Get", "userservice.get", "UserService", "UserService.Get.Extra"} {
+		code, er := ts.post(t, name, `{}`)
+		if code != http.StatusNotFound || er.Error == nil || er.Error.Code != Unimplemented {
+			t.Errorf("%s: %d %+v", name, code, er.Error)
+		}
+	}
+	resp, err := http.Get(ts.url + "/rpc/UserService.Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: %d", resp.StatusCode)
+	}
+}
+
+func TestMalformedBodies(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct {
+		body string
+		msg  string // a part of the message
+	}{
+		{`{"id":`, "unexpected EOF"},
+		{`{"id":"two"}`, "cannot unmarshal string"},
+		{`{"id":1,"admin":true}`, `unknown field "admin"`},
+		{`{"id":1}{"id":2}`, "more than one JSON value"},
+		{`[1]`, "cannot unmarshal array"},
+		{`{"id":1.5}`, "cannot unmarshal number"},
+	}
+	for _, tt := range tests {
+		code, er := ts.post(t, "UserService.Get", tt.body)
+		if code != http.StatusBadRequest || er.Error == nil || er.Error.Code != Invalid || !strings.Contains(er.Error.Msg, tt.msg) {
+			t.Errorf("%q: %d %+v", tt.body, code, er.Error)
+		}
+	}
+	// no body at all is the zero request: user 0, which doesn't exist
+	for _, body := range []string{"", "  \n"} {
+		if code, er := ts.post(t, "UserService.Get", body); code != http.StatusNotFound || er.Error.Msg != "user 0 not found" {
+			t.Errorf("%q: %d %+v", body, code, er.Error)
+		}
+	}
+	big := `{"name":"` + strings.Repeat("a", 2<<20) + `"}`
+	if code, er := ts.post(t, "UserService.Create", big); code != http.StatusBadRequest || er.Error.Msg != "request body too large" {
+		t.Errorf("2MB body: %d %+v", code, er.Error)
+	}
+}
+
+// what the client makes of answers that aren't from an rpc Server
+func TestClientOddResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    Code
+	}{
+		{"proxy error page", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "<html>502 Bad Gateway</html>", http.StatusBadGateway)
+		}, Internal},
+		{"error without an error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"no"}`)
+		}, Internal},
+		{"structured error", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusConflict, errorResponse{&Error{Code: Conflict, Msg: "taken"}})
+		}, Conflict},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(tt.handler)
+		_, err := Call[GetUserRequest, User](context.Background(), &Client{BaseURL: srv.URL, HTTP: srv.Client()}, "UserService.Get", GetUserRequest{})
+		srv.Close()
+		if CodeOf(err) != tt.code {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, `{"id":"x"}`) }))
+	defer srv.Close()
+	c := &Client{BaseURL: srv.URL, HTTP: srv.Client()}
+	if _, err := Call[GetUserRequest, User](context.Background(), c, "UserService.Get", GetUserRequest{}); err == nil || CodeOf(err) != Internal {
+		t.Errorf("bad response body: %v", err)
+	}
+	if _, err := Call[func(), User](context.Background(), c, "UserService.Get", func() {}); err == nil {
+		t.Error("a request that can't be marshaled was sent")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Call[GetUserRequest, User](ctx, c, "UserService.Get", GetUserRequest{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled: %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	s := NewServer()
+	noop := func(context.Context, struct{}) (struct{}, error) { return struct{}{}, nil }
+	Register(s, "B.Two", noop)
+	Register(s, "A.One", noop)
+	if got := s.Methods(); !slices.Equal(got, []string{"A.One", "B.Two"}) {
+		t.Errorf("Methods() = %v", got)
+	}
+	for _, name := range []string{"A.One", "", "NoDot", ".Method", "Service.", "A.B.C"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) didn't panic", name)
+				}
+			}()
+			Register(s, name, noop)
+		}()
+	}
+}
+
+// the context a handler gets is the request's: a client that goes away cancels it
+func TestHandlerContext(t *testing.T) {
+	s := NewServer()
+	entered := make(chan struct{})
+	done := make(chan error, 1)
+	Register(s, "Slow.Wait", func(ctx context.Context, _ struct{}) (struct{}, error) {
+		close(entered)
+		<-ctx.Done()
+		done <- ctx.Err()
+		return struct{}{}, ctx.Err()
+	})
+	mux := http.NewServeMux()
+	mux.Handle("POST /rpc/{method}", s)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-entered
+		cancel()
+	}()
+	Call[struct{}, struct{}](ctx, &Client{BaseURL: srv.URL, HTTP: srv.Client()}, "Slow.Wait", struct{}{})
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("handler's context: %v", err)
+	}
+}
+
+func TestConcurrentCreates(t *testing.T) {
+	ts := newTestServer(t)
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			if _, err := ts.users.Create(context.Background(), CreateUserRequest{"u", fmt.Sprintf("u%d@example.com", i)}); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	list, err := ts.users.List(context.Background(), ListUsersRequest{})
+	if err != nil || list.Total != 20 {
+		t.Errorf("%d users, %v", list.Total, err)
+	}
+}
+
+func TestCodes(t *testing.T) {
+	tests := []struct {
+		err    error
+		code   Code
+		status int
+	}{
+		{nil, "", 500},
+		{E(NotFound, "x", nil), NotFound, 404},
+		{fmt.Errorf("wrapped: %w", E(Conflict, "x", nil)), Conflict, 409},
+		{E(Invalid, "x", errors.New("cause")), Invalid, 400},
+		{E(Unimplemented, "x", nil), Unimplemented, 404},
+		{errors.New("plain"), Internal, 500},
+	}
+	for _, tt := range tests {
+		if got := CodeOf(tt.err); got != tt.code || HTTPStatus(got) != tt.status {
+			t.Errorf("%v: %q %d, want %q %d", tt.err, got, HTTPStatus(got), tt.code, tt.status)
+		}
+	}
+	cause := errors.New("cause")
+	if err := E(Invalid, "bad", cause); !errors.Is(err, cause) || err.Error() != "invalid: bad: cause" {
+		t.Errorf("%v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Three ways of picking at random that come up all the time:
+//   - weighted choice: "A twice as often as B", for load balancing, A/B tests, loot tables
+//   - reservoir sampling: k items, uniformly, from a stream too long to hold or of unknown length
+//   - shuffling: a random order of everything (rand.Shuffle, wrapped so it reads like the rest)
+//
+// Every function takes a *rand.Rand. Passing a seeded one makes the results repeatable,
+// which is what a test (or a bug report) needs.
+
+var (
+	ErrNoItems    = errors.New("no items to choose from")
+	ErrBadWeights = errors.New("invalid weights")
+)
+
+// Weighted picks items with probability proportional to their weight. Build it once and
+// call Pick many times: the cumulative sums are computed once, each pick is a binary search.
+type Weighted[T any] struct {
+	items []T
+	cum   []float64 // cum[i] = weights[0] + ... + weights[i]
+}
+
+func NewWeighted[T any](items []T, weights []float64) (*Weighted[T], error) {
+	if len(items) == 0 {
+		return nil, ErrNoItems
+	}
+	if len(weights) != len(items) {
+		return nil, fmt.Errorf("%w: %d weights for %d items", ErrBadWeights, len(weights), len(items))
+	}
+	w := &Weighted[T]{items: items, cum: make([]float64, len(weights))}
+	total := 0.0
+	for i, wt := range weights {
+		if wt < 0 || math.IsNaN(wt) || math.IsInf(wt, 0) {
+			return nil, fmt.Errorf("%w: weight %d is %v", ErrBadWeights, i, wt)
+		}
+		total += wt
+		w.cum[i] = total
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("%w: all weights are zero", ErrBadWeights)
+	}
+	if math.IsInf(total, 0) {
+		return nil, fmt.Errorf("%w: the weights add up to more than a float64 holds", ErrBadWeights) // Pick would index past the end
+	}
+	return w, nil
+}
+
+func (w *Weighted[T]) Pick(r *rand.Rand) T {
+	x := r.Float64() * w.cum[len(w.cum)-1]
+	// the first i with cum[i] > x; a zero weight has cum equal to its neighbour and is never picked
+	i := sort.Search(len(w.cum), func(i int) bool { return w.cum[i] > x })
+	return w.items[i]
+}
+
+// WeightedChoice is one pick, for when the weights change between calls
+func WeightedChoice[T any](items []T, weights []float64, r *rand.Rand) (T, error) {
+	w, err := NewWeighted(items, weights)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return w.Pick(r), nil
+}
+
+// SampleSeq picks k items uniformly from seq in one pass, holding only k of them
+// (Algorithm R): item number i (from 0) replaces a random slot with probability k/(i+1).
+// Fewer than k items: all of them. The order of the result is not meaningful.
+func SampleSeq[T any](seq iter.Seq[T], k int, r *rand.Rand) []T {
+	if k <= 0 {
+		return nil
+	}
+	reservoir := make([]T, 0, k)
+	i := 0
+	for v := range seq {
+		if i < k {
+			reservoir = append(reservoir, v)
+		} else if j := r.IntN(i + 1); j < k {
+			reservoir[j] = v
+		}
+		i++
+	}
+	return reservoir
+}
+
+func Sample[T any](items []T, k int, r *rand.Rand) []T {
+	return SampleSeq(slices.Values(items), k, r)
+}
+
+// Shuffle puts items in a random order, in place
+func Shuffle[T any](items []T, r *rand.Rand) {
+	r.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+}
+
+// Shuffled returns a shuffled copy and leaves items alone
+func Shuffled[T any](items []T, r *rand.Rand) []T {
+	out := slices.Clone(items)
+	Shuffle(out, r)
+	return out
+}
+
+// ----------------------------------------------------------------------------
+// Load balancer: three servers with weights 5/3/2
+
+type Server struct {
+	Name   string
+	Weight int
+}
+
+// smoothWRR is nginx's smooth weighted round-robin: every pick, each server gains its weight,
+// the one with the most wins and pays back the total. Exact proportions, and no long streaks.
+type smoothWRR struct {
+	servers []Server
+	current []int
+}
+
+func (s *smoothWRR) Pick() Server {
+	total, best := 0, 0
+	for i, srv := range s.servers {
+		s.current[i] += srv.Weight
+		total += srv.Weight
+		if s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	s.current[best] -= total
+	return s.servers[best]
+}
+
+// longestStreak: how many requests in a row one server got, bursts hurt a small server
+func longestStreak(picks []string) int {
+	longest, run := 0, 0
+	for i := range picks {
+		if i > 0 && picks[i] == picks[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+func LoadBalancerExample() {
+	servers := []Server{{"alpha", 5}, {"beta", 3}, {"gamma", 2}}
+	names := make([]string, len(servers))
+	weights := make([]float64, len(servers))
+	for i, s := range servers {
+		names[i], weights[i] = s.Name, float64(s.Weight)
+	}
+	weighted, err := NewWeighted(names, weights)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	rng := rand.New(rand.NewPCG(42, 1))
+	wrr := &smoothWRR{servers: servers, current: make([]int, len(servers))}
+
+	const requests = 10_000
+	strategies := []struct {
+		name string
+		pick func(i int) string
+	}{
+		{"round-robin", func(i int) string { return names[i%len(names)] }},
+		{"weighted random", func(int) string { return weighted.Pick(rng) }},
+		{"smooth weighted RR", func(int) string { return wrr.Pick().Name }},
+	}
+	fmt.Printf("  %-19s %7s %7s %7s  %s  %s\n", "strategy", "alpha", "beta", "gamma", "longest streak", "first 10")
+	for _, st := range strategies {
+		counts := map[string]int{}
+		picks := make([]string, requests)
+		for i := range requests {
+			picks[i] = st.pick(i)
+			counts[picks[i]]++
+		}
+		first := make([]string, 10)
+		for i, p := range picks[:10] {
+			first[i] = p[:1]
+		}
+		fmt.Printf("  %-19s %6.1f%% %6.1f%% %6.1f%%  %14d  %s\n", st.name,
+			100*float64(counts["alpha"])/requests, 100*float64(counts["beta"])/requests,
+			100*float64(counts["gamma"])/requests, longestStreak(picks), strings.Join(first, ""))
+	}
+}
+
+func main() {
+	fmt.Println("Learning weighted and reservoir sampling in Go")
+
+	// a fixed seed gives the same sequence on every run and every machine
+	newRNG := func() *rand.Rand { return rand.New(rand.NewPCG(1, 2)) }
+
+	fmt.Println("Seeded, so the same every run:")
+	r := newRNG()
+	var seq []string
+	for range 12 {
+		v, _ := WeightedChoice([]string{"a", "b", "c"}, []float64{5, 3, 2}, r)
+		seq = append(seq, v)
+	}
+	fmt.Println("  WeightedChoice 5/3/2:", strings.Join(seq, ""))
+	fmt.Println("  Sample 3 of 1..10:   ", Sample([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 3, newRNG()))
+	fmt.Println("  Shuffled 1..10:      ", Shuffled([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, newRNG()))
+	again, _ := WeightedChoice([]string{"a", "b", "c"}, []float64{5, 3, 2}, newRNG())
+	fmt.Println("  a fresh RNG with the same seed starts the same:", again == seq[0])
+
+	fmt.Println("Bad input:")
+	for _, w := range [][]float64{{1, 2}, {1, -1, 1}, {0, 0, 0}, {1, math.NaN(), 1}} {
+		_, err := WeightedChoice([]string{"a", "b", "c"}, w, r)
+		fmt.Printf("  %-14s %v (ErrBadWeights: %v)\n", fmt.Sprint(w), err, errors.Is(err, ErrBadWeights))
+	}
+	_, err := WeightedChoice([]string{}, nil, r)
+	fmt.Println("  no items:", err)
+	fmt.Println("  Sample(k > len):", Sample([]int{1, 2}, 5, r), "| Sample(k = 0):", Sample([]int{1, 2}, 0, r))
+
+	// statistics: over many draws every item must come out close to its share
+	const draws = 200_000
+	fmt.Printf("Within tolerance over %d draws (seeded, so this never flakes):\n", draws)
+	w, _ := NewWeighted([]int{0, 1, 2, 3}, []float64{5, 3, 2, 0})
+	counts := make([]int, 4)
+	r = newRNG()
+	for range draws {
+		counts[w.Pick(r)]++
+	}
+	for i, want := range []float64{0.5, 0.3, 0.2, 0} {
+		got := float64(counts[i]) / draws
+		fmt.Printf("  weight share %.1f: got %.4f, within 0.005: %v\n", want, got, math.Abs(got-want) < 0.005)
+	}
+	// reservoir: each of 10 items should land in a sample of 3 about 30% of the time
+	seen := make([]int, 10)
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	const runs = 50_000
+	for range runs {
+		for _, v := range Sample(items, 3, r) {
+			seen[v]++
+		}
+	}
+	worst := 0.0
+	for _, n := range seen {
+		worst = max(worst, math.Abs(float64(n)/runs-0.3))
+	}
+	fmt.Printf("  Sample 3 of 10, %d runs: worst item off by %.4f from 0.3, within 0.01: %v\n", runs, worst, worst < 0.01)
+
+	// streaming: a million numbers, never more than 5 in memory
+	stream := func(yield func(int) bool) {
+		for i := range 1_000_000 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	fmt.Println("  5 from a stream of a million:", SampleSeq(stream, 5, newRNG()))
+
+	fmt.Println("Load balancer, 10000 requests over weights 5/3/2:")
+	LoadBalancerExample()
+}
+
+// Pass a *rand.Rand everywhere: seeded in tests and bug reports, random in production.
+// Weighted choice: cumulative sums once, then a binary search per pick; reject negative, NaN and all-zero weights.
+// Reservoir sampling picks k uniformly in one pass with k items in memory, it works on any iter.Seq.
+// Weighted random gets the proportions right on average, smooth weighted round-robin gets them exactly, without bursts.
+// Test randomness with a fixed seed (exact sequences) and with many draws against a tolerance (the distribution).
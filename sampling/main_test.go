@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// the same seed as main, so the exact sequences below are the ones the demo prints
+func newRNG() *rand.Rand { return rand.New(rand.NewPCG(1, 2)) }
+
+func TestSeededSequences(t *testing.T) {
+	r := newRNG()
+	var got strings.Builder
+	for range 12 {
+		v, err := WeightedChoice([]string{"a", "b", "c"}, []float64{5, 3, 2}, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got.WriteString(v)
+	}
+	if got.String() != "bababacacabc" {
+		t.Errorf("WeightedChoice: %s", got.String())
+	}
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if got := Sample(items, 3, newRNG()); !slices.Equal(got, []int{9, 2, 3}) {
+		t.Errorf("Sample: %v", got)
+	}
+	if got := Shuffled(items, newRNG()); !slices.Equal(got, []int{5, 3, 4, 7, 10, 2, 9, 1, 6, 8}) {
+		t.Errorf("Shuffled: %v", got)
+	}
+	if !slices.Equal(items, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+		t.Errorf("Sample or Shuffled changed their input: %v", items)
+	}
+	Shuffle(items, newRNG())
+	if !slices.Equal(items, []int{5, 3, 4, 7, 10, 2, 9, 1, 6, 8}) {
+		t.Errorf("Shuffle: %v", items)
+	}
+}
+
+// a Weighted built once picks exactly what WeightedChoice does call by call
+func TestWeightedMatchesWeightedChoice(t *testing.T) {
+	items, weights := []string{"a", "b", "c", "d"}, []float64{0.1, 0, 7, 2.5}
+	w, err := NewWeighted(items, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r1, r2 := newRNG(), newRNG()
+	for i := range 1000 {
+		one, _ := WeightedChoice(items, weights, r1)
+		if got := w.Pick(r2); got != one {
+			t.Fatalf("pick %d: %s, WeightedChoice %s", i, got, one)
+		}
+	}
+}
+
+func TestWeightedErrors(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	tests := []struct {
+		weights []float64
+		msg     string
+	}{
+		{[]float64{1, 2}, "2 weights for 3 items"},
+		{[]float64{1, 2, 3, 4}, "4 weights for 3 items"},
+		{nil, "0 weights for 3 items"},
+		{[]float64{1, -1, 1}, "weight 1 is -1"},
+		{[]float64{1, 1, math.NaN()}, "weight 2 is NaN"},
+		{[]float64{math.Inf(1), 1, 1}, "weight 0 is +Inf"},
+		{[]float64{0, 0, 0}, "all weights are zero"},
+		{[]float64{math.MaxFloat64, math.MaxFloat64, 1}, "more than a float64 holds"},
+	}
+	for _, tt := range tests {
+		_, err := WeightedChoice(items, tt.weights, newRNG())
+		if !errors.Is(err, ErrBadWeights) || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%v: %v, want %q", tt.weights, err, tt.msg)
+		}
+	}
+	if _, err := WeightedChoice([]string{}, nil, newRNG()); err != ErrNoItems {
+		t.Errorf("no items: %v", err)
+	}
+	if _, err := NewWeighted[int](nil, nil); err != ErrNoItems {
+		t.Errorf("nil items: %v", err)
+	}
+}
+
+// many draws against a tolerance; the seed is fixed, so this never flakes
+func TestWeightedDistribution(t *testing.T) {
+	w, err := NewWeighted([]int{0, 1, 2, 3, 4}, []float64{5, 3, 2, 0, 0.1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const draws = 500_000
+	counts := make([]int, 5)
+	r := newRNG()
+	for range draws {
+		counts[w.Pick(r)]++
+	}
+	for i, weight := range []float64{5, 3, 2, 0, 0.1} {
+		want := weight / 10.1
+		got := float64(counts[i]) / draws
+		if math.Abs(got-want) > 0.005 {
+			t.Errorf("item %d: share %.4f, want %.4f ± 0.005", i, got, want)
+		}
+	}
+	if counts[3] != 0 {
+		t.Errorf("a zero weight was picked %d times", counts[3])
+	}
+	if counts[4] == 0 {
+		t.Error("a small weight was never picked")
+	}
+}
+
+func TestWeightedOneItem(t *testing.T) {
+	r := newRNG()
+	for range 100 {
+		if v, err := WeightedChoice([]string{"only"}, []float64{0.001}, r); v != "only" || err != nil {
+			t.Fatalf("%q %v", v, err)
+		}
+	}
+	// zeros around a single positive weight: only that one
+	w, _ := NewWeighted([]int{0, 1, 2}, []float64{0, 1, 0})
+	for range 1000 {
+		if v := w.Pick(r); v != 1 {
+			t.Fatalf("picked %d", v)
+		}
+	}
+}
+
+func TestSampleSizes(t *testing.T) {
+	r := newRNG()
+	items := []int{1, 2, 3}
+	tests := []struct {
+		k    int
+		want int
+	}{{-1, 0}, {0, 0}, {1, 1}, {3, 3}, {5, 3}}
+	for _, tt := range tests {
+		if got := Sample(items, tt.k, r); len(got) != tt.want {
+			t.Errorf("k=%d: %v", tt.k, got)
+		}
+	}
+	got := Sample(items, 5, r)
+	slices.Sort(got)
+	if !slices.Equal(got, items) {
+		t.Errorf("k > len: %v, want every item", got)
+	}
+	if got := Sample([]int{}, 3, r); len(got) != 0 {
+		t.Errorf("no items: %v", got)
+	}
+	// no item twice
+	for range 100 {
+		s := Sample([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 5, r)
+		slices.Sort(s)
+		if len(slices.Compact(s)) != 5 {
+			t.Fatalf("duplicates in %v", s)
+		}
+	}
+}
+
+// every item, early or late in the stream, ends up in the sample with probability k/n
+func TestSampleDistribution(t *testing.T) {
+	const n, k, runs = 10, 3, 100_000
+	items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	seen := make([]int, n)
+	r := newRNG()
+	for range runs {
+		for _, v := range Sample(items, k, r) {
+			seen[v]++
+		}
+	}
+	for i, c := range seen {
+		if got := float64(c) / runs; math.Abs(got-float64(k)/n) > 0.01 {
+			t.Errorf("item %d in %.4f of the samples, want %.2f ± 0.01", i, got, float64(k)/n)
+		}
+	}
+}
+
+// SampleSeq reads the whole stream once and holds on to k items only
+func TestSampleSeqStreams(t *testing.T) {
+	produced := 0
+	stream := func(yield func(int) bool) {
+		for i := range 100_000 {
+			produced++
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	got := SampleSeq(stream, 5, newRNG())
+	if len(got) != 5 || cap(got) != 5 || produced != 100_000 {
+		t.Errorf("%v (cap %d) after %d items", got, cap(got), produced)
+	}
+	if got := SampleSeq(stream, 0, newRNG()); got != nil {
+		t.Errorf("k=0: %v", got)
+	}
+	// the same stream and seed give the same sample
+	if again := SampleSeq(stream, 5, newRNG()); !slices.Equal(again, got) {
+		t.Errorf("%v then %v", got, again)
+	}
+}
+
+func TestSmoothWRR(t *testing.T) {
+	servers := []Server{{"alpha", 5}, {"beta", 3}, {"gamma", 2}}
+	wrr := &smoothWRR{servers: servers, current: make([]int, len(servers))}
+	var picks []string
+	for range 20 {
+		picks = append(picks, wrr.Pick().Name[:1])
+	}
+	// exactly 5/3/2 in every window of 10, the same pattern over and over
+	if got := strings.Join(picks, ""); got != "abgaabagbaabgaabagba" {
+		t.Errorf("picks %s", got)
+	}
+	if streak := longestStreak(picks); streak != 2 {
+		t.Errorf("longest streak %d", streak)
+	}
+}
+
+func TestLongestStreak(t *testing.T) {
+	tests := []struct {
+		picks []string
+		want  int
+	}{
+		{nil, 0},
+		{[]string{"a"}, 1},
+		{[]string{"a", "b", "a"}, 1},
+		{[]string{"a", "a", "b", "b", "b", "a"}, 3},
+		{[]string{"a", "a", "a", "a"}, 4},
+	}
+	for _, tt := range tests {
+		if got := longestStreak(tt.picks); got != tt.want {
+			t.Errorf("%v: %d, want %d", tt.picks, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkPick(b *testing.B) {
+	weights := make([]float64, 1000)
+	items := make([]int, 1000)
+	for i := range weights {
+		items[i], weights[i] = i, float64(i%7+1)
+	}
+	w, _ := NewWeighted(items, weights)
+	r := newRNG()
+	for b.Loop() {
+		w.Pick(r)
+	}
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cron expressions have 5 fields: minute hour day-of-month month day-of-week
+//
+//	*/15 * * * *     every 15 minutes
+//	0 9 * * 1-5      9:00 on weekdays
+//	0 0 1,15 * *     midnight on the 1st and 15th
+//	30 2 * 1-6/2 0   2:30 on Sundays in Jan, Mar and May
+//
+// Each field can be *, a number, a range a-b, a list a,b,c and a step /n (on * or a range).
+
+// ErrInvalidCron is wrapped by every ParseCron error
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// Schedule is a parsed expression. Each field is a bit set: bit n set = value n allowed.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // see dayMatches
+	expr                          string
+}
+
+type fieldSpec struct {
+	name     string
+	min, max int
+}
+
+var fields = []fieldSpec{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+func ParseCron(expr string) (Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return Schedule{}, fmt.Errorf("%w %q: want 5 fields, got %d", ErrInvalidCron, expr, len(parts))
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("%w %q: %s field: %v", ErrInvalidCron, expr, fields[i].name, err)
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1 // 7 -> 0
+	}
+	return Schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+		expr:    expr,
+	}, nil
+}
+
+// parseField turns "1-10/3,20" into a bit set
+func parseField(field string, spec fieldSpec) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", stepPart)
+			}
+			step = n
+		}
+		var lo, hi int
+		switch {
+		case rangePart == "*":
+			lo, hi = spec.min, spec.max
+			if spec.name == "day of week" {
+				hi = 6 // don't count Sunday twice
+			}
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("bad range %q", rangePart)
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("range %q goes backwards", rangePart)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("bad value %q", rangePart)
+			}
+			if hasStep {
+				return 0, fmt.Errorf("step needs * or a range, got %q", item)
+			}
+			lo, hi = n, n
+		}
+		if lo < spec.min || hi > spec.max {
+			return 0, fmt.Errorf("%q out of range %d-%d", rangePart, spec.min, spec.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func has(bits uint64, v int) bool { return bits&(1<<v) != 0 }
+
+// dayMatches follows the classic cron rule: if both day fields are restricted,
+// a day matches when EITHER matches ("0 0 13 * 5" = every 13th and every Friday).
+// If one of them is *, only the other one counts.
+func (s Schedule) dayMatches(t time.Time) bool {
+	domOK := has(s.dom, t.Day())
+	dowOK := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next returns the first matching time strictly after `after`, in after's time zone.
+// It returns the zero time if nothing matches within 5 years (e.g. "0 0 30 2 *", Feb 30th).
+//
+// Around daylight saving changes it behaves like most cron libraries:
+// a time that doesn't exist on the day clocks go forward (2:30 in most of the US) is skipped,
+// a time that happens twice when clocks go back fires twice.
+func (s Schedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	// start at the next whole minute. Not with time.Date: during the repeated DST hour
+	// it would pick the first 1:30 even when after is the second one.
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Year() + 5
+
+	for t.Year() <= limit {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc) // time.Date normalises month 13
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			// hours and minutes move in real time (Add), so skipped/repeated DST hours are handled
+			t = t.Add(time.Hour - time.Duration(t.Minute())*time.Minute)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s Schedule) String() string { return s.expr }
+
+// Clock lets the runner be driven by a fake clock in demos and tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Job gets the scheduled time it was started for
+type Job func(ctx context.Context, at time.Time)
+
+type cronEntry struct {
+	name  string
+	sched Schedule
+	next  time.Time
+	job   Job
+}
+
+// Runner runs jobs on their schedules with ONE timer goroutine:
+// it sleeps until the nearest next run, starts every job that is due, then recomputes.
+type Runner struct {
+	clock   Clock
+	OnPanic func(job string, v any) // called when a job panics, the other jobs keep running
+
+	mu      sync.Mutex
+	entries []*cronEntry
+	changed chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRunner uses the real clock when clock is nil
+func NewRunner(clock Clock) *Runner {
+	if clock == nil {
+		clock = realClock{}
+	}
+	return &Runner{clock: clock, changed: make(chan struct{}, 1)}
+}
+
+// Add registers job under name. It can be called before or while Run is running.
+func (r *Runner) Add(name, expr string, job Job) error {
+	sched, err := ParseCron(expr)
+	if err != nil {
+		return fmt.Errorf("add job %q: %w", name, err)
+	}
+	r.mu.Lock()
+	r.entries = append(r.entries, &cronEntry{name: name, sched: sched, next: sched.Next(r.clock.Now()), job: job})
+	r.mu.Unlock()
+	select {
+	case r.changed <- struct{}{}: // wake Run up, the new job may be the nearest one
+	default:
+	}
+	return nil
+}
+
+// Run blocks until ctx is cancelled, then waits for running jobs to return
+func (r *Runner) Run(ctx context.Context) {
+	defer r.wg.Wait()
+	for {
+		r.mu.Lock()
+		var nearest time.Time
+		for _, e := range r.entries {
+			if !e.next.IsZero() && (nearest.IsZero() || e.next.Before(nearest)) {
+				nearest = e.next
+			}
+		}
+		r.mu.Unlock()
+
+		var timer <-chan time.Time // nil channel: blocks forever when there is nothing to run
+		if !nearest.IsZero() {
+			timer = r.clock.After(nearest.Sub(r.clock.Now()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+		case now := <-timer:
+			r.mu.Lock()
+			for _, e := range r.entries { // registration order
+				if e.next.IsZero() || e.next.After(now) {
+					continue
+				}
+				at := e.next
+				e.next = e.sched.Next(now) // runs missed while the process slept are skipped, like cron
+				r.wg.Add(1)
+				go r.runJob(ctx, e, at)
+			}
+			r.mu.Unlock()
+		}
+	}
+}
+
+// runJob runs one job in its own goroutine, so a slow job doesn't delay the others
+// and a panicking job doesn't crash the process
+func (r *Runner) runJob(ctx context.Context, e *cronEntry, at time.Time) {
+	defer r.wg.Done()
+	defer func() {
+		if v := recover(); v != nil {
+			if r.OnPanic != nil {
+				r.OnPanic(e.name, v)
+			} else {
+				fmt.Printf("job %s panicked: %v\n", e.name, v)
+			}
+		}
+	}()
+	e.job(ctx, at)
+}
+
+// FakeClock only moves when told to, so an hour of schedules runs in microseconds
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	added   chan struct{}
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, added: make(chan struct{}, 1)}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+	return ch
+}
+
+// AdvanceToNext waits until someone is waiting on the clock, then jumps to the
+// earliest wake-up time and fires it. It returns false, without moving,
+// when that time is after until.
+func (c *FakeClock) AdvanceToNext(until time.Time) bool {
+	for {
+		c.mu.Lock()
+		if len(c.waiters) > 0 {
+			break
+		}
+		c.mu.Unlock()
+		<-c.added
+	}
+	defer c.mu.Unlock()
+	sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+	if c.waiters[0].at.After(until) {
+		return false
+	}
+	c.now = c.waiters[0].at
+	for len(c.waiters) > 0 && !c.waiters[0].at.After(c.now) {
+		c.waiters[0].ch <- c.now
+		c.waiters = c.waiters[1:]
+	}
+	return true
+}
+
+func main() {
+	fmt.Println("Learning cron schedules and a job runner in Go")
+
+	// 1. Next
+	base := time.Date(2026, time.January, 30, 22, 47, 0, 0, time.UTC) // a Friday
+	fmt.Println("After", base.Format("Mon 2006-01-02 15:04"))
+	for _, expr := range []string{
+		"*/15 * * * *",
+		"0 9 * * 1-5",  // next weekday at 9: Monday
+		"0 0 1,15 * *", // month rollover
+		"0 0 31 * *",   // skips February
+		"0 0 13 * 5",   // the 13th OR a Friday
+		"0 0 29 2 *",   // next leap day: 2028
+		"30 2 * 1-6/2 0",
+		"0 0 30 2 *", // never
+	} {
+		s, _ := ParseCron(expr)
+		next := s.Next(base)
+		if next.IsZero() {
+			fmt.Printf("  %-16s never\n", expr)
+			continue
+		}
+		fmt.Printf("  %-16s %s\n", expr, next.Format("Mon 2006-01-02 15:04"))
+	}
+
+	// 2. daylight saving time
+	if ny, err := time.LoadLocation("America/New_York"); err != nil {
+		fmt.Println("Time zone data not available:", err)
+	} else {
+		fmt.Println("DST in New York (2026-03-08 2:00 -> 3:00, 2026-11-01 2:00 -> 1:00)")
+		s, _ := ParseCron("30 2 * * *")
+		t := time.Date(2026, time.March, 7, 12, 0, 0, 0, ny)
+		t = s.Next(t)
+		fmt.Println("  30 2 * * * ->", t.Format("2006-01-02 15:04 MST"), "(the 8th has no 2:30, so it's skipped)")
+		s, _ = ParseCron("30 1 * * *")
+		t = time.Date(2026, time.October, 31, 12, 0, 0, 0, ny)
+		for i := 0; i < 3; i++ {
+			t = s.Next(t)
+			fmt.Println("  30 1 * * * ->", t.Format("2006-01-02 15:04 MST"))
+		}
+		s, _ = ParseCron("0 3 * * *")
+		fmt.Println("  0 3 * * *  ->", s.Next(time.Date(2026, time.November, 1, 0, 30, 0, 0, ny)).Format("2006-01-02 15:04 MST"), "(4 real hours later)")
+	}
+
+	// 3. invalid expressions
+	for _, expr := range []string{"* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "5/2 * * * *", "a * * * *", "* * 0 * *"} {
+		_, err := ParseCron(expr)
+		fmt.Println("  ", err, "| errors.Is:", errors.Is(err, ErrInvalidCron))
+	}
+
+	// 4. a runner on a fake clock: 2 hours of schedules
+	fmt.Println("Runner with a fake clock")
+	clock := NewFakeClock(time.Date(2026, time.May, 4, 8, 58, 30, 0, time.UTC))
+	runner := NewRunner(clock)
+	var logMu sync.Mutex
+	var log []string
+	record := func(name string) Job {
+		return func(ctx context.Context, at time.Time) {
+			logMu.Lock()
+			log = append(log, at.Format("15:04")+" "+name)
+			logMu.Unlock()
+		}
+	}
+	runner.Add("metrics-snapshot", "* 9 * * *", record("metrics-snapshot")) // every minute from 9:00
+	runner.Add("report", "0,30 9-10 * * *", record("report"))
+	runner.Add("flaky", "15 9 * * *", func(ctx context.Context, at time.Time) { panic("disk full") })
+	runner.OnPanic = func(job string, v any) {
+		logMu.Lock()
+		log = append(log, clock.Now().Format("15:04")+" "+job+" panicked: "+fmt.Sprint(v))
+		logMu.Unlock()
+	}
+	fmt.Println("  add invalid:", runner.Add("bad", "every minute", record("bad")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	end := time.Date(2026, time.May, 4, 10, 59, 0, 0, time.UTC)
+	for clock.AdvanceToNext(end) {
+		// every call jumps straight to the next minute with something due
+	}
+	cancel()
+	<-done
+	sort.Strings(log) // jobs due at the same minute run concurrently, sort for a stable print
+	snapshots := 0
+	for _, line := range log {
+		if strings.HasSuffix(line, "metrics-snapshot") {
+			snapshots++
+			if !strings.HasPrefix(line, "09:00") && !strings.HasPrefix(line, "09:59") {
+				continue
+			}
+		}
+		fmt.Println("  ", line)
+	}
+	fmt.Println("   metrics-snapshot ran", snapshots, "times (9:00-9:59)")
+
+	// 5. a real clock runner stops on cancel
+	real := NewRunner(nil)
+	real.Add("every-minute", "* * * * *", record("every-minute"))
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	real.Run(ctx)
+	fmt.Println("Real runner stopped after", time.Since(start).Round(10*time.Millisecond))
+}
+
+// Computing Next field by field (month, day, hour, minute) is fast and easy to reason about.
+// Both day fields restricted means OR, that's the classic cron rule that surprises people.
+// Around DST, a skipped local time doesn't run and a repeated one runs twice: prefer UTC for jobs.
+// One timer for all jobs: sleep until the nearest run, start what's due, recompute.
+// Inject the clock: with a fake one, hours of scheduling can be checked instantly.
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	friday := time.Date(2026, time.January, 30, 22, 47, 0, 0, time.UTC)
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  string // "" for never
+	}{
+		{"*/15 * * * *", friday, "2026-01-30 23:00"},
+		{"* * * * *", friday, "2026-01-30 22:48"},
+		{"* * * * *", friday.Add(30 * time.Second), "2026-01-30 22:48"}, // strictly after, whole minutes
+		{"47 22 * * *", friday, "2026-01-31 22:47"},                     // not the same minute again
+		{"0 9 * * 1-5", friday, "2026-02-02 09:00"},                     // Monday
+		{"0 0 1,15 * *", friday, "2026-02-01 00:00"},
+		{"0 0 31 * *", friday, "2026-01-31 00:00"},
+		{"0 0 31 * *", friday.AddDate(0, 0, 2), "2026-03-31 00:00"}, // skips February
+		{"0 0 13 * 5", friday, "2026-02-06 00:00"},                  // a Friday comes before the 13th
+		{"0 0 13 * 5", time.Date(2026, 2, 7, 0, 0, 0, 0, time.UTC), "2026-02-13 00:00"},
+		{"0 0 13 * *", friday, "2026-02-13 00:00"},
+		{"0 0 * * 0", friday, "2026-02-01 00:00"},
+		{"0 0 * * 7", friday, "2026-02-01 00:00"}, // 7 is Sunday too
+		{"0 0 * * 6,7", friday, "2026-01-31 00:00"},
+		{"0 0 29 2 *", friday, "2028-02-29 00:00"},
+		{"30 2 * 1-6/2 0", friday, "2026-03-01 02:30"},
+		{"0 0 1 1 *", time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), "2027-01-01 00:00"},
+		{"59 23 31 12 *", time.Date(2026, 12, 31, 23, 58, 59, 0, time.UTC), "2026-12-31 23:59"},
+		{"0 0 30 2 *", friday, ""},
+		{"0 0 31 4,6,9,11 *", friday, ""},
+	}
+	for _, tt := range tests {
+		s, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+		}
+		got := s.Next(tt.after)
+		if tt.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%q after %v = %v, want never", tt.expr, tt.after, got)
+			}
+			continue
+		}
+		if got.Format("2006-01-02 15:04") != tt.want || got.Location() != tt.after.Location() {
+			t.Errorf("%q after %v = %v, want %s", tt.expr, tt.after.Format("Mon 2006-01-02 15:04:05"), got, tt.want)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	next := func(expr string, after time.Time) time.Time {
+		s, err := ParseCron(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Next(after)
+	}
+	// 2026-03-08: 2:00 jumps to 3:00, 2:30 doesn't exist that day
+	got := next("30 2 * * *", time.Date(2026, 3, 7, 12, 0, 0, 0, ny))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("skipped 2:30: got %v, want %v", got, want)
+	}
+	got = next("0 3 * * *", time.Date(2026, 3, 8, 1, 30, 0, 0, ny))
+	if !got.Equal(time.Date(2026, 3, 8, 3, 0, 0, 0, ny)) || got.Sub(time.Date(2026, 3, 8, 1, 30, 0, 0, ny)) != 30*time.Minute {
+		t.Errorf("3:00 on the spring-forward day: %v", got)
+	}
+	// 2026-11-01: 1:00-1:59 happens twice, both fire
+	first := next("30 1 * * *", time.Date(2026, 10, 31, 12, 0, 0, 0, ny))
+	second := next("30 1 * * *", first)
+	third := next("30 1 * * *", second)
+	if first.Sub(time.Date(2026, 11, 1, 0, 0, 0, 0, ny)) != 90*time.Minute || second.Sub(first) != time.Hour {
+		t.Errorf("repeated 1:30: %v then %v", first, second)
+	}
+	if want := time.Date(2026, 11, 2, 1, 30, 0, 0, ny); !third.Equal(want) {
+		t.Errorf("after the repeat: %v, want %v", third, want)
+	}
+	got = next("0 3 * * *", time.Date(2026, 11, 1, 0, 30, 0, 0, ny))
+	if got.Sub(time.Date(2026, 11, 1, 0, 30, 0, 0, ny)) != 3*time.Hour+30*time.Minute {
+		t.Errorf("3:00 on the fall-back day: %v", got)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *",
+		"60 * * * *", "* 24 * * *", "* * 0 * *", "* * 32 * *", "* * * 0 *", "* * * 13 *", "* * * * 8",
+		"5-1 * * * *", "*/0 * * * *", "*/-1 * * * *", "5/2 * * * *", "a * * * *", "1-x * * * *",
+		"1,,2 * * * *", "-1 * * * *",
+	} {
+		if _, err := ParseCron(expr); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("ParseCron(%q) = %v, want ErrInvalidCron", expr, err)
+		}
+	}
+	s, err := ParseCron("  0   9 * *  1-5 ")
+	if err != nil || s.String() != "  0   9 * *  1-5 " {
+		t.Errorf("extra spaces: %v, %v", s, err)
+	}
+}
+
+func TestParseField(t *testing.T) {
+	minute := fields[0]
+	tests := []struct {
+		field string
+		want  []int
+	}{
+		{"*/20", []int{0, 20, 40}},
+		{"1-10/3,20", []int{1, 4, 7, 10, 20}},
+		{"5,5,5", []int{5}},
+		{"58-59", []int{58, 59}},
+	}
+	for _, tt := range tests {
+		bits, err := parseField(tt.field, minute)
+		var got []int
+		for v := range 60 {
+			if has(bits, v) {
+				got = append(got, v)
+			}
+		}
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("parseField(%q) = %v, %v; want %v", tt.field, got, err, tt.want)
+		}
+	}
+}
+
+// runFor drives runner on clock until end, then cancels it
+func runFor(t *testing.T, runner *Runner, clock *FakeClock, end time.Time) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	for clock.AdvanceToNext(end) {
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
+
+type jobLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *jobLog) job(name string) Job {
+	return func(ctx context.Context, at time.Time) {
+		l.mu.Lock()
+		l.lines = append(l.lines, at.Format("15:04")+" "+name)
+		l.mu.Unlock()
+	}
+}
+
+func (l *jobLog) sorted() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := slices.Clone(l.lines)
+	sort.Strings(out)
+	return out
+}
+
+func TestRunnerFiringOrder(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 5, 4, 8, 58, 30, 0, time.UTC))
+	runner := NewRunner(clock)
+	log := &jobLog{}
+	runner.Add("a-every-20", "*/20 9 * * *", log.job("a-every-20"))
+	runner.Add("b-half-hour", "0,30 9-10 * * *", log.job("b-half-hour"))
+	runner.Add("c-once", "45 9 * * *", log.job("c-once"))
+	runFor(t, runner, clock, time.Date(2026, 5, 4, 10, 59, 0, 0, time.UTC))
+
+	want := []string{
+		"09:00 a-every-20", "09:00 b-half-hour",
+		"09:20 a-every-20",
+		"09:30 b-half-hour",
+		"09:40 a-every-20",
+		"09:45 c-once",
+		"10:00 b-half-hour",
+		"10:30 b-half-hour",
+	}
+	if got := log.sorted(); !slices.Equal(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func waitingFor(c *FakeClock, at time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.ContainsFunc(c.waiters, func(w fakeWaiter) bool { return w.at.Equal(at) })
+}
+
+func TestRunnerAddWhileRunning(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	runner := NewRunner(clock)
+	log := &jobLog{}
+	runner.Add("hourly", "0 * * * *", log.job("hourly"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	// Run is waiting for 10:00; a job due sooner must wake it up
+	if err := runner.Add("soon", "5 9 * * *", log.job("soon")); err != nil {
+		t.Fatal(err)
+	}
+	// Run must now wait on the clock for 9:05, not only for 10:00
+	deadline := time.Now().Add(2 * time.Second)
+	for !waitingFor(clock, time.Date(2026, 5, 4, 9, 5, 0, 0, time.UTC)) {
+		if time.Now().After(deadline) {
+			t.Fatal("Run was not woken up by Add")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	for clock.AdvanceToNext(time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)) {
+	}
+	cancel()
+	<-done
+	if got, want := log.sorted(), []string{"09:05 soon", "10:00 hourly"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if err := runner.Add("bad", "every minute", nil); !errors.Is(err, ErrInvalidCron) {
+		t.Errorf("Add invalid: %v", err)
+	}
+}
+
+func TestRunnerPanicIsolation(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 5, 4, 8, 59, 0, 0, time.UTC))
+	runner := NewRunner(clock)
+	log := &jobLog{}
+	var mu sync.Mutex
+	var panics []string
+	runner.OnPanic = func(job string, v any) {
+		mu.Lock()
+		panics = append(panics, job+": "+v.(string))
+		mu.Unlock()
+	}
+	runner.Add("flaky", "*/2 9 * * *", func(ctx context.Context, at time.Time) { panic("disk full") })
+	runner.Add("steady", "* 9 * * *", log.job("steady"))
+	runFor(t, runner, clock, time.Date(2026, 5, 4, 9, 9, 0, 0, time.UTC))
+	if n := len(log.sorted()); n != 10 {
+		t.Errorf("steady ran %d times, want 10", n)
+	}
+	if len(panics) != 5 || panics[0] != "flaky: disk full" {
+		t.Errorf("panics: %v", panics)
+	}
+}
+
+func TestRunWaitsForJobs(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 5, 4, 8, 59, 0, 0, time.UTC))
+	runner := NewRunner(clock)
+	started := make(chan struct{})
+	var finished bool
+	runner.Add("slow", "0 9 * * *", func(ctx context.Context, at time.Time) {
+		close(started)
+		<-ctx.Done() // jobs get Run's ctx and should stop with it
+		time.Sleep(20 * time.Millisecond)
+		finished = true
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	clock.AdvanceToNext(time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC))
+	<-started
+	cancel()
+	<-done
+	if !finished {
+		t.Error("Run returned before the running job finished")
+	}
+}
+
+func TestRealRunnerStops(t *testing.T) {
+	runner := NewRunner(nil)
+	runner.Add("every-minute", "* * * * *", func(context.Context, time.Time) {})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runner.Run(ctx)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Run took %v after cancel", d)
+	}
+	// no jobs at all: Run still returns on cancel
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	NewRunner(nil).Run(ctx2)
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// With a JWT the server keeps nothing: the token itself says who you are until it expires.
+// With a session the cookie is only a random ID, and the server keeps what it means
+// (user, expiry) in a map. Logout is just a delete, and the server can see every active login.
+
+const cookieName = "session_id"
+
+type Session struct {
+	ID        string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+type SessionStore struct {
+	IdleTimeout time.Duration
+	Now         func() time.Time // replaced by a fake clock in the demo
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewSessionStore(idle time.Duration) *SessionStore {
+	return &SessionStore{IdleTimeout: idle, Now: time.Now, sessions: make(map[string]*Session)}
+}
+
+// Create starts a session for userID. oldID is the session the client came with (may be "").
+// It's deleted, so an ID an attacker planted in the victim's browser before login
+// never becomes a logged-in session (session fixation).
+func (s *SessionStore) Create(userID, oldID string) *Session {
+	sess := &Session{ID: rand.Text(), UserID: userID} // 26 random base32 chars, 130 bits
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, oldID)
+	sess.ExpiresAt = s.Now().Add(s.IdleTimeout)
+	s.sessions[sess.ID] = sess
+	return sess
+}
+
+// Get returns the session and pushes its expiry back: the timeout counts from the last request
+func (s *SessionStore) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	now := s.Now()
+	if !now.Before(sess.ExpiresAt) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	sess.ExpiresAt = now.Add(s.IdleTimeout)
+	return *sess, true // a copy, the caller can't change the stored one without the lock
+}
+
+func (s *SessionStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+func (s *SessionStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Sweep removes expired sessions. Get already refuses them, this only frees the memory
+// of sessions whose users never came back.
+func (s *SessionStore) Sweep() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	removed := 0
+	for id, sess := range s.sessions {
+		if !now.Before(sess.ExpiresAt) {
+			delete(s.sessions, id) // deleting while ranging over a map is allowed
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *SessionStore) StartSweeper(ctx context.Context, every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+type ctxKey struct{}
+
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+var passwords = map[string]string{"rishabh": "gopher123", "alice": "wonderland"}
+
+func checkPassword(user, password string) bool {
+	want, ok := passwords[user]
+	// compare anyway when the user doesn't exist, so the time taken doesn't tell
+	return subtle.ConstantTimeCompare([]byte(password), []byte(want)) == 1 && ok
+}
+
+func setSessionCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,               // <0 deletes the cookie, 0 keeps it until the browser closes
+		Secure:   true,                 // only sent over HTTPS
+		HttpOnly: true,                 // JavaScript can't read it, an XSS can't steal it
+		SameSite: http.SameSiteLaxMode, // not sent on cross-site POSTs (CSRF)
+	})
+}
+
+func handleLogin(store *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("mode") != "session" {
+			http.Error(w, "only mode=session is supported here", http.StatusBadRequest)
+			return
+		}
+		var creds struct{ Username, Password string }
+		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		if !checkPassword(creds.Username, creds.Password) {
+			http.Error(w, "wrong username or password", http.StatusUnauthorized)
+			return
+		}
+		oldID := ""
+		if c, err := r.Cookie(cookieName); err == nil {
+			oldID = c.Value
+		}
+		sess := store.Create(creds.Username, oldID)
+		setSessionCookie(w, sess.ID, 0)
+		fmt.Fprintln(w, "logged in as", creds.Username)
+	}
+}
+
+func handleLogout(store *SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie(cookieName); err == nil {
+			store.Delete(c.Value) // the cookie alone is worthless from now on
+		}
+		setSessionCookie(w, "", -1)
+		fmt.Fprintln(w, "logged out")
+	}
+}
+
+func sessionMiddleware(store *SessionStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie(cookieName)
+		if err != nil {
+			http.Error(w, "not logged in", http.StatusUnauthorized)
+			return
+		}
+		sess, ok := store.Get(c.Value)
+		if !ok {
+			http.Error(w, "session expired or unknown", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, sess.UserID)))
+	})
+}
+
+// fakeClock is moved by hand, so expiry can be shown without waiting
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func main() {
+	fmt.Println("Learning cookie sessions in Go")
+
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	store := NewSessionStore(30 * time.Minute)
+	store.Now = clock.Now
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.StartSweeper(ctx, 10*time.Millisecond)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", handleLogin(store))
+	mux.HandleFunc("POST /api/logout", handleLogout(store))
+	mux.Handle("GET /api/me", sessionMiddleware(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "you are", UserID(r.Context()))
+	})))
+	// TLS, because a Secure cookie is never sent over plain http, a cookie jar respects that too
+	srv := httptest.NewTLSServer(mux)
+	defer srv.Close()
+	srvURL, _ := url.Parse(srv.URL)
+
+	jar, _ := cookiejar.New(nil)
+	client := srv.Client()
+	client.Jar = jar // stores Set-Cookie answers and sends them back, like a browser
+	do := func(method, path, body string) {
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		resp, err := client.Do(req)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(resp.Body)
+		fmt.Printf("  %-4s %-25s -> %d %s", method, path, resp.StatusCode, text)
+	}
+	cookie := func() string {
+		for _, c := range jar.Cookies(srvURL) {
+			if c.Name == cookieName {
+				return c.Value
+			}
+		}
+		return ""
+	}
+
+	fmt.Println("Login and use the session:")
+	do("GET", "/api/me", "")
+	do("POST", "/api/login?mode=session", `{"username":"rishabh","password":"wrong"}`)
+	do("POST", "/api/login?mode=session", `{"username":"rishabh","password":"gopher123"}`)
+	do("GET", "/api/me", "")
+	resp, _ := client.Post(srv.URL+"/api/login?mode=session", "application/json", strings.NewReader(`{"username":"alice","password":"wonderland"}`))
+	resp.Body.Close()
+	fmt.Println("  Set-Cookie:", strings.Replace(resp.Header.Get("Set-Cookie"), cookie(), "<id>", 1))
+
+	fmt.Println("Session fixation: an ID planted before login is thrown away")
+	planted := store.Create("nobody", "").ID
+	jar.SetCookies(srvURL, []*http.Cookie{{Name: cookieName, Value: planted, Path: "/"}})
+	do("POST", "/api/login?mode=session", `{"username":"rishabh","password":"gopher123"}`)
+	_, stillValid := store.Get(planted)
+	fmt.Println("  new ID differs:", cookie() != planted, "| planted ID still valid:", stillValid)
+
+	fmt.Println("Idle expiry (30 min, reset by every request):")
+	clock.Advance(20 * time.Minute)
+	do("GET", "/api/me", "") // pushes the expiry to 09:50
+	clock.Advance(20 * time.Minute)
+	do("GET", "/api/me", "")
+	clock.Advance(31 * time.Minute)
+	do("GET", "/api/me", "")
+
+	fmt.Println("Sweeper:")
+	for _, u := range []string{"a", "b", "c"} {
+		store.Create(u, "")
+	}
+	fmt.Println("  sessions now:", store.Len())
+	clock.Advance(time.Hour)
+	time.Sleep(50 * time.Millisecond) // let the sweeper run
+	fmt.Println("  after an hour:", store.Len())
+
+	fmt.Println("Logout:")
+	do("POST", "/api/login?mode=session", `{"username":"alice","password":"wonderland"}`)
+	stolen := cookie()
+	do("POST", "/api/logout", "")
+	jar.SetCookies(srvURL, []*http.Cookie{{Name: cookieName, Value: stolen, Path: "/"}})
+	fmt.Println("  replaying the old cookie after logout:")
+	do("GET", "/api/me", "")
+
+	// many goroutines using the store at once: go run -race finds nothing
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := store.Create(fmt.Sprint("user", i), "")
+			store.Get(s.ID)
+			store.Delete(s.ID)
+		}()
+	}
+	wg.Wait()
+	fmt.Println("After 50 concurrent login/logout:", store.Len(), "sessions")
+}
+
+// The cookie holds only a random ID (crypto/rand), everything else stays on the server.
+// Secure + HttpOnly + SameSite: HTTPS only, hidden from JavaScript, not sent by cross-site forms.
+// Give a NEW ID on login and delete the old one, or a planted ID becomes a logged-in session.
+// Logout deletes the server-side session, so a copied cookie stops working at once (a JWT can't do that).
+// Every access to the session map goes through one mutex, expiry is checked on read and swept in the background.
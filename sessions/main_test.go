@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	t      *testing.T
+	srv    *httptest.Server
+	url    *url.URL
+	client *http.Client
+	jar    *cookiejar.Jar
+	store  *SessionStore
+	clock  *fakeClock
+}
+
+func newTestServer(t *testing.T) *testServer {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)}
+	store := NewSessionStore(30 * time.Minute)
+	store.Now = clock.Now
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/login", handleLogin(store))
+	mux.HandleFunc("POST /api/logout", handleLogout(store))
+	mux.Handle("GET /api/me", sessionMiddleware(store, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, UserID(r.Context()))
+	})))
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	jar, _ := cookiejar.New(nil)
+	client := srv.Client()
+	client.Jar = jar
+	return &testServer{t: t, srv: srv, url: u, client: client, jar: jar, store: store, clock: clock}
+}
+
+func (ts *testServer) do(method, path, body string) (int, string) {
+	ts.t.Helper()
+	req, _ := http.NewRequest(method, ts.srv.URL+path, strings.NewReader(body))
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	text, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, strings.TrimSpace(string(text))
+}
+
+func (ts *testServer) login(user, password string) int {
+	ts.t.Helper()
+	code, _ := ts.do("POST", "/api/login?mode=session", fmt.Sprintf(`{"username":%q,"password":%q}`, user, password))
+	return code
+}
+
+func (ts *testServer) cookie() string {
+	for _, c := range ts.jar.Cookies(ts.url) {
+		if c.Name == cookieName {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+func (ts *testServer) setCookie(value string) {
+	ts.jar.SetCookies(ts.url, []*http.Cookie{{Name: cookieName, Value: value, Path: "/"}})
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+	if code, _ := ts.do("GET", "/api/me", ""); code != http.StatusUnauthorized {
+		t.Errorf("before login: %d", code)
+	}
+	tests := []struct {
+		user, password string
+		want           int
+	}{
+		{"rishabh", "wrong", http.StatusUnauthorized},
+		{"nobody", "", http.StatusUnauthorized},
+		{"", "", http.StatusUnauthorized},
+		{"rishabh", "gopher123", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if code := ts.login(tt.user, tt.password); code != tt.want {
+			t.Errorf("login %q/%q: %d, want %d", tt.user, tt.password, code, tt.want)
+		}
+	}
+	if code, body := ts.do("GET", "/api/me", ""); code != http.StatusOK || body != "rishabh" {
+		t.Errorf("after login: %d %q", code, body)
+	}
+	if code, _ := ts.do("POST", "/api/login", `{"username":"rishabh","password":"gopher123"}`); code != http.StatusBadRequest {
+		t.Errorf("without mode=session: %d", code)
+	}
+	if code, _ := ts.do("POST", "/api/login?mode=session", `{oops`); code != http.StatusBadRequest {
+		t.Errorf("bad body: %d", code)
+	}
+}
+
+func TestCookieAttributes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	store := NewSessionStore(time.Minute)
+	handleLogin(store)(rec, httptest.NewRequest("POST", "/api/login?mode=session", strings.NewReader(`{"username":"alice","password":"wonderland"}`)))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("cookies: %v", cookies)
+	}
+	c := cookies[0]
+	if c.Name != cookieName || !c.Secure || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode || c.Path != "/" || len(c.Value) != 26 {
+		t.Errorf("cookie %+v", c)
+	}
+	if sess, ok := store.Get(c.Value); !ok || sess.UserID != "alice" {
+		t.Errorf("the cookie's session: %+v %v", sess, ok)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login("rishabh", "gopher123")
+	first := ts.cookie()
+	ts.login("rishabh", "gopher123")
+	second := ts.cookie()
+	if first == "" || first == second {
+		t.Errorf("login again kept the ID %q", first)
+	}
+	if _, ok := ts.store.Get(first); ok || ts.store.Len() != 1 {
+		t.Errorf("the old session survived the new login, %d sessions", ts.store.Len())
+	}
+
+	// fixation: the attacker knows the planted ID, it must not become the victim's session
+	planted := ts.store.Create("nobody", "").ID
+	ts.setCookie(planted)
+	ts.login("alice", "wonderland")
+	if ts.cookie() == planted {
+		t.Error("login kept the planted ID")
+	}
+	if _, ok := ts.store.Get(planted); ok {
+		t.Error("the planted session is still valid")
+	}
+	if _, body := ts.do("GET", "/api/me", ""); body != "alice" {
+		t.Errorf("me: %q", body)
+	}
+}
+
+func TestIdleExpiry(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login("rishabh", "gopher123")
+	for range 4 { // every request pushes the expiry back
+		ts.clock.Advance(29 * time.Minute)
+		if code, _ := ts.do("GET", "/api/me", ""); code != http.StatusOK {
+			t.Fatalf("active session expired: %d", code)
+		}
+	}
+	ts.clock.Advance(30 * time.Minute) // exactly the timeout: expired
+	if code, body := ts.do("GET", "/api/me", ""); code != http.StatusUnauthorized || !strings.Contains(body, "expired") {
+		t.Errorf("idle session: %d %q", code, body)
+	}
+	if ts.store.Len() != 0 {
+		t.Error("Get didn't remove the expired session")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login("alice", "wonderland")
+	stolen := ts.cookie()
+	if code, _ := ts.do("POST", "/api/logout", ""); code != http.StatusOK {
+		t.Fatalf("logout: %d", code)
+	}
+	if ts.cookie() != "" {
+		t.Error("the jar kept the cookie after logout")
+	}
+	ts.setCookie(stolen)
+	if code, _ := ts.do("GET", "/api/me", ""); code != http.StatusUnauthorized {
+		t.Errorf("replayed cookie: %d", code)
+	}
+	if code, _ := ts.do("POST", "/api/logout", ""); code != http.StatusOK {
+		t.Errorf("logout twice: %d", code)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewSessionStore(10 * time.Minute)
+	store.Now = clock.Now
+	old := store.Create("old", "")
+	clock.Advance(6 * time.Minute)
+	young := store.Create("young", "")
+	clock.Advance(5 * time.Minute)
+	if n := store.Sweep(); n != 1 || store.Len() != 1 {
+		t.Errorf("swept %d, %d left", n, store.Len())
+	}
+	if _, ok := store.Get(old.ID); ok {
+		t.Error("old session survived")
+	}
+	if _, ok := store.Get(young.ID); !ok {
+		t.Error("young session was swept")
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	store := NewSessionStore(time.Minute)
+	store.Now = clock.Now
+	for i := range 5 {
+		store.Create(fmt.Sprint("u", i), "")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.StartSweeper(ctx, time.Millisecond)
+	clock.Advance(time.Hour)
+	for deadline := time.Now().Add(5 * time.Second); store.Len() != 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d sessions left after the sweeper ran", store.Len())
+		}
+	}
+}
+
+func TestConcurrentSessions(t *testing.T) {
+	store := NewSessionStore(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.StartSweeper(ctx, time.Millisecond)
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Go(func() {
+			for range 20 {
+				s := store.Create(fmt.Sprint("user", i), "")
+				if got, ok := store.Get(s.ID); !ok || got.UserID != fmt.Sprint("user", i) {
+					t.Errorf("Get: %+v %v", got, ok)
+				}
+				store.Delete(s.ID)
+			}
+		})
+	}
+	wg.Wait()
+	if store.Len() != 0 {
+		t.Errorf("%d sessions left", store.Len())
+	}
+}
+
+func TestSessionIDs(t *testing.T) {
+	store := NewSessionStore(time.Minute)
+	seen := map[string]bool{}
+	for range 1000 {
+		id := store.Create("u", "").ID
+		if seen[id] {
+			t.Fatalf("duplicate ID %s", id)
+		}
+		seen[id] = true
+	}
+}
@@ -1,6 +1,12 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"time"
+)
 
 func main() {
 	fmt.Println("Learning slices in go")
@@ -35,4 +41,255 @@ func main() {
 	fmt.Println("Length of Slice from Array:", len(sliceFromArray))
 	fmt.Println("Capacity of Slice from Array:", cap(sliceFromArray)) // capacity is from index 1 to end of array
 
+	SliceInternalsExamples()
+	SlicePerformanceExamples()
+}
+
+// ErrIndexOutOfRange is returned by the helpers instead of panicking
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// InsertAt inserts value at index i keeping the order of the other elements.
+// i == len(s) appends at the end.
+func InsertAt[T any](s []T, i int, value T) ([]T, error) {
+	if i < 0 || i > len(s) {
+		return s, fmt.Errorf("insert at %d (len %d): %w", i, len(s), ErrIndexOutOfRange)
+	}
+	var zero T
+	s = append(s, zero)  // grow by one
+	copy(s[i+1:], s[i:]) // shift the tail right (copy handles the overlap)
+	s[i] = value
+	return s, nil
+}
+
+// RemoveAt removes the element at index i keeping the order of the other elements.
+// The freed last slot is set to the zero value, so a slice of pointers does not
+// keep the removed object alive through the underlying array (memory leak).
+func RemoveAt[T any](s []T, i int) ([]T, error) {
+	if i < 0 || i >= len(s) {
+		return s, fmt.Errorf("remove at %d (len %d): %w", i, len(s), ErrIndexOutOfRange)
+	}
+	copy(s[i:], s[i+1:]) // shift the tail left
+	var zero T
+	s[len(s)-1] = zero
+	return s[:len(s)-1], nil
+}
+
+// Dedup returns a new slice with duplicates removed, keeping the first occurrence
+func Dedup[T comparable](s []T) []T {
+	seen := make(map[T]struct{}, len(s))
+	result := make([]T, 0, len(s))
+	for _, v := range s {
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result
+}
+
+func SliceInternalsExamples() {
+	fmt.Println("\nLearning slice internals")
+
+	// A slice is a small header: pointer to an array + len + cap.
+	// When append runs out of capacity it allocates a bigger array and copies.
+	var growing []int
+	lastCap := cap(growing)
+	for i := 0; i < 2000; i++ {
+		growing = append(growing, i)
+		if cap(growing) != lastCap {
+			fmt.Printf("len %4d: cap grew %4d -> %4d\n", len(growing), lastCap, cap(growing))
+			lastCap = cap(growing)
+		}
+	}
+
+	// Aliasing: a sub-slice shares the parent's array.
+	// Appending to the sub-slice while it still has capacity OVERWRITES the parent.
+	parent := []int{1, 2, 3, 4, 5}
+	child := parent[1:3] // [2 3], len 2, cap 4
+	child = append(child, 99)
+	fmt.Println("parent after append to child:", parent, "child:", child) // parent[3] is now 99
+
+	// Full slice expression s[low:high:max] limits the capacity,
+	// so append is forced to allocate a new array and the parent is safe
+	parent = []int{1, 2, 3, 4, 5}
+	safeChild := parent[1:3:3] // len 2, cap 2
+	safeChild = append(safeChild, 99)
+	fmt.Println("parent with s[1:3:3]:", parent, "child:", safeChild)
+
+	// copy(dst, src) copies min(len(dst), len(src)) elements and handles overlap correctly
+	nums := []int{1, 2, 3, 4, 5}
+	n := copy(nums[1:], nums) // shift right by one inside the same array
+	fmt.Println("copied", n, "overlapping elements:", nums)
+	dst := make([]int, 2)
+	n = copy(dst, []int{7, 8, 9})
+	fmt.Println("copy into shorter dst copied", n, "elements:", dst)
+
+	// Filter in place: reuse the same array, no new allocation
+	values := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	evens := values[:0]
+	for _, v := range values {
+		if v%2 == 0 {
+			evens = append(evens, v)
+		}
+	}
+	fmt.Println("filtered in place:", evens)
+
+	// Helpers
+	list := []string{"a", "b", "d"}
+	list, err := InsertAt(list, 2, "c")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("InsertAt:", list)
+	list, err = RemoveAt(list, 0)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("RemoveAt:", list)
+	if _, err := RemoveAt(list, 10); err != nil {
+		fmt.Println("Error:", err)
+	}
+	if _, err := InsertAt(list, -1, "z"); err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println("Dedup:", Dedup([]int{3, 1, 3, 2, 1, 4}))
+}
+
+// Realloc is one time append had to move the elements to a bigger array
+type Realloc struct {
+	Len            int // length after the append
+	OldCap, NewCap int
+	Copied         int // elements moved to the new array
+}
+
+// GrowthTracker finds reallocations by watching the address of the first element:
+// as long as append has room, &s[0] stays the same. Call Observe after every append.
+type GrowthTracker[T any] struct {
+	first    *T
+	len, cap int
+	Reallocs []Realloc
+}
+
+func (g *GrowthTracker[T]) Observe(s []T) {
+	if len(s) == 0 {
+		return
+	}
+	if g.first != nil && &s[0] != g.first {
+		g.Reallocs = append(g.Reallocs, Realloc{Len: len(s), OldCap: g.cap, NewCap: cap(s), Copied: g.len})
+	}
+	g.first, g.len, g.cap = &s[0], len(s), cap(s)
+}
+
+// Copied is the total number of elements moved by all reallocations
+func (g *GrowthTracker[T]) Copied() int {
+	total := 0
+	for _, r := range g.Reallocs {
+		total += r.Copied
+	}
+	return total
+}
+
+// heapMiB runs the GC first, so only memory that is still reachable is counted
+func heapMiB() float64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return float64(m.HeapAlloc) / (1 << 20)
+}
+
+func SlicePerformanceExamples() {
+	fmt.Println("\nLearning slice growth and its cost")
+
+	const n = 10_000
+	var fromZero GrowthTracker[int]
+	var s []int
+	for i := range n {
+		s = append(s, i)
+		fromZero.Observe(s)
+	}
+	// +1: the very first append allocates too, there was no array to compare it with
+	fmt.Printf("append %d ints into a nil slice: %d reallocations, %d elements copied\n", n, len(fromZero.Reallocs)+1, fromZero.Copied())
+	for _, r := range fromZero.Reallocs[:4] {
+		fmt.Printf("  at len %d: cap %d -> %d, copied %d\n", r.Len, r.OldCap, r.NewCap, r.Copied)
+	}
+	last := fromZero.Reallocs[len(fromZero.Reallocs)-1]
+	fmt.Printf("  ...\n  at len %d: cap %d -> %d, copied %d\n", last.Len, last.OldCap, last.NewCap, last.Copied)
+
+	var prealloc GrowthTracker[int]
+	s = make([]int, 0, n)
+	for i := range n {
+		s = append(s, i)
+		prealloc.Observe(s)
+	}
+	fmt.Printf("append %d ints into make(0, %d): %d reallocations\n", n, n, len(prealloc.Reallocs))
+
+	// append grows the capacity by a factor, so the copies add up to about n.
+	// Growing by one element each time copies 1+2+3+...+n: about n*n/2, quadratic.
+	var byOne GrowthTracker[int]
+	s = nil
+	for i := range 2000 {
+		grown := make([]int, len(s)+1)
+		copy(grown, s)
+		grown[i] = i
+		s = grown
+		byOne.Observe(s)
+	}
+	fmt.Printf("grow by exactly one, 2000 ints: %d elements copied (append copied %d for %d ints)\n", byOne.Copied(), fromZero.Copied(), n)
+
+	// rough numbers from a plain loop, go test main.go main_test.go -bench . -benchmem measures properly
+	bench := func(name string, fn func() []int) {
+		const runs = 1000
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for range runs {
+			fn()
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		fmt.Printf("  %-22s %8d ns/op %8d B/op %3d allocs/op\n", name,
+			elapsed.Nanoseconds()/runs, (after.TotalAlloc-before.TotalAlloc)/runs, (after.Mallocs-before.Mallocs)/runs)
+	}
+	fmt.Printf("Building %d ints:\n", n)
+	bench("append from nil", func() []int {
+		var s []int
+		for i := range n {
+			s = append(s, i)
+		}
+		return s
+	})
+	bench("make with exact cap", func() []int {
+		s := make([]int, 0, n)
+		for i := range n {
+			s = append(s, i)
+		}
+		return s
+	})
+	bench("make with 4x cap", func() []int { // fast, but 3/4 of the memory is never used
+		s := make([]int, 0, 4*n)
+		for i := range n {
+			s = append(s, i)
+		}
+		return s
+	})
+
+	// Memory pinning: a small sub-slice keeps the WHOLE array alive,
+	// the GC can't free part of an array
+	base := heapMiB()
+	big := make([]byte, 64<<20)
+	header := big[:16]
+	big = nil
+	fmt.Printf("16-byte sub-slice of a 64 MiB slice keeps %.0f MiB alive\n", heapMiB()-base)
+
+	clipped := slices.Clip(header) // only lowers cap, it's still the same array
+	header = nil
+	fmt.Printf("  after slices.Clip: %.0f MiB (Clip stops appends from writing into the array, it doesn't free it)\n", heapMiB()-base)
+
+	small := slices.Clone(clipped) // or: small := make([]byte, len(clipped)); copy(small, clipped)
+	clipped = nil
+	fmt.Printf("  after slices.Clone: %.0f MiB\n", heapMiB()-base)
+	runtime.KeepAlive(small)
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestInsertAt(t *testing.T) {
+	tests := []struct {
+		in    []int
+		i     int
+		value int
+		want  []int
+	}{
+		{nil, 0, 1, []int{1}},
+		{[]int{1, 2, 3}, 0, 0, []int{0, 1, 2, 3}},
+		{[]int{1, 2, 3}, 1, 9, []int{1, 9, 2, 3}},
+		{[]int{1, 2, 3}, 3, 4, []int{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		got, err := InsertAt(slices.Clone(tt.in), tt.i, tt.value)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("InsertAt(%v, %d, %d) = %v, %v; want %v", tt.in, tt.i, tt.value, got, err, tt.want)
+		}
+	}
+	for _, i := range []int{-1, 4, 100} {
+		s := []int{1, 2, 3}
+		got, err := InsertAt(s, i, 0)
+		if !errors.Is(err, ErrIndexOutOfRange) || !slices.Equal(got, s) {
+			t.Errorf("InsertAt at %d = %v, %v; want the slice unchanged and ErrIndexOutOfRange", i, got, err)
+		}
+	}
+}
+
+func TestRemoveAt(t *testing.T) {
+	tests := []struct {
+		in   []int
+		i    int
+		want []int
+	}{
+		{[]int{1}, 0, []int{}},
+		{[]int{1, 2, 3}, 0, []int{2, 3}},
+		{[]int{1, 2, 3}, 1, []int{1, 3}},
+		{[]int{1, 2, 3}, 2, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		got, err := RemoveAt(slices.Clone(tt.in), tt.i)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("RemoveAt(%v, %d) = %v, %v; want %v", tt.in, tt.i, got, err, tt.want)
+		}
+	}
+	for _, tt := range []struct {
+		in []int
+		i  int
+	}{{nil, 0}, {[]int{1, 2, 3}, -1}, {[]int{1, 2, 3}, 3}} {
+		got, err := RemoveAt(tt.in, tt.i)
+		if !errors.Is(err, ErrIndexOutOfRange) || !slices.Equal(got, tt.in) {
+			t.Errorf("RemoveAt(%v, %d) = %v, %v; want the slice unchanged and ErrIndexOutOfRange", tt.in, tt.i, got, err)
+		}
+	}
+}
+
+func TestRemoveAtClearsFreedSlot(t *testing.T) {
+	a, b, c := new(int), new(int), new(int)
+	s := []*int{a, b, c}
+	got, err := RemoveAt(s, 0)
+	if err != nil || len(got) != 2 || got[0] != b || got[1] != c {
+		t.Fatalf("RemoveAt = %v, %v", got, err)
+	}
+	// the old last slot is still in the backing array, it must not pin c
+	if s[2] != nil {
+		t.Fatal("the freed slot still holds a pointer, the GC can't collect it")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	tests := []struct{ in, want []string }{
+		{nil, []string{}},
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"go", "rust", "go", "zig", "rust", "go"}, []string{"go", "rust", "zig"}},
+		{[]string{"b", "a", "b", "a"}, []string{"b", "a"}},
+	}
+	for _, tt := range tests {
+		in := slices.Clone(tt.in)
+		if got := Dedup(in); !slices.Equal(got, tt.want) {
+			t.Errorf("Dedup(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+		if !slices.Equal(in, tt.in) {
+			t.Errorf("Dedup(%v) modified its input", tt.in)
+		}
+	}
+}
+
+// capChanges counts reallocations the plain way, by watching cap, to check the tracker against
+func capChanges(n int) (reallocs, copied int) {
+	var s []int
+	for i := range n {
+		before := cap(s)
+		s = append(s, i)
+		if before != 0 && cap(s) != before {
+			reallocs++
+			copied += i
+		}
+	}
+	return reallocs, copied
+}
+
+func TestGrowthTrackerAppend(t *testing.T) {
+	for _, n := range []int{1, 2, 10, 100, 1000, 10_000} {
+		var g GrowthTracker[int]
+		var s []int
+		for i := range n {
+			s = append(s, i)
+			g.Observe(s)
+		}
+		wantReallocs, wantCopied := capChanges(n)
+		if len(g.Reallocs) != wantReallocs || g.Copied() != wantCopied {
+			t.Errorf("n=%d: %d reallocs copying %d, want %d copying %d", n, len(g.Reallocs), g.Copied(), wantReallocs, wantCopied)
+		}
+		for _, r := range g.Reallocs {
+			// append only moves a full array: everything in it is copied
+			if r.Copied != r.OldCap || r.Len != r.OldCap+1 || r.NewCap <= r.OldCap {
+				t.Errorf("n=%d: %+v", n, r)
+			}
+		}
+	}
+}
+
+func TestGrowthTrackerKnownSizes(t *testing.T) {
+	// small int slices double: 1 -> 2 -> 4 -> 8 -> 16
+	var g GrowthTracker[int]
+	var s []int
+	for i := range 10 {
+		s = append(s, i)
+		g.Observe(s)
+	}
+	want := []Realloc{{2, 1, 2, 1}, {3, 2, 4, 2}, {5, 4, 8, 4}, {9, 8, 16, 8}}
+	if !slices.Equal(g.Reallocs, want) || g.Copied() != 15 {
+		t.Errorf("got %v, want %v", g.Reallocs, want)
+	}
+
+	var pre GrowthTracker[int]
+	s = make([]int, 0, 500)
+	for i := range 500 {
+		s = append(s, i)
+		pre.Observe(s)
+	}
+	if len(pre.Reallocs) != 0 {
+		t.Errorf("preallocated: %v", pre.Reallocs)
+	}
+
+	// a new array on every step: n-1 moves, 1+2+...+(n-1) elements copied
+	const n = 300
+	var byOne GrowthTracker[int]
+	s = nil
+	for i := range n {
+		grown := make([]int, len(s)+1)
+		copy(grown, s)
+		grown[i] = i
+		s = grown
+		byOne.Observe(s)
+	}
+	if len(byOne.Reallocs) != n-1 || byOne.Copied() != n*(n-1)/2 {
+		t.Errorf("grow by one: %d reallocs copying %d", len(byOne.Reallocs), byOne.Copied())
+	}
+}
+
+func TestGrowthTrackerEmpty(t *testing.T) {
+	var g GrowthTracker[string]
+	g.Observe(nil)
+	g.Observe([]string{})
+	g.Observe(make([]string, 0, 8))
+	if len(g.Reallocs) != 0 || g.first != nil {
+		t.Errorf("empty slices were observed: %+v", g)
+	}
+}
+
+func heapBytes() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+func TestMemoryPinning(t *testing.T) {
+	const size = 64 << 20
+	base := heapBytes()
+	header := make([]byte, size)[:16]
+	if pinned := int64(heapBytes()) - int64(base); pinned < size*3/4 {
+		t.Errorf("the sub-slice pins only %d bytes", pinned)
+	}
+	small := slices.Clone(header)
+	header = nil
+	if left := int64(heapBytes()) - int64(base); left > size/4 {
+		t.Errorf("after Clone %d bytes are still alive", left)
+	}
+	runtime.KeepAlive(small)
+	runtime.KeepAlive(header)
+}
+
+// go test main.go main_test.go -bench . -benchmem
+func BenchmarkAppend(b *testing.B) {
+	const n = 10_000
+	for _, bm := range []struct {
+		name string
+		cap  int
+	}{{"from_nil", 0}, {"exact_cap", n}, {"4x_cap", 4 * n}} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var s []int
+				if bm.cap > 0 {
+					s = make([]int, 0, bm.cap)
+				}
+				for i := range n {
+					s = append(s, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type User struct {
+	ID    int
+	Name  string
+	Email string
+	Age   int
+}
+
+// userComparators maps a sort key to a comparison function.
+// Each function returns <0, 0 or >0 like strings.Compare.
+var userComparators = map[string]func(a, b User) int{
+	"id":    func(a, b User) int { return cmp.Compare(a.ID, b.ID) },
+	"name":  func(a, b User) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) },
+	"email": func(a, b User) int { return strings.Compare(a.Email, b.Email) },
+	"age":   func(a, b User) int { return cmp.Compare(a.Age, b.Age) },
+}
+
+// SortUsersBy sorts users in place by one or more keys, e.g. SortUsersBy(users, "age", "-name").
+// A leading "-" reverses that key. The sort is stable, so users equal on every key keep their order.
+func SortUsersBy(users []User, keys ...string) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("sort users: at least one key is required")
+	}
+
+	comparators := make([]func(a, b User) int, 0, len(keys))
+	for _, key := range keys {
+		descending := strings.HasPrefix(key, "-")
+		name := strings.TrimPrefix(key, "-")
+		compare, ok := userComparators[name]
+		if !ok {
+			return fmt.Errorf("sort users: unknown key %q (allowed: id, name, email, age)", name)
+		}
+		if descending {
+			asc := compare
+			compare = func(a, b User) int { return -asc(a, b) }
+		}
+		comparators = append(comparators, compare)
+	}
+
+	slices.SortStableFunc(users, func(a, b User) int {
+		for _, compare := range comparators {
+			if c := compare(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	return nil
+}
+
+func printUsers(title string, users []User) {
+	fmt.Println(title)
+	for _, u := range users {
+		fmt.Printf("  %d %-8s %-20s %d\n", u.ID, u.Name, u.Email, u.Age)
+	}
+}
+
+func main() {
+	fmt.Println("Learning sorting in Go")
+
+	users := []User{
+		{ID: 1, Name: "Rishabh", Email: "rishabh@example.com", Age: 23},
+		{ID: 2, Name: "alice", Email: "alice@example.com", Age: 30},
+		{ID: 3, Name: "Bob", Email: "bob@example.com", Age: 23},
+		{ID: 4, Name: "Sanchay", Email: "sanchay@example.com", Age: 22},
+		{ID: 5, Name: "Carol", Email: "carol@example.com", Age: 30},
+	}
+
+	// Sorting basic slices
+	nums := []int{5, 2, 8, 1, 9}
+	sort.Ints(nums)
+	fmt.Println("sort.Ints:", nums)
+	words := []string{"go", "rust", "c", "python"}
+	slices.Sort(words) // generic version (Go 1.21+)
+	fmt.Println("slices.Sort:", words)
+
+	// sort.Slice takes a "less" function: return true if i should come before j
+	byName := slices.Clone(users)
+	sort.Slice(byName, func(i, j int) bool {
+		return strings.ToLower(byName[i].Name) < strings.ToLower(byName[j].Name)
+	})
+	printUsers("sort.Slice by name:", byName)
+
+	// sort.Slice is NOT stable: equal elements may be reordered.
+	// sort.SliceStable keeps equal elements in their original order (here: by ID)
+	byAge := slices.Clone(users)
+	sort.SliceStable(byAge, func(i, j int) bool { return byAge[i].Age < byAge[j].Age })
+	printUsers("sort.SliceStable by age (ties keep ID order):", byAge)
+
+	// Reverse order: just flip the comparison
+	reversed := slices.Clone(users)
+	sort.Slice(reversed, func(i, j int) bool { return reversed[i].Age > reversed[j].Age })
+	printUsers("Age descending:", reversed)
+
+	// slices.SortFunc uses a cmp function returning <0, 0, >0 instead of a bool
+	// and works on the elements directly instead of indexes
+	multi := slices.Clone(users)
+	slices.SortFunc(multi, func(a, b User) int {
+		if c := cmp.Compare(a.Age, b.Age); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	printUsers("slices.SortFunc by age then name (case-sensitive, so \"Carol\" < \"alice\"):", multi)
+
+	// The reusable helper does all of the above from string keys
+	shared := slices.Clone(users)
+	if err := SortUsersBy(shared, "-age", "name"); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printUsers("SortUsersBy(-age, name):", shared)
+	if err := SortUsersBy(shared, "salary"); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	// Binary search only works on a sorted slice: O(log n) instead of O(n)
+	sortedAges := []int{18, 22, 23, 30, 41, 55}
+	target := 30
+	i := sort.SearchInts(sortedAges, target)
+	fmt.Printf("sort.SearchInts found %d at index %d\n", target, i)
+
+	// sort.Search returns the first index where the function becomes true
+	i = sort.Search(len(sortedAges), func(i int) bool { return sortedAges[i] >= 25 })
+	fmt.Println("First age >= 25:", sortedAges[i])
+
+	// generic version also tells you whether the value was found
+	idx, found := slices.BinarySearch(sortedAges, 40)
+	fmt.Println("BinarySearch 40 -> index:", idx, "found:", found, "(index is where it would be inserted)")
+}
+
+// sort.Slice(s, less)       -> less(i, j int) bool, not stable
+// sort.SliceStable(s, less) -> keeps original order of equal elements
+// slices.SortFunc(s, cmp)   -> cmp(a, b T) int, generic, not stable
+// slices.SortStableFunc     -> generic and stable
+// Sorting multiple keys = compare the first key, only if equal compare the next one
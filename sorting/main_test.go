@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func testUsers() []User {
+	return []User{
+		{1, "carol", "carol@example.com", 30},
+		{2, "Alice", "alice@example.com", 25},
+		{3, "bob", "bob@example.com", 30},
+		{4, "alice", "alice2@example.com", 35},
+		{5, "dave", "dave@example.com", 25},
+	}
+}
+
+func ids(users []User) []int {
+	out := make([]int, len(users))
+	for i, u := range users {
+		out[i] = u.ID
+	}
+	return out
+}
+
+func TestSortUsersBy(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want []int
+	}{
+		{[]string{"id"}, []int{1, 2, 3, 4, 5}},
+		{[]string{"-id"}, []int{5, 4, 3, 2, 1}},
+		// case-insensitive, Alice and alice tie and keep their input order
+		{[]string{"name"}, []int{2, 4, 3, 1, 5}},
+		{[]string{"-name"}, []int{5, 1, 3, 2, 4}},
+		{[]string{"email"}, []int{4, 2, 3, 1, 5}},
+		// stable: equal ages keep the input order
+		{[]string{"age"}, []int{2, 5, 1, 3, 4}},
+		{[]string{"-age"}, []int{4, 1, 3, 2, 5}},
+		{[]string{"age", "name"}, []int{2, 5, 3, 1, 4}},
+		{[]string{"age", "-name"}, []int{5, 2, 1, 3, 4}},
+		{[]string{"-age", "-id"}, []int{4, 3, 1, 5, 2}},
+		{[]string{"name", "-age"}, []int{4, 2, 3, 1, 5}},
+	}
+	for _, tt := range tests {
+		users := testUsers()
+		if err := SortUsersBy(users, tt.keys...); err != nil {
+			t.Fatalf("SortUsersBy(%v): %v", tt.keys, err)
+		}
+		if got := ids(users); !slices.Equal(got, tt.want) {
+			t.Errorf("SortUsersBy(%v) = %v, want %v", tt.keys, got, tt.want)
+		}
+	}
+}
+
+func TestSortUsersByStable(t *testing.T) {
+	// everyone has the same age, so the order must not change at all
+	users := make([]User, 50)
+	for i := range users {
+		users[i] = User{ID: i, Name: "same", Age: 40}
+	}
+	if err := SortUsersBy(users, "age", "name"); err != nil {
+		t.Fatal(err)
+	}
+	for i, u := range users {
+		if u.ID != i {
+			t.Fatalf("position %d holds user %d, the stable sort moved equal elements", i, u.ID)
+		}
+	}
+}
+
+func TestSortUsersByErrors(t *testing.T) {
+	for _, keys := range [][]string{nil, {"height"}, {"age", "-"}, {"--age"}} {
+		users := testUsers()
+		if err := SortUsersBy(users, keys...); err == nil {
+			t.Errorf("SortUsersBy(%q) succeeded, want an error", keys)
+		}
+		if !slices.Equal(ids(users), []int{1, 2, 3, 4, 5}) {
+			t.Errorf("SortUsersBy(%q) failed but still reordered the users", keys)
+		}
+	}
+}
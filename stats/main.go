@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+)
+
+// ErrEmpty is returned for statistics that have no meaning without data.
+// Returning an error is clearer than silently returning NaN (0/0).
+var ErrEmpty = errors.New("no values")
+
+func Sum(xs []float64) float64 {
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total // the sum of nothing is 0, no error needed
+}
+
+func Mean(xs []float64) (float64, error) {
+	if len(xs) == 0 {
+		return 0, fmt.Errorf("mean: %w", ErrEmpty)
+	}
+	return Sum(xs) / float64(len(xs)), nil
+}
+
+// sortedCopy sorts a copy, so the caller's slice keeps its order
+func sortedCopy(xs []float64) []float64 {
+	s := make([]float64, len(xs))
+	copy(s, xs)
+	sort.Float64s(s)
+	return s
+}
+
+// Median is the middle value, or the mean of the two middle values for an even count
+func Median(xs []float64) (float64, error) {
+	if len(xs) == 0 {
+		return 0, fmt.Errorf("median: %w", ErrEmpty)
+	}
+	s := sortedCopy(xs)
+	mid := len(s) / 2
+	if len(s)%2 == 1 {
+		return s[mid], nil
+	}
+	return (s[mid-1] + s[mid]) / 2, nil
+}
+
+// Mode returns the most frequent values in ascending order (there can be several)
+func Mode(xs []float64) ([]float64, error) {
+	if len(xs) == 0 {
+		return nil, fmt.Errorf("mode: %w", ErrEmpty)
+	}
+	counts := make(map[float64]int)
+	best := 0
+	for _, x := range xs {
+		counts[x]++
+		if counts[x] > best {
+			best = counts[x]
+		}
+	}
+	var modes []float64
+	for x, n := range counts {
+		if n == best {
+			modes = append(modes, x)
+		}
+	}
+	sort.Float64s(modes)
+	return modes, nil
+}
+
+// StdDev is the population standard deviation: sqrt(mean((x - mean)^2)).
+// For a sample of a bigger population divide by n-1 instead (see StreamingStats.SampleVariance).
+func StdDev(xs []float64) (float64, error) {
+	mean, err := Mean(xs)
+	if err != nil {
+		return 0, fmt.Errorf("stddev: %w", ErrEmpty)
+	}
+	sq := 0.0
+	for _, x := range xs {
+		sq += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(sq / float64(len(xs))), nil
+}
+
+// Percentile returns the p-th percentile (0-100) with linear interpolation between
+// the closest ranks, the same method as Excel's PERCENTILE.INC and NumPy's default:
+// the position is p/100 * (n-1) in the sorted values, and a fractional position
+// mixes its two neighbours. So the 50th percentile equals the median,
+// 0 is the minimum and 100 the maximum.
+func Percentile(xs []float64, p float64) (float64, error) {
+	if len(xs) == 0 {
+		return 0, fmt.Errorf("percentile: %w", ErrEmpty)
+	}
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, fmt.Errorf("percentile %v: must be between 0 and 100", p)
+	}
+	s := sortedCopy(xs)
+	pos := p / 100 * float64(len(s)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return s[lower] + (s[upper]-s[lower])*frac, nil
+}
+
+// StreamingStats keeps count, mean, variance, min and max without storing the values,
+// so it uses the same tiny memory for 10 or 10 billion samples (e.g. request latencies).
+// Mean and variance use Welford's algorithm, which avoids the precision loss of
+// computing sum(x^2) - sum(x)^2 with big numbers. The zero value is ready to use.
+type StreamingStats struct {
+	count    int
+	mean     float64
+	m2       float64 // sum of squared differences from the current mean
+	min, max float64
+}
+
+func (s *StreamingStats) Add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean) // uses the old AND the new mean
+}
+
+func (s *StreamingStats) Count() int { return s.count }
+
+func (s *StreamingStats) Mean() (float64, error) {
+	if s.count == 0 {
+		return 0, fmt.Errorf("mean: %w", ErrEmpty)
+	}
+	return s.mean, nil
+}
+
+// Variance is the population variance, matching StdDev
+func (s *StreamingStats) Variance() (float64, error) {
+	if s.count == 0 {
+		return 0, fmt.Errorf("variance: %w", ErrEmpty)
+	}
+	return s.m2 / float64(s.count), nil
+}
+
+// SampleVariance divides by n-1 (Bessel's correction) and needs at least 2 values
+func (s *StreamingStats) SampleVariance() (float64, error) {
+	if s.count < 2 {
+		return 0, fmt.Errorf("sample variance of %d values: %w", s.count, ErrEmpty)
+	}
+	return s.m2 / float64(s.count-1), nil
+}
+
+func (s *StreamingStats) StdDev() (float64, error) {
+	v, err := s.Variance()
+	return math.Sqrt(v), err
+}
+
+func (s *StreamingStats) Min() (float64, error) {
+	if s.count == 0 {
+		return 0, fmt.Errorf("min: %w", ErrEmpty)
+	}
+	return s.min, nil
+}
+
+func (s *StreamingStats) Max() (float64, error) {
+	if s.count == 0 {
+		return 0, fmt.Errorf("max: %w", ErrEmpty)
+	}
+	return s.max, nil
+}
+
+// sink keeps the compiler from skipping the timed work
+var sink float64
+
+func main() {
+	fmt.Println("Learning statistics over slices in Go")
+
+	// a dataset with well known answers: mean 5, population stddev exactly 2
+	data := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	mean, _ := Mean(data)
+	median, _ := Median(data)
+	mode, _ := Mode(data)
+	stddev, _ := StdDev(data)
+	fmt.Println("Data:", data)
+	fmt.Println("Sum:", Sum(data), "Mean:", mean, "Median:", median, "Mode:", mode, "StdDev:", stddev)
+
+	for _, p := range []float64{0, 25, 50, 90, 100} {
+		v, _ := Percentile(data, p)
+		fmt.Printf("  P%-3v = %v\n", p, v)
+	}
+	// 1..10: position of P90 is 0.9*9 = 8.1 -> 9 + 0.1*(10-9) = 9.1
+	v, _ := Percentile([]float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5}, 90)
+	fmt.Println("P90 of 1..10 (unsorted input):", v)
+
+	modes, _ := Mode([]float64{1, 1, 2, 2, 3})
+	fmt.Println("Two modes:", modes)
+
+	// empty input is an error, not NaN
+	_, err := Mean(nil)
+	fmt.Println("Mean of nothing:", err, "| is ErrEmpty:", errors.Is(err, ErrEmpty))
+	_, err = Percentile(data, 101)
+	fmt.Println("P101:", err)
+
+	// streaming gives the same answers without keeping the data
+	var s StreamingStats
+	for _, x := range data {
+		s.Add(x)
+	}
+	smean, _ := s.Mean()
+	sstd, _ := s.StdDev()
+	svar, _ := s.SampleVariance()
+	smin, _ := s.Min()
+	smax, _ := s.Max()
+	fmt.Println("Streaming: count", s.Count(), "mean", smean, "stddev", sstd, "sample variance", svar, "min", smin, "max", smax)
+
+	// precision: big numbers with a tiny spread, the naive formula would lose it
+	var big StreamingStats
+	for _, x := range []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16} {
+		big.Add(x)
+	}
+	bigVar, _ := big.Variance()
+	fmt.Println("Variance of 1e9+{4,7,13,16} (exact 22.5):", bigVar)
+
+	// batch vs streaming on 1M samples
+	rng := rand.New(rand.NewPCG(1, 2))
+	samples := make([]float64, 1_000_000)
+	for i := range samples {
+		samples[i] = rng.NormFloat64()*15 + 100 // like latencies around 100ms
+	}
+	start := time.Now()
+	batchMean, _ := Mean(samples)
+	batchStd, _ := StdDev(samples)
+	batchTime := time.Since(start)
+	start = time.Now()
+	var st StreamingStats
+	for _, x := range samples {
+		st.Add(x)
+	}
+	streamMean, _ := st.Mean()
+	streamStd, _ := st.StdDev()
+	streamTime := time.Since(start)
+	sink = batchMean + streamMean
+	fmt.Printf("1M samples batch:     mean %.4f stddev %.4f in %s (needs all samples in memory)\n", batchMean, batchStd, batchTime.Round(time.Microsecond))
+	fmt.Printf("1M samples streaming: mean %.4f stddev %.4f in %s (needs 40 bytes)\n", streamMean, streamStd, streamTime.Round(time.Microsecond))
+}
+
+// Median and percentiles need sorted data: O(n log n) and all values in memory.
+// Mean, variance, min and max can be computed in one pass with O(1) memory (streaming).
+// There are several ways to compute a percentile, always document which one you use.
+// Population stddev divides by n, sample stddev by n-1.
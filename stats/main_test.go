@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+// the textbook dataset: mean 5, population stddev exactly 2
+var classic = []float64{2, 4, 4, 4, 5, 5, 7, 9}
+
+func TestBatchKnownValues(t *testing.T) {
+	if got := Sum(classic); got != 40 {
+		t.Errorf("Sum = %v", got)
+	}
+	if got := Sum(nil); got != 0 {
+		t.Errorf("Sum(nil) = %v", got)
+	}
+	tests := []struct {
+		name string
+		fn   func([]float64) (float64, error)
+		xs   []float64
+		want float64
+	}{
+		{"mean", Mean, classic, 5},
+		{"median even", Median, classic, 4.5},
+		{"median odd", Median, []float64{9, 1, 5}, 5},
+		{"median one", Median, []float64{3}, 3},
+		{"stddev", StdDev, classic, 2},
+		{"stddev constant", StdDev, []float64{7, 7, 7}, 0},
+		{"mean negatives", Mean, []float64{-3, -1, 1, 7}, 1},
+	}
+	for _, tt := range tests {
+		if got, err := tt.fn(tt.xs); err != nil || got != tt.want {
+			t.Errorf("%s(%v) = %v, %v; want %v", tt.name, tt.xs, got, err, tt.want)
+		}
+	}
+}
+
+func TestMode(t *testing.T) {
+	tests := []struct{ xs, want []float64 }{
+		{classic, []float64{4}},
+		{[]float64{3, 1, 1, 2, 2}, []float64{1, 2}},
+		{[]float64{5, 4, 3}, []float64{3, 4, 5}}, // all equally frequent
+	}
+	for _, tt := range tests {
+		if got, err := Mode(tt.xs); err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("Mode(%v) = %v, %v; want %v", tt.xs, got, err, tt.want)
+		}
+	}
+}
+
+// linear interpolation between closest ranks (PERCENTILE.INC, NumPy's default)
+func TestPercentile(t *testing.T) {
+	oneToTen := []float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5}
+	tests := []struct {
+		xs   []float64
+		p    float64
+		want float64
+	}{
+		{classic, 0, 2},
+		{classic, 100, 9},
+		{classic, 50, 4.5}, // equals the median
+		{classic, 25, 4},   // position 1.75 between 4 and 4
+		{oneToTen, 90, 9.1},
+		{oneToTen, 10, 1.9},
+		{oneToTen, 95, 9.55},
+		{[]float64{15, 20, 35, 40, 50}, 40, 29}, // position 1.6: 20 + 0.6*15
+		{[]float64{42}, 73, 42},
+	}
+	for _, tt := range tests {
+		got, err := Percentile(tt.xs, tt.p)
+		if err != nil || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Percentile(%v, %v) = %v, %v; want %v", tt.xs, tt.p, got, err, tt.want)
+		}
+	}
+	for _, p := range []float64{-1, 100.5, math.NaN()} {
+		if _, err := Percentile(classic, p); err == nil {
+			t.Errorf("Percentile(p=%v) gave no error", p)
+		}
+	}
+	// the input order is left alone
+	if oneToTen[0] != 10 {
+		t.Error("Percentile sorted the caller's slice")
+	}
+}
+
+func TestEmptyIsAnError(t *testing.T) {
+	checks := map[string]error{}
+	_, checks["Mean"] = Mean(nil)
+	_, checks["Median"] = Median([]float64{})
+	_, checks["Mode"] = Mode(nil)
+	_, checks["StdDev"] = StdDev(nil)
+	_, checks["Percentile"] = Percentile(nil, 50)
+	var s StreamingStats
+	_, checks["s.Mean"] = s.Mean()
+	_, checks["s.Variance"] = s.Variance()
+	_, checks["s.StdDev"] = s.StdDev()
+	_, checks["s.Min"] = s.Min()
+	_, checks["s.Max"] = s.Max()
+	_, checks["s.SampleVariance"] = s.SampleVariance()
+	s.Add(1)
+	_, checks["s.SampleVariance of one"] = s.SampleVariance()
+	for name, err := range checks {
+		if !errors.Is(err, ErrEmpty) {
+			t.Errorf("%s: %v, want ErrEmpty", name, err)
+		}
+	}
+}
+
+func TestStreamingMatchesBatch(t *testing.T) {
+	var s StreamingStats
+	for _, x := range classic {
+		s.Add(x)
+	}
+	mean, _ := s.Mean()
+	variance, _ := s.Variance()
+	sample, _ := s.SampleVariance()
+	std, _ := s.StdDev()
+	lo, _ := s.Min()
+	hi, _ := s.Max()
+	if s.Count() != 8 || mean != 5 || variance != 4 || sample != 32.0/7 || std != 2 || lo != 2 || hi != 9 {
+		t.Errorf("count %d mean %v var %v sample %v std %v min %v max %v", s.Count(), mean, variance, sample, std, lo, hi)
+	}
+
+	// negative values only: Min and Max must not start from 0
+	var neg StreamingStats
+	for _, x := range []float64{-5, -2, -9} {
+		neg.Add(x)
+	}
+	if lo, _ := neg.Min(); lo != -9 {
+		t.Errorf("Min = %v", lo)
+	}
+	if hi, _ := neg.Max(); hi != -2 {
+		t.Errorf("Max = %v", hi)
+	}
+
+	// big numbers with a small spread: exact with Welford, sum(x^2) would lose it
+	var big StreamingStats
+	for _, x := range []float64{1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16} {
+		big.Add(x)
+	}
+	if v, _ := big.Variance(); v != 22.5 {
+		t.Errorf("Variance = %v, want 22.5", v)
+	}
+
+	rng := rand.New(rand.NewPCG(3, 4))
+	xs := make([]float64, 10_000)
+	var st StreamingStats
+	for i := range xs {
+		xs[i] = rng.NormFloat64()*15 + 100
+		st.Add(xs[i])
+	}
+	bm, _ := Mean(xs)
+	bs, _ := StdDev(xs)
+	sm, _ := st.Mean()
+	ss, _ := st.StdDev()
+	if math.Abs(bm-sm) > 1e-9 || math.Abs(bs-ss) > 1e-9 {
+		t.Errorf("batch %v/%v, streaming %v/%v", bm, bs, sm, ss)
+	}
+}
+
+func millionSamples() []float64 {
+	rng := rand.New(rand.NewPCG(1, 2))
+	xs := make([]float64, 1_000_000)
+	for i := range xs {
+		xs[i] = rng.NormFloat64()*15 + 100
+	}
+	return xs
+}
+
+func BenchmarkBatchMeanStdDev(b *testing.B) {
+	xs := millionSamples()
+	for b.Loop() {
+		m, _ := Mean(xs)
+		s, _ := StdDev(xs)
+		sink = m + s
+	}
+}
+
+func BenchmarkStreamingMeanStdDev(b *testing.B) {
+	xs := millionSamples()
+	for b.Loop() {
+		var st StreamingStats
+		for _, x := range xs {
+			st.Add(x)
+		}
+		m, _ := st.Mean()
+		s, _ := st.StdDev()
+		sink = m + s
+	}
+}
+
+func BenchmarkPercentile(b *testing.B) {
+	xs := millionSamples()
+	for b.Loop() {
+		sink, _ = Percentile(xs, 99)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// titleCase upper-cases the first letter of every word and lower-cases the rest.
+// strings.Title is deprecated (it does not handle Unicode word boundaries well),
+// so for simple ASCII-ish text a small helper like this is enough.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		runes[0] = []rune(strings.ToUpper(string(runes[0])))[0]
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// parseKeyValues parses a query-string-like input such as "name=go&level=2"
+// into a map. Empty pairs are skipped, a pair without "=" is an error.
+func parseKeyValues(input string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(input, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid pair %q: missing '='", pair)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid pair %q: empty key", pair)
+		}
+		result[key] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
+
+func main() {
+	fmt.Println("Learning strings and strconv in Go")
+
+	// Strings are immutable, so every + creates a brand new string.
+	// strings.Builder grows one buffer instead, which is much cheaper in loops.
+	const n = 20000
+	start := time.Now()
+	plus := ""
+	for i := 0; i < n; i++ {
+		plus += "x"
+	}
+	plusTime := time.Since(start)
+
+	start = time.Now()
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString("x")
+	}
+	built := sb.String()
+	builderTime := time.Since(start)
+	fmt.Printf("+ concatenation: %d chars in %v\n", len(plus), plusTime)
+	fmt.Printf("strings.Builder: %d chars in %v\n", len(built), builderTime)
+
+	// Split, Join and Fields
+	csvLine := "go,rust,python"
+	languages := strings.Split(csvLine, ",")
+	fmt.Println("Split:", languages, "length:", len(languages))
+	fmt.Println("Join:", strings.Join(languages, " | "))
+	fmt.Println("Fields:", strings.Fields("  many   spaces\tand\ttabs  ")) // splits on any whitespace
+
+	// Searching
+	sentence := "Go is expressive, concise, clean, and efficient"
+	fmt.Println("Contains \"clean\":", strings.Contains(sentence, "clean"))
+	fmt.Println("HasPrefix \"Go\":", strings.HasPrefix(sentence, "Go"))
+	fmt.Println("HasSuffix \"efficient\":", strings.HasSuffix(sentence, "efficient"))
+	fmt.Println("Index of \"concise\":", strings.Index(sentence, "concise"))
+
+	// Replacing: Replace takes a count (-1 = all), ReplaceAll replaces every occurrence
+	fmt.Println("Replace:", strings.Replace(sentence, ",", ";", 1))
+	fmt.Println("ReplaceAll:", strings.ReplaceAll(sentence, ",", ";"))
+
+	// Case-insensitive comparison without allocating lower-cased copies
+	fmt.Println("EqualFold(\"GoLang\", \"golang\"):", strings.EqualFold("GoLang", "golang"))
+
+	fmt.Println("titleCase:", titleCase("learning go is FUN"))
+
+	// strconv converts between strings and numbers.
+	// Always check the error, user input is never guaranteed to be a number.
+	for _, input := range []string{"42", "4x2", "99999999999999999999"} {
+		num, err := strconv.Atoi(input)
+		if err != nil {
+			fmt.Printf("Atoi(%q) failed: %v\n", input, err)
+			continue
+		}
+		fmt.Printf("Atoi(%q) = %d\n", input, num)
+	}
+
+	if price, err := strconv.ParseFloat("19.99", 64); err != nil {
+		fmt.Println("ParseFloat failed:", err)
+	} else {
+		fmt.Println("ParseFloat:", price)
+	}
+
+	fmt.Println("FormatInt 255 in base 2:", strconv.FormatInt(255, 2))
+	fmt.Println("FormatInt 255 in base 16:", strconv.FormatInt(255, 16))
+	fmt.Println("Itoa:", strconv.Itoa(2024)+" is now a string")
+
+	// Parsing a query-string-like input into a map
+	params, err := parseKeyValues("name=Rishabh&lang=go&&level=beginner")
+	if err != nil {
+		fmt.Println("Error while parsing:", err)
+		return
+	}
+	fmt.Println("Parsed params:", params)
+
+	if _, err := parseKeyValues("name=Rishabh&broken"); err != nil {
+		fmt.Println("Error while parsing:", err)
+	}
+}
+
+// Strings in Go are read-only slices of bytes (usually UTF-8 text).
+// strings package = searching, splitting, replacing, comparing
+// strconv package = converting strings <-> numbers and booleans
+// strconv errors are of type *strconv.NumError and tell you the input and the reason (syntax or range)
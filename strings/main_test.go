@@ -0,0 +1,73 @@
+package main
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"learning go is FUN", "Learning Go Is Fun"},
+		{"", ""},
+		{"   spaces   everywhere  ", "Spaces Everywhere"},
+		{"a", "A"},
+		{"élan ÉCOLE", "Élan École"},
+		{"42 answers", "42 Answers"},
+	}
+	for _, tt := range tests {
+		if got := titleCase(tt.in); got != tt.want {
+			t.Errorf("titleCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr string
+	}{
+		{in: "name=Rishabh&lang=go&&level=beginner", want: map[string]string{"name": "Rishabh", "lang": "go", "level": "beginner"}},
+		{in: "", want: map[string]string{}},
+		{in: " key = value ", want: map[string]string{"key": "value"}},
+		{in: "a=1&a=2", want: map[string]string{"a": "2"}}, // the last one wins
+		{in: "empty=", want: map[string]string{"empty": ""}},
+		{in: "eq=a=b", want: map[string]string{"eq": "a=b"}}, // only the first "=" splits
+		{in: "name=Rishabh&broken", wantErr: `invalid pair "broken": missing '='`},
+		{in: "=value", wantErr: `invalid pair "=value": empty key`},
+	}
+	for _, tt := range tests {
+		got, err := parseKeyValues(tt.in)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseKeyValues(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || !maps.Equal(got, tt.want) {
+			t.Errorf("parseKeyValues(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+const concatN = 1000
+
+func BenchmarkConcatPlus(b *testing.B) {
+	for b.Loop() {
+		s := ""
+		for range concatN {
+			s += "x"
+		}
+	}
+}
+
+func BenchmarkConcatBuilder(b *testing.B) {
+	for b.Loop() {
+		var sb strings.Builder
+		for range concatN {
+			sb.WriteString("x")
+		}
+		_ = sb.String()
+	}
+}
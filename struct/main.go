@@ -1,8 +1,11 @@
 package main
 
 import (
+	crand "crypto/rand"
+	"flag"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"math/rand/v2"
 	"strconv"
 )
 
@@ -24,27 +27,53 @@ type User struct {
 	password string
 }
 
-func randomPasswordGenerator(passLength int) string {
+// randomPasswordGenerator uses crypto/rand on purpose: passwords must never come from
+// a seeded (predictable) generator, so -seed does not affect them
+func randomPasswordGenerator(passLength int) (string, error) {
 	const passwordCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789!@#$%^&*()-_=+[]{}|;:,.<>?/`~"
 	var generatedPassword string
+	charsetSize := big.NewInt(int64(len(passwordCharset)))
 	for i := 0; i < passLength; i++ {
-		generatedPassword = generatedPassword + string(passwordCharset[rand.Intn(len(passwordCharset))])
+		n, err := crand.Int(crand.Reader, charsetSize)
+		if err != nil {
+			return "", fmt.Errorf("generate password: %w", err)
+		}
+		generatedPassword = generatedPassword + string(passwordCharset[n.Int64()])
+	}
+	return generatedPassword, nil
+}
 
+// newUser draws the ID from rng (repeatable with a seed) and the password from crypto/rand (never repeatable)
+func newUser(rng *rand.Rand, name, email string, age int) (User, error) {
+	password, err := randomPasswordGenerator(15)
+	if err != nil {
+		return User{}, err
 	}
-	return generatedPassword
+	return User{
+		ID:       strconv.Itoa(rng.IntN(1000000)),
+		Name:     name,
+		Email:    email,
+		Age:      age,
+		password: password,
+	}, nil
 }
 
 func main() {
-	fmt.Println("Learning Go structs")
-	id := rand.Int() * 1000000
-	password := randomPasswordGenerator(15)
+	seed := flag.Uint64("seed", 0, "seed for the random user ID (0 = random), use the printed seed to repeat a run")
+	flag.Parse()
+	if *seed == 0 {
+		*seed = rand.Uint64()
+	}
+	rng := rand.New(rand.NewPCG(*seed, *seed)) // same seed -> same numbers every run
 
-	user1 := User{}
-	user1.Name = "Sanchay Roy"
-	user1.Email = "sanchayroy@gmail.com"
-	user1.ID = strconv.FormatInt(int64(id), 10)
-	user1.Age = 22
-	user1.password = password
+	fmt.Println("Learning Go structs")
+	fmt.Println("Seed:", *seed)
+	user1, err := newUser(rng, "Sanchay Roy", "sanchayroy@gmail.com", 22)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Printf("User ID from the seeded generator: %s, password length: %d\n", user1.ID, len(user1.password))
 
 	// fmt.Printf("User struct defined: %+v\n", user1)
 	var u User
@@ -84,3 +113,6 @@ func main() {
 // In structs no pvt public concept for importing/exporting packages
 // Only capitalized fields are exported
 // if field starts with small letter then it is unexported
+
+// math/rand is for simulations and demos: a seed makes the "random" output repeatable.
+// crypto/rand is for secrets (passwords, tokens), it can't be seeded or predicted.
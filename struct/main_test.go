@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"testing"
+)
+
+// seededRun is the repeatable part of the demo: user IDs drawn from one seeded generator
+func seededRun(t *testing.T, seed uint64) string {
+	t.Helper()
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var out strings.Builder
+	for i := range 5 {
+		u, err := newUser(rng, fmt.Sprint("user", i), "user@example.com", 20+i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&out, "%s %s %d\n", u.ID, u.Name, u.Age)
+	}
+	return out.String()
+}
+
+func TestSameSeedSameOutput(t *testing.T) {
+	a, b := seededRun(t, 42), seededRun(t, 42)
+	if a != b {
+		t.Errorf("seed 42 gave two different runs:\n%s\n%s", a, b)
+	}
+	if c := seededRun(t, 43); c == a {
+		t.Errorf("seeds 42 and 43 gave the same run:\n%s", a)
+	}
+}
+
+func TestPasswordsIgnoreTheSeed(t *testing.T) {
+	u1, err1 := newUser(rand.New(rand.NewPCG(7, 7)), "a", "a@example.com", 1)
+	u2, err2 := newUser(rand.New(rand.NewPCG(7, 7)), "a", "a@example.com", 1)
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	if u1.ID != u2.ID {
+		t.Errorf("IDs %s and %s differ under the same seed", u1.ID, u2.ID)
+	}
+	if u1.password == u2.password {
+		t.Error("the same seed produced the same password, passwords must come from crypto/rand")
+	}
+}
+
+func TestRandomPasswordGenerator(t *testing.T) {
+	for _, n := range []int{0, 1, 15, 64} {
+		p, err := randomPasswordGenerator(n)
+		if err != nil || len(p) != n {
+			t.Errorf("randomPasswordGenerator(%d) = %q, %v", n, p, err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// pollInterval is how long TailFile sleeps when it reached the end of the file
+const pollInterval = 50 * time.Millisecond
+
+// TailFile works like `tail -f`: it sends every complete line of path on the first channel,
+// from the beginning (fromStart) or only the lines appended after the call.
+// It keeps following the file when it is truncated (size shrinks) or rotated
+// (the path now points to a different file). Both channels are closed when ctx is
+// cancelled or after a fatal error, which is sent on the error channel first.
+func TailFile(ctx context.Context, path string, fromStart bool) (<-chan string, <-chan error) {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+		defer close(errs)
+		if err := tail(ctx, path, fromStart, lines); err != nil && ctx.Err() == nil {
+			errs <- fmt.Errorf("tail %s: %w", path, err)
+		}
+	}()
+	return lines, errs
+}
+
+func tail(ctx context.Context, path string, fromStart bool, lines chan<- string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { file.Close() }() // file is replaced on rotation, close the last one
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if !fromStart {
+		if offset, err = file.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	reader := bufio.NewReader(file)
+	var partial strings.Builder // text after the last newline, waiting for the rest of its line
+
+	// send blocks until the reader takes the line or ctx is cancelled
+	send := func(line string) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// readAvailable sends every complete line that's in the file right now,
+	// it returns false when ctx was cancelled
+	readAvailable := func() (bool, error) {
+		for {
+			chunk, err := reader.ReadString('\n')
+			offset += int64(len(chunk))
+			partial.WriteString(chunk)
+			if err == nil {
+				if !send(strings.TrimRight(partial.String(), "\r\n")) {
+					return false, nil
+				}
+				partial.Reset()
+				continue
+			}
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, err
+		}
+	}
+
+	for {
+		if ok, err := readAvailable(); !ok || err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+
+		current, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // in the middle of a rotation, the new file is not there yet
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !os.SameFile(info, current):
+			// rotated: finish the old file (lines written just before the rename), then switch
+			if ok, err := readAvailable(); !ok || err != nil {
+				return err
+			}
+			newFile, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			file.Close()
+			file = newFile
+			if info, err = file.Stat(); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			offset = 0
+			partial.Reset()
+		case current.Size() < offset:
+			// truncated: start again from the top
+			if _, err := file.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			reader.Reset(file)
+			offset = 0
+			partial.Reset()
+		}
+	}
+}
+
+// accessLog simulates a server writing an access log that gets rotated and truncated
+func accessLog(path string, total int) error {
+	write := func(from, to int) error {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		for i := from; i < to; i++ {
+			// write the line in two parts to show that half lines are held back
+			fmt.Fprintf(f, "GET /api/users/%d ", i)
+			time.Sleep(5 * time.Millisecond)
+			fmt.Fprintf(f, "200 %dms\n", i%7+1)
+		}
+		return nil
+	}
+
+	third := total / 3
+	if err := write(0, third); err != nil {
+		return err
+	}
+	time.Sleep(3 * pollInterval)
+
+	// rotation: access.log -> access.log.1, a new access.log is created
+	if err := os.Rename(path, path+".1"); err != nil {
+		return err
+	}
+	if err := write(third, 2*third); err != nil {
+		return err
+	}
+	time.Sleep(3 * pollInterval)
+
+	// truncation: logrotate's copytruncate mode empties the file in place
+	if err := os.Truncate(path, 0); err != nil {
+		return err
+	}
+	time.Sleep(3 * pollInterval)
+	return write(2*third, total)
+}
+
+func main() {
+	fmt.Println("Learning to follow a growing file (tail -f) in Go")
+
+	dir, err := os.MkdirTemp("", "tail-demo-*")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+	if err := os.WriteFile(path, []byte("GET /old 200 1ms\n"), 0o644); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines, errs := TailFile(ctx, path, true)
+
+	const total = 30
+	done := make(chan error)
+	go func() { done <- accessLog(path, total) }()
+
+	seen := make(map[string]int)
+	received := 0
+	for received < total+1 { // +1 for the line that was there before we started
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println("lines channel closed early")
+				received = total + 1
+				continue
+			}
+			seen[line]++
+			received++
+			fmt.Println("  >", line)
+		case <-time.After(2 * time.Second):
+			fmt.Println("timed out waiting for lines")
+			received = total + 1
+		}
+	}
+	if err := <-done; err != nil {
+		fmt.Println("Writer error:", err)
+	}
+
+	duplicates := 0
+	for _, n := range seen {
+		if n > 1 {
+			duplicates++
+		}
+	}
+	fmt.Printf("Received %d distinct lines, %d duplicates\n", len(seen), duplicates)
+
+	// cancelling stops the goroutine and closes both channels
+	cancel()
+	_, linesOpen := <-lines
+	err, errsOpen := <-errs
+	fmt.Println("After cancel: lines open:", linesOpen, "errors open:", errsOpen, "error:", err)
+
+	// a missing file is reported on the error channel
+	lines, errs = TailFile(context.Background(), filepath.Join(dir, "missing.log"), true)
+	for range lines {
+	}
+	fmt.Println("Missing file:", <-errs)
+}
+
+// Reading at EOF is not an error for a growing file: wait a bit and read again (polling).
+// os.SameFile compares device + inode, so it notices when a path points to a new file after rotation.
+// A shrinking size means the file was truncated, seek back to the start.
+// Closing channels from the goroutine that sends on them tells the reader "no more values".
+// Real tools use OS notifications (inotify, kqueue) instead of polling, e.g. github.com/fsnotify/fsnotify.
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect reads n lines or fails after timeout
+func collect(t *testing.T, lines <-chan string, n int, timeout time.Duration) []string {
+	t.Helper()
+	var got []string
+	deadline := time.After(timeout)
+	for len(got) < n {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("lines closed after %d of %d lines", len(got), n)
+			}
+			got = append(got, line)
+		case <-deadline:
+			t.Fatalf("timed out after %d of %d lines", len(got), n)
+		}
+	}
+	return got
+}
+
+func assertExactlyOnce(t *testing.T, got []string, want []string) {
+	t.Helper()
+	count := map[string]int{}
+	for _, line := range got {
+		count[line]++
+	}
+	for _, w := range want {
+		if count[w] != 1 {
+			t.Errorf("%q delivered %d times", w, count[w])
+		}
+		delete(count, w)
+	}
+	for line := range count {
+		t.Errorf("unexpected line %q", line)
+	}
+}
+
+func appendLine(t *testing.T, path, line string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer f.Close()
+	// one write per line: O_APPEND makes it land whole at the end
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTailConcurrentAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("old 1\nold 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := TailFile(ctx, path, true)
+
+	var want []string
+	var wg sync.WaitGroup
+	for w := range 4 {
+		for i := range 25 {
+			want = append(want, fmt.Sprintf("writer %d line %d", w, i))
+		}
+		wg.Go(func() {
+			for i := range 25 {
+				appendLine(t, path, fmt.Sprintf("writer %d line %d", w, i))
+			}
+		})
+	}
+	got := collect(t, lines, 102, 5*time.Second)
+	wg.Wait()
+	assertExactlyOnce(t, got, append([]string{"old 1", "old 2"}, want...))
+}
+
+func TestTailFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("before\npartial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := TailFile(ctx, path, false)
+	time.Sleep(2 * pollInterval) // let it seek to the end first
+	appendLine(t, path, " rest")
+	appendLine(t, path, "after")
+	// the half line written before we started is not ours, its end shows up on its own
+	got := collect(t, lines, 2, 2*time.Second)
+	if got[0] != " rest" || got[1] != "after" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// accessLog writes half lines, rotates the file and truncates it
+func TestTailRotationAndTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	if err := os.WriteFile(path, []byte("GET /old 200 1ms\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, errs := TailFile(ctx, path, true)
+
+	const total = 30
+	done := make(chan error, 1)
+	go func() { done <- accessLog(path, total) }()
+	got := collect(t, lines, total+1, 10*time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"GET /old 200 1ms"}
+	for i := range total {
+		want = append(want, fmt.Sprintf("GET /api/users/%d 200 %dms", i, i%7+1))
+	}
+	assertExactlyOnce(t, got, want)
+
+	// nothing more arrives: nothing is read twice after the truncation
+	select {
+	case line := <-lines:
+		t.Errorf("extra line %q", line)
+	case err := <-errs:
+		t.Errorf("error %v", err)
+	case <-time.After(4 * pollInterval):
+	}
+}
+
+func TestTailCancelClosesChannels(t *testing.T) {
+	before := runtime.NumGoroutine()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		ctx, cancel := context.WithCancel(context.Background())
+		lines, errs := TailFile(ctx, path, true)
+		<-lines // read one, leave the others unread: the sender is blocked on the channel
+		cancel()
+		for range lines {
+		}
+		if err, ok := <-errs; ok || err != nil {
+			t.Errorf("after cancel: %v, %v; want a closed error channel", err, ok)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines before, %d after cancel", before, runtime.NumGoroutine())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTailMissingFile(t *testing.T) {
+	lines, errs := TailFile(context.Background(), filepath.Join(t.TempDir(), "missing.log"), true)
+	for line := range lines {
+		t.Errorf("line %q from a missing file", line)
+	}
+	if err := <-errs; !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
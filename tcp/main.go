@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IdleTimeout is how long a client may stay silent before the server hangs up
+var IdleTimeout = 2 * time.Second
+
+// StartTCPEchoServer listens on addr ("127.0.0.1:0" picks a free port) and echoes
+// every line back upper-cased. It returns the real address once the server is listening.
+// Cancelling ctx closes the listener and all open connections, then waits for
+// the handlers; done is closed when everything has stopped.
+func StartTCPEchoServer(ctx context.Context, addr string) (net.Addr, <-chan struct{}, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listen %s: %w", addr, err)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		conns = make(map[net.Conn]struct{})
+	)
+	done := make(chan struct{})
+
+	go func() {
+		<-ctx.Done()
+		ln.Close() // makes Accept return an error, which ends the accept loop
+		mu.Lock()
+		for c := range conns {
+			c.Close() // wakes up handlers blocked in Read
+		}
+		mu.Unlock()
+	}()
+
+	go func() {
+		defer close(done)
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if ctx.Err() == nil {
+					fmt.Println("  server: accept:", err)
+				}
+				break
+			}
+			mu.Lock()
+			if ctx.Err() != nil { // accepted just as the shutdown started
+				mu.Unlock()
+				conn.Close()
+				break
+			}
+			conns[conn] = struct{}{}
+			mu.Unlock()
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					mu.Lock()
+					delete(conns, conn)
+					mu.Unlock()
+					conn.Close()
+				}()
+				handleEcho(conn)
+			}()
+		}
+		wg.Wait() // don't report "stopped" while a handler is still running
+	}()
+
+	return ln.Addr(), done, nil
+}
+
+// handleEcho serves one connection until the client leaves, goes idle or the server shuts down
+func handleEcho(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		// the deadline is absolute, so it's moved forward before every read
+		conn.SetReadDeadline(time.Now().Add(IdleTimeout))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				// the client may not be reading either, so the goodbye gets a deadline too
+				if err := conn.SetWriteDeadline(time.Now().Add(time.Second)); err == nil {
+					fmt.Fprintln(conn, "ERR idle timeout, bye")
+				}
+				fmt.Println("  server: closed idle client", conn.RemoteAddr())
+			}
+			return // io.EOF: the client closed the connection
+		}
+		conn.SetWriteDeadline(time.Now().Add(IdleTimeout)) // a client that never reads can't block us forever
+		if _, err := conn.Write([]byte(strings.ToUpper(line))); err != nil {
+			return
+		}
+	}
+}
+
+// TCPClient sends lines to the echo server and waits for each answer
+type TCPClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	Timeout time.Duration
+}
+
+func DialTCPClient(addr string, timeout time.Duration) (*TCPClient, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", addr, err)
+	}
+	return &TCPClient{conn: conn, r: bufio.NewReader(conn), Timeout: timeout}, nil
+}
+
+// Send writes line and returns the server's reply without the trailing newline
+func (c *TCPClient) Send(line string) (string, error) {
+	if strings.Contains(line, "\n") {
+		return "", fmt.Errorf("send %q: line must not contain a newline", line)
+	}
+	c.conn.SetDeadline(time.Now().Add(c.Timeout))
+	if _, err := fmt.Fprintln(c.conn, line); err != nil {
+		return "", fmt.Errorf("send %q: %w", line, err)
+	}
+	reply, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("read reply to %q: %w", line, err)
+	}
+	return strings.TrimSuffix(reply, "\n"), nil
+}
+
+func (c *TCPClient) Close() error { return c.conn.Close() }
+
+func main() {
+	fmt.Println("Learning TCP servers and clients in Go")
+	IdleTimeout = 300 * time.Millisecond // short so the demo is fast
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addr, done, err := StartTCPEchoServer(ctx, "127.0.0.1:0")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("Echo server on", addr)
+
+	// 1. a single client
+	client, err := DialTCPClient(addr.String(), time.Second)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, msg := range []string{"hello", "tcp is a byte stream"} {
+		reply, err := client.Send(msg)
+		fmt.Printf("  sent %q got %q err %v\n", msg, reply, err)
+	}
+	client.Close()
+
+	// 2. concurrent clients, each one has its own handler goroutine
+	var wg sync.WaitGroup
+	var okMu sync.Mutex
+	ok := 0
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := DialTCPClient(addr.String(), time.Second)
+			if err != nil {
+				return
+			}
+			defer c.Close()
+			msg := fmt.Sprintf("client %d", i)
+			if reply, err := c.Send(msg); err == nil && reply == strings.ToUpper(msg) {
+				okMu.Lock()
+				ok++
+				okMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Println("  20 concurrent clients,", ok, "correct replies")
+
+	// 3. an idle client is disconnected by the read deadline
+	idle, err := DialTCPClient(addr.String(), time.Second)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	time.Sleep(2 * IdleTimeout)
+	bye, _ := idle.r.ReadString('\n')
+	_, err = idle.Send("are you there?")
+	fmt.Printf("  idle client got %q, then Send: %v\n", strings.TrimSpace(bye), err != nil)
+	idle.Close()
+
+	// 4. shutdown with an active connection
+	active, err := DialTCPClient(addr.String(), time.Second)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	active.Send("still here")
+	start := time.Now()
+	cancel()
+	<-done
+	fmt.Println("  server stopped in", time.Since(start).Round(time.Millisecond), "with a client still connected")
+	_, err = active.Send("after shutdown")
+	fmt.Println("  send after shutdown fails:", err != nil)
+	active.Close()
+
+	_, err = DialTCPClient(addr.String(), 200*time.Millisecond)
+	fmt.Println("  new connection refused:", err != nil)
+}
+
+// TCP is a stream of bytes, not messages: you need framing (here one message per line).
+// One goroutine per connection is the normal Go design, goroutines are cheap.
+// Deadlines are absolute times: set them again before each read/write.
+// To shut down: close the listener (Accept returns), close open conns (Read returns), then wait.
+// Listening on ":0" or "127.0.0.1:0" lets the OS pick a free port, use ln.Addr() to find it.
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T) (addr string, cancel context.CancelFunc, done <-chan struct{}) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	a, done, err := StartTCPEchoServer(ctx, "127.0.0.1:0")
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return a.String(), cancel, done
+}
+
+func dial(t *testing.T, addr string) *TCPClient {
+	t.Helper()
+	c, err := DialTCPClient(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestEcho(t *testing.T) {
+	addr, _, _ := startServer(t)
+	c := dial(t, addr)
+	for _, msg := range []string{"hello", "", "ünïcode ok", strings.Repeat("long ", 10_000)} {
+		reply, err := c.Send(msg)
+		if err != nil || reply != strings.ToUpper(msg) {
+			t.Errorf("Send(%.20q) = %.20q, %v", msg, reply, err)
+		}
+	}
+	if _, err := c.Send("two\nlines"); err == nil {
+		t.Error("a newline in the line was accepted")
+	}
+}
+
+func TestConcurrentClients(t *testing.T) {
+	addr, _, _ := startServer(t)
+	var wg sync.WaitGroup
+	var ok atomic.Int64
+	for i := range 50 {
+		wg.Go(func() {
+			c, err := DialTCPClient(addr, 2*time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer c.Close()
+			for j := range 10 {
+				msg := fmt.Sprintf("client %d message %d", i, j)
+				if reply, err := c.Send(msg); err != nil || reply != strings.ToUpper(msg) {
+					t.Errorf("%q: %q, %v", msg, reply, err)
+					return
+				}
+			}
+			ok.Add(1)
+		})
+	}
+	wg.Wait()
+	if ok.Load() != 50 {
+		t.Errorf("%d of 50 clients got all their replies", ok.Load())
+	}
+}
+
+func TestShutdownWithActiveConnections(t *testing.T) {
+	addr, cancel, done := startServer(t)
+	clients := make([]*TCPClient, 5)
+	for i := range clients {
+		clients[i] = dial(t, addr)
+		if _, err := clients[i].Send("hi"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop with clients connected")
+	}
+	for _, c := range clients {
+		if _, err := c.Send("after shutdown"); err == nil {
+			t.Error("Send succeeded after shutdown")
+		}
+	}
+	if _, err := DialTCPClient(addr, 200*time.Millisecond); err == nil {
+		t.Error("a new connection was accepted after shutdown")
+	}
+}
+
+func TestIdleClientDisconnected(t *testing.T) {
+	old := IdleTimeout
+	IdleTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { IdleTimeout = old })
+
+	addr, _, _ := startServer(t)
+	c := dial(t, addr)
+	if _, err := c.Send("awake"); err != nil {
+		t.Fatal(err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	start := time.Now()
+	bye, err := c.r.ReadString('\n')
+	if err != nil || bye != "ERR idle timeout, bye\n" {
+		t.Fatalf("got %q, %v", bye, err)
+	}
+	if waited := time.Since(start); waited < IdleTimeout/2 {
+		t.Errorf("disconnected after %v, IdleTimeout is %v", waited, IdleTimeout)
+	}
+	if _, err := c.Send("are you there?"); err == nil {
+		t.Error("Send succeeded on a closed connection")
+	}
+}
+
+// a client that keeps the connection busy is never timed out
+func TestActiveClientKeptAlive(t *testing.T) {
+	old := IdleTimeout
+	IdleTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { IdleTimeout = old })
+
+	addr, _, _ := startServer(t)
+	c := dial(t, addr)
+	for range 6 {
+		time.Sleep(IdleTimeout / 2)
+		if _, err := c.Send("ping"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStartErrors(t *testing.T) {
+	addr, _, _ := startServer(t)
+	_, _, err := StartTCPEchoServer(context.Background(), addr)
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("listening twice on %s: %v", addr, err)
+	}
+	if _, err := DialTCPClient("127.0.0.1:1", 200*time.Millisecond); err == nil {
+		t.Error("dial to a closed port succeeded")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// Building HTML with fmt.Sprintf or + is how cross-site scripting (XSS) happens: a user called
+// `<script>steal()</script>` becomes a script on every page that lists users.
+// html/template escapes every value for the place it lands in: HTML text, an attribute,
+// a URL, inside <script>. text/template has the same syntax but escapes nothing,
+// it is for plain text (emails, reports, config files).
+
+//go:embed tmpl
+var tmplFS embed.FS // the templates are compiled into the binary, nothing to deploy next to it
+
+type User struct {
+	ID      int
+	Name    string
+	Email   string
+	Website string
+}
+
+// UserStore holds users in memory, safe for concurrent use
+type UserStore struct {
+	mu    sync.RWMutex
+	users []User
+}
+
+func (s *UserStore) Add(u User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u.ID = len(s.users) + 1
+	s.users = append(s.users, u)
+	return u
+}
+
+func (s *UserStore) All() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]User(nil), s.users...)
+}
+
+// Route is one registered endpoint, the home page lists them
+type Route struct {
+	Method, Path, Help string
+}
+
+type Server struct {
+	store  *UserStore
+	pages  map[string]*template.Template
+	routes []Route
+	mux    *http.ServeMux
+}
+
+// parsePages parses every page together with the base layout. Each page gets its own
+// template set, so the "title" and "content" blocks of one page can't replace another's.
+func parsePages(fsys fs.FS, pages ...string) (map[string]*template.Template, error) {
+	parsed := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		t, err := template.ParseFS(fsys, "tmpl/base.html", "tmpl/"+page)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", page, err)
+		}
+		parsed[page] = t
+	}
+	return parsed, nil
+}
+
+// NewServer parses the templates once at startup: a broken template stops the program
+// right away instead of failing on the first request
+func NewServer(fsys fs.FS, store *UserStore) (*Server, error) {
+	pages, err := parsePages(fsys, "home.html", "users.html")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{store: store, pages: pages, mux: http.NewServeMux()}
+	s.handle("GET", "/{$}", "this page", s.home)
+	s.handle("GET", "/users", "the users table", s.users)
+	s.handle("POST", "/users", "add a user (form fields name, email, website)", s.addUser)
+	return s, nil
+}
+
+// handle registers h and remembers the route, so the home page is always up to date
+func (s *Server) handle(method, path, help string, h http.HandlerFunc) {
+	s.mux.HandleFunc(method+" "+path, h)
+	s.routes = append(s.routes, Route{Method: method, Path: strings.TrimSuffix(path, "{$}"), Help: help})
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+// render executes into a buffer first: if the template fails halfway, the client gets
+// a clean 500 instead of half a page with a 200 status
+func (s *Server) render(w http.ResponseWriter, page string, data any) {
+	var buf bytes.Buffer
+	if err := s.pages[page].ExecuteTemplate(&buf, "base", data); err != nil {
+		log.Printf("render %s: %v", page, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+func (s *Server) home(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "home.html", struct{ Routes []Route }{s.routes})
+}
+
+type usersPage struct {
+	Users []User
+}
+
+// Names is called from the template, inside <script> it becomes a JavaScript array
+func (p usersPage) Names() []string {
+	names := make([]string, len(p.Users))
+	for i, u := range p.Users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+func (s *Server) users(w http.ResponseWriter, r *http.Request) {
+	s.render(w, "users.html", usersPage{Users: s.store.All()})
+}
+
+func (s *Server) addUser(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	s.store.Add(User{Name: name, Email: r.FormValue("email"), Website: r.FormValue("website")})
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// ----------------------------------------------------------------------------
+// A plain-text report with text/template
+
+type LessonResult struct {
+	Name       string
+	Score, Max int
+	Duration   time.Duration
+}
+
+type SessionSummary struct {
+	User    string
+	Started time.Time
+	Lessons []LessonResult
+}
+
+func (s SessionSummary) Total() int {
+	total := 0
+	for _, l := range s.Lessons {
+		total += l.Score
+	}
+	return total
+}
+
+func (s SessionSummary) MaxTotal() int {
+	total := 0
+	for _, l := range s.Lessons {
+		total += l.Max
+	}
+	return total
+}
+
+var reportFuncs = texttemplate.FuncMap{
+	"percent": func(n, of int) string {
+		if of == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(of))
+	},
+}
+
+// Must: the template is part of the program, an error here is a bug found at startup
+var reportTmpl = texttemplate.Must(texttemplate.New("report.txt").Funcs(reportFuncs).ParseFS(tmplFS, "tmpl/report.txt"))
+
+func WriteReport(w io.Writer, s SessionSummary) error {
+	return reportTmpl.Execute(w, s)
+}
+
+func main() {
+	fmt.Println("Learning html/template and text/template in Go")
+
+	store := &UserStore{}
+	store.Add(User{Name: "Rishabh", Email: "rishabh@example.com", Website: "https://example.com/rishabh"})
+	store.Add(User{Name: `<script>alert("hacked")</script>`, Email: "evil@example.com", Website: "javascript:alert(1)"})
+	store.Add(User{Name: `Tom "Tommy" O'Neil & Co`, Email: "tom@example.com", Website: "https://example.com/?a=1&b=<2>"})
+
+	srv, err := NewServer(tmplFS, store)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	for _, path := range []string{"/", "/users"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		fmt.Printf("GET %s -> %d %s\n%s\n", path, rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	// the same value through both packages
+	const snippet = `<p title="{{.}}">{{.}}</p>`
+	evil := `"><script>alert(1)</script>`
+	fmt.Println("The same value through both packages:")
+	texttemplate.Must(texttemplate.New("t").Parse(snippet)).Execute(os.Stdout, evil)
+	fmt.Println("   <- text/template, the script runs")
+	template.Must(template.New("h").Parse(snippet)).Execute(os.Stdout, evil)
+	fmt.Println("   <- html/template, harmless text")
+
+	fmt.Println("\nA text/template report:")
+	start := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	err = WriteReport(os.Stdout, SessionSummary{User: "Rishabh", Started: start, Lessons: []LessonResult{
+		{Name: "pointers", Score: 8, Max: 10, Duration: 12 * time.Minute},
+		{Name: "maps", Score: 10, Max: 10, Duration: 7*time.Minute + 30*time.Second},
+		{Name: "goroutines", Score: 3, Max: 5, Duration: 21 * time.Minute},
+	}})
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+	fmt.Println()
+	WriteReport(os.Stdout, SessionSummary{User: "nobody", Started: start})
+}
+
+// Generate HTML with html/template, never with fmt or text/template: it escapes by context.
+// In text "<" becomes "&lt;", in an href a "javascript:" URL becomes "#ZgotmplZ", in <script> a value becomes a JS literal.
+// Parse templates once at startup (template.Must), execute into a buffer, then write: no half pages.
+// Keep one template set per page with the shared layout, or the pages' blocks overwrite each other.
+// Embed the templates with go:embed and accept an fs.FS, tests can pass their own fstest.MapFS.
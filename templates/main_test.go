@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func newTestServer(t *testing.T, users ...User) *Server {
+	t.Helper()
+	store := &UserStore{}
+	for _, u := range users {
+		store.Add(u)
+	}
+	srv, err := NewServer(tmplFS, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestHomeListsRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	srv.handle("GET", "/health", "added after startup", func(w http.ResponseWriter, r *http.Request) {})
+	rec := get(t, srv, "/")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("GET / = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<title>Home - go_learning</title>",
+		"<li><code>GET /</code> this page</li>",
+		"<li><code>GET /users</code> the users table</li>",
+		"<li><code>POST /users</code>",
+		"<li><code>GET /health</code> added after startup</li>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("home page is missing %q:\n%s", want, body)
+		}
+	}
+	if rec := get(t, srv, "/nope"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /nope = %d", rec.Code)
+	}
+}
+
+func TestUsersPageEscapes(t *testing.T) {
+	srv := newTestServer(t,
+		User{Name: "Rishabh", Email: "r@example.com", Website: "https://example.com/r"},
+		User{Name: `<script>alert("x")</script>`, Email: "evil@example.com", Website: "javascript:alert(1)"},
+		User{Name: `O'Neil & "Co"`, Email: "o@example.com", Website: "https://example.com/?q=<b>"},
+	)
+	body := get(t, srv, "/users").Body.String()
+
+	if strings.Contains(body, `<script>alert`) {
+		t.Fatalf("the user's script reached the page unescaped:\n%s", body)
+	}
+	for _, want := range []string{
+		"<title>Users (3) - go_learning</title>",
+		`">&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</td>`,                                                // HTML text
+		`title="&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;"`,                                               // attribute
+		`<a href="#ZgotmplZ">javascript:alert(1)</a>`,                                                           // a javascript: URL is neutralised
+		`href="https://example.com/?q=%3cb%3e"`,                                                                 // URL
+		`">O&#39;Neil &amp; &#34;Co&#34;</td>`,                                                                  // quotes and &
+		`const names = ["Rishabh","\u003cscript\u003ealert(\"x\")\u003c/script\u003e","O'Neil \u0026 \"Co\""];`, // JavaScript
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("users page is missing %s:\n%s", want, body)
+		}
+	}
+	if got := strings.Count(body, "<script>"); got != 1 {
+		t.Errorf("%d <script> tags, want only the page's own", got)
+	}
+}
+
+func TestUsersPageEmpty(t *testing.T) {
+	body := get(t, newTestServer(t), "/users").Body.String()
+	if !strings.Contains(body, "<p>No users yet.</p>") || strings.Contains(body, "<table>") || !strings.Contains(body, "const names = [];") {
+		t.Errorf("empty users page:\n%s", body)
+	}
+}
+
+func TestAddUser(t *testing.T) {
+	srv := newTestServer(t)
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := post(url.Values{"name": {"  "}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("blank name: %d", rec.Code)
+	}
+	rec := post(url.Values{"name": {"<b>bold</b>"}, "email": {"b@example.com"}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/users" {
+		t.Fatalf("POST /users = %d, Location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if body := get(t, srv, "/users").Body.String(); !strings.Contains(body, `">&lt;b&gt;bold&lt;/b&gt;</td>`) {
+		t.Errorf("the new user is not listed escaped:\n%s", body)
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	good, _ := tmplFS.ReadFile("tmpl/base.html")
+	home, _ := tmplFS.ReadFile("tmpl/home.html")
+
+	// a syntax error is found at startup
+	broken := fstest.MapFS{
+		"tmpl/base.html":  {Data: good},
+		"tmpl/home.html":  {Data: home},
+		"tmpl/users.html": {Data: []byte(`{{define "content"}}{{range .Users}}{{end}`)},
+	}
+	if _, err := NewServer(broken, &UserStore{}); err == nil || !strings.Contains(err.Error(), "users.html") {
+		t.Errorf("NewServer with a broken template: %v", err)
+	}
+
+	// an error while executing gives a clean 500, not half a page
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	failing := fstest.MapFS{
+		"tmpl/base.html":  {Data: good},
+		"tmpl/home.html":  {Data: home},
+		"tmpl/users.html": {Data: []byte(`{{define "title"}}t{{end}}{{define "content"}}{{.NoSuchField}}{{end}}`)},
+	}
+	srv, err := NewServer(failing, &UserStore{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := get(t, srv, "/users")
+	if rec.Code != http.StatusInternalServerError || strings.Contains(rec.Body.String(), "<html") {
+		t.Errorf("GET /users = %d:\n%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	start := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		summary SessionSummary
+		want    string
+	}{
+		{"lessons", SessionSummary{User: "<Rishabh>", Started: start, Lessons: []LessonResult{
+			{Name: "pointers", Score: 8, Max: 10, Duration: 12 * time.Minute},
+			{Name: "maps", Score: 10, Max: 10, Duration: 90 * time.Second},
+		}}, `Session summary for <Rishabh>
+Started 2026-03-14 09:30
+
+pointers           8/10     12m0s
+maps              10/10     1m30s  perfect
+
+Total: 18/20 (90%)
+`},
+		{"empty", SessionSummary{User: "nobody", Started: start}, `Session summary for nobody
+Started 2026-03-14 09:30
+
+No lessons finished.
+
+Total: 0/0 (-)
+`},
+	}
+	for _, tt := range tests {
+		var sb strings.Builder
+		if err := WriteReport(&sb, tt.summary); err != nil {
+			t.Fatal(err)
+		}
+		// text/template escapes nothing: right for plain text, wrong for HTML
+		if sb.String() != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, sb.String(), tt.want)
+		}
+	}
+}
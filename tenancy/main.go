@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// One server, several customers (tenants), and none of them may ever see another's data.
+// The tenant is decided ONCE, in a middleware, and travels in the context. The store
+// takes it as a parameter for every call, so forgetting it is a compile error, not a data leak.
+
+const (
+	DefaultTenant = "public"
+	SystemTenant  = "system" // its admins may act on any tenant with ?tenant=
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrUnknownTenant = errors.New("unknown tenant")
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type AuditEntry struct {
+	Tenant string
+	By     string // the caller's own tenant, differs from Tenant for a system override
+	Action string
+	UserID int
+}
+
+// UserStore keeps one map per tenant. IDs come from one counter, so an ID never
+// exists in two tenants and a guessed ID from another tenant simply isn't found.
+type UserStore struct {
+	mu      sync.RWMutex
+	tenants map[string]map[int]User
+	nextID  int
+	audit   []AuditEntry
+}
+
+func NewUserStore(tenants ...string) *UserStore {
+	s := &UserStore{tenants: make(map[string]map[int]User), nextID: 1}
+	for _, t := range tenants {
+		s.tenants[t] = make(map[int]User)
+	}
+	return s
+}
+
+func (s *UserStore) HasTenant(tenant string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tenants[tenant]
+	return ok
+}
+
+func (s *UserStore) Create(tenant, by, name string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users, ok := s.tenants[tenant]
+	if !ok {
+		return User{}, fmt.Errorf("create in %q: %w", tenant, ErrUnknownTenant)
+	}
+	u := User{ID: s.nextID, Name: name}
+	s.nextID++
+	users[u.ID] = u
+	s.audit = append(s.audit, AuditEntry{Tenant: tenant, By: by, Action: "create", UserID: u.ID})
+	return u, nil
+}
+
+func (s *UserStore) Get(tenant string, id int) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.tenants[tenant][id] // indexing a missing tenant gives a nil map: not found, no panic
+	if !ok {
+		return User{}, fmt.Errorf("user %d in %q: %w", id, tenant, ErrNotFound)
+	}
+	return u, nil
+}
+
+func (s *UserStore) List(tenant string) []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	users := make([]User, 0, len(s.tenants[tenant]))
+	for _, u := range s.tenants[tenant] {
+		users = append(users, u)
+	}
+	slices.SortFunc(users, func(a, b User) int { return a.ID - b.ID })
+	return users
+}
+
+func (s *UserStore) Audit() []AuditEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.audit)
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+type scope struct {
+	tenant string // the tenant to act on
+	caller string // the tenant the request came from
+}
+
+type scopeKey struct{}
+
+func Tenant(ctx context.Context) string {
+	sc, _ := ctx.Value(scopeKey{}).(scope)
+	return sc.tenant
+}
+
+func CallerTenant(ctx context.Context) string {
+	sc, _ := ctx.Value(scopeKey{}).(scope)
+	return sc.caller
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// tenantMiddleware reads X-Tenant-ID, checks it, and puts the tenant to act on in the context.
+// There is no login in this lesson, so X-Role stands in for the role a real auth middleware
+// would have put in the context.
+func tenantMiddleware(store *UserStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := r.Header.Get("X-Tenant-ID")
+		if tenant == "" {
+			tenant = DefaultTenant
+		}
+		if !tenantIDPattern.MatchString(tenant) || !store.HasTenant(tenant) {
+			writeError(w, http.StatusBadRequest, "unknown tenant")
+			return
+		}
+		sc := scope{tenant: tenant, caller: tenant}
+		if target := r.URL.Query().Get("tenant"); target != "" {
+			if tenant != SystemTenant || r.Header.Get("X-Role") != "admin" {
+				writeError(w, http.StatusForbidden, "only system admins can choose a tenant")
+				return
+			}
+			if !store.HasTenant(target) {
+				writeError(w, http.StatusBadRequest, "unknown tenant")
+				return
+			}
+			sc.tenant = target
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scopeKey{}, sc)))
+	})
+}
+
+func routes(store *UserStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Name string }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Name) == "" {
+			writeError(w, http.StatusBadRequest, "name is required")
+			return
+		}
+		u, err := store.Create(Tenant(r.Context()), CallerTenant(r.Context()), in.Name)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(u)
+	})
+	mux.HandleFunc("GET /api/users", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(store.List(Tenant(r.Context())))
+	})
+	mux.HandleFunc("GET /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		u, err := store.Get(Tenant(r.Context()), id)
+		if errors.Is(err, ErrNotFound) {
+			// 404, not 403: "forbidden" would confirm that user 3 exists somewhere
+			writeError(w, http.StatusNotFound, "user not found")
+			return
+		}
+		json.NewEncoder(w).Encode(u)
+	})
+	return tenantMiddleware(store, mux)
+}
+
+func main() {
+	fmt.Println("Learning multi-tenant request scoping in Go")
+
+	store := NewUserStore(DefaultTenant, SystemTenant, "acme", "globex")
+	h := routes(store)
+	do := func(method, target, tenant, role, body string) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if tenant != "" {
+			req.Header.Set("X-Tenant-ID", tenant)
+		}
+		if role != "" {
+			req.Header.Set("X-Role", role)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		fmt.Printf("  %-4s %-25s tenant=%-8s role=%-6s -> %d %s", method, target, tenant, role, rec.Code, rec.Body.String())
+	}
+
+	fmt.Println("Create users in two tenants:")
+	do("POST", "/api/users", "acme", "", `{"name":"Wile E."}`)
+	do("POST", "/api/users", "acme", "", `{"name":"Road Runner"}`)
+	do("POST", "/api/users", "globex", "", `{"name":"Hank"}`)
+	do("POST", "/api/users", "", "", `{"name":"Guest"}`)
+
+	fmt.Println("Each tenant sees only its own:")
+	do("GET", "/api/users", "acme", "", "")
+	do("GET", "/api/users", "globex", "", "")
+	do("GET", "/api/users/1", "acme", "", "")
+	do("GET", "/api/users/1", "globex", "", "") // exists, but in acme: 404 like a missing one
+	do("GET", "/api/users/99", "globex", "", "")
+
+	fmt.Println("Unknown tenants:")
+	do("GET", "/api/users", "initech", "", "")
+	do("GET", "/api/users", "../acme", "", "")
+
+	fmt.Println("System override:")
+	do("GET", "/api/users?tenant=acme", "system", "admin", "")
+	do("POST", "/api/users?tenant=globex", "system", "admin", `{"name":"Support"}`)
+	do("GET", "/api/users?tenant=acme", "system", "user", "")
+	do("GET", "/api/users?tenant=acme", "globex", "admin", "")
+	do("GET", "/api/users?tenant=nope", "system", "admin", "")
+
+	fmt.Println("Audit log:")
+	for _, e := range store.Audit() {
+		fmt.Printf("  %-8s %s user %d (by %s)\n", e.Tenant, e.Action, e.UserID, e.By)
+	}
+}
+
+// Decide the tenant once, in a middleware, and pass it explicitly to every store call.
+// Namespace the storage by tenant: one map per tenant, or the tenant in every key.
+// Answer 404 for another tenant's resource, a 403 would tell the caller that the ID exists.
+// Validate tenant IDs against a list (and a pattern), never use a raw header as a key or a path.
+// A cross-tenant override is a privilege: only for a specific role in a specific tenant, and audited.
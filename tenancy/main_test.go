@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type response struct {
+	code int
+	body string
+}
+
+func call(h http.Handler, method, target, tenant, role, body string) response {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if tenant != "" {
+		req.Header.Set("X-Tenant-ID", tenant)
+	}
+	if role != "" {
+		req.Header.Set("X-Role", role)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return response{rec.Code, strings.TrimSpace(rec.Body.String())}
+}
+
+func newTestStore() *UserStore {
+	return NewUserStore(DefaultTenant, SystemTenant, "acme", "globex")
+}
+
+func create(t *testing.T, h http.Handler, tenant, name string) User {
+	t.Helper()
+	resp := call(h, "POST", "/api/users", tenant, "", fmt.Sprintf(`{"name":%q}`, name))
+	var u User
+	if resp.code != http.StatusCreated || json.Unmarshal([]byte(resp.body), &u) != nil {
+		t.Fatalf("create %s in %q: %d %s", name, tenant, resp.code, resp.body)
+	}
+	return u
+}
+
+func names(t *testing.T, h http.Handler, target, tenant, role string) []string {
+	t.Helper()
+	resp := call(h, "GET", target, tenant, role, "")
+	var users []User
+	if resp.code != http.StatusOK || json.Unmarshal([]byte(resp.body), &users) != nil {
+		t.Fatalf("GET %s as %s: %d %s", target, tenant, resp.code, resp.body)
+	}
+	out := []string{}
+	for _, u := range users {
+		out = append(out, u.Name)
+	}
+	return out
+}
+
+func TestIsolation(t *testing.T) {
+	h := routes(newTestStore())
+	wile := create(t, h, "acme", "Wile")
+	create(t, h, "acme", "Road Runner")
+	hank := create(t, h, "globex", "Hank")
+	create(t, h, "", "Guest")
+
+	lists := map[string][]string{
+		"acme":        {"Wile", "Road Runner"},
+		"globex":      {"Hank"},
+		"":            {"Guest"},
+		DefaultTenant: {"Guest"},
+		SystemTenant:  {},
+	}
+	for tenant, want := range lists {
+		if got := names(t, h, "/api/users", tenant, ""); !slices.Equal(got, want) {
+			t.Errorf("%q sees %v, want %v", tenant, got, want)
+		}
+	}
+
+	if resp := call(h, "GET", fmt.Sprint("/api/users/", wile.ID), "acme", "", ""); resp.code != http.StatusOK || !strings.Contains(resp.body, "Wile") {
+		t.Errorf("own user: %+v", resp)
+	}
+	if resp := call(h, "GET", fmt.Sprint("/api/users/", hank.ID), "acme", "", ""); resp.code != http.StatusNotFound {
+		t.Errorf("acme reads globex's user: %+v", resp)
+	}
+}
+
+// another tenant's user must look exactly like one that doesn't exist
+func TestOtherTenantIs404(t *testing.T) {
+	h := routes(newTestStore())
+	wile := create(t, h, "acme", "Wile")
+	create(t, h, "globex", "Hank")
+
+	foreign := call(h, "GET", fmt.Sprint("/api/users/", wile.ID), "globex", "", "")
+	missing := call(h, "GET", "/api/users/999", "globex", "", "")
+	if foreign.code != http.StatusNotFound {
+		t.Fatalf("another tenant's user: %+v, want 404", foreign)
+	}
+	if foreign != missing {
+		t.Errorf("a foreign user %+v differs from a missing one %+v", foreign, missing)
+	}
+	// an admin of a normal tenant is no different
+	if resp := call(h, "GET", fmt.Sprint("/api/users/", wile.ID), "globex", "admin", ""); resp.code != http.StatusNotFound {
+		t.Errorf("globex admin: %+v", resp)
+	}
+	if resp := call(h, "GET", "/api/users/abc", "acme", "", ""); resp.code != http.StatusNotFound {
+		t.Errorf("bad id: %+v", resp)
+	}
+}
+
+func TestSystemOverride(t *testing.T) {
+	store := newTestStore()
+	h := routes(store)
+	wile := create(t, h, "acme", "Wile")
+
+	if got := names(t, h, "/api/users?tenant=acme", SystemTenant, "admin"); !slices.Equal(got, []string{"Wile"}) {
+		t.Errorf("system admin lists acme: %v", got)
+	}
+	if resp := call(h, "GET", fmt.Sprint("/api/users/", wile.ID, "?tenant=acme"), SystemTenant, "admin", ""); resp.code != http.StatusOK {
+		t.Errorf("system admin gets acme's user: %+v", resp)
+	}
+	if resp := call(h, "POST", "/api/users?tenant=globex", SystemTenant, "admin", `{"name":"Support"}`); resp.code != http.StatusCreated {
+		t.Fatalf("system admin creates in globex: %+v", resp)
+	}
+	if got := names(t, h, "/api/users", "globex", ""); !slices.Equal(got, []string{"Support"}) {
+		t.Errorf("globex after the override: %v", got)
+	}
+	if got := names(t, h, "/api/users", SystemTenant, "admin"); len(got) != 0 {
+		t.Errorf("the override wrote into system: %v", got)
+	}
+
+	tests := []struct {
+		name, target, tenant, role string
+		code                       int
+	}{
+		{"system user", "/api/users?tenant=acme", SystemTenant, "user", http.StatusForbidden},
+		{"system, no role", "/api/users?tenant=acme", SystemTenant, "", http.StatusForbidden},
+		{"admin of another tenant", "/api/users?tenant=acme", "globex", "admin", http.StatusForbidden},
+		{"default tenant admin", "/api/users?tenant=acme", "", "admin", http.StatusForbidden},
+		{"unknown target", "/api/users?tenant=nope", SystemTenant, "admin", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if resp := call(h, "GET", tt.target, tt.tenant, tt.role, ""); resp.code != tt.code {
+			t.Errorf("%s: %+v, want %d", tt.name, resp, tt.code)
+		}
+	}
+}
+
+func TestUnknownTenant(t *testing.T) {
+	h := routes(newTestStore())
+	for _, tenant := range []string{"initech", "../acme", "ACME", "acme ", "-acme", strings.Repeat("a", 33)} {
+		if resp := call(h, "GET", "/api/users", tenant, "", ""); resp.code != http.StatusBadRequest || !strings.Contains(resp.body, "unknown tenant") {
+			t.Errorf("%q: %+v", tenant, resp)
+		}
+	}
+	if resp := call(h, "POST", "/api/users", "initech", "", `{"name":"X"}`); resp.code != http.StatusBadRequest {
+		t.Errorf("create in an unknown tenant: %+v", resp)
+	}
+	// a valid-looking ID that isn't registered is still unknown
+	store := NewUserStore("acme")
+	if resp := call(routes(store), "GET", "/api/users", "", "", ""); resp.code != http.StatusBadRequest {
+		t.Errorf("default tenant not registered: %+v", resp)
+	}
+	if _, err := store.Create("initech", "initech", "X"); !errors.Is(err, ErrUnknownTenant) {
+		t.Errorf("store.Create: %v", err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	store := newTestStore()
+	h := routes(store)
+	create(t, h, "acme", "Wile")
+	call(h, "POST", "/api/users?tenant=globex", SystemTenant, "admin", `{"name":"Support"}`)
+	call(h, "POST", "/api/users", "acme", "", `{"name":" "}`) // rejected, not audited
+
+	want := []AuditEntry{
+		{Tenant: "acme", By: "acme", Action: "create", UserID: 1},
+		{Tenant: "globex", By: SystemTenant, Action: "create", UserID: 2},
+	}
+	got := store.Audit()
+	if !slices.Equal(got, want) {
+		t.Errorf("audit %+v, want %+v", got, want)
+	}
+	got[0].Tenant = "changed"
+	if store.Audit()[0].Tenant != "acme" {
+		t.Error("Audit returned the store's own slice")
+	}
+}
+
+func TestConcurrentTenants(t *testing.T) {
+	store := newTestStore()
+	h := routes(store)
+	tenants := []string{"acme", "globex", DefaultTenant}
+	var wg sync.WaitGroup
+	for _, tenant := range tenants {
+		for range 10 {
+			wg.Go(func() {
+				for i := range 10 {
+					call(h, "POST", "/api/users", tenant, "", fmt.Sprintf(`{"name":"%s-%d"}`, tenant, i))
+					call(h, "GET", "/api/users", tenant, "", "")
+				}
+			})
+		}
+	}
+	wg.Wait()
+	ids := map[int]bool{}
+	for _, tenant := range tenants {
+		users := store.List(tenant)
+		if len(users) != 100 {
+			t.Errorf("%s has %d users", tenant, len(users))
+		}
+		for _, u := range users {
+			if !strings.HasPrefix(u.Name, tenant+"-") || ids[u.ID] {
+				t.Errorf("%s: %+v", tenant, u)
+			}
+			ids[u.ID] = true
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler tests repeat the same five lines every time: build a request, maybe add a token,
+// record the response, check the status, decode the JSON. A few helpers make each test
+// one line per step, and the failure messages better than what people write by hand.
+//
+// The helpers take a small TB interface instead of *testing.T: the tests pass their *testing.T,
+// and main below passes a fake one that prints, so the helpers can be shown outside go test.
+
+// TB is the part of testing.TB the helpers use
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Fatalf(format string, args ...any)
+}
+
+// ----------------------------------------------------------------------------
+// HS256 JWTs with the standard library: base64url(header).base64url(claims).base64url(hmac)
+
+var (
+	ErrBadToken     = errors.New("malformed token")
+	ErrBadSignature = errors.New("bad token signature")
+	ErrExpired      = errors.New("token expired")
+)
+
+type Claims struct {
+	Subject string `json:"sub"`
+	Role    string `json:"role"`
+	Expires int64  `json:"exp"` // Unix seconds
+}
+
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func SignJWT(secret []byte, c Claims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	signed := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyJWT checks the signature before looking at the claims. The header must be exactly
+// ours: accepting whatever "alg" the token names is how "alg":"none" attacks work.
+func VerifyJWT(secret []byte, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return Claims{}, ErrBadToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrBadToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return Claims{}, ErrBadSignature
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrBadToken
+	}
+	var c Claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return Claims{}, ErrBadToken
+	}
+	if now.Unix() >= c.Expires {
+		return Claims{}, ErrExpired
+	}
+	return c, nil
+}
+
+// ----------------------------------------------------------------------------
+// The API under test
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type API struct {
+	secret []byte
+	now    func() time.Time
+
+	mu    sync.Mutex
+	users []User
+}
+
+func NewAPI(secret []byte) *API {
+	return &API{secret: secret, now: time.Now, users: []User{
+		{ID: 1, Name: "Rishabh", Role: "admin"},
+		{ID: 2, Name: "Gopher", Role: "user"},
+	}}
+}
+
+func (a *API) Routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /me", a.auth("", http.HandlerFunc(a.me)))
+	mux.Handle("GET /users", a.auth("", http.HandlerFunc(a.listUsers)))
+	mux.Handle("POST /users", a.auth("admin", http.HandlerFunc(a.createUser)))
+	return mux
+}
+
+type claimsKey struct{}
+
+// auth checks the bearer token, and the role when role isn't empty
+func (a *API) auth(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing bearer token"})
+			return
+		}
+		c, err := VerifyJWT(a.secret, token, a.now())
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		if role != "" && c.Role != role {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "needs role " + role})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, c)))
+	})
+}
+
+func (a *API) me(w http.ResponseWriter, r *http.Request) {
+	c := r.Context().Value(claimsKey{}).(Claims)
+	writeJSON(w, http.StatusOK, map[string]string{"user": c.Subject, "role": c.Role})
+}
+
+func (a *API) listUsers(w http.ResponseWriter, r *http.Request) {
+	role := r.URL.Query().Get("role")
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := []User{}
+	for _, u := range a.users {
+		if role == "" || u.Role == role {
+			out = append(out, u)
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *API) createUser(w http.ResponseWriter, r *http.Request) {
+	var u User
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil || u.Name == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "want a JSON user with a name"})
+		return
+	}
+	if u.Role == "" {
+		u.Role = "user"
+	}
+	a.mu.Lock()
+	u.ID = len(a.users) + 1
+	a.users = append(a.users, u)
+	a.mu.Unlock()
+	w.Header().Set("Location", fmt.Sprintf("/users/%d", u.ID))
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ----------------------------------------------------------------------------
+// The helpers
+
+// TestSecret signs the tokens of NewAuthenticatedRequest and AsRole, give it to the API under test
+var TestSecret = []byte("test-secret, never used outside tests")
+
+// ReqOption changes the request before it's sent. Options apply in the order given,
+// so a later one wins: AsRole("user") then WithHeader("Authorization", "") sends no token.
+type ReqOption func(*http.Request)
+
+func WithHeader(name, value string) ReqOption {
+	return func(r *http.Request) { r.Header.Set(name, value) }
+}
+
+func WithToken(token string) ReqOption {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// AsRole adds a valid token for a test user with role
+func AsRole(role string) ReqOption {
+	return WithToken(mustTestToken(role))
+}
+
+// WithQuery adds a query parameter, keeping the ones already in the path
+func WithQuery(key, value string) ReqOption {
+	return func(r *http.Request) {
+		q := r.URL.Query()
+		q.Add(key, value)
+		r.URL.RawQuery = q.Encode()
+	}
+}
+
+func mustTestToken(role string) string {
+	token, err := SignJWT(TestSecret, Claims{Subject: "test-" + role, Role: role, Expires: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		panic(err) // Claims always marshals
+	}
+	return token
+}
+
+// NewAuthenticatedRequest is httptest.NewRequest with a valid token for role
+func NewAuthenticatedRequest(role, method, path string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, path, body)
+	AsRole(role)(r)
+	return r
+}
+
+// Response is what DoRequest recorded
+type Response struct {
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+}
+
+func (r *Response) Header(name string) string { return r.Headers.Get(name) }
+
+// JSON decodes the body into target and stops the test if it can't,
+// showing the body: "invalid character '<'" alone doesn't say the server sent HTML
+func (r *Response) JSON(t TB, target any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, target); err != nil {
+		t.Fatalf("decode response (status %d) into %T: %v\nbody: %s", r.StatusCode, target, err, r.Body)
+	}
+}
+
+// DoRequest sends one request to h and records the answer. body can be nil, a string
+// or []byte sent as is, an io.Reader, or any other value, which is sent as JSON.
+func DoRequest(h http.Handler, method, path string, body any, opts ...ReqOption) *Response {
+	var reader io.Reader
+	isJSON := false
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			panic(fmt.Sprintf("DoRequest: body %T doesn't marshal: %v", body, err)) // a bug in the test
+		}
+		reader, isJSON = bytes.NewReader(data), true
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if isJSON {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return &Response{StatusCode: rec.Code, Headers: rec.Header(), Body: rec.Body.Bytes()}
+}
+
+// AssertJSONEqual compares two JSON documents by value: key order and spacing don't matter.
+// On a mismatch it shows both documents indented and a line diff between them.
+func AssertJSONEqual(t TB, want, got string) {
+	t.Helper()
+	w, err := indentJSON(want)
+	if err != nil {
+		t.Fatalf("AssertJSONEqual: want is not JSON: %v\n%s", err, want)
+		return
+	}
+	g, err := indentJSON(got)
+	if err != nil {
+		t.Fatalf("AssertJSONEqual: got is not JSON: %v\n%s", err, got)
+		return
+	}
+	if w != g {
+		t.Errorf("JSON differs (-want +got):\n%s", lineDiff(w, g))
+	}
+}
+
+// indentJSON decodes and re-encodes s: maps come out with sorted keys, so equal values give equal text
+func indentJSON(s string) (string, error) {
+	var v any
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber() // 1 and 1.0 stay as written, no float rounding of big IDs
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	if dec.More() {
+		return "", errors.New("more than one JSON value")
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	return string(out), err
+}
+
+// lineDiff shows the lines of a and b with "-" (only in a), "+" (only in b) or " " (both),
+// using the longest common subsequence of lines
+func lineDiff(a, b string) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	// lcs[i][j] = length of the LCS of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			sb.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			sb.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			sb.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return sb.String()
+}
+
+// ----------------------------------------------------------------------------
+// A stand-in for *testing.T, so main can show what the helpers print
+
+type demoT struct {
+	name   string
+	failed bool
+}
+
+func (t *demoT) Helper() {}
+
+func (t *demoT) Errorf(format string, args ...any) {
+	t.failed = true
+	fmt.Printf("    %s: %s\n", t.name, indent(fmt.Sprintf(format, args...)))
+}
+
+// Fatalf stops the "test" the way testing does: runtime.Goexit ends the goroutine, deferred calls still run
+func (t *demoT) Fatalf(format string, args ...any) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func indent(s string) string { return strings.ReplaceAll(strings.TrimRight(s, "\n"), "\n", "\n      ") }
+
+// run calls fn in its own goroutine like go test does, so Fatalf can stop it
+func run(name string, fn func(t TB)) {
+	t := &demoT{name: name}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(t)
+	}()
+	<-done
+	status := "PASS"
+	if t.failed {
+		status = "FAIL"
+	}
+	fmt.Printf("  --- %s: %s\n", status, name)
+}
+
+func main() {
+	fmt.Println("Learning handler test helpers in Go")
+
+	api := NewAPI(TestSecret)
+	h := api.Routes()
+
+	fmt.Println("\nWithout helpers, every test starts like this:")
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+mustTestToken("admin"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	fmt.Printf("  %d %s", rec.Code, rec.Body.String())
+
+	fmt.Println("\nWith them:")
+	resp := DoRequest(h, http.MethodGet, "/me", nil, AsRole("admin"))
+	fmt.Printf("  %d %s", resp.StatusCode, resp.Body)
+	resp = DoRequest(h, http.MethodGet, "/users", nil, AsRole("user"), WithQuery("role", "admin"))
+	fmt.Printf("  %d %s", resp.StatusCode, resp.Body)
+	resp = DoRequest(h, http.MethodPost, "/users", map[string]string{"name": "Ada"}, AsRole("admin"))
+	fmt.Printf("  %d Location %s %s", resp.StatusCode, resp.Header("Location"), resp.Body)
+	resp = DoRequest(h, http.MethodPost, "/users", map[string]string{"name": "Eve"}, AsRole("user"))
+	fmt.Printf("  %d %s", resp.StatusCode, resp.Body)
+	resp = DoRequest(h, http.MethodGet, "/me", nil, WithToken("not.a.jwt"))
+	fmt.Printf("  %d %s", resp.StatusCode, resp.Body)
+	resp = DoRequest(h, http.MethodGet, "/me", nil, AsRole("admin"), WithHeader("Authorization", ""))
+	fmt.Printf("  %d %s", resp.StatusCode, resp.Body)
+	fmt.Println("  (options apply in order: the empty Authorization header came last and won)")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, NewAuthenticatedRequest("user", http.MethodGet, "/me", nil))
+	fmt.Printf("  NewAuthenticatedRequest: %d %s", rec.Code, rec.Body.String())
+
+	// a token signed with another secret, or changed after signing
+	other, _ := SignJWT([]byte("someone else's secret"), Claims{Subject: "mallory", Role: "admin", Expires: time.Now().Add(time.Hour).Unix()})
+	_, err := VerifyJWT(TestSecret, other, time.Now())
+	fmt.Println("  another secret:", err)
+	expired, _ := SignJWT(TestSecret, Claims{Subject: "old", Role: "user", Expires: time.Now().Add(-time.Minute).Unix()})
+	_, err = VerifyJWT(TestSecret, expired, time.Now())
+	fmt.Println("  expired:", err)
+
+	fmt.Println("\nWhat failures look like:")
+	run("TestCreateUser", func(t TB) {
+		resp := DoRequest(h, http.MethodPost, "/users", map[string]string{"name": "Grace", "role": "admin"}, AsRole("admin"))
+		AssertJSONEqual(t, `{"id": 4, "name": "Grace", "role": "admin"}`, string(resp.Body)) // key order doesn't matter
+	})
+	run("TestListAdmins", func(t TB) {
+		resp := DoRequest(h, http.MethodGet, "/users?role=admin", nil, AsRole("user"))
+		AssertJSONEqual(t, `[{"id": 1, "name": "Rishabh", "role": "admin"}, {"id": 3, "name": "Ada", "role": "admin"}]`, string(resp.Body))
+	})
+	run("TestDecodeHTML", func(t TB) {
+		resp := &Response{StatusCode: 502, Body: []byte("<html>Bad Gateway</html>")}
+		var u User
+		resp.JSON(t, &u)
+		fmt.Println("    never printed: Fatalf stopped the test")
+	})
+}
+
+// Build test helpers on httptest: NewRequest + NewRecorder call the handler directly, no server, no port.
+// Call t.Helper() first, so a failure points at the test's line and not at the helper.
+// Functional options (WithHeader, AsRole) keep one DoRequest for every case; they apply in order.
+// Compare JSON by value and print a diff; "not equal" with two 2 KB strings helps nobody.
+// Sign real tokens with a test secret instead of turning auth off in tests: the middleware gets tested too.
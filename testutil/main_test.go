@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTB records what a helper reported. Fatalf ends the goroutine like testing does,
+// so helpers are called through call.
+type fakeTB struct {
+	errors []string
+	fatal  bool
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+	f.fatal = true
+	runtime.Goexit()
+}
+
+func call(fn func(tb TB)) *fakeTB {
+	f := &fakeTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(f)
+	}()
+	<-done
+	return f
+}
+
+func b64(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+func TestJWT(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	claims := Claims{Subject: "rishabh", Role: "user", Expires: now.Add(time.Minute).Unix()}
+	token, err := SignJWT(TestSecret, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := VerifyJWT(TestSecret, token, now); err != nil || got != claims {
+		t.Fatalf("VerifyJWT = %+v, %v", got, err)
+	}
+	if !strings.HasPrefix(token, b64(`{"alg":"HS256","typ":"JWT"}`)+".") {
+		t.Errorf("header of %s", token)
+	}
+	other, _ := SignJWT([]byte("another secret"), claims)
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + b64(`{"sub":"rishabh","role":"admin","exp":9999999999}`) + "." + parts[2]
+	tests := []struct {
+		name  string
+		token string
+		now   time.Time
+		want  error
+	}{
+		{"another secret", other, now, ErrBadSignature},
+		{"claims changed after signing", forged, now, ErrBadSignature},
+		{"alg none", b64(`{"alg":"none","typ":"JWT"}`) + "." + parts[1] + ".", now, ErrBadToken},
+		{"two parts", parts[0] + "." + parts[1], now, ErrBadToken},
+		{"signature not base64", parts[0] + "." + parts[1] + ".!!!", now, ErrBadToken},
+		{"expired", token, now.Add(time.Minute), ErrExpired},
+		{"empty", "", now, ErrBadToken},
+	}
+	for _, tt := range tests {
+		if _, err := VerifyJWT(TestSecret, tt.token, tt.now); err != tt.want {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// the API's own tests, written with the helpers
+func TestAPI(t *testing.T) {
+	h := NewAPI(TestSecret).Routes()
+
+	resp := DoRequest(h, http.MethodGet, "/me", nil, AsRole("admin"))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /me: %d %s", resp.StatusCode, resp.Body)
+	}
+	AssertJSONEqual(t, `{"user": "test-admin", "role": "admin"}`, string(resp.Body))
+
+	resp = DoRequest(h, http.MethodPost, "/users", User{Name: "Ada"}, AsRole("admin"))
+	var created User
+	resp.JSON(t, &created)
+	if resp.StatusCode != http.StatusCreated || resp.Header("Location") != "/users/3" || created != (User{3, "Ada", "user"}) {
+		t.Errorf("POST /users: %d %q %+v", resp.StatusCode, resp.Header("Location"), created)
+	}
+
+	resp = DoRequest(h, http.MethodGet, "/users", nil, AsRole("user"), WithQuery("role", "user"))
+	AssertJSONEqual(t, `[{"id":2,"name":"Gopher","role":"user"},{"id":3,"name":"Ada","role":"user"}]`, string(resp.Body))
+
+	tests := []struct {
+		name   string
+		method string
+		body   any
+		opts   []ReqOption
+		status int
+		err    string
+	}{
+		{"no token", http.MethodGet, nil, nil, http.StatusUnauthorized, "missing bearer token"},
+		{"garbage token", http.MethodGet, nil, []ReqOption{WithToken("x.y.z")}, http.StatusUnauthorized, "malformed token"},
+		{"not an admin", http.MethodPost, User{Name: "Eve"}, []ReqOption{AsRole("user")}, http.StatusForbidden, "needs role admin"},
+		{"no name", http.MethodPost, User{}, []ReqOption{AsRole("admin")}, http.StatusBadRequest, "want a JSON user with a name"},
+		{"not JSON", http.MethodPost, "name=Eve", []ReqOption{AsRole("admin")}, http.StatusBadRequest, "want a JSON user with a name"},
+	}
+	for _, tt := range tests {
+		resp := DoRequest(h, tt.method, "/users", tt.body, tt.opts...)
+		var body struct{ Error string }
+		resp.JSON(t, &body)
+		if resp.StatusCode != tt.status || body.Error != tt.err {
+			t.Errorf("%s: %d %q, want %d %q", tt.name, resp.StatusCode, body.Error, tt.status, tt.err)
+		}
+	}
+}
+
+func TestNewAuthenticatedRequest(t *testing.T) {
+	r := NewAuthenticatedRequest("user", http.MethodGet, "/me", nil)
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		t.Fatalf("Authorization = %q", r.Header.Get("Authorization"))
+	}
+	c, err := VerifyJWT(TestSecret, token, time.Now())
+	if err != nil || c.Role != "user" || c.Subject != "test-user" {
+		t.Errorf("%+v, %v", c, err)
+	}
+}
+
+// echo answers with what it received, to see what DoRequest sent
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("X-Seen-Query", r.URL.RawQuery)
+	w.Header().Set("X-Seen-Auth", r.Header.Get("Authorization"))
+	w.Header().Set("X-Seen-Type", r.Header.Get("Content-Type"))
+	w.Header().Set("X-Seen-Custom", r.Header.Get("X-Custom"))
+	w.WriteHeader(http.StatusTeapot)
+	w.Write(body)
+})
+
+func TestDoRequestBodies(t *testing.T) {
+	tests := []struct {
+		body     any
+		want     string
+		wantType string
+	}{
+		{nil, "", ""},
+		{"raw text", "raw text", ""},
+		{[]byte("raw bytes"), "raw bytes", ""},
+		{strings.NewReader("a reader"), "a reader", ""},
+		{map[string]int{"n": 1}, `{"n":1}`, "application/json"},
+		{User{ID: 1, Name: "A"}, `{"id":1,"name":"A","role":""}`, "application/json"},
+	}
+	for _, tt := range tests {
+		resp := DoRequest(echo, http.MethodPost, "/", tt.body)
+		if string(resp.Body) != tt.want || resp.Header("X-Seen-Type") != tt.wantType || resp.StatusCode != http.StatusTeapot {
+			t.Errorf("%T: %d %q %q", tt.body, resp.StatusCode, resp.Body, resp.Header("X-Seen-Type"))
+		}
+	}
+}
+
+func TestOptionOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []ReqOption
+		header string
+		want   string
+	}{
+		{"token then empty header", []ReqOption{AsRole("admin"), WithHeader("Authorization", "")}, "X-Seen-Auth", ""},
+		{"header then token", []ReqOption{WithHeader("Authorization", "Basic x"), WithToken("t")}, "X-Seen-Auth", "Bearer t"},
+		{"same header twice", []ReqOption{WithHeader("X-Custom", "1"), WithHeader("X-Custom", "2")}, "X-Seen-Custom", "2"},
+		{"header overrides the JSON type", []ReqOption{WithHeader("Content-Type", "text/plain")}, "X-Seen-Type", "text/plain"},
+		{"queries add up", []ReqOption{WithQuery("b", "2"), WithQuery("a", "1"), WithQuery("b", "3")}, "X-Seen-Query", "a=1&b=2&b=3&page=1"},
+		{"query escaping", []ReqOption{WithQuery("q", "a b&c")}, "X-Seen-Query", "page=1&q=a+b%26c"},
+	}
+	for _, tt := range tests {
+		resp := DoRequest(echo, http.MethodPost, "/?page=1", struct{}{}, tt.opts...)
+		if got := resp.Header(tt.header); got != tt.want {
+			t.Errorf("%s: %s = %q, want %q", tt.name, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestResponseJSON(t *testing.T) {
+	var u User
+	f := call(func(tb TB) { (&Response{Body: []byte(`{"id":7,"name":"x"}`)}).JSON(tb, &u) })
+	if len(f.errors) != 0 || u.ID != 7 {
+		t.Errorf("%+v, %v", u, f.errors)
+	}
+	f = call(func(tb TB) {
+		(&Response{StatusCode: 502, Body: []byte("<html>Bad Gateway</html>")}).JSON(tb, &u)
+		tb.Errorf("still running after Fatalf")
+	})
+	want := "decode response (status 502) into *main.User: invalid character '<' looking for beginning of value\nbody: <html>Bad Gateway</html>"
+	if !f.fatal || len(f.errors) != 1 || f.errors[0] != want {
+		t.Errorf("got %q", f.errors)
+	}
+}
+
+func TestAssertJSONEqual(t *testing.T) {
+	same := [][2]string{
+		{`{"a":1,"b":[1,2]}`, `{"b": [1, 2], "a": 1}`},
+		{`[]`, ` [ ] `},
+		{`{"nested":{"x":null,"y":true}}`, "{\n\"nested\": {\"y\": true, \"x\": null}}"},
+		{`12345678901234567890`, `12345678901234567890`},
+	}
+	for _, pair := range same {
+		if f := call(func(tb TB) { AssertJSONEqual(tb, pair[0], pair[1]) }); len(f.errors) != 0 {
+			t.Errorf("%s vs %s: %v", pair[0], pair[1], f.errors)
+		}
+	}
+	different := [][2]string{
+		{`{"a":1}`, `{"a":2}`},
+		{`[1,2]`, `[2,1]`},
+		{`{"a":1}`, `{"a":1,"b":null}`},
+		{`1`, `1.0`}, // compared as written, not as float64
+		{`12345678901234567890`, `12345678901234567891`},
+		{`"1"`, `1`},
+	}
+	for _, pair := range different {
+		if f := call(func(tb TB) { AssertJSONEqual(tb, pair[0], pair[1]) }); len(f.errors) != 1 || f.fatal {
+			t.Errorf("%s vs %s: %v", pair[0], pair[1], f.errors)
+		}
+	}
+}
+
+func TestAssertJSONEqualDiff(t *testing.T) {
+	f := call(func(tb TB) {
+		AssertJSONEqual(tb, `{"id":1,"name":"Ada","tags":["a","b"]}`, `{"tags":["a","c"],"id":1,"name":"Ada","extra":true}`)
+	})
+	want := `JSON differs (-want +got):
+  {
++   "extra": true,
+    "id": 1,
+    "name": "Ada",
+    "tags": [
+      "a",
+-     "b"
++     "c"
+    ]
+  }
+`
+	if len(f.errors) != 1 || f.errors[0] != want {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(f.errors, "\n"), want)
+	}
+}
+
+func TestAssertJSONEqualInvalid(t *testing.T) {
+	for _, pair := range [][2]string{{`{`, `{}`}, {`{}`, `not json`}, {`{}`, `{} {}`}, {``, `{}`}} {
+		f := call(func(tb TB) {
+			AssertJSONEqual(tb, pair[0], pair[1])
+			tb.Errorf("still running after Fatalf")
+		})
+		if !f.fatal || len(f.errors) != 1 || !strings.Contains(f.errors[0], "is not JSON") {
+			t.Errorf("%q vs %q: %q", pair[0], pair[1], f.errors)
+		}
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"x", "x", "  x\n"},
+		{"a\nb\nc", "a\nc", "  a\n- b\n  c\n"},
+		{"a\nc", "a\nb\nc", "  a\n+ b\n  c\n"},
+		{"a\nb", "c\nd", "- a\n- b\n+ c\n+ d\n"},
+		{"", "a", "- \n+ a\n"},
+	}
+	for _, tt := range tests {
+		if got := lineDiff(tt.a, tt.b); got != tt.want {
+			t.Errorf("lineDiff(%q, %q) =\n%s\nwant\n%s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
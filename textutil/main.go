@@ -0,0 +1,439 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strings typed by users end up in URLs, file names and logs, and each place has its own rules:
+//   - a URL slug: "Zoë O'Brien" -> "zoe-o-brien", lowercase ASCII, hyphens, nothing to escape
+//   - a file name: no "/", no "..", no control characters, no CON or NUL on Windows
+//   - a log line or a name on screen: no control characters, no "\x1b[2J" or right-to-left tricks
+//
+// The standard library has no Unicode normalization (golang.org/x/text does), so Slugify
+// transliterates from a table of the common Latin letters. Anything it doesn't know is dropped.
+
+// translit maps lowercase letters to ASCII. Letters with a plain accent (é, ñ, ü...) are
+// listed too, since "é" usually arrives as one rune, not as "e" plus a combining accent.
+var translit = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ą': "a", 'æ': "ae",
+	'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+const maxSlugLen = 60
+
+// Slugify lowercases s, transliterates what it can, and joins the letters and digits
+// with single hyphens. The result may be empty: "🦫" or "रिषभ" have nothing to keep.
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false // a separator is pending, written only before the next letter
+	for _, r := range strings.ToLower(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue // a combining accent: "e" + U+0301 is just "e"
+		}
+		out, ok := translit[r]
+		if !ok && r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			out, ok = string(r), true
+		}
+		if !ok {
+			hyphen = b.Len() > 0
+			continue
+		}
+		if hyphen {
+			b.WriteByte('-')
+			hyphen = false
+		}
+		b.WriteString(out)
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLen {
+		slug = slug[:maxSlugLen]
+		if i := strings.LastIndexByte(slug, '-'); i > maxSlugLen/2 {
+			slug = slug[:i] // cut at a word boundary when there is one not too far back
+		}
+		slug = strings.TrimRight(slug, "-")
+	}
+	return slug
+}
+
+// isBidiControl: U+202E turns "invoice\u202efdp.exe" into "invoiceexe.pdf" on screen
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069')
+}
+
+// StripControlChars removes control characters (newlines and tabs too: this is for one-line
+// strings like names), bidi overrides and invalid UTF-8. Zero-width joiners stay, emoji need them.
+func StripControlChars(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || isBidiControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+var windowsReserved = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "lpt1": true, "lpt2": true, "lpt3": true,
+}
+
+const maxFilenameBytes = 200 // below the usual 255, room for a "-2" or a ".tmp"
+
+// SanitizeFilename makes s safe as ONE path element of a storage key: it can't climb out
+// of its directory, hide itself, or be a device name. Letters in any script are kept.
+func SanitizeFilename(s string) string {
+	s = StripControlChars(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_':
+			return r // marks too: the vowel signs in "रिषभ" are part of the word
+		case unicode.IsSpace(r):
+			return '_'
+		}
+		return '_' // "/", "\", ":", "*", "?", quotes, <, >, | and the rest
+	}, s)
+	s = strings.Trim(s, "._") // no "..", no hidden ".env", no trailing dot (Windows drops it)
+	for strings.Contains(s, "__") {
+		s = strings.ReplaceAll(s, "__", "_")
+	}
+	base, ext := s, filepath.Ext(s)
+	base = strings.TrimSuffix(base, ext)
+	if windowsReserved[strings.ToLower(base)] {
+		base = "_" + base
+	}
+	if len(base)+len(ext) > maxFilenameBytes {
+		if len(ext) > 16 {
+			base, ext = base+ext, "" // not a real extension, don't let it eat the whole name
+		}
+		base = strings.ToValidUTF8(base[:maxFilenameBytes-len(ext)], "") // the cut may split a rune
+	}
+	if base == "" {
+		base = "file"
+	}
+	return base + ext
+}
+
+// ----------------------------------------------------------------------------
+// Aligned tables: text/tabwriter for the terminal, or Markdown for a README
+
+// Table collects rows and renders them as aligned plain text or as a Markdown table
+type Table struct {
+	headers  []string
+	rows     [][]string
+	MaxWidth int  // longer cells are cut and end with "…", 0 = no limit
+	Markdown bool // render a GitHub Markdown table instead of plain columns
+}
+
+func (t *Table) SetHeaders(headers ...string) {
+	t.headers = headers
+}
+
+// AddRow adds one row, any value is formatted with %v
+func (t *Table) AddRow(values ...any) {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = fmt.Sprint(v)
+	}
+	t.rows = append(t.rows, row)
+}
+
+// truncate shortens s to limit runes (not bytes, so multi-byte characters are never cut in half)
+func truncate(s string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	if limit == 1 {
+		return "…"
+	}
+	runes := []rune(s)
+	return string(runes[:limit-1]) + "…"
+}
+
+// cells returns the header and rows, truncated and padded to the same number of columns
+func (t *Table) cells() [][]string {
+	cols := len(t.headers)
+	for _, row := range t.rows {
+		cols = max(cols, len(row))
+	}
+	var all [][]string
+	if len(t.headers) > 0 {
+		all = append(all, t.headers)
+	}
+	all = append(all, t.rows...)
+
+	out := make([][]string, len(all))
+	for i, row := range all {
+		out[i] = make([]string, cols) // short rows get empty cells
+		for j, cell := range row {
+			// tabs and newlines would break the layout
+			cell = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+			out[i][j] = truncate(cell, t.MaxWidth)
+		}
+	}
+	return out
+}
+
+// Render writes the table to w
+func (t *Table) Render(w io.Writer) error {
+	cells := t.cells()
+	if t.Markdown {
+		return t.renderMarkdown(w, cells)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, row := range cells {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+		if i == 0 && len(t.headers) > 0 {
+			dashes := make([]string, len(row))
+			for j, h := range row {
+				dashes[j] = strings.Repeat("-", max(utf8.RuneCountInString(h), 3))
+			}
+			fmt.Fprintln(tw, strings.Join(dashes, "\t"))
+		}
+	}
+	return tw.Flush()
+}
+
+func (t *Table) renderMarkdown(w io.Writer, cells [][]string) error {
+	if len(cells) == 0 {
+		return nil
+	}
+	escape := strings.NewReplacer("|", `\|`) // a | inside a cell would start a new column
+	writeRow := func(row []string) error {
+		escaped := make([]string, len(row))
+		for i, c := range row {
+			escaped[i] = escape.Replace(c)
+		}
+		_, err := fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | "))
+		return err
+	}
+	header := cells[0]
+	if len(t.headers) == 0 { // Markdown tables need a header row
+		header = make([]string, len(cells[0]))
+	} else {
+		cells = cells[1:]
+	}
+	if err := writeRow(header); err != nil {
+		return err
+	}
+	sep := make([]string, len(header))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	if err := writeRow(sep); err != nil {
+		return err
+	}
+	for _, row := range cells {
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Users with a slug
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"` // set once at creation, a rename doesn't break old links
+}
+
+type UserStore struct {
+	mu     sync.Mutex
+	users  map[int]User
+	slugs  map[string]int
+	nextID int
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[int]User{}, slugs: map[string]int{}, nextID: 1}
+}
+
+// Create picks the slug under the same lock that stores it: two "Alice" created at the same
+// moment can't both see "alice" as free.
+func (s *UserStore) Create(name string) User {
+	name = strings.TrimSpace(StripControlChars(name))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := User{ID: s.nextID, Name: name}
+	s.nextID++
+	base := Slugify(name)
+	if base == "" {
+		base = "user-" + strconv.Itoa(u.ID)
+	}
+	u.Slug = base
+	for n := 2; ; n++ {
+		if _, taken := s.slugs[u.Slug]; !taken {
+			break
+		}
+		u.Slug = base + "-" + strconv.Itoa(n) // may itself be taken by someone named "Alice 2", keep counting
+	}
+	s.users[u.ID] = u
+	s.slugs[u.Slug] = u.ID
+	return u
+}
+
+func (s *UserStore) BySlug(slug string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.slugs[slug]
+	return s.users[id], ok
+}
+
+func (s *UserStore) List() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func routes(s *UserStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		var in struct{ Name string }
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || strings.TrimSpace(in.Name) == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.Create(in.Name))
+	})
+	mux.HandleFunc("GET /api/users/by-slug/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		u, ok := s.BySlug(r.PathValue("slug"))
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such user"})
+			return
+		}
+		writeJSON(w, http.StatusOK, u)
+	})
+	return mux
+}
+
+func main() {
+	fmt.Println("Learning slugs and string sanitizing in Go")
+
+	fmt.Println("Slugify:")
+	for _, s := range []string{
+		"Rishabh Gupta", "  Zoë O'Brien  ", "Ærøskøbing Straße", "José́ Nuñez", "C++ & Go: 2025!",
+		"--already--a--slug--", "Łódź → Kraków", "रिषभ", "🦫🦫", "Rishabh 🚀 Gupta", "",
+		strings.Repeat("long name ", 7),
+	} {
+		fmt.Printf("  %-44q -> %q\n", s, Slugify(s))
+	}
+
+	fmt.Println("SanitizeFilename:")
+	for _, s := range []string{
+		"report 2025.pdf", "../../etc/passwd", ".env", "a/b\\c:d*e?.txt", "CON.txt", "résumé (final).docx",
+		"invoice\u202efdp.exe", "...", "name.\x00.sh", strings.Repeat("ab", 150) + ".txt",
+	} {
+		in, out := fmt.Sprintf("%q", s), fmt.Sprintf("%q", SanitizeFilename(s))
+		if len(s) > 40 {
+			in = fmt.Sprintf("%q (%d bytes)", s[:12], len(s))
+			out = fmt.Sprintf("%q (%d bytes)", SanitizeFilename(s)[:12], len(SanitizeFilename(s)))
+		}
+		fmt.Printf("  %-34s -> %s\n", in, out)
+	}
+
+	fmt.Println("StripControlChars:")
+	for _, s := range []string{"Alice\x1b[2J\x1b[H", "Bob\r\nINFO fake log line", "evil\u202egnp.exe", "family 👨\u200d👩\u200d👧", "bad \xff byte"} {
+		fmt.Printf("  %-30q -> %q\n", s, StripControlChars(s))
+	}
+
+	fmt.Println("Table:")
+	t := &Table{MaxWidth: 20}
+	t.SetHeaders("FILE", "BYTES", "NOTE")
+	t.AddRow("main.go", 1532, "entry point")
+	t.AddRow("internal/very/long/path/to/handler.go", 48211, "truncated to 20 runes")
+	t.AddRow("héllo_wörld.go", 7, "multi-byte | safe")
+	t.AddRow("short_row.go") // missing cells are left empty
+	if err := t.Render(os.Stdout); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	fmt.Println("Same table as Markdown:")
+	t.Markdown = true
+	if err := t.Render(os.Stdout); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	store := NewUserStore()
+	h := routes(store)
+	post := func(name string) User {
+		rec := httptest.NewRecorder()
+		body, _ := json.Marshal(map[string]string{"name": name})
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/users", strings.NewReader(string(body))))
+		var u User
+		json.Unmarshal(rec.Body.Bytes(), &u)
+		return u
+	}
+
+	fmt.Println("Slugs in the store:")
+	for _, n := range []string{"Alice", "Alice", "alice!", "Alice 2", "Alice", "रिषभ", "🦫"} {
+		u := post(n)
+		fmt.Printf("  %-10q -> id=%d slug=%s\n", n, u.ID, u.Slug)
+	}
+
+	// 50 goroutines create the same name: 50 different slugs, go run -race stays quiet
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			post("Rishabh Gupta")
+		}()
+	}
+	wg.Wait()
+	seen := map[string]bool{}
+	count := 0
+	for _, u := range store.List() {
+		if u.Name == "Rishabh Gupta" {
+			count++
+			seen[u.Slug] = true
+		}
+	}
+	fmt.Printf("50 concurrent \"Rishabh Gupta\": %d users, %d distinct slugs, rishabh-gupta-50 taken: %v\n",
+		count, len(seen), seen["rishabh-gupta-50"])
+
+	for _, slug := range []string{"alice-3", "user-6", "rishabh-gupta-17", "nobody"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/users/by-slug/"+slug, nil))
+		fmt.Printf("GET /api/users/by-slug/%-16s -> %d %s", slug, rec.Code, rec.Body.String())
+	}
+}
+
+// A slug is lowercase ASCII letters, digits and single hyphens, so it never needs escaping in a URL.
+// Transliterate what you can, drop what you can't, and fall back to the ID when nothing is left.
+// Pick the slug and store it under ONE lock, or two identical names created together get the same one.
+// A file name from a user: strip separators and control characters, trim dots, avoid device names, cap the length.
+// Strip control characters and bidi overrides from anything shown or logged, but keep the ZWJ emoji need.
+// Line up columns with text/tabwriter and count runes, not bytes, when cutting a cell to a width.
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// ----------------------------------------------------------------------------
+// Golden files: the expected output lives in testdata/<name>.golden, next to the test.
+// go test main.go main_test.go -update rewrites them, git diff then shows what changed.
+
+// golden compares output with the files in dir
+type golden struct {
+	dir    string
+	update bool
+	masks  []goldenMask
+}
+
+// goldenMask replaces what changes from run to run (times, durations) before comparing
+type goldenMask struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// Mask replaces every match of pattern with repl, in the output and in what is written on -update
+func (g *golden) Mask(pattern, repl string) *golden {
+	g.masks = append(g.masks, goldenMask{regexp.MustCompile(pattern), repl})
+	return g
+}
+
+// normalize turns CRLF into LF, so a checkout on Windows still matches, and applies the masks
+func (g *golden) normalize(b []byte) string {
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	for _, m := range g.masks {
+		s = m.re.ReplaceAllString(s, m.repl)
+	}
+	return s
+}
+
+// Assert compares got with dir/name.golden, or rewrites the file when update is set.
+// A mismatch is reported as a unified diff, not as two whole outputs.
+func (g *golden) Assert(t testing.TB, got []byte, name string) {
+	t.Helper()
+	path := filepath.Join(g.dir, name+".golden")
+	out := g.normalize(got)
+	if g.update {
+		if err := os.MkdirAll(g.dir, 0o755); err != nil {
+			t.Errorf("update %s: %v", path, err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+			t.Errorf("update %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("%v (run go test main.go main_test.go -update to create it)", err)
+		return
+	}
+	if w := g.normalize(want); w != out {
+		t.Errorf("output differs from %s (-update accepts it):\n%s", path, unifiedDiff(path, "got", w, out, 3))
+	}
+}
+
+// assertGolden checks got against testdata/name.golden
+func assertGolden(t *testing.T, got []byte, name string) {
+	t.Helper()
+	(&golden{dir: "testdata", update: *update}).Assert(t, got, name)
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' only in a, '+' only in b.
+// ai and bi count the lines of a and b before it, for the hunk headers.
+type diffOp struct {
+	kind   byte
+	text   string
+	ai, bi int
+}
+
+// diffLines finds a longest common subsequence of lines and returns the edit script, removals first
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff renders the changes from want to got like diff -u, with context lines around each change
+func unifiedDiff(nameA, nameB, want, got string, context int) string {
+	ops := diffLines(splitLines(want), splitLines(got))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", nameA, nameB)
+	for i := 0; i < len(ops); {
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+		// grow the hunk while at most 2*context unchanged lines separate it from the next change
+		last := i
+		for j := i; j < len(ops) && j-last-1 <= 2*context; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		start, end := max(i-context, 0), min(last+context+1, len(ops))
+		aCount, bCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aCount++
+			}
+			if op.kind != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ops[start].ai, aCount), hunkRange(ops[start].bi, bCount))
+		for _, op := range ops[start:end] {
+			line, ok := strings.CutSuffix(op.text, "\n")
+			sb.WriteString(string(op.kind) + line + "\n")
+			if !ok {
+				sb.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// splitLines keeps the "\n" on each line, so a missing one at the end shows up in the diff
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// hunkRange formats "start,count" with 1-based lines, an empty range points at the line before it
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprint(before + 1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// recorder catches what Assert reports. The embedded testing.TB is nil,
+// only the methods Assert calls are implemented.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestGoldenUpdate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "testdata") // doesn't exist yet
+	rec := &recorder{}
+	(&golden{dir: dir}).Assert(rec, []byte("hello\n"), "greeting")
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "-update to create it") {
+		t.Fatalf("missing file: %q", rec.errors)
+	}
+
+	rec = &recorder{}
+	(&golden{dir: dir, update: true}).Assert(rec, []byte("hello\r\nworld\n"), "greeting")
+	data, err := os.ReadFile(filepath.Join(dir, "greeting.golden"))
+	if err != nil || string(data) != "hello\nworld\n" || len(rec.errors) != 0 {
+		t.Fatalf("after -update: %q, %v, %q", data, err, rec.errors)
+	}
+	// -update never fails, even when the output changed
+	(&golden{dir: dir, update: true}).Assert(rec, []byte("bye\n"), "greeting")
+	(&golden{dir: dir}).Assert(rec, []byte("bye\n"), "greeting")
+	if len(rec.errors) != 0 {
+		t.Errorf("after the second -update: %q", rec.errors)
+	}
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "report.golden"), []byte("a\nb\nc\n"), 0o644)
+	rec := &recorder{}
+	(&golden{dir: dir}).Assert(rec, []byte("a\nB\nc\n"), "report")
+	want := "output differs from " + filepath.Join(dir, "report.golden") + ` (-update accepts it):
+--- ` + filepath.Join(dir, "report.golden") + `
++++ got
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`
+	if len(rec.errors) != 1 || rec.errors[0] != want {
+		t.Errorf("got %q\nwant %q", rec.errors, want)
+	}
+}
+
+func TestGoldenLineEndings(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "crlf.golden"), []byte("one\r\ntwo\r\n"), 0o644)
+	rec := &recorder{}
+	(&golden{dir: dir}).Assert(rec, []byte("one\ntwo\n"), "crlf")
+	(&golden{dir: dir}).Assert(rec, []byte("one\r\ntwo\r\n"), "crlf")
+	if len(rec.errors) != 0 {
+		t.Errorf("%q", rec.errors)
+	}
+}
+
+func TestGoldenMasks(t *testing.T) {
+	dir := t.TempDir()
+	g := func(update bool) *golden {
+		return (&golden{dir: dir, update: update}).
+			Mask(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d)`, "<TIME>").
+			Mask(`\b\d+(\.\d+)?(ns|µs|ms|s)\b`, "<DURATION>")
+	}
+	rec := &recorder{}
+	g(true).Assert(rec, []byte("started 2026-03-14T09:30:00Z, took 1.5ms\n"), "run")
+	data, _ := os.ReadFile(filepath.Join(dir, "run.golden"))
+	if string(data) != "started <TIME>, took <DURATION>\n" {
+		t.Errorf("written: %q", data)
+	}
+	g(false).Assert(rec, []byte("started 2027-01-02T03:04:05.123+05:30, took 12s\n"), "run")
+	if len(rec.errors) != 0 {
+		t.Errorf("another time and duration: %q", rec.errors)
+	}
+	g(false).Assert(rec, []byte("stopped 2027-01-02T03:04:05Z, took 12s\n"), "run")
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "-started <TIME>, took <DURATION>\n+stopped <TIME>, took <DURATION>\n") {
+		t.Errorf("a real change: %q", rec.errors)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int) string {
+		var sb strings.Builder
+		for i := from; i <= to; i++ {
+			fmt.Fprintf(&sb, "%d\n", i)
+		}
+		return sb.String()
+	}
+	// change replaces whole lines, strings.Replace would also hit "12" when replacing "2"
+	change := func(s string, repl map[string]string) string {
+		out := splitLines(s)
+		for i, l := range out {
+			if r, ok := repl[strings.TrimSuffix(l, "\n")]; ok {
+				out[i] = r + "\n"
+			}
+		}
+		return strings.Join(out, "")
+	}
+	tests := []struct {
+		name, want, got string
+		diff            string
+	}{
+		{"added at the end", "a\n", "a\nb\n", "@@ -1 +1,2 @@\n a\n+b\n"},
+		{"removed everything", "a\nb\n", "", "@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{"into an empty file", "", "a\n", "@@ -0,0 +1 @@\n+a\n"},
+		{"missing final newline", "a\n", "a", "@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n"},
+		{"context is cut", lines(1, 10), change(lines(1, 10), map[string]string{"5": "five"}),
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n"},
+		{"far apart: two hunks", lines(1, 20), change(lines(1, 20), map[string]string{"2": "x", "19": "y"}),
+			"@@ -1,5 +1,5 @@\n 1\n-2\n+x\n 3\n 4\n 5\n@@ -16,5 +16,5 @@\n 16\n 17\n 18\n-19\n+y\n 20\n"},
+		{"close: one hunk", lines(1, 12), change(lines(1, 12), map[string]string{"3": "x", "10": "y"}),
+			"@@ -1,12 +1,12 @@\n 1\n 2\n-3\n+x\n 4\n 5\n 6\n 7\n 8\n 9\n-10\n+y\n 11\n 12\n"},
+	}
+	for _, tt := range tests {
+		want := "--- want\n+++ got\n" + tt.diff
+		if got := unifiedDiff("want", "got", tt.want, tt.got, 3); got != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, want)
+		}
+	}
+	if got := unifiedDiff("want", "got", "same\n", "same\n", 3); got != "--- want\n+++ got\n" {
+		t.Errorf("no changes: %q", got)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Table
+
+func sampleTable() *Table {
+	t := &Table{MaxWidth: 12}
+	t.SetHeaders("FILE", "BYTES", "NOTE")
+	t.AddRow("main.go", 1532, "entry point")
+	t.AddRow("internal/handler.go", 48211, "cut to twelve runes")
+	t.AddRow("héllo_wörld.go", 7, "a | b")
+	t.AddRow("tab\there") // short row: the missing cells stay empty
+	return t
+}
+
+func render(t *testing.T, table *Table) string {
+	t.Helper()
+	var sb strings.Builder
+	if err := table.Render(&sb); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestTableGolden(t *testing.T) {
+	for _, markdown := range []bool{false, true} {
+		table := sampleTable()
+		table.Markdown = markdown
+		name := "table_plain"
+		if markdown {
+			name = "table_markdown"
+		}
+		assertGolden(t, []byte(render(t, table)), name)
+	}
+}
+
+func TestTableWithoutHeaders(t *testing.T) {
+	table := &Table{}
+	table.AddRow("a", 1)
+	table.AddRow("bbb", 22)
+	if got, want := render(t, table), "a    1\nbbb  22\n"; got != want {
+		t.Errorf("plain: %q, want %q", got, want)
+	}
+	table.Markdown = true // Markdown needs a header row, an empty one is added
+	if got, want := render(t, table), "|  |  |\n| --- | --- |\n| a | 1 |\n| bbb | 22 |\n"; got != want {
+		t.Errorf("markdown: %q, want %q", got, want)
+	}
+	if got := render(t, &Table{Markdown: true}); got != "" {
+		t.Errorf("empty markdown table: %q", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		limit int
+		want  string
+	}{
+		{"golang", 0, "golang"},
+		{"golang", 6, "golang"},
+		{"golang", 5, "gola…"},
+		{"golang", 1, "…"},
+		{"wörld", 3, "wö…"}, // runes, never half a character
+		{"日本語テキスト", 4, "日本語…"},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.s, tt.limit); got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+		}
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestTableWriteError(t *testing.T) {
+	for _, markdown := range []bool{false, true} {
+		table := sampleTable()
+		table.Markdown = markdown
+		if err := table.Render(failingWriter{}); err == nil {
+			t.Errorf("markdown=%v: no error from a failing writer", markdown)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Slugs and sanitizing
+
+func TestSlugify(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Rishabh Gupta", "rishabh-gupta"},
+		{"  Zoë O'Brien  ", "zoe-o-brien"},
+		{"Ærøskøbing Straße", "aeroskobing-strasse"},
+		{"Jose\u0301 Nun\u0303ez", "jose-nunez"}, // combining accents, not precomposed letters
+		{"ÀÉÎÕÜ", "aeiou"},                       // uppercase goes through ToLower first
+		{"C++ & Go: 2025!", "c-go-2025"},
+		{"--already--a--slug--", "already-a-slug"},
+		{"Łódź → Kraków", "lodz-krakow"},
+		{"Rishabh 🚀 Gupta", "rishabh-gupta"},
+		{"रिषभ", ""},
+		{"🦫🦫", ""},
+		{"日本 2025", "2025"},
+		{"", ""},
+		{"Ⅻ ① ٣", ""}, // letters and digits outside ASCII with no table entry are dropped
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.in); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+var slugRe = regexp.MustCompile(`^([a-z0-9]+(-[a-z0-9]+)*)?$`)
+
+func TestSlugifyShape(t *testing.T) {
+	long := strings.Repeat("long name ", 7)
+	if got := Slugify(long); got != "long-name-long-name-long-name-long-name-long-name-long-name" {
+		t.Errorf("cut at a word: %q", got)
+	}
+	oneWord := strings.Repeat("a", 100)
+	if got := Slugify(oneWord); got != oneWord[:maxSlugLen] {
+		t.Errorf("one long word: %q", got)
+	}
+	// whatever goes in, what comes out needs no escaping and is stable
+	for _, in := range []string{long, oneWord, "a-" + oneWord, "x " + strings.Repeat("é", 80), "\x00\xff\u202e", "A__B..C", "ß-ß"} {
+		got := Slugify(in)
+		if !slugRe.MatchString(got) || len(got) > maxSlugLen || Slugify(got) != got {
+			t.Errorf("Slugify(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestStripControlChars(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Alice\x1b[2J\x1b[H", "Alice[2J[H"},
+		{"Bob\r\nINFO fake log line", "BobINFO fake log line"},
+		{"tab\there", "tabhere"},
+		{"evil\u202egnp.exe", "evilgnp.exe"},
+		{"\u2066isolate\u2069 \u202aembed\u202c", "isolate embed"},
+		{"family 👨\u200d👩\u200d👧", "family 👨\u200d👩\u200d👧"}, // ZWJ stays
+		{"bad \xff byte", "bad  byte"},
+		{"\u0085next line, \u009bCSI", "next line, CSI"}, // C1 controls
+		{"रिषभ Zoë", "रिषभ Zoë"},
+	}
+	for _, tt := range tests {
+		if got := StripControlChars(tt.in); got != tt.want {
+			t.Errorf("StripControlChars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"report 2025.pdf", "report_2025.pdf"},
+		{"../../etc/passwd", "etc_passwd"},
+		{".env", "env"},
+		{"a/b\\c:d*e?.txt", "a_b_c_d_e_.txt"},
+		{"CON.txt", "_CON.txt"},
+		{"nul", "_nul"},
+		{"console.txt", "console.txt"},
+		{"résumé (final).docx", "résumé_final_.docx"},
+		{"invoice\u202efdp.exe", "invoicefdp.exe"},
+		{"...", "file"},
+		{"", "file"},
+		{"name.\x00.sh", "name..sh"},
+		{"रिषभ.txt", "रिषभ.txt"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	got := SanitizeFilename(strings.Repeat("ab", 150) + ".txt")
+	if len(got) != maxFilenameBytes || !strings.HasSuffix(got, ".txt") {
+		t.Errorf("%d bytes: %q", len(got), got)
+	}
+	// a cut in the middle of a rune drops the broken half
+	got = SanitizeFilename(strings.Repeat("é", 150))
+	if !utf8.ValidString(got) || len(got) > maxFilenameBytes {
+		t.Errorf("%d bytes, valid %v", len(got), utf8.ValidString(got))
+	}
+	// a "extension" that long is part of the name
+	got = SanitizeFilename("a." + strings.Repeat("x", 300))
+	if len(got) != maxFilenameBytes || !strings.HasPrefix(got, "a.xxx") {
+		t.Errorf("long extension: %d bytes, %q...", len(got), got[:10])
+	}
+}
+
+// the result is always one harmless path element
+func TestSanitizeFilenameIsOneElement(t *testing.T) {
+	inputs := []string{"../../etc/passwd", "..", "/", "a/../../b", `C:\Windows\system32`, ".hidden", "x\x00y", "  ", "aux.tar.gz", "lpt1", strings.Repeat("../", 100)}
+	for _, in := range inputs {
+		got := SanitizeFilename(in)
+		if got == "" || got == "." || got == ".." || strings.ContainsAny(got, `/\:`) || strings.HasPrefix(got, ".") ||
+			filepath.Base(got) != got || SanitizeFilename(got) != got {
+			t.Errorf("SanitizeFilename(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestStoreSlugs(t *testing.T) {
+	s := NewUserStore()
+	tests := []struct{ name, slug string }{
+		{"Alice", "alice"},
+		{"Alice", "alice-2"},
+		{"alice!", "alice-3"},
+		{"Alice 2", "alice-2-2"}, // "alice-2" is taken by the second Alice
+		{"Alice", "alice-4"},
+		{"रिषभ", "user-6"}, // nothing left: the ID
+		{"🦫", "user-7"},
+		{"User 8", "user-8"},
+		{"  Bob\x1b[2J  ", "bob-2j"},
+	}
+	for _, tt := range tests {
+		if u := s.Create(tt.name); u.Slug != tt.slug {
+			t.Errorf("Create(%q).Slug = %q, want %q", tt.name, u.Slug, tt.slug)
+		}
+	}
+	if u, ok := s.BySlug("alice-2-2"); !ok || u.Name != "Alice 2" || u.ID != 4 {
+		t.Errorf("BySlug(alice-2-2) = %+v, %v", u, ok)
+	}
+	if u, _ := s.BySlug("bob-2j"); u.Name != "Bob[2J" {
+		t.Errorf("stored name %q", u.Name)
+	}
+	// a name can take the ID fallback first: user 11 then counts on from it like any other
+	s.Create("User 11")
+	if u := s.Create("🦫"); u.ID != 11 || u.Slug != "user-11-2" {
+		t.Errorf("user 11: %+v", u)
+	}
+}
+
+func TestConcurrentSlugs(t *testing.T) {
+	s := NewUserStore()
+	h := routes(s)
+	const n = 50
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Rishabh Gupta"}`)))
+			if rec.Code != 201 {
+				t.Errorf("create: %d", rec.Code)
+			}
+		})
+	}
+	wg.Wait()
+	seen := map[string]bool{}
+	for _, u := range s.List() {
+		seen[u.Slug] = true
+	}
+	if len(seen) != n || !seen["rishabh-gupta"] || !seen["rishabh-gupta-50"] || seen["rishabh-gupta-51"] {
+		t.Errorf("%d distinct slugs for %d users", len(seen), n)
+	}
+}
+
+func TestBySlugEndpoint(t *testing.T) {
+	s := NewUserStore()
+	s.Create("Zoë O'Brien")
+	s.Create("🦫")
+	h := routes(s)
+	tests := []struct {
+		target string
+		code   int
+		want   User
+	}{
+		{"/api/users/by-slug/zoe-o-brien", 200, User{ID: 1, Name: "Zoë O'Brien", Slug: "zoe-o-brien"}},
+		{"/api/users/by-slug/user-2", 200, User{ID: 2, Name: "🦫", Slug: "user-2"}},
+		{"/api/users/by-slug/Zoe-O-Brien", 404, User{}}, // slugs are matched exactly
+		{"/api/users/by-slug/nobody", 404, User{}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+		var got User
+		json.Unmarshal(rec.Body.Bytes(), &got)
+		if rec.Code != tt.code || got != tt.want {
+			t.Errorf("%s: %d %s", tt.target, rec.Code, rec.Body)
+		}
+	}
+	for _, body := range []string{`{}`, `{"name":"  "}`, `not json`} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/users", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("POST %s: %d", body, rec.Code)
+		}
+	}
+}
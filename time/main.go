@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// FormatUptime renders a duration the way a health endpoint would, e.g. "2d 3h 14m".
+// Durations under a minute are shown in seconds, negative durations get a "-" prefix.
+func FormatUptime(d time.Duration) string {
+	if d < 0 {
+		if d == math.MinInt64 { // -d would overflow, and one nanosecond never shows
+			d++
+		}
+		return "-" + FormatUptime(-d)
+	}
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d/time.Second))
+	}
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if days > 0 || hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	parts = append(parts, fmt.Sprintf("%dm", minutes))
+	return strings.Join(parts, " ")
+}
+
+// flexibleLayouts are tried in order by ParseFlexibleTime.
+// Order matters: the most specific layouts come first.
+var flexibleLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123,
+	"02 Jan 2006",
+}
+
+// ParseFlexibleTime accepts several common layouts. Ambiguous formats like
+// "01/02/2006" (is it Jan 2 or Feb 1?) are deliberately NOT accepted.
+func ParseFlexibleTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, errors.New("parse time: empty input")
+	}
+	for _, layout := range flexibleLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parse time %q: no supported layout matched", s)
+}
+
+func main() {
+	fmt.Println("Learning time and durations in Go")
+
+	// Go does not use YYYY-MM-DD style patterns.
+	// It uses a reference time: Mon Jan 2 15:04:05 MST 2006 (1 2 3 4 5 6 7)
+	now := time.Now()
+	fmt.Println("Now:", now.Format("2006-01-02 15:04:05"))
+	fmt.Println("Only date:", now.Format("02 Jan 2006"))
+	fmt.Println("12 hour clock:", now.Format("3:04 PM"))
+
+	// Parsing uses the same reference layout
+	birthday, err := time.Parse("2006-01-02", "2001-08-15")
+	if err != nil {
+		fmt.Println("Error while parsing:", err)
+		return
+	}
+	fmt.Println("Parsed birthday:", birthday, "Weekday:", birthday.Weekday())
+
+	// RFC3339 is the format used by JSON APIs, round trips are lossless
+	encoded := now.Format(time.RFC3339Nano)
+	decoded, err := time.Parse(time.RFC3339Nano, encoded)
+	if err != nil {
+		fmt.Println("Error while parsing:", err)
+		return
+	}
+	fmt.Println("RFC3339:", encoded, "round trip equal:", decoded.Equal(now))
+
+	// Durations are just int64 nanoseconds with helper methods
+	timeout := 90 * time.Second
+	fmt.Println("Timeout:", timeout, "in minutes:", timeout.Minutes())
+	d, err := time.ParseDuration("1h15m30s")
+	if err != nil {
+		fmt.Println("Error while parsing duration:", err)
+		return
+	}
+	fmt.Println("Parsed duration:", d)
+
+	// Time arithmetic
+	tomorrow := now.Add(24 * time.Hour)
+	nextMonth := now.AddDate(0, 1, 0)
+	fmt.Println("Tomorrow:", tomorrow.Format(time.DateOnly))
+	fmt.Println("Next month:", nextMonth.Format(time.DateOnly))
+	fmt.Println("Age in days:", int(now.Sub(birthday).Hours()/24))
+
+	// Time zones
+	// LoadLocation needs the tz database on the machine (or import _ "time/tzdata")
+	if loc, err := time.LoadLocation("Asia/Kolkata"); err != nil {
+		fmt.Println("Could not load location:", err)
+	} else {
+		fmt.Println("Time in India:", now.In(loc).Format("15:04 MST"))
+	}
+	fmt.Println("Time in UTC:", now.UTC().Format("15:04 MST"))
+	fixed := time.FixedZone("UTC+5:30", 5*60*60+30*60)
+	fmt.Println("Fixed zone:", now.In(fixed).Format(time.RFC3339))
+
+	// Truncate always rounds down, Round rounds to the nearest
+	t := time.Date(2024, 3, 10, 14, 47, 31, 0, time.UTC)
+	fmt.Println("Truncate to hour:", t.Truncate(time.Hour).Format(time.TimeOnly))
+	fmt.Println("Round to hour:", t.Round(time.Hour).Format(time.TimeOnly))
+
+	// Comparing times: use Before/After/Equal, not ==
+	// == also compares the location and monotonic reading
+	utc := t
+	india := t.In(fixed)
+	fmt.Println("Same instant with Equal:", utc.Equal(india), "with ==:", utc == india)
+	fmt.Println("t before tomorrow:", t.Before(tomorrow))
+
+	// Monotonic clock
+	// time.Now() carries a monotonic reading which time.Since uses,
+	// so measured durations are correct even if the wall clock jumps (NTP, DST).
+	start := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	fmt.Println("Elapsed:", time.Since(start).Round(time.Millisecond))
+
+	// Helpers
+	for _, up := range []time.Duration{0, 45 * time.Second, 3*time.Hour + 14*time.Minute, 51*time.Hour + 14*time.Minute, 40 * 24 * time.Hour, -5 * time.Minute} {
+		fmt.Printf("FormatUptime(%v) = %s\n", up, FormatUptime(up))
+	}
+	for _, input := range []string{"2024-03-10", "2024-03-10 14:47", "2024-03-10T14:47:31Z", "03/10/2024", ""} {
+		parsed, err := ParseFlexibleTime(input)
+		if err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		fmt.Printf("ParseFlexibleTime(%q) = %s\n", input, parsed.Format(time.RFC3339))
+	}
+}
+
+// time.Time = an instant (wall clock + optional monotonic reading + location)
+// time.Duration = elapsed time in nanoseconds
+// Format/Parse use the reference time "2006-01-02 15:04:05" instead of YYYY-MM-DD
+// Store and send times in UTC (RFC3339), convert to a location only for display
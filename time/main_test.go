@@ -0,0 +1,65 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{999 * time.Millisecond, "0s"},
+		{59 * time.Second, "59s"},
+		{time.Minute, "1m"},
+		{time.Hour + 30*time.Second, "1h 0m"},
+		{2*24*time.Hour + 3*time.Hour + 14*time.Minute, "2d 3h 14m"},
+		{24 * time.Hour, "1d 0h 0m"},
+		{45*24*time.Hour + 5*time.Minute, "45d 0h 5m"},
+		{-90 * time.Minute, "-1h 30m"},
+		{-5 * time.Second, "-5s"},
+		{math.MaxInt64, "106751d 23h 47m"},
+		{math.MinInt64, "-106751d 23h 47m"},
+	}
+	for _, tt := range tests {
+		if got := FormatUptime(tt.in); got != tt.want {
+			t.Errorf("FormatUptime(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseFlexibleTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-03-15T10:30:00Z", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-15T10:30:00.123456789Z", time.Date(2024, 3, 15, 10, 30, 0, 123456789, time.UTC)},
+		{"2024-03-15T10:30:00", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-15 10:30:00", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"2024-03-15 10:30", time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)},
+		{"  2024-03-15  ", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"15 Mar 2024", time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseFlexibleTime(tt.in)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseFlexibleTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	offset, err := ParseFlexibleTime("2024-03-15T10:30:00+05:30")
+	if err != nil || !offset.Equal(time.Date(2024, 3, 15, 5, 0, 0, 0, time.UTC)) {
+		t.Errorf("offset input: got %v, %v", offset, err)
+	}
+
+	// ambiguous and invalid inputs must fail rather than guess
+	for _, in := range []string{"", "   ", "01/02/2024", "03/04/05", "2024-13-01", "2024-02-30", "yesterday"} {
+		if got, err := ParseFlexibleTime(in); err == nil {
+			t.Errorf("ParseFlexibleTime(%q) = %v, want an error", in, got)
+		}
+	}
+}
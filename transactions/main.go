@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A bulk create must be all or nothing: if user 7 of 10 is invalid, users 1-6 must not stay.
+// Two ways to get there:
+//   - insert one by one and undo the inserted ones on failure (the undo can fail too,
+//     and other requests already saw the half-done state)
+//   - stage the writes in a transaction and apply them all at once under one lock
+// The second is what a database does, and what the store below does.
+
+var (
+	ErrInvalid        = errors.New("invalid user")
+	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrTxDone         = errors.New("transaction already committed or rolled back")
+)
+
+type User struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type CreateUserRequest struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (r CreateUserRequest) validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalid)
+	}
+	if _, err := mail.ParseAddress(r.Email); err != nil {
+		return fmt.Errorf("%w: email %q is not valid", ErrInvalid, r.Email)
+	}
+	return nil
+}
+
+// emailKey is what uniqueness compares: the bare address, lowercased, so "Al@Example.com"
+// and "Al <al@example.com>" are the same email
+func emailKey(email string) string {
+	if addr, err := mail.ParseAddress(email); err == nil {
+		email = addr.Address
+	}
+	return strings.ToLower(email)
+}
+
+type UserStore struct {
+	mu      sync.Mutex
+	users   map[int]User
+	byEmail map[string]int // by emailKey
+	nextID  int
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{users: map[int]User{}, byEmail: map[string]int{}, nextID: 1}
+}
+
+// Dump returns the users as JSON, sorted by ID: two equal dumps mean two equal stores
+func (s *UserStore) Dump() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	data, _ := json.Marshal(struct {
+		Users  []User
+		NextID int
+	}{users, s.nextID})
+	return data
+}
+
+// Tx collects writes without touching the store. Nothing is visible to other
+// requests until Commit, and Rollback just forgets the staged writes.
+type Tx struct {
+	s       *UserStore
+	creates []CreateUserRequest
+	deletes []int
+	done    bool
+}
+
+func (s *UserStore) Begin() *Tx {
+	return &Tx{s: s}
+}
+
+// Create stages a new user. Validation happens now, uniqueness is checked again at Commit,
+// because another transaction can take the email in between.
+func (tx *Tx) Create(req CreateUserRequest) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if err := req.validate(); err != nil {
+		return err
+	}
+	for _, c := range tx.creates {
+		if emailKey(c.Email) == emailKey(req.Email) {
+			return fmt.Errorf("%w: %s appears twice in the batch", ErrDuplicateEmail, req.Email)
+		}
+	}
+	tx.creates = append(tx.creates, req)
+	return nil
+}
+
+func (tx *Tx) Delete(id int) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.deletes = append(tx.deletes, id)
+	return nil
+}
+
+// Commit checks everything first and only then writes, all under one lock.
+// If a check fails nothing has been written, so there is nothing to undo.
+// Deletes are applied before creates, so a batch can free an email and reuse it.
+func (tx *Tx) Commit() ([]User, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	tx.done = true
+	s := tx.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	freed := make(map[string]bool, len(tx.deletes))
+	for _, id := range tx.deletes {
+		if u, ok := s.users[id]; ok {
+			freed[emailKey(u.Email)] = true
+		}
+	}
+	for i, c := range tx.creates {
+		if _, taken := s.byEmail[emailKey(c.Email)]; taken && !freed[emailKey(c.Email)] {
+			return nil, fmt.Errorf("user #%d: %w: %s", i+1, ErrDuplicateEmail, c.Email)
+		}
+	}
+
+	for _, id := range tx.deletes {
+		if u, ok := s.users[id]; ok {
+			delete(s.byEmail, emailKey(u.Email))
+			delete(s.users, id)
+		}
+	}
+	created := make([]User, len(tx.creates))
+	for i, c := range tx.creates {
+		u := User{ID: s.nextID, Name: c.Name, Email: c.Email}
+		s.nextID++
+		s.users[u.ID] = u
+		s.byEmail[emailKey(u.Email)] = u.ID
+		created[i] = u
+	}
+	return created, nil
+}
+
+func (tx *Tx) Rollback() {
+	tx.done = true
+	tx.creates, tx.deletes = nil, nil
+}
+
+// DeleteMany deletes each ID on its own and reports what happened to every one of them.
+// An ID listed twice keeps the result of its first delete.
+func (s *UserStore) DeleteMany(ids []int) map[int]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if _, seen := results[id]; seen {
+			continue
+		}
+		u, ok := s.users[id]
+		if !ok {
+			results[id] = "not found"
+			continue
+		}
+		delete(s.byEmail, emailKey(u.Email))
+		delete(s.users, id)
+		results[id] = "deleted"
+	}
+	return results
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+const (
+	MaxBulk     = 100
+	maxBulkBody = 1 << 20 // 1 MiB, far more than MaxBulk real users need
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func handleBulkCreate(s *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the cap on the count only helps once the array is decoded, this one stops a huge body before that
+		r.Body = http.MaxBytesReader(w, r.Body, maxBulkBody)
+		var reqs []CreateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			if tooBig := new(http.MaxBytesError); errors.As(err, &tooBig) {
+				writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("body is larger than %d bytes", maxBulkBody)})
+				return
+			}
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be a JSON array of users"})
+			return
+		}
+		if len(reqs) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "send at least one user"})
+			return
+		}
+		if len(reqs) > MaxBulk {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("send at most %d users, got %d", MaxBulk, len(reqs))})
+			return
+		}
+		tx := s.Begin()
+		for i, req := range reqs {
+			if err := tx.Create(req); err != nil {
+				tx.Rollback()
+				writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"error": err.Error(), "index": i})
+				return
+			}
+		}
+		created, err := tx.Commit()
+		if errors.Is(err, ErrDuplicateEmail) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+	}
+}
+
+// handleBulkDelete: DELETE /api/users?ids=1,2,3 answers 200 with a result per ID, even when some failed
+func handleBulkDelete(s *UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		raw := r.URL.Query().Get("ids")
+		if raw == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ids is required"})
+			return
+		}
+		parts := strings.Split(raw, ",")
+		if len(parts) > MaxBulk {
+			writeJSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("at most %d ids", MaxBulk)})
+			return
+		}
+		results := map[string]string{}
+		var ids []int
+		for _, p := range parts {
+			id, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || id <= 0 {
+				results[p] = "invalid id"
+				continue
+			}
+			ids = append(ids, id)
+		}
+		for id, res := range s.DeleteMany(ids) {
+			results[strconv.Itoa(id)] = res
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"results": results})
+	}
+}
+
+func main() {
+	fmt.Println("Learning all-or-nothing bulk writes in Go")
+
+	store := NewUserStore()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users/bulk", handleBulkCreate(store))
+	mux.HandleFunc("DELETE /api/users", handleBulkDelete(store))
+	do := func(method, target, body string) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		fmt.Printf("  %s %s -> %d %s", method, target, rec.Code, rec.Body.String())
+	}
+
+	do("POST", "/api/users/bulk", `[{"name":"Rishabh","email":"r@example.com"},{"name":"Sanchay","email":"s@example.com"}]`)
+
+	before := store.Dump()
+	fmt.Println("Failing batches leave the store as it was:")
+	do("POST", "/api/users/bulk", `[{"name":"Alice","email":"a@example.com"},{"name":"","email":"b@example.com"}]`)
+	do("POST", "/api/users/bulk", `[{"name":"Alice","email":"a@example.com"},{"name":"Al","email":"a@example.com"}]`)
+	do("POST", "/api/users/bulk", `[{"name":"Alice","email":"a@example.com"},{"name":"Copy","email":"r@example.com"}]`)
+	fmt.Println("  store unchanged:", bytes.Equal(before, store.Dump()))
+
+	big, _ := json.Marshal(make([]CreateUserRequest, MaxBulk+1))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("POST", "/api/users/bulk", bytes.NewReader(big)))
+	fmt.Printf("  %d users -> %d %s", MaxBulk+1, rec.Code, rec.Body.String())
+
+	// two batches race for the same email: the uniqueness check at Commit
+	// runs under the same lock as the writes, so exactly one of them wins
+	fmt.Println("Two concurrent batches with the same email:")
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tx := store.Begin()
+			tx.Create(CreateUserRequest{Name: fmt.Sprint("racer", i), Email: fmt.Sprintf("racer%d@example.com", i)})
+			tx.Create(CreateUserRequest{Name: "Shared", Email: "shared@example.com"})
+			_, errs[i] = tx.Commit()
+		}()
+	}
+	wg.Wait()
+	fmt.Println("  results:", errs)
+
+	tx := store.Begin()
+	tx.Commit()
+	_, err := tx.Commit()
+	fmt.Println("Commit twice:", err)
+
+	fmt.Println("Bulk delete:")
+	do("DELETE", "/api/users?ids=1,2,99,abc", "")
+	do("DELETE", "/api/users", "")
+	fmt.Println("Left:", string(store.Dump()))
+}
+
+// Stage writes, validate everything, then apply under ONE lock: nothing to undo when a check fails.
+// Check uniqueness again at commit time, another request may have written in between.
+// Cap the batch size, an unbounded array is an easy way to exhaust memory.
+// Bulk deletes are usually not all-or-nothing: report a result per ID instead.
+// Comparing a dump before and after is the simplest proof that a failed batch changed nothing.
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newMux(s *UserStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/users/bulk", handleBulkCreate(s))
+	mux.HandleFunc("DELETE /api/users", handleBulkDelete(s))
+	return mux
+}
+
+func do(mux http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func users(n int, from int) string {
+	reqs := make([]CreateUserRequest, n)
+	for i := range reqs {
+		reqs[i] = CreateUserRequest{Name: fmt.Sprint("user", from+i), Email: fmt.Sprintf("u%d@example.com", from+i)}
+	}
+	data, _ := json.Marshal(reqs)
+	return string(data)
+}
+
+// checkIndex fails when byEmail and users disagree, Dump alone doesn't show byEmail
+func checkIndex(t *testing.T, s *UserStore) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.byEmail) != len(s.users) {
+		t.Fatalf("%d emails indexed for %d users", len(s.byEmail), len(s.users))
+	}
+	for email, id := range s.byEmail {
+		if emailKey(s.users[id].Email) != email {
+			t.Fatalf("byEmail[%s] = %d, which is %+v", email, id, s.users[id])
+		}
+	}
+}
+
+func TestBulkCreateRollback(t *testing.T) {
+	s := NewUserStore()
+	mux := newMux(s)
+	if rec := do(mux, "POST", "/api/users/bulk", users(3, 1)); rec.Code != http.StatusCreated {
+		t.Fatalf("seed: %d %s", rec.Code, rec.Body)
+	}
+	before := s.Dump()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"invalid name last", `[{"name":"A","email":"a@example.com"},{"name":"B","email":"b@example.com"},{"name":" ","email":"c@example.com"}]`, 422},
+		{"invalid email", `[{"name":"A","email":"a@example.com"},{"name":"B","email":"nope"}]`, 422},
+		{"twice in the batch", `[{"name":"A","email":"a@example.com"},{"name":"B","email":"a@example.com"}]`, 422},
+		{"taken in the store", `[{"name":"A","email":"a@example.com"},{"name":"B","email":"u2@example.com"}]`, 409},
+		{"over the cap", users(MaxBulk+1, 100), 413},
+		{"empty", `[]`, 400},
+		{"null", `null`, 400},
+		{"not an array", `{"name":"A","email":"a@example.com"}`, 400},
+		{"cut short", `[{"name":"A","email":"a@ex`, 400},
+	}
+	for _, tt := range tests {
+		rec := do(mux, "POST", "/api/users/bulk", tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s: %d %s, want %d", tt.name, rec.Code, rec.Body, tt.status)
+		}
+		if after := s.Dump(); !bytes.Equal(before, after) {
+			t.Fatalf("%s changed the store:\nbefore %s\nafter  %s", tt.name, before, after)
+		}
+	}
+	checkIndex(t, s)
+
+	// the next good batch continues the IDs as if the failures never happened
+	rec := do(mux, "POST", "/api/users/bulk", users(1, 50))
+	var created []User
+	json.Unmarshal(rec.Body.Bytes(), &created)
+	if rec.Code != http.StatusCreated || len(created) != 1 || created[0].ID != 4 {
+		t.Errorf("after the failures: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestBulkCreateLimits(t *testing.T) {
+	s := NewUserStore()
+	mux := newMux(s)
+	if rec := do(mux, "POST", "/api/users/bulk", users(MaxBulk, 1)); rec.Code != http.StatusCreated {
+		t.Errorf("exactly %d: %d %s", MaxBulk, rec.Code, rec.Body)
+	}
+	// one user whose name alone is over the body limit: stopped while reading, not after
+	huge := `[{"name":"` + strings.Repeat("x", maxBulkBody) + `","email":"big@example.com"}]`
+	rec := do(mux, "POST", "/api/users/bulk", huge)
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "larger than") {
+		t.Errorf("huge body: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(mux, "POST", "/api/users/bulk", `[]`); !strings.Contains(rec.Body.String(), "at least one") {
+		t.Errorf("empty: %s", rec.Body)
+	}
+	if got := len(s.users); got != MaxBulk {
+		t.Errorf("%d users stored", got)
+	}
+}
+
+func TestTx(t *testing.T) {
+	s := NewUserStore()
+	tx := s.Begin()
+	tx.Create(CreateUserRequest{Name: "A", Email: "a@example.com"})
+	tx.Create(CreateUserRequest{Name: "B", Email: "b@example.com"})
+	before := s.Dump()
+	if !bytes.Equal(before, NewUserStore().Dump()) {
+		t.Fatal("staged writes are visible before Commit")
+	}
+	tx.Rollback()
+	if !bytes.Equal(before, s.Dump()) {
+		t.Error("Rollback changed the store")
+	}
+	if err := tx.Create(CreateUserRequest{Name: "C", Email: "c@example.com"}); err != ErrTxDone {
+		t.Errorf("Create after Rollback: %v", err)
+	}
+	if _, err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Commit after Rollback: %v", err)
+	}
+
+	tx = s.Begin()
+	tx.Create(CreateUserRequest{Name: "A", Email: "a@example.com"})
+	if created, err := tx.Commit(); err != nil || len(created) != 1 || created[0].ID != 1 {
+		t.Fatalf("Commit: %v %v", created, err)
+	}
+	if _, err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("second Commit: %v", err)
+	}
+	if err := tx.Delete(1); err != ErrTxDone {
+		t.Errorf("Delete after Commit: %v", err)
+	}
+}
+
+// a batch may delete a user and give its email to a new one
+func TestTxDeleteThenReuseEmail(t *testing.T) {
+	s := NewUserStore()
+	tx := s.Begin()
+	tx.Create(CreateUserRequest{Name: "Old", Email: "a@example.com"})
+	tx.Create(CreateUserRequest{Name: "Keep", Email: "k@example.com"})
+	tx.Commit()
+
+	tx = s.Begin()
+	tx.Delete(1)
+	tx.Create(CreateUserRequest{Name: "New", Email: "a@example.com"})
+	created, err := tx.Commit()
+	if err != nil || len(created) != 1 || created[0].ID != 3 {
+		t.Fatalf("Commit: %v %v", created, err)
+	}
+	if got := s.byEmail["a@example.com"]; got != 3 {
+		t.Errorf("a@example.com belongs to %d, want 3", got)
+	}
+	if _, ok := s.users[1]; ok {
+		t.Error("user 1 wasn't deleted")
+	}
+	checkIndex(t, s)
+
+	// deleting someone else doesn't free the email
+	before := s.Dump()
+	tx = s.Begin()
+	tx.Delete(3)
+	tx.Create(CreateUserRequest{Name: "Copy", Email: "k@example.com"})
+	if _, err := tx.Commit(); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("reuse of a kept email: %v", err)
+	}
+	if !bytes.Equal(before, s.Dump()) {
+		t.Error("the failed commit applied its delete")
+	}
+}
+
+func TestConcurrentCommits(t *testing.T) {
+	s := NewUserStore()
+	const racers = 20
+	errs := make([]error, racers)
+	var wg sync.WaitGroup
+	for i := range racers {
+		wg.Go(func() {
+			tx := s.Begin()
+			tx.Create(CreateUserRequest{Name: "own", Email: fmt.Sprintf("own%d@example.com", i)})
+			tx.Create(CreateUserRequest{Name: "shared", Email: "shared@example.com"})
+			_, errs[i] = tx.Commit()
+		})
+	}
+	wg.Wait()
+	wins := 0
+	for _, err := range errs {
+		if err == nil {
+			wins++
+		} else if !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+	if wins != 1 || len(s.users) != 2 {
+		t.Errorf("%d commits won, %d users stored", wins, len(s.users))
+	}
+	checkIndex(t, s)
+}
+
+func TestBulkDelete(t *testing.T) {
+	s := NewUserStore()
+	mux := newMux(s)
+	do(mux, "POST", "/api/users/bulk", users(3, 1))
+
+	rec := do(mux, "DELETE", "/api/users?ids=%201,3,99,abc,-2", "") // " 1" is trimmed
+	var resp struct{ Results map[string]string }
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	want := map[string]string{"1": "deleted", "3": "deleted", "99": "not found", "abc": "invalid id", "-2": "invalid id"}
+	if rec.Code != http.StatusOK || len(resp.Results) != len(want) {
+		t.Fatalf("%d %s", rec.Code, rec.Body)
+	}
+	for id, res := range want {
+		if resp.Results[id] != res {
+			t.Errorf("id %s: %q, want %q", id, resp.Results[id], res)
+		}
+	}
+	if len(s.users) != 1 || s.users[2].ID != 2 {
+		t.Errorf("left: %v", s.users)
+	}
+	checkIndex(t, s)
+
+	if rec := do(mux, "DELETE", "/api/users", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("no ids: %d", rec.Code)
+	}
+	tooMany := strings.Repeat("1,", MaxBulk) + "1"
+	if rec := do(mux, "DELETE", "/api/users?ids="+tooMany, ""); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("%d ids: %d", MaxBulk+1, rec.Code)
+	}
+}
+
+func TestEmailsIgnoreCase(t *testing.T) {
+	s := NewUserStore()
+	tx := s.Begin()
+	tx.Create(CreateUserRequest{Name: "Al", Email: "al@example.com"})
+	if err := tx.Create(CreateUserRequest{Name: "Al2", Email: "AL@Example.com"}); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("same batch: %v", err)
+	}
+	tx.Commit()
+	for _, email := range []string{"Al@EXAMPLE.com", "Al <al@example.com>"} {
+		tx = s.Begin()
+		tx.Create(CreateUserRequest{Name: "Copy", Email: email})
+		if _, err := tx.Commit(); !errors.Is(err, ErrDuplicateEmail) {
+			t.Errorf("%s: %v", email, err)
+		}
+	}
+	// deleting the user frees the email in any case
+	tx = s.Begin()
+	tx.Delete(1)
+	tx.Create(CreateUserRequest{Name: "New", Email: "AL@example.com"})
+	if _, err := tx.Commit(); err != nil {
+		t.Errorf("reuse after delete: %v", err)
+	}
+	checkIndex(t, s)
+}
+
+func TestDeleteMany(t *testing.T) {
+	s := NewUserStore()
+	tx := s.Begin()
+	tx.Create(CreateUserRequest{Name: "A", Email: "a@example.com"})
+	tx.Create(CreateUserRequest{Name: "B", Email: "b@example.com"})
+	tx.Commit()
+	got := s.DeleteMany([]int{2, 5, 2})
+	if len(got) != 2 || got[2] != "deleted" || got[5] != "not found" {
+		t.Errorf("DeleteMany: %v", got)
+	}
+	checkIndex(t, s)
+	// the email of a deleted user is free again
+	tx = s.Begin()
+	tx.Create(CreateUserRequest{Name: "B2", Email: "b@example.com"})
+	if _, err := tx.Commit(); err != nil {
+		t.Errorf("reuse after DeleteMany: %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UDP sends single datagrams: no connection, no retries, no ordering.
+// A lost packet is simply gone, which is fine for metrics (one missing +1 doesn't matter)
+// and is why statsd uses UDP: sending a metric never slows down or breaks the app.
+
+// ErrBadMetric is returned by ParseMetric for lines that don't follow "name:value|type"
+var ErrBadMetric = errors.New("malformed metric")
+
+// Metric is one statsd-like line: "requests:1|c" (counter) or "latency:23|ms" (timer)
+type Metric struct {
+	Name  string
+	Value float64
+	Type  string // "c" or "ms"
+}
+
+// ParseMetric parses one line
+func ParseMetric(line string) (Metric, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return Metric{}, fmt.Errorf("%w %q: want name:value|type", ErrBadMetric, line)
+	}
+	value, typ, ok := strings.Cut(rest, "|")
+	if !ok {
+		return Metric{}, fmt.Errorf("%w %q: missing |type", ErrBadMetric, line)
+	}
+	if typ != "c" && typ != "ms" {
+		return Metric{}, fmt.Errorf("%w %q: unknown type %q", ErrBadMetric, line, typ)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return Metric{}, fmt.Errorf("%w %q: bad value: %v", ErrBadMetric, line, err)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		// ParseFloat accepts "NaN" and "Inf", one of them would stick to the counter or timer forever
+		return Metric{}, fmt.Errorf("%w %q: value is %v", ErrBadMetric, line, v)
+	}
+	return Metric{Name: name, Value: v, Type: typ}, nil
+}
+
+// TimerStats summarises the values of one timer
+type TimerStats struct {
+	Count         int
+	Min, Max, Sum float64
+}
+
+func (t TimerStats) Mean() float64 {
+	if t.Count == 0 {
+		return 0
+	}
+	return t.Sum / float64(t.Count)
+}
+
+// Snapshot is a copy of the aggregated metrics, safe to use after the sink moves on
+type Snapshot struct {
+	Counters  map[string]float64
+	Timers    map[string]TimerStats
+	Packets   int
+	Malformed int
+}
+
+// MetricsSink receives datagrams and aggregates them in memory
+type MetricsSink struct {
+	conn net.PacketConn
+
+	mu        sync.Mutex
+	counters  map[string]float64
+	timers    map[string]TimerStats
+	packets   int
+	malformed int
+}
+
+// StartMetricsSink listens on addr ("127.0.0.1:0" picks a free port) until ctx is cancelled
+func StartMetricsSink(ctx context.Context, addr string) (*MetricsSink, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("listen udp %s: %w", addr, err)
+	}
+	s := &MetricsSink{conn: conn, counters: make(map[string]float64), timers: make(map[string]TimerStats)}
+	go func() {
+		<-ctx.Done()
+		conn.Close() // unblocks ReadFrom
+	}()
+	go s.serve()
+	return s, nil
+}
+
+func (s *MetricsSink) Addr() net.Addr { return s.conn.LocalAddr() }
+
+func (s *MetricsSink) serve() {
+	buf := make([]byte, 65535) // the biggest possible UDP payload
+	for {
+		n, _, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return // closed
+		}
+		s.handlePacket(string(buf[:n]))
+	}
+}
+
+// handlePacket applies every line of one datagram. A bad line is counted and skipped,
+// the other metrics in the same packet are still used.
+func (s *MetricsSink) handlePacket(packet string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packets++
+	for _, line := range strings.Split(packet, "\n") {
+		if line == "" {
+			continue
+		}
+		m, err := ParseMetric(line)
+		if err != nil {
+			s.malformed++
+			continue
+		}
+		switch m.Type {
+		case "c":
+			s.counters[m.Name] += m.Value
+		case "ms":
+			t := s.timers[m.Name]
+			if t.Count == 0 || m.Value < t.Min {
+				t.Min = m.Value
+			}
+			if t.Count == 0 || m.Value > t.Max { // the zero value isn't a sample, all values may be negative
+				t.Max = m.Value
+			}
+			t.Count++
+			t.Sum += m.Value
+			s.timers[m.Name] = t
+		}
+	}
+}
+
+// Snapshot copies the current values, e.g. for a /metrics endpoint
+func (s *MetricsSink) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snap := Snapshot{
+		Counters:  make(map[string]float64, len(s.counters)),
+		Timers:    make(map[string]TimerStats, len(s.timers)),
+		Packets:   s.packets,
+		Malformed: s.malformed,
+	}
+	for k, v := range s.counters {
+		snap.Counters[k] = v
+	}
+	for k, v := range s.timers {
+		snap.Timers[k] = v
+	}
+	return snap
+}
+
+// MaxPacketSize keeps datagrams under the usual network MTU (1500 minus IP/UDP headers),
+// bigger ones get fragmented and are much more likely to be lost
+const MaxPacketSize = 1432
+
+// MetricsClient buffers metrics and sends several per datagram, one per line
+type MetricsClient struct {
+	conn   net.Conn
+	mu     sync.Mutex
+	buf    []byte
+	Sent   int // datagrams sent
+	Failed int // datagrams the write failed for, their metrics are dropped
+}
+
+func DialMetricsClient(addr string) (*MetricsClient, error) {
+	// "dialing" UDP sends nothing, it only sets the default destination
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial udp %s: %w", addr, err)
+	}
+	return &MetricsClient{conn: conn}, nil
+}
+
+func (c *MetricsClient) Incr(name string, n int) error {
+	return c.add(name + ":" + strconv.Itoa(n) + "|c")
+}
+
+func (c *MetricsClient) Timing(name string, d time.Duration) error {
+	return c.add(name + ":" + strconv.FormatInt(d.Milliseconds(), 10) + "|ms")
+}
+
+func (c *MetricsClient) add(line string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(line)+1 > MaxPacketSize {
+		return fmt.Errorf("metric %q: longer than a packet", line[:20]+"...")
+	}
+	if len(c.buf)+len(line)+1 > MaxPacketSize {
+		if err := c.flushLocked(); err != nil {
+			return err
+		}
+	}
+	c.buf = append(c.buf, line...)
+	c.buf = append(c.buf, '\n')
+	return nil
+}
+
+// Flush sends the buffered metrics now
+func (c *MetricsClient) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.flushLocked()
+}
+
+func (c *MetricsClient) flushLocked() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.conn.Write(c.buf)
+	c.buf = c.buf[:0] // on error the metrics are dropped, never block or grow forever
+	if err != nil {
+		c.Failed++
+		return fmt.Errorf("send metrics: %w", err)
+	}
+	c.Sent++
+	return nil
+}
+
+func (c *MetricsClient) Close() error {
+	c.Flush()
+	return c.conn.Close()
+}
+
+func main() {
+	fmt.Println("Learning UDP with a statsd-like metrics sink in Go")
+
+	fmt.Println("\nParsing")
+	for _, line := range []string{"requests:1|c", "latency:23.5|ms", "requests:1", "latency:fast|ms", "requests:NaN|c", ":1|c", "cpu:3|g"} {
+		m, err := ParseMetric(line)
+		fmt.Printf("  %-17q -> %+v %v\n", line, m, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink, err := StartMetricsSink(ctx, "127.0.0.1:0")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	client, err := DialMetricsClient(sink.Addr().String())
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println("\nSending over loopback to", sink.Addr())
+	for i := 0; i < 500; i++ {
+		client.Incr("requests", 1)
+		client.Timing("latency", time.Duration(10+i%50)*time.Millisecond)
+		if i%100 == 0 {
+			client.Incr("errors", 1)
+		}
+	}
+	client.Close()
+
+	// a raw packet with a bad line in the middle
+	raw, _ := net.Dial("udp", sink.Addr().String())
+	raw.Write([]byte("requests:5|c\nthis is garbage\nerrors:1|c\n"))
+	raw.Close()
+
+	time.Sleep(100 * time.Millisecond) // UDP gives no acknowledgement, give the sink time to read
+	snap := sink.Snapshot()
+	fmt.Printf("  client sent %d datagrams for 1005 metrics (batched up to %d bytes), %d failed\n", client.Sent, MaxPacketSize, client.Failed)
+	fmt.Printf("  sink received %d packets, %d malformed lines skipped\n", snap.Packets, snap.Malformed)
+	names := make([]string, 0, len(snap.Counters))
+	for name := range snap.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  counter %-9s %v\n", name, snap.Counters[name])
+	}
+	for name, t := range snap.Timers {
+		fmt.Printf("  timer   %-9s count %d min %vms max %vms mean %.1fms\n", name, t.Count, t.Min, t.Max, t.Mean())
+	}
+
+	// sending to a port nobody listens on doesn't fail: fire and forget
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	lost, _ := DialMetricsClient(sink.Addr().String())
+	lost.Incr("requests", 1)
+	fmt.Println("\nSink stopped, Flush error:", lost.Flush(), "(the packet is just lost)")
+	lost.Close()
+}
+
+// UDP: no connection, no delivery guarantee, no order, but no waiting either.
+// Keep datagrams small (under ~1400 bytes) and put several metrics in one to save packets.
+// A server must expect garbage: count bad input and skip it, don't crash.
+// Snapshot copies the maps under the lock, so readers never see a half-updated state.
+// Use TCP (or HTTP) when every message matters.
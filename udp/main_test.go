@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMetric(t *testing.T) {
+	tests := []struct {
+		line string
+		want Metric
+		err  bool
+	}{
+		{"requests:1|c", Metric{"requests", 1, "c"}, false},
+		{"latency:23.5|ms", Metric{"latency", 23.5, "ms"}, false},
+		{"drift:-4|ms", Metric{"drift", -4, "ms"}, false},
+		{"a:b:1|c", Metric{}, true}, // the value is "b:1"
+		{"requests:1", Metric{}, true},
+		{"latency:fast|ms", Metric{}, true},
+		{":1|c", Metric{}, true},
+		{"cpu:3|g", Metric{}, true},
+		{"", Metric{}, true},
+		{"x:NaN|c", Metric{}, true},
+		{"x:nan|ms", Metric{}, true},
+		{"x:Inf|c", Metric{}, true},
+		{"x:+Inf|ms", Metric{}, true},
+		{"x:-inf|c", Metric{}, true},
+		{"x:infinity|c", Metric{}, true},
+		{"x:1e400|c", Metric{}, true}, // out of range
+	}
+	for _, tt := range tests {
+		got, err := ParseMetric(tt.line)
+		if tt.err {
+			if !errors.Is(err, ErrBadMetric) {
+				t.Errorf("ParseMetric(%q) error = %v, want ErrBadMetric", tt.line, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMetric(%q) = %+v, %v; want %+v", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func newSink() *MetricsSink {
+	return &MetricsSink{counters: make(map[string]float64), timers: make(map[string]TimerStats)}
+}
+
+func TestTimerStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values string
+		want   TimerStats
+	}{
+		{"positive", "t:5|ms\nt:2|ms\nt:9|ms", TimerStats{Count: 3, Min: 2, Max: 9, Sum: 16}},
+		{"all negative", "t:-5|ms\nt:-2|ms\nt:-9|ms", TimerStats{Count: 3, Min: -9, Max: -2, Sum: -16}},
+		{"one sample", "t:-7|ms", TimerStats{Count: 1, Min: -7, Max: -7, Sum: -7}},
+		{"zeros", "t:0|ms\nt:0|ms", TimerStats{Count: 2}},
+	}
+	for _, tt := range tests {
+		s := newSink()
+		s.handlePacket(tt.values)
+		if got := s.Snapshot().Timers["t"]; got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	if m := (TimerStats{}).Mean(); m != 0 {
+		t.Errorf("empty Mean = %v", m)
+	}
+	if m := (TimerStats{Count: 4, Sum: 10}).Mean(); m != 2.5 {
+		t.Errorf("Mean = %v", m)
+	}
+}
+
+func TestHandlePacketSkipsBadLines(t *testing.T) {
+	s := newSink()
+	s.handlePacket("requests:5|c\nthis is garbage\n\nerrors:1|c\n")
+	s.handlePacket("requests:2|c")
+	snap := s.Snapshot()
+	if snap.Packets != 2 || snap.Malformed != 1 || snap.Counters["requests"] != 7 || snap.Counters["errors"] != 1 {
+		t.Errorf("%+v", snap)
+	}
+	// NaN and Inf are malformed too, they don't poison what was already counted
+	s.handlePacket("requests:NaN|c\nrequests:Inf|c\nlatency:NaN|ms\nlatency:4|ms")
+	snap = s.Snapshot()
+	if snap.Malformed != 4 || snap.Counters["requests"] != 7 || snap.Timers["latency"] != (TimerStats{Count: 1, Min: 4, Max: 4, Sum: 4}) {
+		t.Errorf("after NaN and Inf: %+v", snap)
+	}
+	// the snapshot is a copy
+	snap.Counters["requests"] = 0
+	if s.Snapshot().Counters["requests"] != 7 {
+		t.Error("changing a snapshot changed the sink")
+	}
+}
+
+// waitFor polls cond, UDP gives no acknowledgement to wait on
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	sink, err := StartMetricsSink(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := DialMetricsClient(sink.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 300 {
+		client.Incr("requests", 1)
+		client.Timing("latency", time.Duration(10+i%50)*time.Millisecond)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// loopback doesn't drop packets this small, everything must arrive
+	waitFor(t, func() bool { return sink.Snapshot().Timers["latency"].Count == 300 })
+	snap := sink.Snapshot()
+	if snap.Counters["requests"] != 300 || snap.Packets != client.Sent || snap.Malformed != 0 {
+		t.Errorf("%+v, client sent %d", snap, client.Sent)
+	}
+	if lat := snap.Timers["latency"]; lat.Min != 10 || lat.Max != 59 {
+		t.Errorf("latency %+v", lat)
+	}
+	if client.Sent < 2 || client.Sent > 10 {
+		t.Errorf("600 metrics in %d datagrams, want them batched", client.Sent)
+	}
+}
+
+func TestClientBatching(t *testing.T) {
+	c := &MetricsClient{} // no conn: nothing may be sent while the batch fits
+	line := strings.Repeat("x", 100) + ":1|c"
+	for range MaxPacketSize / (len(line) + 1) {
+		if err := c.add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if c.Sent != 0 || len(c.buf) > MaxPacketSize {
+		t.Errorf("sent %d, buffered %d bytes", c.Sent, len(c.buf))
+	}
+	if err := c.add(strings.Repeat("x", MaxPacketSize) + ":1|c"); err == nil {
+		t.Error("a metric longer than a packet was accepted")
+	}
+}
+
+func TestClientCountsFailedSends(t *testing.T) {
+	c, err := DialMetricsClient("127.0.0.1:9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.conn.Close() // every write fails from here on
+	c.Incr("requests", 1)
+	if err := c.Flush(); err == nil {
+		t.Fatal("a flush on a closed connection succeeded")
+	}
+	c.Incr("requests", 1)
+	c.Flush()
+	if c.Sent != 0 || c.Failed != 2 || len(c.buf) != 0 {
+		t.Errorf("sent %d, failed %d, buffered %d bytes", c.Sent, c.Failed, len(c.buf))
+	}
+}
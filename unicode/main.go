@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReverseString reverses s rune by rune, so multi-byte characters like "é" or "🚀" stay intact.
+// Note: combining characters (e + U+0301) are separate runes and get separated on reversal.
+func ReverseString(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+// TruncateRunes shortens s to at most n runes, appending ellipsis when it had to cut.
+// The ellipsis counts towards n, so the result never has more than n runes.
+func TruncateRunes(s string, n int, ellipsis string) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	keep := n - utf8.RuneCountInString(ellipsis)
+	if keep <= 0 {
+		// not even room for the ellipsis, just cut the ellipsis itself
+		return string([]rune(ellipsis)[:n])
+	}
+	return string([]rune(s)[:keep]) + ellipsis
+}
+
+// IsASCII reports whether every byte of s is a 7-bit ASCII character
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func main() {
+	fmt.Println("Learning runes, bytes and UTF-8 in Go")
+
+	word := "Go🚀"
+
+	// len counts BYTES, not characters. 🚀 takes 4 bytes in UTF-8.
+	fmt.Println("len:", len(word))
+	fmt.Println("RuneCountInString:", utf8.RuneCountInString(word))
+
+	// Indexing a string gives a byte (uint8), not a character
+	fmt.Printf("word[2] = %d (%T), not the rocket\n", word[2], word[2])
+
+	// Iterating with a classic for loop goes byte by byte
+	for i := 0; i < len(word); i++ {
+		fmt.Printf("byte %d: %x\n", i, word[i])
+	}
+
+	// range over a string decodes runes; the index jumps over multi-byte characters
+	for index, r := range word {
+		fmt.Printf("index %d: %c (%U, %d bytes)\n", index, r, r, utf8.RuneLen(r))
+	}
+
+	// Converting to []rune gives random access by character (at the cost of a copy)
+	runes := []rune(word)
+	fmt.Printf("runes[2] = %c\n", runes[2])
+
+	// Slicing a string cuts bytes and can produce invalid UTF-8
+	broken := word[:3]
+	fmt.Printf("word[:3] = %q valid UTF-8: %v\n", broken, utf8.ValidString(broken))
+
+	// Case conversion works on runes, including non-English letters
+	fmt.Println("ToUpper:", strings.ToUpper("straße ñandú"))
+	fmt.Println("EqualFold:", strings.EqualFold("ÑANDÚ", "ñandú"))
+
+	// Pitfall: what a human sees as one character can be several runes.
+	// "é" can be one rune (U+00E9) or "e" + combining accent (U+0301)
+	composed := "café"
+	decomposed := "café"
+	fmt.Println(composed, decomposed, "equal:", composed == decomposed)
+	fmt.Println("rune counts:", utf8.RuneCountInString(composed), utf8.RuneCountInString(decomposed))
+	fmt.Printf("reversed decomposed: %q (the accent moved!)\n", ReverseString(decomposed))
+
+	// Helpers
+	for _, s := range []string{"hello", "Go🚀", "नमस्ते", ""} {
+		fmt.Printf("ReverseString(%q) = %q IsASCII: %v\n", s, ReverseString(s), IsASCII(s))
+	}
+	userAgent := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) 🚀"
+	fmt.Println("TruncateRunes:", TruncateRunes(userAgent, 30, "..."))
+	fmt.Println("TruncateRunes emoji:", TruncateRunes("🚀🚀🚀🚀🚀", 4, "…"))
+	fmt.Println("TruncateRunes short:", TruncateRunes("short", 10, "..."))
+}
+
+// byte = alias for uint8, one byte of raw data
+// rune = alias for int32, one Unicode code point
+// string = read-only bytes, by convention UTF-8 encoded
+// Use utf8.RuneCountInString for "number of characters" and range to iterate characters.
+// Even runes are not "characters" as humans see them (grapheme clusters), for that you need golang.org/x/text.
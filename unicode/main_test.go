@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestReverseString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", ""},
+		{"a", "a"},
+		{"hello", "olleh"},
+		{"héllo", "olléh"},
+		{"Go🚀", "🚀oG"},
+		{"日本語", "語本日"},
+		// combining characters are their own runes, so the accent moves to the other letter
+		{"e\u0301a", "a\u0301e"},
+	}
+	for _, tt := range tests {
+		got := ReverseString(tt.in)
+		if got != tt.want {
+			t.Errorf("ReverseString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("ReverseString(%q) is not valid UTF-8", tt.in)
+		}
+		if back := ReverseString(got); back != tt.in {
+			t.Errorf("reversing twice: %q, want %q", back, tt.in)
+		}
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		s        string
+		n        int
+		ellipsis string
+		want     string
+	}{
+		{"", 5, "...", ""},
+		{"hello", 0, "...", ""},
+		{"hello", -1, "...", ""},
+		{"hello", 5, "...", "hello"},
+		{"hello world", 8, "...", "hello..."},
+		{"hello world", 8, "…", "hello w…"},
+		{"hello world", 2, "...", ".."},
+		{"hello world", 5, "", "hello"},
+		{"🚀🚀🚀🚀🚀", 3, "…", "🚀🚀…"},
+		{"naïve café", 6, "...", "naï..."},
+		{"ééé", 4, "", "éé"},
+	}
+	for _, tt := range tests {
+		got := TruncateRunes(tt.s, tt.n, tt.ellipsis)
+		if got != tt.want {
+			t.Errorf("TruncateRunes(%q, %d, %q) = %q, want %q", tt.s, tt.n, tt.ellipsis, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("TruncateRunes(%q, %d, %q) is not valid UTF-8", tt.s, tt.n, tt.ellipsis)
+		}
+		if tt.n >= 0 && utf8.RuneCountInString(got) > tt.n {
+			t.Errorf("TruncateRunes(%q, %d, %q) has more than %d runes", tt.s, tt.n, tt.ellipsis, tt.n)
+		}
+	}
+}
+
+func TestIsASCII(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", true},
+		{"hello, world 123 ~\x7f", true},
+		{"héllo", false},
+		{"Go🚀", false},
+		{"é", false},
+		{"\x80", false},
+	}
+	for _, tt := range tests {
+		if got := IsASCII(tt.in); got != tt.want {
+			t.Errorf("IsASCII(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Upload and download, without trusting the client:
+//   - the size is capped while reading (http.MaxBytesReader), not checked afterwards
+//   - the type comes from the first 512 bytes (http.DetectContentType), not from the
+//     client's Content-Type or the file name: "cat.png" full of HTML is still HTML
+//   - the file name is only kept for display, the file is stored under a generated ID
+//
+// Downloads go through http.ServeContent, which does Content-Length, Range and 206/416.
+
+const MaxUploadSize = 1 << 20 // 1 MB per file
+
+var (
+	ErrNotFound    = errors.New("file not found")
+	ErrEmpty       = errors.New("file is empty")
+	ErrTooLarge    = errors.New("file too large")
+	ErrUnsupported = errors.New("file type not allowed")
+)
+
+// allowed maps an extension to the type DetectContentType must find in the content.
+// Both have to agree, a ".png" that sniffs as text is rejected.
+var allowed = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".pdf":  "application/pdf",
+	".txt":  "text/plain; charset=utf-8",
+}
+
+type FileInfo struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Uploaded    time.Time `json:"uploaded"`
+}
+
+// ----------------------------------------------------------------------------
+// Storage: content on disk under the ID, metadata in memory
+
+type FileStorage struct {
+	dir   string
+	mu    sync.RWMutex
+	files map[string]FileInfo
+}
+
+func NewFileStorage(dir string) *FileStorage {
+	return &FileStorage{dir: dir, files: map[string]FileInfo{}}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Put copies at most limit bytes from r into a temp file and renames it into place only when
+// everything was read, a failed upload leaves nothing behind
+func (s *FileStorage) Put(info FileInfo, r io.Reader, limit int64) (FileInfo, error) {
+	tmp, err := os.CreateTemp(s.dir, "upload-*.tmp")
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer os.Remove(tmp.Name()) // a no-op after the rename
+	n, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	switch {
+	case err != nil:
+		return FileInfo{}, err
+	case n > limit:
+		return FileInfo{}, fmt.Errorf("%w: more than %d bytes", ErrTooLarge, limit)
+	case n == 0:
+		return FileInfo{}, ErrEmpty
+	}
+	info.ID, info.Size, info.Uploaded = newID(), n, time.Now().UTC()
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, info.ID)); err != nil {
+		return FileInfo{}, err
+	}
+	s.mu.Lock()
+	s.files[info.ID] = info
+	s.mu.Unlock()
+	return info, nil
+}
+
+func (s *FileStorage) Open(id string) (*os.File, FileInfo, error) {
+	s.mu.RLock()
+	info, ok := s.files[id]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, FileInfo{}, ErrNotFound // also for "../x": only IDs we generated are ever opened
+	}
+	f, err := os.Open(filepath.Join(s.dir, id))
+	return f, info, err
+}
+
+// SanitizeFilename keeps a name safe to show and to put in Content-Disposition,
+// a shorter version of the one in the textutil lesson
+func SanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/")) // browsers on Windows send C:\Users\...
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || strings.ContainsRune(".-_ ()", r) {
+			return r
+		}
+		return '_'
+	}, name)
+	name = strings.Trim(name, ". ")
+	if len(name) > 100 {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = "" // not a real extension, slicing 100-len(ext) would go negative
+		}
+		name = strings.ToValidUTF8(name[:100-len(ext)], "") + ext
+	}
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrTooLarge), errors.As(err, &maxErr):
+		status, err = http.StatusRequestEntityTooLarge, ErrTooLarge
+	case errors.Is(err, ErrUnsupported):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrEmpty):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	}
+	msg := err.Error()
+	if status == http.StatusInternalServerError {
+		fmt.Println("    log:", err)
+		msg = "internal error"
+	}
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// upload reads the multipart body as a stream: the file goes straight to disk,
+// nothing is buffered in memory the way ParseMultipartForm would
+func upload(s *FileStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxUploadSize+64<<10) // room for the multipart headers
+		mr, err := r.MultipartReader()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "expected multipart/form-data"})
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": `no "file" field`})
+				return
+			}
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			if part.FormName() != "file" {
+				continue // NextPart skips what we didn't read
+			}
+			info, err := storeUpload(s, part)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			w.Header().Set("Location", "/api/files/"+info.ID)
+			writeJSON(w, http.StatusCreated, info)
+			return
+		}
+	}
+}
+
+func storeUpload(s *FileStorage, part *multipart.Part) (FileInfo, error) {
+	name := SanitizeFilename(part.FileName())
+	ext := strings.ToLower(filepath.Ext(name))
+	want, ok := allowed[ext]
+	if !ok {
+		return FileInfo{}, fmt.Errorf("%w: %q extension", ErrUnsupported, ext)
+	}
+	head := make([]byte, 512) // all DetectContentType ever looks at
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return FileInfo{}, err
+	}
+	head = head[:n]
+	if n == 0 {
+		return FileInfo{}, ErrEmpty
+	}
+	if got := http.DetectContentType(head); got != want {
+		return FileInfo{}, fmt.Errorf("%w: %s content in a %s file", ErrUnsupported, got, ext)
+	}
+	// the sniffed bytes first, then the rest of the part
+	return s.Put(FileInfo{Name: name, ContentType: want}, io.MultiReader(bytes.NewReader(head), part), MaxUploadSize)
+}
+
+func download(s *FileStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f, info, err := s.Open(r.PathValue("id"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		defer f.Close()
+		if strings.Contains(r.Header.Get("Range"), ",") {
+			r.Header.Del("Range") // one range only: answering with the whole file is allowed
+		}
+		w.Header().Set("Content-Type", info.ContentType) // ServeContent keeps it, no second sniffing
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+		http.ServeContent(w, r, info.Name, info.Uploaded, f) // Content-Length, Range, 206 and 416
+	}
+}
+
+func routes(s *FileStorage) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/files", upload(s))
+	mux.HandleFunc("GET /api/files/{id}", download(s))
+	return mux
+}
+
+// ----------------------------------------------------------------------------
+// Demo client
+
+// multipartBody builds a form with one file field, the way a browser would
+func multipartBody(field, filename string, content []byte) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("description", "sent before the file, skipped by the server")
+	fw, _ := mw.CreateFormFile(field, filename)
+	fw.Write(content)
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func main() {
+	fmt.Println("Learning file uploads and downloads in Go")
+
+	dir, err := os.MkdirTemp("", "uploads")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	srv := httptest.NewServer(routes(NewFileStorage(dir)))
+	defer srv.Close()
+
+	send := func(field, filename string, content []byte) (int, FileInfo, string) {
+		body, ctype := multipartBody(field, filename, content)
+		resp, err := http.Post(srv.URL+"/api/files", ctype, body)
+		if err != nil {
+			return 0, FileInfo{}, err.Error()
+		}
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(resp.Body)
+		var info FileInfo
+		json.Unmarshal(raw, &info)
+		return resp.StatusCode, info, strings.TrimSpace(string(raw))
+	}
+	get := func(id, rangeHeader string) (*http.Response, []byte) {
+		req, _ := http.NewRequest("GET", srv.URL+"/api/files/"+id, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return nil, nil
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	// a PNG is recognized by its first 8 bytes, the rest here is filler
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0xAB, 0xCD}, 500)...)
+	png = append(png, "END!"...)
+
+	fmt.Println("1. Upload a small PNG")
+	code, info, _ := send("file", `C:\Users\rishabh\Desktop\my photo (1).png`, png)
+	fmt.Printf("  %d id=%s… name=%q type=%s size=%d\n", code, info.ID[:8], info.Name, info.ContentType, info.Size)
+
+	fmt.Println("2. Download it")
+	resp, body := get(info.ID, "")
+	fmt.Printf("  %d %s Content-Length=%s same bytes: %v\n", resp.StatusCode, resp.Header.Get("Content-Type"),
+		resp.Header.Get("Content-Length"), bytes.Equal(body, png))
+	fmt.Println("  Content-Disposition:", resp.Header.Get("Content-Disposition"))
+	for _, rg := range []string{"bytes=0-7", "bytes=-4", "bytes=1008-", "bytes=0-1,10-11", "bytes=5000-6000"} {
+		resp, body = get(info.ID, rg)
+		shown := fmt.Sprintf("%q", body)
+		if len(body) > 12 {
+			shown = fmt.Sprintf("(%d bytes)", len(body))
+		}
+		fmt.Printf("  Range %-16s -> %d Content-Range=%-20q %s\n", rg, resp.StatusCode, resp.Header.Get("Content-Range"), shown)
+	}
+
+	fmt.Println("3. Uploads that are refused")
+	exe := append([]byte("MZ\x90\x00"), make([]byte, 100)...)
+	for _, c := range []struct {
+		field, name string
+		content     []byte
+	}{
+		{"file", "setup.exe", exe},
+		{"file", "cat.png", []byte("<html><script>alert(1)</script></html>")},
+		{"file", "notes.txt", exe},
+		{"file", "big.txt", bytes.Repeat([]byte("a"), MaxUploadSize+1)},
+		{"file", "huge.txt", bytes.Repeat([]byte("a"), 3*MaxUploadSize)},
+		{"file", "empty.png", nil},
+		{"avatar", "me.png", png},
+	} {
+		code, _, body := send(c.field, c.name, c.content)
+		fmt.Printf("  %-6s %-10s -> %d %s\n", c.field, c.name, code, body)
+	}
+
+	fmt.Println("4. Names and IDs")
+	code, info, _ = send("file", "../../etc/passwd.txt", []byte("root:x:0:0"))
+	fmt.Printf("  ../../etc/passwd.txt -> %d stored as %q under id %s…\n", code, info.Name, info.ID[:8])
+	for _, id := range []string{"nope", "..%2F..%2Fetc%2Fpasswd"} {
+		resp, body = get(id, "")
+		fmt.Printf("  GET /api/files/%s -> %d %s", id, resp.StatusCode, body)
+	}
+	entries, _ := os.ReadDir(dir)
+	fmt.Println("  files on disk (no temp files left over):", len(entries))
+}
+
+// Cap the body with http.MaxBytesReader and the file with a LimitReader: 413, not a full disk.
+// Decide the type from the content with http.DetectContentType, and require the extension to agree.
+// Store under a generated ID, the client's file name is only for display and Content-Disposition.
+// Stream multipart parts to a temp file and rename it when complete, a failed upload leaves nothing.
+// http.ServeContent handles Content-Length, Range, 206 and 416, set Content-Type and nosniff yourself.
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+type testServer struct {
+	dir string
+	url string
+}
+
+func newTestServer(t *testing.T) *testServer {
+	dir := t.TempDir()
+	srv := httptest.NewServer(routes(NewFileStorage(dir)))
+	t.Cleanup(srv.Close)
+	return &testServer{dir: dir, url: srv.URL}
+}
+
+func (ts *testServer) upload(t *testing.T, field, filename string, content []byte) (int, FileInfo, string) {
+	t.Helper()
+	body, ctype := multipartBody(field, filename, content)
+	resp, err := http.Post(ts.url+"/api/files", ctype, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(resp.Body)
+	var info FileInfo
+	json.Unmarshal(raw, &info)
+	return resp.StatusCode, info, strings.TrimSpace(string(raw))
+}
+
+func (ts *testServer) get(t *testing.T, id, rangeHeader string) (*http.Response, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", ts.url+"/api/files/"+id, nil)
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+// onDisk lists what is left in the storage directory, temp files included
+func (ts *testServer) onDisk(t *testing.T) []string {
+	t.Helper()
+	entries, err := os.ReadDir(ts.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// a small PNG: the signature, then every byte value so nothing in the middle is special
+func pngBytes() []byte {
+	b := []byte("\x89PNG\r\n\x1a\n")
+	for i := range 1000 {
+		b = append(b, byte(i))
+	}
+	return append(b, "END!"...)
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	ts := newTestServer(t)
+	png := pngBytes()
+	code, info, body := ts.upload(t, "file", "photo.png", png)
+	if code != http.StatusCreated || info.Name != "photo.png" || info.ContentType != "image/png" || info.Size != int64(len(png)) {
+		t.Fatalf("upload: %d %s", code, body)
+	}
+	if len(info.ID) != 32 || info.Uploaded.IsZero() {
+		t.Errorf("id %q uploaded %v", info.ID, info.Uploaded)
+	}
+
+	resp, got := ts.get(t, info.ID, "")
+	if resp.StatusCode != 200 || !bytes.Equal(got, png) {
+		t.Fatalf("download: %d, %d bytes, same: %v", resp.StatusCode, len(got), bytes.Equal(got, png))
+	}
+	headers := map[string]string{
+		"Content-Type":           "image/png",
+		"Content-Length":         fmt.Sprint(len(png)),
+		"Content-Disposition":    `attachment; filename=photo.png`,
+		"X-Content-Type-Options": "nosniff",
+		"Accept-Ranges":          "bytes",
+	}
+	for k, want := range headers {
+		if got := resp.Header.Get(k); got != want {
+			t.Errorf("%s: %q, want %q", k, got, want)
+		}
+	}
+	if got := ts.onDisk(t); len(got) != 1 || got[0] != info.ID {
+		t.Errorf("on disk: %v", got)
+	}
+}
+
+func TestRange(t *testing.T) {
+	ts := newTestServer(t)
+	png := pngBytes()
+	_, info, _ := ts.upload(t, "file", "photo.png", png)
+	size := len(png)
+	tests := []struct {
+		header       string
+		code         int
+		contentRange string
+		body         []byte
+	}{
+		{"bytes=0-7", 206, fmt.Sprintf("bytes 0-7/%d", size), png[:8]},
+		{"bytes=8-9", 206, fmt.Sprintf("bytes 8-9/%d", size), png[8:10]},
+		{"bytes=-4", 206, fmt.Sprintf("bytes %d-%d/%d", size-4, size-1, size), []byte("END!")},
+		{fmt.Sprintf("bytes=%d-", size-4), 206, fmt.Sprintf("bytes %d-%d/%d", size-4, size-1, size), []byte("END!")},
+		{"bytes=1000-99999", 206, fmt.Sprintf("bytes 1000-%d/%d", size-1, size), png[1000:]},
+		{"bytes=0-1,10-11", 200, "", png}, // more than one range: the whole file
+		{fmt.Sprintf("bytes=%d-", size), 416, fmt.Sprintf("bytes */%d", size), nil},
+		{"bytes=5000-6000", 416, fmt.Sprintf("bytes */%d", size), nil},
+	}
+	for _, tt := range tests {
+		resp, body := ts.get(t, info.ID, tt.header)
+		if resp.StatusCode != tt.code || resp.Header.Get("Content-Range") != tt.contentRange {
+			t.Errorf("%s: %d Content-Range=%q, want %d %q", tt.header, resp.StatusCode, resp.Header.Get("Content-Range"), tt.code, tt.contentRange)
+			continue
+		}
+		if tt.body == nil {
+			continue
+		}
+		if !bytes.Equal(body, tt.body) || resp.Header.Get("Content-Length") != fmt.Sprint(len(tt.body)) {
+			t.Errorf("%s: %d bytes, Content-Length %s, want %d", tt.header, len(body), resp.Header.Get("Content-Length"), len(tt.body))
+		}
+		if resp.Header.Get("Content-Type") != "image/png" {
+			t.Errorf("%s: Content-Type %q", tt.header, resp.Header.Get("Content-Type"))
+		}
+	}
+}
+
+func TestRefused(t *testing.T) {
+	ts := newTestServer(t)
+	exe := append([]byte("MZ\x90\x00"), make([]byte, 100)...)
+	tests := []struct {
+		name     string
+		field    string
+		filename string
+		content  []byte
+		code     int
+		msg      string
+	}{
+		{"exe", "file", "setup.exe", exe, 415, `file type not allowed: ".exe" extension`},
+		{"no extension", "file", "README", []byte("hello"), 415, `file type not allowed: "" extension`},
+		{"html in a png", "file", "cat.png", []byte("<html><script>alert(1)</script></html>"), 415,
+			"file type not allowed: text/html; charset=utf-8 content in a .png file"},
+		{"binary in a txt", "file", "notes.txt", exe, 415, "file type not allowed: application/octet-stream content in a .txt file"},
+		{"png in a jpg", "file", "photo.jpg", pngBytes(), 415, "file type not allowed: image/png content in a .jpg file"},
+		{"empty", "file", "empty.png", nil, 400, "file is empty"},
+		{"empty txt", "file", "empty.txt", []byte{}, 400, "file is empty"},
+		{"one byte over", "file", "big.txt", bytes.Repeat([]byte("a"), MaxUploadSize+1), 413, "file too large"},
+		{"far over", "file", "huge.txt", bytes.Repeat([]byte("a"), 3*MaxUploadSize), 413, "file too large"},
+		{"wrong field", "avatar", "me.png", pngBytes(), 400, `no "file" field`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, body := ts.upload(t, tt.field, tt.filename, tt.content)
+			var resp struct{ Error string }
+			json.Unmarshal([]byte(body), &resp)
+			if code != tt.code || resp.Error != tt.msg {
+				t.Errorf("%d %s, want %d %q", code, body, tt.code, tt.msg)
+			}
+		})
+	}
+	if got := ts.onDisk(t); len(got) != 0 {
+		t.Errorf("refused uploads left %v behind", got)
+	}
+}
+
+func TestSizeLimit(t *testing.T) {
+	ts := newTestServer(t)
+	exact := bytes.Repeat([]byte("a"), MaxUploadSize)
+	code, info, body := ts.upload(t, "file", "exact.txt", exact)
+	if code != http.StatusCreated || info.Size != MaxUploadSize {
+		t.Fatalf("exactly the limit: %d %s", code, body)
+	}
+	resp, got := ts.get(t, info.ID, "")
+	if resp.Header.Get("Content-Type") != "text/plain; charset=utf-8" || !bytes.Equal(got, exact) {
+		t.Errorf("download: %s, %d bytes", resp.Header.Get("Content-Type"), len(got))
+	}
+}
+
+func TestNotMultipart(t *testing.T) {
+	ts := newTestServer(t)
+	for _, ctype := range []string{"application/json", "text/plain", ""} {
+		resp, err := http.Post(ts.url+"/api/files", ctype, strings.NewReader(`{"file":"x"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != 400 || !strings.Contains(string(body), "expected multipart/form-data") {
+			t.Errorf("%q: %d %s", ctype, resp.StatusCode, body)
+		}
+	}
+}
+
+// the file field after other fields, and a second file field that is never read
+func TestFieldOrder(t *testing.T) {
+	ts := newTestServer(t)
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("a", strings.Repeat("x", 10000))
+	fw, _ := mw.CreateFormFile("file", "first.txt")
+	fw.Write([]byte("first"))
+	fw, _ = mw.CreateFormFile("file", "second.txt")
+	fw.Write([]byte("second"))
+	mw.Close()
+	resp, err := http.Post(ts.url+"/api/files", mw.FormDataContentType(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var info FileInfo
+	json.NewDecoder(resp.Body).Decode(&info)
+	if resp.StatusCode != 201 || info.Name != "first.txt" || resp.Header.Get("Location") != "/api/files/"+info.ID {
+		t.Fatalf("%d %+v Location=%q", resp.StatusCode, info, resp.Header.Get("Location"))
+	}
+	if _, body := ts.get(t, info.ID, ""); string(body) != "first" {
+		t.Errorf("stored %q", body)
+	}
+}
+
+func TestUnknownIDs(t *testing.T) {
+	ts := newTestServer(t)
+	os.WriteFile(filepath.Join(ts.dir, "planted"), []byte("secret"), 0o644)
+	for _, id := range []string{"nope", "planted", "..%2F..%2Fetc%2Fpasswd", "00000000000000000000000000000000"} {
+		resp, body := ts.get(t, id, "")
+		if resp.StatusCode != 404 || strings.Contains(string(body), "secret") {
+			t.Errorf("%s: %d %s", id, resp.StatusCode, body)
+		}
+	}
+}
+
+// the client's name never becomes a path, it is only what Content-Disposition shows
+func TestFilenames(t *testing.T) {
+	ts := newTestServer(t)
+	tests := []struct{ sent, stored string }{
+		{"../../etc/passwd.txt", "passwd.txt"},
+		{`C:\Users\rishabh\Desktop\my photo (1).txt`, "my photo (1).txt"},
+		{"नमस्ते.txt", "नमस्ते.txt"},
+		{"a\"b;c.txt", "a_b_c.txt"},
+		{"..hidden.txt", "hidden.txt"},
+	}
+	for _, tt := range tests {
+		code, info, body := ts.upload(t, "file", tt.sent, []byte("hello"))
+		if code != 201 || info.Name != tt.stored {
+			t.Errorf("%q: %d %s, want name %q", tt.sent, code, body, tt.stored)
+			continue
+		}
+		resp, _ := ts.get(t, info.ID, "")
+		if got := resp.Header.Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; filename") {
+			t.Errorf("%q: Content-Disposition %q", tt.sent, got)
+		}
+	}
+	for _, name := range ts.onDisk(t) {
+		if len(name) != 32 {
+			t.Errorf("stored under %q, not an ID", name)
+		}
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Windows\evil.exe`, "evil.exe"},
+		{"a/b\\c.txt", "c.txt"},
+		{"", "file"},
+		{"..", "file"},
+		{"/", "_"},
+		{" . ", "file"},
+		{"name\x00with\nnul.txt", "name_with_nul.txt"},
+		{"<script>.txt", "_script_.txt"},
+		{"हिन्दी.txt", "हिन्दी.txt"}, // the vowel signs are marks, not letters
+		{"über café.txt", "über café.txt"},
+	}
+	for _, tt := range tests {
+		if got := SanitizeFilename(tt.in); got != tt.want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := []string{
+		strings.Repeat("a", 300) + ".png",
+		strings.Repeat("é", 300) + ".png",
+		"a." + strings.Repeat("b", 300), // an "extension" longer than the limit
+		strings.Repeat("x", 50) + "." + strings.Repeat("ü", 60),
+	}
+	for _, in := range long {
+		got := SanitizeFilename(in)
+		if len(got) > 100 || !utf8.ValidString(got) || got == "" {
+			t.Errorf("%.20q…: %d bytes %q", in, len(got), got)
+		}
+		if strings.HasSuffix(in, ".png") && !strings.HasSuffix(got, ".png") {
+			t.Errorf("%.20q…: lost the extension: %q", in, got)
+		}
+	}
+}
+
+func TestConcurrentUploads(t *testing.T) {
+	ts := newTestServer(t)
+	const n = 20
+	ids := make([]string, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			body, ctype := multipartBody("file", fmt.Sprintf("f%d.txt", i), []byte(fmt.Sprintf("content %d", i)))
+			resp, err := http.Post(ts.url+"/api/files", ctype, body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer resp.Body.Close()
+			var info FileInfo
+			json.NewDecoder(resp.Body).Decode(&info)
+			ids[i] = info.ID
+		})
+	}
+	wg.Wait()
+	for i, id := range ids {
+		if _, body := ts.get(t, id, ""); string(body) != fmt.Sprintf("content %d", i) {
+			t.Errorf("upload %d: %q", i, body)
+		}
+	}
+	if got := ts.onDisk(t); len(got) != n {
+		t.Errorf("%d files on disk", len(got))
+	}
+}
+
+func TestPut(t *testing.T) {
+	s := NewFileStorage(t.TempDir())
+	if _, err := s.Put(FileInfo{Name: "x"}, strings.NewReader("12345"), 4); err == nil || !strings.Contains(err.Error(), "file too large") {
+		t.Errorf("over the limit: %v", err)
+	}
+	if _, err := s.Put(FileInfo{Name: "x"}, strings.NewReader(""), 4); err != ErrEmpty {
+		t.Errorf("empty: %v", err)
+	}
+	info, err := s.Put(FileInfo{Name: "x"}, strings.NewReader("1234"), 4)
+	if err != nil || info.Size != 4 {
+		t.Fatalf("at the limit: %+v %v", info, err)
+	}
+	f, got, err := s.Open(info.ID)
+	if err != nil || got != info {
+		t.Fatalf("open: %+v %v", got, err)
+	}
+	f.Close()
+	if _, _, err := s.Open("../" + info.ID); err != ErrNotFound {
+		t.Errorf("open with a path: %v", err)
+	}
+	if _, err := NewFileStorage(filepath.Join(t.TempDir(), "missing")).Put(FileInfo{}, strings.NewReader("x"), 4); err == nil {
+		t.Error("no error for a missing directory")
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,25 +14,140 @@ import (
 // The main goroutine calls Add to set the number of goroutines to wait for.
 // Then each of the goroutines runs and calls Done when finished.
 // At the same time, Wait can be used to block until all goroutines have finished.
-var wg sync.WaitGroup
 
 // wg := new(sync.WaitGroup) way to create wait group pointer
 // var wg *sync.WaitGroup = &sync.WaitGroup{} another way to create wait group pointer
+// Keep the WaitGroup local to the function that waits: a package-level one is shared by
+// every caller and a second caller's Wait would also wait for the first caller's goroutines.
 
-func Worker(id int) {
-	defer wg.Done()
-	fmt.Println("Worker", id, "started")
-	time.Sleep(time.Second)
-	fmt.Println("Worker", id, "done")
+// ErrTaskPanicked is wrapped by the error of a task that panicked
+var ErrTaskPanicked = errors.New("task panicked")
+
+// RunParallel runs the named tasks with at most maxConcurrent running at the same time
+// (maxConcurrent <= 0 means no limit) and returns every task's error, nil for success.
+// A panicking task becomes an error wrapping ErrTaskPanicked instead of crashing the program.
+// Once ctx is cancelled, tasks that haven't started yet are skipped with ctx's error.
+// Tasks are started in name order, so the result doesn't depend on map iteration order.
+func RunParallel(ctx context.Context, tasks map[string]func(ctx context.Context) error, maxConcurrent int) map[string]error {
+	names := make([]string, 0, len(tasks))
+	for name := range tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if maxConcurrent <= 0 || maxConcurrent > len(names) {
+		maxConcurrent = len(names)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex // guards results, several goroutines write to it
+	results := make(map[string]error, len(tasks))
+	setResult := func(name string, err error) {
+		mu.Lock()
+		results[name] = err
+		mu.Unlock()
+	}
+
+	sem := make(chan struct{}, maxConcurrent) // a buffered channel as a semaphore: sending takes a slot
+	for _, name := range names {
+		select {
+		case <-ctx.Done():
+			setResult(name, fmt.Errorf("task %s not started: %w", name, ctx.Err()))
+			continue
+		case sem <- struct{}{}:
+		}
+		// the slot may have become free at the same moment ctx was cancelled, select picks randomly
+		if ctx.Err() != nil {
+			<-sem
+			setResult(name, fmt.Errorf("task %s not started: %w", name, ctx.Err()))
+			continue
+		}
+
+		wg.Add(1) // Add before starting the goroutine, never inside it
+		go func(name string, task func(ctx context.Context) error) {
+			defer wg.Done()
+			defer func() { <-sem }() // free the slot
+			defer func() {
+				if r := recover(); r != nil {
+					setResult(name, fmt.Errorf("task %s: %w: %v", name, ErrTaskPanicked, r))
+				}
+			}()
+			setResult(name, task(ctx))
+		}(name, tasks[name])
+	}
+	wg.Wait()
+	return results
+}
+
+// sleepTask returns a task that works for d, or stops early when ctx is cancelled
+func sleepTask(d time.Duration, running, highWater *atomic.Int32) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for cur := highWater.Load(); n > cur && !highWater.CompareAndSwap(cur, n); cur = highWater.Load() {
+		}
+		select {
+		case <-time.After(d):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func printResults(results map[string]error) {
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := "ok"
+		if err := results[name]; err != nil {
+			status = err.Error()
+		}
+		fmt.Printf("  %-10s %s\n", name, status)
+	}
 }
 
 func main() {
-	for i := 0; i < 5; i++ {
-		wg.Add(1)
-		go Worker(i)
+	fmt.Println("Learning sync.WaitGroup with a parallel task runner")
+
+	// 1. a mix of succeeding, failing and panicking tasks, at most 3 at a time
+	var running, highWater atomic.Int32
+	tasks := map[string]func(ctx context.Context) error{
+		"fetch-a": sleepTask(50*time.Millisecond, &running, &highWater),
+		"fetch-b": sleepTask(80*time.Millisecond, &running, &highWater),
+		"fetch-c": sleepTask(30*time.Millisecond, &running, &highWater),
+		"fetch-d": sleepTask(60*time.Millisecond, &running, &highWater),
+		"fetch-e": sleepTask(40*time.Millisecond, &running, &highWater),
+		"validate": func(ctx context.Context) error {
+			return errors.New("email is missing")
+		},
+		"resize": func(ctx context.Context) error {
+			var m map[string]int
+			m["boom"] = 1 // assignment to a nil map panics
+			return nil
+		},
 	}
-	wg.Wait()
-	fmt.Println("Main code done")
+	start := time.Now()
+	results := RunParallel(context.Background(), tasks, 3)
+	fmt.Printf("Finished in %s, at most %d tasks ran at the same time\n", time.Since(start).Round(10*time.Millisecond), highWater.Load())
+	printResults(results)
+	fmt.Println("resize panicked:", errors.Is(results["resize"], ErrTaskPanicked))
+
+	// 2. cancellation: one slot, a 100ms timeout and five 60ms tasks
+	// -> the first finishes, the second is cut short, the rest never start
+	running.Store(0)
+	highWater.Store(0)
+	slow := make(map[string]func(ctx context.Context) error)
+	for i := 1; i <= 5; i++ {
+		slow[fmt.Sprintf("slow-%d", i)] = sleepTask(60*time.Millisecond, &running, &highWater)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results = RunParallel(ctx, slow, 1)
+	fmt.Println("With a 100ms timeout:")
+	printResults(results)
 }
 
 // Output random but wait till last all go routine finishes it works
@@ -50,3 +169,8 @@ func main() {
 
 // 	fmt.Println("Main code done")
 // }
+
+// A buffered channel of size N works as a semaphore: at most N goroutines hold a slot.
+// recover() only works inside a deferred function of the goroutine that panicked,
+// a panic in any goroutine without recover crashes the whole program.
+// golang.org/x/sync/errgroup offers a similar runner (Group.SetLimit, first error cancels the rest).
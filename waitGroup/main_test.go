@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunParallelBound(t *testing.T) {
+	for _, limit := range []int{1, 3, 8} {
+		t.Run(fmt.Sprint("limit=", limit), func(t *testing.T) {
+			var running, highWater atomic.Int32
+			tasks := map[string]func(ctx context.Context) error{}
+			for i := range 12 {
+				tasks[fmt.Sprint("task-", i)] = sleepTask(10*time.Millisecond, &running, &highWater)
+			}
+			results := RunParallel(context.Background(), tasks, limit)
+			if len(results) != 12 {
+				t.Fatalf("%d results, want 12", len(results))
+			}
+			for name, err := range results {
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+			if hw := highWater.Load(); hw > int32(limit) || hw < 1 {
+				t.Errorf("high-water mark %d, limit %d", hw, limit)
+			}
+			if limit == 8 && highWater.Load() < 2 {
+				t.Error("tasks never overlapped with a limit of 8")
+			}
+		})
+	}
+}
+
+func TestRunParallelNoLimit(t *testing.T) {
+	var running, highWater atomic.Int32
+	release := make(chan struct{})
+	tasks := map[string]func(ctx context.Context) error{}
+	for i := range 5 {
+		tasks[fmt.Sprint(i)] = func(ctx context.Context) error {
+			n := running.Add(1)
+			for cur := highWater.Load(); n > cur && !highWater.CompareAndSwap(cur, n); cur = highWater.Load() {
+			}
+			if n == 5 {
+				close(release) // only reachable when all five run at once
+			}
+			<-release
+			return nil
+		}
+	}
+	done := make(chan map[string]error)
+	go func() { done <- RunParallel(context.Background(), tasks, 0) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("maxConcurrent 0 did not run all tasks at once")
+	}
+	if len(RunParallel(context.Background(), nil, 3)) != 0 {
+		t.Error("no tasks gave results")
+	}
+}
+
+func TestRunParallelErrorsAndPanics(t *testing.T) {
+	boom := errors.New("boom")
+	results := RunParallel(context.Background(), map[string]func(ctx context.Context) error{
+		"ok":    func(ctx context.Context) error { return nil },
+		"fails": func(ctx context.Context) error { return boom },
+		"nilmap": func(ctx context.Context) error {
+			var m map[string]int
+			m["x"] = 1
+			return nil
+		},
+		"custom": func(ctx context.Context) error { panic(fmt.Sprintf("bad input %d", 7)) },
+	}, 2)
+	if err, ok := results["ok"]; !ok || err != nil {
+		t.Errorf("ok: %v, %v", err, ok)
+	}
+	if !errors.Is(results["fails"], boom) {
+		t.Errorf("fails: %v", results["fails"])
+	}
+	for _, name := range []string{"nilmap", "custom"} {
+		if !errors.Is(results[name], ErrTaskPanicked) {
+			t.Errorf("%s: %v, want ErrTaskPanicked", name, results[name])
+		}
+	}
+	if want := "task custom: task panicked: bad input 7"; results["custom"].Error() != want {
+		t.Errorf("custom: %q, want %q", results["custom"], want)
+	}
+}
+
+func TestRunParallelCancellation(t *testing.T) {
+	var ran atomic.Int32
+	task := func(ctx context.Context) error { ran.Add(1); return nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := RunParallel(ctx, map[string]func(ctx context.Context) error{"a": task, "b": task, "c": task}, 2)
+	if ran.Load() != 0 {
+		t.Errorf("%d tasks ran with a cancelled context", ran.Load())
+	}
+	for name, err := range results {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: %v, want context.Canceled", name, err)
+		}
+	}
+
+	// one slot: "a" starts and cancels, the tasks after it must be skipped
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	ran.Store(0)
+	results = RunParallel(ctx, map[string]func(ctx context.Context) error{
+		"a": func(ctx context.Context) error { ran.Add(1); cancel(); return nil },
+		"b": task,
+		"c": task,
+	}, 1)
+	if ran.Load() != 1 || results["a"] != nil {
+		t.Errorf("ran %d tasks, a: %v", ran.Load(), results["a"])
+	}
+	for _, name := range []string{"b", "c"} {
+		if !errors.Is(results[name], context.Canceled) {
+			t.Errorf("%s: %v, want skipped with context.Canceled", name, results[name])
+		}
+	}
+}
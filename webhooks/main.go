@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A webhook turns "poll us every minute" into "we call you when something happens".
+// The hard parts are not the POST itself:
+//   - the receiver must be able to tell the call really came from us (HMAC signature)
+//   - an old captured call must not be accepted again (timestamp inside the signature)
+//   - receivers go down, so failures are retried with backoff and every attempt is recorded
+//   - none of this may slow down the request that caused the event (a worker pool does it)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	MaxClockSkew    = 5 * time.Minute
+)
+
+var (
+	ErrBadSignature = errors.New("bad signature")
+	ErrStale        = errors.New("timestamp too old or in the future")
+	ErrClosed       = errors.New("dispatcher closed")
+)
+
+// ----------------------------------------------------------------------------
+// Signing, used by both sides
+
+// Sign returns hex(HMAC-SHA256(secret, "<timestamp>.<body>")). The timestamp is signed too,
+// so an attacker can't replay an old body with a fresh timestamp.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is what a receiver runs before trusting a delivery
+func Verify(secret string, r *http.Request, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrBadSignature
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > MaxClockSkew || d < -MaxClockSkew {
+		return ErrStale
+	}
+	want := Sign(secret, ts, body)
+	// hmac.Equal takes the same time wherever the first difference is
+	if !hmac.Equal([]byte(want), []byte(r.Header.Get(SignatureHeader))) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Events
+
+type Event struct {
+	ID   string    `json:"id"` // receivers use it to ignore a delivery they already processed
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+	Data any       `json:"data"`
+}
+
+// EventBus calls every subscriber for every event. Subscribers must return quickly,
+// Publish runs on the request's goroutine.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs []func(Event)
+}
+
+func (b *EventBus) Subscribe(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, fn)
+}
+
+func (b *EventBus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, fn := range b.subs {
+		fn(e)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Webhook registry and delivery history
+
+type Webhook struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"` // shown once, when the webhook is created
+}
+
+func (w Webhook) Wants(eventType string) bool {
+	return slices.Contains(w.Events, eventType) || slices.Contains(w.Events, "*")
+}
+
+type Attempt struct {
+	EventID  string    `json:"event_id"`
+	Event    string    `json:"event"`
+	Attempt  int       `json:"attempt"`
+	At       time.Time `json:"at"`
+	Status   int       `json:"status,omitempty"` // 0 when the request didn't get an answer
+	Error    string    `json:"error,omitempty"`
+	Duration string    `json:"duration"`
+}
+
+type Registry struct {
+	mu         sync.RWMutex
+	hooks      map[int]Webhook
+	deliveries map[int][]Attempt
+	nextID     int
+}
+
+func NewRegistry() *Registry {
+	return &Registry{hooks: map[int]Webhook{}, deliveries: map[int][]Attempt{}, nextID: 1}
+}
+
+func (r *Registry) Add(url string, events []string) Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w := Webhook{ID: r.nextID, URL: url, Events: events, Secret: "whsec_" + crand.Text()}
+	r.nextID++
+	r.hooks[w.ID] = w
+	return w
+}
+
+func (r *Registry) Matching(eventType string) []Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var out []Webhook
+	for _, w := range r.hooks {
+		if w.Wants(eventType) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func (r *Registry) Record(hookID int, a Attempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[hookID] = append(r.deliveries[hookID], a)
+}
+
+func (r *Registry) Deliveries(hookID int) ([]Attempt, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.hooks[hookID]
+	return slices.Clone(r.deliveries[hookID]), ok
+}
+
+// ----------------------------------------------------------------------------
+// Dispatcher: a worker pool that delivers with retries
+
+type job struct {
+	hook Webhook
+	body []byte
+	evt  Event
+}
+
+type Dispatcher struct {
+	Registry    *Registry
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration // first retry waits about this long, doubling after that
+
+	mu      sync.Mutex // guards closed and the send on jobs, so nothing sends on a closed channel
+	closed  bool
+	jobs    chan job
+	pending sync.WaitGroup // jobs queued or running, for Flush
+	workers sync.WaitGroup
+}
+
+func NewDispatcher(reg *Registry, workers, queue int) *Dispatcher {
+	d := &Dispatcher{
+		Registry:    reg,
+		Client:      &http.Client{Timeout: 5 * time.Second},
+		MaxAttempts: 5,
+		BaseDelay:   time.Second,
+		jobs:        make(chan job, queue),
+	}
+	for range workers {
+		d.workers.Add(1)
+		go d.worker()
+	}
+	return d
+}
+
+// Handle is the EventBus subscriber: it only queues, the workers do the network calls.
+// After Close nothing is queued any more, the drop is recorded and Handle returns ErrClosed.
+func (d *Dispatcher) Handle(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, w := range d.Registry.Matching(e.Type) {
+		if d.closed {
+			d.Registry.Record(w.ID, Attempt{EventID: e.ID, Event: e.Type, At: time.Now(), Error: "dispatcher closed, dropped"})
+			continue
+		}
+		d.pending.Add(1)
+		select {
+		case d.jobs <- job{hook: w, body: body, evt: e}:
+		default:
+			// a full queue must not block user creation; a real system writes to an outbox instead
+			d.pending.Done()
+			d.Registry.Record(w.ID, Attempt{EventID: e.ID, Event: e.Type, At: time.Now(), Error: "queue full, dropped"})
+		}
+	}
+	if d.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Flush waits until every queued delivery has succeeded or given up
+func (d *Dispatcher) Flush() { d.pending.Wait() }
+
+// Close stops the workers after the queue is drained. Calling it again does nothing.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.jobs)
+	}
+	d.mu.Unlock()
+	d.workers.Wait()
+}
+
+func (d *Dispatcher) worker() {
+	defer d.workers.Done()
+	for j := range d.jobs {
+		d.deliver(j)
+		d.pending.Done()
+	}
+}
+
+// backoff: BaseDelay * 2^(attempt-1), plus up to 50% jitter so failed receivers
+// aren't hit by every retry at the same moment when they come back
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.BaseDelay << (attempt - 1)
+	return delay + rand.N(delay/2+1)
+}
+
+func (d *Dispatcher) deliver(j job) {
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		start := time.Now()
+		status, err := d.post(j)
+		a := Attempt{EventID: j.evt.ID, Event: j.evt.Type, Attempt: attempt, At: start, Status: status,
+			Duration: time.Since(start).Round(time.Millisecond).String()}
+		if err != nil {
+			a.Error = err.Error()
+		}
+		d.Registry.Record(j.hook.ID, a)
+		if err == nil {
+			return
+		}
+		if status >= 400 && status < 500 && status != http.StatusTooManyRequests {
+			return // the receiver rejected the payload itself, sending it again won't help
+		}
+		if attempt < d.MaxAttempts {
+			time.Sleep(d.backoff(attempt))
+		}
+	}
+}
+
+func (d *Dispatcher) post(j job) (int, error) {
+	ts := time.Now().Unix() // a new timestamp and signature on every attempt, retries stay fresh
+	req, err := http.NewRequest(http.MethodPost, j.hook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(j.hook.Secret, ts, j.body))
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // read it, so the connection can be reused
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// ----------------------------------------------------------------------------
+// HTTP API
+
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func routes(reg *Registry, bus *EventBus) http.Handler {
+	var userSeq, eventSeq atomic.Int64
+	newEvent := func(typ string, data any) Event {
+		return Event{ID: fmt.Sprintf("evt_%d", eventSeq.Add(1)), Type: typ, At: time.Now().UTC(), Data: data}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/admin/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || !strings.HasPrefix(in.URL, "http") || len(in.Events) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "url and events are required"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, reg.Add(in.URL, in.Events))
+	})
+	mux.HandleFunc("GET /api/admin/webhooks/{id}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		attempts, ok := reg.Deliveries(id)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "webhook not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, attempts)
+	})
+	mux.HandleFunc("POST /api/users", func(w http.ResponseWriter, r *http.Request) {
+		var u User
+		json.NewDecoder(r.Body).Decode(&u)
+		u.ID = int(userSeq.Add(1))
+		bus.Publish(newEvent("user.created", u)) // only queues, returns at once
+		writeJSON(w, http.StatusCreated, u)
+	})
+	mux.HandleFunc("DELETE /api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		bus.Publish(newEvent("user.deleted", map[string]int{"id": id}))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func main() {
+	fmt.Println("Learning webhook delivery with signing and retry in Go")
+
+	reg := NewRegistry()
+	bus := &EventBus{}
+	disp := NewDispatcher(reg, 4, 100)
+	disp.BaseDelay = 20 * time.Millisecond // seconds in real life, short for the demo
+	// an event dropped after Close is in the delivery history, the bus has no use for the error
+	bus.Subscribe(func(e Event) { disp.Handle(e) })
+	api := routes(reg, bus)
+
+	// the receiver: slow, fails every event twice, then accepts; it verifies every call
+	var secret atomic.Value
+	var mu sync.Mutex
+	calls := map[string]int{}
+	var received []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify(secret.Load().(string), r, body, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+		var e Event
+		json.Unmarshal(body, &e)
+		mu.Lock()
+		defer mu.Unlock()
+		if calls[e.ID]++; calls[e.ID] <= 2 {
+			http.Error(w, "temporarily down", http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, e.ID+" "+e.Type)
+	}))
+	defer receiver.Close()
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := do("POST", "/api/admin/webhooks", fmt.Sprintf(`{"url":%q,"events":["user.created","user.deleted"]}`, receiver.URL))
+	var hook Webhook
+	json.Unmarshal(rec.Body.Bytes(), &hook)
+	secret.Store(hook.Secret)
+	fmt.Printf("Registered webhook %d -> %d, secret %s...\n", hook.ID, rec.Code, hook.Secret[:10])
+	do("POST", "/api/admin/webhooks", `{"url":"http://127.0.0.1:1/unused","events":["order.paid"]}`) // never matches
+
+	start := time.Now()
+	rec = do("POST", "/api/users", `{"name":"Rishabh"}`)
+	fmt.Printf("POST /api/users -> %d in %v (the receiver takes 100ms per call, not waited for)\n",
+		rec.Code, time.Since(start).Round(time.Millisecond))
+	do("DELETE", "/api/users/1", "")
+
+	disp.Flush()
+	slices.Sort(received) // the two events were delivered in parallel
+	fmt.Println("Receiver accepted:", received)
+	rec = do("GET", fmt.Sprintf("/api/admin/webhooks/%d/deliveries", hook.ID), "")
+	var attempts []Attempt
+	json.Unmarshal(rec.Body.Bytes(), &attempts)
+	slices.SortStableFunc(attempts, func(a, b Attempt) int { return strings.Compare(a.EventID, b.EventID) })
+	fmt.Println("Delivery history:")
+	for _, a := range attempts {
+		fmt.Printf("  %s %-12s attempt %d status %d %s\n", a.EventID, a.Event, a.Attempt, a.Status, a.Error)
+	}
+
+	fmt.Println("What the receiver's Verify rejects:")
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Now()
+	check := func(label string, ts int64, sig string) {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(SignatureHeader, sig)
+		fmt.Printf("  %-30s %v\n", label, Verify(hook.Secret, req, body, now))
+	}
+	check("valid", now.Unix(), Sign(hook.Secret, now.Unix(), body))
+	check("wrong secret", now.Unix(), Sign("guess", now.Unix(), body))
+	old := now.Add(-time.Hour).Unix()
+	check("replayed from an hour ago", old, Sign(hook.Secret, old, body))
+	check("old body, new timestamp", now.Unix(), Sign(hook.Secret, old, body))
+
+	disp.Close()
+}
+
+// Sign timestamp + body with a per-webhook secret, the receiver checks both and rejects old timestamps.
+// Compare signatures with hmac.Equal, a normal == leaks how many bytes matched through timing.
+// Publish only queues, a worker pool does the slow network calls off the request path.
+// Retry 5xx and network errors with exponential backoff and jitter; a 4xx won't get better by retrying.
+// Record every attempt, "did you send it?" is the first question every integrator asks.
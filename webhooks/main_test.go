@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is an httptest server that verifies every call and answers from a script
+type receiver struct {
+	*httptest.Server
+	secret string
+
+	mu       sync.Mutex
+	calls    map[string]int // per event ID
+	accepted []Event
+	badSigs  int
+}
+
+// newReceiver answers fail(n) for the n-th call of each event, 200 when it returns 0
+func newReceiver(t *testing.T, fail func(n int) int) *receiver {
+	rc := &receiver{calls: map[string]int{}}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		if err := Verify(rc.secret, r, body, time.Now()); err != nil {
+			rc.badSigs++
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		var e Event
+		json.Unmarshal(body, &e)
+		rc.calls[e.ID]++
+		if status := fail(rc.calls[e.ID]); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		rc.accepted = append(rc.accepted, e)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) got() ([]Event, int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return slices.Clone(rc.accepted), rc.badSigs
+}
+
+type setup struct {
+	reg  *Registry
+	disp *Dispatcher
+	api  http.Handler
+}
+
+func newSetup(t *testing.T) *setup {
+	reg := NewRegistry()
+	bus := &EventBus{}
+	disp := NewDispatcher(reg, 4, 100)
+	disp.BaseDelay = time.Millisecond
+	bus.Subscribe(func(e Event) { disp.Handle(e) })
+	t.Cleanup(disp.Close)
+	return &setup{reg: reg, disp: disp, api: routes(reg, bus)}
+}
+
+func (s *setup) do(method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.api.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func (s *setup) register(t *testing.T, rc *receiver, events ...string) Webhook {
+	t.Helper()
+	list, _ := json.Marshal(events)
+	rec := s.do("POST", "/api/admin/webhooks", fmt.Sprintf(`{"url":%q,"events":%s}`, rc.URL, list))
+	var hook Webhook
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &hook) != nil || !strings.HasPrefix(hook.Secret, "whsec_") {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	rc.mu.Lock()
+	rc.secret = hook.Secret
+	rc.mu.Unlock()
+	return hook
+}
+
+func (s *setup) history(t *testing.T, hookID int) []Attempt {
+	t.Helper()
+	rec := s.do("GET", fmt.Sprintf("/api/admin/webhooks/%d/deliveries", hookID), "")
+	var attempts []Attempt
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &attempts) != nil {
+		t.Fatalf("deliveries: %d %s", rec.Code, rec.Body)
+	}
+	return attempts
+}
+
+func TestRetryThenSuccess(t *testing.T) {
+	s := newSetup(t)
+	rc := newReceiver(t, func(n int) int {
+		if n <= 2 {
+			return http.StatusServiceUnavailable
+		}
+		return 0
+	})
+	hook := s.register(t, rc, "user.created")
+	s.do("POST", "/api/users", `{"name":"Rishabh"}`)
+	s.disp.Flush()
+
+	accepted, badSigs := rc.got()
+	if len(accepted) != 1 || accepted[0].Type != "user.created" || badSigs != 0 {
+		t.Fatalf("accepted %+v, %d bad signatures", accepted, badSigs)
+	}
+	data, _ := json.Marshal(accepted[0].Data)
+	if string(data) != `{"id":1,"name":"Rishabh"}` {
+		t.Errorf("payload data %s", data)
+	}
+
+	attempts := s.history(t, hook.ID)
+	if len(attempts) != 3 {
+		t.Fatalf("history %+v", attempts)
+	}
+	for i, a := range attempts {
+		wantStatus, failed := 503, true
+		if i == 2 {
+			wantStatus, failed = 200, false
+		}
+		if a.Attempt != i+1 || a.Status != wantStatus || (a.Error != "") != failed || a.EventID != accepted[0].ID || a.Event != "user.created" || a.Duration == "" {
+			t.Errorf("attempt %d: %+v", i+1, a)
+		}
+		if i > 0 && a.At.Sub(attempts[i-1].At) < s.disp.BaseDelay<<(i-1) {
+			t.Errorf("attempt %d came %v after the previous one, no backoff", i+1, a.At.Sub(attempts[i-1].At))
+		}
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{"server error", http.StatusInternalServerError, 3},
+		{"rate limited", http.StatusTooManyRequests, 3},
+		{"rejected", http.StatusBadRequest, 1}, // retrying a 4xx won't help
+		{"gone", http.StatusGone, 1},
+	}
+	for _, tt := range tests {
+		s := newSetup(t)
+		s.disp.MaxAttempts = 3
+		rc := newReceiver(t, func(int) int { return tt.status })
+		hook := s.register(t, rc, "*")
+		s.do("DELETE", "/api/users/7", "")
+		s.disp.Flush()
+		attempts := s.history(t, hook.ID)
+		if len(attempts) != tt.attempts {
+			t.Errorf("%s: %d attempts, want %d", tt.name, len(attempts), tt.attempts)
+		}
+		for _, a := range attempts {
+			if a.Status != tt.status || !strings.Contains(a.Error, strconv.Itoa(tt.status)) || a.Event != "user.deleted" {
+				t.Errorf("%s: %+v", tt.name, a)
+			}
+		}
+	}
+
+	// nobody listening: a network error, retried, status 0
+	s := newSetup(t)
+	s.disp.MaxAttempts = 2
+	rc := newReceiver(t, func(int) int { return 0 })
+	hook := s.register(t, rc, "*")
+	rc.Close()
+	s.do("DELETE", "/api/users/7", "")
+	s.disp.Flush()
+	if attempts := s.history(t, hook.ID); len(attempts) != 2 || attempts[0].Status != 0 || attempts[0].Error == "" {
+		t.Errorf("unreachable receiver: %+v", attempts)
+	}
+}
+
+// user creation returns while the receiver is still busy with the delivery
+func TestOffTheRequestPath(t *testing.T) {
+	s := newSetup(t)
+	release := make(chan struct{})
+	rc := newReceiver(t, func(int) int {
+		<-release
+		return 0
+	})
+	unblock := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(unblock) // runs before rc.Close, which waits for the blocked handler
+	s.register(t, rc, "user.created")
+
+	done := make(chan int)
+	go func() { done <- s.do("POST", "/api/users", `{"name":"A"}`).Code }()
+	select {
+	case code := <-done:
+		if code != http.StatusCreated {
+			t.Errorf("create: %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("POST /api/users waited for the webhook receiver")
+	}
+	unblock()
+	s.disp.Flush()
+	if accepted, _ := rc.got(); len(accepted) != 1 {
+		t.Errorf("accepted %d", len(accepted))
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	s := newSetup(t)
+	ok := func(int) int { return 0 }
+	created, deleted, all := newReceiver(t, ok), newReceiver(t, ok), newReceiver(t, ok)
+	s.register(t, created, "user.created")
+	s.register(t, deleted, "user.deleted")
+	s.register(t, all, "*")
+	s.do("POST", "/api/users", `{"name":"A"}`)
+	s.do("DELETE", "/api/users/1", "")
+	s.disp.Flush()
+
+	types := func(rc *receiver) []string {
+		var out []string
+		accepted, _ := rc.got()
+		for _, e := range accepted {
+			out = append(out, e.Type)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got := types(created); !slices.Equal(got, []string{"user.created"}) {
+		t.Errorf("created hook got %v", got)
+	}
+	if got := types(deleted); !slices.Equal(got, []string{"user.deleted"}) {
+		t.Errorf("deleted hook got %v", got)
+	}
+	if got := types(all); !slices.Equal(got, []string{"user.created", "user.deleted"}) {
+		t.Errorf("* hook got %v", got)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	reg := NewRegistry()
+	d := NewDispatcher(reg, 0, 1) // no workers: nothing drains the queue
+	hook := reg.Add("http://127.0.0.1:1/", []string{"*"})
+	d.Handle(Event{ID: "evt_1", Type: "user.created"})
+	d.Handle(Event{ID: "evt_2", Type: "user.created"})
+	attempts, _ := reg.Deliveries(hook.ID)
+	if len(attempts) != 1 || attempts[0].EventID != "evt_2" || !strings.Contains(attempts[0].Error, "queue full") {
+		t.Errorf("dropped delivery not recorded: %+v", attempts)
+	}
+}
+
+func TestHandleAfterClose(t *testing.T) {
+	reg := NewRegistry()
+	d := NewDispatcher(reg, 2, 10)
+	hook := reg.Add("http://127.0.0.1:1/", []string{"*"})
+	d.Close()
+	d.Close() // twice is fine
+	if err := d.Handle(Event{ID: "evt_1", Type: "user.created"}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Handle after Close: %v", err)
+	}
+	attempts, _ := reg.Deliveries(hook.ID)
+	if len(attempts) != 1 || !strings.Contains(attempts[0].Error, "dispatcher closed") {
+		t.Errorf("dropped delivery not recorded: %+v", attempts)
+	}
+	d.Flush() // nothing pending
+}
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1_700_000_000, 0)
+	ts := now.Unix()
+	old := now.Add(-MaxClockSkew - time.Second).Unix()
+	tests := []struct {
+		name, ts, sig string
+		body          []byte
+		want          error
+	}{
+		{"valid", fmt.Sprint(ts), Sign(secret, ts, body), body, nil},
+		{"at the skew limit", fmt.Sprint(ts - 300), Sign(secret, ts-300, body), body, nil},
+		{"wrong secret", fmt.Sprint(ts), Sign("guess", ts, body), body, ErrBadSignature},
+		{"changed body", fmt.Sprint(ts), Sign(secret, ts, body), []byte(`{"id":"evt_2"}`), ErrBadSignature},
+		{"replayed", fmt.Sprint(old), Sign(secret, old, body), body, ErrStale},
+		{"from the future", fmt.Sprint(ts + 301), Sign(secret, ts+301, body), body, ErrStale},
+		{"old signature, new timestamp", fmt.Sprint(ts), Sign(secret, old, body), body, ErrBadSignature},
+		{"no timestamp", "", Sign(secret, ts, body), body, ErrBadSignature},
+		{"no signature", fmt.Sprint(ts), "", body, ErrBadSignature},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set(TimestampHeader, tt.ts)
+		r.Header.Set(SignatureHeader, tt.sig)
+		if err := Verify(secret, r, tt.body, now); !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if sig := Sign(secret, ts, body); !strings.HasPrefix(sig, "sha256=") || len(sig) != len("sha256=")+64 {
+		t.Errorf("Sign = %q", sig)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{BaseDelay: 100 * time.Millisecond}
+	for attempt := 1; attempt <= 5; attempt++ {
+		base := d.BaseDelay << (attempt - 1)
+		for range 100 {
+			if got := d.backoff(attempt); got < base || got > base+base/2 {
+				t.Fatalf("attempt %d: %v outside [%v, %v]", attempt, got, base, base+base/2)
+			}
+		}
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	s := newSetup(t)
+	for _, body := range []string{`{}`, `{"url":"ftp://x","events":["*"]}`, `{"url":"http://x"}`, `{"url":"http://x","events":[]}`, `nope`} {
+		if rec := s.do("POST", "/api/admin/webhooks", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", body, rec.Code)
+		}
+	}
+	if rec := s.do("GET", "/api/admin/webhooks/9/deliveries", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown webhook: %d", rec.Code)
+	}
+	hook := s.reg.Add("http://x", []string{"*"})
+	if rec := s.do("GET", fmt.Sprintf("/api/admin/webhooks/%d/deliveries", hook.ID), ""); rec.Code != 200 || strings.TrimSpace(rec.Body.String()) != "null" {
+		t.Errorf("no deliveries yet: %d %s", rec.Code, rec.Body)
+	}
+	if other := s.reg.Add("http://y", []string{"*"}); other.Secret == hook.Secret || other.ID == hook.ID {
+		t.Error("two webhooks share a secret or an ID")
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// maxTokenSize lets the scanner handle very long "words" (a minified file has no spaces at all)
+const maxTokenSize = 10 * 1024 * 1024
+
+// WordFrequency counts the words read from r (case-insensitive, punctuation stripped)
+// and writes the topN most frequent ones to w as an aligned table.
+// topN <= 0 prints every word.
+func WordFrequency(r io.Reader, w io.Writer, topN int) error {
+	counts := make(map[string]int)
+	total := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTokenSize)
+	scanner.Split(bufio.ScanWords) // split on any whitespace instead of lines
+	for scanner.Scan() {
+		word := normalizeWord(scanner.Text())
+		if word == "" {
+			continue // the token was only punctuation, like "--" or "..."
+		}
+		counts[word]++
+		total++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("word frequency: %w", err)
+	}
+
+	if total == 0 {
+		_, err := fmt.Fprintln(w, "No words found in the input.")
+		return err
+	}
+
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	// most frequent first, ties in alphabetical order so the output is always the same
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if topN > 0 && topN < len(words) {
+		words = words[:topN]
+	}
+
+	// tabwriter pads the tab separated cells so the columns line up
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "RANK\tWORD\tCOUNT\tSHARE\t")
+	for i, word := range words {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%.1f%%\t\n", i+1, word, counts[word], float64(counts[word])*100/float64(total))
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("word frequency: %w", err)
+	}
+	_, err := fmt.Fprintf(w, "%d words, %d distinct\n", total, len(counts))
+	return err
+}
+
+// normalizeWord lower-cases the word and strips punctuation around it.
+// Apostrophes and hyphens inside a word are kept: "don't", "well-known".
+func normalizeWord(s string) string {
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.ToLower(s) // ToLower handles Unicode, "ÉTÉ" -> "été"
+}
+
+func main() {
+	stdin := flag.Bool("stdin", false, "read the text from standard input, e.g. cat file.txt | go run main.go -stdin")
+	top := flag.Int("top", 10, "how many words to show, 0 = all")
+	flag.Parse()
+
+	if *stdin {
+		// real pipe filter: input from stdin, table to stdout, errors to stderr
+		if err := WordFrequency(os.Stdin, os.Stdout, *top); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println("Learning bufio, stdin/stdout and text/tabwriter in Go")
+
+	text := `Go is expressive, concise, clean, and efficient. Its concurrency
+mechanisms make it easy to write programs that get the most out of multicore
+and networked machines... Go compiles quickly to machine code yet has the
+convenience of garbage collection. GO is fast; go is fun -- isn't it?`
+	fmt.Println("\nTop 5 words:")
+	if err := WordFrequency(strings.NewReader(text), os.Stdout, 5); err != nil {
+		fmt.Println("Error:", err)
+	}
+
+	fmt.Println("\nOnly punctuation:")
+	WordFrequency(strings.NewReader("... -- !!! ,"), os.Stdout, 5)
+
+	fmt.Println("\nA 1MB word (more than the scanner's default 64KB limit):")
+	var sb strings.Builder
+	err := WordFrequency(strings.NewReader(strings.Repeat("a", 1<<20)+" short short"), &sb, 1)
+	fmt.Print(sb.String())
+	fmt.Println("error:", err)
+
+	fmt.Println("\nTry it as a pipe: cat ../README.md | go run main.go -stdin -top 5")
+}
+
+// bufio.Scanner reads input piece by piece: ScanLines (default), ScanWords, ScanRunes or a custom split function.
+// Its default token limit is 64KB, raise it with scanner.Buffer for long lines.
+// os.Stdin and os.Stdout are just an io.Reader and an io.Writer, so the same function works
+// with files, strings.Reader, network connections or pipes.
+// text/tabwriter aligns columns, remember to call Flush.
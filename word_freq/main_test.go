@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const sampleText = `Go is expressive, concise, clean, and efficient. Its concurrency
+mechanisms make it easy to write programs that get the most out of multicore
+and networked machines... Go compiles quickly to machine code yet has the
+convenience of garbage collection. GO is fast; go is fun -- isn't it?`
+
+// update and checkGolden are a shorter version of the golden helper in the textutil lesson
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name.golden; -update rewrites the file first
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want = bytes.ReplaceAll(want, []byte("\r\n"), []byte("\n")); !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (go test main.go main_test.go -update rewrites it)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+func TestWordFrequencyGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		topN  int
+	}{
+		{"top5", sampleText, 5},
+		{"all", sampleText, 0},
+		{"top_more_than_words", "b a b c", 10},
+		{"punctuation_and_case", "Hello, HELLO! hello... ÉTÉ été -- don't well-known (well-known)", 0},
+		{"empty", "", 5},
+		{"only_punctuation", "... -- !!! ,", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := WordFrequency(strings.NewReader(tt.input), &out, tt.topN); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "word_freq_"+tt.name, out.Bytes())
+		})
+	}
+}
+
+func TestWordFrequencyLongWord(t *testing.T) {
+	var out strings.Builder
+	long := strings.Repeat("a", 1<<20)
+	if err := WordFrequency(strings.NewReader(long+" short short"), &out, 1); err != nil {
+		t.Fatalf("a 1MB word: %v", err)
+	}
+	if !strings.Contains(out.String(), "short") || !strings.HasSuffix(out.String(), "3 words, 2 distinct\n") {
+		t.Errorf("got:\n%s", out.String())
+	}
+
+	err := WordFrequency(strings.NewReader(strings.Repeat("a", maxTokenSize+1)), &out, 1)
+	if err == nil || !strings.Contains(err.Error(), "too long") {
+		t.Errorf("a word over maxTokenSize: %v", err)
+	}
+}
+
+func TestWordFrequencyReadError(t *testing.T) {
+	boom := errors.New("boom")
+	if err := WordFrequency(iotest.ErrReader(boom), &strings.Builder{}, 5); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want it to wrap boom", err)
+	}
+	// the reader hands out one byte at a time: words split across reads still count once
+	var out strings.Builder
+	if err := WordFrequency(iotest.OneByteReader(strings.NewReader("go go gopher")), &out, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out.String(), "3 words, 2 distinct\n") {
+		t.Errorf("got:\n%s", out.String())
+	}
+}
+
+func TestNormalizeWord(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Go", "go"},
+		{"efficient.", "efficient"},
+		{"(well-known)", "well-known"},
+		{"isn't", "isn't"},
+		{"--", ""},
+		{"ÉTÉ!", "été"},
+		{"42nd", "42nd"},
+	}
+	for _, tt := range tests {
+		if got := normalizeWord(tt.in); got != tt.want {
+			t.Errorf("normalizeWord(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}